@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/smallnest/goclaw/agent/hooks"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/providers"
@@ -25,6 +26,7 @@ type Agent struct {
 	workspace          string
 	skillsLoader       *SkillsLoader
 	helper             *AgentHelper
+	hooks              *hooks.Registry
 	maxHistoryMessages int // 最大历史消息数量
 
 	mu        sync.RWMutex
@@ -44,6 +46,8 @@ type NewAgentConfig struct {
 	MaxIteration       int
 	MaxHistoryMessages int // 最大历史消息数量
 	SkillsLoader       *SkillsLoader
+	ThinkingLevel      string          // 扩展思考级别: off, minimal, low, medium, high, xhigh
+	Hooks              *hooks.Registry // 生命周期钩子，为空时创建空注册表
 }
 
 // NewAgent creates a new agent
@@ -61,6 +65,10 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 		cfg.MaxHistoryMessages = 100
 	}
 
+	if cfg.Hooks == nil {
+		cfg.Hooks = hooks.NewRegistry()
+	}
+
 	state := NewAgentState()
 	state.SystemPrompt = cfg.Context.BuildSystemPrompt(nil)
 	state.Model = getModelName(cfg.Provider)
@@ -68,10 +76,14 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 	state.SessionKey = "main"
 	state.Tools = ToAgentTools(cfg.Tools.ListExisting())
 	state.LoadedSkills = []string{} // Initialize with empty loaded skills
+	state.ThinkingLevel = cfg.ThinkingLevel
 
 	// Load skills list
 	var skills []*Skill
+	var skillsSource func() []*Skill
 	if cfg.SkillsLoader != nil {
+		// Each run takes the loader's current skills so reloads apply to new turns
+		skillsSource = cfg.SkillsLoader.List
 		if err := cfg.SkillsLoader.Discover(); err == nil {
 			skills = cfg.SkillsLoader.List()
 			logger.Info("Skills discovered for agent",
@@ -89,8 +101,10 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 		ConvertToLLM:     defaultConvertToLLM,
 		TransformContext: nil,
 		Skills:           skills,
+		SkillsSource:     skillsSource,
 		LoadedSkills:     state.LoadedSkills,
 		ContextBuilder:   cfg.Context,
+		Hooks:            cfg.Hooks,
 		GetSteeringMessages: func(s *AgentState) func() ([]AgentMessage, error) {
 			return func() ([]AgentMessage, error) {
 				return s.DequeueSteeringMessages(), nil
@@ -115,6 +129,7 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 		workspace:          cfg.Workspace,
 		skillsLoader:       cfg.SkillsLoader,
 		helper:             NewAgentHelper(cfg.SessionMgr),
+		hooks:              cfg.Hooks,
 		maxHistoryMessages: cfg.MaxHistoryMessages,
 		state:              state,
 		eventSubs:          make([]chan *Event, 0),
@@ -219,6 +234,9 @@ func (a *Agent) handleInboundMessage(ctx context.Context, msg *bus.InboundMessag
 		zap.String("content", msg.Content),
 	)
 
+	ctx, span := startInboundSpan(ctx, msg, "")
+	defer span.End()
+
 	// Generate session key
 	sessionKey := msg.SessionKey()
 	logger.Debug("Generated session key", zap.String("session_key", sessionKey))
@@ -231,10 +249,16 @@ func (a *Agent) handleInboundMessage(ctx context.Context, msg *bus.InboundMessag
 	}
 	logger.Debug("Session retrieved/created", zap.String("session_key", sess.Key))
 
+	// Inbound hooks may block or rewrite the message
+	content, ok := runMessageHooks(ctx, a.hooks, a.sessionMgr, sess, channelHookPayload(hooks.StageInbound, "", sessionKey, msg, msg.Content))
+	if !ok {
+		return
+	}
+
 	// Convert to agent message
 	agentMsg := AgentMessage{
 		Role:      RoleUser,
-		Content:   []ContentBlock{TextContent{Text: msg.Content}},
+		Content:   []ContentBlock{TextContent{Text: content}},
 		Timestamp: msg.Timestamp.UnixMilli(),
 	}
 
@@ -289,11 +313,21 @@ func (a *Agent) handleInboundMessage(ctx context.Context, msg *bus.InboundMessag
 	if len(finalMessages) > 0 {
 		lastMsg := finalMessages[len(finalMessages)-1]
 		if lastMsg.Role == RoleAssistant {
-			a.publishToBus(ctx, msg.Channel, msg.ChatID, lastMsg)
+			// Outbound hooks may scrub or block the reply
+			reply, ok := runMessageHooks(ctx, a.hooks, a.sessionMgr, sess, channelHookPayload(hooks.StageOutbound, "", sessionKey, msg, extractTextContent(lastMsg)))
+			if ok {
+				lastMsg.Content = []ContentBlock{TextContent{Text: reply}}
+				a.publishToBus(ctx, msg.Channel, msg.ChatID, lastMsg)
+			}
 		}
 	}
 }
 
+// Hooks returns the lifecycle hook registry, which Go hooks can be registered on
+func (a *Agent) Hooks() *hooks.Registry {
+	return a.hooks
+}
+
 // updateSession updates the session with new messages
 func (a *Agent) updateSession(sess *session.Session, messages []AgentMessage) {
 	_ = a.helper.UpdateSession(sess, messages, &UpdateSessionOptions{SaveImmediately: true})
@@ -349,7 +383,14 @@ func (a *Agent) publishToBus(ctx context.Context, channel, chatID string, msg Ag
 // Returns a read-only channel. Call Unsubscribe to clean up.
 // IMPORTANT: Always call Unsubscribe when done to prevent memory leaks.
 func (a *Agent) Subscribe() <-chan *Event {
-	ch := make(chan *Event, 10)
+	return a.SubscribeWithBuffer(10)
+}
+
+// SubscribeWithBuffer subscribes with a channel of the given capacity
+// Events are dropped for a subscriber whose channel is full, so consumers of
+// token-by-token stream events should use a large buffer.
+func (a *Agent) SubscribeWithBuffer(size int) <-chan *Event {
+	ch := make(chan *Event, size)
 
 	a.mu.Lock()
 	a.eventSubs = append(a.eventSubs, ch)
@@ -439,6 +480,14 @@ func (a *Agent) SetSystemPrompt(prompt string) {
 	a.state.SystemPrompt = prompt
 }
 
+// SetThinkingLevel updates the extended thinking level
+func (a *Agent) SetThinkingLevel(level string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state.ThinkingLevel = level
+}
+
 // SetTools updates the available tools
 func (a *Agent) SetTools(tools []Tool) {
 	a.mu.Lock()
@@ -508,7 +557,9 @@ func (a *Agent) Reset() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	thinkingLevel := a.state.ThinkingLevel
 	a.state = NewAgentState()
+	a.state.ThinkingLevel = thinkingLevel
 	a.state.SystemPrompt = a.context.BuildSystemPrompt(nil)
 	a.state.Model = getModelName(a.provider)
 	a.state.Provider = "provider"
@@ -597,6 +648,12 @@ func defaultConvertToLLM(messages []AgentMessage) ([]providers.Message, error) {
 				} else if b.URL != "" {
 					providerMsg.Images = append(providerMsg.Images, b.URL)
 				}
+			case ThinkingContent:
+				providerMsg.Thinking = append(providerMsg.Thinking, providers.ThinkingBlock{
+					Thinking:  b.Thinking,
+					Signature: b.Signature,
+					Redacted:  b.Redacted,
+				})
 			}
 		}
 
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/smallnest/goclaw/agent/hooks"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+var (
+	// ErrAgentNotFound 请求的 Agent 不存在
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrMessageDenied 消息或回复被钩子拒绝
+	ErrMessageDenied = errors.New("message denied by hook")
+)
+
+// ChatRequest 直接对话请求，不经过消息总线（供 OpenAI 兼容接口等同步调用方使用）
+type ChatRequest struct {
+	AgentID    string         // 为空时使用默认 Agent
+	SessionKey string         // 会话键；设置后从会话加载历史并保存本轮消息
+	History    []AgentMessage // 未设置会话键时作为上下文的历史消息
+	Message    AgentMessage   // 本轮用户消息
+	Channel    string         // 钩子中使用的通道名称，默认 api
+	SenderID   string         // 发送者 ID
+	OnEvent    func(*Event)   // 可选，接收本轮的流式和工具事件
+
+	ExtraSystemPrompt string // 追加在 Agent 系统提示词之后的内容，如分身的任务说明
+}
+
+// ChatResult 直接对话结果
+type ChatResult struct {
+	AgentID  string
+	Reply    AgentMessage     // 最终的 assistant 回复（已经过出站钩子）
+	Messages []AgentMessage   // 本轮产生的新消息
+	Usage    *providers.Usage // 本轮所有 LLM 调用的用量之和，provider 未报告时为 nil
+}
+
+// Chat 运行一轮对话并返回最终回复
+// 每次调用使用独立的 orchestrator，可以安全地并发调用
+func (m *AgentManager) Chat(ctx context.Context, req *ChatRequest) (*ChatResult, error) {
+	m.mu.RLock()
+	agentID := req.AgentID
+	var agent *Agent
+	if agentID == "" {
+		agent = m.defaultAgent
+		agentID = m.agentIDLocked(agent)
+	} else {
+		agent = m.agents[agentID]
+	}
+	maxHistory := 0
+	if m.cfg != nil {
+		maxHistory = m.cfg.Agents.Defaults.MaxHistoryMessages
+	}
+	m.mu.RUnlock()
+
+	if agent == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, req.AgentID)
+	}
+	if maxHistory <= 0 {
+		maxHistory = 100
+	}
+
+	channel := req.Channel
+	if channel == "" {
+		channel = "api"
+	}
+
+	// 加载会话历史
+	var sess *session.Session
+	history := req.History
+	if req.SessionKey != "" {
+		var err error
+		sess, err = m.sessionMgr.GetOrCreate(req.SessionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		history = sessionMessagesToAgentMessages(sess.GetHistorySafe(maxHistory))
+	}
+
+	// 入站钩子
+	hookMsg := &bus.InboundMessage{Channel: channel, ChatID: req.SessionKey, SenderID: req.SenderID}
+	content, ok := runMessageHooks(ctx, agent.hooks, m.sessionMgr, sess, channelHookPayload(hooks.StageInbound, agentID, req.SessionKey, hookMsg, extractTextContent(req.Message)))
+	if !ok {
+		return nil, ErrMessageDenied
+	}
+	userMsg := req.Message
+	userMsg.Content = replaceText(userMsg.Content, content)
+
+	ctx = context.WithValue(ctx, AgentIDContextKey, agentID)
+	if req.SessionKey != "" {
+		ctx = context.WithValue(ctx, SessionKeyContextKey, req.SessionKey)
+	}
+	ctx, span := startInboundSpan(ctx, hookMsg, agentID)
+	defer span.End()
+
+	onEvent := req.OnEvent
+	if onEvent != nil && (agent.hooks.Has(hooks.StagePostLLM) || agent.hooks.Has(hooks.StageOutbound)) {
+		// Raw stream deltas would bypass the response hooks, only the final reply is delivered
+		onEvent = func(event *Event) {
+			switch event.Type {
+			case EventStreamContent, EventStreamThinking, EventStreamFinal:
+				return
+			}
+			req.OnEvent(event)
+		}
+	}
+
+	allMessages := append(append([]AgentMessage{}, history...), userMsg)
+	orchestrator := agent.GetOrchestrator().Fork()
+	orchestrator.config.ExtraSystemPrompt = req.ExtraSystemPrompt
+	finalMessages, err := runWithEvents(ctx, orchestrator, allMessages, onEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if sess != nil {
+		m.updateSession(sess, finalMessages, len(history))
+	}
+
+	result := &ChatResult{AgentID: agentID, Reply: AgentMessage{Role: RoleAssistant}}
+	if len(finalMessages) > len(history) {
+		result.Messages = finalMessages[len(history):]
+	}
+	if len(finalMessages) > 0 && finalMessages[len(finalMessages)-1].Role == RoleAssistant {
+		result.Reply = finalMessages[len(finalMessages)-1]
+	}
+	result.Usage = sumUsage(result.Messages)
+
+	// 出站钩子
+	reply, ok := runMessageHooks(ctx, agent.hooks, m.sessionMgr, sess, channelHookPayload(hooks.StageOutbound, agentID, req.SessionKey, hookMsg, extractTextContent(result.Reply)))
+	if !ok {
+		return nil, ErrMessageDenied
+	}
+	result.Reply.Content = []ContentBlock{TextContent{Text: reply}}
+
+	return result, nil
+}
+
+// sumUsage adds up the usage recorded on the assistant messages of a run
+func sumUsage(messages []AgentMessage) *providers.Usage {
+	var total *providers.Usage
+	for _, msg := range messages {
+		usage, ok := msg.Metadata[UsageMetadataKey].(providers.Usage)
+		if !ok || msg.Role != RoleAssistant {
+			continue
+		}
+		if total == nil {
+			total = &providers.Usage{}
+		}
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+		total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+		total.CacheReadInputTokens += usage.CacheReadInputTokens
+	}
+	return total
+}
+
+// runWithEvents runs the orchestrator and forwards its events to onEvent
+func runWithEvents(ctx context.Context, o *Orchestrator, messages []AgentMessage, onEvent func(*Event)) ([]AgentMessage, error) {
+	if onEvent == nil {
+		return o.Run(ctx, messages)
+	}
+
+	type runResult struct {
+		messages []AgentMessage
+		err      error
+	}
+	done := make(chan runResult, 1)
+	events := o.Subscribe()
+	go func() {
+		msgs, err := o.Run(ctx, messages)
+		done <- runResult{messages: msgs, err: err}
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			onEvent(event)
+		case res := <-done:
+			// Events are emitted before Run returns, drain what is left
+			for {
+				select {
+				case event := <-events:
+					onEvent(event)
+				default:
+					return res.messages, res.err
+				}
+			}
+		}
+	}
+}
+
+// replaceText replaces the text blocks of a message with a single text block, keeping other blocks
+func replaceText(content []ContentBlock, text string) []ContentBlock {
+	result := []ContentBlock{TextContent{Text: text}}
+	for _, block := range content {
+		if _, ok := block.(TextContent); !ok {
+			result = append(result, block)
+		}
+	}
+	return result
+}
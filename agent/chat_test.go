@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/goclaw/agent/hooks"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// echoRegistryTool is the registry flavour of echoTool
+type echoRegistryTool struct{}
+
+func (echoRegistryTool) Name() string        { return "echo" }
+func (echoRegistryTool) Description() string { return "Echo the input" }
+func (echoRegistryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (echoRegistryTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	text, _ := params["text"].(string)
+	return text, nil
+}
+
+func newChatTestManager(t *testing.T, provider providers.Provider) *AgentManager {
+	t.Helper()
+	workspace := t.TempDir()
+	sessionMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolRegistry := NewToolRegistry()
+	if err := toolRegistry.RegisterExisting(echoRegistryTool{}); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        bus.NewMessageBus(10),
+		Provider:   provider,
+		SessionMgr: sessionMgr,
+		Tools:      toolRegistry,
+		DataDir:    workspace,
+	})
+	cfg := &config.Config{}
+	cfg.Workspace.Path = workspace
+	cfg.Agents.List = []config.AgentConfig{{ID: "helper", Default: true}}
+	if err := mgr.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+	return mgr
+}
+
+func TestAgentManagerChat(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "echo", Params: map[string]interface{}{"text": "pong"}}},
+			Usage: providers.Usage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13}},
+		{Content: "pong!", Usage: providers.Usage{PromptTokens: 20, CompletionTokens: 2, TotalTokens: 22}},
+		{Content: "second"},
+	}}
+	mgr := newChatTestManager(t, provider)
+
+	var events []EventType
+	result, err := mgr.Chat(context.Background(), &ChatRequest{
+		SessionKey: "api:test",
+		Message:    AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "ping"}}},
+		OnEvent:    func(e *Event) { events = append(events, e.Type) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AgentID != "helper" || extractTextContent(result.Reply) != "pong!" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.Usage == nil || result.Usage.PromptTokens != 30 || result.Usage.CompletionTokens != 5 || result.Usage.TotalTokens != 35 {
+		t.Errorf("expected the usage of both LLM calls, got %+v", result.Usage)
+	}
+
+	var sawTool bool
+	for _, e := range events {
+		if e == EventToolExecutionEnd {
+			sawTool = true
+		}
+	}
+	if !sawTool {
+		t.Errorf("expected tool events, got %v", events)
+	}
+
+	// The session keeps the turn, so the next request sees the history
+	result, err = mgr.Chat(context.Background(), &ChatRequest{
+		SessionKey: "api:test",
+		Message:    AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "again"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Usage != nil {
+		t.Errorf("expected no usage when the provider reports none, got %+v", result.Usage)
+	}
+	last := provider.calls[len(provider.calls)-1]
+	var userTurns int
+	for _, msg := range last {
+		if msg.Role == "user" {
+			userTurns++
+		}
+	}
+	if userTurns != 2 {
+		t.Errorf("expected the stored history to be sent, got %d user messages", userTurns)
+	}
+
+	if _, err := mgr.Chat(context.Background(), &ChatRequest{AgentID: "missing"}); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("expected ErrAgentNotFound, got %v", err)
+	}
+}
+
+func TestAgentManagerChatHooks(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.Response{{Content: "token sk-123"}}}
+	mgr := newChatTestManager(t, provider)
+
+	ag, _ := mgr.GetAgent("helper")
+	ag.Hooks().Register(hooks.StageInbound, hooks.NewFuncHook("block", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		if p.Content == "forbidden" {
+			return hooks.Deny("nope"), nil
+		}
+		return nil, nil
+	}))
+	ag.Hooks().Register(hooks.StageOutbound, hooks.NewFuncHook("scrub", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		content := "token [REDACTED]"
+		return &hooks.Decision{Action: hooks.ActionMutate, Content: &content}, nil
+	}))
+
+	_, err := mgr.Chat(context.Background(), &ChatRequest{Message: AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "forbidden"}}}})
+	if !errors.Is(err, ErrMessageDenied) {
+		t.Fatalf("expected ErrMessageDenied, got %v", err)
+	}
+
+	var streamed bool
+	result, err := mgr.Chat(context.Background(), &ChatRequest{
+		Message: AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}},
+		OnEvent: func(e *Event) {
+			if e.Type == EventStreamContent {
+				streamed = true
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractTextContent(result.Reply) != "token [REDACTED]" || streamed {
+		t.Errorf("expected the outbound hook to apply, got %q (streamed=%v)", extractTextContent(result.Reply), streamed)
+	}
+}
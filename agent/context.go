@@ -51,11 +51,22 @@ func (b *ContextBuilder) BuildSystemPromptWithMode(skills []*Skill, mode PromptM
 
 // buildSystemPromptWithSkills 使用指定的技能内容和模式构建系统提示词
 func (b *ContextBuilder) buildSystemPromptWithSkills(skillsContent string, mode PromptMode) string {
+	stable, dynamic := b.buildSystemPromptBlocks(skillsContent, mode)
+	if dynamic == "" {
+		return stable
+	}
+	return stable + "---\n\n" + dynamic
+}
+
+// buildSystemPromptBlocks 构建系统提示词的稳定前缀和动态后缀
+// 稳定前缀（身份、工具、技能、Bootstrap 文件等）在多轮对话间保持不变，可作为提示词缓存断点；
+// 动态后缀（当前时间）每次调用都会变化，必须放在缓存断点之后
+func (b *ContextBuilder) buildSystemPromptBlocks(skillsContent string, mode PromptMode) (stable, dynamic string) {
 	isMinimal := mode == PromptModeMinimal || mode == PromptModeNone
 
 	// 对于 "none" 模式，只返回基本身份行
 	if mode == PromptModeNone {
-		return "You are a personal assistant running inside GoClaw."
+		return "You are a personal assistant running inside GoClaw.", ""
 	}
 
 	var parts []string
@@ -117,13 +128,18 @@ func (b *ContextBuilder) buildSystemPromptWithSkills(skillsContent string, mode
 		parts = append(parts, b.buildRuntime())
 	}
 
-	return fmt.Sprintf("%s\n\n", joinNonEmpty(parts, "\n\n---\n\n"))
+	return fmt.Sprintf("%s\n\n", joinNonEmpty(parts, "\n\n---\n\n")), b.buildCurrentTime() + "\n\n"
+}
+
+// buildCurrentTime 构建当前时间信息
+func (b *ContextBuilder) buildCurrentTime() string {
+	return fmt.Sprintf(`## Current Time
+
+%s`, time.Now().Format("2006-01-02 15:04:05 MST"))
 }
 
 // buildIdentityAndTools 构建核心身份和工具列表
 func (b *ContextBuilder) buildIdentityAndTools() string {
-	now := time.Now()
-
 	// 定义核心工具摘要 - 参考了 OpenClaw 的详细描述风格
 	coreToolSummaries := map[string]string{
 		"browser_navigate":       "Navigate to a URL and wait for page load",
@@ -175,7 +191,6 @@ You are **GoClaw**, a personal AI assistant running on the user's system.
 You are NOT a passive chat bot. You are a **DOER** that executes tasks directly.
 Your mission: complete user requests using all available means, minimizing human intervention.
 
-**Current Time**: %s
 **Workspace**: %s
 
 ## Tooling
@@ -207,7 +222,6 @@ TOOLS.md does not control tool availability; it is user guidance for how to use
 - If you have tools available for a task, use them. No permission needed for safe operations.
 - **NEVER HALLUCINATE SEARCH RESULTS**: When presenting search results, ONLY use the exact data returned by the tool. If no results were found, clearly state that no results were found.
 - When a tool fails: analyze the error, try an alternative approach WITHOUT asking the user unless absolutely necessary.`,
-		now.Format("2006-01-02 15:04:05 MST"),
 		b.workspace,
 		strings.Join(toolLines, "\n"))
 }
@@ -533,6 +547,19 @@ func (b *ContextBuilder) buildSelectedSkills(selectedSkillNames []string, skills
 					}
 				}
 
+				// 列出可通过 skill_run 运行的入口
+				if len(skill.Entrypoints) > 0 {
+					sb.WriteString("**Entrypoints (run with the skill_run tool):**\n")
+					for _, ep := range skill.Entrypoints {
+						sb.WriteString(fmt.Sprintf("- `%s`: `%s`", ep.Name, strings.Join(ep.Command, " ")))
+						if ep.Description != "" {
+							sb.WriteString(" - " + ep.Description)
+						}
+						sb.WriteString("\n")
+					}
+					sb.WriteString("\n")
+				}
+
 				// 注入技能正文内容
 				if skill.Content != "" {
 					sb.WriteString(skill.Content)
@@ -66,6 +66,14 @@ func (h *AgentHelper) UpdateSession(sess *session.Session, messages []AgentMessa
 			}
 		}
 
+		// Preserve hook denials so they can be audited later
+		if denial, ok := msg.Metadata[HookDenialMetadataKey]; ok {
+			if sessMsg.Metadata == nil {
+				sessMsg.Metadata = make(map[string]interface{})
+			}
+			sessMsg.Metadata[HookDenialMetadataKey] = denial
+		}
+
 		sess.AddMessage(sessMsg)
 	}
 
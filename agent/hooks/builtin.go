@@ -0,0 +1,222 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// BuiltinFactory 根据配置选项创建 Go 实现的钩子
+type BuiltinFactory func(name string, options map[string]any) (Hook, error)
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = map[string]BuiltinFactory{
+		"deny_pattern":   newDenyPatternHook,
+		"redact_pattern": newRedactPatternHook,
+	}
+)
+
+// RegisterBuiltin 注册内置钩子，注册后可在配置中通过 builtin 字段引用
+func RegisterBuiltin(name string, factory BuiltinFactory) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[name] = factory
+}
+
+// Builtins 返回已注册的内置钩子名称
+func Builtins() []string {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegistryFromConfig 根据 Agent 配置创建钩子注册表
+func NewRegistryFromConfig(cfgs []config.HookConfig) (*Registry, error) {
+	registry := NewRegistry()
+	for i, cfg := range cfgs {
+		if !ValidStage(cfg.Stage) {
+			return nil, fmt.Errorf("hook %d: invalid stage %q", i, cfg.Stage)
+		}
+
+		var hook Hook
+		switch {
+		case cfg.Builtin != "" && cfg.Command != "":
+			return nil, fmt.Errorf("hook %d: builtin and command are mutually exclusive", i)
+		case cfg.Builtin != "":
+			builtinsMu.RLock()
+			factory, ok := builtins[cfg.Builtin]
+			builtinsMu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("hook %d: unknown builtin %q", i, cfg.Builtin)
+			}
+			name := cfg.Name
+			if name == "" {
+				name = cfg.Builtin
+			}
+			h, err := factory(name, cfg.Options)
+			if err != nil {
+				return nil, fmt.Errorf("hook %s: %w", name, err)
+			}
+			hook = h
+		case cfg.Command != "":
+			hook = NewExecHook(cfg.Name, cfg.Command, cfg.Args, time.Duration(cfg.Timeout)*time.Second)
+		default:
+			return nil, fmt.Errorf("hook %d: builtin or command is required", i)
+		}
+
+		var opts []Option
+		if len(cfg.Tools) > 0 {
+			opts = append(opts, WithTools(cfg.Tools...))
+		}
+		if cfg.FailOpen {
+			opts = append(opts, WithFailOpen())
+		}
+		registry.Register(Stage(cfg.Stage), hook, opts...)
+	}
+	return registry, nil
+}
+
+// patternOption 读取并编译 pattern 选项
+func patternOption(options map[string]any) (*regexp.Regexp, error) {
+	pattern, _ := options["pattern"].(string)
+	if pattern == "" {
+		return nil, fmt.Errorf("option pattern is required")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// payloadText 返回当前阶段被检查的文本
+func payloadText(p *Payload) string {
+	switch p.Stage {
+	case StagePreLLM:
+		if len(p.Messages) > 0 {
+			return p.Messages[len(p.Messages)-1].Content
+		}
+		return ""
+	case StagePreTool:
+		data, _ := json.Marshal(p.Arguments)
+		return string(data)
+	case StagePostTool:
+		return p.Result
+	default:
+		return p.Content
+	}
+}
+
+// newDenyPatternHook 内容匹配正则时拒绝
+// 选项：pattern（必填）、reason
+func newDenyPatternHook(name string, options map[string]any) (Hook, error) {
+	re, err := patternOption(options)
+	if err != nil {
+		return nil, err
+	}
+	reason, _ := options["reason"].(string)
+	if reason == "" {
+		reason = fmt.Sprintf("matched blocked pattern %q", re.String())
+	}
+
+	return NewFuncHook(name, func(ctx context.Context, p *Payload) (*Decision, error) {
+		if re.MatchString(payloadText(p)) {
+			return Deny(reason), nil
+		}
+		return nil, nil
+	}), nil
+}
+
+// newRedactPatternHook 将匹配正则的内容替换为 replacement
+// 选项：pattern（必填）、replacement（默认 [REDACTED]）
+func newRedactPatternHook(name string, options map[string]any) (Hook, error) {
+	re, err := patternOption(options)
+	if err != nil {
+		return nil, err
+	}
+	replacement, ok := options["replacement"].(string)
+	if !ok {
+		replacement = "[REDACTED]"
+	}
+
+	return NewFuncHook(name, func(ctx context.Context, p *Payload) (*Decision, error) {
+		decision := &Decision{Action: ActionMutate}
+		changed := false
+
+		switch p.Stage {
+		case StagePreLLM:
+			messages := make([]providers.Message, len(p.Messages))
+			copy(messages, p.Messages)
+			for i := range messages {
+				if redacted := re.ReplaceAllString(messages[i].Content, replacement); redacted != messages[i].Content {
+					messages[i].Content = redacted
+					changed = true
+				}
+			}
+			decision.Messages = messages
+		case StagePreTool:
+			args, argsChanged := redactValue(p.Arguments, re, replacement)
+			if argsChanged {
+				decision.Arguments = args.(map[string]any)
+				changed = true
+			}
+		case StagePostTool:
+			if redacted := re.ReplaceAllString(p.Result, replacement); redacted != p.Result {
+				decision.Result = &redacted
+				changed = true
+			}
+		default:
+			if redacted := re.ReplaceAllString(p.Content, replacement); redacted != p.Content {
+				decision.Content = &redacted
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil, nil
+		}
+		return decision, nil
+	}), nil
+}
+
+// redactValue 递归替换参数中的字符串值
+func redactValue(value any, re *regexp.Regexp, replacement string) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		redacted := re.ReplaceAllString(v, replacement)
+		return redacted, redacted != v
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		changed := false
+		for key, item := range v {
+			redacted, c := redactValue(item, re, replacement)
+			out[key] = redacted
+			changed = changed || c
+		}
+		return out, changed
+	case []any:
+		out := make([]any, len(v))
+		changed := false
+		for i, item := range v {
+			redacted, c := redactValue(item, re, replacement)
+			out[i] = redacted
+			changed = changed || c
+		}
+		return out, changed
+	default:
+		return value, false
+	}
+}
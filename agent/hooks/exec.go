@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultExecTimeout 外部钩子默认超时
+	DefaultExecTimeout = 10 * time.Second
+
+	// ExecDenyExitCode 外部钩子以该退出码结束时视为拒绝，stderr 作为原因
+	ExecDenyExitCode = 2
+)
+
+// ExecHook 外部可执行文件钩子
+// Payload 以 JSON 写入 stdin，stdout 输出 JSON 格式的 Decision；
+// 输出为空表示放行，退出码 2 表示拒绝，其他非零退出码视为钩子出错
+type ExecHook struct {
+	name    string
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+// NewExecHook 创建外部钩子
+func NewExecHook(name, command string, args []string, timeout time.Duration) *ExecHook {
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	if name == "" {
+		name = command
+	}
+	return &ExecHook{
+		name:    name,
+		command: command,
+		args:    args,
+		timeout: timeout,
+	}
+}
+
+// Name 返回钩子名称
+func (h *ExecHook) Name() string {
+	return h.name
+}
+
+// Run 执行外部命令
+func (h *ExecHook) Run(ctx context.Context, p *Payload) (*Decision, error) {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.command, h.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"GOCLAW_HOOK_STAGE="+string(p.Stage),
+		"GOCLAW_HOOK_NAME="+h.name,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", h.timeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == ExecDenyExitCode {
+			return Deny(strings.TrimSpace(stderr.String())), nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	var decision Decision
+	if err := json.Unmarshal(out, &decision); err != nil {
+		return nil, fmt.Errorf("invalid hook output: %w", err)
+	}
+	return &decision, nil
+}
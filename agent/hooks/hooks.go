@@ -0,0 +1,286 @@
+// Package hooks 提供 Agent 生命周期钩子（护栏）管线
+//
+// 钩子挂在六个阶段上：入站消息（inbound）、LLM 调用前后（pre_llm / post_llm）、
+// 工具执行前后（pre_tool / post_tool）和出站消息（outbound）。每个钩子可以放行、
+// 拒绝或修改当前阶段的数据，可以用 Go 实现，也可以是通过 stdin/stdout 交换 JSON 的外部可执行文件。
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
+	"go.uber.org/zap"
+)
+
+// Stage 钩子阶段
+type Stage string
+
+const (
+	StagePreLLM   Stage = "pre_llm"
+	StagePostLLM  Stage = "post_llm"
+	StagePreTool  Stage = "pre_tool"
+	StagePostTool Stage = "post_tool"
+	StageInbound  Stage = "inbound"
+	StageOutbound Stage = "outbound"
+)
+
+// Stages 所有阶段
+var Stages = []Stage{StagePreLLM, StagePostLLM, StagePreTool, StagePostTool, StageInbound, StageOutbound}
+
+// ValidStage 检查阶段名称是否有效
+func ValidStage(stage string) bool {
+	return slices.Contains(Stages, Stage(stage))
+}
+
+// Action 钩子决定
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionDeny   Action = "deny"
+	ActionMutate Action = "mutate"
+)
+
+// Payload 钩子输入，不同阶段填充不同字段
+type Payload struct {
+	Stage      Stage  `json:"stage"`
+	AgentID    string `json:"agent_id,omitempty"`
+	SessionKey string `json:"session_key,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+	ChatID     string `json:"chat_id,omitempty"`
+	SenderID   string `json:"sender_id,omitempty"`
+
+	// Content 入站/出站消息文本，或 post_llm 阶段的模型回复
+	Content string `json:"content,omitempty"`
+	// Messages pre_llm 阶段即将发送给模型的消息（包含系统提示词）
+	Messages []providers.Message `json:"messages,omitempty"`
+	// ToolCalls post_llm 阶段模型请求的工具调用
+	ToolCalls []providers.ToolCall `json:"tool_calls,omitempty"`
+
+	// 工具阶段
+	ToolName   string         `json:"tool_name,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Result     string         `json:"result,omitempty"`   // post_tool
+	IsError    bool           `json:"is_error,omitempty"` // post_tool
+}
+
+// Decision 钩子输出
+// Action 为 mutate 时，非空字段替换 Payload 中的对应字段
+type Decision struct {
+	Action Action `json:"action"`
+	Reason string `json:"reason,omitempty"`
+
+	Content   *string              `json:"content,omitempty"`
+	Messages  []providers.Message  `json:"messages,omitempty"`
+	ToolCalls []providers.ToolCall `json:"tool_calls,omitempty"` // 空数组表示清除全部工具调用
+	Arguments map[string]any       `json:"arguments,omitempty"`
+	Result    *string              `json:"result,omitempty"`
+}
+
+// Allow 放行
+func Allow() *Decision {
+	return &Decision{Action: ActionAllow}
+}
+
+// Deny 拒绝
+func Deny(reason string) *Decision {
+	return &Decision{Action: ActionDeny, Reason: reason}
+}
+
+// apply 将修改应用到 payload
+func (d *Decision) apply(p *Payload) {
+	if d.Content != nil {
+		p.Content = *d.Content
+	}
+	if d.Messages != nil {
+		p.Messages = d.Messages
+	}
+	if d.ToolCalls != nil {
+		p.ToolCalls = d.ToolCalls
+	}
+	if d.Arguments != nil {
+		p.Arguments = d.Arguments
+	}
+	if d.Result != nil {
+		p.Result = *d.Result
+	}
+}
+
+// Hook 钩子
+type Hook interface {
+	// Name 钩子名称，用于日志和拒绝记录
+	Name() string
+	// Run 检查 payload 并返回决定，返回 nil 表示放行
+	Run(ctx context.Context, p *Payload) (*Decision, error)
+}
+
+// FuncHook 用函数实现的钩子
+type FuncHook struct {
+	name string
+	fn   func(ctx context.Context, p *Payload) (*Decision, error)
+}
+
+// NewFuncHook 创建函数钩子
+func NewFuncHook(name string, fn func(ctx context.Context, p *Payload) (*Decision, error)) *FuncHook {
+	return &FuncHook{name: name, fn: fn}
+}
+
+// Name 返回钩子名称
+func (h *FuncHook) Name() string {
+	return h.name
+}
+
+// Run 执行钩子
+func (h *FuncHook) Run(ctx context.Context, p *Payload) (*Decision, error) {
+	return h.fn(ctx, p)
+}
+
+// Option 注册选项
+type Option func(*entry)
+
+// WithTools 钩子只对指定工具生效（仅工具阶段）
+func WithTools(tools ...string) Option {
+	return func(e *entry) {
+		e.tools = tools
+	}
+}
+
+// WithFailOpen 钩子出错时放行（默认拒绝）
+func WithFailOpen() Option {
+	return func(e *entry) {
+		e.failOpen = true
+	}
+}
+
+// entry 已注册的钩子
+type entry struct {
+	hook     Hook
+	tools    []string
+	failOpen bool
+}
+
+// matches 检查钩子是否适用于 payload
+func (e *entry) matches(p *Payload) bool {
+	if len(e.tools) == 0 || (p.Stage != StagePreTool && p.Stage != StagePostTool) {
+		return true
+	}
+	return slices.Contains(e.tools, p.ToolName)
+}
+
+// Result 钩子管线的执行结果
+type Result struct {
+	Stage   Stage
+	Denied  bool
+	Hook    string // 做出拒绝决定的钩子
+	Reason  string
+	Mutated bool
+}
+
+// Registry 钩子注册表，按注册顺序执行
+// nil 注册表可以安全使用，所有阶段都直接放行
+type Registry struct {
+	mu    sync.RWMutex
+	hooks map[Stage][]*entry
+}
+
+// NewRegistry 创建钩子注册表
+func NewRegistry() *Registry {
+	return &Registry{hooks: make(map[Stage][]*entry)}
+}
+
+// Register 在指定阶段注册钩子
+func (r *Registry) Register(stage Stage, hook Hook, opts ...Option) {
+	e := &entry{hook: hook}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[stage] = append(r.hooks[stage], e)
+}
+
+// Has 检查阶段是否注册了钩子
+func (r *Registry) Has(stage Stage) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.hooks[stage]) > 0
+}
+
+// Run 依次执行 payload 所在阶段的钩子
+// 修改会传递给后续钩子，第一个拒绝决定终止管线；钩子出错时按 fail_open 设置放行或拒绝
+func (r *Registry) Run(ctx context.Context, p *Payload) Result {
+	result := Result{Stage: p.Stage}
+	if r == nil {
+		return result
+	}
+
+	r.mu.RLock()
+	entries := slices.Clone(r.hooks[p.Stage])
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		if !e.matches(p) {
+			continue
+		}
+
+		name := e.hook.Name()
+		decision, err := e.hook.Run(ctx, p)
+		if err == nil && decision != nil {
+			switch decision.Action {
+			case "", ActionAllow, ActionDeny, ActionMutate:
+			default:
+				err = fmt.Errorf("unknown action %q", decision.Action)
+			}
+		}
+		if err != nil {
+			if e.failOpen {
+				logger.Warn("Hook failed, allowing",
+					zap.String("stage", string(p.Stage)),
+					zap.String("hook", name),
+					zap.Error(err))
+				continue
+			}
+			logger.Warn("Hook failed, denying",
+				zap.String("stage", string(p.Stage)),
+				zap.String("hook", name),
+				zap.Error(err))
+			result.Denied, result.Hook, result.Reason = true, name, fmt.Sprintf("hook error: %v", err)
+			return result
+		}
+		if decision == nil {
+			continue
+		}
+
+		switch decision.Action {
+		case ActionDeny:
+			reason := decision.Reason
+			if reason == "" {
+				reason = "denied by policy"
+			}
+			logger.Info("Hook denied",
+				zap.String("stage", string(p.Stage)),
+				zap.String("hook", name),
+				zap.String("tool", p.ToolName),
+				zap.String("reason", reason))
+			result.Denied, result.Hook, result.Reason = true, name, reason
+			return result
+		case ActionMutate:
+			decision.apply(p)
+			result.Mutated = true
+			logger.Debug("Hook mutated payload",
+				zap.String("stage", string(p.Stage)),
+				zap.String("hook", name))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestRegistryChainsMutationsAndStopsOnDeny(t *testing.T) {
+	registry := NewRegistry()
+	var calls []string
+
+	registry.Register(StageInbound, NewFuncHook("upper", func(ctx context.Context, p *Payload) (*Decision, error) {
+		calls = append(calls, "upper")
+		content := p.Content + "!"
+		return &Decision{Action: ActionMutate, Content: &content}, nil
+	}))
+	registry.Register(StageInbound, NewFuncHook("check", func(ctx context.Context, p *Payload) (*Decision, error) {
+		calls = append(calls, "check")
+		if p.Content != "hi!" {
+			t.Errorf("expected mutation from the previous hook, got %q", p.Content)
+		}
+		return Deny("no greetings"), nil
+	}))
+	registry.Register(StageInbound, NewFuncHook("never", func(ctx context.Context, p *Payload) (*Decision, error) {
+		calls = append(calls, "never")
+		return nil, nil
+	}))
+
+	res := registry.Run(context.Background(), &Payload{Stage: StageInbound, Content: "hi"})
+	if !res.Denied || res.Hook != "check" || res.Reason != "no greetings" || res.Stage != StageInbound || !res.Mutated {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(calls) != 2 {
+		t.Errorf("expected the chain to stop at the deny, got %v", calls)
+	}
+
+	// Other stages are unaffected
+	if res := registry.Run(context.Background(), &Payload{Stage: StageOutbound}); res.Denied {
+		t.Error("outbound should not be denied")
+	}
+
+	// A nil registry allows everything
+	var empty *Registry
+	if empty.Has(StageInbound) || empty.Run(context.Background(), &Payload{Stage: StageInbound}).Denied {
+		t.Error("nil registry should allow")
+	}
+}
+
+func TestRegistryErrorsAndToolFilter(t *testing.T) {
+	failing := NewFuncHook("broken", func(ctx context.Context, p *Payload) (*Decision, error) {
+		return nil, errors.New("boom")
+	})
+
+	closed := NewRegistry()
+	closed.Register(StagePreTool, failing)
+	if res := closed.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "shell"}); !res.Denied {
+		t.Error("hook errors should deny by default")
+	}
+
+	open := NewRegistry()
+	open.Register(StagePreTool, failing, WithFailOpen())
+	if res := open.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "shell"}); res.Denied {
+		t.Error("fail-open hook errors should allow")
+	}
+
+	filtered := NewRegistry()
+	filtered.Register(StagePreTool, NewFuncHook("shell_only", func(ctx context.Context, p *Payload) (*Decision, error) {
+		return Deny("no shell"), nil
+	}), WithTools("shell"))
+	if res := filtered.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "read_file"}); res.Denied {
+		t.Error("hook should only apply to the shell tool")
+	}
+	if res := filtered.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "shell"}); !res.Denied {
+		t.Error("hook should deny the shell tool")
+	}
+}
+
+func TestBuiltinHooksFromConfig(t *testing.T) {
+	registry, err := NewRegistryFromConfig([]config.HookConfig{
+		{Stage: "pre_tool", Builtin: "deny_pattern", Tools: []string{"shell"}, Options: map[string]interface{}{"pattern": `rm\s+-rf`}},
+		{Stage: "pre_tool", Builtin: "redact_pattern", Options: map[string]interface{}{"pattern": `sk-[a-z0-9]+`}},
+		{Stage: "outbound", Name: "no-secrets", Builtin: "redact_pattern", Options: map[string]interface{}{"pattern": `sk-[a-z0-9]+`, "replacement": "***"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := registry.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "shell", Arguments: map[string]any{"command": "rm -rf /"}})
+	if !res.Denied || res.Hook != "deny_pattern" {
+		t.Errorf("expected deny_pattern to deny, got %+v", res)
+	}
+
+	payload := &Payload{Stage: StagePreTool, ToolName: "web", Arguments: map[string]any{"headers": map[string]any{"auth": "sk-abc123"}}}
+	if res := registry.Run(context.Background(), payload); res.Denied || !res.Mutated {
+		t.Fatalf("expected redaction, got %+v", res)
+	}
+	if got := payload.Arguments["headers"].(map[string]any)["auth"]; got != "[REDACTED]" {
+		t.Errorf("unexpected redacted argument %v", got)
+	}
+
+	payload = &Payload{Stage: StageOutbound, Content: "key is sk-abc123"}
+	registry.Run(context.Background(), payload)
+	if payload.Content != "key is ***" {
+		t.Errorf("unexpected redacted content %q", payload.Content)
+	}
+
+	if _, err := NewRegistryFromConfig([]config.HookConfig{{Stage: "pre_tool", Builtin: "missing"}}); err == nil {
+		t.Error("expected an error for an unknown builtin")
+	}
+	if _, err := NewRegistryFromConfig([]config.HookConfig{{Stage: "later", Builtin: "deny_pattern"}}); err == nil {
+		t.Error("expected an error for an unknown stage")
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	body := `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"tool_name":"shell"'*) echo "shell is disabled" >&2; exit 2 ;;
+  *'"stage":"outbound"'*) echo '{"action": "mutate", "content": "scrubbed"}' ;;
+  *'"stage":"post_tool"'*) exit 1 ;;
+esac
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := NewExecHook("script", script, nil, 0)
+
+	decision, err := hook.Run(context.Background(), &Payload{Stage: StagePreTool, ToolName: "shell"})
+	if err != nil || decision.Action != ActionDeny || decision.Reason != "shell is disabled" {
+		t.Errorf("unexpected deny decision %+v, %v", decision, err)
+	}
+
+	decision, err = hook.Run(context.Background(), &Payload{Stage: StageOutbound, Content: "secret"})
+	if err != nil || decision.Action != ActionMutate || *decision.Content != "scrubbed" {
+		t.Errorf("unexpected mutate decision %+v, %v", decision, err)
+	}
+
+	decision, err = hook.Run(context.Background(), &Payload{Stage: StageInbound, Content: "hello"})
+	if err != nil || decision != nil {
+		t.Errorf("empty output should allow, got %+v, %v", decision, err)
+	}
+
+	if _, err := hook.Run(context.Background(), &Payload{Stage: StagePostTool}); err == nil {
+		t.Error("expected an error for a failing script")
+	}
+}
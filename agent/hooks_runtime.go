@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/hooks"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// HookDenialMetadataKey is the message metadata key recording a hook denial
+const HookDenialMetadataKey = "hook_denial"
+
+// hookDenial describes a denial for message metadata
+func hookDenial(res hooks.Result) map[string]any {
+	return map[string]any{
+		"stage":  string(res.Stage),
+		"hook":   res.Hook,
+		"reason": res.Reason,
+	}
+}
+
+// hookDeniedMessage builds the assistant message that replaces a denied LLM turn
+func hookDeniedMessage(res hooks.Result) AgentMessage {
+	return AgentMessage{
+		Role:      RoleAssistant,
+		Content:   []ContentBlock{TextContent{Text: fmt.Sprintf("Request blocked by policy: %s", res.Reason)}},
+		Timestamp: time.Now().UnixMilli(),
+		Metadata: map[string]any{
+			"stop_reason":         "hook_denied",
+			HookDenialMetadataKey: hookDenial(res),
+		},
+	}
+}
+
+// hookPayload creates a payload carrying the agent and session identity
+func (o *Orchestrator) hookPayload(ctx context.Context, state *AgentState, stage hooks.Stage) *hooks.Payload {
+	payload := &hooks.Payload{Stage: stage, SessionKey: state.SessionKey}
+	if agentID, ok := ctx.Value(AgentIDContextKey).(string); ok {
+		payload.AgentID = agentID
+	}
+	return payload
+}
+
+// runPreLLMHooks lets hooks rewrite or veto the messages about to be sent to the LLM.
+// It returns the (possibly rewritten) messages, or a denial.
+func (o *Orchestrator) runPreLLMHooks(ctx context.Context, state *AgentState, messages []providers.Message) ([]providers.Message, *hooks.Result) {
+	if !o.config.Hooks.Has(hooks.StagePreLLM) {
+		return messages, nil
+	}
+
+	payload := o.hookPayload(ctx, state, hooks.StagePreLLM)
+	payload.Messages = messages
+	res := o.config.Hooks.Run(ctx, payload)
+	if res.Denied {
+		return nil, &res
+	}
+	return payload.Messages, nil
+}
+
+// runPostLLMHooks lets hooks scrub the assistant response and its tool calls
+func (o *Orchestrator) runPostLLMHooks(ctx context.Context, state *AgentState, msg AgentMessage) AgentMessage {
+	if !o.config.Hooks.Has(hooks.StagePostLLM) {
+		return msg
+	}
+
+	payload := o.hookPayload(ctx, state, hooks.StagePostLLM)
+	payload.Content = extractTextContent(msg)
+	for _, tc := range extractToolCalls(msg) {
+		payload.ToolCalls = append(payload.ToolCalls, providers.ToolCall{
+			ID:     tc.ID,
+			Name:   tc.Name,
+			Params: tc.Arguments,
+		})
+	}
+
+	res := o.config.Hooks.Run(ctx, payload)
+	if res.Denied {
+		return hookDeniedMessage(res)
+	}
+	if !res.Mutated {
+		return msg
+	}
+
+	// Rebuild the message, keeping thinking blocks in front
+	var content []ContentBlock
+	for _, block := range msg.Content {
+		if thinking, ok := block.(ThinkingContent); ok {
+			content = append(content, thinking)
+		}
+	}
+	content = append(content, TextContent{Text: payload.Content})
+	for _, tc := range payload.ToolCalls {
+		content = append(content, ToolCallContent{
+			ID:        tc.ID,
+			Name:      tc.Name,
+			Arguments: convertInterfaceToAny(tc.Params),
+		})
+	}
+	msg.Content = content
+	return msg
+}
+
+// runPreToolHooks lets hooks veto a tool call or rewrite its arguments in place
+func (o *Orchestrator) runPreToolHooks(ctx context.Context, state *AgentState, tc *ToolCallContent) *hooks.Result {
+	if !o.config.Hooks.Has(hooks.StagePreTool) {
+		return nil
+	}
+
+	payload := o.hookPayload(ctx, state, hooks.StagePreTool)
+	payload.ToolName = tc.Name
+	payload.ToolCallID = tc.ID
+	payload.Arguments = tc.Arguments
+
+	res := o.config.Hooks.Run(ctx, payload)
+	if res.Denied {
+		return &res
+	}
+	if res.Mutated {
+		tc.Arguments = payload.Arguments
+	}
+	return nil
+}
+
+// runPostToolHooks lets hooks rewrite or withhold a tool result
+func (o *Orchestrator) runPostToolHooks(ctx context.Context, state *AgentState, tc ToolCallContent, result *ToolResult, err *error) *hooks.Result {
+	if !o.config.Hooks.Has(hooks.StagePostTool) {
+		return nil
+	}
+
+	payload := o.hookPayload(ctx, state, hooks.StagePostTool)
+	payload.ToolName = tc.Name
+	payload.ToolCallID = tc.ID
+	payload.Arguments = tc.Arguments
+	if *err != nil {
+		payload.Result = (*err).Error()
+		payload.IsError = true
+	} else {
+		payload.Result = extractToolResultContent(result.Content)
+	}
+
+	res := o.config.Hooks.Run(ctx, payload)
+	if res.Denied {
+		*err = fmt.Errorf("tool result withheld by hook %s: %s", res.Hook, res.Reason)
+		*result = ToolResult{
+			Content: []ContentBlock{TextContent{Text: (*err).Error()}},
+			Details: map[string]any{"error": (*err).Error()},
+		}
+		return &res
+	}
+	if res.Mutated {
+		if *err != nil {
+			*err = errors.New(payload.Result)
+		} else {
+			result.Content = []ContentBlock{TextContent{Text: payload.Result}}
+		}
+	}
+	return nil
+}
+
+// channelHookPayload creates an inbound or outbound payload for a channel message
+func channelHookPayload(stage hooks.Stage, agentID, sessionKey string, msg *bus.InboundMessage, content string) *hooks.Payload {
+	return &hooks.Payload{
+		Stage:      stage,
+		AgentID:    agentID,
+		SessionKey: sessionKey,
+		Channel:    msg.Channel,
+		ChatID:     msg.ChatID,
+		SenderID:   msg.SenderID,
+		Content:    content,
+	}
+}
+
+// runMessageHooks runs inbound or outbound hooks on a channel message.
+// It returns the (possibly rewritten) content and false when the message was denied;
+// denials are recorded in the session.
+func runMessageHooks(ctx context.Context, registry *hooks.Registry, sessionMgr *session.Manager, sess *session.Session, payload *hooks.Payload) (string, bool) {
+	if !registry.Has(payload.Stage) {
+		return payload.Content, true
+	}
+
+	res := registry.Run(ctx, payload)
+	if !res.Denied {
+		return payload.Content, true
+	}
+
+	if sess != nil && sessionMgr != nil {
+		recordHookDenial(sessionMgr, sess, res)
+	}
+	return "", false
+}
+
+// recordHookDenial appends a system message describing the denial to the session
+func recordHookDenial(sessionMgr *session.Manager, sess *session.Session, res hooks.Result) {
+	sess.AddMessage(session.Message{
+		Role:      string(RoleSystem),
+		Content:   fmt.Sprintf("[%s hook %s denied] %s", res.Stage, res.Hook, res.Reason),
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{HookDenialMetadataKey: hookDenial(res)},
+	})
+	if err := sessionMgr.Save(sess); err != nil {
+		logger.Error("Failed to save hook denial", zap.String("session", sess.Key), zap.Error(err))
+	}
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/smallnest/goclaw/acp"
 	acpruntime "github.com/smallnest/goclaw/acp/runtime"
+	"github.com/smallnest/goclaw/agent/hooks"
 	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/channels"
@@ -18,6 +20,7 @@ import (
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
+	"github.com/smallnest/goclaw/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -45,6 +48,7 @@ type AgentManager struct {
 	// 分身支持
 	subagentRegistry  *SubagentRegistry
 	subagentAnnouncer *SubagentAnnouncer
+	subagentParents   sync.Map // runID -> 生成分身的父运行上下文
 	dataDir           string
 }
 
@@ -99,6 +103,17 @@ func NewAgentManager(cfg *NewAgentManagerConfig) *AgentManager {
 // handleSubagentCompletion 处理分身完成事件
 func (m *AgentManager) handleSubagentCompletion(runID string, record *SubagentRunRecord) {
 
+	// 父运行仍在进行时，等它结束再决定是否宣告，以便本轮通过 subagent_wait 收集结果
+	if parent, ok := m.subagentParents.LoadAndDelete(runID); ok {
+		<-parent.(context.Context).Done()
+	}
+
+	// 结果已经由 subagent_wait 交给请求者，不再宣告
+	if record.Outcome != nil && m.subagentRegistry.IsDelivered(runID) {
+		m.subagentRegistry.Cleanup(runID, record.Cleanup, true)
+		return
+	}
+
 	// 启动宣告流程
 	if record.Outcome != nil {
 		announceParams := &SubagentAnnounceParams{
@@ -137,7 +152,10 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 
 	logger.Info("Setting up agents from config")
 
-	// 1. 创建 Agent 实例
+	// 1. 设置分身支持（Agent 创建时会读取工具列表，需要先注册分身工具）
+	m.setupSubagentSupport(cfg, contextBuilder)
+
+	// 2. 创建 Agent 实例
 	for _, agentCfg := range cfg.Agents.List {
 		if err := m.createAgent(agentCfg, contextBuilder, cfg); err != nil {
 			logger.Error("Failed to create agent",
@@ -147,7 +165,7 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 		}
 	}
 
-	// 2. 如果没有配置 Agent，创建默认 Agent
+	// 3. 如果没有配置 Agent，创建默认 Agent
 	if len(m.agents) == 0 {
 		logger.Info("No agents configured, creating default agent")
 		defaultAgentCfg := config.AgentConfig{
@@ -162,7 +180,7 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 		}
 	}
 
-	// 3. 设置绑定
+	// 4. 设置绑定
 	for _, binding := range cfg.Bindings {
 		if err := m.setupBinding(binding); err != nil {
 			logger.Error("Failed to setup binding",
@@ -173,9 +191,6 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 		}
 	}
 
-	// 4. 设置分身支持
-	m.setupSubagentSupport(cfg, contextBuilder)
-
 	logger.Info("Agent manager setup complete",
 		zap.Int("agents", len(m.agents)),
 		zap.Int("bindings", len(m.bindings)))
@@ -190,6 +205,13 @@ func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *
 		logger.Warn("Failed to load subagent registry", zap.Error(err))
 	}
 
+	// 每个请求者会话的并发分身上限
+	maxConcurrent := defaultSubagentMaxConcurrent
+	if cfg.Agents.Defaults.Subagents != nil && cfg.Agents.Defaults.Subagents.MaxConcurrent > 0 {
+		maxConcurrent = cfg.Agents.Defaults.Subagents.MaxConcurrent
+	}
+	m.subagentRegistry.SetMaxConcurrentPerRequester(maxConcurrent)
+
 	// 设置分身运行完成回调
 	m.subagentRegistry.SetOnRunComplete(func(runID string, record *SubagentRunRecord) {
 		m.handleSubagentCompletion(runID, record)
@@ -230,13 +252,19 @@ func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *
 		}
 		return agentID
 	})
-	spawnTool.SetOnSpawn(func(result *tools.SubagentSpawnResult) error {
-		return m.handleSubagentSpawn(result)
+	spawnTool.SetOnSpawn(func(ctx context.Context, result *tools.SubagentSpawnResult) error {
+		return m.handleSubagentSpawn(ctx, result)
 	})
 
 	// 注册工具
-	if err := m.tools.RegisterExisting(spawnTool); err != nil {
-		logger.Error("Failed to register sessions_spawn tool", zap.Error(err))
+	for _, tool := range []tools.Tool{
+		spawnTool,
+		tools.NewSubagentWaitTool(registryAdapter),
+		tools.NewSubagentStatusTool(registryAdapter),
+	} {
+		if err := m.tools.RegisterExisting(tool); err != nil {
+			logger.Error("Failed to register subagent tool", zap.String("tool", tool.Name()), zap.Error(err))
+		}
 	}
 
 	logger.Info("Subagent support configured")
@@ -270,47 +298,125 @@ func (a *subagentRegistryAdapter) RegisterRun(params *tools.SubagentRunParams) e
 		Cleanup:             params.Cleanup,
 		Label:               params.Label,
 		ArchiveAfterMinutes: params.ArchiveAfterMinutes,
+		TimeoutSeconds:      params.TimeoutSeconds,
 	})
 }
 
-// handleSubagentSpawn 处理分身生成
-func (m *AgentManager) handleSubagentSpawn(result *tools.SubagentSpawnResult) error {
+// ListRuns 列出请求者会话的分身运行
+func (a *subagentRegistryAdapter) ListRuns(requesterSessionKey string) []tools.SubagentRunStatus {
+	return subagentRunStatuses(a.registry.SnapshotForRequester(requesterSessionKey))
+}
+
+// WaitRuns 等待请求者会话的分身运行结束
+func (a *subagentRegistryAdapter) WaitRuns(ctx context.Context, requesterSessionKey string, runIDs []string, any bool) ([]tools.SubagentRunStatus, error) {
+	records, err := a.registry.Wait(ctx, requesterSessionKey, runIDs, any)
+	return subagentRunStatuses(records), err
+}
+
+// subagentRunStatuses 将运行记录转换为工具返回的状态
+func subagentRunStatuses(records []SubagentRunRecord) []tools.SubagentRunStatus {
+	now := time.Now().UnixMilli()
+	statuses := make([]tools.SubagentRunStatus, 0, len(records))
+	for _, record := range records {
+		status := tools.SubagentRunStatus{
+			RunID:           record.RunID,
+			ChildSessionKey: record.ChildSessionKey,
+			Label:           record.Label,
+			Task:            record.Task,
+			Status:          "running",
+			Delivered:       record.Delivered,
+		}
+		if record.Outcome != nil {
+			status.Status = record.Outcome.Status
+			status.Error = record.Outcome.Error
+			status.Output = record.Outcome.Output
+		}
+		end := now
+		if record.EndedAt != nil {
+			end = *record.EndedAt
+		}
+		if record.StartedAt != nil {
+			status.ElapsedSeconds = float64(end-*record.StartedAt) / 1000
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// handleSubagentSpawn 处理分身生成，在后台运行分身
+// 分身运行不受生成它的工具调用和父运行正常结束的影响，但父运行被取消时会一并取消。
+func (m *AgentManager) handleSubagentSpawn(ctx context.Context, result *tools.SubagentSpawnResult) error {
 	// 解析子会话密钥
 	agentID, subagentID, isSubagent := ParseAgentSessionKey(result.ChildSessionKey)
 	if !isSubagent {
 		return fmt.Errorf("invalid subagent session key: %s", result.ChildSessionKey)
 	}
 
-	// Get the agent to use for this subagent
-	var agent *Agent
-	if agentID != "" {
-		var ok bool
-		agent, ok = m.GetAgent(agentID)
-		if !ok {
-			agent = m.GetDefaultAgent()
-		}
-	} else {
-		agent = m.GetDefaultAgent()
+	// 目标 Agent 不存在时使用默认 Agent
+	if _, ok := m.GetAgent(agentID); !ok {
+		agentID = ""
 	}
-
-	if agent == nil {
+	if agentID == "" && m.GetDefaultAgent() == nil {
 		return fmt.Errorf("no agent available for subagent: %s", result.ChildSessionKey)
 	}
 
-	// Set the system prompt if provided
-	if result.ChildSystemPrompt != "" {
-		// For subagent, we need to pass this through context
-		// This will be used when the subagent processes messages
-		logger.Debug("Subagent system prompt set",
-			zap.String("run_id", result.RunID),
-			zap.String("subagent_id", subagentID),
-			zap.Int("prompt_length", len(result.ChildSystemPrompt)))
+	record, ok := m.subagentRegistry.GetRun(result.RunID)
+	if !ok {
+		return fmt.Errorf("subagent run not found: %s", result.RunID)
+	}
+	timeout := time.Duration(record.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	stop := func() bool { return true }
+	if parent, ok := runContext(ctx); ok {
+		m.subagentParents.Store(result.RunID, parent)
+		stop = context.AfterFunc(parent, func() {
+			if !errors.Is(context.Cause(parent), errRunFinished) {
+				cancel()
+			}
+		})
 	}
 
 	logger.Debug("Subagent spawn handled",
 		zap.String("run_id", result.RunID),
 		zap.String("subagent_id", subagentID),
-		zap.String("child_session_key", result.ChildSessionKey))
+		zap.String("child_session_key", result.ChildSessionKey),
+		zap.Duration("timeout", timeout))
+
+	go func() {
+		defer cancel()
+		defer stop()
+
+		chat, err := m.Chat(runCtx, &ChatRequest{
+			AgentID:           agentID,
+			SessionKey:        result.ChildSessionKey,
+			Message:           AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: record.Task}}, Timestamp: time.Now().UnixMilli()},
+			Channel:           "subagent",
+			ExtraSystemPrompt: result.ChildSystemPrompt,
+		})
+
+		outcome := &SubagentRunOutcome{Status: "ok"}
+		switch {
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			outcome = &SubagentRunOutcome{Status: "timeout", Error: fmt.Sprintf("subagent timed out after %s", timeout)}
+		case runCtx.Err() != nil:
+			outcome = &SubagentRunOutcome{Status: "cancelled", Error: "parent run was cancelled"}
+		case err != nil:
+			outcome = &SubagentRunOutcome{Status: "error", Error: err.Error()}
+		default:
+			outcome.Output = extractTextContent(chat.Reply)
+		}
+
+		endedAt := time.Now().UnixMilli()
+		if err := m.subagentRegistry.MarkCompleted(result.RunID, outcome, &endedAt); err != nil {
+			logger.Error("Failed to record subagent outcome",
+				zap.String("run_id", result.RunID),
+				zap.Error(err))
+		}
+	}()
 
 	return nil
 }
@@ -374,6 +480,18 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		maxHistoryMessages = 100
 	}
 
+	// 获取扩展思考级别
+	thinkingLevel := cfg.Thinking
+	if thinkingLevel == "" {
+		thinkingLevel = globalCfg.Agents.Defaults.Thinking
+	}
+
+	// 创建生命周期钩子
+	hookRegistry, err := hooks.NewRegistryFromConfig(cfg.Hooks)
+	if err != nil {
+		return fmt.Errorf("failed to create hooks for agent %s: %w", cfg.ID, err)
+	}
+
 	// 创建 Agent
 	agent, err := NewAgent(&NewAgentConfig{
 		Bus:                m.bus,
@@ -385,6 +503,8 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		MaxIteration:       maxIterations,
 		MaxHistoryMessages: maxHistoryMessages,
 		SkillsLoader:       m.skillsLoader,
+		ThinkingLevel:      thinkingLevel,
+		Hooks:              hookRegistry,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create agent %s: %w", cfg.ID, err)
@@ -450,23 +570,40 @@ func (m *AgentManager) RouteInbound(ctx context.Context, msg *bus.InboundMessage
 	// 查找绑定的 Agent
 	entry, ok := m.bindings[bindingKey]
 	var agent *Agent
+	var agentID string
 	if ok {
 		agent = entry.Agent
+		agentID = entry.AgentID
 		logger.Debug("Message routed by binding",
 			zap.String("binding_key", bindingKey),
 			zap.String("agent_id", entry.AgentID))
 	} else if m.defaultAgent != nil {
 		// 使用默认 Agent
 		agent = m.defaultAgent
+		agentID = m.agentIDLocked(agent)
 		logger.Debug("Message routed to default agent",
 			zap.String("channel", msg.Channel),
 			zap.String("account_id", msg.AccountID))
 	} else {
 		return fmt.Errorf("no agent found for message: %s", bindingKey)
 	}
+	ctx = context.WithValue(ctx, AgentIDContextKey, agentID)
+
+	// 处理消息，span 覆盖从入站到回复的整个过程
+	ctx, span := startInboundSpan(ctx, msg, agentID)
+	err := m.handleInboundMessage(ctx, msg, agent)
+	telemetry.EndSpan(span, err)
+	return err
+}
 
-	// 处理消息
-	return m.handleInboundMessage(ctx, msg, agent)
+// agentIDLocked 查找 Agent 实例对应的 ID（调用方需持有锁）
+func (m *AgentManager) agentIDLocked(agent *Agent) string {
+	for id, a := range m.agents {
+		if a == agent {
+			return id
+		}
+	}
+	return ""
 }
 
 // handleInboundMessage 处理入站消息
@@ -508,10 +645,17 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 		return err
 	}
 
+	// 入站钩子可以拦截或改写消息
+	agentID, _ := ctx.Value(AgentIDContextKey).(string)
+	content, ok := runMessageHooks(ctx, agent.hooks, m.sessionMgr, sess, channelHookPayload(hooks.StageInbound, agentID, sessionKey, msg, msg.Content))
+	if !ok {
+		return nil
+	}
+
 	// 转换为 Agent 消息
 	agentMsg := AgentMessage{
 		Role:      RoleUser,
-		Content:   []ContentBlock{TextContent{Text: msg.Content}},
+		Content:   []ContentBlock{TextContent{Text: content}},
 		Timestamp: msg.Timestamp.UnixMilli(),
 	}
 
@@ -540,7 +684,8 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 	historyAgentMsgs := sessionMessagesToAgentMessages(history)
 	allMessages := append(historyAgentMsgs, agentMsg)
 
-	// 执行 Agent
+	// 执行 Agent（会话键通过 context 传递给工具）
+	ctx = context.WithValue(ctx, SessionKeyContextKey, sessionKey)
 	logger.Info("[Manager] Starting agent execution",
 		zap.String("message_id", msg.ID),
 		zap.Int("history_count", len(history)),
@@ -582,7 +727,7 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 				if len(finalMessages) > 0 {
 					lastMsg := finalMessages[len(finalMessages)-1]
 					if lastMsg.Role == RoleAssistant {
-						m.publishToBus(ctx, msg.Channel, msg.ChatID, lastMsg, msg.ID)
+						m.publishReply(ctx, agent, sess, msg, lastMsg)
 					}
 				}
 				return nil
@@ -599,7 +744,7 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 	if len(finalMessages) > 0 {
 		lastMsg := finalMessages[len(finalMessages)-1]
 		if lastMsg.Role == RoleAssistant {
-			m.publishToBus(ctx, msg.Channel, msg.ChatID, lastMsg, msg.ID)
+			m.publishReply(ctx, agent, sess, msg, lastMsg)
 		}
 	}
 
@@ -833,6 +978,17 @@ func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMess
 	_ = m.helper.UpdateSession(sess, newMessages, &UpdateSessionOptions{SaveImmediately: true})
 }
 
+// publishReply 运行出站钩子后将回复发布到总线，被拒绝的回复不会发送
+func (m *AgentManager) publishReply(ctx context.Context, agent *Agent, sess *session.Session, msg *bus.InboundMessage, reply AgentMessage) {
+	agentID, _ := ctx.Value(AgentIDContextKey).(string)
+	content, ok := runMessageHooks(ctx, agent.hooks, m.sessionMgr, sess, channelHookPayload(hooks.StageOutbound, agentID, sess.Key, msg, extractTextContent(reply)))
+	if !ok {
+		return
+	}
+	reply.Content = []ContentBlock{TextContent{Text: content}}
+	m.publishToBus(ctx, msg.Channel, msg.ChatID, reply, msg.ID)
+}
+
 // publishToBus 发布消息到总线
 func (m *AgentManager) publishToBus(ctx context.Context, channel, chatID string, msg AgentMessage, replyTo string) {
 	content := extractTextContent(msg)
@@ -2,12 +2,15 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -17,8 +20,23 @@ type contextKey string
 const (
 	SessionKeyContextKey contextKey = "session_key"
 	AgentIDContextKey    contextKey = "agent_id"
+	runContextKey        contextKey = "run"
 )
 
+// timedToolGrace is the extra time a TimedTool gets beyond its own limit to build its result
+const timedToolGrace = 30 * time.Second
+
+// errRunFinished is the cancel cause of a run that ended on its own, as opposed
+// to one that was stopped or whose caller went away
+var errRunFinished = errors.New("agent run finished")
+
+// runContext returns the context of the orchestrator run that ctx was derived
+// from, so work started by a tool can outlive the tool call but not the run
+func runContext(ctx context.Context) (context.Context, bool) {
+	runCtx, ok := ctx.Value(runContextKey).(context.Context)
+	return runCtx, ok
+}
+
 // toolResultPair is used to pass tool execution results from goroutines
 type toolResultPair struct {
 	result *ToolResult
@@ -35,7 +53,7 @@ type Orchestrator struct {
 	config     *LoopConfig
 	state      *AgentState // Initial state, used as template for each Run
 	eventChan  chan *Event
-	cancelFunc context.CancelFunc
+	cancelFunc context.CancelCauseFunc
 }
 
 // NewOrchestrator creates a new agent orchestrator
@@ -47,25 +65,44 @@ func NewOrchestrator(config *LoopConfig, initialState *AgentState) *Orchestrator
 	}
 }
 
+// Fork creates an orchestrator that shares the configuration and state template
+// but has its own event channel, so a caller can observe the events of a single run.
+// Steering and follow-up queues are not shared with the original.
+func (o *Orchestrator) Fork() *Orchestrator {
+	cfg := *o.config
+	noMessages := func() ([]AgentMessage, error) { return nil, nil }
+	cfg.GetSteeringMessages = noMessages
+	cfg.GetFollowUpMessages = noMessages
+	return NewOrchestrator(&cfg, o.state)
+}
+
 // Run starts the agent loop with initial prompts
 func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage) ([]AgentMessage, error) {
 	logger.Debug("=== Orchestrator Run Start ===",
 		zap.Int("prompts_count", len(prompts)))
 
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
 	o.cancelFunc = cancel
+	ctx = context.WithValue(ctx, runContextKey, ctx)
 
 	// Initialize state with prompts
 	newMessages := make([]AgentMessage, len(prompts))
 	copy(newMessages, prompts)
 	currentState := o.state.Clone()
 	currentState.AddMessages(newMessages)
+	currentState.Skills = o.skillsSnapshot()
+	if sessionKey, ok := ctx.Value(SessionKeyContextKey).(string); ok && sessionKey != "" {
+		currentState.SessionKey = sessionKey
+	}
+
+	ctx, span := o.startRunSpan(ctx, currentState)
 
 	// Emit start event
 	o.emit(NewEvent(EventAgentStart))
 
 	// Main loop
 	finalMessages, err := o.runLoop(ctx, currentState)
+	telemetry.EndSpan(span, err)
 
 	logger.Debug("=== Orchestrator Run End ===",
 		zap.Int("final_messages_count", len(finalMessages)),
@@ -78,7 +115,7 @@ func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage) ([]Agent
 	}
 	o.emit(endEvent)
 
-	cancel()
+	cancel(errRunFinished)
 	if err != nil {
 		return nil, fmt.Errorf("agent loop failed: %w", err)
 	}
@@ -86,6 +123,15 @@ func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage) ([]Agent
 	return finalMessages, nil
 }
 
+// skillsSnapshot returns the skills a new run works with. A reload while the
+// run is in flight does not change the snapshot.
+func (o *Orchestrator) skillsSnapshot() []*Skill {
+	if o.config.SkillsSource != nil {
+		return o.config.SkillsSource()
+	}
+	return o.config.Skills
+}
+
 // runLoop implements the main agent loop logic
 func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentMessage, error) {
 	firstTurn := true
@@ -249,16 +295,24 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 		skillsContent := ""
 		if len(state.LoadedSkills) > 0 {
 			// Second phase: inject full content of loaded skills
-			skillsContent = o.config.ContextBuilder.buildSelectedSkills(state.LoadedSkills, o.config.Skills)
-		} else if len(o.config.Skills) > 0 {
+			skillsContent = o.config.ContextBuilder.buildSelectedSkills(state.LoadedSkills, state.Skills)
+		} else if len(state.Skills) > 0 {
 			// First phase: inject skill summary (available skills list)
-			skillsContent = o.config.ContextBuilder.buildSkillsPrompt(o.config.Skills, PromptModeFull)
+			skillsContent = o.config.ContextBuilder.buildSkillsPrompt(state.Skills, PromptModeFull)
 		}
-		systemPrompt := o.config.ContextBuilder.buildSystemPromptWithSkills(skillsContent, PromptModeFull)
+		// The stable prefix is a cache breakpoint, the dynamic tail (current time) follows it
+		stable, dynamic := o.config.ContextBuilder.buildSystemPromptBlocks(skillsContent, PromptModeFull)
 		fullMessages = append(fullMessages, providers.Message{
-			Role:    "system",
-			Content: systemPrompt,
+			Role:            "system",
+			Content:         stable,
+			CacheBreakpoint: true,
 		})
+		if dynamic != "" {
+			fullMessages = append(fullMessages, providers.Message{
+				Role:    "system",
+				Content: dynamic,
+			})
+		}
 	} else if state.SystemPrompt != "" {
 		// Fallback to stored system prompt
 		fullMessages = append(fullMessages, providers.Message{
@@ -266,20 +320,47 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 			Content: state.SystemPrompt,
 		})
 	}
+	if o.config.ExtraSystemPrompt != "" {
+		fullMessages = append(fullMessages, providers.Message{
+			Role:    "system",
+			Content: o.config.ExtraSystemPrompt,
+		})
+	}
 	fullMessages = append(fullMessages, providerMsgs...)
 
+	fullMessages, denial := o.runPreLLMHooks(ctx, state, fullMessages)
+	if denial != nil {
+		o.emit(NewEvent(EventMessageEnd))
+		return hookDeniedMessage(*denial), nil
+	}
+
 	logger.Info("=== Calling LLM ===",
 		zap.Int("messages_count", len(fullMessages)),
 		zap.Int("tools_count", len(toolDefs)),
 		zap.Bool("has_loaded_skills", len(state.LoadedSkills) > 0))
 
+	chatOpts := chatOptions(state)
+	start := time.Now()
+
 	// Try streaming if provider supports it
 	if sp, ok := o.config.Provider.(providers.StreamingProvider); ok {
-		return o.callWithStreaming(ctx, sp, fullMessages, toolDefs)
+		callCtx, span := o.startProviderSpan(ctx, len(fullMessages), len(toolDefs), true)
+		assistantMsg, err := o.callWithStreaming(callCtx, sp, fullMessages, toolDefs, chatOpts)
+		o.endProviderSpan(span, start, nil, err)
+		if err != nil {
+			return AgentMessage{}, err
+		}
+		return o.runPostLLMHooks(ctx, state, assistantMsg), nil
 	}
 
 	// Fallback to non-streaming
-	response, err := o.config.Provider.Chat(ctx, fullMessages, toolDefs)
+	callCtx, span := o.startProviderSpan(ctx, len(fullMessages), len(toolDefs), false)
+	response, err := o.config.Provider.Chat(callCtx, fullMessages, toolDefs, chatOpts...)
+	var usage *providers.Usage
+	if response != nil {
+		usage = &response.Usage
+	}
+	o.endProviderSpan(span, start, usage, err)
 	if err != nil {
 		logger.Error("LLM call failed", zap.Error(err))
 		return AgentMessage{}, fmt.Errorf("LLM call failed: %w", err)
@@ -288,7 +369,18 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 	logger.Info("=== LLM Response Received ===",
 		zap.Int("content_length", len(response.Content)),
 		zap.Int("tool_calls_count", len(response.ToolCalls)),
-		zap.String("content_preview", truncateString(response.Content, 200)))
+		zap.String("content_preview", truncateString(response.Content, 200)),
+		zap.Int("cache_read_tokens", response.Usage.CacheReadInputTokens),
+		zap.Int("cache_creation_tokens", response.Usage.CacheCreationInputTokens))
+
+	// Surface extended thinking as a distinct event
+	if thinking := response.ThinkingText(); thinking != "" {
+		o.emit(&Event{
+			Type:      EventThinking,
+			Thinking:  thinking,
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
 
 	// Emit message end
 	o.emit(NewEvent(EventMessageEnd))
@@ -300,11 +392,11 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 		zap.Bool("has_tool_calls", len(response.ToolCalls) > 0),
 		zap.Int("tool_calls_count", len(response.ToolCalls)))
 
-	return assistantMsg, nil
+	return o.runPostLLMHooks(ctx, state, assistantMsg), nil
 }
 
 // callWithStreaming calls the LLM with streaming support
-func (o *Orchestrator) callWithStreaming(ctx context.Context, sp providers.StreamingProvider, messages []providers.Message, tools []providers.ToolDefinition) (AgentMessage, error) {
+func (o *Orchestrator) callWithStreaming(ctx context.Context, sp providers.StreamingProvider, messages []providers.Message, tools []providers.ToolDefinition, opts []providers.ChatOption) (AgentMessage, error) {
 	var contentBuilder, thinkingBuilder, finalBuilder strings.Builder
 	var toolCalls []providers.ToolCall
 	var streamErr error
@@ -318,6 +410,12 @@ func (o *Orchestrator) callWithStreaming(ctx context.Context, sp providers.Strea
 		// Handle different chunk types
 		if chunk.ToolCall != nil {
 			toolCalls = append(toolCalls, *chunk.ToolCall)
+			o.emit(&Event{
+				Type:      EventStreamToolCall,
+				ToolID:    chunk.ToolCall.ID,
+				ToolName:  chunk.ToolCall.Name,
+				Timestamp: time.Now().UnixMilli(),
+			})
 		} else if chunk.IsThinking {
 			thinkingBuilder.WriteString(chunk.Content)
 			// Emit thinking stream event
@@ -351,7 +449,7 @@ func (o *Orchestrator) callWithStreaming(ctx context.Context, sp providers.Strea
 				Timestamp: time.Now().UnixMilli(),
 			})
 		}
-	})
+	}, opts...)
 
 	if err != nil {
 		logger.Error("LLM streaming call failed", zap.Error(err))
@@ -404,6 +502,11 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 	logger.Info("=== Execute Tool Calls Start ===",
 		zap.Int("count", len(toolCalls)))
 	for _, tc := range toolCalls {
+		toolSpanCtx, span := startToolSpan(ctx, tc)
+
+		// Hooks may veto the call or rewrite its arguments
+		denial := o.runPreToolHooks(ctx, state, &tc)
+
 		logger.Info("Tool call start",
 			zap.String("tool_id", tc.ID),
 			zap.String("tool_name", tc.Name),
@@ -424,7 +527,13 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 		var result ToolResult
 		var err error
 
-		if tool == nil {
+		if denial != nil {
+			err = fmt.Errorf("tool call denied by hook %s: %s", denial.Hook, denial.Reason)
+			result = ToolResult{
+				Content: []ContentBlock{TextContent{Text: err.Error()}},
+				Details: map[string]any{"error": err.Error()},
+			}
+		} else if tool == nil {
 			err = fmt.Errorf("tool %s not found", tc.Name)
 			result = ToolResult{
 				Content: []ContentBlock{TextContent{Text: fmt.Sprintf("Tool not found: %s", tc.Name)}},
@@ -437,17 +546,28 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 			state.AddPendingTool(tc.ID)
 
 			// Create context with session key for tools to access
-			toolCtx := context.WithValue(ctx, SessionKeyContextKey, state.SessionKey)
+			toolCtx := context.WithValue(toolSpanCtx, SessionKeyContextKey, state.SessionKey)
+			toolCtx = tools.WithSessionKey(toolCtx, state.SessionKey)
+			if agentID, ok := ctx.Value(AgentIDContextKey).(string); ok && agentID != "" {
+				toolCtx = tools.WithAgentID(toolCtx, agentID)
+			}
 
 			// Add timeout for tool execution (safety net in case tool doesn't handle its own timeout)
 			toolTimeout := o.config.ToolTimeout
 			if toolTimeout <= 0 {
 				toolTimeout = 3 * time.Minute // default 3 minutes
 			}
+			// Tools with their own time limit (e.g. subagent_wait) get room to report it themselves
+			if tt, ok := tool.(TimedTool); ok {
+				if limit := tt.ExecutionTimeout(tc.Arguments) + timedToolGrace; limit > toolTimeout {
+					toolTimeout = limit
+				}
+			}
 			execCtx, execCancel := context.WithTimeout(toolCtx, toolTimeout)
 			defer execCancel()
 
 			// Execute tool with streaming support in a goroutine to handle timeout properly
+			start := time.Now()
 			resultCh := make(chan *toolResultPair, 1)
 			go func() {
 				r, e := tool.Execute(execCtx, tc.Arguments, func(partial ToolResult) {
@@ -475,6 +595,9 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 			}
 
 			state.RemovePendingTool(tc.ID)
+			telemetry.RecordToolCall(tc.Name, time.Since(start), err)
+
+			denial = o.runPostToolHooks(ctx, state, tc, &result, &err)
 		}
 
 		// Log tool execution result
@@ -507,6 +630,9 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 			resultMsg.Metadata["error"] = err.Error()
 			result.Content = []ContentBlock{TextContent{Text: err.Error()}}
 		}
+		if denial != nil {
+			resultMsg.Metadata[HookDenialMetadataKey] = hookDenial(*denial)
+		}
 
 		results = append(results, resultMsg)
 
@@ -531,6 +657,8 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 			}
 		}
 
+		telemetry.EndSpan(span, err)
+
 		// Emit tool execution end
 		event := NewEvent(EventToolExecutionEnd).
 			WithToolExecution(tc.ID, tc.Name, tc.Arguments).
@@ -593,7 +721,7 @@ func (o *Orchestrator) fetchFollowUpMessages() []AgentMessage {
 // Safe to call multiple times
 func (o *Orchestrator) Stop() {
 	if o.cancelFunc != nil {
-		o.cancelFunc()
+		o.cancelFunc(nil)
 		o.cancelFunc = nil
 	}
 	if o.eventChan != nil {
@@ -654,6 +782,12 @@ func convertToProviderMessages(messages []AgentMessage) []providers.Message {
 				} else if b.URL != "" {
 					providerMsg.Images = append(providerMsg.Images, b.URL)
 				}
+			case ThinkingContent:
+				providerMsg.Thinking = append(providerMsg.Thinking, providers.ThinkingBlock{
+					Thinking:  b.Thinking,
+					Signature: b.Signature,
+					Redacted:  b.Redacted,
+				})
 			}
 		}
 
@@ -688,9 +822,22 @@ func convertToProviderMessages(messages []AgentMessage) []providers.Message {
 	return result
 }
 
+// UsageMetadataKey is the assistant message metadata key holding the provider usage of the call
+const UsageMetadataKey = "usage"
+
 // convertFromProviderResponse converts provider response to agent message
 func convertFromProviderResponse(response *providers.Response) AgentMessage {
-	content := []ContentBlock{TextContent{Text: response.Content}}
+	var content []ContentBlock
+
+	// Thinking blocks come first so they can be sent back in tool use turns
+	for _, t := range response.Thinking {
+		content = append(content, ThinkingContent{
+			Thinking:  t.Thinking,
+			Signature: t.Signature,
+			Redacted:  t.Redacted,
+		})
+	}
+	content = append(content, TextContent{Text: response.Content})
 
 	// Handle tool calls
 	for _, tc := range response.ToolCalls {
@@ -701,12 +848,28 @@ func convertFromProviderResponse(response *providers.Response) AgentMessage {
 		})
 	}
 
+	metadata := map[string]any{"stop_reason": response.FinishReason}
+	if response.Usage != (providers.Usage{}) {
+		metadata[UsageMetadataKey] = response.Usage
+	}
 	return AgentMessage{
 		Role:      RoleAssistant,
 		Content:   content,
 		Timestamp: time.Now().UnixMilli(),
-		Metadata:  map[string]any{"stop_reason": response.FinishReason},
+		Metadata:  metadata,
+	}
+}
+
+// chatOptions builds the provider options for the current state:
+// prompt cache breakpoints and the extended thinking budget
+func chatOptions(state *AgentState) []providers.ChatOption {
+	opts := []providers.ChatOption{
+		providers.WithCacheHints(providers.CacheHints{Tools: true, System: true}),
+	}
+	if budget := providers.ThinkingBudgetForLevel(state.ThinkingLevel); budget > 0 {
+		opts = append(opts, providers.WithThinkingBudget(budget))
 	}
+	return opts
 }
 
 // convertToToolDefinitions converts agent tools to provider tool definitions
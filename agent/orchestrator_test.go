@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/agent/hooks"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// scriptedProvider returns canned responses and records every call
+type scriptedProvider struct {
+	responses []*providers.Response
+	calls     [][]providers.Message
+	options   []providers.ChatOptions
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	var opts providers.ChatOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	p.calls = append(p.calls, messages)
+	p.options = append(p.options, opts)
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *scriptedProvider) Close() error {
+	return nil
+}
+
+// echoTool returns its input
+type echoTool struct{}
+
+func (echoTool) Name() string               { return "echo" }
+func (echoTool) Description() string        { return "Echo the input" }
+func (echoTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (echoTool) Label() string              { return "Echo" }
+func (echoTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	text, _ := params["text"].(string)
+	return ToolResult{Content: []ContentBlock{TextContent{Text: text}}}, nil
+}
+
+func TestOrchestratorThinkingAndPromptCache(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "SOUL.md"), []byte("Be kind."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{
+			Thinking:  []providers.ThinkingBlock{{Thinking: "I should echo.", Signature: "sig-1"}},
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "echo", Params: map[string]interface{}{"text": "hi"}}},
+		},
+		{Content: "done"},
+	}}
+
+	state := NewAgentState()
+	state.ThinkingLevel = "medium"
+	state.Tools = []Tool{echoTool{}}
+	o := NewOrchestrator(&LoopConfig{
+		Provider:       provider,
+		MaxIterations:  5,
+		ContextBuilder: NewContextBuilder(NewMemoryStore(workspace), workspace),
+	}, state)
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "say hi"}}}
+	if _, err := o.Run(context.Background(), []AgentMessage{prompt}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(provider.calls) != 2 {
+		t.Fatalf("expected 2 LLM calls, got %d", len(provider.calls))
+	}
+	for _, opts := range provider.options {
+		if opts.ThinkingBudget != 8192 || !opts.Cache.System || !opts.Cache.Tools {
+			t.Errorf("unexpected chat options %+v", opts)
+		}
+	}
+
+	// The stable prefix carries the bootstrap files and the cache breakpoint;
+	// the current time lives in the uncached tail
+	first := provider.calls[0]
+	if !first[0].CacheBreakpoint || !strings.Contains(first[0].Content, "Be kind.") || strings.Contains(first[0].Content, "Current Time") {
+		t.Errorf("unexpected stable system message %+v", first[0])
+	}
+	if first[1].Role != "system" || first[1].CacheBreakpoint || !strings.Contains(first[1].Content, "Current Time") {
+		t.Errorf("unexpected dynamic system message %+v", first[1])
+	}
+
+	// The thinking block must be sent back with the tool use turn
+	var assistant *providers.Message
+	for i, msg := range provider.calls[1] {
+		if msg.Role == "assistant" {
+			assistant = &provider.calls[1][i]
+		}
+	}
+	if assistant == nil || len(assistant.Thinking) != 1 || assistant.Thinking[0].Signature != "sig-1" {
+		t.Fatalf("expected thinking round trip, got %+v", assistant)
+	}
+
+	var thinkingEvents []string
+	for len(o.eventChan) > 0 {
+		event := <-o.eventChan
+		if event.Type == EventThinking {
+			thinkingEvents = append(thinkingEvents, event.Thinking)
+		}
+	}
+	if len(thinkingEvents) != 1 || thinkingEvents[0] != "I should echo." {
+		t.Errorf("unexpected thinking events %v", thinkingEvents)
+	}
+}
+
+func TestOrchestratorToolAndLLMHooks(t *testing.T) {
+	registry := hooks.NewRegistry()
+	registry.Register(hooks.StagePreTool, hooks.NewFuncHook("guard", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		switch p.Arguments["text"] {
+		case "forbidden":
+			return hooks.Deny("not allowed"), nil
+		case "loud":
+			return &hooks.Decision{Action: hooks.ActionMutate, Arguments: map[string]any{"text": "quiet"}}, nil
+		}
+		return nil, nil
+	}))
+	registry.Register(hooks.StagePostLLM, hooks.NewFuncHook("scrub", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		content := strings.ReplaceAll(p.Content, "secret", "***")
+		return &hooks.Decision{Action: hooks.ActionMutate, Content: &content}, nil
+	}))
+
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{ToolCalls: []providers.ToolCall{
+			{ID: "call_1", Name: "echo", Params: map[string]interface{}{"text": "forbidden"}},
+			{ID: "call_2", Name: "echo", Params: map[string]interface{}{"text": "loud"}},
+		}},
+		{Content: "the secret is out"},
+	}}
+
+	state := NewAgentState()
+	state.Tools = []Tool{echoTool{}}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 5, Hooks: registry}, state)
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}
+	messages, err := o.Run(context.Background(), []AgentMessage{prompt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []AgentMessage
+	for _, msg := range messages {
+		if msg.Role == RoleToolResult {
+			results = append(results, msg)
+		}
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 tool results, got %d", len(results))
+	}
+	denial, ok := results[0].Metadata[HookDenialMetadataKey].(map[string]any)
+	if !ok || denial["hook"] != "guard" || denial["stage"] != "pre_tool" || !strings.Contains(extractTextContent(results[0]), "not allowed") {
+		t.Errorf("expected a recorded denial, got %+v", results[0])
+	}
+	if got := extractTextContent(results[1]); got != "quiet" {
+		t.Errorf("expected mutated arguments to reach the tool, got %q", got)
+	}
+
+	final := messages[len(messages)-1]
+	if got := extractTextContent(final); got != "the *** is out" {
+		t.Errorf("expected post_llm scrubbing, got %q", got)
+	}
+}
+
+func TestPreLLMHookDenialStopsTurn(t *testing.T) {
+	registry := hooks.NewRegistry()
+	registry.Register(hooks.StagePreLLM, hooks.NewFuncHook("budget", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		return hooks.Deny("over budget"), nil
+	}))
+
+	provider := &scriptedProvider{}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 5, Hooks: registry}, NewAgentState())
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}
+	messages, err := o.Run(context.Background(), []AgentMessage{prompt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("the provider must not be called, got %d calls", len(provider.calls))
+	}
+	final := messages[len(messages)-1]
+	if final.Role != RoleAssistant || final.Metadata[HookDenialMetadataKey] == nil {
+		t.Errorf("expected a denial message, got %+v", final)
+	}
+}
+
+func TestMessageHookDenialIsRecordedInSession(t *testing.T) {
+	sessionMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessionMgr.GetOrCreate("telegram:bot:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := hooks.NewRegistry()
+	registry.Register(hooks.StageInbound, hooks.NewFuncHook("spam", func(ctx context.Context, p *hooks.Payload) (*hooks.Decision, error) {
+		if p.SenderID == "spammer" {
+			return hooks.Deny("sender is blocked"), nil
+		}
+		return nil, nil
+	}))
+
+	msg := &bus.InboundMessage{Channel: "telegram", ChatID: "42", SenderID: "spammer", Content: "buy now"}
+	if _, ok := runMessageHooks(context.Background(), registry, sessionMgr, sess, channelHookPayload(hooks.StageInbound, "main", sess.Key, msg, msg.Content)); ok {
+		t.Fatal("expected the message to be denied")
+	}
+
+	reloaded, err := sessionMgr.GetOrCreate(sess.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Messages) != 1 {
+		t.Fatalf("expected the denial to be recorded, got %d messages", len(reloaded.Messages))
+	}
+	recorded := reloaded.Messages[0]
+	denial, ok := recorded.Metadata[HookDenialMetadataKey].(map[string]any)
+	if recorded.Role != "system" || !ok || denial["reason"] != "sender is blocked" {
+		t.Errorf("unexpected recorded denial %+v", recorded)
+	}
+
+	msg.SenderID = "friend"
+	if content, ok := runMessageHooks(context.Background(), registry, sessionMgr, sess, channelHookPayload(hooks.StageInbound, "main", sess.Key, msg, msg.Content)); !ok || content != "buy now" {
+		t.Errorf("expected the message to pass, got %q %v", content, ok)
+	}
+}
@@ -2,7 +2,10 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,6 +13,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/smallnest/goclaw/internal/logger"
@@ -41,8 +45,10 @@ type Skill struct {
 			Install []SkillInstall `yaml:"install"`
 		} `yaml:"openclaw"`
 	} `yaml:"metadata"`
-	Requires SkillRequirements `yaml:"requires"` // 兼容旧格式
-	Content  string            `yaml:"-"`        // 技能内容（Markdown）
+	Requires    SkillRequirements `yaml:"requires"`    // 兼容旧格式
+	Entrypoints []SkillEntrypoint `yaml:"entrypoints"` // 可执行入口，由 skill_run 工具调用
+	Content     string            `yaml:"-"`           // 技能内容（Markdown）
+	Dir         string            `yaml:"-"`           // 技能目录
 	// 缺失的依赖信息
 	MissingDeps *MissingDeps `yaml:"-"` // 解析时填充
 }
@@ -75,13 +81,42 @@ type SkillInstall struct {
 }
 
 // SkillsLoader 技能加载器
+// 可以并发读取；Reload 重新扫描后整体替换技能集合，已取得的技能快照不受影响
 type SkillsLoader struct {
 	workspace      string
 	skillsDirs     []string
-	skills         map[string]*Skill
-	alwaysSkills   []string
 	autoInstall    bool          // 是否启用自动安装依赖
 	installTimeout time.Duration // 安装超时时间
+	strictLock     bool          // 内容与锁文件不一致时拒绝加载
+
+	reloadMu     sync.Mutex // 串行化 Discover/Reload
+	mu           sync.RWMutex
+	skills       map[string]*Skill
+	alwaysSkills []string
+	fingerprints map[string]string // 技能名 -> 来源路径和 SKILL.md 内容的哈希
+	lockWarnings []string          // 非严格模式下与 skills.lock 不一致的技能
+	listeners    []func(*SkillChanges)
+}
+
+// SkillChanges 一次重新加载中新增、删除和修改的技能
+type SkillChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty 是否没有任何变化
+func (c *SkillChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// skillSet is the result of one discovery pass
+type skillSet struct {
+	skills       map[string]*Skill
+	alwaysSkills []string
+	fingerprints map[string]string
+	lock         *SkillLock
+	lockWarnings []string
 }
 
 // Default installation timeout
@@ -95,8 +130,10 @@ func NewSkillsLoader(workspace string, skillsDirs []string) *SkillsLoader {
 		workspace:      workspace,
 		skillsDirs:     skillsDirs,
 		skills:         make(map[string]*Skill),
+		fingerprints:   make(map[string]string),
 		autoInstall:    os.Getenv("GOCLAW_SKILL_AUTO_INSTALL") == "true",
 		installTimeout: DefaultInstallTimeout,
+		strictLock:     os.Getenv("GOCLAW_SKILL_LOCK_STRICT") == "true",
 	}
 }
 
@@ -110,12 +147,72 @@ func (l *SkillsLoader) SetInstallTimeout(timeout time.Duration) {
 	l.installTimeout = timeout
 }
 
+// SetStrictLock 设置技能内容与 skills.lock 不一致时是否拒绝加载（默认仅警告）
+func (l *SkillsLoader) SetStrictLock(strict bool) {
+	l.strictLock = strict
+}
+
+// Dirs 返回配置的技能目录，按加载顺序
+func (l *SkillsLoader) Dirs() []string {
+	return append([]string(nil), l.skillsDirs...)
+}
+
+// OnChange 注册技能变化回调，Reload 发现变化后调用
+func (l *SkillsLoader) OnChange(fn func(*SkillChanges)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
 // Discover 发现技能
 // 按照顺序加载技能，后加载的同名技能会覆盖前面的
 func (l *SkillsLoader) Discover() error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	l.swap(l.discover())
+	return nil
+}
+
+// Reload 重新扫描技能目录并替换技能集合
+// 返回新增、删除和修改的技能，有变化时记录日志并通知 OnChange 回调
+func (l *SkillsLoader) Reload() (*SkillChanges, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	changes := l.swap(l.discover())
+	if changes.Empty() {
+		return changes, nil
+	}
+
+	logger.Info("Skills reloaded",
+		zap.Strings("added", changes.Added),
+		zap.Strings("removed", changes.Removed),
+		zap.Strings("changed", changes.Changed))
+
+	l.mu.RLock()
+	listeners := append([]func(*SkillChanges){}, l.listeners...)
+	l.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(changes)
+	}
+	return changes, nil
+}
+
+// discover scans all skill directories into a new set
+func (l *SkillsLoader) discover() *skillSet {
+	set := &skillSet{
+		skills:       make(map[string]*Skill),
+		fingerprints: make(map[string]string),
+	}
+	lock, err := l.loadLock()
+	if err != nil {
+		logger.Warn("Failed to load skills lock file", zap.Error(err))
+	}
+	set.lock = lock
+
 	// 按照配置的技能目录顺序加载（后面的会覆盖前面的）
 	for _, dir := range l.skillsDirs {
-		if err := l.discoverInDir(dir); err != nil {
+		if err := l.discoverInDir(dir, set); err != nil {
 			// 目录不存在是正常的，继续
 			if !os.IsNotExist(err) {
 				logger.Warn("Failed to discover skills in directory",
@@ -125,11 +222,49 @@ func (l *SkillsLoader) Discover() error {
 		}
 	}
 
-	return nil
+	// A skill overridden by a later directory is not always-on through the earlier one
+	for name, skill := range set.skills {
+		if skill.Always {
+			set.alwaysSkills = append(set.alwaysSkills, name)
+		}
+	}
+	sort.Strings(set.alwaysSkills)
+	return set
+}
+
+// swap replaces the current skills with set and returns the difference
+func (l *SkillsLoader) swap(set *skillSet) *SkillChanges {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	changes := &SkillChanges{}
+	for name, fp := range set.fingerprints {
+		old, ok := l.fingerprints[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case old != fp:
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range l.fingerprints {
+		if _, ok := set.fingerprints[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+
+	l.skills = set.skills
+	l.alwaysSkills = set.alwaysSkills
+	l.fingerprints = set.fingerprints
+	l.lockWarnings = set.lockWarnings
+	return changes
 }
 
 // discoverInDir 在目录中发现技能
-func (l *SkillsLoader) discoverInDir(dir string) error {
+func (l *SkillsLoader) discoverInDir(dir string, set *skillSet) error {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return err
@@ -142,8 +277,13 @@ func (l *SkillsLoader) discoverInDir(dir string) error {
 		}
 
 		skillPath := filepath.Join(dir, entry.Name())
-		if err := l.loadSkill(skillPath); err != nil {
+		if err := l.loadSkill(skillPath, set); err != nil {
 			// 跳过无法加载的技能
+			if errors.Is(err, ErrSkillLockMismatch) {
+				logger.Error("Refusing to load skill modified since it was locked",
+					zap.String("path", skillPath),
+					zap.Error(err))
+			}
 			continue
 		}
 	}
@@ -152,7 +292,7 @@ func (l *SkillsLoader) discoverInDir(dir string) error {
 }
 
 // loadSkill 加载技能
-func (l *SkillsLoader) loadSkill(path string) error {
+func (l *SkillsLoader) loadSkill(path string, set *skillSet) error {
 	// 查找 SKILL.md 或 skill.md
 	skillFile := filepath.Join(path, "SKILL.md")
 	if _, err := os.Stat(skillFile); os.IsNotExist(err) {
@@ -162,6 +302,11 @@ func (l *SkillsLoader) loadSkill(path string) error {
 		}
 	}
 
+	// 校验 skills.lock，严格模式下拒绝被修改过的技能
+	if err := l.verifyLock(path, set); err != nil {
+		return err
+	}
+
 	// 读取文件
 	content, err := os.ReadFile(skillFile)
 	if err != nil {
@@ -192,14 +337,16 @@ func (l *SkillsLoader) loadSkill(path string) error {
 	if skill.Name == "" {
 		skill.Name = filepath.Base(path)
 	}
-
-	l.skills[skill.Name] = &skill
-
-	// 记录 always 技能
-	if skill.Always {
-		l.alwaysSkills = append(l.alwaysSkills, skill.Name)
+	if abs, err := filepath.Abs(path); err == nil {
+		skill.Dir = abs
+	} else {
+		skill.Dir = path
 	}
 
+	set.skills[skill.Name] = &skill
+	sum := sha256.Sum256(append([]byte(skillFile+"\x00"), content...))
+	set.fingerprints[skill.Name] = hex.EncodeToString(sum[:])
+
 	return nil
 }
 
@@ -322,6 +469,8 @@ func (l *SkillsLoader) extractContent(content string) string {
 
 // List 列出所有技能
 func (l *SkillsLoader) List() []*Skill {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	result := make([]*Skill, 0, len(l.skills))
 	for _, skill := range l.skills {
 		result = append(result, skill)
@@ -331,17 +480,23 @@ func (l *SkillsLoader) List() []*Skill {
 
 // Get 获取技能
 func (l *SkillsLoader) Get(name string) (*Skill, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	skill, ok := l.skills[name]
 	return skill, ok
 }
 
 // GetAlwaysSkills 获取始终加载的技能
 func (l *SkillsLoader) GetAlwaysSkills() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.alwaysSkills
 }
 
 // BuildSummary 构建技能摘要
 func (l *SkillsLoader) BuildSummary() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	if len(l.skills) == 0 {
 		return "No skills available."
 	}
@@ -368,7 +523,7 @@ func (l *SkillsLoader) BuildSummary() string {
 
 // LoadContent 加载技能内容
 func (l *SkillsLoader) LoadContent(name string) (string, error) {
-	skill, ok := l.skills[name]
+	skill, ok := l.Get(name)
 	if !ok {
 		return "", fmt.Errorf("skill not found: %s", name)
 	}
@@ -378,7 +533,7 @@ func (l *SkillsLoader) LoadContent(name string) (string, error) {
 
 // InstallDependencies 安装技能依赖
 func (l *SkillsLoader) InstallDependencies(skillName string) error {
-	skill, ok := l.skills[skillName]
+	skill, ok := l.Get(skillName)
 	if !ok {
 		return fmt.Errorf("skill not found: %s", skillName)
 	}
@@ -756,6 +911,8 @@ type SearchResult struct {
 
 // Search 搜索技能
 func (l *SkillsLoader) Search(query string) []*SearchResult {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	if len(l.skills) == 0 {
 		return nil
 	}
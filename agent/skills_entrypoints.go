@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+)
+
+// 技能入口超时的默认值和上限
+const (
+	DefaultSkillEntrypointTimeout = 60 * time.Second
+	MaxSkillEntrypointTimeout     = 10 * time.Minute
+)
+
+// SkillEntrypoint 技能在 frontmatter 中声明的可执行入口
+//
+//	entrypoints:
+//	  - name: fetch
+//	    description: Download a feed
+//	    command: [python3, ./scripts/fetch.py]
+//	    permissions:
+//	      network: true
+//	      read: [$WORKSPACE/feeds.txt]
+//	      write: [$WORKSPACE/out]
+//	      env: [FEED_TOKEN]
+//	      timeout: 120
+//	      unrestricted_filesystem: false
+type SkillEntrypoint struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Command     []string         `yaml:"command"` // argv，./ 开头的参数相对于技能目录
+	Permissions SkillPermissions `yaml:"permissions"`
+}
+
+// SkillPermissions 入口运行时需要的权限，未声明的一律不给
+// 路径可以使用 $SKILL_DIR、$WORKSPACE 和 ~ 开头
+type SkillPermissions struct {
+	Network bool     `yaml:"network"` // 是否访问网络
+	Read    []string `yaml:"read"`    // 只读路径
+	Write   []string `yaml:"write"`   // 可写路径
+	Env     []string `yaml:"env"`     // 透传的环境变量名
+	Timeout int      `yaml:"timeout"` // 超时秒数，默认 60，最大 600
+
+	// UnrestrictedFilesystem 入口可以在宿主机上以 Agent 的身份读写任意文件
+	// 本地执行器无法限制文件系统，只运行这样声明的入口；Docker 沙箱仍然只挂载 read/write 路径
+	UnrestrictedFilesystem bool `yaml:"unrestricted_filesystem"`
+}
+
+// SkillEntrypointIssue 入口声明中的一个问题，Warning 为 true 时不影响运行
+type SkillEntrypointIssue struct {
+	Entrypoint string
+	Message    string
+	Warning    bool
+}
+
+func (i SkillEntrypointIssue) String() string {
+	if i.Entrypoint == "" {
+		return i.Message
+	}
+	return i.Entrypoint + ": " + i.Message
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateSkillEntrypoints 检查技能的入口声明
+// workspace 用于展开 $WORKSPACE，为空时使用 $WORKSPACE 的路径视为错误
+func ValidateSkillEntrypoints(skill *Skill, workspace string) []SkillEntrypointIssue {
+	var issues []SkillEntrypointIssue
+	seen := make(map[string]bool)
+	for i := range skill.Entrypoints {
+		ep := &skill.Entrypoints[i]
+		if ep.Name == "" {
+			issues = append(issues, SkillEntrypointIssue{Message: fmt.Sprintf("entrypoint #%d has no name", i+1)})
+			continue
+		}
+		if seen[ep.Name] {
+			issues = append(issues, SkillEntrypointIssue{Entrypoint: ep.Name, Message: "duplicate entrypoint name"})
+			continue
+		}
+		seen[ep.Name] = true
+		_, epIssues := resolveSkillEntrypoint(skill, ep, workspace)
+		issues = append(issues, epIssues...)
+	}
+	return issues
+}
+
+// LocalRunnerSkillIssues 返回本地执行器无法按声明运行的入口（未启用 tools.shell.sandbox 时使用）
+// 本地执行器不能限制文件系统访问，也可能无法隔离网络，这些入口只能在 Docker 沙箱中运行
+func LocalRunnerSkillIssues(skill *Skill) []SkillEntrypointIssue {
+	var issues []SkillEntrypointIssue
+	for _, ep := range skill.Entrypoints {
+		if ep.Name == "" {
+			continue
+		}
+		if !ep.Permissions.UnrestrictedFilesystem {
+			issues = append(issues, SkillEntrypointIssue{
+				Entrypoint: ep.Name,
+				Message:    "the local runner cannot restrict filesystem access to the declared paths, enable tools.shell.sandbox to run it (or declare unrestricted_filesystem: true)",
+				Warning:    true,
+			})
+		}
+		if !ep.Permissions.Network && !tools.LocalNetworkIsolation() {
+			issues = append(issues, SkillEntrypointIssue{
+				Entrypoint: ep.Name,
+				Message:    "network isolation is not available on this host, enable tools.shell.sandbox to run it without network",
+				Warning:    true,
+			})
+		}
+	}
+	return issues
+}
+
+// Entrypoint 按名称查找技能入口
+func (s *Skill) Entrypoint(name string) (*SkillEntrypoint, bool) {
+	for i := range s.Entrypoints {
+		if s.Entrypoints[i].Name == name {
+			return &s.Entrypoints[i], true
+		}
+	}
+	return nil, false
+}
+
+// ResolveSkillEntrypoint 展开入口中的路径并检查声明，声明有错误时拒绝运行
+func ResolveSkillEntrypoint(skill *Skill, name, workspace string) (*tools.SkillEntrypointSpec, error) {
+	ep, ok := skill.Entrypoint(name)
+	if !ok {
+		return nil, fmt.Errorf("skill %s has no entrypoint %q", skill.Name, name)
+	}
+	spec, issues := resolveSkillEntrypoint(skill, ep, workspace)
+	for _, issue := range issues {
+		if !issue.Warning {
+			return nil, fmt.Errorf("invalid entrypoint %s of skill %s: %s", name, skill.Name, issue.Message)
+		}
+	}
+	return spec, nil
+}
+
+// resolveSkillEntrypoint builds the runnable spec of ep and collects every
+// problem of its declaration
+func resolveSkillEntrypoint(skill *Skill, ep *SkillEntrypoint, workspace string) (*tools.SkillEntrypointSpec, []SkillEntrypointIssue) {
+	var issues []SkillEntrypointIssue
+	fail := func(format string, args ...any) {
+		issues = append(issues, SkillEntrypointIssue{Entrypoint: ep.Name, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(format string, args ...any) {
+		issues = append(issues, SkillEntrypointIssue{Entrypoint: ep.Name, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	spec := &tools.SkillEntrypointSpec{
+		Skill:      skill.Name,
+		Entrypoint: ep.Name,
+		Dir:        skill.Dir,
+		Network:    ep.Permissions.Network,
+		HostFS:     ep.Permissions.UnrestrictedFilesystem,
+		Timeout:    DefaultSkillEntrypointTimeout,
+	}
+
+	// 命令
+	if len(ep.Command) == 0 || ep.Command[0] == "" {
+		fail("command is empty")
+	}
+	for i, arg := range ep.Command {
+		resolved, err := expandSkillCommandArg(arg, skill.Dir, workspace)
+		if err != nil {
+			fail("command %q: %v", arg, err)
+			continue
+		}
+		if strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "$SKILL_DIR") || strings.HasPrefix(arg, "${SKILL_DIR}") {
+			if !withinDir(skill.Dir, resolved) {
+				fail("command %q points outside the skill directory", arg)
+				continue
+			}
+			if _, err := os.Stat(resolved); err != nil {
+				fail("command %q does not exist in the skill directory", arg)
+				continue
+			}
+		} else if i == 0 && !strings.Contains(arg, "/") {
+			if _, err := exec.LookPath(arg); err != nil {
+				warn("%s is not on PATH of this host", arg)
+			}
+		}
+		spec.Command = append(spec.Command, resolved)
+	}
+
+	// 文件系统
+	resolvePaths := func(kind string, paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			resolved, err := expandSkillPath(p, skill.Dir, workspace)
+			if err != nil {
+				fail("%s path %q: %v", kind, p, err)
+				continue
+			}
+			if resolved == "/" {
+				fail("%s path %q grants the whole filesystem", kind, p)
+				continue
+			}
+			if kind == "write" && (withinDir(skill.Dir, resolved) || withinDir(resolved, skill.Dir)) {
+				fail("write path %q overlaps the skill directory, which stays read-only", p)
+				continue
+			}
+			if _, err := os.Stat(resolved); err != nil {
+				warn("%s path %s does not exist", kind, resolved)
+			}
+			out = append(out, resolved)
+		}
+		return out
+	}
+	spec.Read = resolvePaths("read", ep.Permissions.Read)
+	spec.Write = resolvePaths("write", ep.Permissions.Write)
+
+	// 环境变量
+	for _, name := range ep.Permissions.Env {
+		switch {
+		case !envNamePattern.MatchString(name):
+			fail("invalid environment variable name %q", name)
+		case name == "PATH" || name == "HOME" || name == "TMPDIR":
+			fail("%s is set by the runner and cannot be passed through", name)
+		default:
+			if _, ok := os.LookupEnv(name); !ok {
+				warn("environment variable %s is not set", name)
+			}
+			spec.Env = append(spec.Env, name)
+		}
+	}
+
+	// 超时
+	switch timeout := time.Duration(ep.Permissions.Timeout) * time.Second; {
+	case ep.Permissions.Timeout < 0:
+		fail("timeout must not be negative")
+	case timeout > MaxSkillEntrypointTimeout:
+		fail("timeout %ds exceeds the maximum of %ds", ep.Permissions.Timeout, int(MaxSkillEntrypointTimeout/time.Second))
+	case timeout > 0:
+		spec.Timeout = timeout
+	}
+
+	return spec, issues
+}
+
+// expandSkillPath expands $SKILL_DIR, $WORKSPACE and ~ at the start of a
+// permission path and returns a clean absolute path
+func expandSkillPath(p, skillDir, workspace string) (string, error) {
+	expanded, err := expandSkillVars(p, skillDir, workspace)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(expanded, "~/") || expanded == "~" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		expanded = filepath.Join(home, strings.TrimPrefix(expanded, "~"))
+	}
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("must be absolute or start with $SKILL_DIR, $WORKSPACE or ~")
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// expandSkillCommandArg expands $SKILL_DIR and $WORKSPACE in a command
+// argument and resolves ./ arguments against the skill directory
+func expandSkillCommandArg(arg, skillDir, workspace string) (string, error) {
+	expanded, err := expandSkillVars(arg, skillDir, workspace)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(expanded, "./") {
+		return filepath.Join(skillDir, expanded), nil
+	}
+	return expanded, nil
+}
+
+// expandSkillVars replaces a leading $SKILL_DIR or $WORKSPACE
+func expandSkillVars(s, skillDir, workspace string) (string, error) {
+	for _, v := range []struct{ name, value string }{
+		{"SKILL_DIR", skillDir},
+		{"WORKSPACE", workspace},
+	} {
+		for _, prefix := range []string{"$" + v.name, "${" + v.name + "}"} {
+			rest, ok := strings.CutPrefix(s, prefix)
+			if !ok || (rest != "" && rest[0] != '/') {
+				continue
+			}
+			if v.value == "" {
+				return "", fmt.Errorf("$%s is not known here", v.name)
+			}
+			return filepath.Join(v.value, rest), nil
+		}
+	}
+	return s, nil
+}
+
+// withinDir reports whether path is dir or inside it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// skillEntrypointResolver looks entrypoints up in the current skills of a loader
+type skillEntrypointResolver struct {
+	loader    *SkillsLoader
+	workspace string
+}
+
+// NewSkillEntrypointResolver 创建 skill_run 工具使用的入口解析器
+// workspace 为 Agent 工作区，用于展开 $WORKSPACE
+func NewSkillEntrypointResolver(loader *SkillsLoader, workspace string) tools.SkillEntrypointResolver {
+	return &skillEntrypointResolver{loader: loader, workspace: workspace}
+}
+
+// ResolveEntrypoint 实现 tools.SkillEntrypointResolver
+func (r *skillEntrypointResolver) ResolveEntrypoint(skill, entrypoint string) (*tools.SkillEntrypointSpec, error) {
+	s, ok := r.loader.Get(skill)
+	if !ok {
+		return nil, fmt.Errorf("skill not found: %s", skill)
+	}
+	return ResolveSkillEntrypoint(s, entrypoint, r.workspace)
+}
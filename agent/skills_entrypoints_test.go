@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSkillEntrypointsParseAndResolve(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "workspace")
+	skillDir := filepath.Join(root, "skills", "feeds")
+	writeSkill(t, skillDir, `---
+name: feeds
+description: Fetch feeds
+entrypoints:
+  - name: fetch
+    description: Download a feed
+    command: [sh, ./fetch.sh, $WORKSPACE/feeds.txt]
+    permissions:
+      network: true
+      read: [$WORKSPACE/feeds.txt]
+      write: [$WORKSPACE/out]
+      env: [GOCLAW_TEST_FEED_TOKEN]
+      timeout: 120
+---
+Use the fetch entrypoint.
+`)
+	if err := os.WriteFile(filepath.Join(skillDir, "fetch.sh"), []byte("echo ok\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOCLAW_TEST_FEED_TOKEN", "secret")
+
+	loader := NewSkillsLoader("", []string{filepath.Dir(skillDir)})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	skill, ok := loader.Get("feeds")
+	if !ok {
+		t.Fatal("expected the skill to load")
+	}
+	if len(skill.Entrypoints) != 1 || skill.Dir != skillDir {
+		t.Fatalf("unexpected skill %+v", skill)
+	}
+	if !strings.Contains(skill.Content, "Use the fetch entrypoint.") {
+		t.Errorf("unexpected content %q", skill.Content)
+	}
+
+	spec, err := NewSkillEntrypointResolver(loader, workspace).ResolveEntrypoint("feeds", "fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCmd := []string{"sh", filepath.Join(skillDir, "fetch.sh"), filepath.Join(workspace, "feeds.txt")}
+	if strings.Join(spec.Command, " ") != strings.Join(wantCmd, " ") {
+		t.Errorf("unexpected command %v", spec.Command)
+	}
+	if !spec.Network || spec.Timeout != 2*time.Minute || spec.Dir != skillDir {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	if spec.Read[0] != filepath.Join(workspace, "feeds.txt") || spec.Write[0] != filepath.Join(workspace, "out") {
+		t.Errorf("unexpected paths read=%v write=%v", spec.Read, spec.Write)
+	}
+	if strings.Join(spec.Env, ",") != "GOCLAW_TEST_FEED_TOKEN" {
+		t.Errorf("unexpected env %v", spec.Env)
+	}
+
+	// Paths that do not exist yet are only warnings
+	for _, issue := range ValidateSkillEntrypoints(skill, workspace) {
+		if !issue.Warning {
+			t.Errorf("unexpected error %s", issue)
+		}
+	}
+
+	if _, err := ResolveSkillEntrypoint(skill, "missing", workspace); err == nil {
+		t.Error("expected an unknown entrypoint to fail")
+	}
+}
+
+func TestValidateSkillEntrypoints(t *testing.T) {
+	skillDir := t.TempDir()
+	skill := &Skill{Name: "bad", Dir: skillDir, Entrypoints: []SkillEntrypoint{
+		{Name: "empty"},
+		{Name: "escape", Command: []string{"sh", "./../outside.sh"}},
+		{Name: "absent", Command: []string{"./absent.sh"}},
+		{Name: "paths", Command: []string{"true"}, Permissions: SkillPermissions{
+			Read:  []string{"/", "relative/path"},
+			Write: []string{"$SKILL_DIR/cache"},
+		}},
+		{Name: "env", Command: []string{"true"}, Permissions: SkillPermissions{Env: []string{"NOT-VALID", "HOME"}}},
+		{Name: "timeout", Command: []string{"true"}, Permissions: SkillPermissions{Timeout: 3600}},
+		{Name: "timeout", Command: []string{"true"}},
+		{Command: []string{"true"}},
+	}}
+
+	issues := ValidateSkillEntrypoints(skill, "")
+	got := make(map[string]int)
+	for _, issue := range issues {
+		if issue.Warning {
+			t.Errorf("unexpected warning %s", issue)
+			continue
+		}
+		got[issue.Entrypoint]++
+	}
+	want := map[string]int{"empty": 1, "escape": 1, "absent": 1, "paths": 3, "env": 2, "timeout": 2, "": 1}
+	for name, n := range want {
+		if got[name] != n {
+			t.Errorf("%q: expected %d errors, got %d (%v)", name, n, got[name], issues)
+		}
+	}
+
+	if _, err := ResolveSkillEntrypoint(skill, "escape", ""); err == nil {
+		t.Error("expected an invalid entrypoint to be refused")
+	}
+}
+
+func TestLocalRunnerSkillIssues(t *testing.T) {
+	skill := &Skill{Name: "demo", Dir: t.TempDir(), Entrypoints: []SkillEntrypoint{
+		{Name: "scoped", Command: []string{"true"}, Permissions: SkillPermissions{Network: true, Read: []string{"/etc/hosts"}}},
+		{Name: "host", Command: []string{"true"}, Permissions: SkillPermissions{Network: true, UnrestrictedFilesystem: true}},
+	}}
+
+	issues := LocalRunnerSkillIssues(skill)
+	if len(issues) != 1 || issues[0].Entrypoint != "scoped" || !issues[0].Warning {
+		t.Fatalf("expected one warning for the scoped entrypoint, got %v", issues)
+	}
+	if !strings.Contains(issues[0].Message, "tools.shell.sandbox") {
+		t.Errorf("expected the warning to point to the sandbox, got %q", issues[0].Message)
+	}
+
+	spec, err := ResolveSkillEntrypoint(skill, "host", "")
+	if err != nil || !spec.HostFS {
+		t.Errorf("expected unrestricted_filesystem to reach the spec, got %+v (%v)", spec, err)
+	}
+}
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// SkillLockFile 锁文件名，位于 ~/.goclaw 下，记录 ~/.goclaw/skills 中已安装技能的版本
+const SkillLockFile = "skills.lock"
+
+// skillLockVersion 锁文件格式版本
+const skillLockVersion = 1
+
+// ErrSkillLockMismatch 磁盘上的技能内容与锁文件记录不一致
+var ErrSkillLockMismatch = errors.New("skill content does not match skills.lock")
+
+// LockedSkill 锁文件中的一个技能
+type LockedSkill struct {
+	Source      string    `json:"source"`           // Git URL 或本地路径
+	Commit      string    `json:"commit,omitempty"` // 解析后的提交，本地安装为空
+	Hash        string    `json:"hash"`             // 技能目录内容哈希
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// SkillLock 技能锁文件，键为技能目录名
+type SkillLock struct {
+	Version int                     `json:"version"`
+	Skills  map[string]*LockedSkill `json:"skills"`
+}
+
+// NewSkillLock 创建空锁文件
+func NewSkillLock() *SkillLock {
+	return &SkillLock{Version: skillLockVersion, Skills: make(map[string]*LockedSkill)}
+}
+
+// LoadSkillLock 读取锁文件，文件不存在时返回空锁
+func LoadSkillLock(path string) (*SkillLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewSkillLock(), nil
+		}
+		return nil, err
+	}
+	lock := NewSkillLock()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if lock.Version > skillLockVersion {
+		return nil, fmt.Errorf("%s has unsupported version %d", path, lock.Version)
+	}
+	if lock.Skills == nil {
+		lock.Skills = make(map[string]*LockedSkill)
+	}
+	return lock, nil
+}
+
+// Save 原子地写入锁文件
+func (l *SkillLock) Save(path string) error {
+	l.Version = skillLockVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Names 返回按名称排序的技能目录名
+func (l *SkillLock) Names() []string {
+	names := make([]string, 0, len(l.Skills))
+	for name := range l.Skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Verify 检查技能目录内容是否与锁文件记录一致
+// 未锁定的技能返回 nil
+func (l *SkillLock) Verify(name, dir string) error {
+	entry, ok := l.Skills[name]
+	if !ok || entry.Hash == "" {
+		return nil
+	}
+	hash, err := HashSkillDir(dir)
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return fmt.Errorf("%w: %s is %s, locked %s", ErrSkillLockMismatch, name, hash, entry.Hash)
+	}
+	return nil
+}
+
+// HashSkillDir 计算技能目录的内容哈希
+// 哈希覆盖所有文件的相对路径、可执行位和内容，忽略 .git 目录，与时间戳和克隆位置无关
+func HashSkillDir(dir string) (string, error) {
+	type entry struct {
+		path string
+		line string
+	}
+	var entries []entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		var sum [sha256.Size]byte
+		kind := "f"
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			kind = "l"
+			sum = sha256.Sum256([]byte(target))
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Mode()&0111 != 0 {
+				kind = "x"
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			sum = sha256.Sum256(data)
+		default:
+			// Sockets, devices and the like are not part of a skill
+			return nil
+		}
+		entries = append(entries, entry{path: rel, line: kind + " " + hex.EncodeToString(sum[:]) + " " + rel + "\n"})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e.line))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lockedDir reports whether path is a skill directory managed by the lock file
+func (l *SkillsLoader) lockedDir(path string) bool {
+	if l.workspace == "" {
+		return false
+	}
+	managed, err := filepath.Abs(filepath.Join(l.workspace, "skills"))
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return filepath.Dir(abs) == managed
+}
+
+// verifyLock checks a skill directory against the lock file. A mismatch is
+// logged and only returned as an error in strict mode.
+func (l *SkillsLoader) verifyLock(path string, set *skillSet) error {
+	if set.lock == nil || !l.lockedDir(path) {
+		return nil
+	}
+	err := set.lock.Verify(filepath.Base(path), path)
+	if err == nil {
+		return nil
+	}
+	if l.strictLock {
+		return err
+	}
+	logger.Warn("Skill content does not match skills.lock",
+		zap.String("path", path),
+		zap.Error(err))
+	set.lockWarnings = append(set.lockWarnings, err.Error())
+	return nil
+}
+
+// LockWarnings 返回最近一次 Discover 中与锁文件不一致的技能
+func (l *SkillsLoader) LockWarnings() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]string(nil), l.lockWarnings...)
+}
+
+// loadLock reads the lock file next to the managed skills directory, returning
+// nil when there is none
+func (l *SkillsLoader) loadLock() (*SkillLock, error) {
+	if l.workspace == "" {
+		return nil, nil
+	}
+	path := filepath.Join(l.workspace, SkillLockFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return LoadSkillLock(path)
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSkill(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashSkillDir(t *testing.T) {
+	a := filepath.Join(t.TempDir(), "a")
+	b := filepath.Join(t.TempDir(), "b")
+	writeSkill(t, a, "---\nname: demo\n---\nhello\n")
+	writeSkill(t, b, "---\nname: demo\n---\nhello\n")
+	// Git metadata is not part of the content
+	if err := os.MkdirAll(filepath.Join(b, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(b, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hashA, err := HashSkillDir(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashB, err := HashSkillDir(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashA != hashB {
+		t.Errorf("expected identical trees to hash equally: %s != %s", hashA, hashB)
+	}
+
+	if err := os.WriteFile(filepath.Join(b, "run.sh"), []byte("echo hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hashB, _ = HashSkillDir(b)
+	if hashA == hashB {
+		t.Error("expected an added script to change the hash")
+	}
+	if err := os.Chmod(filepath.Join(b, "run.sh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hashC, _ := HashSkillDir(b); hashC == hashB {
+		t.Error("expected the executable bit to change the hash")
+	}
+}
+
+func TestSkillsLoaderVerifiesLock(t *testing.T) {
+	home := t.TempDir()
+	skillsDir := filepath.Join(home, "skills")
+	skillPath := filepath.Join(skillsDir, "demo")
+	writeSkill(t, skillPath, "---\nname: demo\ndescription: locked\n---\nhello\n")
+
+	hash, err := HashSkillDir(skillPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := NewSkillLock()
+	lock.Skills["demo"] = &LockedSkill{Source: "https://example.com/demo.git", Commit: "abc", Hash: hash}
+	lockPath := filepath.Join(home, SkillLockFile)
+	if err := lock.Save(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSkillLock(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Skills["demo"].Commit != "abc" || loaded.Skills["demo"].Hash != hash {
+		t.Fatalf("lock did not round-trip: %+v", loaded.Skills["demo"])
+	}
+
+	loader := NewSkillsLoader(home, []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loader.Get("demo"); !ok || len(loader.LockWarnings()) != 0 {
+		t.Fatalf("expected the locked skill to load cleanly, warnings %v", loader.LockWarnings())
+	}
+
+	writeSkill(t, skillPath, "---\nname: demo\ndescription: tampered\n---\nhello\n")
+	if err := loaded.Verify("demo", skillPath); !errors.Is(err, ErrSkillLockMismatch) {
+		t.Fatalf("expected a lock mismatch, got %v", err)
+	}
+
+	// By default a modified skill still loads with a warning
+	loader = NewSkillsLoader(home, []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loader.Get("demo"); !ok || len(loader.LockWarnings()) != 1 {
+		t.Errorf("expected a warning for the modified skill, got %v", loader.LockWarnings())
+	}
+
+	// In strict mode it is refused
+	loader = NewSkillsLoader(home, []string{skillsDir})
+	loader.SetStrictLock(true)
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loader.Get("demo"); ok {
+		t.Error("expected strict mode to refuse the modified skill")
+	}
+
+	// Skills outside the managed directory are not checked
+	other := filepath.Join(t.TempDir(), "demo")
+	writeSkill(t, other, "---\nname: demo\n---\nother\n")
+	loader = NewSkillsLoader(home, []string{filepath.Dir(other)})
+	loader.SetStrictLock(true)
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loader.Get("demo"); !ok {
+		t.Error("expected an unmanaged skill to load")
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultSkillsWatchDebounce 技能目录变化后等待多久再重新加载，合并编辑器的多次写入
+const DefaultSkillsWatchDebounce = 300 * time.Millisecond
+
+// SkillsWatcher 监听技能目录，文件变化后重新加载技能
+// 只影响之后开始的对话轮次，进行中的轮次继续使用开始时的技能快照
+type SkillsWatcher struct {
+	loader   *SkillsLoader
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+	dirs     []string // absolute skill directories of the loader
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewSkillsWatcher 创建技能目录监听器，debounce 为 0 时使用 DefaultSkillsWatchDebounce
+func NewSkillsWatcher(loader *SkillsLoader, debounce time.Duration) (*SkillsWatcher, error) {
+	if debounce <= 0 {
+		debounce = DefaultSkillsWatchDebounce
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SkillsWatcher{
+		loader:   loader,
+		debounce: debounce,
+		watcher:  watcher,
+		done:     make(chan struct{}),
+	}
+	for _, dir := range loader.Dirs() {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		w.dirs = append(w.dirs, abs)
+	}
+	return w, nil
+}
+
+// Start 开始监听，ctx 结束或调用 Stop 后停止
+func (w *SkillsWatcher) Start(ctx context.Context) error {
+	for _, dir := range w.dirs {
+		w.watchSkillsDir(dir)
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.run(ctx)
+
+	logger.Info("Watching skills directories for changes", zap.Strings("dirs", w.dirs))
+	return nil
+}
+
+// Stop 停止监听
+func (w *SkillsWatcher) Stop() error {
+	var err error
+	w.once.Do(func() {
+		if w.cancel != nil {
+			w.cancel()
+			<-w.done
+		}
+		err = w.watcher.Close()
+	})
+	return err
+}
+
+// run collects events and reloads once they settle for the debounce interval
+func (w *SkillsWatcher) run(ctx context.Context) {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.handle(event) {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("Skills watcher error", zap.Error(err))
+		case <-timer.C:
+			if _, err := w.loader.Reload(); err != nil {
+				logger.Warn("Failed to reload skills", zap.Error(err))
+			}
+		}
+	}
+}
+
+// handle adds watches for new directories and reports whether the event
+// concerns a skill and should trigger a reload
+func (w *SkillsWatcher) handle(event fsnotify.Event) bool {
+	path := filepath.Clean(event.Name)
+	parent := filepath.Dir(path)
+
+	for _, dir := range w.dirs {
+		switch {
+		case path == dir:
+			// The skills directory itself appeared or went away
+			if event.Has(fsnotify.Create) {
+				w.watchSkillsDir(dir)
+			}
+			return true
+		case parent == dir:
+			// A skill directory was added, removed or renamed
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(path); err == nil && info.IsDir() {
+					w.add(path)
+				}
+			}
+			return true
+		case filepath.Dir(parent) == dir:
+			// A file inside a skill directory, such as SKILL.md
+			return true
+		}
+	}
+	return false
+}
+
+// watchSkillsDir watches a skills directory and each skill in it. A missing
+// directory is watched through its parent so it is picked up once created.
+func (w *SkillsWatcher) watchSkillsDir(dir string) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		w.add(filepath.Dir(dir))
+		return
+	}
+	// Watch before listing so a skill created in between is not missed
+	w.add(dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			w.add(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// add watches path, logging instead of failing so one bad directory does not
+// disable reloads for the others
+func (w *SkillsWatcher) add(path string) {
+	if err := w.watcher.Add(path); err != nil {
+		logger.Debug("Failed to watch skills path",
+			zap.String("path", path),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/providers"
+)
+
+func TestSkillsLoaderReloadReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, filepath.Join(dir, "alpha"), "---\nname: alpha\ndescription: first\n---\nalpha\n")
+	writeSkill(t, filepath.Join(dir, "beta"), "---\nname: beta\ndescription: second\n---\nbeta\n")
+
+	loader := NewSkillsLoader("", []string{dir})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	var notified []*SkillChanges
+	loader.OnChange(func(c *SkillChanges) { notified = append(notified, c) })
+
+	changes, err := loader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() || len(notified) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	writeSkill(t, filepath.Join(dir, "alpha"), "---\nname: alpha\ndescription: edited\n---\nalpha\n")
+	writeSkill(t, filepath.Join(dir, "gamma"), "---\nname: gamma\n---\ngamma\n")
+	// A skill that can no longer run on this OS drops out like a deleted one
+	writeSkill(t, filepath.Join(dir, "beta"), "---\nname: beta\nmetadata:\n  openclaw:\n    requires:\n      os: [plan9-only]\n---\nbeta\n")
+
+	changes, err = loader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changes.Added, ",") != "gamma" || strings.Join(changes.Changed, ",") != "alpha" || strings.Join(changes.Removed, ",") != "beta" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if len(notified) != 1 {
+		t.Errorf("expected one notification, got %d", len(notified))
+	}
+	if skill, _ := loader.Get("alpha"); skill.Description != "edited" {
+		t.Errorf("expected the edited description, got %q", skill.Description)
+	}
+	if _, ok := loader.Get("beta"); ok {
+		t.Error("expected beta to be removed")
+	}
+}
+
+func TestSkillsWatcherReloadsOnChange(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "global")
+	missing := filepath.Join(root, "workspace", "skills")
+	writeSkill(t, filepath.Join(existing, "alpha"), "---\nname: alpha\ndescription: first\n---\nalpha\n")
+	if err := os.MkdirAll(filepath.Dir(missing), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewSkillsLoader("", []string{existing, missing})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan *SkillChanges, 10)
+	loader.OnChange(func(c *SkillChanges) { changed <- c })
+
+	watcher, err := NewSkillsWatcher(loader, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	wait := func() *SkillChanges {
+		t.Helper()
+		select {
+		case c := <-changed:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a reload")
+			return nil
+		}
+	}
+
+	writeSkill(t, filepath.Join(existing, "alpha"), "---\nname: alpha\ndescription: edited\n---\nalpha\n")
+	if c := wait(); strings.Join(c.Changed, ",") != "alpha" {
+		t.Errorf("expected alpha to change, got %+v", c)
+	}
+
+	// The workspace skills folder did not exist when the watcher started
+	if err := os.Mkdir(missing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(missing, "beta"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Give the watcher a moment to pick up the new directories
+	time.Sleep(200 * time.Millisecond)
+	writeSkill(t, filepath.Join(missing, "beta"), "---\nname: beta\n---\nbeta\n")
+	var added []string
+	for len(added) == 0 {
+		added = wait().Added
+	}
+	if strings.Join(added, ",") != "beta" {
+		t.Errorf("expected beta to be added, got %v", added)
+	}
+
+	if err := os.RemoveAll(filepath.Join(existing, "alpha")); err != nil {
+		t.Fatal(err)
+	}
+	if c := wait(); strings.Join(c.Removed, ",") != "alpha" {
+		t.Errorf("expected alpha to be removed, got %+v", c)
+	}
+}
+
+// reloadTool edits a skill and reloads the loader while a run is in flight
+type reloadTool struct {
+	loader *SkillsLoader
+	dir    string
+	t      *testing.T
+}
+
+func (reloadTool) Name() string               { return "reload" }
+func (reloadTool) Description() string        { return "Edit and reload skills" }
+func (reloadTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (reloadTool) Label() string              { return "Reload" }
+func (r reloadTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	writeSkill(r.t, r.dir, "---\nname: alpha\ndescription: after reload\n---\nalpha\n")
+	if _, err := r.loader.Reload(); err != nil {
+		return ToolResult{}, err
+	}
+	return ToolResult{Content: []ContentBlock{TextContent{Text: "reloaded"}}}, nil
+}
+
+func TestInFlightRunKeepsSkillSnapshot(t *testing.T) {
+	workspace := t.TempDir()
+	skillDir := filepath.Join(workspace, "skills", "alpha")
+	writeSkill(t, skillDir, "---\nname: alpha\ndescription: before reload\n---\nalpha\n")
+	loader := NewSkillsLoader("", []string{filepath.Dir(skillDir)})
+	if err := loader.Discover(); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "reload", Params: map[string]interface{}{}}}},
+		{Content: "done"},
+		{Content: "next turn"},
+	}}
+	state := NewAgentState()
+	state.Tools = []Tool{reloadTool{loader: loader, dir: skillDir, t: t}}
+	o := NewOrchestrator(&LoopConfig{
+		Provider:       provider,
+		MaxIterations:  5,
+		SkillsSource:   loader.List,
+		ContextBuilder: NewContextBuilder(NewMemoryStore(workspace), workspace),
+	}, state)
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "reload"}}}
+	if _, err := o.Run(context.Background(), []AgentMessage{prompt}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Run(context.Background(), []AgentMessage{prompt}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(provider.calls) != 3 {
+		t.Fatalf("expected 3 LLM calls, got %d", len(provider.calls))
+	}
+	for i, want := range []string{"before reload", "before reload", "after reload"} {
+		if !strings.Contains(provider.calls[i][0].Content, want) {
+			t.Errorf("call %d: expected the system prompt to describe the skill as %q", i, want)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,10 +17,17 @@ import (
 	"go.uber.org/zap"
 )
 
+// defaultSubagentMaxConcurrent 未配置时每个请求者会话的并发分身上限
+const defaultSubagentMaxConcurrent = 4
+
+// ErrSubagentLimit 请求者会话的并发分身数量已达上限
+var ErrSubagentLimit = errors.New("too many concurrent subagents for this session")
+
 // SubagentRunOutcome 分身运行结果
 type SubagentRunOutcome struct {
-	Status string `json:"status"` // ok, error, timeout, unknown
+	Status string `json:"status"` // ok, error, timeout, cancelled, unknown
 	Error  string `json:"error,omitempty"`
+	Output string `json:"output,omitempty"` // 分身的最终回复
 }
 
 // DeliveryContext 传递上下文
@@ -45,6 +55,14 @@ type SubagentRunRecord struct {
 	ArchiveAtMs         *int64              `json:"archive_at_ms,omitempty"`
 	CleanupCompletedAt  *int64              `json:"cleanup_completed_at,omitempty"`
 	CleanupHandled      bool                `json:"cleanup_handled"`
+	TimeoutSeconds      int                 `json:"timeout_seconds,omitempty"`
+	// Delivered 结果已通过 subagent_wait 交给请求者，不再宣告
+	Delivered bool `json:"delivered,omitempty"`
+}
+
+// Running 分身是否仍在运行
+func (r *SubagentRunRecord) Running() bool {
+	return r.Outcome == nil
 }
 
 // SubagentRegistry 分身注册表
@@ -57,6 +75,12 @@ type SubagentRegistry struct {
 	sweeperOnce sync.Once
 	// 事件回调
 	onRunComplete func(runID string, record *SubagentRunRecord)
+	// 每个请求者会话的并发分身上限，0 表示不限制
+	maxPerRequester int
+	// 正在等待各运行结果的 subagent_wait 调用数
+	waiting map[string]int
+	// 任一运行完成时关闭并替换，用于唤醒等待者
+	changed chan struct{}
 }
 
 // NewSubagentRegistry 创建分身注册表
@@ -66,14 +90,27 @@ func NewSubagentRegistry(dataDir string) *SubagentRegistry {
 		runs:      make(map[string]*SubagentRunRecord),
 		dataDir:   dataDir,
 		storeFile: storeFile,
+		waiting:   make(map[string]int),
+		changed:   make(chan struct{}),
 	}
 }
 
+// SetMaxConcurrentPerRequester 设置每个请求者会话的并发分身上限
+func (r *SubagentRegistry) SetMaxConcurrentPerRequester(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPerRequester = n
+}
+
 // RegisterRun 注册分身运行
 func (r *SubagentRegistry) RegisterRun(params *SubagentRunParams) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.maxPerRequester > 0 && r.activeForRequesterLocked(params.RequesterSessionKey) >= r.maxPerRequester {
+		return fmt.Errorf("%w (limit %d)", ErrSubagentLimit, r.maxPerRequester)
+	}
+
 	now := time.Now().UnixMilli()
 	archiveAfterMs := int64(params.ArchiveAfterMinutes) * 60_000
 	var archiveAtMs *int64
@@ -95,6 +132,7 @@ func (r *SubagentRegistry) RegisterRun(params *SubagentRunParams) error {
 		StartedAt:           &now,
 		ArchiveAtMs:         archiveAtMs,
 		CleanupHandled:      false,
+		TimeoutSeconds:      params.TimeoutSeconds,
 	}
 
 	r.runs[params.RunID] = record
@@ -128,6 +166,7 @@ type SubagentRunParams struct {
 	Cleanup             string
 	Label               string
 	ArchiveAfterMinutes int
+	TimeoutSeconds      int
 }
 
 // GetRun 获取运行记录
@@ -157,6 +196,23 @@ func (r *SubagentRegistry) ListRunsForRequester(requesterSessionKey string) []*S
 	return result
 }
 
+// SnapshotForRequester 返回请求者所有分身运行的副本，按创建时间排序
+func (r *SubagentRegistry) SnapshotForRequester(requesterSessionKey string) []SubagentRunRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []SubagentRunRecord
+	for _, record := range r.runs {
+		if record.RequesterSessionKey == requesterSessionKey {
+			result = append(result, *record)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt < result[j].CreatedAt
+	})
+	return result
+}
+
 // MarkCompleted 标记分身运行完成
 func (r *SubagentRegistry) MarkCompleted(runID string, outcome *SubagentRunOutcome, endedAt *int64) error {
 	r.mu.Lock()
@@ -170,6 +226,10 @@ func (r *SubagentRegistry) MarkCompleted(runID string, outcome *SubagentRunOutco
 	record.EndedAt = endedAt
 	record.Outcome = outcome
 
+	// 唤醒等待者
+	close(r.changed)
+	r.changed = make(chan struct{})
+
 	// 保存到磁盘
 	if err := r.saveToDisk(); err != nil {
 		logger.Error("Failed to save subagent registry", zap.Error(err))
@@ -183,6 +243,102 @@ func (r *SubagentRegistry) MarkCompleted(runID string, outcome *SubagentRunOutco
 	return nil
 }
 
+// ActiveForRequester 返回请求者会话中仍在运行的分身数量
+func (r *SubagentRegistry) ActiveForRequester(requesterSessionKey string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.activeForRequesterLocked(requesterSessionKey)
+}
+
+func (r *SubagentRegistry) activeForRequesterLocked(requesterSessionKey string) int {
+	count := 0
+	for _, record := range r.runs {
+		if record.RequesterSessionKey == requesterSessionKey && record.Running() {
+			count++
+		}
+	}
+	return count
+}
+
+// Wait 等待请求者的分身运行结束
+// any 为 true 时任一运行结束即返回，否则等待全部结束；ctx 结束时返回当前快照和 ctx 的错误。
+// 不属于该请求者的运行 ID 会被忽略。
+func (r *SubagentRegistry) Wait(ctx context.Context, requesterSessionKey string, runIDs []string, any bool) ([]SubagentRunRecord, error) {
+	r.mu.Lock()
+	ids := make([]string, 0, len(runIDs))
+	for _, id := range runIDs {
+		if record, ok := r.runs[id]; ok && record.RequesterSessionKey == requesterSessionKey {
+			ids = append(ids, id)
+			r.waiting[id]++
+		}
+	}
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		for _, id := range ids {
+			if r.waiting[id]--; r.waiting[id] <= 0 {
+				delete(r.waiting, id)
+			}
+		}
+		r.mu.Unlock()
+	}()
+
+	for {
+		r.mu.RLock()
+		snapshot := make([]SubagentRunRecord, 0, len(ids))
+		finished := 0
+		for _, id := range ids {
+			if record, ok := r.runs[id]; ok {
+				snapshot = append(snapshot, *record)
+				if !record.Running() {
+					finished++
+				}
+			}
+		}
+		changed := r.changed
+		r.mu.RUnlock()
+
+		if finished == len(snapshot) || (any && finished > 0) {
+			r.markDelivered(snapshot)
+			return snapshot, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			r.markDelivered(snapshot)
+			return snapshot, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// markDelivered records that the finished runs in snapshot were handed to the requester
+func (r *SubagentRegistry) markDelivered(snapshot []SubagentRunRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range snapshot {
+		if snapshot[i].Running() {
+			continue
+		}
+		snapshot[i].Delivered = true
+		if record, ok := r.runs[snapshot[i].RunID]; ok {
+			record.Delivered = true
+		}
+	}
+	if err := r.saveToDisk(); err != nil {
+		logger.Error("Failed to save subagent registry", zap.Error(err))
+	}
+}
+
+// IsDelivered 结果是否已经交给请求者
+func (r *SubagentRegistry) IsDelivered(runID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	record, ok := r.runs[runID]
+	return ok && record.Delivered
+}
+
 // ReleaseRun 释放运行记录
 func (r *SubagentRegistry) ReleaseRun(runID string) {
 	r.mu.Lock()
@@ -235,6 +391,15 @@ func (r *SubagentRegistry) LoadFromDisk() error {
 
 	r.runs = loaded
 
+	// 上次进程退出时仍在运行的分身已经中断
+	now := time.Now().UnixMilli()
+	for _, record := range r.runs {
+		if record.Running() {
+			record.Outcome = &SubagentRunOutcome{Status: "unknown", Error: "interrupted by restart"}
+			record.EndedAt = &now
+		}
+	}
+
 	// 恢复有归档时间的运行记录的清理器
 	for _, record := range r.runs {
 		if record.ArchiveAtMs != nil {
@@ -346,7 +511,8 @@ func (r *SubagentRegistry) Cleanup(runID string, cleanup string, didAnnounce boo
 		return
 	}
 
-	if cleanup == "delete" {
+	// 仍有等待者时保留记录，交给清理器归档
+	if cleanup == "delete" && r.waiting[runID] == 0 {
 		delete(r.runs, runID)
 		// 先置 nil 再关闭，防止多次关闭导致 panic
 		if len(r.runs) == 0 && r.sweeperStop != nil {
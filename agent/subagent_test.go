@@ -0,0 +1,351 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+var subagentTaskPattern = regexp.MustCompile(`You were created to handle: (.*)`)
+
+// fanoutProvider answers subagent turns with child and parent turns with
+// parent. Unlike scriptedProvider it is safe to call concurrently.
+type fanoutProvider struct {
+	mu            sync.Mutex
+	child         func(ctx context.Context, task string) (*providers.Response, error)
+	parent        func(messages []providers.Message) *providers.Response
+	childRequests [][]providers.Message
+}
+
+func (p *fanoutProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			if m := subagentTaskPattern.FindStringSubmatch(msg.Content); m != nil {
+				p.mu.Lock()
+				p.childRequests = append(p.childRequests, messages)
+				p.mu.Unlock()
+				return p.child(ctx, m[1])
+			}
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parent(messages), nil
+}
+
+func (p *fanoutProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *fanoutProvider) Close() error {
+	return nil
+}
+
+// blockUntilDone is a child that never answers on its own
+func blockUntilDone(ctx context.Context, task string) (*providers.Response, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newSubagentTestManager(t *testing.T, provider providers.Provider, maxConcurrent int) *AgentManager {
+	t.Helper()
+	workspace := t.TempDir()
+	sessionMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        bus.NewMessageBus(10),
+		Provider:   provider,
+		SessionMgr: sessionMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    workspace,
+	})
+	cfg := &config.Config{}
+	cfg.Workspace.Path = workspace
+	cfg.Agents.Defaults.Subagents = &config.SubagentsConfig{MaxConcurrent: maxConcurrent}
+	cfg.Agents.List = []config.AgentConfig{{ID: "main", Default: true}}
+	if err := mgr.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+	// Completion handlers announce and persist in the background; let them
+	// finish before the temporary directories are removed
+	t.Cleanup(func() { waitForSubagentCleanup(t, mgr) })
+	return mgr
+}
+
+// waitForSubagentCleanup waits until every run finished and was cleaned up
+func waitForSubagentCleanup(t *testing.T, mgr *AgentManager) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pending := 0
+		mgr.subagentRegistry.mu.RLock()
+		for _, record := range mgr.subagentRegistry.runs {
+			if record.Running() || record.CleanupCompletedAt == nil {
+				pending++
+			}
+		}
+		mgr.subagentRegistry.mu.RUnlock()
+		if pending == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("subagent runs were not cleaned up in time")
+}
+
+func spawnSubagent(t *testing.T, mgr *AgentManager, ctx context.Context, params map[string]interface{}) string {
+	t.Helper()
+	out, err := mgr.tools.Execute(ctx, "sessions_spawn", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`Run ID: ([0-9a-f-]+)`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("spawn failed: %s", out)
+	}
+	return m[1]
+}
+
+func waitForOutcome(t *testing.T, mgr *AgentManager, runID string) *SubagentRunOutcome {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	records, err := mgr.subagentRegistry.Wait(ctx, "parent", []string{runID}, false)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("run %s did not finish: %v", runID, err)
+	}
+	return records[0].Outcome
+}
+
+func TestSubagentFanOutAndWait(t *testing.T) {
+	var waitResult string
+	provider := &fanoutProvider{
+		child: func(ctx context.Context, task string) (*providers.Response, error) {
+			return &providers.Response{Content: "found " + task}, nil
+		},
+		parent: func(messages []providers.Message) *providers.Response {
+			last := messages[len(messages)-1]
+			switch {
+			case last.Role == "user":
+				var calls []providers.ToolCall
+				for i, topic := range []string{"alpha", "beta", "gamma"} {
+					calls = append(calls, providers.ToolCall{
+						ID:     "spawn_" + topic,
+						Name:   "sessions_spawn",
+						Params: map[string]interface{}{"task": topic, "label": topic, "cleanup": []string{"keep", "delete", "keep"}[i]},
+					})
+				}
+				return &providers.Response{ToolCalls: calls}
+			case last.ToolName == "sessions_spawn":
+				return &providers.Response{ToolCalls: []providers.ToolCall{{ID: "wait", Name: "subagent_wait", Params: map[string]interface{}{"timeout_seconds": float64(5)}}}}
+			default:
+				waitResult = last.Content
+				return &providers.Response{Content: "summary"}
+			}
+		},
+	}
+	mgr := newSubagentTestManager(t, provider, 0)
+
+	result, err := mgr.Chat(context.Background(), &ChatRequest{
+		SessionKey: "parent",
+		Message:    AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "research"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractTextContent(result.Reply) != "summary" {
+		t.Fatalf("unexpected reply %+v", result.Reply)
+	}
+
+	var waited struct {
+		Completed []tools.SubagentRunStatus `json:"completed"`
+		Pending   []tools.SubagentRunStatus `json:"pending"`
+		TimedOut  bool                      `json:"timed_out"`
+	}
+	if err := json.Unmarshal([]byte(waitResult), &waited); err != nil {
+		t.Fatalf("invalid subagent_wait result %q: %v", waitResult, err)
+	}
+	if len(waited.Completed) != 3 || len(waited.Pending) != 0 || waited.TimedOut {
+		t.Fatalf("expected all runs to complete, got %+v", waited)
+	}
+	for _, run := range waited.Completed {
+		if run.Status != "ok" || run.Output != "found "+run.Label {
+			t.Errorf("unexpected run %+v", run)
+		}
+	}
+
+	// Collected results are not announced to the requester a second time
+	for _, record := range mgr.subagentRegistry.SnapshotForRequester("parent") {
+		if !record.Delivered {
+			t.Errorf("expected run %s to be marked delivered", record.RunID)
+		}
+	}
+
+	status, err := mgr.tools.Execute(tools.WithSessionKey(context.Background(), "parent"), "subagent_status", map[string]interface{}{})
+	if err != nil || !strings.Contains(status, `"running": 0`) {
+		t.Errorf("unexpected status %s (%v)", status, err)
+	}
+	other, _ := mgr.tools.Execute(tools.WithSessionKey(context.Background(), "other"), "subagent_status", map[string]interface{}{})
+	if strings.Contains(other, "alpha") {
+		t.Errorf("runs of another session leaked: %s", other)
+	}
+}
+
+func TestSubagentConcurrencyCap(t *testing.T) {
+	mgr := newSubagentTestManager(t, &fanoutProvider{child: blockUntilDone}, 2)
+	parent, cancel := context.WithCancelCause(tools.WithSessionKey(context.Background(), "parent"))
+	defer cancel(nil)
+	ctx := context.WithValue(parent, runContextKey, parent)
+
+	first := spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "one"})
+	spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "two"})
+
+	out, _ := mgr.tools.Execute(ctx, "sessions_spawn", map[string]interface{}{"task": "three"})
+	if !strings.Contains(out, ErrSubagentLimit.Error()) {
+		t.Fatalf("expected the third spawn to hit the limit, got %s", out)
+	}
+	if got := mgr.subagentRegistry.ActiveForRequester("parent"); got != 2 {
+		t.Errorf("expected 2 active runs, got %d", got)
+	}
+
+	// Another session has its own budget
+	otherCtx := tools.WithSessionKey(context.Background(), "other")
+	out, _ = mgr.tools.Execute(otherCtx, "sessions_spawn", map[string]interface{}{"task": "four", "run_timeout_seconds": float64(1)})
+	if !strings.Contains(out, "Run ID") {
+		t.Errorf("expected another session to spawn, got %s", out)
+	}
+
+	// Cancelling the parent run cancels its children
+	cancel(context.Canceled)
+	if outcome := waitForOutcome(t, mgr, first); outcome.Status != "cancelled" {
+		t.Errorf("expected cancelled outcome, got %+v", outcome)
+	}
+	spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "five"})
+}
+
+func TestSubagentTimeoutAndFinishedParent(t *testing.T) {
+	release := make(chan struct{})
+	mgr := newSubagentTestManager(t, &fanoutProvider{
+		child: func(ctx context.Context, task string) (*providers.Response, error) {
+			if task == "slow" {
+				return blockUntilDone(ctx, task)
+			}
+			select {
+			case <-release:
+				return &providers.Response{Content: "late result"}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}, 0)
+	parent, cancel := context.WithCancelCause(tools.WithSessionKey(context.Background(), "parent"))
+	ctx := context.WithValue(parent, runContextKey, parent)
+
+	slow := spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "slow", "run_timeout_seconds": float64(1)})
+	outlives := spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "background"})
+
+	// A parent turn that ends normally leaves its children running
+	cancel(errRunFinished)
+	if outcome := waitForOutcome(t, mgr, slow); outcome.Status != "timeout" {
+		t.Errorf("expected timeout outcome, got %+v", outcome)
+	}
+	close(release)
+	if outcome := waitForOutcome(t, mgr, outlives); outcome.Status != "ok" || outcome.Output != "late result" {
+		t.Errorf("expected the child to outlive the finished parent, got %+v", outcome)
+	}
+}
+
+func TestSubagentChildPrompt(t *testing.T) {
+	provider := &fanoutProvider{
+		child: func(ctx context.Context, task string) (*providers.Response, error) {
+			return &providers.Response{Content: "done"}, nil
+		},
+	}
+	mgr := newSubagentTestManager(t, provider, 0)
+	ctx := tools.WithSessionKey(context.Background(), "parent")
+
+	runID := spawnSubagent(t, mgr, ctx, map[string]interface{}{"task": "summarize the changelog"})
+	if outcome := waitForOutcome(t, mgr, runID); outcome.Status != "ok" {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.childRequests) != 1 {
+		t.Fatalf("expected one child request, got %d", len(provider.childRequests))
+	}
+	var system, user []string
+	for _, msg := range provider.childRequests[0] {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "user":
+			user = append(user, msg.Content)
+		}
+	}
+	if !strings.Contains(strings.Join(system, "\n"), "You were created to handle: summarize the changelog") {
+		t.Errorf("expected the subagent instructions in the system prompt, got %q", system)
+	}
+	if len(user) != 1 || user[0] != "summarize the changelog" {
+		t.Errorf("expected the task as the only user message, got %q", user)
+	}
+}
+
+// stalledTracker reports one run that never finishes
+type stalledTracker struct{}
+
+func (stalledTracker) ListRuns(requester string) []tools.SubagentRunStatus {
+	return []tools.SubagentRunStatus{{RunID: "run-1", Status: "running"}}
+}
+
+func (stalledTracker) WaitRuns(ctx context.Context, requester string, runIDs []string, any bool) ([]tools.SubagentRunStatus, error) {
+	<-ctx.Done()
+	return []tools.SubagentRunStatus{{RunID: "run-1", Status: "running"}}, ctx.Err()
+}
+
+func TestSubagentWaitOutlivesToolTimeout(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{ToolCalls: []providers.ToolCall{{ID: "wait", Name: "subagent_wait", Params: map[string]interface{}{"timeout_seconds": float64(1)}}}},
+		{Content: "done"},
+	}}
+	state := NewAgentState()
+	state.Tools = ToAgentTools([]tools.Tool{tools.NewSubagentWaitTool(stalledTracker{})})
+	// The wait is longer than the orchestrator's tool timeout; the tool must
+	// still report its own timed_out result instead of being cut off
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3, ToolTimeout: 100 * time.Millisecond}, state)
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "wait"}}}
+	messages, err := o.Run(context.Background(), []AgentMessage{prompt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result string
+	for _, msg := range messages {
+		if msg.Role == RoleToolResult {
+			result = extractTextContent(msg)
+		}
+	}
+	var waited struct {
+		Pending  []tools.SubagentRunStatus `json:"pending"`
+		TimedOut bool                      `json:"timed_out"`
+	}
+	if err := json.Unmarshal([]byte(result), &waited); err != nil {
+		t.Fatalf("expected the subagent_wait result, got %q: %v", result, err)
+	}
+	if !waited.TimedOut || len(waited.Pending) != 1 {
+		t.Errorf("expected a timed out wait with one pending run, got %+v", waited)
+	}
+}
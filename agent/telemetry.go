@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startInboundSpan starts the root span of an inbound channel message
+func startInboundSpan(ctx context.Context, msg *bus.InboundMessage, agentID string) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "agent.inbound",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("goclaw.agent_id", agentID),
+			attribute.String("goclaw.channel", msg.Channel),
+			attribute.String("goclaw.account_id", msg.AccountID),
+			attribute.String("goclaw.chat_id", msg.ChatID),
+			attribute.String("goclaw.message_id", msg.ID),
+		))
+}
+
+// startRunSpan starts the span covering one orchestrator run
+func (o *Orchestrator) startRunSpan(ctx context.Context, state *AgentState) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String("goclaw.session_key", state.SessionKey)}
+	if agentID, ok := ctx.Value(AgentIDContextKey).(string); ok {
+		attrs = append(attrs, attribute.String("goclaw.agent_id", agentID))
+	}
+	return telemetry.Tracer().Start(ctx, "agent.run", trace.WithAttributes(attrs...))
+}
+
+// startProviderSpan starts the span of a single LLM call
+func (o *Orchestrator) startProviderSpan(ctx context.Context, messages, tools int, streaming bool) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "llm.chat",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", providerLabel(o.config.Provider)),
+			attribute.Int("goclaw.llm.messages", messages),
+			attribute.Int("goclaw.llm.tools", tools),
+			attribute.Bool("goclaw.llm.streaming", streaming),
+		))
+}
+
+// endProviderSpan records the metrics of an LLM call and ends its span
+func (o *Orchestrator) endProviderSpan(span trace.Span, start time.Time, usage *providers.Usage, err error) {
+	label := providerLabel(o.config.Provider)
+	telemetry.RecordProviderCall(label, time.Since(start), err)
+
+	if usage != nil {
+		telemetry.RecordProviderTokens(label, "prompt", usage.PromptTokens)
+		telemetry.RecordProviderTokens(label, "completion", usage.CompletionTokens)
+		telemetry.RecordProviderTokens(label, "cache_read", usage.CacheReadInputTokens)
+		telemetry.RecordProviderTokens(label, "cache_creation", usage.CacheCreationInputTokens)
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+		)
+	}
+	if err != nil {
+		span.SetAttributes(attribute.String("goclaw.failover_reason", telemetry.FailoverReason(err)))
+	}
+	telemetry.EndSpan(span, err)
+}
+
+// startToolSpan starts the span of a single tool call
+func startToolSpan(ctx context.Context, tc ToolCallContent) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "tool.execute", trace.WithAttributes(
+		attribute.String("gen_ai.tool.name", tc.Name),
+		attribute.String("gen_ai.tool.call.id", tc.ID),
+	))
+}
+
+// providerLabel derives a short provider name for metric labels, e.g. "anthropic" for *providers.AnthropicProvider
+func providerLabel(p providers.Provider) string {
+	if p == nil {
+		return "none"
+	}
+	name := fmt.Sprintf("%T", p)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "Provider")
+	if name == "" {
+		return "unknown"
+	}
+	return strings.ToLower(name)
+}
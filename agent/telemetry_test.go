@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/telemetry"
+	"go.opentelemetry.io/otel"
+)
+
+// failingProvider always fails with the configured error
+type failingProvider struct {
+	err error
+}
+
+func (p *failingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return nil, p.err
+}
+
+func (p *failingProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *failingProvider) Close() error {
+	return nil
+}
+
+func TestOrchestratorSpansLinkInboundToProviderAndTools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := telemetry.NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := telemetry.NewTracerProvider(exporter, config.TracingConfig{ServiceName: "goclaw-test"})
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(previous)
+
+	provider := &scriptedProvider{responses: []*providers.Response{
+		{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "echo", Params: map[string]interface{}{"text": "hi"}}}, Usage: providers.Usage{PromptTokens: 10, CompletionTokens: 3}},
+		{Content: "done"},
+	}}
+	state := NewAgentState()
+	state.Tools = []Tool{echoTool{}}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 5}, state)
+
+	msg := &bus.InboundMessage{ID: "msg-1", Channel: "telegram", ChatID: "42"}
+	ctx, span := startInboundSpan(context.Background(), msg, "main")
+	if _, err := o.Run(ctx, []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans, err := telemetry.ReadSpanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string][]telemetry.Span)
+	for _, s := range spans {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	if len(byName["agent.inbound"]) != 1 || len(byName["agent.run"]) != 1 || len(byName["llm.chat"]) != 2 || len(byName["tool.execute"]) != 1 {
+		t.Fatalf("unexpected spans %v", byName)
+	}
+
+	inbound := byName["agent.inbound"][0]
+	run := byName["agent.run"][0]
+	if inbound.Attribute("goclaw.channel") != "telegram" || inbound.Attribute("goclaw.message_id") != "msg-1" {
+		t.Errorf("unexpected inbound attributes %+v", inbound.Attributes)
+	}
+	if run.ParentSpanID != inbound.SpanID {
+		t.Errorf("run span should be a child of the inbound span")
+	}
+	for _, s := range append(byName["llm.chat"], byName["tool.execute"]...) {
+		if s.TraceID != inbound.TraceID || s.ParentSpanID != run.SpanID {
+			t.Errorf("span %s is not linked to the run span", s.Name)
+		}
+	}
+	if got := byName["tool.execute"][0].Attribute("gen_ai.tool.name"); got != "echo" {
+		t.Errorf("unexpected tool name %q", got)
+	}
+	if got := byName["llm.chat"][0].Attribute("gen_ai.usage.input_tokens"); got != "10" {
+		t.Errorf("unexpected input tokens %q", got)
+	}
+}
+
+func TestProviderErrorsAreLabelledByFailoverReason(t *testing.T) {
+	o := NewOrchestrator(&LoopConfig{Provider: &failingProvider{err: errors.New("429 too many requests")}, MaxIterations: 1}, NewAgentState())
+	if _, err := o.Run(context.Background(), []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}}); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+
+	families, err := telemetry.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "goclaw_provider_errors_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["provider"] == "failing" && labels["reason"] == "rate_limit" && metric.GetCounter().GetValue() >= 1 {
+				return
+			}
+		}
+	}
+	t.Error("expected a rate_limit provider error for the failing provider")
+}
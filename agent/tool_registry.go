@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/smallnest/goclaw/agent/tools"
 )
@@ -110,6 +111,11 @@ func (a *toolAdapter) Execute(ctx context.Context, params map[string]any, onUpda
 		existingParams[k] = v
 	}
 
+	// Prefer streaming execution so partial output reaches the UI
+	if st, ok := a.tool.(tools.StreamingTool); ok {
+		return a.executeStreaming(ctx, st, existingParams, onUpdate)
+	}
+
 	// Execute using existing tool
 	resultStr, err := a.tool.Execute(ctx, existingParams)
 
@@ -131,6 +137,51 @@ func (a *toolAdapter) Execute(ctx context.Context, params map[string]any, onUpda
 	return result, nil
 }
 
+// ExecutionTimeout forwards the time limit of tools that enforce their own
+func (a *toolAdapter) ExecutionTimeout(params map[string]any) time.Duration {
+	if tt, ok := a.tool.(tools.TimedTool); ok {
+		return tt.ExecutionTimeout(params)
+	}
+	return 0
+}
+
+// executeStreaming runs a streaming tool and converts its results to agent format
+func (a *toolAdapter) executeStreaming(ctx context.Context, st tools.StreamingTool, params map[string]interface{}, onUpdate func(ToolResult)) (ToolResult, error) {
+	var update func(tools.ToolResult)
+	if onUpdate != nil {
+		update = func(partial tools.ToolResult) {
+			onUpdate(fromExistingToolResult(partial))
+		}
+	}
+
+	existing, err := st.ExecuteWithStreaming(ctx, params, update)
+	if err != nil {
+		return ToolResult{Error: err, Details: map[string]any{"error": err.Error()}}, nil
+	}
+	return fromExistingToolResult(existing), nil
+}
+
+// fromExistingToolResult converts tools.ToolResult to ToolResult
+func fromExistingToolResult(r tools.ToolResult) ToolResult {
+	result := ToolResult{
+		Content: make([]ContentBlock, 0, len(r.Content)),
+		Details: make(map[string]any),
+		Error:   r.Error,
+	}
+	for _, block := range r.Content {
+		switch b := block.(type) {
+		case tools.TextContent:
+			result.Content = append(result.Content, TextContent{Text: b.Text})
+		case tools.ImageContent:
+			result.Content = append(result.Content, ImageContent{URL: b.URL, Data: b.Data, MimeType: b.MimeType})
+		}
+	}
+	for k, v := range r.Details {
+		result.Details[k] = v
+	}
+	return result
+}
+
 // ToExistingTools converts agent tools to existing tools.Tool format
 func ToExistingTools(agentTools []Tool) []tools.Tool {
 	result := make([]tools.Tool, 0, len(agentTools))
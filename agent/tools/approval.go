@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// ApprovalRequest 审批请求
+type ApprovalRequest struct {
+	Tool       string      `json:"tool"`
+	Command    string      `json:"command"`
+	Reason     string      `json:"reason"`
+	Risks      []ShellRisk `json:"risks,omitempty"`
+	SessionKey string      `json:"session_key,omitempty"`
+	AgentID    string      `json:"agent_id,omitempty"`
+}
+
+// Approver 审批者，决定是否放行需要审批的操作
+type Approver interface {
+	RequestApproval(ctx context.Context, req ApprovalRequest) (bool, error)
+}
+
+// ApproverFunc 函数形式的审批者
+type ApproverFunc func(ctx context.Context, req ApprovalRequest) (bool, error)
+
+// RequestApproval 实现 Approver 接口
+func (f ApproverFunc) RequestApproval(ctx context.Context, req ApprovalRequest) (bool, error) {
+	return f(ctx, req)
+}
+
+// ConfigApprover 基于 approvals 配置的审批者
+// behavior 为 auto 时自动放行；prompt 时交给 prompt 函数（未提供时按 manual 处理）；manual 或未设置时拒绝
+// allowlist 中的工具名放行该工具不带命令的请求；Shell 命令只能由 "shell:<命令前缀>" 条目放行，
+// 且命令中的每个调用（管道、命令列表、子 shell 等）都必须匹配某个前缀
+type ConfigApprover struct {
+	cfg    config.ApprovalsConfig
+	prompt ApproverFunc
+}
+
+// NewConfigApprover 创建基于配置的审批者
+func NewConfigApprover(cfg config.ApprovalsConfig, prompt ApproverFunc) *ConfigApprover {
+	return &ConfigApprover{cfg: cfg, prompt: prompt}
+}
+
+// RequestApproval 实现 Approver 接口
+func (a *ConfigApprover) RequestApproval(ctx context.Context, req ApprovalRequest) (bool, error) {
+	if a.allowlisted(req) {
+		return true, nil
+	}
+
+	switch a.cfg.Behavior {
+	case "auto":
+		return true, nil
+	case "prompt":
+		if a.prompt != nil {
+			return a.prompt(ctx, req)
+		}
+	}
+	return false, nil
+}
+
+// allowlisted 判断请求是否命中审批白名单
+func (a *ConfigApprover) allowlisted(req ApprovalRequest) bool {
+	if req.Command == "" {
+		return slices.Contains(a.cfg.Allowlist, req.Tool)
+	}
+
+	var prefixes [][]string
+	for _, entry := range a.cfg.Allowlist {
+		if prefix, ok := strings.CutPrefix(entry, "shell:"); ok {
+			if words := strings.Fields(prefix); len(words) > 0 {
+				prefixes = append(prefixes, words)
+			}
+		}
+	}
+	if len(prefixes) == 0 {
+		return false
+	}
+
+	invocations, err := ParseShellCommand(req.Command)
+	if err != nil || len(invocations) == 0 {
+		return false
+	}
+	for _, inv := range invocations {
+		if !slices.ContainsFunc(prefixes, func(words []string) bool { return invocationHasPrefix(inv, words) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// invocationHasPrefix reports whether the invocation's argv starts with the given words
+func invocationHasPrefix(inv ShellInvocation, words []string) bool {
+	if inv.Dynamic() || (words[0] != inv.Name && words[0] != inv.Path) || len(inv.Args) < len(words)-1 {
+		return false
+	}
+	return slices.Equal(inv.Args[:len(words)-1], words[1:])
+}
+
+// FormatApprovalRequest 格式化审批请求用于展示
+func FormatApprovalRequest(req ApprovalRequest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s wants to run:\n  %s\n", req.Tool, req.Command)
+	if len(req.Risks) > 0 {
+		sb.WriteString("Risks:\n")
+		for _, r := range req.Risks {
+			fmt.Fprintf(&sb, "  - [%s] %s\n", r.Category, r.Reason)
+		}
+	} else if req.Reason != "" {
+		fmt.Fprintf(&sb, "Reason: %s\n", req.Reason)
+	}
+	return sb.String()
+}
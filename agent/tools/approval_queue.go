@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PendingApproval 等待处理的审批请求
+type PendingApproval struct {
+	ID        string          `json:"id"`
+	Request   ApprovalRequest `json:"request"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	result chan bool
+}
+
+// ApprovalQueue 审批队列，请求在队列中等待外部（如网关 approvals.resolve）放行或拒绝
+// 调用方的上下文结束（例如工具超时）时请求按拒绝处理并移出队列
+type ApprovalQueue struct {
+	mu        sync.Mutex
+	pending   map[string]*PendingApproval
+	onRequest func(PendingApproval)
+}
+
+// NewApprovalQueue 创建审批队列
+func NewApprovalQueue() *ApprovalQueue {
+	return &ApprovalQueue{pending: make(map[string]*PendingApproval)}
+}
+
+// OnRequest 设置新请求入队时的回调，用于通知审批方
+func (q *ApprovalQueue) OnRequest(fn func(PendingApproval)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onRequest = fn
+}
+
+// RequestApproval 实现 Approver 接口，阻塞直到请求被处理或上下文结束
+func (q *ApprovalQueue) RequestApproval(ctx context.Context, req ApprovalRequest) (bool, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return false, fmt.Errorf("failed to generate approval id: %w", err)
+	}
+	p := &PendingApproval{
+		ID:        hex.EncodeToString(b[:]),
+		Request:   req,
+		CreatedAt: time.Now(),
+		result:    make(chan bool, 1),
+	}
+
+	q.mu.Lock()
+	q.pending[p.ID] = p
+	notify := q.onRequest
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.pending, p.ID)
+		q.mu.Unlock()
+	}()
+
+	if notify != nil {
+		notify(*p)
+	}
+
+	select {
+	case approved := <-p.result:
+		return approved, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Pending 返回等待处理的请求，按创建时间排序
+func (q *ApprovalQueue) Pending() []PendingApproval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]PendingApproval, 0, len(q.pending))
+	for _, p := range q.pending {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Resolve 放行或拒绝等待中的请求
+func (q *ApprovalQueue) Resolve(id string, approved bool) error {
+	q.mu.Lock()
+	p, ok := q.pending[id]
+	if ok {
+		delete(q.pending, id)
+	}
+	q.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("approval request not found: %s", id)
+	}
+	p.result <- approved
+	return nil
+}
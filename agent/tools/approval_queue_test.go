@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestApprovalQueue(t *testing.T) {
+	q := NewApprovalQueue()
+	requested := make(chan PendingApproval, 1)
+	q.OnRequest(func(p PendingApproval) { requested <- p })
+
+	result := make(chan bool, 1)
+	go func() {
+		ok, _ := q.RequestApproval(context.Background(), ApprovalRequest{Tool: "run_shell", Command: "rm -rf build"})
+		result <- ok
+	}()
+
+	p := <-requested
+	if pending := q.Pending(); len(pending) != 1 || pending[0].ID != p.ID || pending[0].Request.Command != "rm -rf build" {
+		t.Fatalf("unexpected pending requests %+v", pending)
+	}
+	if err := q.Resolve(p.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !<-result {
+		t.Error("expected the resolved request to be approved")
+	}
+	if err := q.Resolve(p.ID, true); err == nil {
+		t.Error("expected resolving twice to fail")
+	}
+	if len(q.Pending()) != 0 {
+		t.Error("expected the queue to be empty")
+	}
+
+	// Requests that nobody answers are rejected when the caller gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if ok, err := q.RequestApproval(ctx, ApprovalRequest{Tool: "run_shell", Command: "sudo ls"}); ok || err == nil {
+		t.Errorf("expected a timed out request to be rejected, got %v, %v", ok, err)
+	}
+	<-requested
+	if len(q.Pending()) != 0 {
+		t.Error("expected the timed out request to leave the queue")
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // ContentBlock represents a block of content in a message
@@ -52,6 +53,27 @@ type Tool interface {
 	Execute(ctx context.Context, params map[string]interface{}) (string, error)
 }
 
+// StreamingTool 支持增量输出的工具
+// 执行过程中通过 onUpdate 回调推送部分结果（例如命令输出）
+type StreamingTool interface {
+	Tool
+
+	// ExecuteWithStreaming 执行工具并推送增量结果
+	ExecuteWithStreaming(ctx context.Context, params map[string]interface{}, onUpdate func(ToolResult)) (ToolResult, error)
+}
+
+// TimedTool 自行限制执行时长的工具
+// 编排器对单个工具的超时至少放宽到 ExecutionTimeout 返回的时长，工具可以在自己的时限到达时返回结果
+type TimedTool interface {
+	Tool
+
+	// ExecutionTimeout 返回按给定参数执行时的最长时长，0 表示使用默认超时
+	ExecutionTimeout(params map[string]interface{}) time.Duration
+}
+
+// StreamFunc 流式执行函数
+type StreamFunc func(ctx context.Context, params map[string]interface{}, onUpdate func(ToolResult)) (string, error)
+
 // ToolCall 工具调用
 type ToolCall struct {
 	ID       string                 `json:"id"`
@@ -67,6 +89,7 @@ type BaseTool struct {
 	description string
 	parameters  map[string]interface{}
 	executeFunc func(ctx context.Context, params map[string]interface{}) (string, error)
+	streamFunc  StreamFunc
 }
 
 // NewBaseTool 创建基础工具
@@ -79,6 +102,19 @@ func NewBaseTool(name, description string, parameters map[string]interface{}, ex
 	}
 }
 
+// NewStreamingBaseTool 创建支持增量输出的基础工具
+func NewStreamingBaseTool(name, description string, parameters map[string]interface{}, streamFunc StreamFunc) *BaseTool {
+	return &BaseTool{
+		name:        name,
+		description: description,
+		parameters:  parameters,
+		executeFunc: func(ctx context.Context, params map[string]interface{}) (string, error) {
+			return streamFunc(ctx, params, nil)
+		},
+		streamFunc: streamFunc,
+	}
+}
+
 // Name 返回工具名称
 func (t *BaseTool) Name() string {
 	return t.name
@@ -101,7 +137,13 @@ func (t *BaseTool) Execute(ctx context.Context, params map[string]interface{}) (
 
 // ExecuteWithStreaming executes the tool with streaming support (new agent compatibility)
 func (t *BaseTool) ExecuteWithStreaming(ctx context.Context, params map[string]interface{}, onUpdate func(ToolResult)) (ToolResult, error) {
-	resultStr, err := t.executeFunc(ctx, params)
+	var resultStr string
+	var err error
+	if t.streamFunc != nil {
+		resultStr, err = t.streamFunc(ctx, params, onUpdate)
+	} else {
+		resultStr, err = t.executeFunc(ctx, params)
+	}
 
 	result := ToolResult{
 		Content: []ContentBlock{TextContent{Text: resultStr}},
@@ -15,6 +15,8 @@ import (
 	"github.com/mafredri/cdp/protocol/input"
 	"github.com/mafredri/cdp/protocol/page"
 	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/mafredri/cdp/rpcc"
+	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
@@ -42,9 +44,16 @@ func (e *BrowserCDPExecutor) ExecuteCDP(ctx context.Context, method string, para
 		return relayClient.Execute(ctx, method, params)
 	}
 
-	// 直接模式需要通过 CDP 客户端执行
-	// 这里返回错误，因为直接 CDP 模式应该使用 GetDirectClient
-	return nil, fmt.Errorf("direct CDP mode should use GetDirectClient")
+	// 直接模式通过底层 RPC 连接发送原始 CDP 命令
+	conn, err := e.session.GetConn()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	if err := rpcc.Invoke(ctx, method, params, &result, conn); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // IsDirectMode 检查是否为直接模式
@@ -58,12 +67,14 @@ func (e *BrowserCDPExecutor) GetDirectClient() (*cdp.Client, error) {
 }
 
 // BrowserTool Browser tool using Chrome DevTools Protocol or OpenClaw Relay
+// 每个 agent 会话使用独立的浏览器上下文（见 BrowserPool）
 type BrowserTool struct {
 	headless  bool
 	timeout   time.Duration
 	outputDir string // 固定输出目录，截图将保存到这里
 	relayURL  string // OpenClaw Relay URL
 	relayMode string // Connection mode: "auto", "direct", "relay"
+	pool      *BrowserPool
 }
 
 // NewBrowserTool Create browser tool
@@ -73,26 +84,47 @@ func NewBrowserTool(headless bool, timeout int) *BrowserTool {
 
 // NewBrowserToolWithRelay Create browser tool with Relay support
 func NewBrowserToolWithRelay(headless bool, timeout int, relayURL, relayMode string) *BrowserTool {
-	var t time.Duration
-	if timeout > 0 {
-		t = time.Duration(timeout) * time.Second
-	} else {
-		t = 30 * time.Second
-	}
+	return NewBrowserToolWithConfig(config.BrowserToolConfig{
+		Headless:  headless,
+		Timeout:   timeout,
+		RelayURL:  relayURL,
+		RelayMode: relayMode,
+	})
+}
+
+// NewBrowserToolWithConfig Create browser tool from configuration
+func NewBrowserToolWithConfig(cfg config.BrowserToolConfig) *BrowserTool {
+	opts := BrowserPoolOptionsFromConfig(cfg)
 
 	// 设置固定输出目录用于保存截图
 	homeDir, _ := os.UserHomeDir()
 	outputDir := filepath.Join(homeDir, "goclaw-screenshots")
 
 	return &BrowserTool{
-		headless:  headless,
-		timeout:   t,
+		headless:  cfg.Headless,
+		timeout:   opts.Timeout,
 		outputDir: outputDir,
-		relayURL:  relayURL,
-		relayMode: relayMode,
+		relayURL:  cfg.RelayURL,
+		relayMode: cfg.RelayMode,
+		pool:      NewBrowserPool(opts),
 	}
 }
 
+// browserSession 获取当前 agent 会话的浏览器会话，必要时启动
+func (b *BrowserTool) browserSession(ctx context.Context) (*BrowserSessionManager, error) {
+	return b.pool.Get(ctx)
+}
+
+// Pool 返回浏览器会话池
+func (b *BrowserTool) Pool() *BrowserPool {
+	return b.pool
+}
+
+// CleanupSession 释放会话的浏览器上下文（会话删除时调用）
+func (b *BrowserTool) CleanupSession(sessionKey string) {
+	b.pool.Release(sessionKey)
+}
+
 // Close Close browser tool and cleanup resources
 func (b *BrowserTool) Close() error {
 	// 确保输出目录存在
@@ -102,6 +134,7 @@ func (b *BrowserTool) Close() error {
 		}
 	}
 
+	b.pool.Close()
 	return nil
 }
 
@@ -118,27 +151,14 @@ func (b *BrowserTool) BrowserNavigate(ctx context.Context, params map[string]int
 
 	logger.Debug("Browser navigating to", zap.String("url", urlStr))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		mode := ModeAuto
-		if b.relayMode != "" {
-			switch b.relayMode {
-			case "direct":
-				mode = ModeDirect
-			case "relay":
-				mode = ModeRelay
-			case "auto":
-				mode = ModeAuto
-			}
-		}
-		if err := sessionMgr.StartWithMode(b.timeout, b.relayURL, mode); err != nil {
-			return "", fmt.Errorf("failed to start browser session: %w", err)
-		}
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	// Relay 模式下的处理
 	if sessionMgr.IsRelayMode() {
-		return b.navigateViaRelay(ctx, urlStr)
+		return b.navigateViaRelay(ctx, sessionMgr, urlStr)
 	}
 
 	// 直接 CDP 模式下的处理
@@ -150,7 +170,7 @@ func (b *BrowserTool) BrowserNavigate(ctx context.Context, params map[string]int
 	navArgs := page.NewNavigateArgs(urlStr)
 	nav, err := client.Page.Navigate(ctx, navArgs)
 	if err != nil {
-		sessionMgr.Stop()
+		b.pool.Release(sessionMgr.key)
 		return "", fmt.Errorf("failed to navigate: %w", err)
 	}
 
@@ -180,8 +200,7 @@ func (b *BrowserTool) BrowserNavigate(ctx context.Context, params map[string]int
 }
 
 // navigateViaRelay 通过 Relay 执行导航
-func (b *BrowserTool) navigateViaRelay(ctx context.Context, urlStr string) (string, error) {
-	sessionMgr := GetBrowserSession()
+func (b *BrowserTool) navigateViaRelay(ctx context.Context, sessionMgr *BrowserSessionManager, urlStr string) (string, error) {
 	relayClient := sessionMgr.GetRelayClient()
 	if relayClient == nil {
 		return "", fmt.Errorf("relay client not available")
@@ -221,14 +240,14 @@ func (b *BrowserTool) BrowserScreenshot(ctx context.Context, params map[string]i
 
 	logger.Debug("Browser screenshot", zap.String("url", urlStr), zap.Int("width", width), zap.Int("height", height))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	// Relay 模式下的处理
 	if sessionMgr.IsRelayMode() {
-		return b.screenshotViaRelay(ctx, urlStr, width, height)
+		return b.screenshotViaRelay(ctx, sessionMgr, urlStr, width, height)
 	}
 
 	// 直接 CDP 模式下的处理
@@ -281,8 +300,7 @@ func (b *BrowserTool) BrowserScreenshot(ctx context.Context, params map[string]i
 }
 
 // screenshotViaRelay 通过 Relay 执行截图
-func (b *BrowserTool) screenshotViaRelay(ctx context.Context, urlStr string, width, height int) (string, error) {
-	sessionMgr := GetBrowserSession()
+func (b *BrowserTool) screenshotViaRelay(ctx context.Context, sessionMgr *BrowserSessionManager, urlStr string, width, height int) (string, error) {
 	relayClient := sessionMgr.GetRelayClient()
 	if relayClient == nil {
 		return "", fmt.Errorf("relay client not available")
@@ -353,9 +371,9 @@ func (b *BrowserTool) BrowserExecuteScript(ctx context.Context, params map[strin
 
 	logger.Debug("Browser executing script", zap.String("url", urlStr), zap.String("script", script))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -404,9 +422,9 @@ func (b *BrowserTool) BrowserClick(ctx context.Context, params map[string]interf
 
 	logger.Debug("Browser clicking element", zap.String("url", urlStr), zap.String("selector", selector))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -484,9 +502,9 @@ func (b *BrowserTool) BrowserFillInput(ctx context.Context, params map[string]in
 
 	logger.Debug("Browser filling input", zap.String("url", urlStr), zap.String("selector", selector), zap.String("value", "***"))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready. Please navigate to a page first using browser_navigate.")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -545,11 +563,9 @@ func (b *BrowserTool) BrowserGetText(ctx context.Context, params map[string]inte
 
 	logger.Debug("Browser getting text", zap.String("url", urlStr))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		if err := sessionMgr.Start(b.timeout); err != nil {
-			return "", fmt.Errorf("failed to start browser session: %w", err)
-		}
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -616,7 +632,7 @@ func (b *BrowserTool) querySelector(ctx context.Context, client *cdp.Client, sel
 
 // GetTools Get all browser tools
 func (b *BrowserTool) GetTools() []Tool {
-	return []Tool{
+	tools := []Tool{
 		NewBaseTool(
 			"browser_navigate",
 			"Navigate browser to a URL and wait for it to load",
@@ -731,6 +747,9 @@ func (b *BrowserTool) GetTools() []Tool {
 			b.BrowserGetText,
 		),
 	}
+	tools = append(tools, b.snapshotTools()...)
+	tools = append(tools, b.tabTools()...)
+	return b.pool.inUse(tools)
 }
 
 // htmlToText Convert HTML to plain text
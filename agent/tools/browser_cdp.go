@@ -51,11 +51,9 @@ func (b *BrowserCDPTool) BrowserPrintToPDF(ctx context.Context, params map[strin
 		zap.Bool("print_background", printBackground),
 	)
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		if err := sessionMgr.Start(b.timeout); err != nil {
-			return "", fmt.Errorf("failed to start browser session: %w", err)
-		}
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -134,11 +132,9 @@ func (b *BrowserCDPTool) BrowserExtractStructuredData(ctx context.Context, param
 		zap.String("type", extractType),
 	)
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		if err := sessionMgr.Start(b.timeout); err != nil {
-			return "", fmt.Errorf("failed to start browser session: %w", err)
-		}
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -302,9 +298,9 @@ func (b *BrowserCDPTool) buildExtractionScript(extractType string) string {
 
 // BrowserGetMetrics Get performance metrics for the current page
 func (b *BrowserCDPTool) BrowserGetMetrics(ctx context.Context, params map[string]interface{}) (string, error) {
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -351,9 +347,9 @@ func (b *BrowserCDPTool) BrowserEmulateDevice(ctx context.Context, params map[st
 
 	logger.Debug("Browser emulating device", zap.String("device", device))
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -426,9 +422,9 @@ func (b *BrowserCDPTool) BrowserSetViewport(ctx context.Context, params map[stri
 		zap.Float64("height", height),
 	)
 
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -447,9 +443,9 @@ func (b *BrowserCDPTool) BrowserSetViewport(ctx context.Context, params map[stri
 
 // BrowserGetAllCookies Get all cookies for the current page
 func (b *BrowserCDPTool) BrowserGetAllCookies(ctx context.Context, params map[string]interface{}) (string, error) {
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	client, err := sessionMgr.GetClient()
@@ -487,41 +483,12 @@ func (b *BrowserCDPTool) BrowserGetAllCookies(ctx context.Context, params map[st
 
 // BrowserClose Close current tab
 func (b *BrowserCDPTool) BrowserClose(ctx context.Context, params map[string]interface{}) (string, error) {
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
-	}
-
-	client, err := sessionMgr.GetClient()
-	if err != nil {
-		return "", fmt.Errorf("failed to get browser client: %w", err)
-	}
-
-	if err := client.Page.Close(ctx); err != nil {
-		return "", fmt.Errorf("failed to close page: %w", err)
-	}
-
-	return "Page closed successfully", nil
+	return b.BrowserTabClose(ctx, map[string]interface{}{})
 }
 
 // BrowserCreateTab Create a new tab
 func (b *BrowserCDPTool) BrowserCreateTab(ctx context.Context, params map[string]interface{}) (string, error) {
-	sessionMgr := GetBrowserSession()
-	if !sessionMgr.IsReady() {
-		return "", fmt.Errorf("browser session not ready")
-	}
-
-	// Create new target (tab)
-	sessionMgr.mu.Lock()
-	defer sessionMgr.mu.Unlock()
-
-	pt, err := sessionMgr.devt.Create(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to create new tab: %w", err)
-	}
-
-	// Get the target ID from the created target
-	return fmt.Sprintf("New tab created: %v", pt), nil
+	return b.BrowserTabOpen(ctx, params)
 }
 
 // GetCDPTools Get all CDP-enhanced browser tools
@@ -663,5 +630,5 @@ func (b *BrowserCDPTool) GetCDPTools() []Tool {
 		),
 	}
 
-	return append(baseTools, cdpTools...)
+	return append(baseTools, b.pool.inUse(cdpTools)...)
 }
@@ -0,0 +1,628 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/browser"
+	"github.com/mafredri/cdp/protocol/target"
+	"github.com/mafredri/cdp/rpcc"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultBrowserSessionKey 上下文中没有会话键时使用的会话
+	defaultBrowserSessionKey = "default"
+	// defaultBrowserIdleTimeout 空闲浏览器上下文的默认回收时间
+	defaultBrowserIdleTimeout = 10 * time.Minute
+)
+
+// BrowserPoolOptions 浏览器会话池选项
+type BrowserPoolOptions struct {
+	Headless    bool
+	Timeout     time.Duration
+	RelayURL    string
+	Mode        ConnectionMode
+	ProfileDir  string        // 持久化 profile 根目录，为空时每个会话使用临时的隔离上下文
+	IdleTimeout time.Duration // 会话空闲多久后回收，<0 表示不回收
+}
+
+// BrowserPoolOptionsFromConfig 从配置构建会话池选项
+func BrowserPoolOptionsFromConfig(cfg config.BrowserToolConfig) BrowserPoolOptions {
+	opts := BrowserPoolOptions{
+		Headless:   cfg.Headless,
+		Timeout:    30 * time.Second,
+		RelayURL:   cfg.RelayURL,
+		Mode:       parseConnectionMode(cfg.RelayMode),
+		ProfileDir: cfg.ProfileDir,
+	}
+	if cfg.Timeout > 0 {
+		opts.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.IdleTimeout > 0 {
+		opts.IdleTimeout = time.Duration(cfg.IdleTimeout) * time.Second
+	}
+	return opts
+}
+
+// parseConnectionMode 解析连接模式字符串
+func parseConnectionMode(mode string) ConnectionMode {
+	switch mode {
+	case "direct":
+		return ModeDirect
+	case "relay":
+		return ModeRelay
+	default:
+		return ModeAuto
+	}
+}
+
+// BrowserSessionInfo 浏览器会话信息
+type BrowserSessionInfo struct {
+	Key      string
+	Mode     ConnectionMode
+	Profile  string
+	Tabs     int
+	LastUsed time.Time
+}
+
+// BrowserPool 按 agent 会话隔离的浏览器会话池
+// 直接模式下所有会话共享一个 Chrome 进程，各自使用独立的浏览器上下文（cookie、存储互不可见）；
+// 配置了 profile 目录时，每个会话使用独立的 Chrome 进程和持久化的 profile 目录。
+// Relay 模式由扩展控制用户的浏览器，无法隔离，所有会话共享同一个 Relay 连接。
+type BrowserPool struct {
+	mu         sync.Mutex
+	opts       BrowserPoolOptions
+	sessions   map[string]*BrowserSessionManager
+	opening    map[string]*browserOpening // 正在创建的会话，同一会话的并发请求等待同一次创建
+	busy       map[string]int             // 正在执行工具调用的会话，空闲回收时跳过
+	generation int                        // Close 时递增，丢弃关闭前发起的创建
+	gcStop     chan struct{}
+	openFn     func(key string) (*BrowserSessionManager, error)
+
+	hostMu sync.Mutex   // 串行化共享 Chrome 进程的启动，不阻塞其他会话的 Get
+	host   *browserHost // 共享的 Chrome 进程
+}
+
+// browserOpening 一次进行中的会话创建
+type browserOpening struct {
+	done chan struct{}
+	err  error
+}
+
+// NewBrowserPool 创建浏览器会话池
+func NewBrowserPool(opts BrowserPoolOptions) *BrowserPool {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = defaultBrowserIdleTimeout
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeAuto
+	}
+	p := &BrowserPool{
+		opts:     opts,
+		sessions: make(map[string]*BrowserSessionManager),
+		opening:  make(map[string]*browserOpening),
+		busy:     make(map[string]int),
+	}
+	p.openFn = p.open
+	return p
+}
+
+// browserSessionKey 返回上下文对应的会话键
+func browserSessionKey(ctx context.Context) string {
+	if key := SessionKeyFromContext(ctx); key != "" {
+		return key
+	}
+	return defaultBrowserSessionKey
+}
+
+// Get 获取当前 agent 会话的浏览器会话，不存在时创建
+// 创建（可能需要启动 Chrome）在锁外进行，不会阻塞其他会话
+func (p *BrowserPool) Get(ctx context.Context) (*BrowserSessionManager, error) {
+	key := browserSessionKey(ctx)
+
+	for {
+		p.mu.Lock()
+		if s, ok := p.sessions[key]; ok {
+			if s.IsReady() {
+				p.mu.Unlock()
+				s.touch()
+				return s, nil
+			}
+			// 连接已断开的会话先释放浏览器上下文和标签页，再重新打开
+			delete(p.sessions, key)
+			p.mu.Unlock()
+			s.release()
+			continue
+		}
+
+		if op, ok := p.opening[key]; ok {
+			p.mu.Unlock()
+			select {
+			case <-op.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if op.err != nil {
+				return nil, op.err
+			}
+			continue
+		}
+
+		op := &browserOpening{done: make(chan struct{})}
+		p.opening[key] = op
+		generation := p.generation
+		p.mu.Unlock()
+
+		s, err := p.openFn(key)
+
+		p.mu.Lock()
+		delete(p.opening, key)
+		if err == nil && generation != p.generation {
+			err = fmt.Errorf("browser pool was closed")
+		} else if err == nil {
+			p.sessions[key] = s
+			p.startGCLocked()
+		}
+		p.mu.Unlock()
+
+		op.err = err
+		close(op.done)
+		if err != nil {
+			if s != nil {
+				s.release()
+			}
+			return nil, err
+		}
+
+		logger.Debug("Browser session opened",
+			zap.String("session", key),
+			zap.String("mode", string(s.GetConnectionMode())),
+			zap.String("profile", s.profileDir))
+		return s, nil
+	}
+}
+
+// acquire 将会话标记为使用中，返回的函数结束使用并刷新最近使用时间
+func (p *BrowserPool) acquire(key string) func() {
+	p.mu.Lock()
+	p.busy[key]++
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		if p.busy[key]--; p.busy[key] <= 0 {
+			delete(p.busy, key)
+		}
+		s, ok := p.sessions[key]
+		p.mu.Unlock()
+		if ok {
+			s.touch()
+		}
+	}
+}
+
+// inUse 包装浏览器工具，执行期间当前会话不会被空闲回收
+func (p *BrowserPool) inUse(tools []Tool) []Tool {
+	for _, tool := range tools {
+		bt, ok := tool.(*BaseTool)
+		if !ok || bt.executeFunc == nil {
+			continue
+		}
+		execute := bt.executeFunc
+		bt.executeFunc = func(ctx context.Context, params map[string]interface{}) (string, error) {
+			done := p.acquire(browserSessionKey(ctx))
+			defer done()
+			return execute(ctx, params)
+		}
+	}
+	return tools
+}
+
+// open 为会话创建浏览器上下文
+func (p *BrowserPool) open(key string) (*BrowserSessionManager, error) {
+	s := &BrowserSessionManager{key: key, lastUsed: time.Now()}
+
+	if p.opts.Mode == ModeRelay || (p.opts.Mode == ModeAuto && p.opts.RelayURL != "") {
+		err := s.StartWithMode(p.opts.Timeout, p.opts.RelayURL, ModeRelay)
+		if err == nil {
+			s.sharedRelay = true
+			return s, nil
+		}
+		if p.opts.Mode == ModeRelay {
+			return nil, fmt.Errorf("failed to start browser session: %w", err)
+		}
+		logger.Warn("OpenClaw Relay connection failed, falling back to direct CDP", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	// 持久化 profile：每个会话独立的 Chrome 进程
+	if p.opts.ProfileDir != "" {
+		profileDir := filepath.Join(p.opts.ProfileDir, browserProfileName(key))
+		if err := os.MkdirAll(profileDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create browser profile dir: %w", err)
+		}
+		host, err := launchBrowserHost(ctx, p.opts.Headless, profileDir, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start browser session: %w", err)
+		}
+		if err := s.attachHost(ctx, host, "", true); err != nil {
+			host.close()
+			return nil, fmt.Errorf("failed to start browser session: %w", err)
+		}
+		s.profileDir = profileDir
+		return s, nil
+	}
+
+	// 共享 Chrome 进程中的隔离上下文
+	host, err := p.sharedHost(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start browser session: %w", err)
+	}
+	created, err := host.client.Target.CreateBrowserContext(ctx, target.NewCreateBrowserContextArgs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create browser context: %w", err)
+	}
+	if err := s.attachHost(ctx, host, created.BrowserContextID, false); err != nil {
+		_ = host.client.Target.DisposeBrowserContext(ctx, target.NewDisposeBrowserContextArgs(created.BrowserContextID))
+		return nil, fmt.Errorf("failed to start browser session: %w", err)
+	}
+	return s, nil
+}
+
+// sharedHost 返回共享的 Chrome 进程，必要时连接已运行的实例或启动新实例
+func (p *BrowserPool) sharedHost(ctx context.Context) (*browserHost, error) {
+	p.hostMu.Lock()
+	defer p.hostMu.Unlock()
+
+	if p.host != nil && p.host.alive(ctx) {
+		return p.host, nil
+	}
+	if p.host != nil {
+		p.host.close()
+		p.host = nil
+	}
+
+	// 优先连接已运行的 Chrome 实例
+	for _, port := range []int{9222, 9223, 9224} {
+		host, err := connectBrowserHost(ctx, fmt.Sprintf("http://localhost:%d", port))
+		if err == nil {
+			logger.Debug("Connected to existing Chrome instance", zap.Int("port", port))
+			p.host = host
+			return host, nil
+		}
+	}
+
+	userDataDir, err := os.MkdirTemp("", "goclaw-chrome-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user data dir: %w", err)
+	}
+	host, err := launchBrowserHost(ctx, p.opts.Headless, userDataDir, true)
+	if err != nil {
+		return nil, err
+	}
+	p.host = host
+	return host, nil
+}
+
+// Release 关闭指定会话的浏览器上下文（会话删除时调用）
+func (p *BrowserPool) Release(key string) {
+	p.mu.Lock()
+	s, ok := p.sessions[key]
+	delete(p.sessions, key)
+	p.mu.Unlock()
+
+	if ok {
+		s.release()
+		logger.Debug("Browser session released", zap.String("session", key))
+	}
+}
+
+// PruneIdle 回收空闲超过 maxIdle 的会话，返回被回收的会话键
+func (p *BrowserPool) PruneIdle(maxIdle time.Duration) []string {
+	now := time.Now()
+
+	p.mu.Lock()
+	var idle []*BrowserSessionManager
+	for key, s := range p.sessions {
+		if p.busy[key] > 0 {
+			continue
+		}
+		if now.Sub(s.LastUsed()) >= maxIdle || !s.IsReady() {
+			idle = append(idle, s)
+			delete(p.sessions, key)
+		}
+	}
+	p.mu.Unlock()
+
+	keys := make([]string, 0, len(idle))
+	for _, s := range idle {
+		s.release()
+		keys = append(keys, s.key)
+	}
+	if len(keys) > 0 {
+		logger.Info("Pruned idle browser sessions", zap.Strings("sessions", keys))
+	}
+	return keys
+}
+
+// startGCLocked 启动空闲会话回收（调用方需持有锁）
+func (p *BrowserPool) startGCLocked() {
+	if p.gcStop != nil || p.opts.IdleTimeout < 0 {
+		return
+	}
+	p.gcStop = make(chan struct{})
+	stop := p.gcStop
+	interval := p.opts.IdleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.PruneIdle(p.opts.IdleTimeout)
+			}
+		}
+	}()
+}
+
+// Sessions 返回当前所有会话的信息
+func (p *BrowserPool) Sessions() []BrowserSessionInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	infos := make([]BrowserSessionInfo, 0, len(p.sessions))
+	for key, s := range p.sessions {
+		s.mu.RLock()
+		infos = append(infos, BrowserSessionInfo{
+			Key:      key,
+			Mode:     s.connectionMode,
+			Profile:  s.profileDir,
+			Tabs:     len(s.tabs),
+			LastUsed: s.lastUsed,
+		})
+		s.mu.RUnlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
+}
+
+// Close 关闭所有会话和共享的 Chrome 进程
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	sessions := p.sessions
+	p.sessions = make(map[string]*BrowserSessionManager)
+	p.generation++
+	if p.gcStop != nil {
+		close(p.gcStop)
+		p.gcStop = nil
+	}
+	p.mu.Unlock()
+
+	p.hostMu.Lock()
+	host := p.host
+	p.host = nil
+	p.hostMu.Unlock()
+
+	relay := false
+	for _, s := range sessions {
+		relay = relay || s.sharedRelay
+		s.release()
+	}
+	if relay {
+		GetRelaySession().Stop()
+	}
+	if host != nil {
+		host.close()
+	}
+}
+
+var profileNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// browserProfileName 将会话键转换为安全的目录名
+func browserProfileName(key string) string {
+	name := strings.Trim(profileNameRe.ReplaceAllString(key, "_"), "._")
+	if name == "" {
+		name = defaultBrowserSessionKey
+	}
+	return name
+}
+
+// browserHost 一个 Chrome 进程及其浏览器级 CDP 连接
+type browserHost struct {
+	devt        *devtool.DevTools
+	conn        *rpcc.Conn
+	client      *cdp.Client
+	wsBase      *url.URL // 浏览器 WebSocket 地址，用于拼接页面地址
+	cmd         *exec.Cmd
+	userDataDir string
+	removeDir   bool // 关闭时删除 userDataDir（临时目录）
+}
+
+// connectBrowserHost 连接到已运行的 Chrome 实例
+func connectBrowserHost(ctx context.Context, endpoint string) (*browserHost, error) {
+	devt := devtool.New(endpoint)
+	version, err := devt.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("browser at %s does not expose a browser websocket", endpoint)
+	}
+	wsBase, err := url.Parse(version.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid browser websocket URL: %w", err)
+	}
+	conn, err := rpcc.DialContext(ctx, version.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial browser websocket: %w", err)
+	}
+	return &browserHost{devt: devt, conn: conn, client: cdp.NewClient(conn), wsBase: wsBase}, nil
+}
+
+// launchBrowserHost 启动新的 Chrome 进程，使用随机调试端口
+func launchBrowserHost(ctx context.Context, headless bool, userDataDir string, removeDir bool) (*browserHost, error) {
+	chromePath, err := (&BrowserSessionManager{}).findChrome()
+	if err != nil {
+		if removeDir {
+			_ = os.RemoveAll(userDataDir)
+		}
+		return nil, fmt.Errorf("failed to find Chrome: %w", err)
+	}
+
+	// 清理上次运行残留的端口文件
+	portFile := filepath.Join(userDataDir, "DevToolsActivePort")
+	_ = os.Remove(portFile)
+
+	args := []string{
+		"--no-sandbox",
+		"--disable-setuid-sandbox",
+		"--disable-dev-shm-usage",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--remote-debugging-port=0",
+		fmt.Sprintf("--user-data-dir=%s", userDataDir),
+		"--disable-background-timer-throttling",
+		"--disable-backgrounding-occluded-windows",
+		"--disable-renderer-backgrounding",
+	}
+	if headless {
+		args = append([]string{"--headless=new"}, args...)
+	}
+
+	cmd := exec.Command(chromePath, args...)
+	if err := cmd.Start(); err != nil {
+		if removeDir {
+			_ = os.RemoveAll(userDataDir)
+		}
+		return nil, fmt.Errorf("failed to start Chrome: %w", err)
+	}
+
+	host := &browserHost{cmd: cmd, userDataDir: userDataDir, removeDir: removeDir}
+
+	// 等待 Chrome 写出调试端口
+	port, err := waitDevToolsPort(ctx, portFile)
+	if err != nil {
+		host.close()
+		return nil, err
+	}
+
+	connected, err := connectBrowserHost(ctx, fmt.Sprintf("http://127.0.0.1:%d", port))
+	if err != nil {
+		host.close()
+		return nil, fmt.Errorf("failed to connect to Chrome: %w", err)
+	}
+	host.devt, host.conn, host.client, host.wsBase = connected.devt, connected.conn, connected.client, connected.wsBase
+
+	logger.Debug("Chrome started", zap.Int("port", port), zap.String("user_data_dir", userDataDir))
+	return host, nil
+}
+
+// waitDevToolsPort 等待 DevToolsActivePort 文件并返回端口
+func waitDevToolsPort(ctx context.Context, portFile string) (int, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if data, err := os.ReadFile(portFile); err == nil {
+			line, _, _ := strings.Cut(string(data), "\n")
+			if port, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && port > 0 {
+				return port, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("Chrome did not start within timeout")
+		case <-ticker.C:
+		}
+	}
+}
+
+// alive 检查浏览器连接是否仍然可用
+func (h *browserHost) alive(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := h.client.Target.GetTargets(ctx)
+	return err == nil
+}
+
+// pageURL 返回页面目标的 WebSocket 地址
+func (h *browserHost) pageURL(id target.ID) string {
+	u := *h.wsBase
+	u.Path = "/devtools/page/" + string(id)
+	return u.String()
+}
+
+// pages 返回浏览器上下文中的页面目标（contextID 为空时返回所有页面）
+func (h *browserHost) pages(ctx context.Context, contextID browser.ContextID) ([]target.Info, error) {
+	reply, err := h.client.Target.GetTargets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list targets: %w", err)
+	}
+	var pages []target.Info
+	for _, info := range reply.TargetInfos {
+		if info.Type != "page" {
+			continue
+		}
+		if contextID != "" && (info.BrowserContextID == nil || *info.BrowserContextID != contextID) {
+			continue
+		}
+		pages = append(pages, info)
+	}
+	return pages, nil
+}
+
+// createPage 在浏览器上下文中创建页面
+func (h *browserHost) createPage(ctx context.Context, contextID browser.ContextID, pageURL string) (target.ID, error) {
+	if pageURL == "" {
+		pageURL = "about:blank"
+	}
+	args := target.NewCreateTargetArgs(pageURL)
+	if contextID != "" {
+		args.SetBrowserContextID(contextID)
+	}
+	reply, err := h.client.Target.CreateTarget(ctx, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tab: %w", err)
+	}
+	return reply.TargetID, nil
+}
+
+// close 关闭浏览器连接，停止自己启动的进程
+func (h *browserHost) close() {
+	if h.conn != nil {
+		_ = h.conn.Close()
+	}
+	if h.cmd != nil && h.cmd.Process != nil {
+		_ = h.cmd.Process.Kill()
+		_ = h.cmd.Wait()
+	}
+	if h.removeDir && h.userDataDir != "" {
+		_ = os.RemoveAll(h.userDataDir)
+	}
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/target"
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestBrowserPoolOptionsFromConfig(t *testing.T) {
+	opts := BrowserPoolOptionsFromConfig(config.BrowserToolConfig{
+		Headless:    true,
+		Timeout:     45,
+		RelayMode:   "relay",
+		ProfileDir:  "/tmp/profiles",
+		IdleTimeout: 120,
+	})
+	if opts.Timeout != 45*time.Second || opts.Mode != ModeRelay || opts.IdleTimeout != 2*time.Minute || opts.ProfileDir != "/tmp/profiles" {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	pool := NewBrowserPool(BrowserPoolOptionsFromConfig(config.BrowserToolConfig{}))
+	if pool.opts.Timeout != 30*time.Second || pool.opts.Mode != ModeAuto || pool.opts.IdleTimeout != defaultBrowserIdleTimeout {
+		t.Errorf("unexpected defaults %+v", pool.opts)
+	}
+}
+
+func TestBrowserSessionKey(t *testing.T) {
+	if got := browserSessionKey(context.Background()); got != defaultBrowserSessionKey {
+		t.Errorf("expected default key, got %q", got)
+	}
+	if got := browserSessionKey(WithSessionKey(context.Background(), "telegram:42")); got != "telegram:42" {
+		t.Errorf("unexpected key %q", got)
+	}
+}
+
+func TestBrowserProfileName(t *testing.T) {
+	tests := map[string]string{
+		"telegram:42":     "telegram_42",
+		"../../etc":       "etc",
+		"agent/main sess": "agent_main_sess",
+		"...":             defaultBrowserSessionKey,
+	}
+	for key, want := range tests {
+		if got := browserProfileName(key); got != want {
+			t.Errorf("browserProfileName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestBrowserPoolPruneIdle(t *testing.T) {
+	pool := NewBrowserPool(BrowserPoolOptions{})
+	pool.sessions["old"] = &BrowserSessionManager{key: "old", ready: true, lastUsed: time.Now().Add(-time.Hour)}
+	pool.sessions["new"] = &BrowserSessionManager{key: "new", ready: true, lastUsed: time.Now()}
+	pool.sessions["dead"] = &BrowserSessionManager{key: "dead", lastUsed: time.Now()}
+
+	pruned := pool.PruneIdle(10 * time.Minute)
+	if len(pruned) != 2 {
+		t.Fatalf("expected 2 pruned sessions, got %v", pruned)
+	}
+	infos := pool.Sessions()
+	if len(infos) != 1 || infos[0].Key != "new" {
+		t.Errorf("unexpected remaining sessions %+v", infos)
+	}
+
+	pool.Release("new")
+	if len(pool.Sessions()) != 0 {
+		t.Error("expected session to be released")
+	}
+}
+
+func TestBrowserPoolGetOpensOutsideLock(t *testing.T) {
+	pool := NewBrowserPool(BrowserPoolOptions{IdleTimeout: -1})
+	unblock := make(chan struct{})
+	var opened atomic.Int32
+	pool.openFn = func(key string) (*BrowserSessionManager, error) {
+		opened.Add(1)
+		if key == "slow" {
+			<-unblock
+		}
+		return &BrowserSessionManager{key: key, ready: true, lastUsed: time.Now()}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan *BrowserSessionManager, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := pool.Get(WithSessionKey(context.Background(), "slow"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- s
+		}()
+	}
+
+	// Another session is served while the slow one is still launching
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := pool.Get(WithSessionKey(context.Background(), "fast")); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Get for another session not to wait for the slow launch")
+	}
+
+	close(unblock)
+	wg.Wait()
+	close(results)
+	var first *BrowserSessionManager
+	for s := range results {
+		if first == nil {
+			first = s
+		} else if s != first {
+			t.Error("expected concurrent Gets for one session to share a single launch")
+		}
+	}
+	if opened.Load() != 2 {
+		t.Errorf("expected one launch per session, got %d", opened.Load())
+	}
+}
+
+func TestBrowserPoolGetReleasesStaleSession(t *testing.T) {
+	pool := NewBrowserPool(BrowserPoolOptions{IdleTimeout: -1})
+	stale := &BrowserSessionManager{key: "s1", activeTab: "tab-1", lastUsed: time.Now()}
+	pool.sessions["s1"] = stale
+	pool.openFn = func(key string) (*BrowserSessionManager, error) {
+		return &BrowserSessionManager{key: key, ready: true, lastUsed: time.Now()}, nil
+	}
+
+	s, err := pool.Get(WithSessionKey(context.Background(), "s1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s == stale {
+		t.Fatal("expected a disconnected session to be reopened")
+	}
+	if stale.activeTab != "" {
+		t.Error("expected the disconnected session to be released before reopening")
+	}
+}
+
+func TestBrowserPoolBusySessionsNotPruned(t *testing.T) {
+	pool := NewBrowserPool(BrowserPoolOptions{})
+	pool.sessions["s1"] = &BrowserSessionManager{key: "s1", ready: true, lastUsed: time.Now().Add(-time.Hour)}
+
+	tool := pool.inUse([]Tool{NewBaseTool("browser_test", "", nil, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		return "", nil
+	})})[0].(*BaseTool)
+	release := pool.acquire("s1")
+	if pruned := pool.PruneIdle(time.Minute); len(pruned) != 0 {
+		t.Fatalf("expected a busy session to survive pruning, got %v", pruned)
+	}
+	release()
+	if pruned := pool.PruneIdle(time.Minute); len(pruned) != 0 {
+		t.Fatal("expected finishing a call to refresh the last use")
+	}
+
+	if _, err := tool.Execute(WithSessionKey(context.Background(), "s1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.busy) != 0 {
+		t.Errorf("expected the wrapped tool to release the session, got %v", pool.busy)
+	}
+}
+
+func TestMatchTab(t *testing.T) {
+	pages := []target.Info{
+		{TargetID: "A1B2C3D4E5F6", Title: "one"},
+		{TargetID: "A1FFFFFFFFFF", Title: "two"},
+		{TargetID: "0099887766", Title: "three"},
+	}
+
+	if tab, err := matchTab(pages, "a1b2c3d4"); err != nil || tab.Title != "one" {
+		t.Errorf("expected prefix match, got %+v, %v", tab, err)
+	}
+	if tab, err := matchTab(pages, "0099887766"); err != nil || tab.Title != "three" {
+		t.Errorf("expected exact match, got %+v, %v", tab, err)
+	}
+	if _, err := matchTab(pages, "A1"); err == nil {
+		t.Error("expected ambiguous prefix to fail")
+	}
+	if _, err := matchTab(pages, "ZZ"); err == nil {
+		t.Error("expected unknown tab to fail")
+	}
+	if got := shortTabID("A1B2C3D4E5F6"); got != "A1B2C3D4" {
+		t.Errorf("unexpected short id %q", got)
+	}
+}
+
+func TestBrowserTabsRequireDirectSession(t *testing.T) {
+	relay := &BrowserSessionManager{ready: true, sharedRelay: true}
+	if _, err := relay.ListTabs(context.Background()); err == nil {
+		t.Error("expected tab listing to fail without a direct CDP session")
+	}
+}
@@ -10,8 +10,10 @@ import (
 
 	"github.com/mafredri/cdp"
 	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/browser"
 	"github.com/mafredri/cdp/protocol/emulation"
 	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/target"
 	"github.com/mafredri/cdp/rpcc"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
@@ -40,11 +42,30 @@ type BrowserSessionManager struct {
 	connectionMode ConnectionMode       // 连接模式
 	relayURL       string               // OpenClaw Relay URL
 	relaySession   *RelaySessionManager // Relay 会话
+
+	// 以下字段仅用于 BrowserPool 创建的会话
+	key              string                    // 所属 agent 会话
+	host             *browserHost              // 所在的 Chrome 进程
+	ownsHost         bool                      // 是否独占 Chrome 进程（持久化 profile）
+	browserContextID browser.ContextID         // 隔离的浏览器上下文
+	profileDir       string                    // 持久化 profile 目录
+	tabs             map[target.ID]*browserTab // 已连接的标签页
+	activeTab        target.ID                 // 当前标签页
+	sharedRelay      bool                      // 使用共享的 Relay 连接
+	lastUsed         time.Time
+}
+
+// browserTab 已连接的标签页
+type browserTab struct {
+	id     target.ID
+	conn   *rpcc.Conn
+	client *cdp.Client
 }
 
 var sessionManager *BrowserSessionManager
 
-// GetBrowserSession 获取浏览器会话管理器（单例）
+// GetBrowserSession 获取进程级的浏览器会话（单例），供 goclaw browser 命令行使用
+// agent 工具通过 BrowserPool 按会话获取隔离的浏览器上下文
 func GetBrowserSession() *BrowserSessionManager {
 	if sessionManager == nil {
 		sessionManager = &BrowserSessionManager{}
@@ -215,32 +236,149 @@ func (b *BrowserSessionManager) connect(port int) error {
 	b.conn = conn
 
 	// 创建 CDP 客户端
-	b.client = cdp.NewClient(conn)
+	client, err := setupPageClient(ctx, conn)
+	if err != nil {
+		return err
+	}
+	b.client = client
+
+	return nil
+}
+
+// setupPageClient 为页面连接创建 CDP 客户端并启用需要的域
+func setupPageClient(ctx context.Context, conn *rpcc.Conn) (*cdp.Client, error) {
+	client := cdp.NewClient(conn)
 
 	// 启用需要的域
-	if err := b.client.DOM.Enable(ctx); err != nil {
-		return fmt.Errorf("failed to enable DOM: %w", err)
+	if err := client.DOM.Enable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enable DOM: %w", err)
 	}
-	if err := b.client.Page.Enable(ctx); err != nil {
-		return fmt.Errorf("failed to enable Page: %w", err)
+	if err := client.Page.Enable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enable Page: %w", err)
 	}
-	if err := b.client.Runtime.Enable(ctx); err != nil {
-		return fmt.Errorf("failed to enable Runtime: %w", err)
+	if err := client.Runtime.Enable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enable Runtime: %w", err)
 	}
-	if err := b.client.Network.Enable(ctx, network.NewEnableArgs()); err != nil {
-		return fmt.Errorf("failed to enable Network: %w", err)
+	if err := client.Network.Enable(ctx, network.NewEnableArgs()); err != nil {
+		return nil, fmt.Errorf("failed to enable Network: %w", err)
 	}
 
 	// 设置真实的 User-Agent 以避免被检测为自动化工具
 	// 使用最新 Chrome 的 User-Agent
 	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
-	if err := b.client.Emulation.SetUserAgentOverride(ctx, emulation.NewSetUserAgentOverrideArgs(userAgent)); err != nil {
+	if err := client.Emulation.SetUserAgentOverride(ctx, emulation.NewSetUserAgentOverrideArgs(userAgent)); err != nil {
 		logger.Warn("Failed to set User-Agent", zap.Error(err))
 	}
 
+	return client, nil
+}
+
+// attachHost 将会话绑定到 Chrome 进程中的浏览器上下文，并连接第一个标签页
+func (b *BrowserSessionManager) attachHost(ctx context.Context, host *browserHost, contextID browser.ContextID, ownsHost bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.host = host
+	b.ownsHost = ownsHost
+	b.browserContextID = contextID
+	b.devt = host.devt
+	b.tabs = make(map[target.ID]*browserTab)
+
+	// 复用上下文中已有的标签页（持久化 profile 启动时会打开一个）
+	var id target.ID
+	if pages, err := host.pages(ctx, contextID); err == nil && len(pages) > 0 {
+		id = pages[0].TargetID
+	} else {
+		created, err := host.createPage(ctx, contextID, "")
+		if err != nil {
+			return err
+		}
+		id = created
+	}
+
+	if err := b.switchTabLocked(ctx, id); err != nil {
+		return err
+	}
+	b.connectionMode = ModeDirect
+	b.ready = true
 	return nil
 }
 
+// connectTabLocked 连接标签页（调用方需持有锁）
+func (b *BrowserSessionManager) connectTabLocked(ctx context.Context, id target.ID) (*browserTab, error) {
+	if tab, ok := b.tabs[id]; ok {
+		return tab, nil
+	}
+	conn, err := rpcc.DialContext(ctx, b.host.pageURL(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to tab: %w", err)
+	}
+	client, err := setupPageClient(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	tab := &browserTab{id: id, conn: conn, client: client}
+	b.tabs[id] = tab
+	return tab, nil
+}
+
+// switchTabLocked 切换当前标签页（调用方需持有锁）
+func (b *BrowserSessionManager) switchTabLocked(ctx context.Context, id target.ID) error {
+	tab, err := b.connectTabLocked(ctx, id)
+	if err != nil {
+		return err
+	}
+	b.activeTab = id
+	b.conn = tab.conn
+	b.client = tab.client
+	return nil
+}
+
+// touch 更新最近使用时间
+func (b *BrowserSessionManager) touch() {
+	b.mu.Lock()
+	b.lastUsed = time.Now()
+	b.mu.Unlock()
+}
+
+// LastUsed 返回最近使用时间
+func (b *BrowserSessionManager) LastUsed() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastUsed
+}
+
+// release 释放会话池中的会话：关闭标签页连接、销毁浏览器上下文、停止独占的 Chrome 进程
+// 共享的 Chrome 进程和 Relay 连接由 BrowserPool 负责关闭
+func (b *BrowserSessionManager) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, tab := range b.tabs {
+		_ = tab.conn.Close()
+	}
+	if b.host != nil {
+		if b.ownsHost {
+			b.host.close()
+		} else if b.browserContextID != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := b.host.client.Target.DisposeBrowserContext(ctx, target.NewDisposeBrowserContextArgs(b.browserContextID)); err != nil {
+				logger.Warn("Failed to dispose browser context", zap.String("session", b.key), zap.Error(err))
+			}
+			cancel()
+		}
+	}
+
+	b.ready = false
+	b.tabs = nil
+	b.activeTab = ""
+	b.client = nil
+	b.conn = nil
+	b.host = nil
+	b.relaySession = nil
+}
+
 // findChrome 查找 Chrome 可执行文件
 func (b *BrowserSessionManager) findChrome() (string, error) {
 	// 常见 Chrome 路径
@@ -290,6 +428,18 @@ func (b *BrowserSessionManager) GetClient() (*cdp.Client, error) {
 	return b.client, nil
 }
 
+// GetConn 获取底层 RPC 连接（直接模式），用于发送原始 CDP 命令
+func (b *BrowserSessionManager) GetConn() (*rpcc.Conn, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.ready || b.conn == nil {
+		return nil, fmt.Errorf("browser session not ready")
+	}
+
+	return b.conn, nil
+}
+
 // GetRelayClient 获取 Relay 客户端
 func (b *BrowserSessionManager) GetRelayClient() *RelaySessionManager {
 	b.mu.RLock()
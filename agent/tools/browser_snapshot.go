@@ -0,0 +1,643 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/accessibility"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSnapshotMaxNodes 快照默认最多输出的节点数
+	defaultSnapshotMaxNodes = 400
+	// snapshotMaxTextLen 节点名称和值的最大长度
+	snapshotMaxTextLen = 100
+)
+
+// interactiveRoles 可交互的无障碍角色
+var interactiveRoles = map[string]bool{
+	"button": true, "link": true, "textbox": true, "searchbox": true, "combobox": true,
+	"listbox": true, "option": true, "checkbox": true, "radio": true, "switch": true,
+	"slider": true, "spinbutton": true, "menuitem": true, "menuitemcheckbox": true,
+	"menuitemradio": true, "tab": true, "treeitem": true, "textarea": true,
+}
+
+// transparentRoles 无名称时不单独输出、只展开子节点的角色
+var transparentRoles = map[string]bool{
+	"generic": true, "none": true, "presentation": true, "group": true, "paragraph": true,
+	"section": true, "div": true, "LayoutTable": true, "LayoutTableRow": true,
+	"LayoutTableCell": true, "RootWebArea": true, "WebArea": true, "Unknown": true,
+}
+
+// snapshotProperties 快照中展示的节点属性
+var snapshotProperties = []accessibility.AXPropertyName{
+	accessibility.AXPropertyNameChecked,
+	accessibility.AXPropertyNameDisabled,
+	accessibility.AXPropertyNameExpanded,
+	accessibility.AXPropertyNameFocused,
+	accessibility.AXPropertyNameLevel,
+	accessibility.AXPropertyNamePressed,
+	accessibility.AXPropertyNameRequired,
+	accessibility.AXPropertyNameSelected,
+}
+
+// SnapshotOptions 快照选项
+type SnapshotOptions struct {
+	InteractiveOnly bool // 只输出可交互元素
+	MaxNodes        int  // 最多输出的节点数
+}
+
+// AXSnapshot 无障碍树快照
+type AXSnapshot struct {
+	Text      string // 格式化后的树
+	Nodes     int    // 输出的节点数
+	Refs      int    // 分配了引用的节点数
+	Truncated bool
+}
+
+// axFormatter 将无障碍节点格式化为紧凑的缩进树
+type axFormatter struct {
+	nodes     map[accessibility.AXNodeID]*accessibility.AXNode
+	opts      SnapshotOptions
+	sb        strings.Builder
+	count     int
+	refs      int
+	truncated bool
+}
+
+// FormatAXSnapshot 将 Accessibility.getFullAXTree 的结果格式化为带元素引用的快照
+// 每个关联 DOM 节点的元素都有形如 e123 的引用（基于 backendDOMNodeId，在节点存活期间保持稳定）
+func FormatAXSnapshot(nodes []accessibility.AXNode, opts SnapshotOptions) AXSnapshot {
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = defaultSnapshotMaxNodes
+	}
+
+	f := &axFormatter{
+		nodes: make(map[accessibility.AXNodeID]*accessibility.AXNode, len(nodes)),
+		opts:  opts,
+	}
+	children := make(map[accessibility.AXNodeID]bool)
+	for i := range nodes {
+		f.nodes[nodes[i].NodeID] = &nodes[i]
+		for _, id := range nodes[i].ChildIDs {
+			children[id] = true
+		}
+	}
+
+	for i := range nodes {
+		if !children[nodes[i].NodeID] {
+			f.walk(&nodes[i], 0)
+		}
+	}
+
+	return AXSnapshot{Text: f.sb.String(), Nodes: f.count, Refs: f.refs, Truncated: f.truncated}
+}
+
+// walk 深度优先输出节点
+func (f *axFormatter) walk(node *accessibility.AXNode, depth int) {
+	if f.count >= f.opts.MaxNodes {
+		f.truncated = true
+		return
+	}
+
+	role := axString(node.Role)
+	name := axString(node.Name)
+	childDepth := depth
+
+	if f.visible(node, role, name) {
+		f.writeNode(node, role, name, depth)
+		childDepth = depth + 1
+	}
+
+	for _, id := range node.ChildIDs {
+		if child, ok := f.nodes[id]; ok {
+			f.walk(child, childDepth)
+		}
+	}
+}
+
+// visible 判断节点是否应出现在快照中
+func (f *axFormatter) visible(node *accessibility.AXNode, role, name string) bool {
+	if node.Ignored || role == "" || role == "InlineTextBox" || role == "LineBreak" {
+		return false
+	}
+	if f.opts.InteractiveOnly {
+		return interactiveRoles[role]
+	}
+	if role == "StaticText" {
+		return strings.TrimSpace(name) != ""
+	}
+	if transparentRoles[role] && name == "" {
+		return false
+	}
+	return true
+}
+
+// writeNode 输出一行节点描述
+func (f *axFormatter) writeNode(node *accessibility.AXNode, role, name string, depth int) {
+	f.count++
+	f.sb.WriteString(strings.Repeat("  ", depth))
+
+	if role == "StaticText" {
+		fmt.Fprintf(&f.sb, "- text %q\n", truncateSnapshotText(name))
+		return
+	}
+
+	f.sb.WriteString("- ")
+	f.sb.WriteString(role)
+	if name != "" {
+		fmt.Fprintf(&f.sb, " %q", truncateSnapshotText(name))
+	}
+	if node.BackendDOMNodeID != nil && *node.BackendDOMNodeID > 0 {
+		fmt.Fprintf(&f.sb, " [ref=e%d]", *node.BackendDOMNodeID)
+		f.refs++
+	}
+	for _, prop := range node.Properties {
+		for _, want := range snapshotProperties {
+			if prop.Name != want {
+				continue
+			}
+			if v := axString(&prop.Value); v != "" && v != "false" {
+				if v == "true" {
+					fmt.Fprintf(&f.sb, " [%s]", prop.Name)
+				} else {
+					fmt.Fprintf(&f.sb, " [%s=%s]", prop.Name, v)
+				}
+			}
+		}
+	}
+	if value := axString(node.Value); value != "" {
+		fmt.Fprintf(&f.sb, " value=%q", truncateSnapshotText(value))
+	}
+	f.sb.WriteString("\n")
+}
+
+// axString 将 AXValue 转为字符串
+func axString(v *accessibility.AXValue) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		return s
+	}
+	return strings.Trim(string(v.Value), `"`)
+}
+
+// truncateSnapshotText 截断过长的文本并压缩空白
+func truncateSnapshotText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > snapshotMaxTextLen {
+		return string(r[:snapshotMaxTextLen]) + "…"
+	}
+	return s
+}
+
+// parseElementRef 解析元素引用（e123 或 123）为 backendNodeId
+func parseElementRef(params map[string]interface{}) (int64, string, error) {
+	ref, _ := params["ref"].(string)
+	ref = strings.TrimSpace(ref)
+	ref = strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(ref, "[ref="), "]"), "ref=")
+	if ref == "" {
+		return 0, "", fmt.Errorf("ref parameter is required (take a browser_snapshot first)")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(ref, "e"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", fmt.Errorf("invalid element ref %q (expected a ref like e42 from browser_snapshot)", ref)
+	}
+	return id, "e" + strconv.FormatInt(id, 10), nil
+}
+
+// cdpExecutor 返回当前会话的 CDP 执行器，必要时启动会话
+func (b *BrowserTool) cdpExecutor(ctx context.Context) (*BrowserCDPExecutor, error) {
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BrowserCDPExecutor{session: sessionMgr}, nil
+}
+
+// cdpCall 执行 CDP 命令并将结果解码到 out（可为 nil）
+func cdpCall(ctx context.Context, exec CDPExecutor, method string, params map[string]interface{}, out interface{}) error {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	result, err := exec.ExecuteCDP(ctx, method, params)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	if out == nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s result: %w", method, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// BrowserSnapshot 获取当前页面的无障碍树快照
+func (b *BrowserTool) BrowserSnapshot(ctx context.Context, params map[string]interface{}) (string, error) {
+	exec, err := b.cdpExecutor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if urlStr, ok := params["url"].(string); ok && urlStr != "" {
+		if _, err := b.BrowserNavigate(ctx, map[string]interface{}{"url": urlStr}); err != nil {
+			return "", err
+		}
+	}
+
+	opts := SnapshotOptions{}
+	if v, ok := params["interactive_only"].(bool); ok {
+		opts.InteractiveOnly = v
+	}
+	if v, ok := params["max_nodes"].(float64); ok {
+		opts.MaxNodes = int(v)
+	}
+
+	_ = cdpCall(ctx, exec, "Accessibility.enable", nil, nil)
+
+	var tree accessibility.GetFullAXTreeReply
+	if err := cdpCall(ctx, exec, "Accessibility.getFullAXTree", nil, &tree); err != nil {
+		return "", err
+	}
+
+	snap := FormatAXSnapshot(tree.Nodes, opts)
+
+	var header strings.Builder
+	var page struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+	}
+	if err := cdpCall(ctx, exec, "Runtime.evaluate", map[string]interface{}{
+		"expression":    "JSON.stringify([location.href, document.title])",
+		"returnByValue": true,
+	}, &page); err == nil {
+		var info []string
+		if json.Unmarshal([]byte(page.Result.Value), &info) == nil && len(info) == 2 {
+			fmt.Fprintf(&header, "URL: %s\nTitle: %s\n", info[0], info[1])
+		}
+	}
+	fmt.Fprintf(&header, "Elements: %d (refs: %d)", snap.Nodes, snap.Refs)
+	if snap.Truncated {
+		header.WriteString(" - truncated, use interactive_only or increase max_nodes")
+	}
+	header.WriteString("\n\n")
+
+	logger.Debug("Browser snapshot taken", zap.Int("nodes", snap.Nodes), zap.Int("refs", snap.Refs))
+
+	return header.String() + snap.Text, nil
+}
+
+// elementCenter 滚动到元素并返回其中心坐标
+func elementCenter(ctx context.Context, exec CDPExecutor, backendID int64, ref string) (float64, float64, error) {
+	_ = cdpCall(ctx, exec, "DOM.scrollIntoViewIfNeeded", map[string]interface{}{"backendNodeId": backendID}, nil)
+
+	var box struct {
+		Model struct {
+			Content []float64 `json:"content"`
+		} `json:"model"`
+	}
+	if err := cdpCall(ctx, exec, "DOM.getBoxModel", map[string]interface{}{"backendNodeId": backendID}, &box); err != nil {
+		return 0, 0, fmt.Errorf("element %s not found or not visible (the page may have changed, take a new snapshot): %w", ref, err)
+	}
+	if len(box.Model.Content) < 8 {
+		return 0, 0, fmt.Errorf("invalid box model for element %s", ref)
+	}
+	c := box.Model.Content
+	return (c[0] + c[4]) / 2, (c[1] + c[5]) / 2, nil
+}
+
+// dispatchMouse 发送鼠标事件
+func dispatchMouse(ctx context.Context, exec CDPExecutor, eventType string, x, y float64, clickCount int) error {
+	params := map[string]interface{}{"type": eventType, "x": x, "y": y}
+	if eventType != "mouseMoved" {
+		params["button"] = "left"
+		params["clickCount"] = clickCount
+	}
+	return cdpCall(ctx, exec, "Input.dispatchMouseEvent", params, nil)
+}
+
+// resolveRef 将 backendNodeId 解析为运行时对象 ID
+func resolveRef(ctx context.Context, exec CDPExecutor, backendID int64, ref string) (string, error) {
+	var resolved struct {
+		Object struct {
+			ObjectID string `json:"objectId"`
+		} `json:"object"`
+	}
+	if err := cdpCall(ctx, exec, "DOM.resolveNode", map[string]interface{}{"backendNodeId": backendID}, &resolved); err != nil {
+		return "", fmt.Errorf("element %s not found (the page may have changed, take a new snapshot): %w", ref, err)
+	}
+	if resolved.Object.ObjectID == "" {
+		return "", fmt.Errorf("element %s could not be resolved", ref)
+	}
+	return resolved.Object.ObjectID, nil
+}
+
+// callOnRef 在元素上调用 JavaScript 函数并返回结果值
+func callOnRef(ctx context.Context, exec CDPExecutor, backendID int64, ref, fn string, args ...interface{}) (json.RawMessage, error) {
+	objectID, err := resolveRef(ctx, exec, backendID, ref)
+	if err != nil {
+		return nil, err
+	}
+	callArgs := make([]map[string]interface{}, 0, len(args))
+	for _, a := range args {
+		callArgs = append(callArgs, map[string]interface{}{"value": a})
+	}
+
+	var reply struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	if err := cdpCall(ctx, exec, "Runtime.callFunctionOn", map[string]interface{}{
+		"objectId":            objectID,
+		"functionDeclaration": fn,
+		"arguments":           callArgs,
+		"returnByValue":       true,
+	}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.ExceptionDetails != nil {
+		msg := reply.ExceptionDetails.Text
+		if reply.ExceptionDetails.Exception != nil && reply.ExceptionDetails.Exception.Description != "" {
+			msg = reply.ExceptionDetails.Exception.Description
+		}
+		return nil, fmt.Errorf("script error on element %s: %s", ref, msg)
+	}
+	return reply.Result.Value, nil
+}
+
+// BrowserClickRef 点击快照中的元素
+func (b *BrowserTool) BrowserClickRef(ctx context.Context, params map[string]interface{}) (string, error) {
+	backendID, ref, err := parseElementRef(params)
+	if err != nil {
+		return "", err
+	}
+	exec, err := b.cdpExecutor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	clickCount := 1
+	if double, _ := params["double_click"].(bool); double {
+		clickCount = 2
+	}
+
+	x, y, err := elementCenter(ctx, exec, backendID, ref)
+	if err != nil {
+		return "", err
+	}
+	for _, evt := range []string{"mouseMoved", "mousePressed", "mouseReleased"} {
+		if err := dispatchMouse(ctx, exec, evt, x, y, clickCount); err != nil {
+			return "", fmt.Errorf("failed to click element %s: %w", ref, err)
+		}
+	}
+	return fmt.Sprintf("Clicked element %s", ref), nil
+}
+
+// BrowserHoverRef 将鼠标悬停在快照中的元素上
+func (b *BrowserTool) BrowserHoverRef(ctx context.Context, params map[string]interface{}) (string, error) {
+	backendID, ref, err := parseElementRef(params)
+	if err != nil {
+		return "", err
+	}
+	exec, err := b.cdpExecutor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	x, y, err := elementCenter(ctx, exec, backendID, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := dispatchMouse(ctx, exec, "mouseMoved", x, y, 0); err != nil {
+		return "", fmt.Errorf("failed to hover element %s: %w", ref, err)
+	}
+	return fmt.Sprintf("Hovering over element %s", ref), nil
+}
+
+// BrowserTypeRef 向快照中的元素输入文本
+func (b *BrowserTool) BrowserTypeRef(ctx context.Context, params map[string]interface{}) (string, error) {
+	backendID, ref, err := parseElementRef(params)
+	if err != nil {
+		return "", err
+	}
+	text, ok := params["text"].(string)
+	if !ok {
+		return "", fmt.Errorf("text parameter is required")
+	}
+	exec, err := b.cdpExecutor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cdpCall(ctx, exec, "DOM.focus", map[string]interface{}{"backendNodeId": backendID}, nil); err != nil {
+		return "", fmt.Errorf("failed to focus element %s (take a new snapshot if the page changed): %w", ref, err)
+	}
+
+	// 默认先清空已有内容
+	if clear, ok := params["clear"].(bool); !ok || clear {
+		if _, err := callOnRef(ctx, exec, backendID, ref, `function() {
+			if ('value' in this) { this.value = ''; }
+			else if (this.isContentEditable) { this.textContent = ''; }
+			this.dispatchEvent(new Event('input', { bubbles: true }));
+		}`); err != nil {
+			return "", err
+		}
+	}
+
+	if err := cdpCall(ctx, exec, "Input.insertText", map[string]interface{}{"text": text}, nil); err != nil {
+		return "", fmt.Errorf("failed to type into element %s: %w", ref, err)
+	}
+	// insertText 触发 input 事件，补发 change 事件以兼容监听 change 的页面
+	_, _ = callOnRef(ctx, exec, backendID, ref, `function() { this.dispatchEvent(new Event('change', { bubbles: true })); }`)
+
+	if submit, _ := params["submit"].(bool); submit {
+		for _, evt := range []string{"keyDown", "keyUp"} {
+			keyParams := map[string]interface{}{
+				"type":                  evt,
+				"key":                   "Enter",
+				"code":                  "Enter",
+				"windowsVirtualKeyCode": 13,
+			}
+			if evt == "keyDown" {
+				keyParams["text"] = "\r"
+			}
+			if err := cdpCall(ctx, exec, "Input.dispatchKeyEvent", keyParams, nil); err != nil {
+				return "", fmt.Errorf("failed to press Enter: %w", err)
+			}
+		}
+		return fmt.Sprintf("Typed %d characters into element %s and pressed Enter", len([]rune(text)), ref), nil
+	}
+
+	return fmt.Sprintf("Typed %d characters into element %s", len([]rune(text)), ref), nil
+}
+
+// BrowserSelectRef 在快照中的下拉框里选择选项
+func (b *BrowserTool) BrowserSelectRef(ctx context.Context, params map[string]interface{}) (string, error) {
+	backendID, ref, err := parseElementRef(params)
+	if err != nil {
+		return "", err
+	}
+
+	var values []string
+	switch v := params["values"].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case string:
+		values = []string{v}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("values parameter is required")
+	}
+
+	exec, err := b.cdpExecutor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := callOnRef(ctx, exec, backendID, ref, `function(values) {
+		if (!(this instanceof HTMLSelectElement)) { throw new Error('element is not a <select>'); }
+		const selected = [];
+		for (const opt of this.options) {
+			const match = values.includes(opt.value) || values.includes(opt.label) || values.includes(opt.text.trim());
+			if (match && (this.multiple || selected.length === 0)) { selected.push(opt.label || opt.value); }
+			opt.selected = match && (this.multiple || selected.length === 1 && selected[0] === (opt.label || opt.value));
+		}
+		if (selected.length === 0) { throw new Error('no option matches ' + JSON.stringify(values)); }
+		this.dispatchEvent(new Event('input', { bubbles: true }));
+		this.dispatchEvent(new Event('change', { bubbles: true }));
+		return selected;
+	}`, values)
+	if err != nil {
+		return "", err
+	}
+
+	var selected []string
+	_ = json.Unmarshal(raw, &selected)
+	return fmt.Sprintf("Selected %s in element %s", strings.Join(selected, ", "), ref), nil
+}
+
+// snapshotTools 返回快照及基于引用的交互工具
+func (b *BrowserTool) snapshotTools() []Tool {
+	refParam := map[string]interface{}{
+		"type":        "string",
+		"description": "Element ref from browser_snapshot (e.g. 'e42')",
+	}
+
+	return []Tool{
+		NewBaseTool(
+			"browser_snapshot",
+			"Capture an accessibility-tree snapshot of the current page. Each element is listed with its role, accessible name and a ref like [ref=e42]; pass the ref to browser_click_ref, browser_type_ref, browser_select_ref or browser_hover_ref instead of guessing CSS selectors. Take a new snapshot after the page changes.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL to navigate to before taking the snapshot (optional)",
+					},
+					"interactive_only": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only list interactive elements such as links, buttons and inputs (default: false)",
+					},
+					"max_nodes": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum number of elements to list (default: 400)",
+					},
+				},
+			},
+			b.BrowserSnapshot,
+		),
+		NewBaseTool(
+			"browser_click_ref",
+			"Click an element identified by a ref from browser_snapshot",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"ref": refParam,
+					"double_click": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Double-click instead of single click (default: false)",
+					},
+				},
+				"required": []string{"ref"},
+			},
+			b.BrowserClickRef,
+		),
+		NewBaseTool(
+			"browser_type_ref",
+			"Type text into an input, textarea or contenteditable element identified by a ref from browser_snapshot",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"ref": refParam,
+					"text": map[string]interface{}{
+						"type":        "string",
+						"description": "Text to type",
+					},
+					"clear": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Clear the existing value first (default: true)",
+					},
+					"submit": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Press Enter after typing (default: false)",
+					},
+				},
+				"required": []string{"ref", "text"},
+			},
+			b.BrowserTypeRef,
+		),
+		NewBaseTool(
+			"browser_select_ref",
+			"Select option(s) in a <select> element identified by a ref from browser_snapshot, matching option value or label",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"ref": refParam,
+					"values": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Option values or labels to select",
+					},
+				},
+				"required": []string{"ref", "values"},
+			},
+			b.BrowserSelectRef,
+		),
+		NewBaseTool(
+			"browser_hover_ref",
+			"Move the mouse over an element identified by a ref from browser_snapshot (e.g. to open menus or tooltips)",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"ref": refParam,
+				},
+				"required": []string{"ref"},
+			},
+			b.BrowserHoverRef,
+		),
+	}
+}
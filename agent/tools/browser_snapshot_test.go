@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/accessibility"
+	"github.com/mafredri/cdp/protocol/dom"
+)
+
+func axValue(v string) *accessibility.AXValue {
+	raw, _ := json.Marshal(v)
+	return &accessibility.AXValue{Type: accessibility.AXValueTypeString, Value: raw}
+}
+
+func axNode(id, role, name string, backend int, children ...string) accessibility.AXNode {
+	n := accessibility.AXNode{NodeID: accessibility.AXNodeID(id), Role: axValue(role)}
+	if name != "" {
+		n.Name = axValue(name)
+	}
+	if backend > 0 {
+		b := dom.BackendNodeID(backend)
+		n.BackendDOMNodeID = &b
+	}
+	for _, c := range children {
+		n.ChildIDs = append(n.ChildIDs, accessibility.AXNodeID(c))
+	}
+	return n
+}
+
+func testAXTree() []accessibility.AXNode {
+	checked := axNode("6", "checkbox", "Remember me", 16)
+	checked.Properties = []accessibility.AXProperty{
+		{Name: accessibility.AXPropertyNameChecked, Value: accessibility.AXValue{Type: accessibility.AXValueTypeTristate, Value: json.RawMessage(`"true"`)}},
+		{Name: accessibility.AXPropertyNameFocusable, Value: accessibility.AXValue{Type: accessibility.AXValueTypeBoolean, Value: json.RawMessage(`true`)}},
+	}
+	input := axNode("5", "textbox", "Email", 15)
+	input.Value = axValue("me@example.com")
+	ignored := axNode("9", "generic", "", 19, "10")
+	ignored.Ignored = true
+
+	return []accessibility.AXNode{
+		axNode("1", "RootWebArea", "Login", 1, "2", "3"),
+		axNode("2", "heading", "Sign in", 12, "7"),
+		axNode("3", "generic", "", 13, "4", "5", "6", "9"),
+		axNode("4", "link", "Forgot password?", 14),
+		input,
+		checked,
+		axNode("7", "StaticText", "Sign in", 0, "8"),
+		axNode("8", "InlineTextBox", "Sign in", 0),
+		ignored,
+		axNode("10", "button", "Submit", 20),
+	}
+}
+
+func TestFormatAXSnapshot(t *testing.T) {
+	snap := FormatAXSnapshot(testAXTree(), SnapshotOptions{})
+
+	want := `- RootWebArea "Login" [ref=e1]
+  - heading "Sign in" [ref=e12]
+    - text "Sign in"
+  - link "Forgot password?" [ref=e14]
+  - textbox "Email" [ref=e15] value="me@example.com"
+  - checkbox "Remember me" [ref=e16] [checked]
+  - button "Submit" [ref=e20]
+`
+	if snap.Text != want {
+		t.Errorf("unexpected snapshot:\n%s\nwant:\n%s", snap.Text, want)
+	}
+	if snap.Nodes != 7 || snap.Refs != 6 || snap.Truncated {
+		t.Errorf("unexpected counts %+v", snap)
+	}
+
+	interactive := FormatAXSnapshot(testAXTree(), SnapshotOptions{InteractiveOnly: true})
+	if strings.Contains(interactive.Text, "heading") || !strings.Contains(interactive.Text, `- button "Submit" [ref=e20]`) {
+		t.Errorf("unexpected interactive snapshot:\n%s", interactive.Text)
+	}
+
+	limited := FormatAXSnapshot(testAXTree(), SnapshotOptions{MaxNodes: 2})
+	if limited.Nodes != 2 || !limited.Truncated {
+		t.Errorf("expected truncated snapshot, got %+v", limited)
+	}
+}
+
+func TestParseElementRef(t *testing.T) {
+	for _, ref := range []string{"e42", "42", "[ref=e42]", " ref=e42 "} {
+		id, norm, err := parseElementRef(map[string]interface{}{"ref": ref})
+		if err != nil || id != 42 || norm != "e42" {
+			t.Errorf("parseElementRef(%q) = %d, %q, %v", ref, id, norm, err)
+		}
+	}
+	for _, ref := range []string{"", "button", "e0", "e-1"} {
+		if _, _, err := parseElementRef(map[string]interface{}{"ref": ref}); err == nil {
+			t.Errorf("expected error for ref %q", ref)
+		}
+	}
+}
+
+// fakeCDPExecutor 记录调用并返回预设结果的 CDP 执行器
+type fakeCDPExecutor struct {
+	calls   []string
+	params  []map[string]interface{}
+	replies map[string]map[string]interface{}
+}
+
+func (f *fakeCDPExecutor) ExecuteCDP(ctx context.Context, method string, params map[string]interface{}) (map[string]interface{}, error) {
+	f.calls = append(f.calls, method)
+	f.params = append(f.params, params)
+	if r, ok := f.replies[method]; ok {
+		return r, nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (f *fakeCDPExecutor) IsDirectMode() bool { return false }
+
+func (f *fakeCDPExecutor) GetDirectClient() (*cdp.Client, error) { return nil, nil }
+
+func TestElementCenterAndMouse(t *testing.T) {
+	exec := &fakeCDPExecutor{replies: map[string]map[string]interface{}{
+		"DOM.getBoxModel": {"model": map[string]interface{}{
+			"content": []interface{}{10.0, 20.0, 110.0, 20.0, 110.0, 60.0, 10.0, 60.0},
+		}},
+	}}
+
+	x, y, err := elementCenter(context.Background(), exec, 42, "e42")
+	if err != nil {
+		t.Fatalf("elementCenter failed: %v", err)
+	}
+	if x != 60 || y != 40 {
+		t.Errorf("unexpected center (%v, %v)", x, y)
+	}
+	if exec.params[1]["backendNodeId"] != int64(42) {
+		t.Errorf("unexpected getBoxModel params %+v", exec.params[1])
+	}
+
+	if err := dispatchMouse(context.Background(), exec, "mousePressed", x, y, 1); err != nil {
+		t.Fatalf("dispatchMouse failed: %v", err)
+	}
+	last := exec.params[len(exec.params)-1]
+	if last["button"] != "left" || last["clickCount"] != 1 {
+		t.Errorf("unexpected mouse params %+v", last)
+	}
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/target"
+)
+
+// tabIDLen 展示给模型的标签页 ID 长度
+const tabIDLen = 8
+
+// BrowserTabInfo 标签页信息
+type BrowserTabInfo struct {
+	ID     string
+	Title  string
+	URL    string
+	Active bool
+}
+
+// requireHostLocked 检查会话是否支持标签页管理（调用方需持有锁）
+func (b *BrowserSessionManager) requireHostLocked() error {
+	if !b.ready {
+		return fmt.Errorf("browser session not ready")
+	}
+	if b.host == nil {
+		return fmt.Errorf("tab management requires a direct CDP browser session (not available in relay mode)")
+	}
+	return nil
+}
+
+// ListTabs 列出会话中的标签页
+func (b *BrowserSessionManager) ListTabs(ctx context.Context) ([]BrowserTabInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.requireHostLocked(); err != nil {
+		return nil, err
+	}
+	pages, err := b.host.pages(ctx, b.browserContextID)
+	if err != nil {
+		return nil, err
+	}
+
+	tabs := make([]BrowserTabInfo, 0, len(pages))
+	for _, p := range pages {
+		tabs = append(tabs, BrowserTabInfo{
+			ID:     shortTabID(p.TargetID),
+			Title:  p.Title,
+			URL:    p.URL,
+			Active: p.TargetID == b.activeTab,
+		})
+	}
+	return tabs, nil
+}
+
+// OpenTab 打开新标签页并切换到该标签页
+func (b *BrowserSessionManager) OpenTab(ctx context.Context, pageURL string) (BrowserTabInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.requireHostLocked(); err != nil {
+		return BrowserTabInfo{}, err
+	}
+	id, err := b.host.createPage(ctx, b.browserContextID, pageURL)
+	if err != nil {
+		return BrowserTabInfo{}, err
+	}
+	if err := b.switchTabLocked(ctx, id); err != nil {
+		return BrowserTabInfo{}, err
+	}
+	return BrowserTabInfo{ID: shortTabID(id), URL: pageURL, Active: true}, nil
+}
+
+// SwitchTab 切换到指定标签页（支持 ID 前缀）
+func (b *BrowserSessionManager) SwitchTab(ctx context.Context, tabID string) (BrowserTabInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.requireHostLocked(); err != nil {
+		return BrowserTabInfo{}, err
+	}
+	info, err := b.findTabLocked(ctx, tabID)
+	if err != nil {
+		return BrowserTabInfo{}, err
+	}
+	if err := b.switchTabLocked(ctx, info.TargetID); err != nil {
+		return BrowserTabInfo{}, err
+	}
+	_ = b.host.client.Target.ActivateTarget(ctx, target.NewActivateTargetArgs(info.TargetID))
+	return BrowserTabInfo{ID: shortTabID(info.TargetID), Title: info.Title, URL: info.URL, Active: true}, nil
+}
+
+// CloseTab 关闭标签页（tabID 为空时关闭当前标签页），返回关闭后的当前标签页
+// 关闭最后一个标签页时会打开一个空白页，保证会话始终有可用的标签页
+func (b *BrowserSessionManager) CloseTab(ctx context.Context, tabID string) (BrowserTabInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.requireHostLocked(); err != nil {
+		return BrowserTabInfo{}, err
+	}
+
+	id := b.activeTab
+	if tabID != "" {
+		info, err := b.findTabLocked(ctx, tabID)
+		if err != nil {
+			return BrowserTabInfo{}, err
+		}
+		id = info.TargetID
+	}
+
+	if tab, ok := b.tabs[id]; ok {
+		_ = tab.conn.Close()
+		delete(b.tabs, id)
+	}
+	if _, err := b.host.client.Target.CloseTarget(ctx, target.NewCloseTargetArgs(id)); err != nil {
+		return BrowserTabInfo{}, fmt.Errorf("failed to close tab: %w", err)
+	}
+	if id != b.activeTab {
+		return BrowserTabInfo{ID: shortTabID(b.activeTab), Active: true}, nil
+	}
+
+	// 关闭的是当前标签页，切换到剩余的标签页
+	next := target.ID("")
+	if pages, err := b.host.pages(ctx, b.browserContextID); err == nil {
+		for _, p := range pages {
+			if p.TargetID != id {
+				next = p.TargetID
+				break
+			}
+		}
+	}
+	if next == "" {
+		created, err := b.host.createPage(ctx, b.browserContextID, "")
+		if err != nil {
+			return BrowserTabInfo{}, err
+		}
+		next = created
+	}
+	if err := b.switchTabLocked(ctx, next); err != nil {
+		return BrowserTabInfo{}, err
+	}
+	return BrowserTabInfo{ID: shortTabID(next), Active: true}, nil
+}
+
+// findTabLocked 按 ID 或唯一前缀查找会话中的标签页
+func (b *BrowserSessionManager) findTabLocked(ctx context.Context, tabID string) (target.Info, error) {
+	pages, err := b.host.pages(ctx, b.browserContextID)
+	if err != nil {
+		return target.Info{}, err
+	}
+	return matchTab(pages, tabID)
+}
+
+// matchTab 按 ID 或唯一前缀（不区分大小写）匹配标签页
+func matchTab(pages []target.Info, tabID string) (target.Info, error) {
+	tabID = strings.ToUpper(strings.TrimSpace(tabID))
+	if tabID == "" {
+		return target.Info{}, fmt.Errorf("tab_id is required")
+	}
+
+	var matches []target.Info
+	for _, p := range pages {
+		id := strings.ToUpper(string(p.TargetID))
+		if id == tabID {
+			return p, nil
+		}
+		if strings.HasPrefix(id, tabID) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return target.Info{}, fmt.Errorf("tab %s not found (use browser_tabs to list tabs)", tabID)
+	case 1:
+		return matches[0], nil
+	default:
+		return target.Info{}, fmt.Errorf("tab id %s is ambiguous", tabID)
+	}
+}
+
+// shortTabID 返回标签页 ID 的短格式
+func shortTabID(id target.ID) string {
+	if len(id) > tabIDLen {
+		return string(id[:tabIDLen])
+	}
+	return string(id)
+}
+
+// BrowserTabs 列出当前会话的标签页
+func (b *BrowserTool) BrowserTabs(ctx context.Context, params map[string]interface{}) (string, error) {
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	tabs, err := sessionMgr.ListTabs(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(tabs) == 0 {
+		return "No open tabs", nil
+	}
+
+	var sb strings.Builder
+	for _, tab := range tabs {
+		marker := " "
+		if tab.Active {
+			marker = "*"
+		}
+		title := tab.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Fprintf(&sb, "%s %s  %s\n    %s\n", marker, tab.ID, title, tab.URL)
+	}
+	return sb.String(), nil
+}
+
+// BrowserTabOpen 打开新标签页
+func (b *BrowserTool) BrowserTabOpen(ctx context.Context, params map[string]interface{}) (string, error) {
+	urlStr, _ := params["url"].(string)
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	tab, err := sessionMgr.OpenTab(ctx, urlStr)
+	if err != nil {
+		return "", err
+	}
+	if urlStr == "" {
+		urlStr = "about:blank"
+	}
+	return fmt.Sprintf("Opened tab %s (%s) and switched to it", tab.ID, urlStr), nil
+}
+
+// BrowserTabSwitch 切换标签页
+func (b *BrowserTool) BrowserTabSwitch(ctx context.Context, params map[string]interface{}) (string, error) {
+	tabID, _ := params["tab_id"].(string)
+	if tabID == "" {
+		return "", fmt.Errorf("tab_id parameter is required")
+	}
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	tab, err := sessionMgr.SwitchTab(ctx, tabID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Switched to tab %s: %s\n%s", tab.ID, tab.Title, tab.URL), nil
+}
+
+// BrowserTabClose 关闭标签页
+func (b *BrowserTool) BrowserTabClose(ctx context.Context, params map[string]interface{}) (string, error) {
+	tabID, _ := params["tab_id"].(string)
+	sessionMgr, err := b.browserSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	active, err := sessionMgr.CloseTab(ctx, tabID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Tab closed, current tab is %s", active.ID), nil
+}
+
+// tabTools 返回标签页管理工具
+func (b *BrowserTool) tabTools() []Tool {
+	return []Tool{
+		NewBaseTool(
+			"browser_tabs",
+			"List the browser tabs of this conversation; the current tab is marked with *",
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+			b.BrowserTabs,
+		),
+		NewBaseTool(
+			"browser_tab_open",
+			"Open a new browser tab and make it the current tab",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL to open (default: about:blank)",
+					},
+				},
+			},
+			b.BrowserTabOpen,
+		),
+		NewBaseTool(
+			"browser_tab_switch",
+			"Switch the current browser tab; subsequent browser tools act on this tab",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"tab_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Tab ID from browser_tabs",
+					},
+				},
+				"required": []string{"tab_id"},
+			},
+			b.BrowserTabSwitch,
+		),
+		NewBaseTool(
+			"browser_tab_close",
+			"Close a browser tab (the current tab if tab_id is omitted)",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"tab_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Tab ID from browser_tabs (optional)",
+					},
+				},
+			},
+			b.BrowserTabClose,
+		),
+	}
+}
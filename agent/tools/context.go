@@ -0,0 +1,43 @@
+package tools
+
+import "context"
+
+// contextKey 工具上下文键类型
+type contextKey string
+
+const (
+	sessionKeyContextKey contextKey = "session_key"
+	agentIDContextKey    contextKey = "agent_id"
+)
+
+// WithSessionKey 将会话键写入工具执行上下文
+func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyContextKey, sessionKey)
+}
+
+// SessionKeyFromContext 从工具执行上下文中读取会话键，不存在时返回空字符串
+func SessionKeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if key, ok := ctx.Value(sessionKeyContextKey).(string); ok {
+		return key
+	}
+	return ""
+}
+
+// WithAgentID 将 Agent ID 写入工具执行上下文
+func WithAgentID(ctx context.Context, agentID string) context.Context {
+	return context.WithValue(ctx, agentIDContextKey, agentID)
+}
+
+// AgentIDFromContext 从工具执行上下文中读取 Agent ID，不存在时返回空字符串
+func AgentIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(agentIDContextKey).(string); ok {
+		return id
+	}
+	return ""
+}
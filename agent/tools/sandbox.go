@@ -0,0 +1,652 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	units "github.com/docker/go-units"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// SandboxLabel 标记由 goclaw 管理的沙箱容器
+	SandboxLabel = "goclaw.sandbox"
+	// SandboxScopeLabel 记录沙箱容器所属的范围键（会话或 Agent）
+	SandboxScopeLabel = "goclaw.sandbox.scope"
+
+	sandboxScopeSession = "session"
+	sandboxScopeAgent   = "agent"
+	sandboxScopeNone    = "none"
+
+	defaultSandboxIdleTimeout = 30 * time.Minute
+)
+
+// SandboxInfo 沙箱容器信息
+type SandboxInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scope     string    `json:"scope"`
+	Image     string    `json:"image"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used,omitempty"`
+}
+
+// sandboxContainer 被管理的持久化沙箱容器
+type sandboxContainer struct {
+	id       string
+	name     string
+	scope    string
+	lastUsed time.Time
+	busy     int        // 正在执行的命令数，忙碌的容器不会被空闲回收
+	mu       sync.Mutex // 串行化同一容器的创建
+}
+
+// SandboxManager 持久化沙箱管理器
+// 每个会话（或 Agent）复用一个长期运行的容器，命令通过 docker exec 执行
+type SandboxManager struct {
+	client     *client.Client
+	cfg        config.SandboxConfig
+	hostDir    string
+	mu         sync.Mutex
+	containers map[string]*sandboxContainer
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+}
+
+// NewSandboxManager 创建沙箱管理器
+func NewSandboxManager(cli *client.Client, cfg config.SandboxConfig, hostDir string) *SandboxManager {
+	return &SandboxManager{
+		client:     cli,
+		cfg:        cfg,
+		hostDir:    hostDir,
+		containers: make(map[string]*sandboxContainer),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// ScopeKey 根据配置的复用范围从上下文中计算容器键
+func (m *SandboxManager) ScopeKey(ctx context.Context) string {
+	switch m.cfg.Scope {
+	case sandboxScopeAgent:
+		if id := AgentIDFromContext(ctx); id != "" {
+			return "agent:" + id
+		}
+		return "agent:default"
+	default:
+		if key := SessionKeyFromContext(ctx); key != "" {
+			return "session:" + key
+		}
+		return "session:main"
+	}
+}
+
+// Exec 在范围对应的容器中执行命令，必要时创建容器
+func (m *SandboxManager) Exec(ctx context.Context, scope, command string) (string, error) {
+	c, err := m.ensure(ctx, scope)
+	if err != nil {
+		return "", err
+	}
+	defer m.done(c)
+
+	// 包装命令记录进程 PID，上下文取消时据此结束容器内的进程
+	pidFile := sandboxPIDFile()
+	execResp, err := m.client.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", sandboxExecWrapper, pidFile, command},
+		WorkingDir:   m.cfg.Workdir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := m.client.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer attach.Close()
+
+	// 读取输出，直到命令结束或上下文取消
+	var stdout, stderr bytes.Buffer
+	copyDone := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(&stdout, &stderr, attach.Reader)
+		copyDone <- err
+	}()
+
+	select {
+	case err := <-copyDone:
+		if err != nil {
+			return "", fmt.Errorf("failed to read exec output: %w", err)
+		}
+	case <-ctx.Done():
+		m.killExec(c.id, pidFile)
+		return "", ctx.Err()
+	}
+
+	output := stdout.String() + stderr.String()
+	inspect, err := m.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return "", fmt.Errorf("command exited with code %d, output: %s", inspect.ExitCode, output)
+	}
+	return output, nil
+}
+
+// StartBackground 在范围对应的容器中启动后台命令，输出持续写入 stdout/stderr
+// 返回的句柄和标准输入供 ProcessManager 跟踪，进程在本次调用返回后继续运行
+func (m *SandboxManager) StartBackground(ctx context.Context, scope, command string, stdout, stderr io.Writer) (processHandle, io.WriteCloser, error) {
+	c, err := m.ensure(ctx, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	started := false
+	defer func() {
+		if !started {
+			m.done(c)
+		}
+	}()
+
+	pidFile := sandboxPIDFile()
+	execResp, err := m.client.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", sandboxExecWrapper, pidFile, command},
+		WorkingDir:   m.cfg.Workdir,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// 连接的生命周期跟随进程而不是本次工具调用
+	attach, err := m.client.ContainerExecAttach(context.Background(), execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to attach exec: %w", err)
+	}
+
+	proc := &sandboxProcess{
+		manager:     m,
+		container:   c,
+		containerID: c.id,
+		execID:      execResp.ID,
+		pidFile:     pidFile,
+		attach:      attach,
+		stdout:      stdout,
+		stderr:      stderr,
+	}
+	if inspect, err := m.client.ContainerExecInspect(ctx, execResp.ID); err == nil {
+		proc.pid = inspect.Pid
+	}
+	started = true
+	return proc, sandboxStdin{attach: attach}, nil
+}
+
+// sandboxProcess 在沙箱容器中通过 docker exec 运行的后台进程
+type sandboxProcess struct {
+	manager     *SandboxManager
+	container   *sandboxContainer
+	containerID string
+	execID      string
+	pidFile     string
+	pid         int
+	attach      types.HijackedResponse
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+func (p *sandboxProcess) PID() int {
+	return p.pid
+}
+
+// Wait 持续复制输出直到进程结束，然后读取退出码
+func (p *sandboxProcess) Wait() int {
+	_, _ = stdcopy.StdCopy(p.stdout, p.stderr, p.attach.Reader)
+	p.attach.Close()
+	p.manager.done(p.container)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	inspect, err := p.manager.client.ContainerExecInspect(ctx, p.execID)
+	if err != nil || inspect.Running {
+		return -1
+	}
+	return inspect.ExitCode
+}
+
+// Terminate 结束容器内的进程树
+// 容器内没有进程组可用，直接发送 SIGKILL；force 时同时断开连接，确保 Wait 返回
+func (p *sandboxProcess) Terminate(force bool) {
+	p.manager.killExec(p.containerID, p.pidFile)
+	if force {
+		p.attach.Close()
+	}
+}
+
+// sandboxStdin 将写入转发到 exec 的标准输入，Close 只关闭写方向，输出仍可继续读取
+type sandboxStdin struct {
+	attach types.HijackedResponse
+}
+
+func (s sandboxStdin) Write(p []byte) (int, error) {
+	return s.attach.Conn.Write(p)
+}
+
+func (s sandboxStdin) Close() error {
+	return s.attach.CloseWrite()
+}
+
+// killExec 结束容器内由 Exec 启动的进程及其子进程
+func (m *SandboxManager) killExec(containerID, pidFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	execResp, err := m.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd: []string{"sh", "-c", sandboxKillScript, pidFile},
+	})
+	if err == nil {
+		err = m.client.ContainerExecStart(ctx, execResp.ID, container.ExecStartOptions{Detach: true})
+	}
+	if err != nil {
+		logger.Warn("Failed to kill cancelled sandbox command",
+			zap.String("container", containerID),
+			zap.Error(err))
+	}
+}
+
+// Release 删除范围对应的容器
+func (m *SandboxManager) Release(ctx context.Context, scope string) error {
+	m.mu.Lock()
+	c, ok := m.containers[scope]
+	delete(m.containers, scope)
+	m.mu.Unlock()
+
+	// 未被当前进程跟踪时按名称删除，覆盖重启前遗留的容器
+	target := SandboxContainerName(scope)
+	if ok && c.id != "" {
+		target = c.id
+	}
+	err := m.client.ContainerRemove(ctx, target, container.RemoveOptions{Force: true})
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	logger.Info("Sandbox container released",
+		zap.String("scope", scope),
+		zap.String("container", SandboxContainerName(scope)))
+	return nil
+}
+
+// PruneIdle 删除空闲超过 idle 的容器，返回删除数量
+// 正在执行命令（包括后台进程）的容器不会被删除
+func (m *SandboxManager) PruneIdle(ctx context.Context, idle time.Duration) int {
+	now := time.Now()
+	expired := make(map[string]*sandboxContainer)
+
+	// 在锁内摘除，之后的 ensure 会重新登记而不会拿到正在删除的容器
+	m.mu.Lock()
+	for scope, c := range m.containers {
+		c.mu.Lock()
+		if c.busy == 0 && now.Sub(c.lastUsed) > idle {
+			expired[scope] = c
+			delete(m.containers, scope)
+		}
+		c.mu.Unlock()
+	}
+	m.mu.Unlock()
+
+	for scope, c := range expired {
+		target := c.name
+		if c.id != "" {
+			target = c.id
+		}
+		if err := m.client.ContainerRemove(ctx, target, container.RemoveOptions{Force: true}); err != nil && !client.IsErrNotFound(err) {
+			logger.Warn("Failed to remove idle sandbox container",
+				zap.String("scope", scope),
+				zap.Error(err))
+		}
+	}
+	return len(expired)
+}
+
+// StartGC 启动空闲容器回收
+func (m *SandboxManager) StartGC() {
+	idle := m.idleTimeout()
+	interval := idle / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				if n := m.PruneIdle(ctx, idle); n > 0 {
+					logger.Info("Idle sandbox containers pruned", zap.Int("count", n))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// List 列出当前进程管理的容器
+func (m *SandboxManager) List() []SandboxInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	infos := make([]SandboxInfo, 0, len(m.containers))
+	for _, c := range m.containers {
+		c.mu.Lock()
+		infos = append(infos, SandboxInfo{
+			ID:       c.id,
+			Name:     c.name,
+			Scope:    c.scope,
+			Image:    m.cfg.Image,
+			State:    "running",
+			LastUsed: c.lastUsed,
+		})
+		c.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Scope < infos[j].Scope })
+	return infos
+}
+
+// Close 停止空闲回收
+// 容器本身保留，下次启动时按名称复用，可通过 goclaw sandbox prune 清理
+func (m *SandboxManager) Close() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// ensure 返回范围对应的运行中容器并标记为忙碌，不存在时创建
+// 调用方用完后需调用 done
+func (m *SandboxManager) ensure(ctx context.Context, scope string) (*sandboxContainer, error) {
+	m.mu.Lock()
+	c, ok := m.containers[scope]
+	if !ok {
+		c = &sandboxContainer{name: SandboxContainerName(scope), scope: scope}
+		m.containers[scope] = c
+	}
+	m.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.id != "" {
+		c.lastUsed = time.Now()
+		c.busy++
+		return c, nil
+	}
+
+	hostConfig, err := m.hostConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// 复用同名容器（例如 goclaw 重启后遗留的容器），配置已变更时重建
+	if inspect, err := m.client.ContainerInspect(ctx, c.name); err == nil {
+		if sandboxMatches(inspect, m.cfg.Image, hostConfig) {
+			if inspect.State == nil || !inspect.State.Running {
+				if err := m.client.ContainerStart(ctx, inspect.ID, container.StartOptions{}); err != nil {
+					return nil, fmt.Errorf("failed to start sandbox container: %w", err)
+				}
+			}
+			c.id = inspect.ID
+			c.lastUsed = time.Now()
+			c.busy++
+			return c, nil
+		}
+		logger.Info("Sandbox config changed, recreating container",
+			zap.String("scope", scope),
+			zap.String("container", c.name))
+		if err := m.client.ContainerRemove(ctx, inspect.ID, container.RemoveOptions{Force: true}); err != nil && !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("failed to remove outdated sandbox container: %w", err)
+		}
+	} else if !client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("failed to inspect sandbox container: %w", err)
+	}
+
+	resp, err := m.client.ContainerCreate(ctx, &container.Config{
+		Image:      m.cfg.Image,
+		Cmd:        []string{"sh", "-c", "trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done"},
+		WorkingDir: m.cfg.Workdir,
+		Labels: map[string]string{
+			SandboxLabel:      "true",
+			SandboxScopeLabel: scope,
+		},
+	}, hostConfig, nil, nil, c.name)
+	if err != nil {
+		m.forget(scope, c)
+		return nil, fmt.Errorf("failed to create sandbox container: %w", err)
+	}
+
+	if err := m.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		_ = m.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		m.forget(scope, c)
+		return nil, fmt.Errorf("failed to start sandbox container: %w", err)
+	}
+
+	logger.Info("Sandbox container created",
+		zap.String("scope", scope),
+		zap.String("container", c.name),
+		zap.String("image", m.cfg.Image))
+
+	c.id = resp.ID
+	c.lastUsed = time.Now()
+	c.busy++
+	return c, nil
+}
+
+// forget 创建失败时移除占位记录
+func (m *SandboxManager) forget(scope string, c *sandboxContainer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.containers[scope] == c {
+		delete(m.containers, scope)
+	}
+}
+
+// done 结束一次由 ensure 标记的执行，并更新容器最近使用时间
+func (m *SandboxManager) done(c *sandboxContainer) {
+	c.mu.Lock()
+	c.busy--
+	c.lastUsed = time.Now()
+	c.mu.Unlock()
+}
+
+// idleTimeout 返回空闲回收时间
+func (m *SandboxManager) idleTimeout() time.Duration {
+	if m.cfg.IdleTimeout > 0 {
+		return time.Duration(m.cfg.IdleTimeout) * time.Second
+	}
+	return defaultSandboxIdleTimeout
+}
+
+// hostConfig 构建包含资源限制和挂载的 HostConfig
+func (m *SandboxManager) hostConfig() (*container.HostConfig, error) {
+	return BuildSandboxHostConfig(m.cfg, m.hostDir)
+}
+
+// BuildSandboxHostConfig 根据沙箱配置构建容器 HostConfig
+// 工作区以读写方式挂载，其余挂载一律只读
+func BuildSandboxHostConfig(cfg config.SandboxConfig, hostDir string) (*container.HostConfig, error) {
+	if hostDir == "" {
+		hostDir = "."
+	}
+	absDir, err := filepath.Abs(hostDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+
+	binds := []string{absDir + ":" + cfg.Workdir + ":rw"}
+	for _, mount := range cfg.Mounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid sandbox mount: %s", mount)
+		}
+		binds = append(binds, parts[0]+":"+parts[1]+":ro")
+	}
+
+	hc := &container.HostConfig{
+		Binds:          binds,
+		NetworkMode:    container.NetworkMode(cfg.Network),
+		Privileged:     cfg.Privileged,
+		ReadonlyRootfs: cfg.ReadOnlyRoot,
+	}
+	if cfg.ReadOnlyRoot {
+		hc.Tmpfs = map[string]string{"/tmp": "rw,exec,size=256m"}
+	}
+	if err := applySandboxResources(hc, cfg); err != nil {
+		return nil, err
+	}
+
+	return hc, nil
+}
+
+// applySandboxResources 将沙箱配置中的 CPU、内存、进程数和磁盘限制写入 hc
+func applySandboxResources(hc *container.HostConfig, cfg config.SandboxConfig) error {
+	if cfg.CPUs > 0 {
+		hc.Resources.NanoCPUs = int64(cfg.CPUs * 1e9)
+	}
+	if cfg.Memory != "" {
+		mem, err := units.RAMInBytes(cfg.Memory)
+		if err != nil {
+			return fmt.Errorf("invalid sandbox memory limit %q: %w", cfg.Memory, err)
+		}
+		hc.Resources.Memory = mem
+	}
+	if cfg.PidsLimit > 0 {
+		pids := cfg.PidsLimit
+		hc.Resources.PidsLimit = &pids
+	}
+	if cfg.DiskSize != "" {
+		hc.StorageOpt = map[string]string{"size": cfg.DiskSize}
+	}
+	return nil
+}
+
+// sandboxExecWrapper 将自身 PID 写入 $0 后执行 $1，命令结束时删除 PID 文件
+const sandboxExecWrapper = `echo $$ > "$0"; sh -c "$1"; rc=$?; rm -f "$0"; exit $rc`
+
+// sandboxKillScript 结束 $0 中记录的进程树（先子进程后父进程），没有 pgrep 时只结束记录的进程
+const sandboxKillScript = `pid=$(cat "$0" 2>/dev/null) || exit 0
+kill_tree() {
+	for child in $(pgrep -P "$1" 2>/dev/null); do kill_tree "$child"; done
+	kill -KILL "$1" 2>/dev/null
+}
+kill_tree "$pid"
+rm -f "$0"`
+
+// sandboxPIDFile 为一次 Exec 生成唯一的 PID 文件路径
+func sandboxPIDFile() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return "/tmp/goclaw-exec-" + hex.EncodeToString(b[:]) + ".pid"
+}
+
+// sandboxMatches 检查已有容器的镜像、挂载、网络、资源限制、磁盘大小和 tmpfs 是否与当前配置一致
+func sandboxMatches(inspect container.InspectResponse, image string, want *container.HostConfig) bool {
+	if inspect.ContainerJSONBase == nil || inspect.HostConfig == nil || inspect.Config == nil {
+		return false
+	}
+	have := inspect.HostConfig
+	if inspect.Config.Image != image {
+		return false
+	}
+	if !slices.Equal(have.Binds, want.Binds) {
+		return false
+	}
+	if normalizeNetworkMode(have.NetworkMode) != normalizeNetworkMode(want.NetworkMode) {
+		return false
+	}
+	if have.Privileged != want.Privileged || have.ReadonlyRootfs != want.ReadonlyRootfs {
+		return false
+	}
+	if have.Resources.NanoCPUs != want.Resources.NanoCPUs || have.Resources.Memory != want.Resources.Memory {
+		return false
+	}
+	if !maps.Equal(have.StorageOpt, want.StorageOpt) || !maps.Equal(have.Tmpfs, want.Tmpfs) {
+		return false
+	}
+	return pidsLimit(have.Resources.PidsLimit) == pidsLimit(want.Resources.PidsLimit)
+}
+
+// normalizeNetworkMode 将未设置的网络模式视为 Docker 默认网络
+func normalizeNetworkMode(mode container.NetworkMode) string {
+	if mode == "" || mode == "default" {
+		return "bridge"
+	}
+	return string(mode)
+}
+
+// pidsLimit 将未设置和非正数的进程数限制统一视为不限制
+func pidsLimit(limit *int64) int64 {
+	if limit == nil || *limit <= 0 {
+		return 0
+	}
+	return *limit
+}
+
+// SandboxContainerName 根据范围键生成稳定的容器名
+func SandboxContainerName(scope string) string {
+	sum := sha256.Sum256([]byte(scope))
+	return "goclaw-sandbox-" + hex.EncodeToString(sum[:6])
+}
+
+// ListSandboxContainers 通过 Docker API 列出所有 goclaw 沙箱容器（包括其他进程创建的）
+func ListSandboxContainers(ctx context.Context, cli *client.Client) ([]SandboxInfo, error) {
+	list, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", SandboxLabel+"=true")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	infos := make([]SandboxInfo, 0, len(list))
+	for _, c := range list {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		infos = append(infos, SandboxInfo{
+			ID:        c.ID,
+			Name:      name,
+			Scope:     c.Labels[SandboxScopeLabel],
+			Image:     c.Image,
+			State:     c.State,
+			CreatedAt: time.Unix(c.Created, 0),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos, nil
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestBuildSandboxHostConfig(t *testing.T) {
+	hc, err := BuildSandboxHostConfig(config.SandboxConfig{
+		Workdir:      "/workspace",
+		Network:      "none",
+		CPUs:         1.5,
+		Memory:       "512m",
+		PidsLimit:    64,
+		DiskSize:     "1G",
+		ReadOnlyRoot: true,
+		Mounts:       []string{"/opt/data:/data", "/etc/hosts:/etc/hosts:rw"},
+	}, "/tmp/ws")
+	if err != nil {
+		t.Fatalf("BuildSandboxHostConfig failed: %v", err)
+	}
+
+	want := []string{"/tmp/ws:/workspace:rw", "/opt/data:/data:ro", "/etc/hosts:/etc/hosts:ro"}
+	if strings.Join(hc.Binds, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected binds %v", hc.Binds)
+	}
+	if hc.Resources.NanoCPUs != 1_500_000_000 {
+		t.Errorf("unexpected NanoCPUs %d", hc.Resources.NanoCPUs)
+	}
+	if hc.Resources.Memory != 512*1024*1024 {
+		t.Errorf("unexpected memory %d", hc.Resources.Memory)
+	}
+	if hc.Resources.PidsLimit == nil || *hc.Resources.PidsLimit != 64 {
+		t.Errorf("unexpected pids limit %v", hc.Resources.PidsLimit)
+	}
+	if hc.StorageOpt["size"] != "1G" {
+		t.Errorf("unexpected storage opt %v", hc.StorageOpt)
+	}
+	if !hc.ReadonlyRootfs || hc.Tmpfs["/tmp"] == "" {
+		t.Error("expected read-only root with tmpfs /tmp")
+	}
+
+	if _, err := BuildSandboxHostConfig(config.SandboxConfig{Memory: "lots"}, "/tmp/ws"); err == nil {
+		t.Error("expected invalid memory limit to fail")
+	}
+}
+
+func TestSandboxScopeKey(t *testing.T) {
+	ctx := WithAgentID(WithSessionKey(context.Background(), "telegram:bot:42"), "coder")
+
+	session := NewSandboxManager(nil, config.SandboxConfig{Scope: "session"}, "")
+	if key := session.ScopeKey(ctx); key != "session:telegram:bot:42" {
+		t.Errorf("unexpected session scope key %q", key)
+	}
+
+	agentScoped := NewSandboxManager(nil, config.SandboxConfig{Scope: "agent"}, "")
+	if key := agentScoped.ScopeKey(ctx); key != "agent:coder" {
+		t.Errorf("unexpected agent scope key %q", key)
+	}
+
+	if SandboxContainerName("session:a") == SandboxContainerName("session:b") {
+		t.Error("expected distinct container names for distinct scopes")
+	}
+	if SandboxContainerName("session:a") != SandboxContainerName("session:a") {
+		t.Error("expected stable container names")
+	}
+}
+
+func TestSandboxExecWrapperKill(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	pidFile := filepath.Join(t.TempDir(), "exec.pid")
+
+	// The wrapper and kill script are plain sh, so they can be exercised on the host
+	cmd := exec.Command("sh", "-c", sandboxExecWrapper, pidFile, "sleep 30; echo done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(pidFile); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("wrapper did not record its PID")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if out, err := exec.Command("sh", "-c", sandboxKillScript, pidFile).CombinedOutput(); err != nil {
+		t.Fatalf("kill script failed: %v, %s", err, out)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the wrapped command to be killed")
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Error("expected the kill script to remove the PID file")
+	}
+
+	// A command that finishes normally cleans up its PID file and keeps the exit code
+	err := exec.Command("sh", "-c", sandboxExecWrapper, pidFile, "exit 3").Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("expected exit code 3, got %v", err)
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Error("expected the wrapper to remove the PID file")
+	}
+}
+
+func TestSandboxMatches(t *testing.T) {
+	cfg := config.SandboxConfig{Image: "alpine:3", Workdir: "/workspace", Network: "none", CPUs: 1, Memory: "256m"}
+	want, err := BuildSandboxHostConfig(cfg, "/tmp/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := func(hc container.HostConfig, image string) container.InspectResponse {
+		return container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{HostConfig: &hc},
+			Config:            &container.Config{Image: image},
+		}
+	}
+
+	if !sandboxMatches(existing(*want, "alpine:3"), cfg.Image, want) {
+		t.Error("expected a container built from the same config to be reused")
+	}
+	if sandboxMatches(existing(*want, "alpine:2"), cfg.Image, want) {
+		t.Error("expected an image change to force a rebuild")
+	}
+
+	for name, change := range map[string]func(*config.SandboxConfig){
+		"memory":  func(c *config.SandboxConfig) { c.Memory = "1g" },
+		"cpus":    func(c *config.SandboxConfig) { c.CPUs = 2 },
+		"network": func(c *config.SandboxConfig) { c.Network = "bridge" },
+		"pids":    func(c *config.SandboxConfig) { c.PidsLimit = 32 },
+		"disk":    func(c *config.SandboxConfig) { c.DiskSize = "10G" },
+	} {
+		changed := cfg
+		change(&changed)
+		old, err := BuildSandboxHostConfig(changed, "/tmp/ws")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sandboxMatches(existing(*old, "alpine:3"), cfg.Image, want) {
+			t.Errorf("expected a %s change to force a rebuild", name)
+		}
+	}
+
+	tmpfs := *want
+	tmpfs.Tmpfs = map[string]string{"/tmp": "rw,exec,size=64m"}
+	if sandboxMatches(existing(tmpfs, "alpine:3"), cfg.Image, want) {
+		t.Error("expected a tmpfs change to force a rebuild")
+	}
+}
+
+func TestSandboxPruneIdleSkipsBusy(t *testing.T) {
+	m := NewSandboxManager(nil, config.SandboxConfig{}, "")
+	c := &sandboxContainer{id: "abc", scope: "session:a", lastUsed: time.Now().Add(-time.Hour), busy: 1}
+	m.containers["session:a"] = c
+
+	// A long exec keeps the container alive past the idle timeout
+	if n := m.PruneIdle(context.Background(), time.Minute); n != 0 {
+		t.Fatalf("expected a busy container to be kept, pruned %d", n)
+	}
+	m.done(c)
+	if c.busy != 0 || time.Since(c.lastUsed) > time.Minute {
+		t.Errorf("expected done to clear busy and refresh last use, got busy=%d last=%v", c.busy, c.lastUsed)
+	}
+	if n := m.PruneIdle(context.Background(), time.Minute); n != 0 {
+		t.Errorf("expected a just-used container to be kept, pruned %d", n)
+	}
+}
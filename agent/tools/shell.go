@@ -1,7 +1,9 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os/exec"
@@ -25,6 +27,10 @@ type ShellTool struct {
 	workingDir    string
 	sandboxConfig config.SandboxConfig
 	dockerClient  *client.Client
+	sandboxes     *SandboxManager
+	processes     *ProcessManager
+	policy        *ShellPolicy
+	approver      Approver
 }
 
 // NewShellTool 创建 Shell 工具
@@ -49,12 +55,21 @@ func NewShellTool(
 		timeout:       t,
 		workingDir:    workingDir,
 		sandboxConfig: sandboxConfig,
+		processes:     NewProcessManager(),
 	}
 
+	// 旧版 allowed/denied 列表转换为基于解析的策略
+	st.policy, _ = NewShellPolicy(allowedCmds, deniedCmds, config.ShellPolicyConfig{}, workingDir)
+
 	// 如果启用沙箱，初始化 Docker 客户端
 	if sandboxConfig.Enabled {
 		if cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation()); err == nil {
 			st.dockerClient = cli
+			// 非 none 范围使用持久化容器，按会话或 Agent 复用
+			if sandboxConfig.Scope != sandboxScopeNone {
+				st.sandboxes = NewSandboxManager(cli, sandboxConfig, workingDir)
+				st.sandboxes.StartGC()
+			}
 		} else {
 			zap.L().Warn("Failed to initialize Docker client, sandbox disabled", zap.Error(err))
 			st.sandboxConfig.Enabled = false
@@ -66,6 +81,11 @@ func NewShellTool(
 
 // Exec 执行 Shell 命令
 func (t *ShellTool) Exec(ctx context.Context, params map[string]interface{}) (string, error) {
+	return t.ExecStreaming(ctx, params, nil)
+}
+
+// ExecStreaming 执行 Shell 命令，并通过 onUpdate 推送增量输出
+func (t *ShellTool) ExecStreaming(ctx context.Context, params map[string]interface{}, onUpdate func(ToolResult)) (string, error) {
 	if !t.enabled {
 		return "", fmt.Errorf("shell tool is disabled")
 	}
@@ -75,20 +95,49 @@ func (t *ShellTool) Exec(ctx context.Context, params map[string]interface{}) (st
 		return "", fmt.Errorf("command parameter is required")
 	}
 
-	// 检查危险命令
-	if t.isDenied(command) {
-		return "", fmt.Errorf("command is not allowed: %s", command)
+	// 按策略检查命令
+	if err := t.checkPolicy(ctx, command); err != nil {
+		return "", err
+	}
+
+	useSandbox := t.sandboxConfig.Enabled && t.dockerClient != nil
+
+	// 后台模式：立即返回进程句柄
+	if background, _ := params["background"].(bool); background {
+		return t.startBackground(ctx, command, useSandbox)
 	}
 
 	// 根据是否启用沙箱选择执行方式
-	if t.sandboxConfig.Enabled && t.dockerClient != nil {
+	if useSandbox {
 		return t.execInSandbox(ctx, command)
 	}
-	return t.execDirect(ctx, command)
+	return t.execDirect(ctx, command, onUpdate)
+}
+
+// streamWriter 收集输出并将每个数据块推送给 onUpdate
+type streamWriter struct {
+	buf      bytes.Buffer
+	stream   string
+	mu       *sync.Mutex
+	onUpdate func(ToolResult)
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	if w.onUpdate != nil {
+		w.onUpdate(ToolResult{
+			Content: []ContentBlock{TextContent{Text: string(p)}},
+			Details: map[string]any{"stream": w.stream, "partial": true},
+		})
+	}
+	return len(p), nil
 }
 
 // execDirect 直接执行命令
-func (t *ShellTool) execDirect(ctx context.Context, command string) (string, error) {
+func (t *ShellTool) execDirect(ctx context.Context, command string, onUpdate func(ToolResult)) (string, error) {
 	// 执行命令
 	cmd := exec.Command("sh", "-c", command)
 	if t.workingDir != "" {
@@ -100,21 +149,15 @@ func (t *ShellTool) execDirect(ctx context.Context, command string) (string, err
 		Setpgid: true,
 	}
 
-	// 获取输出管道
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		stdout.Close()
-		return "", fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
+	// 输出写入缓冲区，同时推送增量更新
+	var mu sync.Mutex
+	stdout := &streamWriter{stream: "stdout", mu: &mu, onUpdate: onUpdate}
+	stderr := &streamWriter{stream: "stderr", mu: &mu, onUpdate: onUpdate}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	// 启动命令
 	if err := cmd.Start(); err != nil {
-		stdout.Close()
-		stderr.Close()
 		return "", fmt.Errorf("failed to start command: %w", err)
 	}
 
@@ -128,45 +171,17 @@ func (t *ShellTool) execDirect(ctx context.Context, command string) (string, err
 	go func() {
 		defer close(resultCh)
 
-		// 读取输出 - 分别读取以避免死锁
-		var stdoutBuf, stderrBuf []byte
-		var stdoutErr, stderrErr error
-
-		// 使用 goroutine 并行读取 stdout 和 stderr
-		var wg sync.WaitGroup
-		wg.Add(2)
-
-		go func() {
-			defer wg.Done()
-			stdoutBuf, stdoutErr = io.ReadAll(stdout)
-			stdout.Close()
-		}()
-
-		go func() {
-			defer wg.Done()
-			stderrBuf, stderrErr = io.ReadAll(stderr)
-			stderr.Close()
-		}()
-
-		wg.Wait()
-
-		// 等待命令完成
+		// 等待命令完成（Wait 会等待输出复制结束）
 		waitErr := cmd.Wait()
 
 		// 组合输出
+		mu.Lock()
 		var outputBuf []byte
-		outputBuf = append(outputBuf, stdoutBuf...)
-		outputBuf = append(outputBuf, stderrBuf...)
-
-		// 确定返回的错误
-		resultErr := waitErr
-		if stdoutErr != nil {
-			resultErr = stdoutErr
-		} else if stderrErr != nil {
-			resultErr = stderrErr
-		}
+		outputBuf = append(outputBuf, stdout.buf.Bytes()...)
+		outputBuf = append(outputBuf, stderr.buf.Bytes()...)
+		mu.Unlock()
 
-		resultCh <- result{output: outputBuf, err: resultErr}
+		resultCh <- result{output: outputBuf, err: waitErr}
 	}()
 
 	// 等待结果或超时
@@ -198,18 +213,29 @@ func (t *ShellTool) execDirect(ctx context.Context, command string) (string, err
 
 // execInSandbox 在 Docker 容器中执行命令
 func (t *ShellTool) execInSandbox(ctx context.Context, command string) (string, error) {
-	containerName := fmt.Sprintf("goclaw-%d", time.Now().UnixNano())
+	if t.sandboxes != nil {
+		execCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		defer cancel()
 
-	// 准备工作目录
-	workdir := t.workingDir
-	if workdir == "" {
-		workdir = "."
+		output, err := t.sandboxes.Exec(execCtx, t.sandboxes.ScopeKey(ctx), command)
+		if err != nil && execCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return "", fmt.Errorf("command timed out after %v", t.timeout)
+		}
+		return output, err
 	}
+	return t.execOneShot(ctx, command)
+}
 
-	// 准备挂载点
-	binds := []string{
-		workdir + ":" + t.sandboxConfig.Workdir,
+// execOneShot 为每条命令创建一次性容器（scope 为 none 时）
+func (t *ShellTool) execOneShot(ctx context.Context, command string) (string, error) {
+	containerName := fmt.Sprintf("goclaw-%d", time.Now().UnixNano())
+
+	// 准备挂载点和资源限制
+	hostConfig, err := BuildSandboxHostConfig(t.sandboxConfig, t.workingDir)
+	if err != nil {
+		return "", err
 	}
+	hostConfig.AutoRemove = t.sandboxConfig.Remove
 
 	// 创建并运行容器
 	resp, err := t.dockerClient.ContainerCreate(ctx, &container.Config{
@@ -217,12 +243,8 @@ func (t *ShellTool) execInSandbox(ctx context.Context, command string) (string,
 		Cmd:        []string{"sh", "-c", command},
 		WorkingDir: t.sandboxConfig.Workdir,
 		Tty:        false,
-	}, &container.HostConfig{
-		Binds:       binds,
-		NetworkMode: container.NetworkMode(t.sandboxConfig.Network),
-		Privileged:  t.sandboxConfig.Privileged,
-		AutoRemove:  t.sandboxConfig.Remove,
-	}, nil, nil, containerName)
+		Labels:     map[string]string{SandboxLabel: "true"},
+	}, hostConfig, nil, nil, containerName)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
@@ -273,32 +295,217 @@ func (t *ShellTool) execInSandbox(ctx context.Context, command string) (string,
 	return string(logs), nil
 }
 
-// isDenied 检查命令是否被拒绝
-func (t *ShellTool) isDenied(command string) bool {
-	// 检查明确拒绝的命令
-	for _, denied := range t.deniedCmds {
-		if strings.Contains(command, denied) {
-			return true
+// startBackground 在后台启动命令并返回进程句柄
+// 启用沙箱时进程运行在会话（或 Agent）的持久化容器中
+func (t *ShellTool) startBackground(ctx context.Context, command string, useSandbox bool) (string, error) {
+	launch := hostLauncher(command, t.workingDir)
+	if useSandbox {
+		// scope 为 none 时每条命令使用一次性容器，没有可供后台进程常驻的容器
+		if t.sandboxes == nil {
+			return "", fmt.Errorf("background mode requires a persistent sandbox (sandbox scope \"session\" or \"agent\"); run the command in the foreground instead")
+		}
+		scope := t.sandboxes.ScopeKey(ctx)
+		launch = func(stdout, stderr io.Writer) (processHandle, io.WriteCloser, error) {
+			return t.sandboxes.StartBackground(ctx, scope, command, stdout, stderr)
+		}
+	}
+
+	proc, err := t.processes.startWith(SessionKeyFromContext(ctx), command, launch)
+	if err != nil {
+		return "", err
+	}
+	return marshalShellResult(map[string]interface{}{
+		"process_id": proc.ID,
+		"status":     ProcessStatusRunning,
+		"message":    "Process started in background. Use shell_read to fetch output, shell_write to send stdin, shell_status to check it and shell_kill to stop it.",
+	})
+}
+
+// ReadProcess 读取后台进程的增量输出
+// wait_seconds > 0 时会等待新的输出或进程结束，期间通过 onUpdate 推送输出
+func (t *ShellTool) ReadProcess(ctx context.Context, params map[string]interface{}, onUpdate func(ToolResult)) (string, error) {
+	proc, err := t.lookupProcess(ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	wait := 0.0
+	if v, ok := params["wait_seconds"].(float64); ok && v > 0 {
+		wait = v
+		if wait > 60 {
+			wait = 60
 		}
 	}
 
-	// 如果有允许列表，检查是否在允许列表中
-	if len(t.allowedCmds) > 0 {
-		parts := strings.Fields(command)
-		if len(parts) == 0 {
-			return true
+	var collected ProcessOutput
+	appendOutput := func() bool {
+		out := proc.ReadOutput()
+		collected.Stdout += out.Stdout
+		collected.Stderr += out.Stderr
+		collected.Truncated = collected.Truncated || out.Truncated
+		if onUpdate != nil && (out.Stdout != "" || out.Stderr != "") {
+			onUpdate(ToolResult{
+				Content: []ContentBlock{TextContent{Text: out.Stdout + out.Stderr}},
+				Details: map[string]any{"process_id": proc.ID, "partial": true},
+			})
 		}
-		cmdName := parts[0]
+		return out.Stdout != "" || out.Stderr != ""
+	}
 
-		for _, allowed := range t.allowedCmds {
-			if cmdName == allowed {
-				return false
+	if wait > 0 {
+		deadline := time.NewTimer(time.Duration(wait * float64(time.Second)))
+		defer deadline.Stop()
+	loop:
+		for {
+			updates := proc.Updates()
+			appendOutput()
+			select {
+			case <-proc.Done():
+				break loop
+			case <-updates:
+			case <-deadline.C:
+				break loop
+			case <-ctx.Done():
+				break loop
 			}
 		}
-		return true
 	}
+	appendOutput()
+
+	snap := proc.Snapshot()
+	return marshalShellResult(map[string]interface{}{
+		"process_id": proc.ID,
+		"status":     snap.Status,
+		"exit_code":  snap.ExitCode,
+		"stdout":     collected.Stdout,
+		"stderr":     collected.Stderr,
+		"truncated":  collected.Truncated,
+	})
+}
+
+// WriteProcess 向后台进程标准输入写入数据
+func (t *ShellTool) WriteProcess(ctx context.Context, params map[string]interface{}) (string, error) {
+	proc, err := t.lookupProcess(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	input, _ := params["input"].(string)
+	closeStdin, _ := params["close_stdin"].(bool)
+	if input == "" && !closeStdin {
+		return "", fmt.Errorf("input or close_stdin is required")
+	}
+	if err := proc.WriteInput(input, closeStdin); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %d bytes to %s", len(input), proc.ID), nil
+}
+
+// GetProcessStatus 查询后台进程状态，未指定 process_id 时列出当前会话的所有进程
+func (t *ShellTool) GetProcessStatus(ctx context.Context, params map[string]interface{}) (string, error) {
+	if id, _ := params["process_id"].(string); id == "" {
+		return marshalShellResult(map[string]interface{}{
+			"processes": t.processes.List(SessionKeyFromContext(ctx)),
+		})
+	}
+	proc, err := t.lookupProcess(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return marshalShellResult(proc.Snapshot())
+}
+
+// KillProcess 终止并移除后台进程
+func (t *ShellTool) KillProcess(ctx context.Context, params map[string]interface{}) (string, error) {
+	id, _ := params["process_id"].(string)
+	if id == "" {
+		return "", fmt.Errorf("process_id parameter is required")
+	}
+	if err := t.processes.Remove(SessionKeyFromContext(ctx), id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Process %s killed", id), nil
+}
 
-	return false
+// CleanupSession 终止会话的所有后台进程并删除会话沙箱（会话结束时调用）
+func (t *ShellTool) CleanupSession(sessionKey string) {
+	t.processes.CleanupSession(sessionKey)
+
+	if t.sandboxes != nil && t.sandboxConfig.Scope != sandboxScopeAgent {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := t.sandboxes.Release(ctx, "session:"+sessionKey); err != nil {
+			zap.L().Warn("Failed to release session sandbox",
+				zap.String("session_key", sessionKey),
+				zap.Error(err))
+		}
+	}
+}
+
+// lookupProcess 根据参数查找当前会话中的后台进程
+func (t *ShellTool) lookupProcess(ctx context.Context, params map[string]interface{}) (*BackgroundProcess, error) {
+	id, _ := params["process_id"].(string)
+	if id == "" {
+		return nil, fmt.Errorf("process_id parameter is required")
+	}
+	return t.processes.Get(SessionKeyFromContext(ctx), id)
+}
+
+// marshalShellResult 序列化结果为 JSON 字符串
+func marshalShellResult(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+// SetPolicy 设置命令策略（替换由 allowed/denied 列表生成的默认策略）
+func (t *ShellTool) SetPolicy(policy *ShellPolicy) {
+	t.policy = policy
+}
+
+// SetApprover 设置审批者，策略判定需要审批的命令会交给它决定
+func (t *ShellTool) SetApprover(approver Approver) {
+	t.approver = approver
+}
+
+// checkPolicy 评估命令策略，拒绝或未获审批时返回错误
+func (t *ShellTool) checkPolicy(ctx context.Context, command string) error {
+	if t.policy == nil {
+		return nil
+	}
+
+	decision := t.policy.Evaluate(command)
+	switch decision.Action {
+	case ShellActionAllow:
+		return nil
+	case ShellActionDeny:
+		return fmt.Errorf("command is not allowed: %s", decision.Reason)
+	}
+
+	if t.approver == nil {
+		return fmt.Errorf("command requires approval and no approver is configured: %s", decision.Reason)
+	}
+
+	approved, err := t.approver.RequestApproval(ctx, ApprovalRequest{
+		Tool:       "run_shell",
+		Command:    command,
+		Reason:     decision.Reason,
+		Risks:      decision.Risks,
+		SessionKey: SessionKeyFromContext(ctx),
+		AgentID:    AgentIDFromContext(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("approval failed: %w", err)
+	}
+	if !approved {
+		return fmt.Errorf("command was not approved: %s", decision.Reason)
+	}
+
+	zap.L().Info("Shell command approved",
+		zap.String("command", command),
+		zap.String("reason", decision.Reason))
+	return nil
 }
 
 // GetTools 获取所有 Shell 工具
@@ -318,8 +525,18 @@ func (t *ShellTool) GetTools() []Tool {
 	desc.WriteString("The 'cron' tool manages goclaw's built-in scheduler - this is the ONLY way to manage scheduled tasks. ")
 	desc.WriteString("Available cron commands: 'add' (create), 'list/ls' (list), 'rm/remove' (delete), 'enable', 'disable', 'run' (execute immediately), 'status', 'runs' (history).")
 
+	desc.WriteString(" For long-running commands (dev servers, log tails, long builds) set 'background' to true: the call returns a process_id immediately and the process keeps running until killed or the session ends.")
+	if t.sandboxConfig.Enabled && t.sandboxes == nil {
+		desc.WriteString(" Background mode is not available because every command runs in a one-shot sandbox container.")
+	}
+
+	processIDParam := map[string]interface{}{
+		"type":        "string",
+		"description": "Background process ID returned by run_shell",
+	}
+
 	return []Tool{
-		NewBaseTool(
+		NewStreamingBaseTool(
 			"run_shell",
 			desc.String(),
 			map[string]interface{}{
@@ -329,16 +546,83 @@ func (t *ShellTool) GetTools() []Tool {
 						"type":        "string",
 						"description": "Shell command to execute. DO NOT use crontab commands - use the 'cron' tool for scheduled task management.",
 					},
+					"background": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the command in the background and return a process handle instead of waiting for it to exit",
+					},
 				},
 				"required": []string{"command"},
 			},
-			t.Exec,
+			t.ExecStreaming,
+		),
+		NewStreamingBaseTool(
+			"shell_read",
+			"Read new stdout/stderr produced by a background process since the last read, plus its status and exit code. Set wait_seconds to wait for more output.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"process_id": processIDParam,
+					"wait_seconds": map[string]interface{}{
+						"type":        "number",
+						"description": "Wait up to this many seconds (max 60) for new output or process exit",
+					},
+				},
+				"required": []string{"process_id"},
+			},
+			t.ReadProcess,
+		),
+		NewBaseTool(
+			"shell_write",
+			"Send input to the stdin of a background process. Include a trailing newline to submit a line.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"process_id": processIDParam,
+					"input": map[string]interface{}{
+						"type":        "string",
+						"description": "Data to write to stdin",
+					},
+					"close_stdin": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Close stdin after writing (sends EOF)",
+					},
+				},
+				"required": []string{"process_id"},
+			},
+			t.WriteProcess,
+		),
+		NewBaseTool(
+			"shell_status",
+			"Check the status of a background process, or list all background processes of this session when process_id is omitted.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"process_id": processIDParam,
+				},
+			},
+			t.GetProcessStatus,
+		),
+		NewBaseTool(
+			"shell_kill",
+			"Terminate a background process (SIGTERM, then SIGKILL) and remove it.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"process_id": processIDParam,
+				},
+				"required": []string{"process_id"},
+			},
+			t.KillProcess,
 		),
 	}
 }
 
 // Close 关闭工具
 func (t *ShellTool) Close() error {
+	t.processes.CloseAll()
+	if t.sandboxes != nil {
+		t.sandboxes.Close()
+	}
 	if t.dockerClient != nil {
 		return t.dockerClient.Close()
 	}
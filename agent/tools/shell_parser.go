@@ -0,0 +1,835 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// maxShellParseDepth 嵌套解析（sh -c、eval、命令替换）的最大深度
+const maxShellParseDepth = 16
+
+// ShellRedirect 重定向
+type ShellRedirect struct {
+	Op     string `json:"op"`
+	Target string `json:"target"`
+}
+
+// ShellInvocation 解析出的一次命令调用
+type ShellInvocation struct {
+	Name      string          `json:"name"` // 命令名（去掉路径）
+	Path      string          `json:"path"` // 原始命令词
+	Args      []string        `json:"args,omitempty"`
+	Redirects []ShellRedirect `json:"redirects,omitempty"`
+	PipedFrom string          `json:"piped_from,omitempty"` // 管道上游命令名
+	Nested    bool            `json:"nested,omitempty"`     // 来自子 shell、命令替换、sh -c 或 eval
+}
+
+// Dynamic 命令名是否在运行时才能确定（包含变量或命令替换）
+func (inv ShellInvocation) Dynamic() bool {
+	return strings.ContainsAny(inv.Path, "$`")
+}
+
+// shellTokenKind 词法单元类型
+type shellTokenKind int
+
+const (
+	shellTokenWord shellTokenKind = iota
+	shellTokenOp
+)
+
+// shellToken 词法单元
+type shellToken struct {
+	kind  shellTokenKind
+	value string
+	subs  []string // 单词中包含的命令替换 / 进程替换内容
+}
+
+// heredoc 待跳过的 here-document
+type heredoc struct {
+	delim string
+	strip bool
+}
+
+// shellLexer Shell 词法分析器
+type shellLexer struct {
+	src      []rune
+	pos      int
+	tokens   []shellToken
+	heredocs []heredoc
+}
+
+// ParseShellCommand 解析 Shell 命令，返回所有会被执行的命令调用
+// 支持管道、命令列表、子 shell、命令替换、进程替换、sh -c、eval、find -exec 以及常见的包装命令（sudo、env、xargs 等）
+func ParseShellCommand(command string) ([]ShellInvocation, error) {
+	return parseShell(command, 0, false)
+}
+
+func parseShell(command string, depth int, nested bool) ([]ShellInvocation, error) {
+	if depth > maxShellParseDepth {
+		return nil, fmt.Errorf("shell command nested too deeply")
+	}
+	lx := &shellLexer{src: []rune(command)}
+	if err := lx.run(); err != nil {
+		return nil, err
+	}
+	p := &shellParser{tokens: lx.tokens, depth: depth, nested: nested}
+	return p.parse()
+}
+
+func isShellOpChar(r rune) bool {
+	return strings.ContainsRune("|&;()<>", r)
+}
+
+func (lx *shellLexer) peek(offset int) rune {
+	if lx.pos+offset < len(lx.src) {
+		return lx.src[lx.pos+offset]
+	}
+	return 0
+}
+
+func (lx *shellLexer) hasPrefix(s string) bool {
+	r := []rune(s)
+	if lx.pos+len(r) > len(lx.src) {
+		return false
+	}
+	for i, c := range r {
+		if lx.src[lx.pos+i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+func (lx *shellLexer) emitOp(op string) {
+	lx.tokens = append(lx.tokens, shellToken{kind: shellTokenOp, value: op})
+	lx.pos += len([]rune(op))
+}
+
+// run 执行词法分析
+func (lx *shellLexer) run() error {
+	for lx.pos < len(lx.src) {
+		r := lx.src[lx.pos]
+
+		switch {
+		case r == '\\' && lx.peek(1) == '\n':
+			lx.pos += 2
+		case r == ' ' || r == '\t' || r == '\r':
+			lx.pos++
+		case r == '#':
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
+				lx.pos++
+			}
+		case r == '\n':
+			lx.tokens = append(lx.tokens, shellToken{kind: shellTokenOp, value: ";"})
+			lx.pos++
+			lx.skipHeredocs()
+		case (r == '<' || r == '>') && lx.peek(1) == '(':
+			// 进程替换 <(...) / >(...)
+			lx.pos += 2
+			body, err := lx.readBalanced()
+			if err != nil {
+				return err
+			}
+			lx.tokens = append(lx.tokens, shellToken{
+				kind:  shellTokenWord,
+				value: string(r) + "(" + body + ")",
+				subs:  []string{body},
+			})
+		case unicode.IsDigit(r) && lx.fdRedirect():
+			// 2>file、2>&1 等带文件描述符的重定向
+			start := lx.pos
+			for unicode.IsDigit(lx.src[lx.pos]) {
+				lx.pos++
+			}
+			fd := string(lx.src[start:lx.pos])
+			op := lx.redirectOp()
+			lx.emitOp(op)
+			lx.tokens[len(lx.tokens)-1].value = fd + op
+		case isShellOpChar(r):
+			lx.readOperator()
+		default:
+			if err := lx.readWord(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fdRedirect 判断当前位置的数字是否为重定向的文件描述符
+func (lx *shellLexer) fdRedirect() bool {
+	i := lx.pos
+	for i < len(lx.src) && unicode.IsDigit(lx.src[i]) {
+		i++
+	}
+	return i < len(lx.src) && (lx.src[i] == '<' || lx.src[i] == '>')
+}
+
+// redirectOp 返回当前位置的重定向运算符
+func (lx *shellLexer) redirectOp() string {
+	for _, op := range []string{"<<<", "<<-", "&>>", ">>", ">|", ">&", "<<", "<>", "<&", "&>", ">", "<"} {
+		if lx.hasPrefix(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// readOperator 读取控制或重定向运算符
+func (lx *shellLexer) readOperator() {
+	if op := lx.redirectOp(); op != "" {
+		lx.emitOp(op)
+		if op == "<<" || op == "<<-" {
+			lx.queueHeredoc(op == "<<-")
+		}
+		return
+	}
+	for _, op := range []string{"&&", "||", ";;", "|&", "|", "&", ";", "(", ")"} {
+		if lx.hasPrefix(op) {
+			lx.emitOp(op)
+			return
+		}
+	}
+	lx.pos++
+}
+
+// queueHeredoc 读取 here-document 分隔符，正文在下一个换行后跳过
+func (lx *shellLexer) queueHeredoc(strip bool) {
+	for lx.pos < len(lx.src) && (lx.src[lx.pos] == ' ' || lx.src[lx.pos] == '\t') {
+		lx.pos++
+	}
+	start := len(lx.tokens)
+	if err := lx.readWord(); err != nil || len(lx.tokens) == start {
+		return
+	}
+	lx.heredocs = append(lx.heredocs, heredoc{delim: lx.tokens[len(lx.tokens)-1].value, strip: strip})
+}
+
+// skipHeredocs 跳过待处理的 here-document 正文
+func (lx *shellLexer) skipHeredocs() {
+	for _, hd := range lx.heredocs {
+		for lx.pos < len(lx.src) {
+			end := lx.pos
+			for end < len(lx.src) && lx.src[end] != '\n' {
+				end++
+			}
+			line := string(lx.src[lx.pos:end])
+			lx.pos = end
+			if lx.pos < len(lx.src) {
+				lx.pos++
+			}
+			if hd.strip {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == hd.delim {
+				break
+			}
+		}
+	}
+	lx.heredocs = nil
+}
+
+// readWord 读取一个单词，处理引号、转义和替换
+func (lx *shellLexer) readWord() error {
+	var sb strings.Builder
+	var subs []string
+
+	for lx.pos < len(lx.src) {
+		r := lx.src[lx.pos]
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' || isShellOpChar(r) {
+			break
+		}
+
+		switch r {
+		case '\\':
+			if lx.pos+1 < len(lx.src) {
+				sb.WriteRune(lx.src[lx.pos+1])
+			}
+			lx.pos += 2
+		case '\'':
+			end := lx.pos + 1
+			for end < len(lx.src) && lx.src[end] != '\'' {
+				end++
+			}
+			if end >= len(lx.src) {
+				return fmt.Errorf("unterminated single quote")
+			}
+			sb.WriteString(string(lx.src[lx.pos+1 : end]))
+			lx.pos = end + 1
+		case '"':
+			lx.pos++
+			closed := false
+			for lx.pos < len(lx.src) {
+				c := lx.src[lx.pos]
+				if c == '"' {
+					lx.pos++
+					closed = true
+					break
+				}
+				if c == '\\' && lx.pos+1 < len(lx.src) && strings.ContainsRune("$`\"\\\n", lx.src[lx.pos+1]) {
+					sb.WriteRune(lx.src[lx.pos+1])
+					lx.pos += 2
+					continue
+				}
+				if c == '$' || c == '`' {
+					text, bodies, err := lx.readDollarOrBacktick()
+					if err != nil {
+						return err
+					}
+					sb.WriteString(text)
+					subs = append(subs, bodies...)
+					continue
+				}
+				sb.WriteRune(c)
+				lx.pos++
+			}
+			if !closed {
+				return fmt.Errorf("unterminated double quote")
+			}
+		case '$', '`':
+			text, bodies, err := lx.readDollarOrBacktick()
+			if err != nil {
+				return err
+			}
+			sb.WriteString(text)
+			subs = append(subs, bodies...)
+		default:
+			sb.WriteRune(r)
+			lx.pos++
+		}
+	}
+
+	lx.tokens = append(lx.tokens, shellToken{kind: shellTokenWord, value: sb.String(), subs: subs})
+	return nil
+}
+
+// readDollarOrBacktick 读取 $(...)、$((...))、${...}、$VAR 或 `...`
+// 返回原文和其中所有命令替换的内容，${...} 内嵌套的命令替换也会返回
+func (lx *shellLexer) readDollarOrBacktick() (string, []string, error) {
+	start := lx.pos
+
+	if lx.src[lx.pos] == '`' {
+		lx.pos++
+		var body strings.Builder
+		for lx.pos < len(lx.src) && lx.src[lx.pos] != '`' {
+			if lx.src[lx.pos] == '\\' && lx.pos+1 < len(lx.src) {
+				body.WriteRune(lx.src[lx.pos+1])
+				lx.pos += 2
+				continue
+			}
+			body.WriteRune(lx.src[lx.pos])
+			lx.pos++
+		}
+		if lx.pos >= len(lx.src) {
+			return "", nil, fmt.Errorf("unterminated backquote")
+		}
+		lx.pos++
+		return string(lx.src[start:lx.pos]), nonEmpty(body.String()), nil
+	}
+
+	// $
+	lx.pos++
+	switch {
+	case lx.hasPrefix("(("):
+		// 算术展开，不执行命令
+		lx.pos += 2
+		depth := 2
+		for lx.pos < len(lx.src) && depth > 0 {
+			switch lx.src[lx.pos] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			lx.pos++
+		}
+		if depth > 0 {
+			return "", nil, fmt.Errorf("unterminated arithmetic expansion")
+		}
+		return string(lx.src[start:lx.pos]), nil, nil
+	case lx.peek(0) == '(':
+		lx.pos++
+		body, err := lx.readBalanced()
+		if err != nil {
+			return "", nil, err
+		}
+		return string(lx.src[start:lx.pos]), nonEmpty(body), nil
+	case lx.peek(0) == '{':
+		// ${x:-word} 等展开的 word 部分会被求值，其中的命令替换同样会执行
+		// 引号在双引号内外含义不同，这里不把引号当作边界，保证不会漏掉命令替换
+		lx.pos++
+		var bodies []string
+		for lx.pos < len(lx.src) && lx.src[lx.pos] != '}' {
+			switch lx.src[lx.pos] {
+			case '\\':
+				lx.pos += 2
+			case '$', '`':
+				_, inner, err := lx.readDollarOrBacktick()
+				if err != nil {
+					return "", nil, err
+				}
+				bodies = append(bodies, inner...)
+			default:
+				lx.pos++
+			}
+		}
+		if lx.pos >= len(lx.src) {
+			return "", nil, fmt.Errorf("unterminated parameter expansion")
+		}
+		lx.pos++
+		return string(lx.src[start:lx.pos]), bodies, nil
+	default:
+		for lx.pos < len(lx.src) {
+			c := lx.src[lx.pos]
+			if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_') {
+				if lx.pos == start+1 && strings.ContainsRune("?#@*$!-", c) {
+					lx.pos++
+				}
+				break
+			}
+			lx.pos++
+		}
+		return string(lx.src[start:lx.pos]), nil, nil
+	}
+}
+
+// nonEmpty 将非空的命令替换内容包装为切片
+func nonEmpty(body string) []string {
+	if body == "" {
+		return nil
+	}
+	return []string{body}
+}
+
+// readBalanced 读取到与已消费的左括号匹配的右括号，返回括号内的内容
+func (lx *shellLexer) readBalanced() (string, error) {
+	start := lx.pos
+	depth := 1
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch c {
+		case '\\':
+			lx.pos += 2
+			continue
+		case '\'':
+			lx.pos++
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\'' {
+				lx.pos++
+			}
+		case '"':
+			lx.pos++
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '"' {
+				if lx.src[lx.pos] == '\\' {
+					lx.pos++
+				}
+				lx.pos++
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				body := string(lx.src[start:lx.pos])
+				lx.pos++
+				return body, nil
+			}
+		}
+		lx.pos++
+	}
+	return "", fmt.Errorf("unterminated command substitution")
+}
+
+// shellParser 将词法单元组装为命令调用
+type shellParser struct {
+	tokens []shellToken
+	depth  int
+	nested bool
+
+	result    []ShellInvocation
+	words     []shellToken
+	redirects []ShellRedirect
+	pendingOp string   // 等待目标的重定向运算符
+	pipedFrom string   // 当前命令的管道上游
+	stack     []string // "(" 表示子 shell，"case" 表示 case 语句
+}
+
+// shellReservedWords 命令位置上被忽略的保留字
+var shellReservedWords = map[string]bool{
+	"!": true, "{": true, "}": true, "if": true, "then": true, "else": true, "elif": true,
+	"fi": true, "while": true, "until": true, "do": true, "done": true,
+}
+
+func (p *shellParser) parse() ([]ShellInvocation, error) {
+	for i := 0; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+
+		if tok.kind == shellTokenWord {
+			if p.pendingOp != "" {
+				p.redirects = append(p.redirects, ShellRedirect{Op: p.pendingOp, Target: tok.value})
+				p.pendingOp = ""
+				if err := p.addSubs(tok); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			p.words = append(p.words, tok)
+			continue
+		}
+
+		if p.pendingOp != "" {
+			return nil, fmt.Errorf("missing redirect target after %s", p.pendingOp)
+		}
+
+		switch op := tok.value; op {
+		case "|", "|&":
+			name, err := p.finish()
+			if err != nil {
+				return nil, err
+			}
+			p.pipedFrom = name
+		case "&&", "||", ";", "&", ";;":
+			if _, err := p.finish(); err != nil {
+				return nil, err
+			}
+			p.pipedFrom = ""
+		case "(":
+			if len(p.words) == 1 && i+1 < len(p.tokens) && p.tokens[i+1].value == ")" {
+				// 函数定义 name() { ... }，函数体按普通命令解析
+				p.words = nil
+				i++
+				continue
+			}
+			if len(p.words) > 0 {
+				return nil, fmt.Errorf("syntax error near unexpected token '('")
+			}
+			p.stack = append(p.stack, "(")
+		case ")":
+			if len(p.stack) == 0 {
+				return nil, fmt.Errorf("syntax error near unexpected token ')'")
+			}
+			if p.stack[len(p.stack)-1] == "case" {
+				// case 分支模式，不是命令
+				p.words = nil
+				continue
+			}
+			if _, err := p.finish(); err != nil {
+				return nil, err
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+			p.pipedFrom = ""
+		default:
+			p.pendingOp = op
+		}
+	}
+
+	if p.pendingOp != "" {
+		return nil, fmt.Errorf("missing redirect target after %s", p.pendingOp)
+	}
+	if _, err := p.finish(); err != nil {
+		return nil, err
+	}
+	if len(p.stack) > 0 && p.stack[len(p.stack)-1] == "(" {
+		return nil, fmt.Errorf("unterminated subshell")
+	}
+	return p.result, nil
+}
+
+// addSubs 解析单词中的命令替换
+func (p *shellParser) addSubs(tok shellToken) error {
+	for _, sub := range tok.subs {
+		invs, err := parseShell(sub, p.depth+1, true)
+		if err != nil {
+			return err
+		}
+		p.result = append(p.result, invs...)
+	}
+	return nil
+}
+
+// finish 结束当前简单命令，返回命令名
+func (p *shellParser) finish() (string, error) {
+	words := p.words
+	redirects := p.redirects
+	p.words = nil
+	p.redirects = nil
+
+	for _, w := range words {
+		if err := p.addSubs(w); err != nil {
+			return "", err
+		}
+	}
+
+	// 跳过保留字和变量赋值
+	for len(words) > 0 {
+		first := words[0].value
+		switch {
+		case shellReservedWords[first]:
+			words = words[1:]
+			continue
+		case first == "for" || first == "select":
+			return "", nil
+		case first == "case":
+			p.stack = append(p.stack, "case")
+			for len(words) > 0 && words[0].value != "in" {
+				words = words[1:]
+			}
+			if len(words) > 0 {
+				words = words[1:]
+			}
+			// 剩余单词为第一个分支模式
+			return "", nil
+		case first == "esac":
+			if len(p.stack) > 0 && p.stack[len(p.stack)-1] == "case" {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			words = words[1:]
+			continue
+		case isShellAssignment(first):
+			words = words[1:]
+			continue
+		}
+		break
+	}
+
+	if len(words) == 0 {
+		return "", nil
+	}
+
+	args := make([]string, 0, len(words)-1)
+	for _, w := range words[1:] {
+		args = append(args, w.value)
+	}
+	inv := ShellInvocation{
+		Name:      filepath.Base(words[0].value),
+		Path:      words[0].value,
+		Args:      args,
+		Redirects: redirects,
+		PipedFrom: p.pipedFrom,
+		Nested:    p.nested || len(p.stack) > 0,
+	}
+	if err := p.emit(inv); err != nil {
+		return "", err
+	}
+	return inv.Name, nil
+}
+
+// emit 记录调用，并展开包装命令和内联脚本
+func (p *shellParser) emit(inv ShellInvocation) error {
+	p.result = append(p.result, inv)
+
+	// sh -c / bash -c
+	if isShellInterpreter(inv.Name) {
+		for i, arg := range inv.Args {
+			if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") {
+				if i+1 < len(inv.Args) {
+					return p.emitScript(inv.Args[i+1], inv)
+				}
+				break
+			}
+			if !strings.HasPrefix(arg, "-") {
+				break
+			}
+		}
+		return nil
+	}
+
+	switch inv.Name {
+	case "eval":
+		return p.emitScript(strings.Join(inv.Args, " "), inv)
+	case "find":
+		for i := 0; i < len(inv.Args); i++ {
+			switch inv.Args[i] {
+			case "-exec", "-execdir", "-ok", "-okdir":
+				end := i + 1
+				for end < len(inv.Args) && inv.Args[end] != ";" && inv.Args[end] != "+" {
+					end++
+				}
+				if end > i+1 {
+					if err := p.emit(wrappedInvocation(inv, inv.Args[i+1:end])); err != nil {
+						return err
+					}
+				}
+				i = end
+			}
+		}
+		return nil
+	}
+
+	if rest, ok := unwrapCommand(inv.Name, inv.Args); ok && len(rest) > 0 {
+		return p.emit(wrappedInvocation(inv, rest))
+	}
+	return nil
+}
+
+// emitScript 解析内联脚本并记录其中的调用
+func (p *shellParser) emitScript(script string, parent ShellInvocation) error {
+	invs, err := parseShell(script, p.depth+1, true)
+	if err != nil {
+		return err
+	}
+	for i := range invs {
+		if invs[i].PipedFrom == "" {
+			invs[i].PipedFrom = parent.PipedFrom
+		}
+	}
+	p.result = append(p.result, invs...)
+	return nil
+}
+
+// wrappedInvocation 构建被包装命令的调用，继承重定向和管道信息
+func wrappedInvocation(parent ShellInvocation, words []string) ShellInvocation {
+	return ShellInvocation{
+		Name:      filepath.Base(words[0]),
+		Path:      words[0],
+		Args:      append([]string(nil), words[1:]...),
+		Redirects: parent.Redirects,
+		PipedFrom: parent.PipedFrom,
+		Nested:    parent.Nested,
+	}
+}
+
+// wrapperValueFlags 包装命令中需要额外参数值的选项
+var wrapperValueFlags = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-h": true, "-p": true, "-U": true, "-r": true, "-t": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true},
+	"nice":    {"-n": true},
+	"timeout": {"-s": true, "-k": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-E": true, "-a": true},
+	"watch":   {"-n": true},
+	"ionice":  {"-c": true, "-n": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"strace":  {"-e": true, "-o": true, "-p": true, "-s": true, "-u": true, "-E": true, "-P": true, "-a": true, "-b": true, "-I": true, "-X": true},
+	"ltrace":  {"-e": true, "-o": true, "-p": true, "-s": true, "-u": true, "-n": true, "-a": true, "-A": true, "-D": true, "-F": true, "-l": true, "-w": true, "-x": true},
+}
+
+// unwrapCommand 返回包装命令（sudo、env、xargs、strace 等）实际执行的命令及参数
+func unwrapCommand(name string, args []string) ([]string, bool) {
+	switch name {
+	case "sudo", "doas", "env", "nohup", "nice", "time", "timeout", "xargs", "exec",
+		"command", "builtin", "stdbuf", "watch", "ionice", "chroot", "setsid", "unbuffer", "busybox",
+		"strace", "ltrace":
+	default:
+		return nil, false
+	}
+
+	valueFlags := wrapperValueFlags[name]
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if name == "env" && isShellAssignment(arg) {
+			i++
+			continue
+		}
+		if name == "env" {
+			// env -S 将字符串拆分为命令及参数，拆分结果替换原选项后继续解析
+			if split, n := envSplitString(args[i:]); n > 0 {
+				args = append(split, args[i+n:]...)
+				i = 0
+				continue
+			}
+		}
+		if name == "command" && (arg == "-v" || arg == "-V") {
+			// command -v 只查找命令，不执行
+			return nil, false
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		i++
+		if valueFlags[arg] && i < len(args) {
+			i++
+		}
+	}
+
+	switch name {
+	case "timeout":
+		// 第一个位置参数是时长
+		if i < len(args) {
+			i++
+		}
+	case "chroot":
+		// 第一个位置参数是新根目录
+		if i < len(args) {
+			i++
+		}
+	}
+
+	if i >= len(args) {
+		return nil, false
+	}
+	return args[i:], true
+}
+
+// envSplitString 识别 env 的 -S/--split-string 选项，返回拆分后的单词和消耗的参数个数
+func envSplitString(args []string) ([]string, int) {
+	arg := args[0]
+	value, n := "", 0
+	switch {
+	case arg == "--split-string":
+		if len(args) < 2 {
+			return nil, 0
+		}
+		value, n = args[1], 2
+	case strings.HasPrefix(arg, "--split-string="):
+		value, n = strings.TrimPrefix(arg, "--split-string="), 1
+	case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--"):
+		// -S 可以与不带参数的短选项合并，如 env -iS 'cmd args'
+		k := strings.IndexByte(arg, 'S')
+		if k < 1 || strings.Trim(arg[1:k], "iv0") != "" {
+			return nil, 0
+		}
+		if rest := arg[k+1:]; rest != "" {
+			value, n = rest, 1
+		} else if len(args) >= 2 {
+			value, n = args[1], 2
+		} else {
+			return nil, 0
+		}
+	default:
+		return nil, 0
+	}
+	return splitShellWords(value), n
+}
+
+// splitShellWords 按 Shell 引号规则拆分字符串，无法解析时按空白拆分
+func splitShellWords(s string) []string {
+	lx := &shellLexer{src: []rune(s)}
+	if err := lx.run(); err != nil {
+		return strings.Fields(s)
+	}
+	words := make([]string, 0, len(lx.tokens))
+	for _, tok := range lx.tokens {
+		words = append(words, tok.value)
+	}
+	return words
+}
+
+// isShellInterpreter 判断命令是否为 Shell 解释器
+func isShellInterpreter(name string) bool {
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "ash", "fish":
+		return true
+	}
+	return false
+}
+
+// isShellAssignment 判断单词是否为变量赋值（NAME=value）
+func isShellAssignment(word string) bool {
+	idx := strings.IndexByte(word, '=')
+	if idx <= 0 {
+		return false
+	}
+	for i, r := range word[:idx] {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
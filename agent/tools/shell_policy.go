@@ -0,0 +1,849 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// ShellAction 策略动作
+type ShellAction string
+
+const (
+	ShellActionAllow   ShellAction = "allow"
+	ShellActionDeny    ShellAction = "deny"
+	ShellActionApprove ShellAction = "approve"
+)
+
+// ShellRisk 风险分类
+type ShellRisk struct {
+	Category string `json:"category"`
+	Command  string `json:"command"`
+	Reason   string `json:"reason"`
+}
+
+// 风险类别
+const (
+	RiskRecursiveDelete = "recursive_delete"
+	RiskRemoteExec      = "remote_exec"
+	RiskOutsideWrite    = "outside_workspace_write"
+	RiskPrivilege       = "privilege_escalation"
+	RiskDisk            = "disk_operation"
+	RiskSystem          = "system_control"
+	RiskGitDestructive  = "destructive_git"
+	RiskGitConfig       = "git_config_override"
+	RiskDynamic         = "dynamic_command"
+	RiskInlineCode      = "inline_code"
+	RiskUnparseable     = "unparseable"
+)
+
+// ShellDecision 策略评估结果
+type ShellDecision struct {
+	Action      ShellAction       `json:"action"`
+	Reason      string            `json:"reason,omitempty"`
+	Risks       []ShellRisk       `json:"risks,omitempty"`
+	Invocations []ShellInvocation `json:"invocations,omitempty"`
+}
+
+// ShellRule 命令规则
+type ShellRule struct {
+	Command string
+	Args    string
+	Action  ShellAction
+	Reason  string
+
+	commandRe *regexp.Regexp
+	argsRe    *regexp.Regexp
+}
+
+// legacyDenial 旧版 denied_cmds 条目
+type legacyDenial struct {
+	entry string
+	words []string
+	raw   bool // 包含 Shell 元字符，按原文匹配
+}
+
+// ShellPolicy 基于解析结果的 Shell 命令策略
+type ShellPolicy struct {
+	rules      []ShellRule
+	allowed    map[string]bool
+	denied     []legacyDenial
+	riskAction ShellAction
+	workspace  string
+}
+
+// NewShellPolicy 创建 Shell 命令策略
+// allowedCmds 为命令名白名单（为空不限制），deniedCmds 为旧版拒绝列表（如 "rm -rf"）
+func NewShellPolicy(allowedCmds, deniedCmds []string, cfg config.ShellPolicyConfig, workspace string) (*ShellPolicy, error) {
+	p := &ShellPolicy{
+		riskAction: ShellActionApprove,
+		workspace:  workspace,
+	}
+
+	if cfg.RiskAction != "" {
+		p.riskAction = ShellAction(cfg.RiskAction)
+	}
+
+	if len(allowedCmds) > 0 {
+		p.allowed = make(map[string]bool, len(allowedCmds))
+		for _, name := range allowedCmds {
+			p.allowed[name] = true
+		}
+	}
+
+	for _, entry := range deniedCmds {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		words := strings.Fields(entry)
+		p.denied = append(p.denied, legacyDenial{
+			entry: entry,
+			words: words,
+			raw:   strings.ContainsAny(entry, "|&;()<>{}$`\"'"),
+		})
+	}
+
+	for _, rc := range cfg.Rules {
+		rule := ShellRule{
+			Command: rc.Command,
+			Args:    rc.Args,
+			Action:  ShellAction(rc.Action),
+			Reason:  rc.Reason,
+		}
+		switch rule.Action {
+		case ShellActionAllow, ShellActionDeny, ShellActionApprove:
+		default:
+			return nil, fmt.Errorf("invalid shell rule action: %s", rc.Action)
+		}
+		rule.commandRe = globToRegexp(rc.Command)
+		if rc.Args != "" {
+			rule.argsRe = globToRegexp(rc.Args)
+		}
+		p.rules = append(p.rules, rule)
+	}
+
+	return p, nil
+}
+
+// DefaultShellPolicy 不带规则的默认策略（仅做风险分类）
+func DefaultShellPolicy(workspace string) *ShellPolicy {
+	p, _ := NewShellPolicy(nil, nil, config.ShellPolicyConfig{}, workspace)
+	return p
+}
+
+// globToRegexp 将 * / ? 通配模式转换为锚定的正则表达式
+func globToRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// matches 判断规则是否匹配调用
+func (r *ShellRule) matches(inv ShellInvocation) bool {
+	if !r.commandRe.MatchString(inv.Name) && !r.commandRe.MatchString(inv.Path) {
+		return false
+	}
+	if r.argsRe == nil {
+		return true
+	}
+	return r.argsRe.MatchString(strings.Join(inv.Args, " "))
+}
+
+// Evaluate 评估命令，返回允许、拒绝或需要审批
+func (p *ShellPolicy) Evaluate(command string) ShellDecision {
+	// 含 Shell 元字符的旧版拒绝条目按原文匹配（如 fork 炸弹）
+	for _, d := range p.denied {
+		if d.raw && strings.Contains(command, d.entry) {
+			return ShellDecision{Action: ShellActionDeny, Reason: fmt.Sprintf("matches denied pattern %q", d.entry)}
+		}
+	}
+
+	invocations, err := ParseShellCommand(command)
+	if err != nil {
+		risk := ShellRisk{Category: RiskUnparseable, Reason: fmt.Sprintf("command could not be parsed: %v", err)}
+		if p.allowed != nil {
+			return ShellDecision{Action: ShellActionDeny, Reason: risk.Reason, Risks: []ShellRisk{risk}}
+		}
+		return p.riskDecision([]ShellRisk{risk}, nil)
+	}
+
+	var risks []ShellRisk
+	approveReason := ""
+	for _, inv := range invocations {
+		if rule := p.matchRule(inv); rule != nil {
+			switch rule.Action {
+			case ShellActionDeny:
+				return ShellDecision{Action: ShellActionDeny, Reason: ruleReason(rule, inv), Invocations: invocations}
+			case ShellActionApprove:
+				if approveReason == "" {
+					approveReason = ruleReason(rule, inv)
+				}
+				continue
+			case ShellActionAllow:
+				// 显式允许的调用不再做白名单和风险检查
+				continue
+			}
+		}
+
+		for _, d := range p.denied {
+			if !d.raw && d.matches(inv) {
+				return ShellDecision{Action: ShellActionDeny, Reason: fmt.Sprintf("%s matches denied command %q", inv.Name, d.entry), Invocations: invocations}
+			}
+		}
+
+		if p.allowed != nil && !p.allowed[inv.Name] && !p.allowed[inv.Path] {
+			return ShellDecision{Action: ShellActionDeny, Reason: fmt.Sprintf("command %q is not in the allowed list", inv.Path), Invocations: invocations}
+		}
+
+		risks = append(risks, p.classify(inv)...)
+	}
+
+	decision := p.riskDecision(risks, invocations)
+	if approveReason != "" && decision.Action == ShellActionAllow {
+		decision.Action = ShellActionApprove
+		decision.Reason = approveReason
+	}
+	return decision
+}
+
+// riskDecision 根据风险列表和 risk_action 生成结果
+func (p *ShellPolicy) riskDecision(risks []ShellRisk, invocations []ShellInvocation) ShellDecision {
+	if len(risks) == 0 {
+		return ShellDecision{Action: ShellActionAllow, Invocations: invocations}
+	}
+	reasons := make([]string, 0, len(risks))
+	for _, r := range risks {
+		reasons = append(reasons, r.Reason)
+	}
+	return ShellDecision{
+		Action:      p.riskAction,
+		Reason:      strings.Join(reasons, "; "),
+		Risks:       risks,
+		Invocations: invocations,
+	}
+}
+
+// matchRule 返回第一条匹配的规则
+func (p *ShellPolicy) matchRule(inv ShellInvocation) *ShellRule {
+	for i := range p.rules {
+		if p.rules[i].matches(inv) {
+			return &p.rules[i]
+		}
+	}
+	return nil
+}
+
+func ruleReason(rule *ShellRule, inv ShellInvocation) string {
+	if rule.Reason != "" {
+		return rule.Reason
+	}
+	return fmt.Sprintf("%s matches %s rule for %q", inv.Name, rule.Action, rule.Command)
+}
+
+// matches 判断旧版拒绝条目是否匹配调用
+// "rm -rf" 匹配 rm -rf、rm -fr、rm -r -f、rm --recursive --force 等写法
+func (d legacyDenial) matches(inv ShellInvocation) bool {
+	if len(d.words) == 0 {
+		return false
+	}
+	// mkfs 同时匹配 mkfs.ext4 等变体
+	if inv.Name != d.words[0] && inv.Path != d.words[0] && !strings.HasPrefix(inv.Name, d.words[0]+".") {
+		return false
+	}
+	flags := shortFlags(inv.Args)
+	for _, w := range d.words[1:] {
+		if strings.HasPrefix(w, "-") && !strings.HasPrefix(w, "--") && len(w) > 1 {
+			for _, f := range w[1:] {
+				if !flags[f] && !(f == 'r' && flags['R']) && !hasLongFlag(inv.Args, longFlagAliases[f]) {
+					return false
+				}
+			}
+			continue
+		}
+		if !containsString(inv.Args, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// longFlagAliases 常见短选项对应的长选项
+var longFlagAliases = map[rune]string{
+	'r': "--recursive",
+	'R': "--recursive",
+	'f': "--force",
+}
+
+// shortFlags 收集参数中的短选项字母
+func shortFlags(args []string) map[rune]bool {
+	flags := make(map[rune]bool)
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
+			for _, f := range arg[1:] {
+				flags[f] = true
+			}
+		}
+	}
+	return flags
+}
+
+func hasLongFlag(args []string, flag string) bool {
+	return flag != "" && containsString(args, flag)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// positionalArgs 返回非选项参数
+func positionalArgs(args []string) []string {
+	var out []string
+	afterDash := false
+	for _, arg := range args {
+		if !afterDash && arg == "--" {
+			afterDash = true
+			continue
+		}
+		if !afterDash && strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// downloadCommands 下载远程内容的命令
+var downloadCommands = map[string]bool{"curl": true, "wget": true, "fetch": true, "aria2c": true}
+
+// interpreterCommands 可以执行标准输入内容的解释器
+var interpreterCommands = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "ash": true, "fish": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+}
+
+// classify 对单个调用进行风险分类
+func (p *ShellPolicy) classify(inv ShellInvocation) []ShellRisk {
+	var risks []ShellRisk
+	add := func(category, reason string) {
+		risks = append(risks, ShellRisk{Category: category, Command: inv.Name, Reason: reason})
+	}
+
+	if inv.Dynamic() {
+		add(RiskDynamic, fmt.Sprintf("command name %q is computed at runtime", inv.Path))
+	}
+
+	switch inv.Name {
+	case "rm":
+		flags := shortFlags(inv.Args)
+		recursive := flags['r'] || flags['R'] || containsString(inv.Args, "--recursive")
+		force := flags['f'] || containsString(inv.Args, "--force")
+		if recursive && force {
+			add(RiskRecursiveDelete, "recursive forced delete (rm -rf)")
+		}
+	case "dd", "mkfs", "fdisk", "sfdisk", "parted", "wipefs", "shred", "mkswap":
+		add(RiskDisk, fmt.Sprintf("raw disk operation (%s)", inv.Name))
+	case "sudo", "su", "doas", "pkexec":
+		add(RiskPrivilege, fmt.Sprintf("privilege escalation (%s)", inv.Name))
+	case "shutdown", "reboot", "halt", "poweroff":
+		add(RiskSystem, fmt.Sprintf("system power control (%s)", inv.Name))
+	case "git":
+		if reason := gitConfigOverride(inv.Args); reason != "" {
+			add(RiskGitConfig, reason)
+		}
+		if reason := destructiveGit(inv.Args); reason != "" {
+			add(RiskGitDestructive, reason)
+		}
+	case "source", ".":
+		add(RiskDynamic, fmt.Sprintf("sourced script cannot be inspected (%s %s)", inv.Name, strings.Join(inv.Args, " ")))
+	case "find":
+		if reason := destructiveFind(inv.Args); reason != "" {
+			add(RiskRecursiveDelete, reason)
+		}
+	}
+	if strings.HasPrefix(inv.Name, "mkfs.") {
+		add(RiskDisk, fmt.Sprintf("raw disk operation (%s)", inv.Name))
+	}
+
+	if flag := inlineCodeFlag(inv); flag != "" {
+		add(RiskInlineCode, fmt.Sprintf("inline %s code (%s %s) cannot be inspected", inv.Name, inv.Name, flag))
+	}
+	if reason := awkCommandExec(inv); reason != "" {
+		add(RiskInlineCode, reason)
+	}
+
+	if isShellInterpreter(inv.Name) && readsScriptFromStdin(inv.Args) {
+		// 管道、here-document、here-string 或 < 重定向提供的脚本内容无法检查
+		add(RiskDynamic, fmt.Sprintf("%s reads its script from standard input, which cannot be inspected", inv.Name))
+	}
+
+	if interpreterCommands[inv.Name] {
+		if downloadCommands[inv.PipedFrom] {
+			add(RiskRemoteExec, fmt.Sprintf("remote content piped into %s (%s | %s)", inv.Name, inv.PipedFrom, inv.Name))
+		}
+		for _, arg := range inv.Args {
+			if strings.HasPrefix(arg, "<(") && containsDownload(arg) {
+				add(RiskRemoteExec, fmt.Sprintf("remote content executed by %s via process substitution", inv.Name))
+				break
+			}
+		}
+	}
+
+	for _, target := range writeTargets(inv) {
+		if !p.insideWorkspace(target) {
+			add(RiskOutsideWrite, fmt.Sprintf("%s writes outside the workspace: %s", inv.Name, target))
+		}
+	}
+
+	return risks
+}
+
+// containsDownload 判断进程替换内容中是否包含下载命令
+func containsDownload(arg string) bool {
+	invs, err := ParseShellCommand(strings.TrimSuffix(strings.TrimPrefix(arg, "<("), ")"))
+	if err != nil {
+		return false
+	}
+	for _, inv := range invs {
+		if downloadCommands[inv.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// shellValueOptions Shell 解释器中需要额外参数值的选项
+var shellValueOptions = map[string]bool{
+	"-o": true, "+o": true, "-O": true, "+O": true, "--rcfile": true, "--init-file": true,
+}
+
+// readsScriptFromStdin 判断 Shell 解释器是否从标准输入读取脚本（没有 -c 内容也没有脚本文件）
+func readsScriptFromStdin(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return i+1 >= len(args) || args[i+1] == "-"
+		}
+		if arg == "-" {
+			return true
+		}
+		if !strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "+") {
+			// 第一个位置参数是脚本文件
+			return false
+		}
+		if strings.HasPrefix(arg, "--") {
+			if shellValueOptions[arg] {
+				i++
+			}
+			continue
+		}
+		if strings.Contains(arg[1:], "s") {
+			return true
+		}
+		if strings.Contains(arg[1:], "c") {
+			// sh -c 的内容已被解析检查
+			return false
+		}
+		if shellValueOptions[arg] {
+			i++
+		}
+	}
+	return true
+}
+
+// inlineCodeFlags 非 Shell 解释器中用于执行内联代码的选项（sh -c 的内容会被解析检查）
+var inlineCodeFlags = map[string][]string{
+	"python":  {"-c"},
+	"python3": {"-c"},
+	"perl":    {"-e", "-E"},
+	"ruby":    {"-e"},
+	"node":    {"-e", "--eval", "-p", "--print"},
+	"php":     {"-r"},
+}
+
+// inlineCodeFlag 返回调用中执行内联代码的选项，只检查脚本名之前的选项
+func inlineCodeFlag(inv ShellInvocation) string {
+	flags, ok := inlineCodeFlags[inv.Name]
+	if !ok {
+		return ""
+	}
+	for _, arg := range inv.Args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return ""
+		}
+		for _, flag := range flags {
+			// 短选项可以合并，如 perl -ne、python -uc
+			if arg == flag || (!strings.HasPrefix(flag, "--") && !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg[1:], rune(flag[1]))) {
+				return flag
+			}
+		}
+	}
+	return ""
+}
+
+// awkCommands awk 的各种实现
+var awkCommands = map[string]bool{"awk": true, "gawk": true, "mawk": true, "nawk": true}
+
+// awkValueOptions awk 中需要额外参数值的选项
+var awkValueOptions = map[string]bool{"-F": true, "-v": true, "-f": true, "-e": true, "--source": true, "--file": true, "--assign": true, "--field-separator": true}
+
+// awkExecPattern awk 程序中执行命令的写法：system()、print | "cmd"、"cmd" | getline、gawk 协进程 |&
+var awkExecPattern = regexp.MustCompile(`\bsystem\s*\(|\|&|\|\s*getline\b|\bprintf?\b[^;}\n]*\|`)
+
+// awkCommandExec 检测通过 system() 或管道执行命令的 awk 程序
+func awkCommandExec(inv ShellInvocation) string {
+	if !awkCommands[inv.Name] {
+		return ""
+	}
+	var programs []string
+	fromFile := false
+	for i := 0; i < len(inv.Args); i++ {
+		arg := inv.Args[i]
+		if arg == "--" {
+			if !fromFile && i+1 < len(inv.Args) {
+				programs = append(programs, inv.Args[i+1])
+			}
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			// 没有 -f/-e 时第一个位置参数是程序
+			if !fromFile && len(programs) == 0 {
+				programs = append(programs, arg)
+			}
+			break
+		}
+		switch {
+		case arg == "-f" || arg == "--file":
+			fromFile = true
+		case arg == "-e" || arg == "--source":
+			if i+1 < len(inv.Args) {
+				programs = append(programs, inv.Args[i+1])
+			}
+			fromFile = true
+		}
+		if awkValueOptions[arg] {
+			i++
+		}
+	}
+	for _, prog := range programs {
+		if awkExecPattern.MatchString(prog) {
+			return fmt.Sprintf("%s program runs commands via system() or pipes, which cannot be inspected", inv.Name)
+		}
+	}
+	return ""
+}
+
+// deleteCommands find -exec 中会删除文件的命令
+var deleteCommands = map[string]bool{"rm": true, "rmdir": true, "unlink": true, "shred": true}
+
+// destructiveFind 检测批量删除匹配文件的 find 调用
+func destructiveFind(args []string) string {
+	for i, arg := range args {
+		switch arg {
+		case "-delete":
+			return "delete every matching file (find -delete)"
+		case "-exec", "-execdir", "-ok", "-okdir":
+			if i+1 < len(args) && deleteCommands[filepath.Base(args[i+1])] {
+				return fmt.Sprintf("delete every matching file (find %s %s)", arg, filepath.Base(args[i+1]))
+			}
+		}
+	}
+	return ""
+}
+
+// gitGlobalValueOptions 子命令之前需要额外参数值的 git 全局选项
+var gitGlobalValueOptions = map[string]bool{
+	"-C": true, "-c": true, "--git-dir": true, "--work-tree": true, "--namespace": true,
+	"--exec-path": true, "--super-prefix": true, "--config-env": true,
+}
+
+// gitSubcommandArgs 跳过 git 全局选项（及其参数值），返回从子命令开始的参数
+func gitSubcommandArgs(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return args[i:]
+		}
+		if arg == "--" {
+			return args[i+1:]
+		}
+		if gitGlobalValueOptions[arg] {
+			i++
+		}
+	}
+	return nil
+}
+
+// gitConfigOverride 检测可以改写配置或执行路径的 git 全局选项
+// core.pager、core.sshCommand、alias.x=!cmd 等配置都能执行任意命令
+func gitConfigOverride(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "--" {
+			return ""
+		}
+		switch {
+		case strings.HasPrefix(arg, "-c"):
+			return "git configuration override (git -c) can run arbitrary commands"
+		case arg == "--config-env" || strings.HasPrefix(arg, "--config-env="):
+			return "git configuration override (git --config-env) can run arbitrary commands"
+		case arg == "--exec-path" || strings.HasPrefix(arg, "--exec-path="):
+			return "git helper path override (git --exec-path) can run arbitrary programs"
+		}
+		if gitGlobalValueOptions[arg] {
+			i++
+		}
+	}
+	return ""
+}
+
+// destructiveGit 检测破坏性的 git 操作
+func destructiveGit(args []string) string {
+	args = gitSubcommandArgs(args)
+	pos := positionalArgs(args)
+	if len(pos) == 0 {
+		return ""
+	}
+	switch pos[0] {
+	case "push":
+		if containsString(args, "--force") || containsString(args, "-f") || containsString(args, "--mirror") {
+			return "force push (git push --force)"
+		}
+		for _, ref := range pos[1:] {
+			if strings.HasPrefix(ref, "+") || strings.HasPrefix(ref, ":") {
+				return "force push or remote branch deletion"
+			}
+		}
+	case "reset":
+		if containsString(args, "--hard") {
+			return "discard local changes (git reset --hard)"
+		}
+	case "clean":
+		if shortFlags(args)['f'] || containsString(args, "--force") {
+			return "delete untracked files (git clean -f)"
+		}
+	}
+	return ""
+}
+
+// writeTargets 返回调用会写入的路径
+func writeTargets(inv ShellInvocation) []string {
+	var targets []string
+	for _, r := range inv.Redirects {
+		if strings.Contains(r.Op, ">") {
+			if r.Op == ">&" || strings.HasSuffix(r.Op, ">&") {
+				// 2>&1 之类复制文件描述符
+				if r.Target == "-" || isAllDigits(r.Target) {
+					continue
+				}
+			}
+			targets = append(targets, r.Target)
+		}
+	}
+
+	pos := positionalArgs(inv.Args)
+	switch inv.Name {
+	case "tee", "rm", "rmdir", "touch", "mkdir", "truncate", "shred", "unlink":
+		targets = append(targets, pos...)
+	case "cp", "mv", "install", "ln", "rsync", "scp":
+		if len(pos) >= 2 {
+			targets = append(targets, pos[len(pos)-1])
+		}
+	case "chmod", "chown", "chgrp":
+		if len(pos) >= 2 {
+			targets = append(targets, pos[1:]...)
+		}
+	case "dd":
+		for _, arg := range inv.Args {
+			if strings.HasPrefix(arg, "of=") {
+				targets = append(targets, strings.TrimPrefix(arg, "of="))
+			}
+		}
+	case "sed", "gsed":
+		targets = append(targets, inPlaceTargets(inv.Args, sedOptions, sedInPlace)...)
+	case "perl":
+		targets = append(targets, inPlaceTargets(inv.Args, perlOptions, perlInPlace)...)
+	}
+	return targets
+}
+
+// inPlaceOptions 就地编辑命令的选项：values 需要额外参数值，scripts 提供脚本（此时所有位置参数都是文件）
+type inPlaceOptions struct {
+	values  map[string]bool
+	scripts map[string]bool
+}
+
+var sedOptions = inPlaceOptions{
+	values:  map[string]bool{"-e": true, "-f": true, "-l": true, "--expression": true, "--file": true, "--line-length": true},
+	scripts: map[string]bool{"-e": true, "-f": true, "--expression": true, "--file": true},
+}
+
+var perlOptions = inPlaceOptions{
+	values:  map[string]bool{"-e": true, "-E": true, "-I": true, "-M": true, "-m": true},
+	scripts: map[string]bool{"-e": true, "-E": true},
+}
+
+// sedInPlace 判断 sed 选项是否开启就地编辑（-i、-iSUFFIX、-Ei、--in-place[=SUFFIX]）
+func sedInPlace(arg string) bool {
+	if arg == "--in-place" || strings.HasPrefix(arg, "--in-place=") {
+		return true
+	}
+	if strings.HasPrefix(arg, "--") {
+		return false
+	}
+	// -i 之后的字符是备份后缀，之前只能是不带参数的短选项
+	k := strings.IndexByte(arg, 'i')
+	return k > 0 && strings.Trim(arg[1:k], "nrsuzEO") == ""
+}
+
+// perlInPlace 判断 perl 选项是否开启就地编辑（-i、-i.bak、-pi、-pi.bak）
+func perlInPlace(arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		return false
+	}
+	k := strings.IndexByte(arg, 'i')
+	return k > 0 && strings.Trim(arg[1:k], "aclnpswx0123456789") == ""
+}
+
+// inPlaceTargets 返回 sed -i / perl -i 就地修改的文件
+func inPlaceTargets(args []string, opts inPlaceOptions, inPlace func(string) bool) []string {
+	editing, script := false, false
+	var pos []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			pos = append(pos, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			pos = append(pos, arg)
+			continue
+		}
+		if inPlace(arg) {
+			editing = true
+			continue
+		}
+		name := arg
+		if idx := strings.IndexByte(arg, '='); idx > 0 && strings.HasPrefix(arg, "--") {
+			name = arg[:idx]
+		} else if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// 合并的短选项中，第一个带参数的选项之后都是参数值，如 -e's/a/b/'、-pe 's/a/b/'
+			for j := 1; j < len(arg); j++ {
+				if short := "-" + arg[j:j+1]; opts.values[short] {
+					script = script || opts.scripts[short]
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+			continue
+		}
+		if opts.scripts[name] {
+			script = true
+		}
+		if opts.values[arg] {
+			i++
+		}
+	}
+	if !editing {
+		return nil
+	}
+	if !script && len(pos) > 0 {
+		// 第一个位置参数是脚本
+		pos = pos[1:]
+	}
+	var targets []string
+	for _, p := range pos {
+		// macOS sed -i '' 的空后缀
+		if p != "" {
+			targets = append(targets, p)
+		}
+	}
+	return targets
+}
+
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// insideWorkspace 判断路径是否位于工作区（或临时目录等无害位置）内
+func (p *ShellPolicy) insideWorkspace(target string) bool {
+	switch target {
+	case "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return true
+	}
+	// scp/rsync 远程目标（host:path）不属于本机写入
+	if idx := strings.Index(target, ":"); idx > 0 && !strings.Contains(target[:idx], "/") {
+		return true
+	}
+
+	home, _ := os.UserHomeDir()
+	switch {
+	case target == "~" || strings.HasPrefix(target, "~/"):
+		if home == "" {
+			return false
+		}
+		target = filepath.Join(home, strings.TrimPrefix(target, "~"))
+	case strings.HasPrefix(target, "$HOME"):
+		if home == "" {
+			return false
+		}
+		target = filepath.Join(home, strings.TrimPrefix(target, "$HOME"))
+	case strings.ContainsAny(target, "$`"):
+		// 运行时才能确定的路径
+		return false
+	}
+
+	workspace := p.workspace
+	if workspace == "" {
+		workspace, _ = os.Getwd()
+	}
+	workspace, _ = filepath.Abs(workspace)
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(workspace, target)
+	}
+	target = filepath.Clean(target)
+
+	for _, root := range []string{workspace, filepath.Clean(os.TempDir()), "/tmp"} {
+		if root == "" {
+			continue
+		}
+		if target == root || strings.HasPrefix(target, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,529 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultProcessOutputLimit 每个输出流保留的最大字节数
+	defaultProcessOutputLimit = 1 << 20
+	// defaultMaxProcessesPerSession 每个会话允许的最大后台进程数
+	defaultMaxProcessesPerSession = 8
+	// defaultProcessRetention 已结束进程保留的时长，过期后连同输出一起回收
+	defaultProcessRetention = 30 * time.Minute
+	// defaultMaxFinishedProcesses 每个会话保留的已结束进程数上限，超出时回收最早结束的
+	defaultMaxFinishedProcesses = 16
+)
+
+// ProcessStatus 后台进程状态
+type ProcessStatus string
+
+const (
+	ProcessStatusRunning ProcessStatus = "running"
+	ProcessStatusExited  ProcessStatus = "exited"
+	ProcessStatusKilled  ProcessStatus = "killed"
+)
+
+// outputBuffer 有界输出缓冲区，支持增量读取
+// 超出上限时丢弃最早的数据，dropped 记录被丢弃的字节数
+type outputBuffer struct {
+	data    []byte
+	dropped int64
+	readPos int64
+	limit   int
+}
+
+// write 追加数据
+func (b *outputBuffer) write(p []byte) {
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		b.data = append([]byte(nil), b.data[over:]...)
+		b.dropped += int64(over)
+	}
+}
+
+// readNew 读取自上次读取以来的新数据，返回内容和是否有数据被截断
+func (b *outputBuffer) readNew() (string, bool) {
+	truncated := false
+	if b.readPos < b.dropped {
+		b.readPos = b.dropped
+		truncated = true
+	}
+	start := int(b.readPos - b.dropped)
+	out := string(b.data[start:])
+	b.readPos = b.dropped + int64(len(b.data))
+	return out, truncated
+}
+
+// BackgroundProcess 后台运行的 Shell 进程
+type BackgroundProcess struct {
+	ID         string
+	SessionKey string
+	Command    string
+	StartedAt  time.Time
+
+	handle   processHandle
+	stdin    io.WriteCloser
+	mu       sync.Mutex
+	stdout   outputBuffer
+	stderr   outputBuffer
+	status   ProcessStatus
+	exitCode int
+	endedAt  time.Time
+	notify   chan struct{}
+	done     chan struct{}
+}
+
+// ProcessSnapshot 后台进程状态快照
+type ProcessSnapshot struct {
+	ID         string        `json:"id"`
+	SessionKey string        `json:"session_key,omitempty"`
+	Command    string        `json:"command"`
+	Status     ProcessStatus `json:"status"`
+	ExitCode   int           `json:"exit_code"`
+	PID        int           `json:"pid"`
+	StartedAt  time.Time     `json:"started_at"`
+	EndedAt    *time.Time    `json:"ended_at,omitempty"`
+}
+
+// ProcessOutput 增量读取的进程输出
+type ProcessOutput struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// processWriter 将输出写入进程缓冲区并唤醒等待者
+type processWriter struct {
+	proc   *BackgroundProcess
+	stderr bool
+}
+
+func (w *processWriter) Write(p []byte) (int, error) {
+	w.proc.mu.Lock()
+	if w.stderr {
+		w.proc.stderr.write(p)
+	} else {
+		w.proc.stdout.write(p)
+	}
+	w.proc.signalLocked()
+	w.proc.mu.Unlock()
+	return len(p), nil
+}
+
+// signalLocked 通知等待新输出的读取者（调用方需持有锁）
+func (p *BackgroundProcess) signalLocked() {
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// Snapshot 返回进程状态快照
+func (p *BackgroundProcess) Snapshot() ProcessSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap := ProcessSnapshot{
+		ID:         p.ID,
+		SessionKey: p.SessionKey,
+		Command:    p.Command,
+		Status:     p.status,
+		ExitCode:   p.exitCode,
+		StartedAt:  p.StartedAt,
+	}
+	if p.handle != nil {
+		snap.PID = p.handle.PID()
+	}
+	if !p.endedAt.IsZero() {
+		ended := p.endedAt
+		snap.EndedAt = &ended
+	}
+	return snap
+}
+
+// ReadOutput 读取自上次读取以来的新输出
+func (p *BackgroundProcess) ReadOutput() ProcessOutput {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stdout, outTrunc := p.stdout.readNew()
+	stderr, errTrunc := p.stderr.readNew()
+	return ProcessOutput{Stdout: stdout, Stderr: stderr, Truncated: outTrunc || errTrunc}
+}
+
+// Updates 返回下一次输出或状态变化时关闭的 channel
+func (p *BackgroundProcess) Updates() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.notify
+}
+
+// Done 返回进程结束时关闭的 channel
+func (p *BackgroundProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// WriteInput 向进程标准输入写入数据
+func (p *BackgroundProcess) WriteInput(input string, closeStdin bool) error {
+	p.mu.Lock()
+	stdin := p.stdin
+	running := p.status == ProcessStatusRunning
+	p.mu.Unlock()
+
+	if !running {
+		return fmt.Errorf("process %s is not running", p.ID)
+	}
+	if stdin == nil {
+		return fmt.Errorf("stdin of process %s is closed", p.ID)
+	}
+	if input != "" {
+		if _, err := io.WriteString(stdin, input); err != nil {
+			return fmt.Errorf("failed to write stdin: %w", err)
+		}
+	}
+	if closeStdin {
+		p.mu.Lock()
+		p.stdin = nil
+		p.mu.Unlock()
+		return stdin.Close()
+	}
+	return nil
+}
+
+// Kill 终止进程：先请求退出，超过宽限期后强制结束
+func (p *BackgroundProcess) Kill(grace time.Duration) {
+	p.mu.Lock()
+	if p.status != ProcessStatusRunning || p.handle == nil {
+		p.mu.Unlock()
+		return
+	}
+	p.status = ProcessStatusKilled
+	handle := p.handle
+	p.mu.Unlock()
+
+	handle.Terminate(false)
+	select {
+	case <-p.done:
+	case <-time.After(grace):
+		handle.Terminate(true)
+		<-p.done
+	}
+}
+
+// wait 等待进程退出并记录状态
+func (p *BackgroundProcess) wait() {
+	exitCode := p.handle.Wait()
+
+	p.mu.Lock()
+	if p.status == ProcessStatusRunning {
+		p.status = ProcessStatusExited
+	}
+	p.exitCode = exitCode
+	p.endedAt = time.Now()
+	if p.stdin != nil {
+		_ = p.stdin.Close()
+		p.stdin = nil
+	}
+	p.signalLocked()
+	p.mu.Unlock()
+
+	close(p.done)
+}
+
+// processHandle 后台进程的底层句柄：宿主机上的进程组或沙箱容器内的 exec
+type processHandle interface {
+	// PID 返回进程 ID，未知时为 0
+	PID() int
+	// Wait 阻塞至进程退出，返回退出码（无法获取时为 -1）
+	Wait() int
+	// Terminate 请求进程退出，force 为 true 时强制结束
+	Terminate(force bool)
+}
+
+// processLauncher 启动进程，输出写入 stdout/stderr，返回进程句柄和标准输入
+type processLauncher func(stdout, stderr io.Writer) (processHandle, io.WriteCloser, error)
+
+// hostProcess 宿主机上以独立进程组运行的命令
+type hostProcess struct {
+	cmd *exec.Cmd
+}
+
+func (h *hostProcess) PID() int {
+	return h.cmd.Process.Pid
+}
+
+func (h *hostProcess) Wait() int {
+	err := h.cmd.Wait()
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (h *hostProcess) Terminate(force bool) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	_ = syscall.Kill(-h.cmd.Process.Pid, sig)
+}
+
+// hostLauncher 在宿主机上启动命令
+func hostLauncher(command, workingDir string) processLauncher {
+	return func(stdout, stderr io.Writer) (processHandle, io.WriteCloser, error) {
+		cmd := exec.Command("sh", "-c", command)
+		if workingDir != "" {
+			cmd.Dir = workingDir
+		}
+		// 设置进程组，确保能够杀死整个进程树
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, fmt.Errorf("failed to start command: %w", err)
+		}
+		return &hostProcess{cmd: cmd}, stdin, nil
+	}
+}
+
+// ProcessManager 后台进程管理器，按会话跟踪进程
+type ProcessManager struct {
+	mu            sync.Mutex
+	procs         map[string]*BackgroundProcess
+	starting      map[string]int // 正在启动、尚未登记的进程数，按会话计
+	outputLimit   int
+	maxPerSession int
+	retention     time.Duration // 已结束进程的保留时长
+	maxFinished   int           // 每个会话保留的已结束进程数
+}
+
+// NewProcessManager 创建后台进程管理器
+func NewProcessManager() *ProcessManager {
+	return &ProcessManager{
+		procs:         make(map[string]*BackgroundProcess),
+		starting:      make(map[string]int),
+		outputLimit:   defaultProcessOutputLimit,
+		maxPerSession: defaultMaxProcessesPerSession,
+		retention:     defaultProcessRetention,
+		maxFinished:   defaultMaxFinishedProcesses,
+	}
+}
+
+// Start 在宿主机后台启动命令
+func (m *ProcessManager) Start(sessionKey, command, workingDir string) (*BackgroundProcess, error) {
+	return m.startWith(sessionKey, command, hostLauncher(command, workingDir))
+}
+
+// startWith 通过 launch 在后台启动命令（宿主机或沙箱容器）
+// 名额在持锁时预留，并发启动也不会超过每个会话的上限
+func (m *ProcessManager) startWith(sessionKey, command string, launch processLauncher) (*BackgroundProcess, error) {
+	if err := m.reserve(sessionKey); err != nil {
+		return nil, err
+	}
+	proc, err := m.start(sessionKey, command, launch)
+
+	m.mu.Lock()
+	m.starting[sessionKey]--
+	if m.starting[sessionKey] == 0 {
+		delete(m.starting, sessionKey)
+	}
+	if err == nil {
+		m.procs[proc.ID] = proc
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	go proc.wait()
+
+	logger.Info("Background process started",
+		zap.String("id", proc.ID),
+		zap.String("session_key", sessionKey),
+		zap.Int("pid", proc.handle.PID()),
+		zap.String("command", command))
+
+	return proc, nil
+}
+
+// reserve takes a slot for a process that is about to start
+func (m *ProcessManager) reserve(sessionKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reapLocked(time.Now())
+	running := m.starting[sessionKey]
+	for _, p := range m.procs {
+		if p.SessionKey == sessionKey && p.Snapshot().Status == ProcessStatusRunning {
+			running++
+		}
+	}
+	if running >= m.maxPerSession {
+		return fmt.Errorf("too many background processes in this session (max %d), kill some first", m.maxPerSession)
+	}
+	m.starting[sessionKey]++
+	return nil
+}
+
+// start launches the command; the caller has reserved a slot
+func (m *ProcessManager) start(sessionKey, command string, launch processLauncher) (*BackgroundProcess, error) {
+	proc := &BackgroundProcess{
+		ID:         "proc-" + uuid.NewString()[:8],
+		SessionKey: sessionKey,
+		Command:    command,
+		StartedAt:  time.Now(),
+		stdout:     outputBuffer{limit: m.outputLimit},
+		stderr:     outputBuffer{limit: m.outputLimit},
+		status:     ProcessStatusRunning,
+		notify:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	handle, stdin, err := launch(&processWriter{proc: proc}, &processWriter{proc: proc, stderr: true})
+	if err != nil {
+		return nil, err
+	}
+	proc.handle = handle
+	proc.stdin = stdin
+	return proc, nil
+}
+
+// Get 获取指定会话中的进程
+func (m *ProcessManager) Get(sessionKey, id string) (*BackgroundProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	proc, ok := m.procs[id]
+	if !ok || proc.SessionKey != sessionKey {
+		return nil, fmt.Errorf("process not found: %s", id)
+	}
+	return proc, nil
+}
+
+// List 列出指定会话中的进程（按启动时间排序）
+func (m *ProcessManager) List(sessionKey string) []ProcessSnapshot {
+	m.mu.Lock()
+	m.reapLocked(time.Now())
+	procs := make([]*BackgroundProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		if p.SessionKey == sessionKey {
+			procs = append(procs, p)
+		}
+	}
+	m.mu.Unlock()
+
+	snaps := make([]ProcessSnapshot, 0, len(procs))
+	for _, p := range procs {
+		snaps = append(snaps, p.Snapshot())
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].StartedAt.Before(snaps[j].StartedAt)
+	})
+	return snaps
+}
+
+// reapLocked 回收已结束的进程，释放其输出缓冲区
+// 结束超过保留时长的进程被移除；每个会话只保留最近结束的 maxFinished 个。调用方需持有 m.mu
+func (m *ProcessManager) reapLocked(now time.Time) {
+	finished := make(map[string][]*BackgroundProcess)
+	for id, p := range m.procs {
+		p.mu.Lock()
+		status, endedAt := p.status, p.endedAt
+		p.mu.Unlock()
+		if status == ProcessStatusRunning || endedAt.IsZero() {
+			continue
+		}
+		if now.Sub(endedAt) > m.retention {
+			delete(m.procs, id)
+			continue
+		}
+		finished[p.SessionKey] = append(finished[p.SessionKey], p)
+	}
+
+	for _, procs := range finished {
+		if len(procs) <= m.maxFinished {
+			continue
+		}
+		sort.Slice(procs, func(i, j int) bool {
+			return procs[i].endedAt.After(procs[j].endedAt)
+		})
+		for _, p := range procs[m.maxFinished:] {
+			delete(m.procs, p.ID)
+		}
+	}
+}
+
+// Remove 终止并移除进程
+func (m *ProcessManager) Remove(sessionKey, id string) error {
+	proc, err := m.Get(sessionKey, id)
+	if err != nil {
+		return err
+	}
+	proc.Kill(2 * time.Second)
+
+	m.mu.Lock()
+	delete(m.procs, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// CleanupSession 终止并移除会话的所有后台进程，返回清理的进程数
+func (m *ProcessManager) CleanupSession(sessionKey string) int {
+	m.mu.Lock()
+	var procs []*BackgroundProcess
+	for id, p := range m.procs {
+		if p.SessionKey == sessionKey {
+			procs = append(procs, p)
+			delete(m.procs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range procs {
+		p.Kill(2 * time.Second)
+	}
+	if len(procs) > 0 {
+		logger.Info("Background processes cleaned up",
+			zap.String("session_key", sessionKey),
+			zap.Int("count", len(procs)))
+	}
+	return len(procs)
+}
+
+// CloseAll 终止所有后台进程
+func (m *ProcessManager) CloseAll() {
+	m.mu.Lock()
+	procs := make([]*BackgroundProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.procs = make(map[string]*BackgroundProcess)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range procs {
+		wg.Add(1)
+		go func(p *BackgroundProcess) {
+			defer wg.Done()
+			p.Kill(2 * time.Second)
+		}(p)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,312 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/smallnest/goclaw/config"
+)
+
+func newTestShellTool() *ShellTool {
+	return NewShellTool(true, nil, nil, 10, "", config.SandboxConfig{})
+}
+
+func waitForExit(t *testing.T, proc *BackgroundProcess) {
+	t.Helper()
+	select {
+	case <-proc.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("process did not exit in time")
+	}
+}
+
+func TestOutputBufferIncrementalRead(t *testing.T) {
+	b := outputBuffer{limit: 8}
+	b.write([]byte("abc"))
+	out, truncated := b.readNew()
+	if out != "abc" || truncated {
+		t.Fatalf("unexpected first read: %q truncated=%v", out, truncated)
+	}
+
+	b.write([]byte("defghijklm"))
+	out, truncated = b.readNew()
+	if out != "fghijklm" || !truncated {
+		t.Fatalf("unexpected read after overflow: %q truncated=%v", out, truncated)
+	}
+
+	out, _ = b.readNew()
+	if out != "" {
+		t.Fatalf("expected no new data, got %q", out)
+	}
+}
+
+func TestProcessManagerStdinAndOutput(t *testing.T) {
+	m := NewProcessManager()
+	defer m.CloseAll()
+
+	proc, err := m.Start("s1", "read line; echo got:$line; echo err >&2", "")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := proc.WriteInput("hello\n", true); err != nil {
+		t.Fatalf("WriteInput failed: %v", err)
+	}
+	waitForExit(t, proc)
+
+	out := proc.ReadOutput()
+	if strings.TrimSpace(out.Stdout) != "got:hello" {
+		t.Errorf("unexpected stdout %q", out.Stdout)
+	}
+	if strings.TrimSpace(out.Stderr) != "err" {
+		t.Errorf("unexpected stderr %q", out.Stderr)
+	}
+	if again := proc.ReadOutput(); again.Stdout != "" || again.Stderr != "" {
+		t.Errorf("expected incremental read to be empty, got %+v", again)
+	}
+
+	snap := proc.Snapshot()
+	if snap.Status != ProcessStatusExited || snap.ExitCode != 0 {
+		t.Errorf("unexpected snapshot %+v", snap)
+	}
+}
+
+func TestProcessManagerSessionScoping(t *testing.T) {
+	m := NewProcessManager()
+	defer m.CloseAll()
+
+	proc, err := m.Start("s1", "sleep 30", "")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := m.Get("s2", proc.ID); err == nil {
+		t.Error("expected process to be invisible to other sessions")
+	}
+	if len(m.List("s1")) != 1 {
+		t.Errorf("expected 1 process in s1")
+	}
+
+	if n := m.CleanupSession("s1"); n != 1 {
+		t.Errorf("expected 1 cleaned process, got %d", n)
+	}
+	waitForExit(t, proc)
+	if proc.Snapshot().Status != ProcessStatusKilled {
+		t.Errorf("expected killed status, got %s", proc.Snapshot().Status)
+	}
+	if len(m.List("s1")) != 0 {
+		t.Error("expected no processes after cleanup")
+	}
+}
+
+func TestProcessManagerConcurrentLimit(t *testing.T) {
+	m := NewProcessManager()
+	m.maxPerSession = 2
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var started []*BackgroundProcess
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if proc, err := m.Start("s1", "sleep 30", ""); err == nil {
+				mu.Lock()
+				started = append(started, proc)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(started) != 2 {
+		t.Fatalf("expected 2 concurrent starts to succeed, got %d", len(started))
+	}
+
+	// Shutdown kills everything that is still running
+	m.CloseAll()
+	for _, proc := range started {
+		waitForExit(t, proc)
+		if proc.Snapshot().Status != ProcessStatusKilled {
+			t.Errorf("expected killed status, got %s", proc.Snapshot().Status)
+		}
+	}
+}
+
+func TestProcessManagerReapsFinished(t *testing.T) {
+	m := NewProcessManager()
+	defer m.CloseAll()
+	m.maxFinished = 2
+
+	var procs []*BackgroundProcess
+	for i := 0; i < 3; i++ {
+		proc, err := m.Start("s1", "true", "")
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		waitForExit(t, proc)
+		procs = append(procs, proc)
+	}
+	running, err := m.Start("s1", "sleep 30", "")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// 超出数量上限时回收最早结束的进程，运行中的进程不受影响
+	if got := len(m.List("s1")); got != 3 {
+		t.Fatalf("expected 2 finished and 1 running process, got %d", got)
+	}
+	if _, err := m.Get("s1", procs[0].ID); err == nil {
+		t.Error("expected the oldest finished process to be reaped")
+	}
+
+	// 超过保留时长后回收全部已结束进程
+	m.mu.Lock()
+	m.reapLocked(time.Now().Add(m.retention + time.Second))
+	m.mu.Unlock()
+	snaps := m.List("s1")
+	if len(snaps) != 1 || snaps[0].ID != running.ID {
+		t.Fatalf("expected only the running process to remain, got %+v", snaps)
+	}
+}
+
+func TestShellToolBackgroundLifecycle(t *testing.T) {
+	st := newTestShellTool()
+	defer st.Close()
+	ctx := WithSessionKey(context.Background(), "sess")
+
+	out, err := st.ExecStreaming(ctx, map[string]interface{}{
+		"command":    "echo started; sleep 30",
+		"background": true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("background start failed: %v", err)
+	}
+	var started map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &started); err != nil {
+		t.Fatalf("invalid result %q: %v", out, err)
+	}
+	id, _ := started["process_id"].(string)
+	if id == "" {
+		t.Fatalf("missing process_id in %q", out)
+	}
+
+	var mu sync.Mutex
+	var streamed strings.Builder
+	readOut, err := st.ReadProcess(ctx, map[string]interface{}{
+		"process_id":   id,
+		"wait_seconds": float64(2),
+	}, func(r ToolResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, block := range r.Content {
+			if text, ok := block.(TextContent); ok {
+				streamed.WriteString(text.Text)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("ReadProcess failed: %v", err)
+	}
+	if !strings.Contains(readOut, "started") {
+		t.Errorf("expected output in read result, got %q", readOut)
+	}
+	mu.Lock()
+	if !strings.Contains(streamed.String(), "started") {
+		t.Errorf("expected streamed output, got %q", streamed.String())
+	}
+	mu.Unlock()
+
+	if _, err := st.ReadProcess(WithSessionKey(context.Background(), "other"), map[string]interface{}{"process_id": id}, nil); err == nil {
+		t.Error("expected other session to be denied")
+	}
+
+	if _, err := st.KillProcess(ctx, map[string]interface{}{"process_id": id}); err != nil {
+		t.Fatalf("KillProcess failed: %v", err)
+	}
+	if _, err := st.GetProcessStatus(ctx, map[string]interface{}{"process_id": id}); err == nil {
+		t.Error("expected killed process to be removed")
+	}
+}
+
+func TestShellToolForegroundStreaming(t *testing.T) {
+	st := newTestShellTool()
+	defer st.Close()
+
+	var updates int
+	out, err := st.ExecStreaming(context.Background(), map[string]interface{}{
+		"command": "echo one; sleep 0.1; echo two",
+	}, func(ToolResult) { updates++ })
+	if err != nil {
+		t.Fatalf("ExecStreaming failed: %v", err)
+	}
+	if out != "one\ntwo\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if updates < 2 {
+		t.Errorf("expected at least 2 streamed updates, got %d", updates)
+	}
+}
+
+// fakeHandle stands in for a sandbox exec in launcher tests
+type fakeHandle struct {
+	exit       chan int
+	terminated chan bool
+}
+
+func (h *fakeHandle) PID() int  { return 42 }
+func (h *fakeHandle) Wait() int { return <-h.exit }
+func (h *fakeHandle) Terminate(force bool) {
+	h.terminated <- force
+	h.exit <- 137
+}
+
+func TestProcessManagerCustomLauncher(t *testing.T) {
+	m := NewProcessManager()
+	handle := &fakeHandle{exit: make(chan int, 1), terminated: make(chan bool, 2)}
+	proc, err := m.startWith("s1", "npm run dev", func(stdout, stderr io.Writer) (processHandle, io.WriteCloser, error) {
+		_, _ = io.WriteString(stdout, "listening\n")
+		return handle, nopWriteCloser{io.Discard}, nil
+	})
+	if err != nil {
+		t.Fatalf("startWith failed: %v", err)
+	}
+	if out := proc.ReadOutput(); out.Stdout != "listening\n" {
+		t.Errorf("unexpected stdout %q", out.Stdout)
+	}
+	if snap := proc.Snapshot(); snap.PID != 42 || snap.Status != ProcessStatusRunning {
+		t.Errorf("unexpected snapshot %+v", snap)
+	}
+
+	if err := m.Remove("s1", proc.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if force := <-handle.terminated; force {
+		t.Error("expected a graceful terminate first")
+	}
+	if snap := proc.Snapshot(); snap.Status != ProcessStatusKilled || snap.ExitCode != 137 {
+		t.Errorf("unexpected snapshot after kill %+v", snap)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestShellToolBackgroundOneShotSandbox(t *testing.T) {
+	// With scope none there is no long-lived container to host the process
+	st := &ShellTool{
+		enabled:       true,
+		timeout:       10 * time.Second,
+		sandboxConfig: config.SandboxConfig{Enabled: true, Scope: sandboxScopeNone},
+		dockerClient:  &client.Client{},
+		processes:     NewProcessManager(),
+	}
+	_, err := st.Exec(context.Background(), map[string]interface{}{"command": "sleep 30", "background": true})
+	if err == nil || !strings.Contains(err.Error(), "foreground") {
+		t.Errorf("expected a hint to run in the foreground, got %v", err)
+	}
+}
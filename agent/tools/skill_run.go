@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/smallnest/goclaw/config"
+	"go.uber.org/zap"
+)
+
+// skillRunOutputLimit 每个输出流最多保留的字节数
+const skillRunOutputLimit = 64 * 1024
+
+// SkillEntrypointSpec 解析后的技能入口，路径均为绝对路径
+type SkillEntrypointSpec struct {
+	Skill      string
+	Entrypoint string
+	Dir        string        // 技能目录，作为工作目录只读挂载
+	Command    []string      // 完整 argv
+	Network    bool          // 是否允许访问网络
+	HostFS     bool          // 声明不限制文件系统访问，本地执行器只运行这样的入口
+	Read       []string      // 只读路径
+	Write      []string      // 可写路径
+	Env        []string      // 允许透传的环境变量名
+	Timeout    time.Duration // 运行超时
+}
+
+// SkillEntrypointResolver 技能入口查询接口
+type SkillEntrypointResolver interface {
+	// ResolveEntrypoint 查找技能入口并展开其中的路径，声明无效时返回错误
+	ResolveEntrypoint(skill, entrypoint string) (*SkillEntrypointSpec, error)
+}
+
+// skillRunResult skill_run 的返回结果
+type skillRunResult struct {
+	Skill      string  `json:"skill"`
+	Entrypoint string  `json:"entrypoint"`
+	Runner     string  `json:"runner"` // docker, local
+	ExitCode   int     `json:"exit_code"`
+	Stdout     string  `json:"stdout"`
+	Stderr     string  `json:"stderr"`
+	Truncated  bool    `json:"truncated,omitempty"`
+	Seconds    float64 `json:"elapsed_seconds"`
+}
+
+// SkillRunTool 以技能声明的权限运行技能入口
+// 启用 tools.shell.sandbox 时在一次性 Docker 容器中运行：只挂载技能目录和声明的路径，
+// 未声明网络时不联网，只传入声明的环境变量。
+// 否则使用受限的本地执行器：清空环境变量、独立的临时 HOME、超时后杀死进程组，
+// 未声明网络时通过 unshare 隔离网络。本地执行器无法限制文件系统访问，
+// 只运行声明了 unrestricted_filesystem 的入口。
+type SkillRunTool struct {
+	resolver      SkillEntrypointResolver
+	sandboxConfig config.SandboxConfig
+	dockerClient  *client.Client
+}
+
+// NewSkillRunTool 创建技能入口执行工具
+func NewSkillRunTool(resolver SkillEntrypointResolver, sandboxConfig config.SandboxConfig) *SkillRunTool {
+	t := &SkillRunTool{resolver: resolver, sandboxConfig: sandboxConfig}
+	if sandboxConfig.Enabled {
+		if cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation()); err == nil {
+			t.dockerClient = cli
+		} else {
+			zap.L().Warn("Failed to initialize Docker client, skill entrypoints run locally", zap.Error(err))
+			t.sandboxConfig.Enabled = false
+		}
+	}
+	return t
+}
+
+// Name 返回工具名称
+func (t *SkillRunTool) Name() string {
+	return "skill_run"
+}
+
+// Description 返回工具描述
+func (t *SkillRunTool) Description() string {
+	var desc strings.Builder
+	desc.WriteString("Run an executable entrypoint declared by a skill in its SKILL.md frontmatter. ")
+	if t.sandboxConfig.Enabled {
+		desc.WriteString("It runs in a one-shot Docker sandbox that only sees the skill directory and the paths, network and environment variables the entrypoint declares. ")
+	} else {
+		desc.WriteString("It runs on the host with a cleared environment, limited to the network access and environment variables the entrypoint declares; only entrypoints that declare unrestricted filesystem access can run this way. ")
+	}
+	desc.WriteString("Load the skill with use_skill first to learn its entrypoints and arguments.")
+	return desc.String()
+}
+
+// Parameters 返回工具参数定义
+func (t *SkillRunTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"skill": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the skill",
+			},
+			"entrypoint": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the entrypoint declared by the skill",
+			},
+			"args": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Extra arguments appended to the entrypoint command",
+			},
+		},
+		"required": []string{"skill", "entrypoint"},
+	}
+}
+
+// Execute 执行工具
+func (t *SkillRunTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	skill, _ := params["skill"].(string)
+	entrypoint, _ := params["entrypoint"].(string)
+	if skill == "" || entrypoint == "" {
+		return "", fmt.Errorf("skill and entrypoint parameters are required")
+	}
+	spec, err := t.resolver.ResolveEntrypoint(skill, entrypoint)
+	if err != nil {
+		return "", err
+	}
+
+	argv := append([]string(nil), spec.Command...)
+	if raw, ok := params["args"].([]interface{}); ok {
+		for _, v := range raw {
+			arg, ok := v.(string)
+			if !ok {
+				return "", fmt.Errorf("args must be strings")
+			}
+			argv = append(argv, arg)
+		}
+	}
+
+	result, err := t.Run(ctx, spec, argv)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Run 按入口声明的权限运行 argv，非零退出码不视为错误
+func (t *SkillRunTool) Run(ctx context.Context, spec *SkillEntrypointSpec, argv []string) (*skillRunResult, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("entrypoint %s of skill %s has no command", spec.Entrypoint, spec.Skill)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, spec.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	var (
+		result *skillRunResult
+		err    error
+	)
+	if t.sandboxConfig.Enabled && t.dockerClient != nil {
+		result, err = t.runDocker(runCtx, spec, argv)
+	} else {
+		result, err = t.runLocal(runCtx, spec, argv)
+	}
+	if err != nil {
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, fmt.Errorf("entrypoint %s of skill %s timed out after %v", spec.Entrypoint, spec.Skill, spec.Timeout)
+		}
+		return nil, err
+	}
+	result.Skill = spec.Skill
+	result.Entrypoint = spec.Entrypoint
+	result.Seconds = time.Since(start).Seconds()
+	return result, nil
+}
+
+// skillEnv returns KEY=value pairs for the declared variables that are set
+func skillEnv(names []string) []string {
+	var env []string
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// runDocker runs argv in a one-shot container that only mounts what the
+// entrypoint declares, at the same paths as on the host
+func (t *SkillRunTool) runDocker(ctx context.Context, spec *SkillEntrypointSpec, argv []string) (*skillRunResult, error) {
+	hostConfig, err := BuildSkillHostConfig(t.sandboxConfig, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.dockerClient.ContainerCreate(ctx, &container.Config{
+		Image:      t.sandboxConfig.Image,
+		Cmd:        argv,
+		WorkingDir: spec.Dir,
+		Env:        append(skillEnv(spec.Env), "HOME=/tmp"),
+		Tty:        false,
+		Labels:     map[string]string{SandboxLabel: "true"},
+	}, hostConfig, nil, nil, fmt.Sprintf("goclaw-skill-%d", time.Now().UnixNano()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	// 运行上下文可能已超时，用独立的 context 清理容器
+	defer func() {
+		_ = t.dockerClient.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := t.dockerClient.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	result := &skillRunResult{Runner: "docker"}
+	statusCh, errCh := t.dockerClient.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return nil, fmt.Errorf("container wait error: %w", err)
+	case status := <-statusCh:
+		result.ExitCode = int(status.StatusCode)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	out, err := t.dockerClient.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer out.Close()
+
+	stdout := &limitedBuffer{limit: skillRunOutputLimit}
+	stderr := &limitedBuffer{limit: skillRunOutputLimit}
+	if _, err := stdcopy.StdCopy(stdout, stderr, out); err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+	return result, nil
+}
+
+// BuildSkillHostConfig 构造技能入口容器的挂载和资源限制
+// 技能目录只读挂载，声明的 read/write 路径分别只读/读写挂载，均保持宿主机上的路径；
+// 未声明网络时网络为 none，声明时使用沙箱配置的网络（配置为 none 时使用 bridge）。
+// 资源限制沿用沙箱配置，但不挂载工作区和沙箱配置的其他目录，也不使用特权模式。
+func BuildSkillHostConfig(cfg config.SandboxConfig, spec *SkillEntrypointSpec) (*container.HostConfig, error) {
+	binds := []string{spec.Dir + ":" + spec.Dir + ":ro"}
+	for _, path := range spec.Read {
+		binds = append(binds, path+":"+path+":ro")
+	}
+	for _, path := range spec.Write {
+		binds = append(binds, path+":"+path+":rw")
+	}
+
+	network := "none"
+	if spec.Network {
+		network = cfg.Network
+		if network == "" || network == "none" {
+			network = "bridge"
+		}
+	}
+
+	hc := &container.HostConfig{
+		Binds:          binds,
+		NetworkMode:    container.NetworkMode(network),
+		ReadonlyRootfs: cfg.ReadOnlyRoot,
+	}
+	if cfg.ReadOnlyRoot {
+		hc.Tmpfs = map[string]string{"/tmp": "rw,exec,size=256m"}
+	}
+	if err := applySandboxResources(hc, cfg); err != nil {
+		return nil, err
+	}
+	return hc, nil
+}
+
+// runLocal runs argv on the host with a cleared environment, a private HOME
+// and, unless the entrypoint declares network access, no network
+func (t *SkillRunTool) runLocal(ctx context.Context, spec *SkillEntrypointSpec, argv []string) (*skillRunResult, error) {
+	if !spec.HostFS {
+		return nil, fmt.Errorf("entrypoint %s of skill %s is limited to its declared paths, but the local runner cannot restrict filesystem access; enable tools.shell.sandbox to run it in Docker", spec.Entrypoint, spec.Skill)
+	}
+	if !spec.Network {
+		if !LocalNetworkIsolation() {
+			return nil, fmt.Errorf("entrypoint %s of skill %s does not declare network access, but network isolation is not available on this host; enable tools.shell.sandbox to run it in Docker", spec.Entrypoint, spec.Skill)
+		}
+		argv = append([]string{"unshare", "--net", "--map-root-user", "--"}, argv...)
+	}
+
+	home, err := os.MkdirTemp("", "goclaw-skill-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(home)
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = spec.Dir
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"TMPDIR=" + home,
+	}, skillEnv(spec.Env)...)
+	// 在独立进程组中运行，超时后杀死整个进程树
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	stdout := &limitedBuffer{limit: skillRunOutputLimit}
+	stderr := &limitedBuffer{limit: skillRunOutputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	result := &skillRunResult{Runner: "local"}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to run entrypoint: %w", err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+	return result, nil
+}
+
+var (
+	unshareOnce sync.Once
+	unshareOK   bool
+)
+
+// LocalNetworkIsolation 本地执行器能否在独立的网络命名空间中运行入口
+// 需要 util-linux 的 unshare 和非特权用户命名空间
+func LocalNetworkIsolation() bool {
+	unshareOnce.Do(func() {
+		if _, err := exec.LookPath("unshare"); err != nil {
+			return
+		}
+		unshareOK = exec.Command("unshare", "--net", "--map-root-user", "--", "true").Run() == nil
+	})
+	return unshareOK
+}
+
+// Close 关闭工具
+func (t *SkillRunTool) Close() error {
+	if t.dockerClient != nil {
+		return t.dockerClient.Close()
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestBuildSkillHostConfig(t *testing.T) {
+	spec := &SkillEntrypointSpec{
+		Dir:   "/skills/feeds",
+		Read:  []string{"/data/in"},
+		Write: []string{"/data/out"},
+	}
+	cfg := config.SandboxConfig{Network: "host", Memory: "256m", Privileged: true, Mounts: []string{"/etc:/etc"}}
+
+	hc, err := BuildSkillHostConfig(cfg, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/skills/feeds:/skills/feeds:ro", "/data/in:/data/in:ro", "/data/out:/data/out:rw"}
+	if strings.Join(hc.Binds, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected binds %v", hc.Binds)
+	}
+	if hc.NetworkMode != "none" || hc.Privileged {
+		t.Errorf("expected no network and no privileges, got %q privileged=%v", hc.NetworkMode, hc.Privileged)
+	}
+	if hc.Resources.Memory != 256*1024*1024 {
+		t.Errorf("expected the sandbox memory limit, got %d", hc.Resources.Memory)
+	}
+
+	spec.Network = true
+	if hc, _ = BuildSkillHostConfig(cfg, spec); hc.NetworkMode != "host" {
+		t.Errorf("expected the sandbox network, got %q", hc.NetworkMode)
+	}
+	cfg.Network = "none"
+	if hc, _ = BuildSkillHostConfig(cfg, spec); hc.NetworkMode != "bridge" {
+		t.Errorf("expected bridge when the sandbox has no network, got %q", hc.NetworkMode)
+	}
+}
+
+type staticResolver map[string]*SkillEntrypointSpec
+
+func (r staticResolver) ResolveEntrypoint(skill, entrypoint string) (*SkillEntrypointSpec, error) {
+	spec, ok := r[entrypoint]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return spec, nil
+}
+
+func TestSkillRunToolLocal(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"pwd=$(pwd) allowed=$ALLOWED secret=$SECRET args=$*\"\necho oops >&2\nexit 3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ALLOWED", "yes")
+	t.Setenv("SECRET", "leaked")
+
+	tool := NewSkillRunTool(staticResolver{
+		"run":   {Skill: "demo", Entrypoint: "run", Dir: dir, Command: []string{script}, Network: true, HostFS: true, Env: []string{"ALLOWED"}, Timeout: 10 * time.Second},
+		"sleep": {Skill: "demo", Entrypoint: "sleep", Dir: dir, Command: []string{"sleep", "10"}, Network: true, HostFS: true, Timeout: 200 * time.Millisecond},
+		"paths": {Skill: "demo", Entrypoint: "paths", Dir: dir, Command: []string{script}, Network: true, Read: []string{dir}, Timeout: 10 * time.Second},
+	}, config.SandboxConfig{})
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"skill":      "demo",
+		"entrypoint": "run",
+		"args":       []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result skillRunResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Runner != "local" || result.ExitCode != 3 || strings.TrimSpace(result.Stderr) != "oops" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if want := "pwd=" + dir + " allowed=yes secret= args=a b"; strings.TrimSpace(result.Stdout) != want {
+		t.Errorf("expected %q, got %q", want, result.Stdout)
+	}
+
+	// Declared paths cannot be enforced on the host
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"skill": "demo", "entrypoint": "paths"})
+	if err == nil || !strings.Contains(err.Error(), "tools.shell.sandbox") {
+		t.Errorf("expected an entrypoint limited to declared paths to be refused, got %v", err)
+	}
+
+	start := time.Now()
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"skill": "demo", "entrypoint": "sleep"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected the entrypoint to be killed at its timeout")
+	}
+}
+
+func TestSkillRunToolLocalWithoutNetwork(t *testing.T) {
+	tool := NewSkillRunTool(nil, config.SandboxConfig{})
+	spec := &SkillEntrypointSpec{Skill: "demo", Entrypoint: "offline", Dir: t.TempDir(), HostFS: true, Timeout: 10 * time.Second}
+
+	result, err := tool.Run(context.Background(), spec, []string{"echo", "isolated"})
+	if !LocalNetworkIsolation() {
+		if err == nil {
+			t.Fatal("expected an entrypoint without network to be refused when isolation is unavailable")
+		}
+		t.Skip("network namespaces are not available")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(result.Stdout) != "isolated" {
+		t.Errorf("unexpected output %q", result.Stdout)
+	}
+}
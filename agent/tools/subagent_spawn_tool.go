@@ -38,6 +38,7 @@ type SubagentRunParams struct {
 	Cleanup             string
 	Label               string
 	ArchiveAfterMinutes int
+	TimeoutSeconds      int
 }
 
 // SubagentSystemPromptParams 系统提示词参数
@@ -177,9 +178,12 @@ type SubagentSpawnTool struct {
 	getAgentConfig   func(agentID string) *config.AgentConfig
 	getDefaultConfig func() *config.AgentDefaults
 	getAgentID       func(sessionKey string) string
-	onSpawn          func(spawnParams *SubagentSpawnResult) error
+	onSpawn          func(ctx context.Context, spawnParams *SubagentSpawnResult) error
 }
 
+// defaultSubagentTimeoutSeconds 未配置时分身运行的超时时间
+const defaultSubagentTimeoutSeconds = 600
+
 // NewSubagentSpawnTool 创建分身生成工具
 func NewSubagentSpawnTool(registry SubagentRegistryInterface) *SubagentSpawnTool {
 	return &SubagentSpawnTool{
@@ -203,7 +207,8 @@ func (t *SubagentSpawnTool) SetAgentIDGetter(getter func(sessionKey string) stri
 }
 
 // SetOnSpawn 设置分身生成回调
-func (t *SubagentSpawnTool) SetOnSpawn(fn func(spawnParams *SubagentSpawnResult) error) {
+// ctx 是工具调用的上下文，回调可以据此将父运行的取消传递给分身
+func (t *SubagentSpawnTool) SetOnSpawn(fn func(ctx context.Context, spawnParams *SubagentSpawnResult) error) {
 	t.onSpawn = fn
 }
 
@@ -214,7 +219,8 @@ func (t *SubagentSpawnTool) Name() string {
 
 // Description 返回工具描述
 func (t *SubagentSpawnTool) Description() string {
-	return "Spawn a background sub-agent run in an isolated session and announce the result back to the requester chat."
+	return "Spawn a background sub-agent run in an isolated session and announce the result back to the requester chat. " +
+		"Spawn several to fan out work, then use subagent_wait to collect their results within the same turn."
 }
 
 // Parameters 返回工具参数定义
@@ -284,10 +290,8 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 
 	// Get requester session info from context
 	requesterSessionKey := "main" // default
-	if sk := ctx.Value("session_key"); sk != nil {
-		if key, ok := sk.(string); ok {
-			requesterSessionKey = key
-		}
+	if key := SessionKeyFromContext(ctx); key != "" {
+		requesterSessionKey = key
 	}
 	requesterAgentID := t.getAgentID(requesterSessionKey)
 	if requesterAgentID == "" {
@@ -331,7 +335,6 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 		Label:               spawnParams.Label,
 		Task:                spawnParams.Task,
 	})
-	_ = childSystemPrompt // TODO: 传递给分身实例使用
 
 	// 获取归档时间
 	archiveAfterMinutes := 60 // 默认值
@@ -341,6 +344,8 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 		}
 	}
 
+	timeoutSeconds := t.resolveTimeout(spawnParams.RunTimeoutSeconds, targetAgentID)
+
 	// 注册分身运行
 	if err := t.registry.RegisterRun(&SubagentRunParams{
 		RunID:               runID,
@@ -352,6 +357,7 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 		Cleanup:             spawnParams.Cleanup,
 		Label:               spawnParams.Label,
 		ArchiveAfterMinutes: archiveAfterMinutes,
+		TimeoutSeconds:      timeoutSeconds,
 	}); err != nil {
 		result := &SubagentSpawnResult{
 			Status: "error",
@@ -368,7 +374,7 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 			RunID:             runID,
 			ChildSystemPrompt: childSystemPrompt,
 		}
-		if err := t.onSpawn(spawnResult); err != nil {
+		if err := t.onSpawn(ctx, spawnResult); err != nil {
 			logger.Error("Failed to handle subagent spawn",
 				zap.String("run_id", runID),
 				zap.Error(err))
@@ -392,6 +398,24 @@ func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]inter
 	return t.marshalResult(result), nil
 }
 
+// resolveTimeout 确定分身运行超时：工具参数优先，其次是目标 Agent 和默认分身配置
+func (t *SubagentSpawnTool) resolveTimeout(requested int, targetAgentID string) int {
+	if requested > 0 {
+		return requested
+	}
+	if t.getAgentConfig != nil {
+		if agentCfg := t.getAgentConfig(targetAgentID); agentCfg != nil && agentCfg.Subagents != nil && agentCfg.Subagents.TimeoutSeconds > 0 {
+			return agentCfg.Subagents.TimeoutSeconds
+		}
+	}
+	if t.getDefaultConfig != nil {
+		if defCfg := t.getDefaultConfig(); defCfg != nil && defCfg.Subagents != nil && defCfg.Subagents.TimeoutSeconds > 0 {
+			return defCfg.Subagents.TimeoutSeconds
+		}
+	}
+	return defaultSubagentTimeoutSeconds
+}
+
 // parseParams 解析参数
 func (t *SubagentSpawnTool) parseParams(params map[string]interface{}) (*SubagentSpawnToolParams, error) {
 	result := &SubagentSpawnToolParams{
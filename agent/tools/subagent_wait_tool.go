@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SubagentRunStatus 分身运行状态
+type SubagentRunStatus struct {
+	RunID           string  `json:"run_id"`
+	ChildSessionKey string  `json:"child_session_key"`
+	Label           string  `json:"label,omitempty"`
+	Task            string  `json:"task"`
+	Status          string  `json:"status"` // running, ok, error, timeout, cancelled, unknown
+	Error           string  `json:"error,omitempty"`
+	Output          string  `json:"output,omitempty"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+	Delivered       bool    `json:"delivered,omitempty"` // 结果已由 subagent_wait 返回过
+}
+
+// SubagentRunTracker 分身运行查询接口
+// 只返回属于请求者会话的运行
+type SubagentRunTracker interface {
+	// ListRuns 列出请求者会话的所有分身运行
+	ListRuns(requesterSessionKey string) []SubagentRunStatus
+	// WaitRuns 等待分身运行结束，any 为 true 时任一结束即返回；ctx 结束时返回当前状态和 ctx 的错误
+	WaitRuns(ctx context.Context, requesterSessionKey string, runIDs []string, any bool) ([]SubagentRunStatus, error)
+}
+
+// 等待时间的默认值和上限
+const (
+	defaultSubagentWaitSeconds = 300
+	maxSubagentWaitSeconds     = 3600
+)
+
+// SubagentWaitTool 等待分身运行结束并收集结果
+type SubagentWaitTool struct {
+	tracker SubagentRunTracker
+}
+
+// NewSubagentWaitTool 创建分身等待工具
+func NewSubagentWaitTool(tracker SubagentRunTracker) *SubagentWaitTool {
+	return &SubagentWaitTool{tracker: tracker}
+}
+
+// Name 返回工具名称
+func (t *SubagentWaitTool) Name() string {
+	return "subagent_wait"
+}
+
+// Description 返回工具描述
+func (t *SubagentWaitTool) Description() string {
+	return "Wait for sub-agent runs started with sessions_spawn and collect their results. " +
+		"Without run_ids it collects every sub-agent of this session whose result has not been returned yet. " +
+		"Results returned here are not announced again."
+}
+
+// Parameters 返回工具参数定义
+func (t *SubagentWaitTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"run_ids": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Run IDs to wait for. Defaults to all sub-agents of this session that are running or whose results have not been collected.",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"description": "'all' waits until every run has finished, 'any' returns as soon as one has.",
+				"enum":        []string{"all", "any"},
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum time to wait (default %d, max %d). Runs keep going after the wait times out.", defaultSubagentWaitSeconds, maxSubagentWaitSeconds),
+			},
+		},
+	}
+}
+
+// ExecutionTimeout 返回本次等待的时长，编排器据此放宽工具超时，让等待超时时返回 timed_out 结果
+func (t *SubagentWaitTool) ExecutionTimeout(params map[string]interface{}) time.Duration {
+	timeout := defaultSubagentWaitSeconds
+	switch v := params["timeout_seconds"].(type) {
+	case float64:
+		timeout = int(v)
+	case int:
+		timeout = v
+	}
+	if timeout <= 0 {
+		timeout = defaultSubagentWaitSeconds
+	} else if timeout > maxSubagentWaitSeconds {
+		timeout = maxSubagentWaitSeconds
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// Execute 执行工具
+func (t *SubagentWaitTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	requester := SessionKeyFromContext(ctx)
+	if requester == "" {
+		requester = "main"
+	}
+
+	mode, _ := params["mode"].(string)
+	if mode == "" {
+		mode = "all"
+	}
+	if mode != "all" && mode != "any" {
+		return "", fmt.Errorf("invalid mode: %s", mode)
+	}
+
+	var runIDs []string
+	if raw, ok := params["run_ids"].([]interface{}); ok {
+		for _, v := range raw {
+			if id, ok := v.(string); ok && id != "" {
+				runIDs = append(runIDs, id)
+			}
+		}
+	}
+	if len(runIDs) == 0 {
+		for _, run := range t.tracker.ListRuns(requester) {
+			if !run.Delivered {
+				runIDs = append(runIDs, run.RunID)
+			}
+		}
+		if len(runIDs) == 0 {
+			return marshalSubagentResult(map[string]interface{}{
+				"completed": []SubagentRunStatus{},
+				"pending":   []SubagentRunStatus{},
+				"message":   "no uncollected sub-agents in this session",
+			})
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, t.ExecutionTimeout(params))
+	defer cancel()
+	runs, err := t.tracker.WaitRuns(waitCtx, requester, runIDs, mode == "any")
+	timedOut := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+	if err != nil && !timedOut {
+		return "", err
+	}
+
+	completed := []SubagentRunStatus{}
+	pending := []SubagentRunStatus{}
+	found := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		found[run.RunID] = true
+		if run.Status == "running" {
+			pending = append(pending, run)
+		} else {
+			completed = append(completed, run)
+		}
+	}
+	result := map[string]interface{}{
+		"completed": completed,
+		"pending":   pending,
+		"timed_out": timedOut,
+	}
+
+	var unknown []string
+	for _, id := range runIDs {
+		if !found[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		result["unknown_run_ids"] = unknown
+	}
+	return marshalSubagentResult(result)
+}
+
+// SubagentStatusTool 查询当前会话的分身运行状态
+type SubagentStatusTool struct {
+	tracker SubagentRunTracker
+}
+
+// NewSubagentStatusTool 创建分身状态工具
+func NewSubagentStatusTool(tracker SubagentRunTracker) *SubagentStatusTool {
+	return &SubagentStatusTool{tracker: tracker}
+}
+
+// Name 返回工具名称
+func (t *SubagentStatusTool) Name() string {
+	return "subagent_status"
+}
+
+// Description 返回工具描述
+func (t *SubagentStatusTool) Description() string {
+	return "Show the status of sub-agent runs spawned by this session, including the output of finished runs."
+}
+
+// Parameters 返回工具参数定义
+func (t *SubagentStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"run_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional run ID. Without it every run of this session is listed.",
+			},
+		},
+	}
+}
+
+// Execute 执行工具
+func (t *SubagentStatusTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	requester := SessionKeyFromContext(ctx)
+	if requester == "" {
+		requester = "main"
+	}
+
+	runs := t.tracker.ListRuns(requester)
+	if id, _ := params["run_id"].(string); id != "" {
+		for _, run := range runs {
+			if run.RunID == id {
+				return marshalSubagentResult(run)
+			}
+		}
+		return "", fmt.Errorf("subagent run not found: %s", id)
+	}
+
+	running := 0
+	for _, run := range runs {
+		if run.Status == "running" {
+			running++
+		}
+	}
+	return marshalSubagentResult(map[string]interface{}{
+		"runs":    runs,
+		"running": running,
+	})
+}
+
+// marshalSubagentResult 序列化结果为 JSON 字符串
+func marshalSubagentResult(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
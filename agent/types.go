@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/smallnest/goclaw/agent/hooks"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
 )
@@ -56,7 +57,9 @@ func (t ToolCallContent) ContentType() string {
 
 // ThinkingContent represents thinking/reasoning content
 type ThinkingContent struct {
-	Thinking string `json:"thinking"`
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature,omitempty"` // Provider signature, required when sending the block back
+	Redacted  string `json:"redacted,omitempty"`  // Encrypted thinking content
 }
 
 func (t ThinkingContent) ContentType() string {
@@ -94,6 +97,13 @@ type Tool interface {
 	Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error)
 }
 
+// TimedTool is implemented by tools that enforce their own time limit
+// The orchestrator extends its per-tool timeout so such a tool can return its own result
+type TimedTool interface {
+	// ExecutionTimeout returns the longest the tool runs with the given parameters (0 keeps the default)
+	ExecutionTimeout(params map[string]any) time.Duration
+}
+
 // MessageQueueMode defines how messages are delivered from queues
 type MessageQueueMode string
 
@@ -128,6 +138,7 @@ type AgentState struct {
 
 	// Skills support
 	LoadedSkills []string
+	Skills       []*Skill // Snapshot taken when the run started, kept for the whole run
 }
 
 // EventType represents types of events emitted by the agent
@@ -149,6 +160,12 @@ const (
 	EventStreamThinking EventType = "stream_thinking"
 	EventStreamFinal    EventType = "stream_final"
 	EventStreamDone     EventType = "stream_done"
+	// EventStreamToolCall marks that the streamed message calls a tool; text
+	// streamed before it is narration rather than the reply
+	EventStreamToolCall EventType = "stream_tool_call"
+
+	// EventThinking carries the complete thinking of a non-streaming response
+	EventThinking EventType = "thinking"
 )
 
 // Event represents an event from the agent
@@ -169,6 +186,8 @@ type Event struct {
 	AssistantMessageEvent interface{} `json:"assistant_message_event,omitempty"`
 	// Streaming fields
 	StreamContent string `json:"stream_content,omitempty"`
+	// Thinking fields
+	Thinking string `json:"thinking,omitempty"`
 }
 
 // LoopConfig contains configuration for the agent loop
@@ -190,8 +209,15 @@ type LoopConfig struct {
 
 	// Skills support
 	Skills         []*Skill
+	SkillsSource   func() []*Skill // Current skills for new runs (hot reload); Skills is used when nil
 	LoadedSkills   []string
 	ContextBuilder *ContextBuilder
+
+	// Extra system content appended after the system prompt of every LLM call (e.g. subagent instructions)
+	ExtraSystemPrompt string
+
+	// Lifecycle hooks (pre_llm, post_llm, pre_tool, post_tool)
+	Hooks *hooks.Registry
 }
 
 // NewAgentState creates a new agent state
@@ -350,6 +376,7 @@ func (s *AgentState) Clone() *AgentState {
 		FollowUpMode:  s.FollowUpMode,
 		SessionKey:    s.SessionKey,
 		LoadedSkills:  loadedSkills,
+		Skills:        append([]*Skill(nil), s.Skills...),
 	}
 }
 
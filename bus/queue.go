@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +41,7 @@ func (b *MessageBus) PublishInbound(ctx context.Context, msg *InboundMessage) er
 	defer b.mu.RUnlock()
 
 	if b.closed {
+		telemetry.RecordChannelMessage(msg.Channel, "inbound", telemetry.ResultDropped)
 		return ErrBusClosed
 	}
 
@@ -53,8 +55,10 @@ func (b *MessageBus) PublishInbound(ctx context.Context, msg *InboundMessage) er
 
 	select {
 	case b.inbound <- msg:
+		telemetry.RecordChannelMessage(msg.Channel, "inbound", telemetry.ResultOK)
 		return nil
 	case <-ctx.Done():
+		telemetry.RecordChannelMessage(msg.Channel, "inbound", telemetry.ResultError)
 		return ctx.Err()
 	}
 }
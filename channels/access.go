@@ -0,0 +1,163 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/pairing"
+	"go.uber.org/zap"
+)
+
+// MetadataDirect 入站消息元数据中标记私聊的键，各通道在调用 PublishInbound 前设置
+// true 按 DM 策略检查，false 按群聊策略检查，未标记的消息（如系统事件）不受两者影响
+const MetadataDirect = "is_direct"
+
+// IsDirectMessage 入站消息是否来自私聊
+func IsDirectMessage(msg *bus.InboundMessage) bool {
+	direct, _ := msg.Metadata[MetadataDirect].(bool)
+	return direct
+}
+
+// IsGroupMessage 入站消息是否来自群聊
+func IsGroupMessage(msg *bus.InboundMessage) bool {
+	direct, ok := msg.Metadata[MetadataDirect].(bool)
+	return ok && !direct
+}
+
+// resolveDMPolicy returns the effective policy of a channel account
+// Without dm_policy the legacy behaviour applies: allowed_ids acts as an allowlist, otherwise DMs are open
+func resolveDMPolicy(name string, cfg BaseChannelConfig) pairing.DMPolicy {
+	policy, err := pairing.ParseDMPolicy(cfg.DMPolicy)
+	if err != nil {
+		logger.Warn("Invalid dm_policy, blocking direct messages",
+			zap.String("channel", name),
+			zap.Error(err))
+		return pairing.DMPolicyDisabled
+	}
+	if policy == "" {
+		if len(cfg.AllowedIDs) > 0 {
+			return pairing.DMPolicyAllowlist
+		}
+		return pairing.DMPolicyOpen
+	}
+	return policy
+}
+
+// DMPolicy 返回通道账号生效的私聊策略
+func (c *BaseChannelImpl) DMPolicy() pairing.DMPolicy {
+	return c.dmPolicy
+}
+
+// allowlisted reports whether the sender is in allowed_ids or was approved via pairing
+func (c *BaseChannelImpl) allowlisted(senderID string) bool {
+	if slices.Contains(c.config.AllowedIDs, senderID) {
+		return true
+	}
+	store := c.pairingStore()
+	return store != nil && store.IsAllowed(senderID)
+}
+
+// pairingStore opens the pairing store of this channel account on first use
+func (c *BaseChannelImpl) pairingStore() *pairing.PairingStore {
+	c.pairingOnce.Do(func() {
+		accountID := c.accountID
+		if accountID == "default" {
+			accountID = ""
+		}
+		store, err := pairing.NewPairingStore(pairing.Config{
+			Channel:   c.name,
+			AccountID: accountID,
+			DataDir:   c.pairingDir,
+		})
+		if err != nil {
+			logger.Warn("Failed to open pairing store",
+				zap.String("channel", c.name),
+				zap.Error(err))
+			return
+		}
+		c.pairing = store
+	})
+	return c.pairing
+}
+
+// admitDirect applies the DM policy to a direct message, replying with a
+// pairing code to unknown senders under the pairing policy
+func (c *BaseChannelImpl) admitDirect(ctx context.Context, msg *bus.InboundMessage) bool {
+	switch c.dmPolicy {
+	case pairing.DMPolicyOpen:
+		return true
+	case pairing.DMPolicyAllowlist, pairing.DMPolicyPairing:
+		if c.allowlisted(msg.SenderID) {
+			return true
+		}
+	}
+
+	logger.Info("Direct message blocked by dm_policy",
+		zap.String("channel", c.name),
+		zap.String("account_id", c.accountID),
+		zap.String("policy", string(c.dmPolicy)),
+		zap.String("sender_id", msg.SenderID))
+	if c.dmPolicy == pairing.DMPolicyPairing && msg.SenderID != "" {
+		c.requestPairing(ctx, msg)
+	}
+	return false
+}
+
+// requestPairing creates a pairing request and sends the code back to the sender once
+func (c *BaseChannelImpl) requestPairing(ctx context.Context, msg *bus.InboundMessage) {
+	store := c.pairingStore()
+	if store == nil {
+		return
+	}
+	code, created, err := store.UpsertRequest(msg.SenderID, senderName(msg))
+	if err != nil {
+		logger.Warn("Failed to create pairing request",
+			zap.String("channel", c.name),
+			zap.String("sender_id", msg.SenderID),
+			zap.Error(err))
+		return
+	}
+	if !created {
+		return
+	}
+
+	idLine := fmt.Sprintf("Your %s user id: %s", c.name, msg.SenderID)
+	reply := pairing.BuildPairingReply(c.name, idLine, code)
+	if c.accountID != "" && c.accountID != "default" {
+		reply += " --account " + c.accountID
+	}
+	// Replies go through the manager so they reach the account that received the message
+	err = c.bus.PublishOutbound(ctx, &bus.OutboundMessage{
+		Channel:   buildChannelName(c.name, c.accountID),
+		ChatID:    msg.ChatID,
+		Content:   reply,
+		ReplyTo:   msg.ID,
+		Metadata:  map[string]interface{}{"pairing": true},
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logger.Warn("Failed to send pairing code",
+			zap.String("channel", c.name),
+			zap.String("sender_id", msg.SenderID),
+			zap.Error(err))
+		return
+	}
+	logger.Info("Sent pairing code",
+		zap.String("channel", c.name),
+		zap.String("sender_id", msg.SenderID),
+		zap.String("code", code))
+}
+
+// senderName returns the display name that channels put in the metadata
+func senderName(msg *bus.InboundMessage) string {
+	for _, key := range []string{"sender_name", "from_name", "user_real_name", "user_name", "author", "from_user"} {
+		if name, ok := msg.Metadata[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/pairing"
+)
+
+func newTestChannel(t *testing.T, accountID string, cfg BaseChannelConfig) (*BaseChannelImpl, *bus.MessageBus) {
+	t.Helper()
+	cfg.Enabled = true
+	messageBus := bus.NewMessageBus(10)
+	c := NewBaseChannelImpl("telegram", accountID, cfg, messageBus)
+	c.pairingDir = t.TempDir()
+	return c, messageBus
+}
+
+func directMessage(id, sender string) *bus.InboundMessage {
+	return &bus.InboundMessage{
+		ID:       id,
+		SenderID: sender,
+		ChatID:   "chat-" + sender,
+		Content:  "hello",
+		Metadata: map[string]interface{}{MetadataDirect: true, "from_name": "Alice"},
+	}
+}
+
+func TestResolveDMPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  BaseChannelConfig
+		want pairing.DMPolicy
+	}{
+		{BaseChannelConfig{}, pairing.DMPolicyOpen},
+		{BaseChannelConfig{AllowedIDs: []string{"1"}}, pairing.DMPolicyAllowlist},
+		{BaseChannelConfig{DMPolicy: "pairing"}, pairing.DMPolicyPairing},
+		{BaseChannelConfig{DMPolicy: "closed"}, pairing.DMPolicyDisabled},
+		{BaseChannelConfig{DMPolicy: "friends"}, pairing.DMPolicyDisabled},
+	} {
+		if got := resolveDMPolicy("telegram", tc.cfg); got != tc.want {
+			t.Errorf("resolveDMPolicy(%+v) = %s, want %s", tc.cfg, got, tc.want)
+		}
+	}
+}
+
+func TestPairingPolicy(t *testing.T) {
+	c, messageBus := newTestChannel(t, "work", BaseChannelConfig{DMPolicy: "pairing"})
+	ctx := context.Background()
+
+	// Unknown senders are held back and receive a pairing code once
+	if err := c.PublishInbound(ctx, directMessage("m1", "42")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PublishInbound(ctx, directMessage("m2", "42")); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 0 {
+		t.Fatal("expected unpaired messages to be dropped")
+	}
+	outCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	reply, err := messageBus.ConsumeOutbound(outCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Channel != "telegram:work" || reply.ChatID != "chat-42" || !strings.Contains(reply.Content, "--account work") {
+		t.Fatalf("unexpected pairing reply %+v", reply)
+	}
+	if messageBus.OutboundCount() != 0 {
+		t.Error("expected a single pairing reply per request")
+	}
+
+	// Approval from another process (the CLI) is picked up without a restart
+	cli, err := pairing.NewPairingStore(pairing.Config{Channel: "telegram", AccountID: "work", DataDir: c.pairingDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := cli.ListPending()
+	if len(pending) != 1 || pending[0].Name != "Alice" {
+		t.Fatalf("unexpected pending requests %+v", pending)
+	}
+	if _, _, err := cli.Approve(pending[0].Code); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PublishInbound(ctx, directMessage("m3", "42")); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 1 {
+		t.Error("expected the approved sender to get through")
+	}
+
+	// Group messages are not subject to the DM policy
+	group := directMessage("m4", "7")
+	delete(group.Metadata, MetadataDirect)
+	if !c.IsAllowed("7") {
+		t.Error("expected IsAllowed to leave pairing to PublishInbound")
+	}
+	if err := c.PublishInbound(ctx, group); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 2 {
+		t.Error("expected the group message to be published")
+	}
+}
+
+func TestAllowlistAndDisabledPolicies(t *testing.T) {
+	// Legacy config: allowed_ids without dm_policy behaves as before
+	c, messageBus := newTestChannel(t, "default", BaseChannelConfig{AllowedIDs: []string{"1"}})
+	if !c.IsAllowed("1") || c.IsAllowed("2") {
+		t.Error("expected allowed_ids to act as an allowlist")
+	}
+	if err := c.PublishInbound(context.Background(), directMessage("m1", "2")); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 0 || messageBus.OutboundCount() != 0 {
+		t.Error("expected the allowlist to drop unknown senders without replying")
+	}
+
+	c, messageBus = newTestChannel(t, "default", BaseChannelConfig{DMPolicy: "disabled", AllowedIDs: []string{"1"}})
+	if err := c.PublishInbound(context.Background(), directMessage("m2", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 0 {
+		t.Error("expected a disabled policy to drop every direct message")
+	}
+}
+
+func TestAllowedIDsRestrictGroupSenders(t *testing.T) {
+	ctx := context.Background()
+	for _, policy := range []string{"pairing", "open"} {
+		// allowed_ids with an explicit non-allowlist dm_policy still restricts group senders
+		c, messageBus := newTestChannel(t, "default", BaseChannelConfig{DMPolicy: policy, AllowedIDs: []string{"1", "g2"}})
+		for _, msg := range []*bus.InboundMessage{
+			groupMessage("g1", "2", "hi", false),
+			groupMessage("g1", "1", "hi", false),
+			groupMessage("g2", "3", "hi", false),
+		} {
+			if err := c.PublishInbound(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if messageBus.InboundCount() != 2 {
+			t.Errorf("dm_policy %s: expected only allowed senders and groups, got %d messages", policy, messageBus.InboundCount())
+		}
+	}
+
+	// sender_policy open lets every group member through
+	c, messageBus := newTestChannel(t, "default", BaseChannelConfig{
+		DMPolicy:   "pairing",
+		AllowedIDs: []string{"1"},
+		Group:      config.GroupPolicyConfig{SenderPolicy: GroupSenderOpen},
+	})
+	if err := c.PublishInbound(ctx, groupMessage("g1", "2", "hi", false)); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 1 {
+		t.Error("expected sender_policy open to admit any group member")
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/pairing"
 )
 
 // BaseChannel 通道基础接口
@@ -38,6 +41,9 @@ type BaseChannelConfig struct {
 	AccountID  string   `mapstructure:"account_id" json:"account_id"` // 账号ID
 	Name       string   `mapstructure:"name" json:"name"`             // 账号显示名称
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	DMPolicy   string   `mapstructure:"dm_policy" json:"dm_policy"` // 私聊策略: open, pairing, allowlist, disabled
+	// 群聊策略
+	Group config.GroupPolicyConfig `mapstructure:"group" json:"group"`
 }
 
 // BaseChannelImpl 通道基础实现
@@ -48,17 +54,31 @@ type BaseChannelImpl struct {
 	bus       *bus.MessageBus
 	running   bool
 	stopChan  chan struct{}
+
+	dmPolicy    pairing.DMPolicy
+	pairingOnce sync.Once
+	pairing     *pairing.PairingStore
+	pairingDir  string // 配对数据目录，为空使用 ~/.goclaw/credentials
+
+	replyMode    string
+	groupSenders string
+	groupMu      sync.Mutex
+	groups       map[string]*groupChat
 }
 
 // NewBaseChannelImpl 创建通道基础实现
 func NewBaseChannelImpl(name, accountID string, config BaseChannelConfig, bus *bus.MessageBus) *BaseChannelImpl {
 	return &BaseChannelImpl{
-		name:      name,
-		accountID: accountID,
-		config:    config,
-		bus:       bus,
-		running:   false,
-		stopChan:  make(chan struct{}),
+		name:         name,
+		accountID:    accountID,
+		config:       config,
+		bus:          bus,
+		running:      false,
+		stopChan:     make(chan struct{}),
+		dmPolicy:     resolveDMPolicy(name, config),
+		replyMode:    resolveReplyMode(name, config.Group),
+		groups:       make(map[string]*groupChat),
+		groupSenders: resolveGroupSenderPolicy(name, config.Group),
 	}
 }
 
@@ -94,32 +114,41 @@ func (c *BaseChannelImpl) Stop() error {
 }
 
 // IsAllowed 检查发送者是否允许
+// allowlist 策略下只允许 allowed_ids 和已配对的发送者；其他策略下私聊由 PublishInbound 按 DM 策略检查，
+// 群聊按群聊策略检查（allowed_ids 默认仍然限制群成员）
 func (c *BaseChannelImpl) IsAllowed(senderID string) bool {
 	if !c.config.Enabled {
 		return false
 	}
 
-	// 如果没有限制列表，允许所有
-	if len(c.config.AllowedIDs) == 0 {
+	if c.dmPolicy != pairing.DMPolicyAllowlist {
 		return true
 	}
 
-	// 检查是否在允许列表中
-	for _, id := range c.config.AllowedIDs {
-		if id == senderID {
-			return true
-		}
-	}
-
-	return false
+	return c.allowlisted(senderID)
 }
 
 // PublishInbound 发布入站消息
+// 私聊消息按 DM 策略、群聊消息按群聊策略检查，被拒绝的消息不会发布，返回 nil
 func (c *BaseChannelImpl) PublishInbound(ctx context.Context, msg *bus.InboundMessage) error {
 	msg.Channel = c.name
+	if !c.admitInbound(ctx, msg) {
+		return nil
+	}
 	return c.bus.PublishInbound(ctx, msg)
 }
 
+// admitInbound applies the DM or group policy according to the is_direct marker
+func (c *BaseChannelImpl) admitInbound(ctx context.Context, msg *bus.InboundMessage) bool {
+	switch {
+	case IsDirectMessage(msg):
+		return c.admitDirect(ctx, msg)
+	case IsGroupMessage(msg):
+		return c.admitGroup(msg)
+	}
+	return true
+}
+
 // IsRunning 检查是否运行中
 func (c *BaseChannelImpl) IsRunning() bool {
 	return c.running
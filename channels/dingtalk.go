@@ -36,6 +36,8 @@ func NewDingTalkChannel(cfg config.DingTalkChannelConfig, bus *bus.MessageBus) (
 	baseCfg := BaseChannelConfig{
 		Enabled:    cfg.Enabled,
 		AllowedIDs: cfg.AllowedIDs,
+		DMPolicy:   cfg.DMPolicy,
+		Group:      cfg.Group,
 	}
 
 	return &DingTalkChannel{
@@ -207,6 +209,8 @@ func (c *DingTalkChannel) onChatBotMessageReceived(ctx context.Context, data *ch
 			"conversation_type": data.ConversationType,
 			"platform":          "dingtalk",
 			"session_webhook":   data.SessionWebhook,
+			"is_direct":         data.ConversationType == "1",
+			"mentioned":         data.IsInAtList,
 		},
 	}
 
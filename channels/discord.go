@@ -153,8 +153,11 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		}
 	}
 
+	mentioned, content := c.detectMention(s, m, content)
+
 	// 构建入站消息
 	msg := &bus.InboundMessage{
+		ID:       m.ID,
 		Channel:  c.Name(),
 		SenderID: senderID,
 		ChatID:   m.ChannelID,
@@ -166,6 +169,8 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 			"author":           m.Author.Username,
 			"discriminator":    m.Author.Discriminator,
 			"mention_everyone": m.MentionEveryone,
+			"is_direct":        m.GuildID == "",
+			"mentioned":        mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -175,6 +180,20 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	}
 }
 
+// detectMention 检查消息是否 @ 了机器人，并去掉 @ 机器人的文本
+func (c *DiscordChannel) detectMention(s *discordgo.Session, m *discordgo.MessageCreate, content string) (bool, string) {
+	if s.State == nil || s.State.User == nil {
+		return false, content
+	}
+	botID := s.State.User.ID
+	for _, user := range m.Mentions {
+		if user.ID == botID {
+			return true, stripMention(content, "<@"+botID+">", "<@!"+botID+">")
+		}
+	}
+	return false, content
+}
+
 // handleCommand 处理命令
 func (c *DiscordChannel) handleCommand(ctx context.Context, m *discordgo.MessageCreate) {
 	command := m.Content
@@ -220,10 +239,10 @@ func (c *DiscordChannel) Send(msg *bus.OutboundMessage) error {
 		Content: msg.Content,
 	}
 
-	// 处理回复
-	if msg.ReplyTo != "" {
+	// 处理回复，只有 thread 模式的群聊回复原消息
+	if replyTo := c.threadReplyTo(msg); replyTo != "" {
 		discordMsg.Reference = &discordgo.MessageReference{
-			MessageID: msg.ReplyTo,
+			MessageID: replyTo,
 		}
 	}
 
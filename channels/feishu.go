@@ -33,7 +33,6 @@ type FeishuChannel struct {
 	domain            string
 	encryptKey        string
 	verificationToken string
-	dmPolicy          string // DM policy: open, pairing, allowlist, closed
 	wsClient          *larkws.Client
 	eventDispatcher   *dispatcher.EventDispatcher
 	httpClient        *lark.Client
@@ -41,9 +40,7 @@ type FeishuChannel struct {
 	typingReactions   map[string]string
 	typingReactionsMu sync.RWMutex
 	// bot open_id for mention checking
-	botOpenId string
-	// pairing store for DM access control
-	pairingStore    *pairing.PairingStore
+	botOpenId        string
 	cronOutputChatID string // cron output target chat ID
 }
 
@@ -61,29 +58,24 @@ func NewFeishuChannel(cfg config.FeishuChannelConfig, bus *bus.MessageBus) (*Fei
 		lark.WithOpenBaseUrl(resolveDomain(cfg.Domain)),
 	)
 
-	baseCfg := BaseChannelConfig{
-		Enabled:    cfg.Enabled,
-		AllowedIDs: cfg.AllowedIDs,
-	}
-
-	// Resolve DM policy (default to "pairing" for security)
+	// 飞书私聊默认使用 pairing 策略
 	dmPolicy := cfg.DMPolicy
 	if dmPolicy == "" {
-		dmPolicy = "pairing"
+		dmPolicy = string(pairing.DMPolicyPairing)
 	}
 
-	// Create pairing store if policy is "pairing"
-	var pairingStore *pairing.PairingStore
-	if dmPolicy == "pairing" {
-		var err error
-		pairingStore, err = pairing.NewPairingStore(pairing.Config{
-			Channel:   "feishu",
-			AccountID: "", // default account
-		})
-		if err != nil {
-			logger.Warn("Failed to create pairing store, pairing disabled", zap.Error(err))
-			dmPolicy = "allowlist" // fallback to allowlist mode
-		}
+	// 飞书群聊默认只响应 @ 机器人的消息
+	group := cfg.Group
+	if group.RequireMention == nil {
+		requireMention := true
+		group.RequireMention = &requireMention
+	}
+
+	baseCfg := BaseChannelConfig{
+		Enabled:    cfg.Enabled,
+		AllowedIDs: cfg.AllowedIDs,
+		DMPolicy:   dmPolicy,
+		Group:      group,
 	}
 
 	return &FeishuChannel{
@@ -93,11 +85,9 @@ func NewFeishuChannel(cfg config.FeishuChannelConfig, bus *bus.MessageBus) (*Fei
 		domain:            cfg.Domain,
 		encryptKey:        cfg.EncryptKey,
 		verificationToken: cfg.VerificationToken,
-		dmPolicy:          dmPolicy,
 		httpClient:        client,
 		typingReactions:   make(map[string]string),
-		pairingStore:      pairingStore,
-		cronOutputChatID:   cfg.CronOutputChatID,
+		cronOutputChatID:  cfg.CronOutputChatID,
 	}, nil
 }
 
@@ -317,36 +307,20 @@ func (c *FeishuChannel) handleMessageReceived(ctx context.Context, event *larkim
 		zap.Int("mentions_count", len(event.Event.Message.Mentions)),
 	)
 
-	// 检查群聊消息是否 @ 了机器人
-	isGroupChat := chatType == "group"
-
-	// 对于私聊消息，检查 DM Policy 和配对状态
-	if !isGroupChat && senderID != "" {
-		allowed := c.checkDMPolicy(senderID)
-		if !allowed {
-			return
-		}
-	}
-
+	// 私聊的 DM Policy 和群聊的 @ 检查在发布前按通道策略统一处理
 	// 对于群聊消息，检查是否在 allowed_ids 中（如果配置了）
-	if isGroupChat {
-		if senderID != "" && !c.IsAllowed(senderID) {
-			return
-		}
-	}
-
-	if isGroupChat {
-		if c.botOpenId == "" {
-			return
-		}
-		mentionedBot := c.checkBotMentioned(event.Event.Message)
-		if !mentionedBot {
+	if chatType == "group" {
+		if senderID != "" && len(c.config.AllowedIDs) > 0 && !c.allowlisted(senderID) {
 			return
 		}
 	}
 
-	// 解析消息内容和媒体
+	// 解析消息内容和媒体，去掉 @ 机器人的占位符
 	content, media := c.extractMessageContentAndMedia(event.Event.Message)
+	mentionKey, mentioned := c.checkBotMentioned(event.Event.Message)
+	if mentioned {
+		content = stripMention(content, mentionKey)
+	}
 	if content == "" && len(media) == 0 {
 		return
 	}
@@ -363,13 +337,6 @@ func (c *FeishuChannel) handleMessageReceived(ctx context.Context, event *larkim
 		timestamp = time.Now()
 	}
 
-	// 发布到消息总线前，先添加 typing indicator
-	// 使用 messageID 来匹配用户消息
-	if err := c.addTypingIndicator(messageID); err != nil {
-		logger.Debug("Failed to add typing indicator (non-critical)", zap.Error(err))
-	}
-
-	// 发布到消息总线
 	inbound := &bus.InboundMessage{
 		ID:        messageID,
 		Content:   content,
@@ -379,12 +346,26 @@ func (c *FeishuChannel) handleMessageReceived(ctx context.Context, event *larkim
 		AccountID: "default",
 		Timestamp: timestamp,
 		Metadata: map[string]interface{}{
-			"msg_type": getStringPtr(event.Event.Message.MessageType),
+			"msg_type":  getStringPtr(event.Event.Message.MessageType),
+			"is_direct": chatType == "p2p",
+			"mentioned": mentioned,
 		},
 		Media: media,
 	}
 
-	if err := c.PublishInbound(ctx, inbound); err != nil {
+	// 按私聊和群聊策略检查，被拒绝的消息不添加 typing indicator
+	if !c.admitInbound(ctx, inbound) {
+		return
+	}
+
+	// 发布到消息总线前，先添加 typing indicator
+	// 使用 messageID 来匹配用户消息
+	if err := c.addTypingIndicator(messageID); err != nil {
+		logger.Debug("Failed to add typing indicator (non-critical)", zap.Error(err))
+	}
+
+	// 发布到消息总线
+	if err := c.bus.PublishInbound(ctx, inbound); err != nil {
 		logger.Error("Failed to publish inbound message",
 			zap.String("message_id", messageID),
 			zap.Error(err))
@@ -485,8 +466,11 @@ func (c *FeishuChannel) extractMessageContentAndMedia(msg *larkim.EventMessage)
 	return "", nil
 }
 
-// checkBotMentioned 检查消息是否 @ 了机器人
-func (c *FeishuChannel) checkBotMentioned(msg *larkim.EventMessage) bool {
+// checkBotMentioned 检查消息是否 @ 了机器人，返回机器人在文本中的占位符（如 @_user_1）
+func (c *FeishuChannel) checkBotMentioned(msg *larkim.EventMessage) (string, bool) {
+	if c.botOpenId == "" {
+		return "", false
+	}
 	mentions := msg.Mentions
 
 	// 如果不 AT 任何机器人，就当废话
@@ -508,7 +492,7 @@ func (c *FeishuChannel) checkBotMentioned(msg *larkim.EventMessage) bool {
 
 		if mention.Id != nil && mention.Id.OpenId != nil {
 			if *mention.Id.OpenId == c.botOpenId {
-				return true
+				return getStringPtr(mention.Key), true
 			}
 		}
 	}
@@ -516,7 +500,7 @@ func (c *FeishuChannel) checkBotMentioned(msg *larkim.EventMessage) bool {
 	logger.Debug("Bot not mentioned in message",
 		zap.String("bot_open_id", c.botOpenId),
 		zap.Int("mentions_count", len(mentions)))
-	return false
+	return "", false
 }
 
 // Send 发送消息
@@ -846,135 +830,6 @@ func getStringPtr(s *string) string {
 	return *s
 }
 
-// checkDMPolicy 检查私聊消息的发送者是否允许根据 DM Policy
-// 返回 true 表示允许处理消息，false 表示拒绝
-func (c *FeishuChannel) checkDMPolicy(senderID string) bool {
-	// 首先检查配置的 allowed_ids（白名单优先）
-	if len(c.config.AllowedIDs) > 0 {
-		for _, id := range c.config.AllowedIDs {
-			if id == senderID {
-				return true
-			}
-		}
-	}
-
-	// 根据 dm_policy 决定
-	switch c.dmPolicy {
-	case "open":
-		// 允许所有发送者
-		return true
-
-	case "closed":
-		// 拒绝所有私聊消息
-		logger.Info("[Feishu] DM closed, blocking message",
-			zap.String("sender_id", senderID))
-		return false
-
-	case "allowlist":
-		// 只允许在 allowed_ids 中的发送者
-		// 上面已经检查过了，如果到这里说明不在列表中
-		logger.Info("[Feishu] Sender not in allowlist",
-			zap.String("sender_id", senderID))
-		return false
-
-	case "pairing":
-		// 检查配对状态
-		if c.pairingStore == nil {
-			// 配对存储不可用，回退到 allowlist 模式
-			logger.Info("[Feishu] Pairing store unavailable, blocking message",
-				zap.String("sender_id", senderID))
-			return false
-		}
-
-		// 检查是否已配对
-		if c.pairingStore.IsAllowed(senderID) {
-			return true
-		}
-
-		// 未配对，创建配对请求并发送配对码
-		logger.Info("[Feishu] Unpaired sender, creating pairing request",
-			zap.String("sender_id", senderID))
-
-		code, created, err := c.pairingStore.UpsertRequest(senderID, "")
-		if err != nil {
-			logger.Error("Failed to create pairing request",
-				zap.String("sender_id", senderID),
-				zap.Error(err))
-			return false
-		}
-
-		if created {
-			// 发送配对码消息
-			idLine := fmt.Sprintf("Your Feishu user id: %s", senderID)
-			replyMsg := pairing.BuildPairingReply("feishu", idLine, code)
-
-			// 发送私聊消息
-			if err := c.sendPrivateMessage(senderID, replyMsg); err != nil {
-				logger.Error("Failed to send pairing message",
-					zap.String("sender_id", senderID),
-					zap.Error(err))
-			} else {
-				logger.Info("[Feishu] Sent pairing message",
-					zap.String("sender_id", senderID),
-					zap.String("code", code))
-			}
-		} else {
-			logger.Info("[Feishu] Pairing request already exists",
-				zap.String("sender_id", senderID),
-				zap.String("code", code))
-		}
-
-		return false
-
-	default:
-		// 未知的 policy，默认拒绝
-		logger.Warn("[Feishu] Unknown dm_policy, blocking message",
-			zap.String("policy", c.dmPolicy),
-			zap.String("sender_id", senderID))
-		return false
-	}
-}
-
-// sendPrivateMessage 发送私聊消息
-func (c *FeishuChannel) sendPrivateMessage(userID, message string) error {
-	// 构建卡片消息
-	cardContent := fmt.Sprintf(`{
-		"schema": "2.0",
-		"config": {
-			"wide_screen_mode": true
-		},
-		"body": {
-			"elements": [
-				{
-					"tag": "markdown",
-					"content": %s
-				}
-			]
-		}
-	}`, jsonEscape(message))
-
-	req := larkim.NewCreateMessageReqBuilder().
-		ReceiveIdType(larkim.ReceiveIdTypeOpenId).
-		Body(larkim.NewCreateMessageReqBodyBuilder().
-			ReceiveId(userID).
-			MsgType(larkim.MsgTypeInteractive).
-			Content(cardContent).
-			Build()).
-		Build()
-
-	ctx := context.Background()
-	resp, err := c.httpClient.Im.Message.Create(ctx, req)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-
-	if !resp.Success() {
-		return fmt.Errorf("feishu api error: %d %s", resp.Code, resp.Msg)
-	}
-
-	return nil
-}
-
 // jsonEscape 转义 JSON 字符串
 func jsonEscape(s string) string {
 	b, _ := json.Marshal(s)
@@ -121,16 +121,20 @@ func (c *GoogleChatChannel) HandleWebhook(ctx context.Context, event *chat.Depre
 		return c.handleCommand(ctx, event)
 	}
 
+	mentioned, content := detectGoogleChatMention(event.Message)
+
 	// 构建入站消息
 	msg := &bus.InboundMessage{
 		Channel:  c.Name(),
 		SenderID: senderID,
 		ChatID:   event.Space.Name,
-		Content:  event.Message.Text,
+		Content:  content,
 		Metadata: map[string]interface{}{
 			"message_id": event.Message.Name,
 			"user_name":  event.User.DisplayName,
 			"space_name": event.Space.DisplayName,
+			"is_direct":  event.Space.Type == "DM" || event.Space.SpaceType == "DIRECT_MESSAGE" || event.Space.SingleUserBotDm,
+			"mentioned":  mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -138,6 +142,22 @@ func (c *GoogleChatChannel) HandleWebhook(ctx context.Context, event *chat.Depre
 	return c.PublishInbound(ctx, msg)
 }
 
+// detectGoogleChatMention 检查消息是否 @ 了机器人，@ 机器人时使用去掉 @ 的 argumentText
+func detectGoogleChatMention(message *chat.Message) (bool, string) {
+	for _, annotation := range message.Annotations {
+		if annotation.Type != "USER_MENTION" || annotation.UserMention == nil || annotation.UserMention.User == nil {
+			continue
+		}
+		if annotation.UserMention.User.Type == "BOT" {
+			if text := strings.TrimSpace(message.ArgumentText); text != "" {
+				return true, text
+			}
+			return true, message.Text
+		}
+	}
+	return false, message.Text
+}
+
 // handleCommand 处理命令
 func (c *GoogleChatChannel) handleCommand(ctx context.Context, event *chat.DeprecatedEvent) error {
 	command := event.Message.Text
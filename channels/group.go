@@ -0,0 +1,183 @@
+package channels
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// MetadataMentioned 入站群消息元数据中标记是否 @ 了机器人的键，由各通道的 mention 适配器设置
+	MetadataMentioned = "mentioned"
+	// MetadataGroupHistory 入站群消息元数据中附带的未 @ 历史消息条数
+	MetadataGroupHistory = "group_history"
+)
+
+// 群聊回复方式
+const (
+	// ReplyModeThread 回复原消息（Slack 中为话题回复）
+	ReplyModeThread = "thread"
+	// ReplyModeInline 直接发送到群里，不引用原消息
+	ReplyModeInline = "inline"
+)
+
+// 群成员限制
+const (
+	// GroupSenderAllowlist 配置了 allowed_ids 时只响应其中的成员或群
+	GroupSenderAllowlist = "allowlist"
+	// GroupSenderOpen 响应群里的所有成员，不受 allowed_ids 限制
+	GroupSenderOpen = "open"
+)
+
+// groupChat is the state kept for a group the channel has seen
+type groupChat struct {
+	history []groupHistoryEntry
+}
+
+// groupHistoryEntry is an unmentioned message kept as context for the next mention
+type groupHistoryEntry struct {
+	sender  string
+	content string
+}
+
+// IsMentioned 群消息是否 @ 了机器人
+func IsMentioned(msg *bus.InboundMessage) bool {
+	mentioned, _ := msg.Metadata[MetadataMentioned].(bool)
+	return mentioned
+}
+
+// resolveReplyMode returns the group reply mode, falling back to thread for unknown values
+func resolveReplyMode(name string, group config.GroupPolicyConfig) string {
+	switch group.ReplyMode {
+	case "", ReplyModeThread:
+		return ReplyModeThread
+	case ReplyModeInline:
+		return ReplyModeInline
+	}
+	logger.Warn("Invalid group reply_mode, replying in thread",
+		zap.String("channel", name),
+		zap.String("reply_mode", group.ReplyMode))
+	return ReplyModeThread
+}
+
+// resolveGroupSenderPolicy returns the group sender policy, falling back to allowlist for unknown values
+func resolveGroupSenderPolicy(name string, group config.GroupPolicyConfig) string {
+	switch group.SenderPolicy {
+	case "", GroupSenderAllowlist:
+		return GroupSenderAllowlist
+	case GroupSenderOpen:
+		return GroupSenderOpen
+	}
+	logger.Warn("Invalid group sender_policy, restricting senders to allowed_ids",
+		zap.String("channel", name),
+		zap.String("sender_policy", group.SenderPolicy))
+	return GroupSenderAllowlist
+}
+
+// groupSenderAllowed reports whether a group message passes allowed_ids: the
+// ids restrict group senders whatever the dm_policy, unless sender_policy is open
+func (c *BaseChannelImpl) groupSenderAllowed(msg *bus.InboundMessage) bool {
+	if c.groupSenders == GroupSenderOpen || len(c.config.AllowedIDs) == 0 {
+		return true
+	}
+	return c.allowlisted(msg.SenderID) || slices.Contains(c.config.AllowedIDs, msg.ChatID)
+}
+
+// requireMention reports whether group messages must mention the bot
+func (c *BaseChannelImpl) requireMention() bool {
+	return c.config.Group.RequireMention != nil && *c.config.Group.RequireMention
+}
+
+// admitGroup applies the group policy; unmentioned messages are kept as
+// history and prepended to the next message that mentions the bot
+func (c *BaseChannelImpl) admitGroup(msg *bus.InboundMessage) bool {
+	group := c.config.Group
+	if len(group.AllowedGroups) > 0 && !slices.Contains(group.AllowedGroups, msg.ChatID) {
+		logger.Debug("Group message blocked by allowed_groups",
+			zap.String("channel", c.name),
+			zap.String("chat_id", msg.ChatID))
+		return false
+	}
+	if !c.groupSenderAllowed(msg) {
+		logger.Debug("Group message blocked by allowed_ids",
+			zap.String("channel", c.name),
+			zap.String("chat_id", msg.ChatID),
+			zap.String("sender_id", msg.SenderID))
+		return false
+	}
+
+	c.groupMu.Lock()
+	defer c.groupMu.Unlock()
+
+	chat, ok := c.groups[msg.ChatID]
+	if !ok {
+		chat = &groupChat{}
+		c.groups[msg.ChatID] = chat
+	}
+
+	if c.requireMention() && !IsMentioned(msg) {
+		if group.HistoryLimit > 0 && msg.Content != "" {
+			chat.history = append(chat.history, groupHistoryEntry{sender: historySender(msg), content: msg.Content})
+			if extra := len(chat.history) - group.HistoryLimit; extra > 0 {
+				chat.history = chat.history[extra:]
+			}
+		}
+		return false
+	}
+
+	if len(chat.history) > 0 {
+		msg.Content = withGroupHistory(chat.history, msg.Content)
+		msg.Metadata[MetadataGroupHistory] = len(chat.history)
+		chat.history = nil
+	}
+	return true
+}
+
+// threadReplyTo returns the message a reply should reference: only group
+// chats in thread mode reply to the original message
+func (c *BaseChannelImpl) threadReplyTo(msg *bus.OutboundMessage) string {
+	if c.replyMode != ReplyModeThread {
+		return ""
+	}
+	c.groupMu.Lock()
+	_, isGroup := c.groups[msg.ChatID]
+	c.groupMu.Unlock()
+	if !isGroup {
+		return ""
+	}
+	return msg.ReplyTo
+}
+
+// withGroupHistory prepends the buffered group messages to the current one
+func withGroupHistory(history []groupHistoryEntry, content string) string {
+	var sb strings.Builder
+	sb.WriteString("[Chat messages since your last reply - for context]\n")
+	for _, entry := range history {
+		sb.WriteString(entry.sender + ": " + entry.content + "\n")
+	}
+	sb.WriteString("\n[Current message - respond to this]\n")
+	sb.WriteString(content)
+	return sb.String()
+}
+
+// historySender labels a history entry with the display name, or the sender ID without one
+func historySender(msg *bus.InboundMessage) string {
+	if name := senderName(msg); name != "" {
+		return name
+	}
+	return msg.SenderID
+}
+
+// stripMention removes the bot's own mention tokens from the content
+func stripMention(content string, tokens ...string) string {
+	for _, token := range tokens {
+		if token != "" {
+			content = strings.ReplaceAll(content, token, "")
+		}
+	}
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,111 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+)
+
+func groupMessage(chatID, sender, content string, mentioned bool) *bus.InboundMessage {
+	return &bus.InboundMessage{
+		ID:       sender + "-" + content,
+		SenderID: sender,
+		ChatID:   chatID,
+		Content:  content,
+		Metadata: map[string]interface{}{MetadataDirect: false, MetadataMentioned: mentioned, "from_name": sender},
+	}
+}
+
+func TestGroupRequireMention(t *testing.T) {
+	requireMention := true
+	c, messageBus := newTestChannel(t, "default", BaseChannelConfig{Group: config.GroupPolicyConfig{
+		RequireMention: &requireMention,
+		AllowedGroups:  []string{"g1"},
+		HistoryLimit:   2,
+	}})
+	ctx := context.Background()
+
+	for _, msg := range []*bus.InboundMessage{
+		groupMessage("g2", "eve", "hi bot", true),
+		groupMessage("g1", "alice", "first", false),
+		groupMessage("g1", "bob", "second", false),
+		groupMessage("g1", "carol", "third", false),
+	} {
+		if err := c.PublishInbound(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if messageBus.InboundCount() != 0 {
+		t.Fatal("expected other groups and unmentioned messages to be dropped")
+	}
+
+	// The next mention carries the most recent unmentioned messages as context
+	if err := c.PublishInbound(ctx, groupMessage("g1", "dave", "what do you think?", true)); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := messageBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.Content, "alice: first") || !strings.Contains(msg.Content, "bob: second\ncarol: third\n") ||
+		!strings.HasSuffix(msg.Content, "what do you think?") || msg.Metadata[MetadataGroupHistory] != 2 {
+		t.Fatalf("unexpected content %q, metadata %v", msg.Content, msg.Metadata)
+	}
+
+	// History is consumed by the mention
+	if err := c.PublishInbound(ctx, groupMessage("g1", "dave", "and now?", true)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := messageBus.ConsumeInbound(ctx); msg.Content != "and now?" {
+		t.Errorf("expected no history on the second mention, got %q", msg.Content)
+	}
+}
+
+func TestGroupRespondToAll(t *testing.T) {
+	c, messageBus := newTestChannel(t, "default", BaseChannelConfig{Group: config.GroupPolicyConfig{HistoryLimit: 5}})
+	if err := c.PublishInbound(context.Background(), groupMessage("g1", "alice", "hello", false)); err != nil {
+		t.Fatal(err)
+	}
+	if messageBus.InboundCount() != 1 {
+		t.Error("expected unmentioned messages to be published without require_mention")
+	}
+}
+
+func TestThreadReplyTo(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		mode string
+		chat string
+		want string
+	}{
+		{"", "g1", "m1"},
+		{"inline", "g1", ""},
+		{"", "dm", ""},
+	} {
+		c, _ := newTestChannel(t, "default", BaseChannelConfig{Group: config.GroupPolicyConfig{ReplyMode: tc.mode}})
+		if err := c.PublishInbound(ctx, groupMessage("g1", "alice", "hello", true)); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.threadReplyTo(&bus.OutboundMessage{ChatID: tc.chat, ReplyTo: "m1"}); got != tc.want {
+			t.Errorf("threadReplyTo(mode=%q, chat=%q) = %q, want %q", tc.mode, tc.chat, got, tc.want)
+		}
+	}
+}
+
+func TestDetectTeamsMention(t *testing.T) {
+	msg := &TeamsWebhookMessage{
+		Text:      "<at>Claw</at> summarize the thread",
+		Recipient: TeamsActor{ID: "28:bot"},
+		Entities: []TeamsEntity{
+			{Type: "mention", Mentioned: &TeamsActor{ID: "29:alice"}, Text: "<at>Alice</at>"},
+			{Type: "mention", Mentioned: &TeamsActor{ID: "28:bot"}, Text: "<at>Claw</at>"},
+		},
+	}
+	mentioned, content := detectTeamsMention(msg)
+	if !mentioned || content != "summarize the thread" {
+		t.Errorf("detectTeamsMention() = %v, %q", mentioned, content)
+	}
+}
@@ -129,6 +129,9 @@ func (c *InfoflowChannel) handleDefault(cmd, fromUserID string, body infoflow.Bo
 			"group_id":   msg.GroupID,
 			"message_id": msg.Message.Header.MessageID,
 			"msg_type":   msg.Message.Header.MsgType,
+			// 如流群机器人只收到 @ 机器人的群消息
+			"is_direct": false,
+			"mentioned": true,
 		},
 	}
 
@@ -9,6 +9,7 @@ import (
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -254,6 +255,7 @@ func (m *Manager) DispatchOutbound(ctx context.Context) error {
 			if msg.ChatID == "" {
 				logger.Warn("Outbound message has no chat_id, skipping",
 					zap.String("channel", msg.Channel))
+				telemetry.RecordChannelMessage(msg.Channel, "outbound", telemetry.ResultDropped)
 				continue
 			}
 
@@ -263,6 +265,7 @@ func (m *Manager) DispatchOutbound(ctx context.Context) error {
 				logger.Warn("Channel not found for outbound message",
 					zap.String("channel", msg.Channel),
 				)
+				telemetry.RecordChannelMessage(msg.Channel, "outbound", telemetry.ResultDropped)
 				continue
 			}
 
@@ -272,7 +275,9 @@ func (m *Manager) DispatchOutbound(ctx context.Context) error {
 					zap.String("channel", msg.Channel),
 					zap.Error(err),
 				)
+				telemetry.RecordChannelMessage(msg.Channel, "outbound", telemetry.ResultError)
 			} else {
+				telemetry.RecordChannelMessage(msg.Channel, "outbound", telemetry.ResultOK)
 				logger.Debug("Message sent successfully via channel",
 					zap.String("channel", msg.Channel),
 					zap.String("chat_id", msg.ChatID))
@@ -298,6 +303,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 							AccountID:  accountID,
 							Name:       accountCfg.Name,
 							AllowedIDs: accountCfg.AllowedIDs,
+							DMPolicy:   accountCfg.DMPolicy,
+							Group:      accountCfg.Group,
 						},
 						Token: accountCfg.Token,
 					}
@@ -328,6 +335,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 					Enabled:    cfg.Channels.Telegram.Enabled,
 					AccountID:  "default",
 					AllowedIDs: cfg.Channels.Telegram.AllowedIDs,
+					DMPolicy:   cfg.Channels.Telegram.DMPolicy,
+					Group:      cfg.Channels.Telegram.Group,
 				},
 				Token: cfg.Channels.Telegram.Token,
 			}
@@ -355,6 +364,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 							AccountID:  accountID,
 							Name:       accountCfg.Name,
 							AllowedIDs: accountCfg.AllowedIDs,
+							DMPolicy:   accountCfg.DMPolicy,
+							Group:      accountCfg.Group,
 						},
 						BridgeURL: accountCfg.BridgeURL,
 					}
@@ -381,6 +392,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 					Enabled:    cfg.Channels.WhatsApp.Enabled,
 					AccountID:  "default",
 					AllowedIDs: cfg.Channels.WhatsApp.AllowedIDs,
+					DMPolicy:   cfg.Channels.WhatsApp.DMPolicy,
+					Group:      cfg.Channels.WhatsApp.Group,
 				},
 				BridgeURL: cfg.Channels.WhatsApp.BridgeURL,
 			}
@@ -407,6 +420,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 						AppID:      accountCfg.AppID,
 						AppSecret:  accountCfg.AppSecret,
 						AllowedIDs: accountCfg.AllowedIDs,
+						DMPolicy:   accountCfg.DMPolicy,
+						Group:      accountCfg.Group,
 					}
 					channel, err := NewFeishuChannel(fsCfg, m.bus)
 					if err != nil {
@@ -447,6 +462,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 						AppID:      accountCfg.AppID,
 						AppSecret:  accountCfg.AppSecret,
 						AllowedIDs: accountCfg.AllowedIDs,
+						DMPolicy:   accountCfg.DMPolicy,
+						Group:      accountCfg.Group,
 					}
 
 					channel, err := NewQQChannel(accountID, qqCfg, m.bus)
@@ -493,6 +510,7 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 						AgentID:    accountCfg.AgentID,
 						Secret:     accountCfg.AppSecret,
 						AllowedIDs: accountCfg.AllowedIDs,
+						DMPolicy:   accountCfg.DMPolicy,
 					}
 					channel, err := NewWeWorkChannel(wwCfg, m.bus)
 					if err != nil {
@@ -533,6 +551,8 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 						ClientID:     accountCfg.ClientID,
 						ClientSecret: accountCfg.ClientSecret,
 						AllowedIDs:   accountCfg.AllowedIDs,
+						DMPolicy:     accountCfg.DMPolicy,
+						Group:        accountCfg.Group,
 					}
 					channel, err := NewDingTalkChannel(dtCfg, m.bus)
 					if err != nil {
@@ -574,6 +594,7 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 							AccountID:  accountID,
 							Name:       accountCfg.Name,
 							AllowedIDs: accountCfg.AllowedIDs,
+							Group:      accountCfg.Group,
 						},
 						WebhookURL:  accountCfg.WebhookURL,
 						Token:       accountCfg.Token,
@@ -603,6 +624,7 @@ func (m *Manager) SetupFromConfig(cfg *config.Config) error {
 					Enabled:    cfg.Channels.Infoflow.Enabled,
 					AccountID:  "default",
 					AllowedIDs: cfg.Channels.Infoflow.AllowedIDs,
+					Group:      cfg.Channels.Infoflow.Group,
 				},
 				WebhookURL:  cfg.Channels.Infoflow.WebhookURL,
 				Token:       cfg.Channels.Infoflow.Token,
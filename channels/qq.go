@@ -111,6 +111,10 @@ type ATMessageEventData struct {
 	} `json:"author"`
 	ChannelID string `json:"channel_id"`
 	GuildID   string `json:"guild_id"`
+	Mentions  []struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"mentions"`
 }
 
 // NewQQChannel 创建 QQ 官方 Bot 通道
@@ -123,6 +127,8 @@ func NewQQChannel(accountID string, cfg config.QQChannelConfig, bus *bus.Message
 		Enabled:    cfg.Enabled,
 		AccountID:  accountID,
 		AllowedIDs: cfg.AllowedIDs,
+		DMPolicy:   cfg.DMPolicy,
+		Group:      cfg.Group,
 	}
 
 	return &QQChannel{
@@ -491,6 +497,7 @@ func (c *QQChannel) handleC2CMessage(data json.RawMessage) {
 		Metadata: map[string]interface{}{
 			"chat_type": "c2c",
 			"msg_id":    event.ID,
+			"is_direct": true,
 		},
 	}
 
@@ -524,6 +531,9 @@ func (c *QQChannel) handleGroupATMessage(data json.RawMessage) {
 			"group_id":      event.GroupOpenID,
 			"member_openid": senderID,
 			"msg_id":        event.ID,
+			// 群和频道只推送 @ 机器人的消息
+			"is_direct": false,
+			"mentioned": true,
 		},
 	}
 
@@ -544,9 +554,17 @@ func (c *QQChannel) handleChannelATMessage(data json.RawMessage) {
 		return
 	}
 
+	// 去掉 @ 机器人的文本
+	content := event.Content
+	for _, mention := range event.Mentions {
+		if mention.Bot {
+			content = stripMention(content, "<@!"+mention.ID+">", "<@"+mention.ID+">")
+		}
+	}
+
 	msg := &bus.InboundMessage{
 		ID:        event.ID,
-		Content:   event.Content,
+		Content:   content,
 		AccountID: c.AccountID(),
 		SenderID:  senderID,
 		ChatID:    event.ChannelID,
@@ -557,6 +575,8 @@ func (c *QQChannel) handleChannelATMessage(data json.RawMessage) {
 			"channel_id": event.ChannelID,
 			"group_id":   event.GuildID,
 			"msg_id":     event.ID,
+			"is_direct":  false,
+			"mentioned":  true,
 		},
 	}
 
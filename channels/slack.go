@@ -18,6 +18,7 @@ type SlackChannel struct {
 	client        *slack.Client
 	token         string
 	signingSecret string
+	botUserID     string
 }
 
 // SlackConfig Slack 配置
@@ -57,6 +58,8 @@ func (c *SlackChannel) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to authenticate with slack: %w", err)
 	}
 
+	c.botUserID = authResp.UserID
+
 	logger.Info("Slack bot started",
 		zap.String("bot_name", authResp.User),
 		zap.String("team_name", authResp.Team),
@@ -131,18 +134,28 @@ func (c *SlackChannel) handleMessage(ctx context.Context, ev *slack.MessageEvent
 		return
 	}
 
+	// 话题中的消息继续在原话题中回复
+	threadTS := ev.ThreadTimestamp
+	if threadTS == "" {
+		threadTS = ev.Timestamp
+	}
+	mentioned, content := c.detectMention(ev.Text)
+
 	// 构建入站消息
 	msg := &bus.InboundMessage{
+		ID:       threadTS,
 		Channel:  c.Name(),
 		SenderID: senderID,
 		ChatID:   ev.Channel,
-		Content:  ev.Text,
+		Content:  content,
 		Media:    c.extractMedia(ev),
 		Metadata: map[string]interface{}{
 			"message_id":     ev.Timestamp,
 			"user_name":      user.Name,
 			"user_real_name": user.RealName,
 			"team":           ev.Team,
+			"is_direct":      strings.HasPrefix(ev.Channel, "D"),
+			"mentioned":      mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -152,6 +165,18 @@ func (c *SlackChannel) handleMessage(ctx context.Context, ev *slack.MessageEvent
 	}
 }
 
+// detectMention 检查消息是否 @ 了机器人，并去掉 @ 机器人的文本
+func (c *SlackChannel) detectMention(text string) (bool, string) {
+	if c.botUserID == "" {
+		return false, text
+	}
+	mention := "<@" + c.botUserID + ">"
+	if !strings.Contains(text, mention) {
+		return false, text
+	}
+	return true, stripMention(text, mention)
+}
+
 // handleCommand 处理命令
 func (c *SlackChannel) handleCommand(ctx context.Context, ev *slack.MessageEvent) {
 	command := ev.Text
@@ -244,9 +269,9 @@ func (c *SlackChannel) Send(msg *bus.OutboundMessage) error {
 		slack.MsgOptionText(msg.Content, false),
 	}
 
-	// 处理回复
-	if msg.ReplyTo != "" {
-		options = append(options, slack.MsgOptionTS(msg.ReplyTo))
+	// 处理回复，只有 thread 模式的群聊在话题中回复
+	if threadTS := c.threadReplyTo(msg); threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
 	}
 
 	// 处理媒体
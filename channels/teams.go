@@ -113,18 +113,23 @@ func (c *TeamsChannel) HandleWebhook(ctx context.Context, webhookMsg *TeamsWebho
 		return c.handleCommand(ctx, webhookMsg)
 	}
 
+	mentioned, content := detectTeamsMention(webhookMsg)
+
 	// 构建入站消息
 	msg := &bus.InboundMessage{
+		ID:       webhookMsg.ID,
 		Channel:  c.Name(),
 		SenderID: senderID,
 		ChatID:   webhookMsg.Conversation.ID,
-		Content:  webhookMsg.Text,
+		Content:  content,
 		Metadata: map[string]interface{}{
 			"message_id":   webhookMsg.ID,
 			"sender_name":  webhookMsg.From.Name,
 			"conversation": webhookMsg.Conversation,
 			"attachments":  webhookMsg.Attachments,
 			"entities":     webhookMsg.Entities,
+			"is_direct":    webhookMsg.Conversation.ConversationType == "personal",
+			"mentioned":    mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -132,6 +137,16 @@ func (c *TeamsChannel) HandleWebhook(ctx context.Context, webhookMsg *TeamsWebho
 	return c.PublishInbound(ctx, msg)
 }
 
+// detectTeamsMention 检查消息是否 @ 了机器人（recipient），并去掉 <at>机器人</at> 文本
+func detectTeamsMention(msg *TeamsWebhookMessage) (bool, string) {
+	for _, entity := range msg.Entities {
+		if entity.Type == "mention" && entity.Mentioned != nil && entity.Mentioned.ID == msg.Recipient.ID {
+			return true, stripMention(msg.Text, entity.Text)
+		}
+	}
+	return false, msg.Text
+}
+
 // handleCommand 处理命令
 func (c *TeamsChannel) handleCommand(ctx context.Context, webhookMsg *TeamsWebhookMessage) error {
 	command := webhookMsg.Text
@@ -239,8 +254,8 @@ func (c *TeamsChannel) sendViaBotFramework(msg *bus.OutboundMessage, serviceURL
 		// 如果有回复的消息 ID
 	}
 
-	if msg.ReplyTo != "" {
-		payload["replyToId"] = msg.ReplyTo
+	if replyTo := c.threadReplyTo(msg); replyTo != "" {
+		payload["replyToId"] = replyTo
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -316,8 +331,8 @@ func (c *TeamsChannel) SendAdaptiveCard(msg *bus.OutboundMessage, card map[strin
 	}
 
 	// 如果有回复，设置回复信息
-	if msg.ReplyTo != "" {
-		messageCard["replyToId"] = msg.ReplyTo
+	if replyTo := c.threadReplyTo(msg); replyTo != "" {
+		messageCard["replyToId"] = replyTo
 	}
 
 	logger.Info("Teams adaptive card sent",
@@ -340,6 +355,7 @@ type TeamsWebhookMessage struct {
 	ServiceURL   string            `json:"serviceUrl"`
 	ChannelID    string            `json:"channelId"`
 	From         TeamsActor        `json:"from"`
+	Recipient    TeamsActor        `json:"recipient"`
 	Conversation TeamsConversation `json:"conversation"`
 	Text         string            `json:"text"`
 	Attachments  []TeamsAttachment `json:"attachments"`
@@ -354,8 +370,9 @@ type TeamsActor struct {
 
 // TeamsConversation Teams 会话
 type TeamsConversation struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	ConversationType string `json:"conversationType"` // personal, groupChat, channel
 }
 
 // TeamsAttachment Teams 附件
@@ -366,6 +383,8 @@ type TeamsAttachment struct {
 
 // TeamsEntity Teams 实体
 type TeamsEntity struct {
-	Type string                 `json:"type"`
-	Data map[string]interface{} `json:"data,omitempty"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Mentioned *TeamsActor            `json:"mentioned,omitempty"` // mention 实体中被 @ 的参与者
+	Text      string                 `json:"text,omitempty"`      // mention 实体在消息中的文本，如 <at>Bot</at>
 }
@@ -159,8 +159,11 @@ func (c *TelegramChannel) handleUpdate(ctx context.Context, update *telegrambot.
 		return c.handleCommand(ctx, message, content)
 	}
 
+	mentioned, content := c.detectMention(message, content)
+
 	// 构建入站消息
 	msg := &bus.InboundMessage{
+		ID:        strconv.Itoa(message.MessageID),
 		Channel:   c.Name(),
 		AccountID: c.AccountID(),
 		SenderID:  senderID,
@@ -173,6 +176,8 @@ func (c *TelegramChannel) handleUpdate(ctx context.Context, update *telegrambot.
 			"from_name":  message.From.FirstName,
 			"chat_type":  message.Chat.Type,
 			"reply_to":   message.ReplyToMessage,
+			"is_direct":  message.Chat.Type == "private",
+			"mentioned":  mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -180,6 +185,23 @@ func (c *TelegramChannel) handleUpdate(ctx context.Context, update *telegrambot.
 	return c.PublishInbound(ctx, msg)
 }
 
+// detectMention 检查消息是否 @ 了机器人或回复了机器人的消息，并去掉 @ 机器人的文本
+func (c *TelegramChannel) detectMention(message *telegrambot.Message, content string) (bool, string) {
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == c.bot.Self.ID {
+		return true, content
+	}
+	if c.bot.Self.UserName == "" {
+		return false, content
+	}
+	// 用户名不区分大小写
+	mention := "@" + c.bot.Self.UserName
+	i := strings.Index(strings.ToLower(content), strings.ToLower(mention))
+	if i < 0 {
+		return false, content
+	}
+	return true, stripMention(content, content[i:i+len(mention)])
+}
+
 // handleCommand 处理命令
 func (c *TelegramChannel) handleCommand(ctx context.Context, message *telegrambot.Message, command string) error {
 	chatID := message.Chat.ID
@@ -271,13 +293,13 @@ func (c *TelegramChannel) Send(msg *bus.OutboundMessage) error {
 	// 创建消息
 	tgMsg := telegrambot.NewMessage(chatID, msg.Content)
 
-	// 解析回复
-	if msg.ReplyTo != "" {
-		replyToID, err := strconv.Atoi(msg.ReplyTo)
+	// 解析回复，只有 thread 模式的群聊回复原消息
+	if replyTo := c.threadReplyTo(msg); replyTo != "" {
+		replyToID, err := strconv.Atoi(replyTo)
 		if err == nil {
 			tgMsg.ReplyToMessageID = replyToID
 		} else {
-			logger.Warn("Invalid reply_to id for telegram", zap.String("id", msg.ReplyTo), zap.Error(err))
+			logger.Warn("Invalid reply_to id for telegram", zap.String("id", replyTo), zap.Error(err))
 		}
 	}
 
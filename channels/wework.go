@@ -50,6 +50,7 @@ func NewWeWorkChannel(cfg config.WeWorkChannelConfig, bus *bus.MessageBus) (*WeW
 	baseCfg := BaseChannelConfig{
 		Enabled:    cfg.Enabled,
 		AllowedIDs: cfg.AllowedIDs,
+		DMPolicy:   cfg.DMPolicy,
 	}
 
 	port := cfg.WebhookPort
@@ -207,7 +208,8 @@ func (c *WeWorkChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 			Channel:   c.Name(),
 			Timestamp: time.Unix(msg.CreateTime, 0),
 			Metadata: map[string]interface{}{
-				"agent_id": msg.AgentID,
+				"agent_id":  msg.AgentID,
+				"is_direct": true,
 			},
 		}
 		_ = c.PublishInbound(context.Background(), inMsg)
@@ -136,6 +136,8 @@ func (c *WhatsAppChannel) handleMessage(ctx context.Context, msg *WhatsAppMessag
 		Metadata: map[string]interface{}{
 			"message_type": msg.Type,
 			"timestamp":    msg.Timestamp,
+			"is_direct":    !strings.HasSuffix(msg.ChatID, "@g.us"),
+			"mentioned":    msg.Mentioned,
 		},
 		Timestamp: time.Now(),
 	}
@@ -194,4 +196,5 @@ type WhatsAppMessage struct {
 	Text      string `json:"text"`
 	Type      string `json:"type"`
 	Timestamp int64  `json:"timestamp"`
+	Mentioned bool   `json:"mentioned"` // bridge 标记群消息是否 @ 了机器人
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/agent/hooks"
 	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/config"
@@ -48,7 +49,7 @@ func init() {
 	agentCmd.Flags().StringVar(&agentTo, "to", "", "Recipient number in E.164 used to derive the session key")
 	agentCmd.Flags().StringVar(&agentID, "agent", "", "Agent id (overrides routing bindings)")
 	agentCmd.Flags().StringVar(&agentSessionID, "session-id", "", "Use an explicit session id")
-	agentCmd.Flags().StringVar(&agentThinking, "thinking", "off", "Thinking level: off | minimal | low | medium | high")
+	agentCmd.Flags().StringVar(&agentThinking, "thinking", "off", "Thinking level: off | minimal | low | medium | high | xhigh")
 	agentCmd.Flags().BoolVar(&agentVerbose, "verbose", false, "Persist agent verbose level for the session")
 	agentCmd.Flags().StringVar(&agentChannel, "channel", "", "Delivery channel: last|telegram|whatsapp|discord|irc|googlechat|slack|signal|imessage|feishu|nostr|msteams|mattermost|nextcloud-talk|matrix|bluebubbles|line|zalo|wecom|zalouser|synology-chat|tlon")
 	agentCmd.Flags().BoolVar(&agentLocal, "local", false, "Run the embedded agent locally (requires model provider API keys in your shell)")
@@ -108,12 +109,17 @@ func runAgent(cmd *cobra.Command, args []string) {
 	defer messageBus.Close()
 
 	// Create session manager
-	sessionDir := homeDir + "/.goclaw/sessions"
-	sessionMgr, err := session.NewManager(sessionDir)
+	sessionDir, err := config.GetSessionsPath(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get sessions directory: %v\n", err)
+		os.Exit(1)
+	}
+	sessionMgr, err := session.Open(cfg.Sessions.Backend, sessionDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create session manager: %v\n", err)
 		os.Exit(1)
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	// Create memory store
 	memoryStore := agent.NewMemoryStore(workspace)
@@ -146,11 +152,15 @@ func runAgent(cmd *cobra.Command, args []string) {
 		cfg.Tools.Shell.WorkingDir,
 		cfg.Tools.Shell.Sandbox,
 	)
+	if err := configureShellPolicy(shellTool, cfg, terminalPrompt()); err != nil && agentVerbose {
+		fmt.Fprintf(os.Stderr, "Warning: Invalid shell policy: %v\n", err)
+	}
 	for _, tool := range shellTool.GetTools() {
 		if err := toolRegistry.RegisterExisting(tool); err != nil && agentVerbose {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to register tool %s: %v\n", tool.Name(), err)
 		}
 	}
+	defer func() { _ = shellTool.Close() }()
 
 	// Register web tool
 	webTool := tools.NewWebTool(
@@ -166,15 +176,13 @@ func runAgent(cmd *cobra.Command, args []string) {
 
 	// Register browser tool if enabled
 	if cfg.Tools.Browser.Enabled {
-		browserTool := tools.NewBrowserTool(
-			cfg.Tools.Browser.Headless,
-			cfg.Tools.Browser.Timeout,
-		)
+		browserTool := tools.NewBrowserToolWithConfig(cfg.Tools.Browser)
 		for _, tool := range browserTool.GetTools() {
 			if err := toolRegistry.RegisterExisting(tool); err != nil && agentVerbose {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to register browser tool %s: %v\n", tool.Name(), err)
 			}
 		}
+		defer func() { _ = browserTool.Close() }()
 	}
 
 	// Register use_skill tool
@@ -207,6 +215,15 @@ func runAgent(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to discover skills: %v\n", skillsErr)
 	}
 
+	// Register skill_run tool
+	if cfg.Tools.Shell.Enabled {
+		skillRunTool := tools.NewSkillRunTool(agent.NewSkillEntrypointResolver(skillsLoader, workspace), cfg.Tools.Shell.Sandbox)
+		if err := toolRegistry.RegisterExisting(skillRunTool); err != nil && agentVerbose {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to register skill_run: %v\n", err)
+		}
+		defer func() { _ = skillRunTool.Close() }()
+	}
+
 	// Create LLM provider
 	provider, err := providers.NewProvider(cfg)
 	if err != nil {
@@ -225,15 +242,30 @@ func runAgent(cmd *cobra.Command, args []string) {
 		sessionKey = agentChannel + ":default"
 	}
 
+	// Lifecycle hooks come from the selected agent's config
+	var hookConfigs []config.HookConfig
+	for _, agentCfg := range cfg.Agents.List {
+		if agentCfg.ID == agentID {
+			hookConfigs = agentCfg.Hooks
+		}
+	}
+	hookRegistry, err := hooks.NewRegistryFromConfig(hookConfigs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create hooks: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create new agent first
 	agentInstance, err := agent.NewAgent(&agent.NewAgentConfig{
-		Bus:          messageBus,
-		Provider:     provider,
-		SessionMgr:   sessionMgr,
-		Tools:        toolRegistry,
-		Context:      contextBuilder,
-		Workspace:    workspace,
-		MaxIteration: agentMaxIterations,
+		Bus:           messageBus,
+		Provider:      provider,
+		SessionMgr:    sessionMgr,
+		Tools:         toolRegistry,
+		Context:       contextBuilder,
+		Workspace:     workspace,
+		MaxIteration:  agentMaxIterations,
+		ThinkingLevel: agentThinking,
+		Hooks:         hookRegistry,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create agent: %v\n", err)
@@ -377,6 +409,12 @@ func handleStreamingOutput(ctx context.Context, eventChan <-chan *agent.Event, m
 					fmt.Print(event.StreamContent)
 				}
 
+			case agent.EventThinking:
+				thinkingContent.WriteString(event.Thinking)
+				if thinkingLevel != "off" {
+					fmt.Printf("\n🤔 Thinking: %s\n", event.Thinking)
+				}
+
 			case agent.EventStreamFinal:
 				finalContent.WriteString(event.StreamContent)
 				if !inFinal {
@@ -1,10 +1,17 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -33,8 +40,8 @@ var approvalsAllowlistCmd = &cobra.Command{
 }
 
 var approvalsAllowlistAddCmd = &cobra.Command{
-	Use:   "add <tool>",
-	Short: "Add a tool to the approval allowlist",
+	Use:   "add <tool|shell:prefix>",
+	Short: "Add a tool or shell command prefix (e.g. shell:git push) to the approval allowlist",
 	Args:  cobra.ExactArgs(1),
 	Run:   runApprovalsAllowlistAdd,
 }
@@ -66,14 +73,24 @@ type ApprovalsConfig struct {
 // runApprovalsGet handles the approvals get command
 func runApprovalsGet(cmd *cobra.Command, args []string) {
 	cfg, err := loadApprovalsConfig()
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if cfg == nil {
+		cfg = &ApprovalsConfig{AskForDangerousTools: true}
+	}
+
+	// Show what the agent actually uses: this file merged over the main config
+	var base config.ApprovalsConfig
+	if mainCfg, err := config.Load(""); err == nil {
+		base = mainCfg.Approvals
+	}
+	effective := mergeApprovals(base, cfg)
 
 	fmt.Println("Approval Settings:")
-	fmt.Printf("  Behavior: %s\n", cfg.Behavior)
-	fmt.Printf("  Allowlist: %v\n", cfg.Allowlist)
+	fmt.Printf("  Behavior: %s\n", effective.Behavior)
+	fmt.Printf("  Allowlist: %v\n", effective.Allowlist)
 	fmt.Printf("  Ask for dangerous tools: %t\n", cfg.AskForDangerousTools)
 }
 
@@ -88,9 +105,8 @@ func runApprovalsSet(cmd *cobra.Command, args []string) {
 
 	cfg, err := loadApprovalsConfig()
 	if err != nil {
-		// Create default config if it doesn't exist
+		// Create default config if it doesn't exist; the behavior stays unset so the main config applies
 		cfg = &ApprovalsConfig{
-			Behavior:             "manual",
 			Allowlist:            []string{},
 			AskForDangerousTools: true,
 		}
@@ -112,9 +128,8 @@ func runApprovalsAllowlistAdd(cmd *cobra.Command, args []string) {
 
 	cfg, err := loadApprovalsConfig()
 	if err != nil {
-		// Create default config if it doesn't exist
+		// Create default config if it doesn't exist; the behavior stays unset so the main config applies
 		cfg = &ApprovalsConfig{
-			Behavior:             "manual",
 			Allowlist:            []string{},
 			AskForDangerousTools: true,
 		}
@@ -184,6 +199,7 @@ func getApprovalsConfigPath() (string, error) {
 }
 
 // loadApprovalsConfig loads the approvals configuration
+// An empty behavior means the file does not override approvals.behavior of the main config
 func loadApprovalsConfig() (*ApprovalsConfig, error) {
 	configPath, err := getApprovalsConfigPath()
 	if err != nil {
@@ -200,11 +216,6 @@ func loadApprovalsConfig() (*ApprovalsConfig, error) {
 		return nil, err
 	}
 
-	// Set defaults if not set
-	if cfg.Behavior == "" {
-		cfg.Behavior = "manual"
-	}
-
 	return &cfg, nil
 }
 
@@ -228,3 +239,90 @@ func saveApprovalsConfig(cfg *ApprovalsConfig) error {
 
 	return os.WriteFile(configPath, data, 0644)
 }
+
+// configureShellPolicy applies the configured command policy and approval behavior to the shell tool
+// prompt handles "prompt" approvals (the terminal or the gateway approval queue); without one they are rejected
+func configureShellPolicy(shellTool *tools.ShellTool, cfg *config.Config, prompt tools.ApproverFunc) error {
+	policy, err := tools.NewShellPolicy(
+		cfg.Tools.Shell.AllowedCmds,
+		cfg.Tools.Shell.DeniedCmds,
+		cfg.Tools.Shell.Policy,
+		cfg.Tools.Shell.WorkingDir,
+	)
+	if err != nil {
+		return err
+	}
+	shellTool.SetPolicy(policy)
+
+	approvals := cfg.Approvals
+	if file, err := loadApprovalsConfig(); err == nil {
+		approvals = mergeApprovals(approvals, file)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load approvals config: %w", err)
+	}
+	shellTool.SetApprover(tools.NewConfigApprover(approvals, prompt))
+	return nil
+}
+
+// mergeApprovals merges the settings written by "goclaw approvals" over the main config:
+// a behavior set there wins, allowlists are combined and an unset behavior falls back to manual
+func mergeApprovals(base config.ApprovalsConfig, file *ApprovalsConfig) config.ApprovalsConfig {
+	merged := config.ApprovalsConfig{
+		Behavior:  base.Behavior,
+		Allowlist: append([]string(nil), base.Allowlist...),
+	}
+	if file != nil {
+		if file.Behavior != "" {
+			merged.Behavior = file.Behavior
+		}
+		for _, entry := range file.Allowlist {
+			if !slices.Contains(merged.Allowlist, entry) {
+				merged.Allowlist = append(merged.Allowlist, entry)
+			}
+		}
+	}
+	if merged.Behavior == "" {
+		merged.Behavior = "manual"
+	}
+	return merged
+}
+
+// terminalPrompt returns the terminal prompt when stdin is interactive
+func terminalPrompt() tools.ApproverFunc {
+	if stdinIsTerminal() {
+		return promptApproval
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+var promptMu sync.Mutex
+
+// promptApproval asks the user on the terminal whether to approve a request
+func promptApproval(ctx context.Context, req tools.ApprovalRequest) (bool, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "\n%sApprove? [y/N]: ", tools.FormatApprovalRequest(req))
+
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer <- strings.ToLower(strings.TrimSpace(line))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case a := <-answer:
+		return a == "y" || a == "yes", nil
+	}
+}
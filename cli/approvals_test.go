@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestMergeApprovals(t *testing.T) {
+	base := config.ApprovalsConfig{Behavior: "auto", Allowlist: []string{"shell:git status"}}
+
+	got := mergeApprovals(base, &ApprovalsConfig{Behavior: "prompt", Allowlist: []string{"shell:git push", "shell:git status"}})
+	if got.Behavior != "prompt" || !slices.Equal(got.Allowlist, []string{"shell:git status", "shell:git push"}) {
+		t.Errorf("unexpected merge %+v", got)
+	}
+
+	// A file written by "approvals allowlist add" leaves the configured behavior alone
+	if got := mergeApprovals(base, &ApprovalsConfig{Allowlist: []string{"shell:ls"}}); got.Behavior != "auto" {
+		t.Errorf("expected the main config behavior, got %q", got.Behavior)
+	}
+
+	if got := mergeApprovals(config.ApprovalsConfig{}, nil); got.Behavior != "manual" {
+		t.Errorf("expected an unset behavior to default to manual, got %q", got.Behavior)
+	}
+}
@@ -124,8 +124,8 @@ func (r *BrowserCommandRegistry) RegisterCommands(registry *CommandRegistry) {
 	// browser snapshot - Take snapshot
 	registry.Register(&Command{
 		Name:        "browser-snapshot",
-		Usage:       "/browser snapshot",
-		Description: "Take page snapshot (HTML + screenshot)",
+		Usage:       "/browser snapshot [-i]",
+		Description: "Take page snapshot (HTML + screenshot + accessibility tree with element refs)",
 		Handler:     r.browserSnapshot,
 	})
 
@@ -527,7 +527,23 @@ func (r *BrowserCommandRegistry) browserSnapshot(args []string) (string, bool) {
 	_ = os.WriteFile(htmlPath, []byte(html.OuterHTML), 0644)
 	_ = os.WriteFile(imgPath, screenshot.Data, 0644)
 
-	return fmt.Sprintf("Snapshot saved:\n  HTML: %s\n  Image: %s", htmlPath, imgPath), false
+	result := fmt.Sprintf("Snapshot saved:\n  HTML: %s\n  Image: %s", htmlPath, imgPath)
+
+	// Accessibility tree with element refs
+	opts := tools.SnapshotOptions{}
+	for _, arg := range args {
+		if arg == "-i" || arg == "--interactive" {
+			opts.InteractiveOnly = true
+		}
+	}
+	_ = client.Accessibility.Enable(ctx)
+	tree, err := client.Accessibility.GetFullAXTree(ctx)
+	if err != nil {
+		return fmt.Sprintf("%s\n\nFailed to get accessibility tree: %v", result, err), false
+	}
+	snap := tools.FormatAXSnapshot(tree.Nodes, opts)
+
+	return fmt.Sprintf("%s\n\n%s", result, snap.Text), false
 }
 
 // browserNavigate Navigate to URL
@@ -1296,9 +1312,11 @@ var browserScreenshotCmd = &cobra.Command{
 }
 
 var browserSnapshotCmd = &cobra.Command{
-	Use:   "snapshot",
-	Short: "Take page snapshot",
-	Run:   runBrowserSnapshot,
+	Use:                "snapshot [-i|--interactive]",
+	Short:              "Take page snapshot with accessibility tree and element refs",
+	Args:               cobra.ArbitraryArgs,
+	DisableFlagParsing: true,
+	Run:                runBrowserSnapshot,
 }
 
 var browserNavigateCmd = &cobra.Command{
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,6 +47,7 @@ type CommandRegistry struct {
 	stopped      bool                                   // 停止标志，用于中止正在运行的 agent
 	toolGetter   func() (map[string]interface{}, error) // 获取工具列表的函数
 	skillsGetter func() ([]*SkillInfo, error)           // 获取技能列表的函数
+	toolActivity func() []*toolActivity                 // 获取上一轮工具调用的函数
 }
 
 // SkillInfo 技能信息
@@ -139,6 +141,10 @@ func (r *CommandRegistry) SetTUIAgent(agent *TUIAgent) {
 		}
 		return result, nil
 	}
+	r.toolActivity = func() []*toolActivity {
+		return agent.lastTurnTools
+	}
+	agent.registerSessionCommands(r)
 }
 
 // GetSessionManager 获取会话管理器
@@ -333,6 +339,16 @@ func (r *CommandRegistry) registerBuiltInCommands() {
 		},
 	})
 
+	// /expand - 展开上一轮的工具调用
+	r.Register(&Command{
+		Name:        "expand",
+		Usage:       "/expand [n]",
+		Description: "Show the tool calls of the last turn, or the full output of call n",
+		Handler: func(args []string) (string, bool) {
+			return r.handleExpand(args), false
+		},
+	})
+
 	// /skills - 显示可用技能
 	r.Register(&Command{
 		Name:        "skills",
@@ -795,6 +811,22 @@ func (r *CommandRegistry) GetCommandListAsText() string {
 	return sb.String()
 }
 
+// handleExpand 处理 expand 命令
+func (r *CommandRegistry) handleExpand(args []string) string {
+	if r.toolActivity == nil {
+		return "Tool activity is only available in the TUI."
+	}
+	index := 0
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "Usage: /expand [n]"
+		}
+		index = n
+	}
+	return formatToolActivities(r.toolActivity(), index)
+}
+
 // handleTools 处理 tools 命令
 func (r *CommandRegistry) handleTools(args []string) string {
 	var sb strings.Builder
@@ -23,6 +23,7 @@ import (
 	"github.com/smallnest/goclaw/gateway"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/session"
+	"github.com/smallnest/goclaw/telemetry"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -135,6 +136,7 @@ func GatewayCommand() *cobra.Command {
 	cmd.AddCommand(runCmd, statusCmd, healthCmd, probeCmd)
 	cmd.AddCommand(installCmd, uninstallCmd, startCmd, stopCmd, restartCmd)
 	cmd.AddCommand(callCmd)
+	cmd.AddCommand(gatewayTokensCommand())
 
 	return cmd
 }
@@ -146,21 +148,23 @@ func runGateway(cmd *cobra.Command, args []string) {
 	if gatewayVerbose {
 		logLevel = "debug"
 	}
-	if err := logger.Init(logLevel, false); err != nil {
+
+	// Load configuration before the logger so that the log file settings apply
+	cfg, cfgErr := config.Load("")
+	if cfgErr != nil {
+		cfg = &config.Config{}
+	}
+	if err := logger.InitWithOptions(logLevel, false, GatewayLogOptions(cfg)); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync() // nolint:errcheck
+	if cfgErr != nil {
+		logger.Warn("Failed to load config, using defaults", zap.Error(cfgErr))
+	}
 
 	fmt.Println("🚀 Starting goclaw Gateway")
 
-	// Load configuration
-	cfg, err := config.Load("")
-	if err != nil {
-		logger.Warn("Failed to load config, using defaults", zap.Error(err))
-		cfg = &config.Config{}
-	}
-
 	// Override config with flags
 	if gatewayPort != 0 {
 		cfg.Gateway.Port = gatewayPort
@@ -172,16 +176,26 @@ func runGateway(cmd *cobra.Command, args []string) {
 	// Create components
 	messageBus := bus.NewMessageBus(100)
 	defer messageBus.Close()
+	telemetry.RegisterQueue("inbound", messageBus.InboundCount)
+	telemetry.RegisterQueue("outbound", messageBus.OutboundCount)
 
-	homeDir, err := os.UserHomeDir()
+	// 初始化 OpenTelemetry 追踪
+	shutdownTracing, err := telemetry.SetupTracing(cfg.Telemetry.Tracing)
+	if err != nil {
+		logger.Warn("Failed to set up tracing", zap.Error(err))
+	} else {
+		defer shutdownTracing(context.Background()) // nolint:errcheck
+	}
+
+	sessionDir, err := config.GetSessionsPath(cfg)
 	if err != nil {
-		logger.Fatal("Failed to get home directory", zap.Error(err))
+		logger.Fatal("Failed to get sessions directory", zap.Error(err))
 	}
-	sessionDir := homeDir + "/.goclaw/sessions"
-	sessionMgr, err := session.NewManager(sessionDir)
+	sessionMgr, err := session.Open(cfg.Sessions.Backend, sessionDir)
 	if err != nil {
 		logger.Fatal("Failed to create session manager", zap.Error(err))
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	channelMgr := channels.NewManager(messageBus)
 	if err := channelMgr.SetupFromConfig(cfg); err != nil {
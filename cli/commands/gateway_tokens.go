@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/gateway"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gatewayTokenName   string
+	gatewayTokenScopes []string
+	gatewayTokensJSON  bool
+)
+
+// gatewayTokensCommand returns the gateway tokens command
+func gatewayTokensCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Manage scoped gateway tokens",
+		Long: `Create, list and revoke named gateway tokens.
+
+Each token carries one or more scopes (read, operator, admin). Tokens are
+stored hashed, so a token is only shown once when it is created.`,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a gateway token",
+		Run:   runGatewayTokensCreate,
+	}
+	createCmd.Flags().StringVarP(&gatewayTokenName, "name", "n", "", "Token name")
+	createCmd.Flags().StringSliceVarP(&gatewayTokenScopes, "scope", "s", []string{"read"}, "Token scopes (read, operator, admin)")
+	createCmd.Flags().BoolVarP(&gatewayTokensJSON, "json", "j", false, "Output as JSON")
+	_ = createCmd.MarkFlagRequired("name")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List gateway tokens",
+		Run:   runGatewayTokensList,
+	}
+	listCmd.Flags().BoolVarP(&gatewayTokensJSON, "json", "j", false, "Output as JSON")
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke <name|id>",
+		Short: "Revoke a gateway token",
+		Args:  cobra.ExactArgs(1),
+		Run:   runGatewayTokensRevoke,
+	}
+
+	cmd.AddCommand(createCmd, listCmd, revokeCmd)
+	return cmd
+}
+
+// openGatewayTokenStore opens the token store configured for the gateway
+func openGatewayTokenStore() (*gateway.TokenStore, *config.Config) {
+	cfg, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load config: %v\n", err)
+	}
+
+	path := ""
+	if cfg != nil {
+		path = cfg.Gateway.Auth.TokensFile
+	}
+	if path == "" {
+		path, err = gateway.DefaultTokensPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	return gateway.NewTokenStore(path), cfg
+}
+
+// runGatewayTokensCreate creates a new token and prints it once
+func runGatewayTokensCreate(cmd *cobra.Command, args []string) {
+	scopes := make([]gateway.Scope, 0, len(gatewayTokenScopes))
+	for _, s := range gatewayTokenScopes {
+		scope, err := gateway.ParseScope(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	store, cfg := openGatewayTokenStore()
+	token, record, err := store.Create(gatewayTokenName, scopes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create token: %v\n", err)
+		os.Exit(1)
+	}
+
+	if gatewayTokensJSON {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"id":     record.ID,
+			"name":   record.Name,
+			"scopes": record.Scopes,
+			"token":  token,
+		}, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Created token %s (%s) with scopes: %s\n", record.Name, record.ID, joinScopes(record.Scopes))
+	fmt.Printf("\n  %s\n\n", token)
+	fmt.Println("Store it now, it will not be shown again.")
+	if cfg != nil && !cfg.Gateway.WebSocket.EnableAuth {
+		fmt.Println("Note: gateway.websocket.enable_auth is false, tokens are not enforced until it is enabled.")
+	}
+}
+
+// runGatewayTokensList lists all tokens
+func runGatewayTokensList(cmd *cobra.Command, args []string) {
+	store, _ := openGatewayTokenStore()
+	records, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	if gatewayTokensJSON {
+		// Never print hashes, only the identifying fields
+		for i := range records {
+			records[i].Hash = ""
+		}
+		data, _ := json.MarshalIndent(records, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No gateway tokens. Create one with: goclaw gateway tokens create --name <name> --scope read")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tTOKEN\tCREATED\tSTATUS")
+	for _, r := range records {
+		status := "active"
+		if r.RevokedAt != nil {
+			status = "revoked " + r.RevokedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s…\t%s\t%s\n",
+			r.ID, r.Name, joinScopes(r.Scopes), r.Hint, r.CreatedAt.Format("2006-01-02 15:04"), status)
+	}
+	_ = w.Flush()
+}
+
+// runGatewayTokensRevoke revokes a token by name or ID
+func runGatewayTokensRevoke(cmd *cobra.Command, args []string) {
+	store, _ := openGatewayTokenStore()
+	record, err := store.Revoke(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to revoke token: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Revoked token %s (%s)\n", record.Name, record.ID)
+}
+
+func joinScopes(scopes []gateway.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
@@ -11,7 +11,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
 )
 
 // LogsCmd 日志查看命令
@@ -23,12 +26,17 @@ var LogsCmd = &cobra.Command{
 }
 
 var (
-	logsFollow  bool
-	logsLimit   int
-	logsPlain   bool
-	logsJSON    bool
-	logsNoColor bool
-	logsFile    string
+	logsFollow    bool
+	logsLimit     int
+	logsPlain     bool
+	logsJSON      bool
+	logsNoColor   bool
+	logsFile      string
+	logsRemote    bool
+	logsURL       string
+	logsToken     string
+	logsLevel     string
+	logsComponent string
 )
 
 func init() {
@@ -38,6 +46,26 @@ func init() {
 	LogsCmd.Flags().BoolVar(&logsJSON, "json", false, "Output in JSON format (line-delimited)")
 	LogsCmd.Flags().BoolVar(&logsNoColor, "no-color", false, "Disable colored output")
 	LogsCmd.Flags().StringVarP(&logsFile, "file", "l", "", "Log file path (default: auto-detect)")
+	LogsCmd.Flags().BoolVar(&logsRemote, "remote", false, "Read logs from the running gateway instead of a local file")
+	LogsCmd.Flags().StringVar(&logsURL, "url", "", "Gateway WebSocket URL for --remote (default: from config)")
+	LogsCmd.Flags().StringVar(&logsToken, "token", "", "Gateway token for --remote (default: gateway.websocket.auth_token)")
+	LogsCmd.Flags().StringVar(&logsLevel, "level", "", "Only show entries at or above this level (debug, info, warn, error)")
+	LogsCmd.Flags().StringVar(&logsComponent, "component", "", "Only show entries from this component or module")
+}
+
+// GatewayLogOptions 根据 logging 配置返回网关日志的文件轮转和内存缓冲选项
+func GatewayLogOptions(cfg *config.Config) logger.Options {
+	opts := logger.Options{}
+	if cfg == nil {
+		return opts
+	}
+	opts.BufferSize = cfg.Logging.BufferSize
+	opts.MaxSizeMB = cfg.Logging.MaxSizeMB
+	opts.MaxBackups = cfg.Logging.MaxBackups
+	if path, err := config.GetLogPath(cfg); err == nil {
+		opts.File = path
+	}
+	return opts
 }
 
 // LogEntry represents a structured log entry
@@ -50,6 +78,17 @@ type LogEntry struct {
 
 // runLogs 执行日志查看命令
 func runLogs(cmd *cobra.Command, args []string) {
+	if logsLevel != "" {
+		if err := (logger.Query{Level: logsLevel}).Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if logsRemote {
+		runRemoteLogs()
+		return
+	}
+
 	// Determine log file path
 	logPath := logsFile
 	if logPath == "" {
@@ -93,6 +132,13 @@ func detectLogPath() string {
 		return ""
 	}
 
+	// The configured gateway log file comes first
+	if cfg, err := config.Load(""); err == nil && cfg.Logging.File != "" && cfg.Logging.File != "-" {
+		if _, err := os.Stat(cfg.Logging.File); err == nil {
+			return cfg.Logging.File
+		}
+	}
+
 	// Common log file locations (platform-agnostic)
 	candidates := []string{
 		filepath.Join(home, ".goclaw", "logs", "goclaw.log"),
@@ -127,7 +173,9 @@ func viewLogs(file *os.File, limit int) {
 	lines := make([]string, 0)
 
 	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		if line := scanner.Text(); matchesLogFilter(line) {
+			lines = append(lines, line)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -168,8 +216,9 @@ func followLogs(file *os.File) {
 			return
 		default:
 			if scanner.Scan() {
-				line := scanner.Text()
-				displayLine(line)
+				if line := scanner.Text(); matchesLogFilter(line) {
+					displayLine(line)
+				}
 			} else {
 				// Wait a bit before trying again
 				time.Sleep(100 * time.Millisecond)
@@ -193,8 +242,8 @@ func displayLine(line string) {
 	}
 
 	if logsJSON {
-		// Try to parse as JSON and re-emit as line-delimited JSON
-		var entry LogEntry
+		// Try to parse as JSON and re-emit as line-delimited JSON, keeping the original keys
+		var entry map[string]interface{}
 		if err := json.Unmarshal([]byte(line), &entry); err == nil {
 			// Re-marshal to ensure consistent formatting
 			if data, err := json.Marshal(entry); err == nil {
@@ -211,6 +260,33 @@ func displayLine(line string) {
 	displayColoredLine(line)
 }
 
+// matchesLogFilter 日志行是否满足 --level 和 --component 过滤条件，设置过滤时跳过非 JSON 行
+func matchesLogFilter(line string) bool {
+	if logsLevel == "" && logsComponent == "" {
+		return true
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return false
+	}
+	if logsLevel != "" {
+		level, err := zapcore.ParseLevel(strings.ToLower(getLevel(entry)))
+		minLevel, _ := zapcore.ParseLevel(strings.ToLower(logsLevel))
+		if err != nil || level < minLevel {
+			return false
+		}
+	}
+	if logsComponent != "" {
+		for _, key := range []string{"component", "module", "N"} {
+			if entry[key] == logsComponent {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
 // displayColoredLine 显示带颜色的日志行
 func displayColoredLine(line string) {
 	// Try to parse as structured log
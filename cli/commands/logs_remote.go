@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/gateway"
+	"github.com/smallnest/goclaw/internal/logger"
+)
+
+// rpcMessage 网关 WebSocket 上的响应或通知
+type rpcMessage struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	Params struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  *gateway.RPCError `json:"error"`
+}
+
+// remoteLogs 通过网关 WebSocket 读取日志的客户端
+type remoteLogs struct {
+	conn   *websocket.Conn
+	nextID int
+}
+
+// runRemoteLogs 从运行中的网关读取日志，--follow 时订阅实时日志
+func runRemoteLogs() {
+	url, token := remoteLogsTarget()
+	client, err := dialRemoteLogs(url, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to gateway at %s: %v\n", url, err)
+		os.Exit(1)
+	}
+	defer client.close()
+
+	params := map[string]interface{}{
+		"limit":     logsLimit,
+		"level":     logsLevel,
+		"component": logsComponent,
+	}
+	var page logger.Page
+	if err := client.call("logs.get", params, &page); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get logs: %v\n", err)
+		os.Exit(1)
+	}
+	for _, entry := range page.Entries {
+		displayLine(entry.Line)
+	}
+	if !logsFollow {
+		return
+	}
+
+	params["cursor"] = page.NextCursor
+	id, err := client.send("logs.subscribe", params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to subscribe to logs: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Closing the connection ends the read loop below
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		<-sigChan
+		close(stopped)
+		client.close()
+	}()
+
+	fmt.Printf("Following gateway logs at %s (Ctrl+C to exit)...\n\n", url)
+	err = client.stream(id, func(entry logger.Entry) {
+		displayLine(entry.Line)
+	})
+	select {
+	case <-stopped:
+		fmt.Println("\nStopped following logs.")
+	default:
+		fmt.Fprintf(os.Stderr, "Log stream ended: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// remoteLogsTarget 返回网关地址和令牌，命令行参数优先于配置
+func remoteLogsTarget() (string, string) {
+	cfg, err := config.Load("")
+	if err != nil {
+		cfg = nil
+	}
+	url := logsURL
+	if url == "" {
+		url = config.GetGatewayWebSocketURL(cfg)
+	}
+	token := logsToken
+	if token == "" && cfg != nil {
+		token = cfg.Gateway.WebSocket.AuthToken
+	}
+	return url, token
+}
+
+// dialRemoteLogs 连接网关 WebSocket，令牌放在 Authorization 请求头中
+func dialRemoteLogs(url, token string) (*remoteLogs, error) {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (HTTP %d)", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+	return &remoteLogs{conn: conn}, nil
+}
+
+// close 发送关闭帧后断开连接，避免网关把断开记录为错误
+func (c *remoteLogs) close() {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	_ = c.conn.Close()
+}
+
+// send 发送请求并返回请求 ID
+func (c *remoteLogs) send(method string, params map[string]interface{}) (string, error) {
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	req := gateway.JSONRPCRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}
+	return id, c.conn.WriteJSON(req)
+}
+
+// call 发送请求并等待对应的响应，期间收到的通知被忽略
+func (c *remoteLogs) call(method string, params map[string]interface{}, result interface{}) error {
+	id, err := c.send(method, params)
+	if err != nil {
+		return err
+	}
+	for {
+		var msg rpcMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("%s", msg.Error.Message)
+		}
+		return json.Unmarshal(msg.Result, result)
+	}
+}
+
+// stream 读取 logs.entry 通知直到连接关闭，订阅请求 subscribeID 失败时返回错误
+func (c *remoteLogs) stream(subscribeID string, handle func(logger.Entry)) error {
+	for {
+		var msg rpcMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		switch {
+		case msg.ID == subscribeID && msg.Error != nil:
+			return fmt.Errorf("%s", msg.Error.Message)
+		case msg.Method == "logs.entry":
+			var entry logger.Entry
+			if err := json.Unmarshal(msg.Params.Data, &entry); err == nil {
+				handle(entry)
+			}
+		case msg.Method == "logs.dropped":
+			var dropped struct {
+				Count uint64 `json:"count"`
+			}
+			if err := json.Unmarshal(msg.Params.Data, &dropped); err == nil {
+				fmt.Fprintf(os.Stderr, "... %d log entries were dropped by the gateway buffer\n", dropped.Count)
+			}
+		}
+	}
+}
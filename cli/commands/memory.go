@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/smallnest/goclaw/config"
@@ -118,6 +119,7 @@ func getSearchManager() (memory.MemorySearchManager, error) {
 	if memoryForceBuiltin {
 		cfg.Memory.Backend = "builtin"
 	}
+	cfg.Memory.Builtin.Embedding = memory.ResolveEmbeddingConfig(cfg)
 
 	return memory.GetMemorySearchManager(cfg.Memory, workspace)
 }
@@ -180,6 +182,14 @@ func runMemoryStatus(cmd *cobra.Command, args []string) {
 		fmt.Printf("Total Entries: %d\n", totalCount)
 	}
 
+	if provider, ok := status["embedding_provider"].(string); ok {
+		fmt.Printf("Embedding: %s (dimension %v)\n", provider, status["embedding_dimension"])
+	}
+
+	if mismatch, ok := status["index_mismatch"].(string); ok {
+		fmt.Printf("\nWarning: %s\n", mismatch)
+	}
+
 	if sourceCounts, ok := status["source_counts"].(map[memory.MemorySource]int); ok {
 		fmt.Println("\nBy Source:")
 		for source, count := range sourceCounts {
@@ -272,30 +282,15 @@ func runMemoryIndex(cmd *cobra.Command, args []string) {
 
 // runBuiltinIndex 执行 builtin 索引
 func runBuiltinIndex(workspace string, cfg *config.Config) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get home directory: %v\n", err)
-		os.Exit(1)
-	}
-
 	memoryDir := filepath.Join(workspace, "memory")
-	dbPath := filepath.Join(home, ".goclaw", "memory", "store.db")
-
-	// Load config for API key
-	apiKey := cfg.Providers.OpenAI.APIKey
-	if apiKey == "" {
-		apiKey = cfg.Providers.OpenRouter.APIKey
-	}
-
-	if apiKey == "" {
-		fmt.Fprintf(os.Stderr, "Error: No embedding provider API key found in config.\n")
-		fmt.Fprintf(os.Stderr, "Please configure OpenAI or OpenRouter API key in ~/.goclaw/config.json\n")
+	dbPath, err := memory.BuiltinDatabasePath(cfg.Memory.Builtin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
 	// Create embedding provider
-	providerCfg := memory.DefaultOpenAIConfig(apiKey)
-	provider, err := memory.NewOpenAIProvider(providerCfg)
+	provider, err := memory.NewEmbeddingProvider(memory.ResolveEmbeddingConfig(cfg))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create embedding provider: %v\n", err)
 		os.Exit(1)
@@ -321,10 +316,23 @@ func runBuiltinIndex(workspace string, cfg *config.Config) {
 
 	fmt.Println("Indexing memory files (builtin backend)...")
 	fmt.Printf("Workspace: %s\n", workspace)
-	fmt.Printf("Database: %s\n\n", dbPath)
+	fmt.Printf("Database: %s\n", dbPath)
+	fmt.Printf("Embedding: %s (dimension %d)\n\n", memory.EmbeddingProviderName(provider), provider.Dimension())
 
 	ctx := context.Background()
 
+	// Re-embed existing chunks if the index was built by another provider
+	if mismatch := store.Mismatch(); mismatch != nil {
+		indexProvider, indexDimension := store.EmbeddingInfo()
+		fmt.Printf("Re-embedding existing memories (index was built with %s, dimension %d)...\n", indexProvider, indexDimension)
+		n, err := manager.Reembed(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to re-embed memories: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Re-embedded %d memories\n\n", n)
+	}
+
 	// Index MEMORY.md
 	longTermPath := filepath.Join(memoryDir, "MEMORY.md")
 	if _, err := os.Stat(longTermPath); err == nil {
@@ -534,6 +542,11 @@ func indexFile(ctx context.Context, manager *memory.MemoryManager, filePath stri
 		return nil
 	}
 
+	// Replace chunks from a previous run of this file
+	if err := removeIndexedChunks(ctx, manager, filePath); err != nil {
+		return err
+	}
+
 	// Split into chunks (paragraphs)
 	chunks := splitIntoChunks(text, 500)
 
@@ -564,6 +577,22 @@ func indexFile(ctx context.Context, manager *memory.MemoryManager, filePath stri
 	return nil
 }
 
+// removeIndexedChunks 删除文件之前索引的块
+func removeIndexedChunks(ctx context.Context, manager *memory.MemoryManager, filePath string) error {
+	existing, err := manager.List(ctx, func(ve *memory.VectorEmbedding) bool {
+		return ve.Metadata.FilePath == filePath && slices.Contains(ve.Metadata.Tags, "indexed")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list indexed chunks: %w", err)
+	}
+	for _, ve := range existing {
+		if err := manager.Delete(ctx, ve.ID); err != nil {
+			return fmt.Errorf("failed to remove old chunk: %w", err)
+		}
+	}
+	return nil
+}
+
 // splitIntoChunks 将文本分割成块
 func splitIntoChunks(text string, maxChunkSize int) []string {
 	// Simple paragraph-based chunking
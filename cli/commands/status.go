@@ -66,23 +66,29 @@ type GatewayStatus struct {
 
 // SystemStatus represents overall system status
 type SystemStatus struct {
-	Gateway      GatewayStatus   `json:"gateway"`
-	Sessions     []SessionStatus `json:"sessions"`
-	SessionDir   string          `json:"session_dir"`
-	TotalSize    int64           `json:"total_size_bytes"`
-	SessionCount int             `json:"session_count"`
+	Gateway        GatewayStatus   `json:"gateway"`
+	Sessions       []SessionStatus `json:"sessions"`
+	SessionDir     string          `json:"session_dir"`
+	SessionBackend string          `json:"session_backend,omitempty"`
+	TotalSize      int64           `json:"total_size_bytes"`
+	SessionCount   int             `json:"session_count"`
 }
 
 // runStatus displays status information
 func runStatus(cmd *cobra.Command, args []string) {
 	// Create status object
-	homeDir, err := os.UserHomeDir()
+	cfg, err := config.Load("")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	sessionDir, err := config.GetSessionsPath(cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get home directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to get sessions directory: %v\n", err)
 		os.Exit(1)
 	}
 	status := &SystemStatus{
-		SessionDir: filepath.Join(homeDir, ".goclaw", "sessions"),
+		SessionDir:     sessionDir,
+		SessionBackend: cfg.Sessions.Backend,
 	}
 
 	// Check gateway status
@@ -152,10 +158,11 @@ func checkGatewayStatus(timeout int) GatewayStatus {
 // getSessionStatus retrieves session status information
 func getSessionStatus(status *SystemStatus, all bool, deep bool) error {
 	// Create session manager
-	sessionMgr, err := session.NewManager(status.SessionDir)
+	sessionMgr, err := session.Open(status.SessionBackend, status.SessionDir)
 	if err != nil {
 		return err
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	// List sessions
 	sessionKeys, err := sessionMgr.List()
@@ -29,12 +29,19 @@ type TUIAgent struct {
 	*agent.Agent
 	sessionMgr    *session.Manager
 	sessionKey    string
+	shellTool     *tools.ShellTool
 	skillsLoader  *agent.SkillsLoader
 	maxIterations int
 	cmdRegistry   *CommandRegistry
+	lastTurnTools []*toolActivity // 上一轮的工具调用，供 /expand 展示
+	pendingInput  string          // /fork 后预填到输入行的消息
 }
 
+// ShellConfigurer applies the configured command policy and approval behavior to a shell tool
+type ShellConfigurer func(shellTool *tools.ShellTool, cfg *config.Config) error
+
 // NewTUIAgent creates a new TUI agent
+// The shell tool follows cfg.Tools.Shell; configureShell wires its policy and approvals (nil keeps the defaults)
 func NewTUIAgent(
 	messageBus *bus.MessageBus,
 	sessionMgr *session.Manager,
@@ -43,6 +50,8 @@ func NewTUIAgent(
 	workspace string,
 	maxIterations int,
 	skillsLoader *agent.SkillsLoader,
+	cfg *config.Config,
+	configureShell ShellConfigurer,
 ) (*TUIAgent, error) {
 	toolRegistry := agent.NewToolRegistry()
 
@@ -56,17 +65,34 @@ func NewTUIAgent(
 	_ = toolRegistry.RegisterExisting(tools.NewUseSkillTool())
 
 	// Register shell tool
+	// 未配置工作目录时命令在工作区中运行
+	shellCfg := *cfg
+	if shellCfg.Tools.Shell.WorkingDir == "" {
+		shellCfg.Tools.Shell.WorkingDir = workspace
+	}
 	shellTool := tools.NewShellTool(
-		true,                   // enabled
-		[]string{},             // allowedCmds
-		[]string{},             // deniedCmds
-		120,                    // timeout
-		workspace,              // workingDir
-		config.SandboxConfig{}, // sandbox
+		shellCfg.Tools.Shell.Enabled,
+		shellCfg.Tools.Shell.AllowedCmds,
+		shellCfg.Tools.Shell.DeniedCmds,
+		shellCfg.Tools.Shell.Timeout,
+		shellCfg.Tools.Shell.WorkingDir,
+		shellCfg.Tools.Shell.Sandbox,
 	)
+	if configureShell != nil {
+		if err := configureShell(shellTool, &shellCfg); err != nil {
+			logger.Warn("Invalid shell policy, using defaults", zap.Error(err))
+		}
+	}
 	for _, tool := range shellTool.GetTools() {
 		_ = toolRegistry.RegisterExisting(tool)
 	}
+	// 会话结束时清理该会话的后台进程
+	sessionMgr.OnDelete(shellTool.CleanupSession)
+
+	// Register skill_run tool
+	if skillsLoader != nil {
+		_ = toolRegistry.RegisterExisting(tools.NewSkillRunTool(agent.NewSkillEntrypointResolver(skillsLoader, workspace), shellCfg.Tools.Shell.Sandbox))
+	}
 
 	// Register web tool
 	webTool := tools.NewWebTool("", "", 30)
@@ -79,6 +105,7 @@ func NewTUIAgent(
 	for _, tool := range browserTool.GetTools() {
 		_ = toolRegistry.RegisterExisting(tool)
 	}
+	sessionMgr.OnDelete(browserTool.CleanupSession)
 
 	// Create Agent
 	newAgent, err := agent.NewAgent(&agent.NewAgentConfig{
@@ -99,6 +126,7 @@ func NewTUIAgent(
 		Agent:         newAgent,
 		sessionMgr:    sessionMgr,
 		sessionKey:    "",
+		shellTool:     shellTool,
 		skillsLoader:  skillsLoader,
 		maxIterations: maxIterations,
 		cmdRegistry:   &CommandRegistry{},
@@ -115,10 +143,14 @@ var (
 	tuiMessage      string
 	tuiTimeoutMs    int
 	tuiHistoryLimit int
+
+	tuiConfigureShell ShellConfigurer
 )
 
 // TUICommand returns the tui command
-func TUICommand() *cobra.Command {
+// configureShell applies the shell command policy and wires risky commands to an interactive approval prompt
+func TUICommand(configureShell ShellConfigurer) *cobra.Command {
+	tuiConfigureShell = configureShell
 	cmd := &cobra.Command{
 		Use:   "tui",
 		Short: "Open Terminal UI for goclaw",
@@ -193,12 +225,17 @@ func runTUI(cmd *cobra.Command, args []string) {
 	defer messageBus.Close()
 
 	// Create session manager
-	sessionDir := homeDir + "/.goclaw/sessions"
-	sessionMgr, err := session.NewManager(sessionDir)
+	sessionDir, err := config.GetSessionsPath(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get sessions directory: %v\n", err)
+		os.Exit(1)
+	}
+	sessionMgr, err := session.Open(cfg.Sessions.Backend, sessionDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create session manager: %v\n", err)
 		os.Exit(1)
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	// Create memory store
 	memoryStore := agent.NewMemoryStore(workspace)
@@ -245,7 +282,7 @@ func runTUI(cmd *cobra.Command, args []string) {
 		maxIterations = 15
 	}
 
-	tuiAgent, err := NewTUIAgent(messageBus, sessionMgr, provider, contextBuilder, workspace, maxIterations, skillsLoader)
+	tuiAgent, err := NewTUIAgent(messageBus, sessionMgr, provider, contextBuilder, workspace, maxIterations, skillsLoader, cfg, tuiConfigureShell)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create TUI agent: %v\n", err)
 		os.Exit(1)
@@ -259,6 +296,7 @@ func runTUI(cmd *cobra.Command, args []string) {
 	defer func() {
 		agentCancel()
 		_ = tuiAgent.Stop()
+		_ = tuiAgent.shellTool.Close()
 	}()
 
 	// Always create a new session (unless explicitly specified)
@@ -291,9 +329,6 @@ func runTUI(cmd *cobra.Command, args []string) {
 
 	tuiAgent.cmdRegistry = cmdRegistry
 
-	// Get orchestrator for running messages
-	orchestrator := tuiAgent.GetOrchestrator()
-
 	// Handle message flag
 	if tuiMessage != "" {
 		fmt.Printf("Sending message: %s\n", tuiMessage)
@@ -301,11 +336,10 @@ func runTUI(cmd *cobra.Command, args []string) {
 		msgCtx, msgCancel := context.WithTimeout(ctx, timeout)
 		defer msgCancel()
 
-		response := processTUIDialogue(msgCtx, sess, orchestrator, tuiHistoryLimit)
-		if response != "" {
-			fmt.Println("\n" + response + "\n")
-			_ = sessionMgr.Save(sess)
-		}
+		stopInterrupt := cancelOnInterrupt(msgCancel)
+		streamTUIDialogue(msgCtx, sess, tuiAgent, tuiHistoryLimit)
+		stopInterrupt()
+		_ = sessionMgr.Save(sess)
 
 		if !tuiDeliver {
 			return
@@ -314,7 +348,7 @@ func runTUI(cmd *cobra.Command, args []string) {
 
 	// Start interactive mode
 	fmt.Println("Starting interactive TUI mode...")
-	fmt.Println("Press Ctrl+C to exit")
+	fmt.Println("Press Ctrl+C to exit, or to cancel the current turn while it runs")
 	fmt.Println()
 	fmt.Println("Arrow keys: ↑/↓ for history, ←/→ for edit")
 	fmt.Println("Enter multi-line mode with Alt+M (or Esc M)")
@@ -337,6 +371,22 @@ func runTUI(cmd *cobra.Command, args []string) {
 	// Input loop with multi-line support
 	fmt.Println("Enter your message (or /help for commands):")
 	for {
+		// /switch 和 /fork 会切换当前会话
+		if tuiAgent.sessionKey != sess.Key {
+			next, err := sessionMgr.GetOrCreate(tuiAgent.sessionKey)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to load session %s: %v\n", tuiAgent.sessionKey, err)
+				tuiAgent.sessionKey = sess.Key
+			} else {
+				sess = next
+				editor.InitHistory(getUserInputHistory(sess))
+			}
+		}
+		if tuiAgent.pendingInput != "" {
+			editor.SetInput(tuiAgent.pendingInput)
+			tuiAgent.pendingInput = ""
+		}
+
 		line, err := editor.ReadLine()
 		if err != nil {
 			if err == input.ErrInterrupt {
@@ -382,31 +432,30 @@ func runTUI(cmd *cobra.Command, args []string) {
 			Content: line,
 		})
 
-		// Run agent with orchestrator
+		// Run agent with orchestrator, streaming its events
+		// 暂停原始模式：输出按行渲染，Ctrl+C 产生 SIGINT 并只取消当前轮次
+		_ = editor.Suspend()
 		timeout := time.Duration(tuiTimeoutMs) * time.Millisecond
 		msgCtx, msgCancel := context.WithTimeout(ctx, timeout)
+		stopInterrupt := cancelOnInterrupt(msgCancel)
 
-		response := processTUIDialogue(msgCtx, sess, orchestrator, tuiHistoryLimit)
+		streamTUIDialogue(msgCtx, sess, tuiAgent, tuiHistoryLimit)
 
+		stopInterrupt()
 		msgCancel()
-
-		if response != "" {
-			// 暂停原始模式以正确输出多行内容
-			_ = editor.Suspend()
-			fmt.Println("\n" + response + "\n")
-			_ = sessionMgr.Save(sess)
-			_ = editor.Resume()
-		}
+		_ = sessionMgr.Save(sess)
+		fmt.Println()
+		_ = editor.Resume()
 	}
 }
 
-// processTUIDialogue 处理 TUI 对话（使用 Orchestrator）
+// processTUIDialogue 处理 TUI 对话（使用 Orchestrator），把新消息写入会话并返回最终回复
 func processTUIDialogue(
 	ctx context.Context,
 	sess *session.Session,
 	orchestrator *agent.Orchestrator,
 	historyLimit int,
-) string {
+) (string, error) {
 	// Load history messages
 	history := sess.GetHistory(historyLimit)
 	if historyLimit < 0 || historyLimit > 1000 {
@@ -417,9 +466,10 @@ func processTUIDialogue(
 	agentMsgs := sessionMessagesToAgentMessages(history)
 
 	// Run orchestrator
+	ctx = context.WithValue(ctx, agent.SessionKeyContextKey, sess.Key)
 	finalMessages, err := orchestrator.Run(ctx, agentMsgs)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return "", err
 	}
 
 	// Update session with new messages
@@ -470,11 +520,11 @@ func processTUIDialogue(
 	if len(finalMessages) > 0 {
 		lastMsg := finalMessages[len(finalMessages)-1]
 		if lastMsg.Role == "assistant" {
-			return extractAgentMessageText(lastMsg)
+			return extractAgentMessageText(lastMsg), nil
 		}
 	}
 
-	return ""
+	return "", nil
 }
 
 // runAgentIteration runs a single agent iteration (copied from chat.go)
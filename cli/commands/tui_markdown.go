@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ANSI styles used by the TUI renderer in addition to the colors in logs.go
+const (
+	ansiDim       = "\033[2m"
+	ansiUnderline = "\033[4m"
+	ansiClearLine = "\r\033[2K"
+)
+
+var (
+	mdBoldPattern    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdCodePattern    = regexp.MustCompile("`([^`]+)`")
+	mdHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdBulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdRulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdTableRule      = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// markdownStream renders markdown written to it in arbitrary chunks.
+//
+// Completed lines are rendered with ANSI styles as soon as their newline
+// arrives. The unfinished line is echoed raw so text appears token by token,
+// then replaced by its rendered form once complete. Tables are buffered until
+// the table ends so their columns can be aligned. Without ANSI the text is
+// passed through unchanged.
+type markdownStream struct {
+	w     io.Writer
+	ansi  bool
+	width int
+
+	line   []rune // the unfinished line
+	echoed int    // runes of line already echoed raw
+	inCode bool
+	table  []string
+	wrote  bool // anything written since the last Flush
+}
+
+func newMarkdownStream(w io.Writer, ansi bool, width int) *markdownStream {
+	if width <= 0 {
+		width = 80
+	}
+	return &markdownStream{w: w, ansi: ansi, width: width}
+}
+
+// Write renders a chunk of markdown
+func (m *markdownStream) Write(s string) {
+	if s == "" {
+		return
+	}
+	m.wrote = true
+	if !m.ansi {
+		_, _ = io.WriteString(m.w, s)
+		return
+	}
+	for _, r := range s {
+		if r == '\n' {
+			m.completeLine()
+			continue
+		}
+		m.line = append(m.line, r)
+	}
+	m.echoPartial()
+}
+
+// Flush completes the unfinished line and any buffered table. It is called at
+// the end of every assistant message.
+func (m *markdownStream) Flush() {
+	if !m.ansi {
+		if m.wrote {
+			_, _ = io.WriteString(m.w, "\n")
+		}
+		m.wrote = false
+		return
+	}
+	if len(m.line) > 0 {
+		m.completeLine()
+	}
+	m.flushTable()
+	m.inCode = false
+	m.wrote = false
+}
+
+// echoPartial prints the not yet echoed part of the unfinished line. Lines
+// that would wrap or belong to a table are not echoed, as they could not be
+// replaced in place.
+func (m *markdownStream) echoPartial() {
+	if len(m.line) == m.echoed || m.isTableLine(string(m.line)) {
+		return
+	}
+	if displayWidth(string(m.line)) >= m.width-1 {
+		return
+	}
+	_, _ = io.WriteString(m.w, string(m.line[m.echoed:]))
+	m.echoed = len(m.line)
+}
+
+func (m *markdownStream) completeLine() {
+	line := string(m.line)
+	if m.echoed > 0 {
+		_, _ = io.WriteString(m.w, ansiClearLine)
+	}
+	m.line = m.line[:0]
+	m.echoed = 0
+
+	if !m.inCode && m.isTableLine(line) {
+		m.table = append(m.table, line)
+		return
+	}
+	m.flushTable()
+	_, _ = io.WriteString(m.w, m.renderLine(line)+"\n")
+}
+
+func (m *markdownStream) isTableLine(line string) bool {
+	return !m.inCode && strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// renderLine styles one complete line outside of tables
+func (m *markdownStream) renderLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		m.inCode = !m.inCode
+		return ansiDim + line + ansiReset
+	}
+	if m.inCode {
+		return ansiCyan + "  " + line + ansiReset
+	}
+
+	if match := mdHeadingPattern.FindStringSubmatch(trimmed); match != nil {
+		style := ansiBold
+		if len(match[1]) == 1 {
+			style += ansiUnderline
+		}
+		return style + renderInline(match[2], style) + ansiReset
+	}
+	if mdRulePattern.MatchString(line) {
+		return ansiDim + strings.Repeat("─", min(m.width-1, 60)) + ansiReset
+	}
+	if match := mdBulletPattern.FindStringSubmatch(line); match != nil {
+		return match[1] + "• " + renderInline(match[2], "")
+	}
+	if strings.HasPrefix(trimmed, ">") {
+		return ansiDim + "│ " + renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")), ansiDim) + ansiReset
+	}
+	return renderInline(line, "")
+}
+
+// renderInline styles bold and code spans. outer is the style to restore
+// after each span.
+func renderInline(s, outer string) string {
+	restore := ansiReset + outer
+	s = mdCodePattern.ReplaceAllString(s, ansiCyan+"$1"+restore)
+	s = mdBoldPattern.ReplaceAllStringFunc(s, func(match string) string {
+		return ansiBold + match[2:len(match)-2] + restore
+	})
+	return s
+}
+
+// flushTable prints the buffered table with aligned columns
+func (m *markdownStream) flushTable() {
+	if len(m.table) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(m.table))
+	ruleRow := -1
+	for _, line := range m.table {
+		if mdTableRule.MatchString(line) && strings.Contains(line, "-") {
+			ruleRow = len(rows)
+			rows = append(rows, nil)
+			continue
+		}
+		rows = append(rows, splitTableRow(line))
+	}
+	m.table = m.table[:0]
+
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		if i == ruleRow {
+			for j, w := range widths {
+				if j > 0 {
+					sb.WriteString(ansiDim + "─┼─" + ansiReset)
+				}
+				sb.WriteString(ansiDim + strings.Repeat("─", w) + ansiReset)
+			}
+			sb.WriteString("\n")
+			continue
+		}
+		for j, w := range widths {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			if j > 0 {
+				sb.WriteString(ansiDim + " │ " + ansiReset)
+			}
+			text := renderInline(cell, "")
+			if i < ruleRow {
+				text = ansiBold + renderInline(cell, ansiBold) + ansiReset
+			}
+			sb.WriteString(text + strings.Repeat(" ", w-displayWidth(cell)))
+		}
+		sb.WriteString("\n")
+	}
+	_, _ = io.WriteString(m.w, sb.String())
+}
+
+// splitTableRow splits a markdown table row into trimmed cells
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// displayWidth returns the number of terminal columns s occupies, counting
+// East Asian wide characters twice and ignoring ANSI sequences
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b {
+			// Skip CSI sequences such as colors
+			j := i + 1
+			if j < len(s) && s[j] == '[' {
+				j++
+				for j < len(s) && (s[j] < '@' || s[j] > '~') {
+					j++
+				}
+			}
+			i = j + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case unicode.Is(unicode.Mn, r) || r < 0x20:
+		case isWideRune(r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303f) || // CJK punctuation
+		(r >= 0xff00 && r <= 0xff60) || // fullwidth forms
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1faff) // emoji
+}
+
+// truncateWidth shortens s to at most width columns, adding an ellipsis
+func truncateWidth(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	var sb strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := 1
+		if isWideRune(r) {
+			rw = 2
+		}
+		if w+rw > width-1 {
+			break
+		}
+		sb.WriteRune(r)
+		w += rw
+	}
+	return sb.String() + "…"
+}
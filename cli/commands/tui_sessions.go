@@ -0,0 +1,403 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+// registerSessionCommands adds the commands that list, switch, fork, diff and
+// search sessions. They change a.sessionKey and a.pendingInput, which the
+// input loop picks up before reading the next line.
+func (a *TUIAgent) registerSessionCommands(r *CommandRegistry) {
+	r.Register(&Command{
+		Name:        "sessions",
+		Usage:       "/sessions [filter]",
+		Description: "List sessions, most recent first",
+		Handler: func(args []string) (string, bool) {
+			return a.handleSessions(args), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "switch",
+		Usage:       "/switch <n|key|branch>",
+		Description: "Switch to a session from /sessions or a branch from /branches",
+		Handler: func(args []string) (string, bool) {
+			return a.handleSwitch(args), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "history",
+		Usage:       "/history [n]",
+		Description: "Show the last n messages of the current session with their numbers",
+		Handler: func(args []string) (string, bool) {
+			return a.handleHistory(args), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "fork",
+		Usage:       "/fork <n> [name]",
+		Description: "Branch before message n and switch to it; a user message is loaded for editing",
+		Handler: func(args []string) (string, bool) {
+			return a.handleFork(args), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "branches",
+		Usage:       "/branches",
+		Description: "Show the branch tree of the current session",
+		Handler: func(args []string) (string, bool) {
+			return a.handleBranches(), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "diff",
+		Usage:       "/diff <branch> [branch]",
+		Description: "Compare two branches (default: against the current session)",
+		Handler: func(args []string) (string, bool) {
+			return a.handleDiff(args), false
+		},
+	})
+	r.Register(&Command{
+		Name:        "search",
+		Usage:       "/search <text>",
+		Description: "Search the messages of all sessions",
+		Handler: func(args []string) (string, bool) {
+			return a.handleSearch(args), false
+		},
+	})
+}
+
+// sessionChoices returns the sessions in the order /sessions numbers them
+func (a *TUIAgent) sessionChoices(filter string) ([]session.SessionMeta, error) {
+	metas, err := a.sessionMgr.ListMeta()
+	if err != nil {
+		return nil, err
+	}
+	var choices []session.SessionMeta
+	for i := len(metas) - 1; i >= 0; i-- {
+		if filter == "" || strings.Contains(metas[i].Key, filter) {
+			choices = append(choices, metas[i])
+		}
+	}
+	return choices, nil
+}
+
+func (a *TUIAgent) handleSessions(args []string) string {
+	choices, err := a.sessionChoices(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Sprintf("Error listing sessions: %v", err)
+	}
+	if len(choices) == 0 {
+		return "No sessions found."
+	}
+
+	var sb strings.Builder
+	for i, meta := range choices {
+		marker := " "
+		if meta.Key == a.sessionKey {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%s %2d. %s  %d message(s), %s", marker, i+1, meta.Key, meta.MessageCount, meta.UpdatedAt.Format("2006-01-02 15:04"))
+		if name, _ := meta.Metadata[session.MetaBranchName].(string); name != "" {
+			line += fmt.Sprintf("  [branch %s of %s]", name, session.BranchParent(meta.Metadata))
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\nUse /switch <n> to continue a session.")
+	return sb.String()
+}
+
+func (a *TUIAgent) handleSwitch(args []string) string {
+	if len(args) != 1 {
+		return "Usage: /switch <n|key|branch>"
+	}
+	key, err := a.resolveSessionRef(args[0])
+	if err != nil {
+		return err.Error()
+	}
+	sess, err := a.sessionMgr.GetOrCreate(key)
+	if err != nil {
+		return fmt.Sprintf("Error loading session %s: %v", key, err)
+	}
+	a.sessionKey = key
+	a.pendingInput = ""
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Switched to %s (%d message(s))", key, len(sess.Messages)))
+	if recent := sess.GetHistory(4); len(recent) > 0 {
+		sb.WriteString("\n" + FormatSessionMessages(recent, len(sess.Messages)-len(recent)))
+	}
+	return sb.String()
+}
+
+// resolveSessionRef accepts a number from /sessions, a session key or the
+// name of a branch in the current tree
+func (a *TUIAgent) resolveSessionRef(ref string) (string, error) {
+	choices, err := a.sessionChoices("")
+	if err != nil {
+		return "", fmt.Errorf("error listing sessions: %v", err)
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(choices) {
+			return "", fmt.Errorf("no session #%d, see /sessions", n)
+		}
+		return choices[n-1].Key, nil
+	}
+	for _, meta := range choices {
+		if meta.Key == ref {
+			return ref, nil
+		}
+	}
+	tree, err := a.sessionMgr.BranchTree(a.sessionKey)
+	if err != nil {
+		return "", fmt.Errorf("no session %q", ref)
+	}
+	node, err := session.ResolveBranch(tree, ref)
+	if err != nil {
+		return "", err
+	}
+	return node.ID, nil
+}
+
+func (a *TUIAgent) handleHistory(args []string) string {
+	limit := 20
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "Usage: /history [n]"
+		}
+		limit = n
+	}
+	sess, err := a.sessionMgr.GetOrCreate(a.sessionKey)
+	if err != nil {
+		return fmt.Sprintf("Error loading session: %v", err)
+	}
+	total := len(sess.Messages)
+	if total == 0 {
+		return "The current session has no messages."
+	}
+	recent := sess.GetHistory(limit)
+	return FormatSessionMessages(recent, total-len(recent))
+}
+
+func (a *TUIAgent) handleFork(args []string) string {
+	if len(args) < 1 {
+		return "Usage: /fork <n> [name] (message numbers are shown by /history)"
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return "Usage: /fork <n> [name] (message numbers are shown by /history)"
+	}
+	sess, err := a.sessionMgr.GetOrCreate(a.sessionKey)
+	if err != nil {
+		return fmt.Sprintf("Error loading session: %v", err)
+	}
+	if n > len(sess.Messages) {
+		return fmt.Sprintf("The current session has %d message(s).", len(sess.Messages))
+	}
+	// The parent must be stored for the branch tree to find it
+	if err := a.sessionMgr.Save(sess); err != nil {
+		return fmt.Sprintf("Error saving session: %v", err)
+	}
+
+	msg := sess.GetHistory(0)[n-1]
+	branch, err := a.sessionMgr.Fork(sess, n-1, strings.Join(args[1:], " "))
+	if err != nil {
+		return fmt.Sprintf("Error creating branch: %v", err)
+	}
+	a.sessionKey = branch.Key
+	a.pendingInput = ""
+
+	result := fmt.Sprintf("Created branch %s (%s) with the %d message(s) before #%d and switched to it.",
+		branch.Key, branch.Metadata[session.MetaBranchName], n-1, n)
+	if msg.Role == "user" {
+		a.pendingInput = msg.Content
+		result += "\nMessage #" + strconv.Itoa(n) + " is loaded in the input line, edit it and press Enter to resend."
+	}
+	return result
+}
+
+func (a *TUIAgent) handleBranches() string {
+	tree, err := a.sessionMgr.BranchTree(a.sessionKey)
+	if err != nil {
+		return "The current session has no saved branches yet."
+	}
+	return FormatBranchTree(tree, a.sessionKey)
+}
+
+func (a *TUIAgent) handleDiff(args []string) string {
+	if len(args) < 1 || len(args) > 2 {
+		return "Usage: /diff <branch> [branch]"
+	}
+	tree, err := a.sessionMgr.BranchTree(a.sessionKey)
+	if err != nil {
+		return fmt.Sprintf("Error loading branches: %v", err)
+	}
+	refs := append([]string{}, args...)
+	if len(refs) == 1 {
+		refs = []string{a.sessionKey, refs[0]}
+	}
+	var ids []string
+	for _, ref := range refs {
+		node, err := session.ResolveBranch(tree, ref)
+		if err != nil {
+			return err.Error()
+		}
+		ids = append(ids, node.ID)
+	}
+	diff, err := tree.CompareSessions(ids[0], ids[1])
+	if err != nil {
+		return fmt.Sprintf("Error comparing branches: %v", err)
+	}
+	return FormatSessionDiff(diff)
+}
+
+func (a *TUIAgent) handleSearch(args []string) string {
+	query := strings.Join(args, " ")
+	if strings.TrimSpace(query) == "" {
+		return "Usage: /search <text>"
+	}
+	hits, err := a.sessionMgr.Search(query, session.SearchOptions{Context: 1, Limit: 20})
+	if err != nil {
+		return fmt.Sprintf("Error searching sessions: %v", err)
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No messages match %q.", query)
+	}
+	return FormatSearchHits(hits, query) + "\n\nUse /switch <key> to open a session."
+}
+
+// FormatSessionMessages 按编号（从 1 开始）逐行显示消息，start 为第一条消息在会话中的下标
+func FormatSessionMessages(messages []session.Message, start int) string {
+	lines := make([]string, 0, len(messages))
+	for i, msg := range messages {
+		lines = append(lines, formatSessionMessage(start+i+1, msg, "  "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatSessionMessage shows one message on a single line
+func formatSessionMessage(number int, msg session.Message, prefix string) string {
+	return formatMessageLine(number, msg, prefix, strings.Join(strings.Fields(msg.Content), " "))
+}
+
+func formatMessageLine(number int, msg session.Message, prefix, text string) string {
+	if len(msg.ToolCalls) > 0 {
+		names := make([]string, 0, len(msg.ToolCalls))
+		for _, tc := range msg.ToolCalls {
+			names = append(names, tc.Name)
+		}
+		calls := "[calls " + strings.Join(names, ", ") + "]"
+		if text == "" {
+			text = calls
+		} else {
+			text += " " + calls
+		}
+	}
+	return fmt.Sprintf("%s#%-3d %-9s %s", prefix, number, msg.Role, truncateWidth(text, 100))
+}
+
+// FormatBranchTree 以树形显示会话的分支，current 标记为 *
+func FormatBranchTree(tree *session.SessionTree, current string) string {
+	root, err := tree.GetRoot()
+	if err != nil {
+		return err.Error()
+	}
+	var sb strings.Builder
+	var walk func(node *session.SessionNode, indent, branch string)
+	walk = func(node *session.SessionNode, indent, branch string) {
+		line := indent + branch + node.ID
+		if node.BranchInfo != nil && node.BranchInfo.Name != "" {
+			line += "  " + node.BranchInfo.Name
+		}
+		details := fmt.Sprintf("%d message(s)", len(node.Session.Messages))
+		if node.BranchInfo != nil && node.BranchInfo.Description != "" {
+			details = node.BranchInfo.Description + ", " + details
+		}
+		line += " (" + details + ")"
+		if node.ID == current {
+			line += " *"
+		}
+		sb.WriteString(line + "\n")
+
+		children, _ := tree.GetChildren(node.ID)
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].CreatedAt.Before(children[j].CreatedAt)
+		})
+		childIndent := indent
+		switch branch {
+		case "├── ":
+			childIndent += "│   "
+		case "└── ":
+			childIndent += "    "
+		}
+		for i, child := range children {
+			if child == nil {
+				continue
+			}
+			connector := "├── "
+			if i == len(children)-1 {
+				connector = "└── "
+			}
+			walk(child, childIndent, connector)
+		}
+	}
+	walk(root, "", "")
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// FormatSessionDiff 显示两个分支在共同前缀之后的消息
+func FormatSessionDiff(diff *session.SessionDiff) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s (%d message(s))\n+++ %s (%d message(s))\n", diff.ID1, diff.Messages1, diff.ID2, diff.Messages2))
+	sb.WriteString(fmt.Sprintf("%d shared message(s)\n", diff.Common))
+	for i, msg := range diff.RemovedContent {
+		sb.WriteString(formatSessionMessage(diff.Common+i+1, msg, "- ") + "\n")
+	}
+	for i, msg := range diff.AddedContent {
+		sb.WriteString(formatSessionMessage(diff.Common+i+1, msg, "+ ") + "\n")
+	}
+	if diff.AddedMessages == 0 && diff.RemovedMessages == 0 {
+		sb.WriteString("The branches have the same messages.\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// FormatSearchHits 显示搜索结果，匹配的消息以 > 标出并显示 query 附近的内容
+func FormatSearchHits(hits []session.SearchHit, query string) string {
+	blocks := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		lines := []string{fmt.Sprintf("%s #%d", hit.Key, hit.Index+1)}
+		for i, msg := range hit.Messages {
+			if hit.Start+i == hit.Index {
+				lines = append(lines, formatMessageLine(hit.Start+i+1, msg, "  > ", matchSnippet(msg.Content, query, 100)))
+				continue
+			}
+			lines = append(lines, formatSessionMessage(hit.Start+i+1, msg, "    "))
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// matchSnippet returns about width columns of s around the first match of
+// query, so the match stays visible in long messages
+func matchSnippet(s, query string, width int) string {
+	text := strings.Join(strings.Fields(s), " ")
+	lower := strings.ToLower(text)
+	at := strings.Index(lower, strings.ToLower(strings.TrimSpace(query)))
+	if at < 0 || displayWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	start := max(utf8.RuneCountInString(lower[:at])-width/3, 0)
+	if start == 0 || start >= len(runes) {
+		return text
+	}
+	return "…" + string(runes[start:])
+}
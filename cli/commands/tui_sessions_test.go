@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestTUISessionCommands(t *testing.T) {
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := mgr.GetOrCreate("tui:1")
+	for _, content := range []string{"first question", "first answer", "second question", "second answer"} {
+		role := "user"
+		if strings.Contains(content, "answer") {
+			role = "assistant"
+		}
+		sess.AddMessage(session.Message{Role: role, Content: content, Timestamp: time.Now()})
+	}
+	_ = mgr.Save(sess)
+
+	a := &TUIAgent{sessionMgr: mgr, sessionKey: "tui:1"}
+
+	// Forking before a user message loads it for editing
+	out := a.handleFork([]string{"3", "retry"})
+	if a.sessionKey != "tui:1~1" || a.pendingInput != "second question" {
+		t.Fatalf("unexpected state after fork: key=%s input=%q (%s)", a.sessionKey, a.pendingInput, out)
+	}
+	branch, _ := mgr.GetOrCreate(a.sessionKey)
+	branch.AddMessage(session.Message{Role: "user", Content: "edited question", Timestamp: time.Now()})
+	_ = mgr.Save(branch)
+
+	if out := a.handleBranches(); !strings.Contains(out, "└── tui:1~1  retry (forked after message 2, 3 message(s)) *") {
+		t.Errorf("unexpected tree:\n%s", out)
+	}
+	out = a.handleDiff([]string{"main"})
+	for _, want := range []string{"2 shared message(s)", "- #3   user      edited question", "+ #3   user      second question"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in diff:\n%s", want, out)
+		}
+	}
+
+	if out := a.handleSwitch([]string{"main"}); a.sessionKey != "tui:1" || !strings.Contains(out, "Switched to tui:1") {
+		t.Errorf("unexpected switch result %q", out)
+	}
+	if out := a.handleSwitch([]string{"9"}); a.sessionKey != "tui:1" || !strings.Contains(out, "no session #9") {
+		t.Errorf("expected an unknown number to be refused, got %q", out)
+	}
+
+	out = a.handleSearch([]string{"EDITED"})
+	if !strings.Contains(out, "tui:1~1 #3") || !strings.Contains(out, "  > #3   user      edited question") {
+		t.Errorf("unexpected search result:\n%s", out)
+	}
+}
+
+func TestMatchSnippet(t *testing.T) {
+	long := strings.Repeat("word ", 60) + "needle " + strings.Repeat("tail ", 10)
+	snippet := matchSnippet(long, "NEEDLE", 60)
+	if !strings.HasPrefix(snippet, "…") || !strings.Contains(truncateWidth(snippet, 60), "needle") {
+		t.Errorf("expected the match to stay visible, got %q", snippet)
+	}
+	if got := matchSnippet("short   text", "text", 60); got != "short text" {
+		t.Errorf("unexpected snippet %q", got)
+	}
+}
@@ -0,0 +1,377 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/cli/input"
+	"github.com/smallnest/goclaw/session"
+)
+
+// tuiEventBuffer is large enough that stream events are not dropped while the
+// terminal is busy
+const tuiEventBuffer = 1000
+
+// toolActivity 一次工具调用的记录，TUI 中折叠为一行，/expand 查看详情
+type toolActivity struct {
+	ID       string
+	Name     string
+	Args     map[string]any
+	Start    time.Time
+	Duration time.Duration
+	Done     bool
+	Failed   bool
+	Output   string
+}
+
+// turnRenderer draws the orchestrator events of one turn: streamed assistant
+// text as markdown, thinking when enabled, and one status line per tool call
+// that is updated in place while the tool runs
+type turnRenderer struct {
+	w        io.Writer
+	ansi     bool
+	width    int
+	thinking bool
+	now      func() time.Time
+
+	md         *markdownStream
+	tools      []*toolActivity
+	running    *toolActivity // tool whose status line is the current line
+	streamed   bool          // assistant text arrived as stream events
+	inThinking bool
+}
+
+func newTurnRenderer(w io.Writer, ansi bool, width int, thinking bool) *turnRenderer {
+	return &turnRenderer{
+		w:        w,
+		ansi:     ansi,
+		width:    width,
+		thinking: thinking,
+		now:      time.Now,
+		md:       newMarkdownStream(w, ansi, width),
+	}
+}
+
+// handle renders one event
+func (r *turnRenderer) handle(event *agent.Event) {
+	switch event.Type {
+	case agent.EventStreamContent, agent.EventStreamFinal:
+		r.endThinking()
+		r.md.Write(event.StreamContent)
+		r.streamed = true
+
+	case agent.EventStreamThinking:
+		if !r.thinking {
+			return
+		}
+		if !r.inThinking {
+			r.inThinking = true
+			r.write(r.style(ansiDim, "💭 "))
+		}
+		r.write(r.style(ansiDim, event.StreamContent))
+
+	case agent.EventThinking:
+		if r.thinking && event.Thinking != "" {
+			r.write(r.style(ansiDim, "💭 "+event.Thinking) + "\n")
+		}
+
+	case agent.EventMessageEnd:
+		r.endThinking()
+		r.md.Flush()
+
+	case agent.EventToolExecutionStart:
+		r.endThinking()
+		r.md.Flush()
+		r.endRunning()
+		act := &toolActivity{ID: event.ToolID, Name: event.ToolName, Args: event.ToolArgs, Start: r.now()}
+		r.tools = append(r.tools, act)
+		if r.ansi {
+			r.running = act
+			r.write(r.toolLine(act))
+		} else {
+			r.write(r.toolLine(act) + "\n")
+		}
+
+	case agent.EventToolExecutionEnd:
+		act := r.findTool(event.ToolID)
+		if act == nil {
+			act = &toolActivity{ID: event.ToolID, Name: event.ToolName, Args: event.ToolArgs, Start: r.now()}
+			r.tools = append(r.tools, act)
+		}
+		act.Done = true
+		act.Failed = event.ToolError
+		act.Duration = r.now().Sub(act.Start)
+		if event.ToolResult != nil {
+			act.Output = toolResultText(event.ToolResult)
+		}
+		if r.running == act {
+			r.write(ansiClearLine + r.toolLine(act) + "\n")
+			r.running = nil
+		} else {
+			r.endRunning()
+			r.write(r.toolLine(act) + "\n")
+		}
+	}
+}
+
+// tick refreshes the elapsed time of the running tool
+func (r *turnRenderer) tick() {
+	if r.running != nil {
+		r.write(ansiClearLine + r.toolLine(r.running))
+	}
+}
+
+// finish completes any open output at the end of the turn
+func (r *turnRenderer) finish() {
+	r.endThinking()
+	r.md.Flush()
+	r.endRunning()
+	if len(r.tools) > 0 {
+		r.write(r.style(ansiDim, fmt.Sprintf("  %d tool call(s), /expand to show details", len(r.tools))) + "\n")
+	}
+}
+
+func (r *turnRenderer) endThinking() {
+	if r.inThinking {
+		r.inThinking = false
+		r.write("\n")
+	}
+}
+
+// endRunning moves past a status line that is still being updated
+func (r *turnRenderer) endRunning() {
+	if r.running != nil {
+		r.write("\n")
+		r.running = nil
+	}
+}
+
+func (r *turnRenderer) findTool(id string) *toolActivity {
+	for i := len(r.tools) - 1; i >= 0; i-- {
+		if r.tools[i].ID == id && !r.tools[i].Done {
+			return r.tools[i]
+		}
+	}
+	return nil
+}
+
+// toolLine formats the collapsed status line of a tool call
+func (r *turnRenderer) toolLine(act *toolActivity) string {
+	icon, color := "⏳", ansiYellow
+	elapsed := r.now().Sub(act.Start)
+	switch {
+	case act.Done && act.Failed:
+		icon, color = "✗", ansiRed
+		elapsed = act.Duration
+	case act.Done:
+		icon, color = "✓", ansiGreen
+		elapsed = act.Duration
+	}
+	duration := fmt.Sprintf("%.1fs", elapsed.Seconds())
+
+	// Keep the line on one terminal row so it can be redrawn in place
+	room := r.width - 1 - displayWidth(act.Name) - displayWidth(duration) - 8
+	summary := ""
+	if room > 10 {
+		summary = truncateWidth(toolArgsSummary(act.Args), room)
+	}
+	return "  " + r.style(color, icon) + " " + r.style(ansiBold, act.Name) + " " + summary + " " + r.style(ansiDim, duration)
+}
+
+func (r *turnRenderer) style(style, s string) string {
+	if !r.ansi {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+func (r *turnRenderer) write(s string) {
+	_, _ = io.WriteString(r.w, s)
+}
+
+// toolArgsSummary shows a single string argument as is and anything else as
+// compact JSON, on one line
+func toolArgsSummary(args map[string]any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if len(args) == 1 {
+		for _, v := range args {
+			if s, ok := v.(string); ok {
+				return strings.Join(strings.Fields(s), " ")
+			}
+		}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// toolResultText joins the text blocks of a tool result
+func toolResultText(result *agent.ToolResult) string {
+	var parts []string
+	for _, block := range result.Content {
+		if text, ok := block.(agent.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	if len(parts) == 0 && result.Error != nil {
+		return result.Error.Error()
+	}
+	return strings.Join(parts, "\n")
+}
+
+// formatToolActivities renders the expanded view of the tool calls of the
+// last turn. index selects one call (1-based) and shows its full output.
+func formatToolActivities(acts []*toolActivity, index int) string {
+	if len(acts) == 0 {
+		return "No tool calls in the last turn."
+	}
+	if index > len(acts) {
+		return fmt.Sprintf("The last turn had %d tool call(s).", len(acts))
+	}
+
+	const previewLimit = 2000
+	var sb strings.Builder
+	for i, act := range acts {
+		if index > 0 && i+1 != index {
+			continue
+		}
+		status := "ok"
+		switch {
+		case !act.Done:
+			status = "interrupted"
+		case act.Failed:
+			status = "error"
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s [%s, %.1fs]\n", i+1, act.Name, status, act.Duration.Seconds()))
+		if len(act.Args) > 0 {
+			keys := make([]string, 0, len(act.Args))
+			for k := range act.Args {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				value, _ := json.Marshal(act.Args[k])
+				sb.WriteString(fmt.Sprintf("   %s: %s\n", k, value))
+			}
+		}
+		output := act.Output
+		if index == 0 && len(output) > previewLimit {
+			output = output[:previewLimit] + fmt.Sprintf("\n... (%d more bytes, /expand %d for all)", len(act.Output)-previewLimit, i+1)
+		}
+		if output != "" {
+			sb.WriteString("   output:\n")
+			for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+				sb.WriteString("   │ " + line + "\n")
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// cancelOnInterrupt cancels the current turn on Ctrl+C instead of exiting
+// the process. The returned function restores the default handling.
+func cancelOnInterrupt(cancel context.CancelFunc) func() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+// streamTUIDialogue runs one turn like processTUIDialogue while rendering the
+// orchestrator events as they arrive. The final response is printed only when
+// it was not already streamed.
+func streamTUIDialogue(ctx context.Context, sess *session.Session, tuiAgent *TUIAgent, historyLimit int) {
+	events := tuiAgent.SubscribeWithBuffer(tuiEventBuffer)
+	defer tuiAgent.Unsubscribe(events)
+
+	fd := int(os.Stdout.Fd())
+	ansi := input.IsTerminal(fd)
+	r := newTurnRenderer(os.Stdout, ansi, input.TerminalWidth(fd), tuiThinking)
+
+	type outcome struct {
+		response string
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		response, err := processTUIDialogue(ctx, sess, tuiAgent.GetOrchestrator(), historyLimit)
+		done <- outcome{response, err}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var result outcome
+wait:
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			r.handle(event)
+		case <-ticker.C:
+			r.tick()
+		case result = <-done:
+			break wait
+		}
+	}
+
+	// Events emitted before Run returned may still be passing through the
+	// agent's dispatcher
+	grace := time.After(200 * time.Millisecond)
+drain:
+	for events != nil {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				break drain
+			}
+			r.handle(event)
+			if event.Type == agent.EventAgentEnd {
+				break drain
+			}
+		case <-grace:
+			break drain
+		}
+	}
+
+	if !r.streamed && result.response != "" {
+		r.md.Write(result.response)
+	}
+	r.finish()
+	tuiAgent.lastTurnTools = r.tools
+
+	switch {
+	case result.err == nil:
+	case ctx.Err() == context.Canceled:
+		fmt.Println(r.style(ansiYellow, "⏹ Turn cancelled"))
+	case ctx.Err() == context.DeadlineExceeded:
+		fmt.Println(r.style(ansiRed, fmt.Sprintf("Error: turn timed out after %d ms", tuiTimeoutMs)))
+	default:
+		fmt.Println(r.style(ansiRed, fmt.Sprintf("Error: %v", result.err)))
+	}
+}
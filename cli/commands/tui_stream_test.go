@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+)
+
+func TestMarkdownStreamPassthrough(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMarkdownStream(&buf, false, 80)
+	m.Write("# Title\n**bo")
+	m.Write("ld** text")
+	m.Flush()
+
+	if got := buf.String(); got != "# Title\n**bold** text\n" {
+		t.Errorf("expected text to pass through unchanged, got %q", got)
+	}
+}
+
+func TestMarkdownStreamRender(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMarkdownStream(&buf, true, 80)
+	m.Write("# Ti")
+	if got := buf.String(); got != "# Ti" {
+		t.Fatalf("expected the partial line to be echoed, got %q", got)
+	}
+	m.Write("tle\n- item `code`\n")
+	m.Write("```go\nx := 1\n```\n")
+	m.Flush()
+
+	out := buf.String()
+	for _, want := range []string{
+		ansiClearLine + ansiBold + ansiUnderline + "Title" + ansiReset + "\n",
+		"• item " + ansiCyan + "code" + ansiReset + "\n",
+		ansiCyan + "  x := 1" + ansiReset + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
+
+func TestMarkdownStreamTable(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMarkdownStream(&buf, true, 80)
+	m.Write("| name | 值 |\n|---|---|\n| a | 中文 |\n")
+	if buf.Len() != 0 {
+		t.Fatalf("expected the table to be buffered, got %q", buf.String())
+	}
+	m.Write("done\n")
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		lines = append(lines, stripANSI(line))
+	}
+	want := []string{"name │ 值  ", "─────┼─────", "a    │ 中文", "done"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected table:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	cases := map[string]int{
+		"abc":                       3,
+		"中文":                        4,
+		ansiBold + "ab" + ansiReset: 2,
+		"👍 ok":                      5,
+	}
+	for s, want := range cases {
+		if got := displayWidth(s); got != want {
+			t.Errorf("displayWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+	if got := truncateWidth("中文字符", 5); got != "中文…" {
+		t.Errorf("unexpected truncation %q", got)
+	}
+}
+
+func TestTurnRendererToolLines(t *testing.T) {
+	var buf bytes.Buffer
+	r := newTurnRenderer(&buf, false, 80, false)
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	r.handle(&agent.Event{Type: agent.EventStreamContent, StreamContent: "Let me check."})
+	r.handle(&agent.Event{Type: agent.EventToolExecutionStart, ToolID: "1", ToolName: "read_file", ToolArgs: map[string]any{"path": "/tmp/a.txt"}})
+	now = now.Add(1500 * time.Millisecond)
+	r.handle(&agent.Event{Type: agent.EventToolExecutionEnd, ToolID: "1", ToolName: "read_file",
+		ToolResult: &agent.ToolResult{Content: []agent.ContentBlock{agent.TextContent{Text: "hello"}}}})
+	r.handle(&agent.Event{Type: agent.EventToolExecutionStart, ToolID: "2", ToolName: "exec", ToolArgs: map[string]any{"command": "false"}})
+	now = now.Add(200 * time.Millisecond)
+	r.handle(&agent.Event{Type: agent.EventToolExecutionEnd, ToolID: "2", ToolName: "exec", ToolError: true})
+	r.finish()
+
+	want := "Let me check.\n" +
+		"  ⏳ read_file /tmp/a.txt 0.0s\n" +
+		"  ✓ read_file /tmp/a.txt 1.5s\n" +
+		"  ⏳ exec false 0.0s\n" +
+		"  ✗ exec false 0.2s\n" +
+		"  2 tool call(s), /expand to show details\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+
+	expanded := formatToolActivities(r.tools, 0)
+	for _, want := range []string{"#1 read_file [ok, 1.5s]", `path: "/tmp/a.txt"`, "│ hello", "#2 exec [error, 0.2s]"} {
+		if !strings.Contains(expanded, want) {
+			t.Errorf("expected %q in %q", want, expanded)
+		}
+	}
+	if one := formatToolActivities(r.tools, 2); strings.Contains(one, "read_file") {
+		t.Errorf("expected only the second call, got %q", one)
+	}
+}
+
+func TestTurnRendererRedrawsRunningTool(t *testing.T) {
+	var buf bytes.Buffer
+	r := newTurnRenderer(&buf, true, 80, false)
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	r.handle(&agent.Event{Type: agent.EventToolExecutionStart, ToolID: "1", ToolName: "web_fetch"})
+	now = now.Add(2 * time.Second)
+	r.tick()
+	now = now.Add(time.Second)
+	r.handle(&agent.Event{Type: agent.EventToolExecutionEnd, ToolID: "1", ToolName: "web_fetch"})
+
+	lines := strings.Split(buf.String(), ansiClearLine)
+	if len(lines) != 3 {
+		t.Fatalf("expected the status line to be redrawn twice, got %q", buf.String())
+	}
+	if got := stripANSI(lines[1]); !strings.Contains(got, "⏳ web_fetch") || !strings.Contains(got, "2.0s") {
+		t.Errorf("unexpected running line %q", got)
+	}
+	if got := stripANSI(lines[2]); !strings.Contains(got, "✓ web_fetch") || !strings.Contains(got, "3.0s") {
+		t.Errorf("unexpected final line %q", got)
+	}
+}
+
+// stripANSI removes the styles so rendered text can be compared
+func stripANSI(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b {
+			for i < len(s) && !(s[i] >= '@' && s[i] <= '~' && s[i] != '[') {
+				i++
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/eval"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/spf13/cobra"
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Run agent evaluation suites",
+	Long:  `Regression-test agent behaviour end-to-end against recorded provider responses.`,
+}
+
+var evalRunCmd = &cobra.Command{
+	Use:   "run <suite>",
+	Short: "Run an evaluation suite",
+	Long: `Run the scenarios of a YAML suite. Each scenario runs one agent turn in a fresh
+workspace and checks the final text, the tools called and the files left behind.
+
+By default provider responses are replayed from fixtures next to the suite
+(fixtures/<suite>/<scenario>.json), so no LLM is needed. A request that does not
+match any recording fails the scenario: the prompt, tools or scenario changed
+since the fixture was recorded. Use --record to call the configured provider
+and rewrite the fixtures.
+
+<suite> is a path to a suite file or the name of a suite in --dir.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runEval,
+}
+
+// Flags for eval run
+var (
+	evalDir      string
+	evalFixtures string
+	evalRecord   bool
+	evalScenario string
+	evalJUnit    string
+	evalJSON     bool
+	evalTimeout  int
+)
+
+func init() {
+	evalRunCmd.Flags().StringVar(&evalDir, "dir", "evals", "Directory to look up suites by name")
+	evalRunCmd.Flags().StringVar(&evalFixtures, "fixtures", "", "Fixture directory (default: fixtures next to the suite)")
+	evalRunCmd.Flags().BoolVar(&evalRecord, "record", false, "Call the configured provider and record fixtures")
+	evalRunCmd.Flags().StringVar(&evalScenario, "scenario", "", "Run only the named scenario")
+	evalRunCmd.Flags().StringVar(&evalJUnit, "junit", "", "Write a JUnit XML report to this file")
+	evalRunCmd.Flags().BoolVarP(&evalJSON, "json", "j", false, "Output results in JSON format")
+	evalRunCmd.Flags().IntVar(&evalTimeout, "timeout", 300, "Timeout per scenario in seconds")
+
+	evalCmd.AddCommand(evalRunCmd)
+}
+
+// runEval runs a suite and exits non-zero when a scenario fails
+func runEval(cmd *cobra.Command, args []string) {
+	suite, err := eval.LoadSuite(args[0], evalDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		if evalRecord {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+		cfg = &config.Config{}
+	}
+
+	opts := eval.Options{
+		Record:     evalRecord,
+		FixtureDir: evalFixtures,
+		Scenario:   evalScenario,
+		Timeout:    time.Duration(evalTimeout) * time.Second,
+		Tools:      evalTools(cfg),
+	}
+	if evalRecord {
+		provider, err := providers.NewProvider(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create LLM provider: %v\n", err)
+			os.Exit(1)
+		}
+		defer provider.Close()
+		opts.Provider = provider
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := eval.NewRunner(opts).Run(ctx, suite)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if evalJUnit != "" {
+		file, err := os.Create(evalJUnit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+		if err := eval.WriteJUnit(file, result); err != nil {
+			file.Close()
+			fmt.Fprintf(os.Stderr, "Failed to write JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+		file.Close()
+	}
+
+	if evalJSON {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printEvalResult(result)
+	}
+
+	if result.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+// evalTools builds the file system and shell tools for a scenario workspace.
+// Approvals are not interactive so recordings never wait on the terminal.
+func evalTools(cfg *config.Config) eval.ToolFactory {
+	return func(workspace string) ([]tools.Tool, func(), error) {
+		toolList := tools.NewFileSystemTool(nil, cfg.Tools.FileSystem.DeniedPaths, workspace).GetTools()
+
+		shellTool := tools.NewShellTool(
+			cfg.Tools.Shell.Enabled,
+			cfg.Tools.Shell.AllowedCmds,
+			cfg.Tools.Shell.DeniedCmds,
+			cfg.Tools.Shell.Timeout,
+			workspace,
+			config.SandboxConfig{},
+		)
+		if err := configureShellPolicy(shellTool, cfg, nil); err != nil {
+			return nil, nil, err
+		}
+		toolList = append(toolList, shellTool.GetTools()...)
+		return toolList, func() { _ = shellTool.Close() }, nil
+	}
+}
+
+// printEvalResult prints one line per scenario and the failed assertions
+func printEvalResult(result *eval.SuiteResult) {
+	fmt.Printf("Suite %s\n", result.Name)
+	for _, sc := range result.Scenarios {
+		status := "PASS"
+		if !sc.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("  %s  %s (%.2fs)\n", status, sc.Name, sc.Duration.Seconds())
+		if sc.Error != "" {
+			fmt.Printf("        error: %s\n", sc.Error)
+		}
+		for _, failure := range sc.Failures {
+			fmt.Printf("        - %s\n", failure)
+		}
+		if !sc.Passed && sc.FinalText != "" {
+			fmt.Printf("        final: %s\n", strings.ReplaceAll(truncateString(sc.FinalText, 200), "\n", " "))
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", len(result.Scenarios)-result.Failed(), result.Failed())
+}
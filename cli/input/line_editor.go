@@ -101,6 +101,18 @@ func (e *lineEditor) SaveToHistory(line string) {
 	e.histPos = len(e.history)
 }
 
+// SetInput 预填下一次 ReadLine 的输入内容，多行内容作为粘贴块
+func (e *lineEditor) SetInput(text string) {
+	e.pendingPaste = ""
+	if strings.Contains(text, "\n") {
+		e.pendingPaste = text
+		e.buf = []rune(e.pastePlaceholder())
+	} else {
+		e.buf = []rune(text)
+	}
+	e.cursor = len(e.buf)
+}
+
 func (e *lineEditor) Refresh() {
 	e.render()
 }
@@ -409,3 +421,19 @@ func restoreTerminal(fd int, state *unix.Termios) error {
 	_, setReq := termiosRequests()
 	return unix.IoctlSetTermios(fd, setReq, state)
 }
+
+// IsTerminal 判断文件描述符是否为终端
+func IsTerminal(fd int) bool {
+	getReq, _ := termiosRequests()
+	_, err := unix.IoctlGetTermios(fd, getReq)
+	return err == nil
+}
+
+// TerminalWidth 返回终端列数，无法获取时返回 80
+func TerminalWidth(fd int) int {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 {
+		return 80
+	}
+	return int(ws.Col)
+}
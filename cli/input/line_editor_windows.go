@@ -33,6 +33,8 @@ func (e *lineEditor) InitHistory(history []string) {}
 
 func (e *lineEditor) SaveToHistory(line string) {}
 
+func (e *lineEditor) SetInput(text string) {}
+
 func (e *lineEditor) Refresh() {}
 
 func (e *lineEditor) ReadLine() (string, error) {
@@ -40,3 +42,13 @@ func (e *lineEditor) ReadLine() (string, error) {
 }
 
 func (e *lineEditor) ClearCurrentLine() {}
+
+// IsTerminal 判断文件描述符是否为终端
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// TerminalWidth 返回终端列数，无法获取时返回 80
+func TerminalWidth(fd int) int {
+	return 80
+}
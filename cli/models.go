@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Inspect available models",
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List models discovered on the local model server (Ollama or OpenAI-compatible)",
+	Run:   runModelsList,
+}
+
+var (
+	modelsListJSON    bool
+	modelsListBaseURL string
+)
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+	modelsCmd.AddCommand(modelsListCmd)
+
+	modelsListCmd.Flags().BoolVar(&modelsListJSON, "json", false, "Output in JSON format")
+	modelsListCmd.Flags().StringVar(&modelsListBaseURL, "base-url", "", "Local model server URL (overrides providers.local.base_url)")
+}
+
+// runModelsList handles the models list command
+func runModelsList(cmd *cobra.Command, args []string) {
+	localCfg := config.LocalProviderConfig{}
+	if cfg, err := config.Load(""); err == nil {
+		localCfg = cfg.Providers.Local
+	}
+	if modelsListBaseURL != "" {
+		localCfg.BaseURL = modelsListBaseURL
+	}
+
+	provider, err := providers.NewLocalProviderFromConfig(localCfg, "", 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	models, err := provider.ListModels(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if modelsListJSON {
+		data, _ := json.MarshalIndent(models, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(models) == 0 {
+		fmt.Println("No local models found")
+		return
+	}
+	for _, model := range models {
+		marker := " "
+		if model == localCfg.Model {
+			marker = "*"
+		}
+		fmt.Printf("%s ollama:%s\n", marker, model)
+	}
+}
@@ -11,17 +11,18 @@ import (
 )
 
 // Supported channels for pairing
-var supportedChannels = []string{"telegram", "whatsapp", "signal", "imessage", "discord", "slack", "feishu"}
+var supportedChannels = []string{"telegram", "whatsapp", "feishu", "dingtalk", "qq", "wework", "discord", "slack", "googlechat", "teams"}
 
 var pairingCmd = &cobra.Command{
 	Use:   "pairing",
 	Short: "Manage DM pairing for channel access control",
 	Long: `Manage DM (direct message) pairing for controlling who can send messages to your bot.
 
-Pairing is used when a channel's dmPolicy is set to "pairing". Unknown senders
+Pairing is used when a channel's dm_policy is set to "pairing". Unknown senders
 will receive a pairing code and must be approved before their messages are processed.
+Approved senders are also accepted under the "allowlist" policy.
 
-Supported channels: telegram, whatsapp, signal, imessage, discord, slack, feishu`,
+Supported channels: telegram, whatsapp, feishu, dingtalk, qq, wework, discord, slack, googlechat, teams`,
 }
 
 var pairingListCmd = &cobra.Command{
@@ -17,11 +17,13 @@ import (
 	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/cron"
 	"github.com/smallnest/goclaw/gateway"
+	"github.com/smallnest/goclaw/heartbeat"
 	"github.com/smallnest/goclaw/internal"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/internal/workspace"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
+	"github.com/smallnest/goclaw/telemetry"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -92,6 +94,7 @@ func init() {
 	rootCmd.AddCommand(agentCmd)
 	rootCmd.AddCommand(sessionsCmd)
 	rootCmd.AddCommand(onboardCmd)
+	rootCmd.AddCommand(evalCmd)
 
 	// Register memory and logs commands from commands package
 	// Note: skills command is already registered in cli/skills.go
@@ -100,7 +103,10 @@ func init() {
 
 	// Register browser, tui, gateway, health, status commands
 	rootCmd.AddCommand(commands.BrowserCommand())
-	rootCmd.AddCommand(commands.TUICommand())
+	// TUI 轮次运行时行编辑器处于暂停状态，需要审批的命令在终端中询问
+	rootCmd.AddCommand(commands.TUICommand(func(shellTool *tools.ShellTool, cfg *config.Config) error {
+		return configureShellPolicy(shellTool, cfg, terminalPrompt())
+	}))
 	rootCmd.AddCommand(commands.GatewayCommand())
 	rootCmd.AddCommand(commands.HealthCommand())
 	rootCmd.AddCommand(commands.StatusCommand())
@@ -150,7 +156,7 @@ func runStart(cmd *cobra.Command, args []string) {
 	}
 
 	// 初始化日志
-	if err := logger.Init(logLevel, false); err != nil {
+	if err := logger.InitWithOptions(logLevel, false, commands.GatewayLogOptions(cfg)); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
@@ -180,17 +186,31 @@ func runStart(cmd *cobra.Command, args []string) {
 	// 创建消息总线
 	messageBus := bus.NewMessageBus(100)
 	defer messageBus.Close()
+	telemetry.RegisterQueue("inbound", messageBus.InboundCount)
+	telemetry.RegisterQueue("outbound", messageBus.OutboundCount)
+
+	// 初始化 OpenTelemetry 追踪
+	shutdownTracing, err := telemetry.SetupTracing(cfg.Telemetry.Tracing)
+	if err != nil {
+		logger.Warn("Failed to set up tracing", zap.Error(err))
+	} else {
+		defer shutdownTracing(context.Background()) // nolint:errcheck
+	}
 
 	// 创建会话管理器
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		logger.Fatal("Failed to get home directory", zap.Error(err))
 	}
-	sessionDir := homeDir + "/.goclaw/sessions"
-	sessionMgr, err := session.NewManager(sessionDir)
+	sessionDir, err := config.GetSessionsPath(cfg)
+	if err != nil {
+		logger.Fatal("Failed to get sessions directory", zap.Error(err))
+	}
+	sessionMgr, err := session.Open(cfg.Sessions.Backend, sessionDir)
 	if err != nil {
 		logger.Fatal("Failed to create session manager", zap.Error(err))
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	// 创建记忆存储
 	memoryStore := agent.NewMemoryStore(workspaceDir)
@@ -247,11 +267,28 @@ func runStart(cmd *cobra.Command, args []string) {
 		cfg.Tools.Shell.WorkingDir,
 		cfg.Tools.Shell.Sandbox,
 	)
+	// "prompt" 审批进入审批队列，通过网关 approvals.resolve 放行或拒绝
+	approvalQueue := tools.NewApprovalQueue()
+	if err := configureShellPolicy(shellTool, cfg, approvalQueue.RequestApproval); err != nil {
+		logger.Warn("Invalid shell policy, using defaults", zap.Error(err))
+	}
 	for _, tool := range shellTool.GetTools() {
 		if err := toolRegistry.RegisterExisting(tool); err != nil {
 			logger.Warn("Failed to register tool", zap.String("tool", tool.Name()))
 		}
 	}
+	defer func() { _ = shellTool.Close() }()
+	// 会话结束时清理该会话的后台进程
+	sessionMgr.OnDelete(shellTool.CleanupSession)
+
+	// 注册 skill_run 工具（按技能声明的权限在沙箱或受限本地环境中运行技能入口）
+	if cfg.Tools.Shell.Enabled {
+		skillRunTool := tools.NewSkillRunTool(agent.NewSkillEntrypointResolver(skillsLoader, workspaceDir), cfg.Tools.Shell.Sandbox)
+		if err := toolRegistry.RegisterExisting(skillRunTool); err != nil {
+			logger.Warn("Failed to register skill_run tool", zap.Error(err))
+		}
+		defer func() { _ = skillRunTool.Close() }()
+	}
 
 	// 注册 Web 工具
 	webTool := tools.NewWebTool(
@@ -267,15 +304,15 @@ func runStart(cmd *cobra.Command, args []string) {
 
 	// 注册浏览器工具（如果启用）
 	if cfg.Tools.Browser.Enabled {
-		browserTool := tools.NewBrowserTool(
-			cfg.Tools.Browser.Headless,
-			cfg.Tools.Browser.Timeout,
-		)
+		browserTool := tools.NewBrowserToolWithConfig(cfg.Tools.Browser)
 		for _, tool := range browserTool.GetTools() {
 			if err := toolRegistry.RegisterExisting(tool); err != nil {
 				logger.Warn("Failed to register tool", zap.String("tool", tool.Name()))
 			}
 		}
+		defer func() { _ = browserTool.Close() }()
+		// 会话结束时关闭该会话的浏览器上下文
+		sessionMgr.OnDelete(browserTool.CleanupSession)
 		logger.Info("Browser tools registered")
 	}
 
@@ -390,12 +427,39 @@ func runStart(cmd *cobra.Command, args []string) {
 		ChannelMgr:     channelMgr,
 		AcpManager:     acpMgr,
 	})
+	gatewayServer.SetAgentRunner(agentManager)
+	gatewayServer.SetApprovalQueue(approvalQueue)
 
 	// 从配置设置 Agent 和绑定
 	if err := agentManager.SetupFromConfig(cfg, contextBuilder); err != nil {
 		logger.Fatal("Failed to setup agent manager", zap.Error(err))
 	}
 
+	// 创建心跳运行器，按 HEARTBEAT.md 定期唤醒 Agent，next-heartbeat 的 Cron 任务随心跳处理
+	heartbeatStatePath, err := heartbeat.DefaultStatePath()
+	if err != nil {
+		logger.Warn("Failed to get heartbeat state path", zap.Error(err))
+	}
+	heartbeatRunner := heartbeat.NewRunner(agentManager, messageBus, heartbeat.SettingsFromConfig(cfg, workspaceDir), heartbeatStatePath)
+	gatewayServer.SetHeartbeatRunner(heartbeatRunner)
+	if cronService != nil {
+		cronService.SetSystemEventSink(heartbeatRunner)
+	}
+	heartbeatRunner.Start(ctx)
+	defer heartbeatRunner.Stop()
+
+	// 监听技能目录，修改 SKILL.md 后无需重启网关
+	skillsLoader.OnChange(func(changes *agent.SkillChanges) {
+		gatewayServer.BroadcastEvent("skills.changed", gateway.ScopeRead, changes)
+	})
+	if skillsWatcher, err := agent.NewSkillsWatcher(skillsLoader, 0); err != nil {
+		logger.Warn("Failed to create skills watcher", zap.Error(err))
+	} else if err := skillsWatcher.Start(ctx); err != nil {
+		logger.Warn("Failed to start skills watcher", zap.Error(err))
+	} else {
+		defer func() { _ = skillsWatcher.Stop() }()
+	}
+
 	// 处理信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/spf13/cobra"
+)
+
+var sandboxCmd = &cobra.Command{
+	Use:   "sandbox",
+	Short: "Manage Docker sandbox containers",
+}
+
+var sandboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sandbox containers",
+	Run:   runSandboxList,
+}
+
+var sandboxPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stopped or old sandbox containers",
+	Run:   runSandboxPrune,
+}
+
+var (
+	sandboxListJSON    bool
+	sandboxPruneAll    bool
+	sandboxPruneOlder  time.Duration
+	sandboxPruneDryRun bool
+)
+
+func init() {
+	rootCmd.AddCommand(sandboxCmd)
+	sandboxCmd.AddCommand(sandboxListCmd)
+	sandboxCmd.AddCommand(sandboxPruneCmd)
+
+	sandboxListCmd.Flags().BoolVar(&sandboxListJSON, "json", false, "Output in JSON format")
+	sandboxPruneCmd.Flags().BoolVar(&sandboxPruneAll, "all", false, "Remove all sandbox containers, including running ones")
+	sandboxPruneCmd.Flags().DurationVar(&sandboxPruneOlder, "older-than", 0, "Also remove running containers created before this duration (e.g. 24h)")
+	sandboxPruneCmd.Flags().BoolVar(&sandboxPruneDryRun, "dry-run", false, "Only show what would be removed")
+}
+
+// newSandboxDockerClient 创建 Docker 客户端
+func newSandboxDockerClient() *client.Client {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to Docker: %v\n", err)
+		os.Exit(1)
+	}
+	return cli
+}
+
+// runSandboxList handles the sandbox list command
+func runSandboxList(cmd *cobra.Command, args []string) {
+	cli := newSandboxDockerClient()
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	infos, err := tools.ListSandboxContainers(ctx, cli)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if sandboxListJSON {
+		data, _ := json.MarshalIndent(infos, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No sandbox containers found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPE\tIMAGE\tSTATE\tCREATED")
+	for _, info := range infos {
+		scope := info.Scope
+		if scope == "" {
+			scope = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			info.Name, scope, info.Image, info.State, info.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	_ = w.Flush()
+}
+
+// runSandboxPrune handles the sandbox prune command
+func runSandboxPrune(cmd *cobra.Command, args []string) {
+	cli := newSandboxDockerClient()
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	infos, err := tools.ListSandboxContainers(ctx, cli)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	removed := 0
+	for _, info := range infos {
+		if !shouldPruneSandbox(info, sandboxPruneAll, sandboxPruneOlder, time.Now()) {
+			continue
+		}
+		if sandboxPruneDryRun {
+			fmt.Printf("Would remove %s (%s)\n", info.Name, info.State)
+			removed++
+			continue
+		}
+		if err := cli.ContainerRemove(ctx, info.ID, container.RemoveOptions{Force: true}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove %s: %v\n", info.Name, err)
+			continue
+		}
+		fmt.Printf("Removed %s\n", info.Name)
+		removed++
+	}
+
+	fmt.Printf("%d sandbox container(s) pruned\n", removed)
+}
+
+// shouldPruneSandbox 判断容器是否应被清理
+// 默认只清理未运行的容器；--older-than 额外清理创建时间过早的容器；--all 清理全部
+func shouldPruneSandbox(info tools.SandboxInfo, all bool, olderThan time.Duration, now time.Time) bool {
+	if all {
+		return true
+	}
+	if info.State != "running" {
+		return true
+	}
+	return olderThan > 0 && now.Sub(info.CreatedAt) > olderThan
+}
@@ -2,14 +2,16 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/smallnest/goclaw/cli/commands"
+	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/session"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +28,77 @@ var sessionsListCmd = &cobra.Command{
 	Run:   runSessionsList,
 }
 
+var sessionsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy sessions between storage backends",
+	Long: `Copy every session from one storage backend to another (jsonl or sqlite).
+
+The source is left untouched. After migrating, set sessions.backend in the
+config to start using the new backend.`,
+	Run: runSessionsMigrate,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <key>",
+	Short: "Show the messages of a session with their numbers",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSessionsShow,
+}
+
+var sessionsForkCmd = &cobra.Command{
+	Use:   "fork <key>",
+	Short: "Create a branch of a session",
+	Long: `Create a branch that keeps the messages before message --at of a session.
+
+The branch is stored as a new session named <root>~N and can be continued
+with 'goclaw tui --session <branch>'.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSessionsFork,
+}
+
+var sessionsBranchesCmd = &cobra.Command{
+	Use:   "branches <key>",
+	Short: "Show the branch tree of a session",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSessionsBranches,
+}
+
+var sessionsDiffCmd = &cobra.Command{
+	Use:   "diff <branch> <branch>",
+	Short: "Compare two branches of the same session",
+	Long:  `Compare two branches by session key or branch name. The messages after their shared prefix are shown.`,
+	Args:  cobra.ExactArgs(2),
+	Run:   runSessionsDiff,
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Search the messages of all sessions",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runSessionsSearch,
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <key>",
+	Short: "Export a session to Markdown, HTML or JSON",
+	Long: `Export a session with its tool calls, attachments and timestamps.
+
+Markdown and HTML are meant for sharing and archiving, tool calls are shown as
+collapsible sections. JSON keeps every field and can be loaded again with
+'goclaw sessions import'. --redact masks API keys, tokens, private keys and
+the patterns in sessions.redact_patterns and redact_pattern hooks.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSessionsExport,
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Load a JSON session export",
+	Long:  `Validate a JSON file written by 'goclaw sessions export --format json' and store it as a session. Use - to read from stdin.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   runSessionsImport,
+}
+
 // Flags for sessions list
 var (
 	sessionsListJSON    bool
@@ -34,13 +107,366 @@ var (
 	sessionsListActive  bool
 )
 
+// Flags for sessions show, fork and search
+var (
+	sessionsShowLimit     int
+	sessionsForkAt        int
+	sessionsForkName      string
+	sessionsSearchContext int
+	sessionsSearchLimit   int
+	sessionsSearchPrefix  string
+	sessionsSearchJSON    bool
+)
+
+// Flags for sessions export and import
+var (
+	sessionsExportFormat   string
+	sessionsExportOutput   string
+	sessionsExportRedact   bool
+	sessionsExportPatterns []string
+	sessionsImportKey      string
+	sessionsImportForce    bool
+)
+
+// Flags for sessions migrate
+var (
+	sessionsMigrateFrom string
+	sessionsMigrateTo   string
+)
+
 func init() {
 	sessionsListCmd.Flags().BoolVar(&sessionsListJSON, "json", false, "Output in JSON format")
 	sessionsListCmd.Flags().BoolVar(&sessionsListVerbose, "verbose", false, "Show detailed information")
 	sessionsListCmd.Flags().StringVar(&sessionsListStore, "store", "", "Path to sessions directory")
 	sessionsListCmd.Flags().BoolVar(&sessionsListActive, "active", false, "Show only active sessions")
 
+	sessionsMigrateCmd.Flags().StringVar(&sessionsMigrateFrom, "from", "", "Source backend (default: the configured backend)")
+	sessionsMigrateCmd.Flags().StringVar(&sessionsMigrateTo, "to", "", "Target backend (jsonl or sqlite)")
+	sessionsMigrateCmd.Flags().StringVar(&sessionsListStore, "store", "", "Path to sessions directory")
+	_ = sessionsMigrateCmd.MarkFlagRequired("to")
+
+	sessionsShowCmd.Flags().IntVarP(&sessionsShowLimit, "limit", "n", 0, "Show only the last n messages")
+	sessionsForkCmd.Flags().IntVar(&sessionsForkAt, "at", 0, "Branch before this message number (default: after the last message)")
+	sessionsForkCmd.Flags().StringVar(&sessionsForkName, "name", "", "Branch name (default: branch-N)")
+	sessionsSearchCmd.Flags().IntVarP(&sessionsSearchContext, "context", "C", 1, "Messages to show before and after each match")
+	sessionsSearchCmd.Flags().IntVar(&sessionsSearchLimit, "limit", 50, "Maximum number of matches (0 for no limit)")
+	sessionsSearchCmd.Flags().StringVar(&sessionsSearchPrefix, "prefix", "", "Only search sessions whose key starts with this prefix")
+	sessionsSearchCmd.Flags().BoolVar(&sessionsSearchJSON, "json", false, "Output in JSON format")
+	sessionsExportCmd.Flags().StringVarP(&sessionsExportFormat, "format", "f", "md", "Output format: md, html or json")
+	sessionsExportCmd.Flags().StringVarP(&sessionsExportOutput, "output", "o", "", "Output file (default: stdout)")
+	sessionsExportCmd.Flags().BoolVar(&sessionsExportRedact, "redact", false, "Mask values that look like secrets")
+	sessionsExportCmd.Flags().StringArrayVar(&sessionsExportPatterns, "redact-pattern", nil, "Additional regular expression to mask (implies --redact, repeatable)")
+	sessionsImportCmd.Flags().StringVar(&sessionsImportKey, "key", "", "Session key to import as (default: the key in the export)")
+	sessionsImportCmd.Flags().BoolVar(&sessionsImportForce, "force", false, "Replace an existing session with the same key")
+	for _, cmd := range []*cobra.Command{sessionsShowCmd, sessionsForkCmd, sessionsBranchesCmd, sessionsDiffCmd, sessionsSearchCmd, sessionsExportCmd, sessionsImportCmd} {
+		cmd.Flags().StringVar(&sessionsListStore, "store", "", "Path to sessions directory")
+	}
+
 	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsMigrateCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsForkCmd)
+	sessionsCmd.AddCommand(sessionsBranchesCmd)
+	sessionsCmd.AddCommand(sessionsDiffCmd)
+	sessionsCmd.AddCommand(sessionsSearchCmd)
+	sessionsCmd.AddCommand(sessionsExportCmd)
+	sessionsCmd.AddCommand(sessionsImportCmd)
+}
+
+// sessionsLocation returns the sessions directory and the configured backend
+func sessionsLocation() (string, string) {
+	cfg, err := config.Load("")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	sessionDir := sessionsListStore
+	if sessionDir == "" {
+		sessionDir, err = config.GetSessionsPath(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting sessions directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	return sessionDir, cfg.Sessions.Backend
+}
+
+// runSessionsMigrate copies all sessions from one backend to another
+func runSessionsMigrate(cmd *cobra.Command, args []string) {
+	sessionDir, backend := sessionsLocation()
+	from := sessionsMigrateFrom
+	if from == "" {
+		from = backend
+	}
+	if from == "" {
+		from = session.BackendJSONL
+	}
+	if from == sessionsMigrateTo {
+		fmt.Fprintf(os.Stderr, "Source and target backend are both %s\n", from)
+		os.Exit(1)
+	}
+
+	src, err := session.OpenStore(from, sessionDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s store: %v\n", from, err)
+		os.Exit(1)
+	}
+	defer src.Close() // nolint:errcheck
+
+	dst, err := session.OpenStore(sessionsMigrateTo, sessionDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s store: %v\n", sessionsMigrateTo, err)
+		os.Exit(1)
+	}
+	defer dst.Close() // nolint:errcheck
+
+	count, skipped, err := session.Migrate(src, dst)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Migration failed after %d sessions: %v\n", count, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Migrated %d sessions from %s to %s in %s\n", count, from, sessionsMigrateTo, sessionDir)
+	if len(skipped) > 0 {
+		fmt.Printf("\nSkipped %d sessions written by an older version whose original key cannot be recovered from the file name:\n", len(skipped))
+		for _, key := range skipped {
+			fmt.Printf("  %s.jsonl\n", key)
+		}
+		fmt.Printf("They remain in the %s store. A session records its key the next time it is used with the %s backend; migrate again afterwards to include it.\n", from, from)
+	}
+	if backend != sessionsMigrateTo {
+		fmt.Printf("Set sessions.backend to %q in your config to use the new backend.\n", sessionsMigrateTo)
+	}
+}
+
+// openSessionManager opens the configured session store
+func openSessionManager() *session.Manager {
+	sessionDir, backend := sessionsLocation()
+	sessionMgr, err := session.Open(backend, sessionDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating session manager: %v\n", err)
+		os.Exit(1)
+	}
+	return sessionMgr
+}
+
+// loadExistingSession loads a session and exits when it does not exist
+func loadExistingSession(mgr *session.Manager, key string) *session.Session {
+	sess, err := mgr.Store().Load(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Session not found: %s\n", key)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error loading session %s: %v\n", key, err)
+		}
+		os.Exit(1)
+	}
+	sess, _ = mgr.GetOrCreate(key)
+	return sess
+}
+
+// runSessionsShow prints the messages of a session
+func runSessionsShow(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	sess := loadExistingSession(sessionMgr, args[0])
+	messages := sess.GetHistory(sessionsShowLimit)
+	fmt.Printf("%s (%d message(s))\n", sess.Key, len(sess.Messages))
+	if parent := session.BranchParent(sess.Metadata); parent != "" {
+		fmt.Printf("Branch %v of %s, forked after message %d\n", sess.Metadata[session.MetaBranchName], parent, session.BranchPoint(sess.Metadata))
+	}
+	if len(messages) > 0 {
+		fmt.Println(commands.FormatSessionMessages(messages, len(sess.Messages)-len(messages)))
+	}
+}
+
+// runSessionsFork creates a branch of a session
+func runSessionsFork(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	sess := loadExistingSession(sessionMgr, args[0])
+	at := len(sess.Messages)
+	if sessionsForkAt > 0 {
+		at = sessionsForkAt - 1
+	}
+	branch, err := sessionMgr.Fork(sess, at, sessionsForkName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating branch: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created branch %s (%v) with %d message(s) from %s\n", branch.Key, branch.Metadata[session.MetaBranchName], at, sess.Key)
+	fmt.Printf("Continue it with: goclaw tui --session %s\n", branch.Key)
+}
+
+// runSessionsBranches prints the branch tree of a session
+func runSessionsBranches(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	tree, err := sessionMgr.BranchTree(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading branches: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(commands.FormatBranchTree(tree, args[0]))
+}
+
+// runSessionsDiff compares two branches of a session
+func runSessionsDiff(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	// Branch names are resolved in the tree of the first argument if it is a
+	// session key, or of the second one
+	var tree *session.SessionTree
+	var err error
+	for _, ref := range args {
+		if tree, err = sessionMgr.BranchTree(ref); err == nil {
+			break
+		}
+	}
+	if tree == nil {
+		fmt.Fprintf(os.Stderr, "Error: neither %s nor %s is a session key\n", args[0], args[1])
+		os.Exit(1)
+	}
+
+	ids := make([]string, 0, len(args))
+	for _, ref := range args {
+		node, err := session.ResolveBranch(tree, ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		ids = append(ids, node.ID)
+	}
+	diff, err := tree.CompareSessions(ids[0], ids[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error comparing branches: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(commands.FormatSessionDiff(diff))
+}
+
+// runSessionsSearch searches the messages of all sessions
+func runSessionsSearch(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	query := strings.Join(args, " ")
+	hits, err := sessionMgr.Search(query, session.SearchOptions{
+		Context: sessionsSearchContext,
+		Limit:   sessionsSearchLimit,
+		Prefix:  sessionsSearchPrefix,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching sessions: %v\n", err)
+		os.Exit(1)
+	}
+
+	if sessionsSearchJSON {
+		data, err := json.MarshalIndent(hits, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	if len(hits) == 0 {
+		fmt.Printf("No messages match %q.\n", query)
+		return
+	}
+	fmt.Println(commands.FormatSearchHits(hits, query))
+	fmt.Printf("\n%d match(es)\n", len(hits))
+}
+
+// runSessionsExport writes a session in the requested format
+func runSessionsExport(cmd *cobra.Command, args []string) {
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	export := session.NewExport(loadExistingSession(sessionMgr, args[0]))
+	if sessionsExportRedact || len(sessionsExportPatterns) > 0 {
+		redactor, err := session.NewRedactor(redactPatterns())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		redactor.Redact(export)
+	}
+
+	out := os.Stdout
+	if sessionsExportOutput != "" && sessionsExportOutput != "-" {
+		f, err := os.Create(sessionsExportOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", sessionsExportOutput, err)
+			os.Exit(1)
+		}
+		defer f.Close() // nolint:errcheck
+		out = f
+	}
+	if err := export.Write(out, sessionsExportFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting session: %v\n", err)
+		os.Exit(1)
+	}
+	if out != os.Stdout {
+		fmt.Printf("Exported %s (%d message(s)) to %s\n", export.Key, len(export.Messages), sessionsExportOutput)
+	}
+}
+
+// redactPatterns collects the built-in secret patterns, sessions.redact_patterns,
+// the patterns of redact_pattern hooks and --redact-pattern
+func redactPatterns() []string {
+	patterns := append([]string{}, session.DefaultRedactPatterns...)
+	cfg, err := config.Load("")
+	if err == nil {
+		patterns = append(patterns, cfg.Sessions.RedactPatterns...)
+		for _, agentCfg := range cfg.Agents.List {
+			for _, hook := range agentCfg.Hooks {
+				if pattern, ok := hook.Options["pattern"].(string); ok && hook.Builtin == "redact_pattern" {
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+	}
+	return append(patterns, sessionsExportPatterns...)
+}
+
+// runSessionsImport validates a JSON export and stores it as a session
+func runSessionsImport(cmd *cobra.Command, args []string) {
+	in := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		defer f.Close() // nolint:errcheck
+		in = f
+	}
+	export, err := session.ParseExport(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sessionMgr := openSessionManager()
+	defer sessionMgr.Close() // nolint:errcheck
+
+	sess, err := sessionMgr.Import(export, sessionsImportKey, sessionsImportForce)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing session: %v\n", err)
+		if !sessionsImportForce && errors.Is(err, session.ErrSessionExists) {
+			fmt.Fprintln(os.Stderr, "Use --key to import under another key or --force to replace it.")
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %s (%d message(s))\n", sess.Key, len(sess.Messages))
+	if export.Redacted {
+		fmt.Println("Note: the export was redacted, masked values were not restored.")
+	}
 }
 
 // SessionInfo represents session information for display
@@ -58,27 +484,16 @@ type SessionInfo struct {
 
 // runSessionsList lists all sessions
 func runSessionsList(cmd *cobra.Command, args []string) {
-	// Determine sessions directory
-	var sessionDir string
-	var err error
-
-	if sessionsListStore != "" {
-		sessionDir = sessionsListStore
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-			os.Exit(1)
-		}
-		sessionDir = filepath.Join(homeDir, ".goclaw", "sessions")
-	}
+	// Determine sessions directory and backend
+	sessionDir, backend := sessionsLocation()
 
 	// Create session manager
-	sessionMgr, err := session.NewManager(sessionDir)
+	sessionMgr, err := session.Open(backend, sessionDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating session manager: %v\n", err)
 		os.Exit(1)
 	}
+	defer sessionMgr.Close() // nolint:errcheck
 
 	// List sessions
 	sessionKeys, err := sessionMgr.List()
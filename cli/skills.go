@@ -32,7 +32,7 @@ var skillsListVerbose bool
 
 var skillsValidateCmd = &cobra.Command{
 	Use:   "validate [skill-name]",
-	Short: "Validate skill dependencies",
+	Short: "Validate skill dependencies and entrypoint declarations",
 	Args:  cobra.ExactArgs(1),
 	Run:   runSkillsValidate,
 }
@@ -49,8 +49,21 @@ var skillsTestPrompt string
 var skillsInstallCmd = &cobra.Command{
 	Use:   "install [url|path]",
 	Short: "Install a skill from URL or local path",
-	Args:  cobra.ExactArgs(1),
-	Run:   runSkillsInstall,
+	Long: `Install a skill from a Git URL or a local path into ~/.goclaw/skills and record
+its source, resolved commit and content hash in ~/.goclaw/skills.lock.
+
+With --frozen and no argument, install exactly the skills recorded in the lock
+file at their locked commits, failing if any content hash differs.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if skillsInstallFrozen {
+			if len(args) > 0 {
+				return fmt.Errorf("--frozen installs from %s and takes no arguments", agent.SkillLockFile)
+			}
+			return nil
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: runSkillsInstall,
 }
 
 var skillsUpdateCmd = &cobra.Command{
@@ -230,7 +243,7 @@ func runSkillsValidate(cmd *cobra.Command, args []string) {
 	skillName := args[0]
 
 	// 加载配置
-	_, err := config.Load("")
+	cfg, err := config.Load("")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to load config: %v\n", err)
 	}
@@ -338,6 +351,38 @@ func runSkillsValidate(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// 检查可执行入口声明
+	if len(skill.Entrypoints) > 0 {
+		fmt.Println("\nEntrypoints:")
+		workspaceDir := goclawDir + "/workspace"
+		if cfg != nil {
+			if dir, err := config.GetWorkspacePath(cfg); err == nil {
+				workspaceDir = dir
+			}
+		}
+		issues := agent.ValidateSkillEntrypoints(skill, workspaceDir)
+		if cfg == nil || !cfg.Tools.Shell.Sandbox.Enabled {
+			issues = append(issues, agent.LocalRunnerSkillIssues(skill)...)
+		}
+		for _, ep := range skill.Entrypoints {
+			if ep.Name == "" {
+				continue
+			}
+			fmt.Printf("  %s: %s\n", ep.Name, strings.Join(ep.Command, " "))
+		}
+		for _, issue := range issues {
+			if issue.Warning {
+				fmt.Printf("  ⚠️  %s\n", issue)
+			} else {
+				fmt.Printf("  ❌ %s\n", issue)
+				allValid = false
+			}
+		}
+		if len(issues) == 0 {
+			fmt.Println("  ✅ Declarations are valid")
+		}
+	}
+
 	fmt.Println()
 	if allValid {
 		fmt.Println("✅ All dependencies satisfied!")
@@ -456,8 +501,6 @@ Please respond as if you were using this skill to handle the user's request. Sho
 }
 
 func runSkillsInstall(cmd *cobra.Command, args []string) {
-	source := args[0]
-
 	// 加载配置
 	_, err := config.Load("")
 	if err != nil {
@@ -483,14 +526,21 @@ func runSkillsInstall(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+	if skillsInstallFrozen {
+		runSkillsInstallFrozen(homeDir, userSkillsDir)
+		return
+	}
+
+	source := args[0]
+	var skillName, targetPath string
+	if isGitSource(source) {
 		// 从 Git 仓库安装
 		fmt.Printf("Installing from URL: %s\n", source)
 
 		// 提取仓库名
 		parts := strings.Split(source, "/")
-		repoName := strings.TrimSuffix(parts[len(parts)-1], ".git")
-		targetPath := filepath.Join(userSkillsDir, repoName)
+		skillName = strings.TrimSuffix(parts[len(parts)-1], ".git")
+		targetPath = filepath.Join(userSkillsDir, skillName)
 
 		// 检查是否已存在
 		if _, err := os.Stat(targetPath); err == nil {
@@ -514,8 +564,6 @@ func runSkillsInstall(cmd *cobra.Command, args []string) {
 			fmt.Fprintf(os.Stderr, "Failed to clone repository: %v\n", err)
 			os.Exit(1)
 		}
-
-		fmt.Printf("✅ Skill installed to %s\n", targetPath)
 	} else {
 		// 从本地目录安装
 		fmt.Printf("Installing from local path: %s\n", source)
@@ -534,8 +582,9 @@ func runSkillsInstall(cmd *cobra.Command, args []string) {
 		}
 
 		// 获取技能目录名
-		skillName := filepath.Base(sourcePath)
-		targetPath := filepath.Join(userSkillsDir, skillName)
+		skillName = filepath.Base(sourcePath)
+		targetPath = filepath.Join(userSkillsDir, skillName)
+		source = sourcePath
 
 		// 检查是否已存在
 		if _, err := os.Stat(targetPath); err == nil {
@@ -556,8 +605,18 @@ func runSkillsInstall(cmd *cobra.Command, args []string) {
 			fmt.Fprintf(os.Stderr, "Failed to copy directory: %v\n", err)
 			os.Exit(1)
 		}
+	}
 
-		fmt.Printf("✅ Skill installed to %s\n", targetPath)
+	// 记录到锁文件
+	entry, err := lockSkill(homeDir, skillName, source, targetPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update %s: %v\n", agent.SkillLockFile, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Skill installed to %s\n", targetPath)
+	if entry.Commit != "" {
+		fmt.Printf("   Locked at %s\n", shortCommit(entry.Commit))
 	}
 }
 
@@ -631,7 +690,26 @@ func runSkillsUpdate(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	fmt.Println("✅ Skill updated successfully")
+	// 刷新锁文件中的提交和内容哈希
+	lock, err := agent.LoadSkillLock(skillLockPath(homeDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load lock file: %v\n", err)
+		os.Exit(1)
+	}
+	source := ""
+	if entry, ok := lock.Skills[skillName]; ok {
+		source = entry.Source
+	} else if source, err = gitOutput("-C", skillPath, "remote", "get-url", "origin"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve skill source: %v\n", err)
+		os.Exit(1)
+	}
+	entry, err := lockSkill(homeDir, skillName, source, skillPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update %s: %v\n", agent.SkillLockFile, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Skill updated successfully (locked at %s)\n", shortCommit(entry.Commit))
 }
 
 func runSkillsUninstall(cmd *cobra.Command, args []string) {
@@ -674,6 +752,10 @@ func runSkillsUninstall(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Failed to remove skill: %v\n", err)
 		os.Exit(1)
 	}
+	if err := unlockSkill(homeDir, skillName); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update %s: %v\n", agent.SkillLockFile, err)
+		os.Exit(1)
+	}
 
 	fmt.Println("✅ Skill uninstalled successfully")
 }
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/spf13/cobra"
+)
+
+var skillsOutdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Compare locked skill commits with their remotes",
+	Long: `List the skills recorded in ~/.goclaw/skills.lock and compare each locked
+commit with the current HEAD of its remote. Nothing is modified; run
+'goclaw skills update <name>' to move a skill forward.`,
+	Args: cobra.NoArgs,
+	Run:  runSkillsOutdated,
+}
+
+var (
+	skillsInstallFrozen bool
+	skillsOutdatedJSON  bool
+)
+
+func init() {
+	skillsInstallCmd.Flags().BoolVar(&skillsInstallFrozen, "frozen", false, "Install exactly the skills and commits recorded in skills.lock")
+	skillsOutdatedCmd.Flags().BoolVarP(&skillsOutdatedJSON, "json", "j", false, "Output as JSON")
+	skillsCmd.AddCommand(skillsOutdatedCmd)
+}
+
+// skillLockPath returns the lock file that covers ~/.goclaw/skills
+func skillLockPath(homeDir string) string {
+	return filepath.Join(homeDir, ".goclaw", agent.SkillLockFile)
+}
+
+// isGitSource reports whether an install source is a Git URL rather than a local path
+func isGitSource(source string) bool {
+	for _, prefix := range []string{"http://", "https://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitOutput runs git and returns its trimmed standard output
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// lockSkill records the installed skill at path in the lock file
+func lockSkill(homeDir, name, source, path string) (*agent.LockedSkill, error) {
+	hash, err := agent.HashSkillDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &agent.LockedSkill{Source: source, Hash: hash, InstalledAt: time.Now().UTC()}
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		if entry.Commit, err = gitOutput("-C", path, "rev-parse", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	lockPath := skillLockPath(homeDir)
+	lock, err := agent.LoadSkillLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	lock.Skills[name] = entry
+	if err := lock.Save(lockPath); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// unlockSkill removes a skill from the lock file
+func unlockSkill(homeDir, name string) error {
+	lockPath := skillLockPath(homeDir)
+	lock, err := agent.LoadSkillLock(lockPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := lock.Skills[name]; !ok {
+		return nil
+	}
+	delete(lock.Skills, name)
+	return lock.Save(lockPath)
+}
+
+// runSkillsInstallFrozen installs every skill of the lock file at its locked
+// commit and refuses any result whose content hash differs from the lock
+func runSkillsInstallFrozen(homeDir, userSkillsDir string) {
+	lockPath := skillLockPath(homeDir)
+	if _, err := os.Stat(lockPath); err != nil {
+		fmt.Fprintf(os.Stderr, "No lock file at %s\n", lockPath)
+		os.Exit(1)
+	}
+	lock, err := agent.LoadSkillLock(lockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load lock file: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, name := range lock.Names() {
+		entry := lock.Skills[name]
+		targetPath := filepath.Join(userSkillsDir, name)
+		if _, err := os.Stat(targetPath); err == nil && lock.Verify(name, targetPath) == nil {
+			fmt.Printf("✓ %s is up to date\n", name)
+			continue
+		}
+		if err := installLockedSkill(name, entry, userSkillsDir); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s installed at %s\n", name, shortCommit(entry.Commit))
+	}
+
+	// Skills that are not in the lock file are left alone but reported
+	entries, _ := os.ReadDir(userSkillsDir)
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			if _, ok := lock.Skills[e.Name()]; !ok {
+				fmt.Printf("⚠️  %s is installed but not in %s\n", e.Name(), agent.SkillLockFile)
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d skill(s) could not be installed from the lock file\n", failed)
+		os.Exit(1)
+	}
+}
+
+// installLockedSkill fetches one locked skill into a staging directory, verifies
+// it and only then replaces the installed copy
+func installLockedSkill(name string, entry *agent.LockedSkill, userSkillsDir string) error {
+	staging, err := os.MkdirTemp(userSkillsDir, "."+name+".frozen-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if entry.Commit != "" {
+		if _, err := gitOutput("clone", "--quiet", entry.Source, staging); err != nil {
+			return err
+		}
+		// Stay on the default branch so a later skills update can pull
+		if _, err := gitOutput("-C", staging, "reset", "--quiet", "--hard", entry.Commit); err != nil {
+			return err
+		}
+	} else if err := copyDir(entry.Source, staging); err != nil {
+		return err
+	}
+
+	hash, err := agent.HashSkillDir(staging)
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return fmt.Errorf("%w: fetched %s, locked %s", agent.ErrSkillLockMismatch, hash, entry.Hash)
+	}
+
+	targetPath := filepath.Join(userSkillsDir, name)
+	if err := os.RemoveAll(targetPath); err != nil {
+		return err
+	}
+	return os.Rename(staging, targetPath)
+}
+
+// skillOutdatedStatus is one row of skills outdated
+type skillOutdatedStatus struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Locked string `json:"locked"`
+	Latest string `json:"latest,omitempty"`
+	Status string `json:"status"` // up-to-date, outdated, local, error
+	Local  string `json:"local"`  // ok, modified, missing
+	Error  string `json:"error,omitempty"`
+}
+
+func runSkillsOutdated(cmd *cobra.Command, args []string) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get home directory: %v\n", err)
+		os.Exit(1)
+	}
+	lock, err := agent.LoadSkillLock(skillLockPath(homeDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load lock file: %v\n", err)
+		os.Exit(1)
+	}
+	userSkillsDir := homeDir + "/.goclaw/skills"
+
+	statuses := make([]skillOutdatedStatus, 0, len(lock.Skills))
+	for _, name := range lock.Names() {
+		entry := lock.Skills[name]
+		st := skillOutdatedStatus{Name: name, Source: entry.Source, Locked: entry.Commit, Local: "ok"}
+
+		skillPath := filepath.Join(userSkillsDir, name)
+		if _, err := os.Stat(skillPath); err != nil {
+			st.Local = "missing"
+		} else if err := lock.Verify(name, skillPath); err != nil {
+			st.Local = "modified"
+		}
+
+		if entry.Commit == "" {
+			st.Status = "local"
+		} else if latest, err := gitOutput("ls-remote", entry.Source, "HEAD"); err != nil {
+			st.Status, st.Error = "error", err.Error()
+		} else if fields := strings.Fields(latest); len(fields) == 0 {
+			st.Status, st.Error = "error", "remote has no HEAD"
+		} else {
+			st.Latest = fields[0]
+			st.Status = "up-to-date"
+			if st.Latest != entry.Commit {
+				st.Status = "outdated"
+			}
+		}
+		statuses = append(statuses, st)
+	}
+
+	if skillsOutdatedJSON {
+		data, _ := json.MarshalIndent(statuses, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No locked skills.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tLOCKED\tLATEST\tSTATUS\tLOCAL\n")
+	fmt.Fprintf(w, "----\t------\t------\t------\t-----\n")
+	for _, st := range statuses {
+		status := st.Status
+		if st.Error != "" {
+			status += ": " + st.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", st.Name, shortCommit(st.Locked), shortCommit(st.Latest), status, st.Local)
+	}
+	_ = w.Flush()
+}
+
+// shortCommit abbreviates a commit id for display
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "-"
+	}
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
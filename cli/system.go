@@ -133,6 +133,19 @@ func handleHeartbeatLast(cfg *config.Config) {
 	if status, ok := data["status"].(string); ok {
 		fmt.Printf("  Status: %s\n", status)
 	}
+	for _, field := range []struct{ key, label string }{
+		{"agent_id", "Agent"},
+		{"reason", "Reason"},
+		{"reply", "Reply"},
+		{"error", "Error"},
+	} {
+		if value, ok := data[field.key].(string); ok && value != "" {
+			fmt.Printf("  %s: %s\n", field.label, value)
+		}
+	}
+	if enabled, ok := data["enabled"].(bool); ok && !enabled {
+		fmt.Println("  Heartbeat is disabled")
+	}
 }
 
 // handleHeartbeatEnable handles enabling heartbeat
@@ -274,6 +287,9 @@ func callGatewayRPC(cfg *config.Config, method string, params map[string]interfa
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if token := cfg.Gateway.WebSocket.AuthToken; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
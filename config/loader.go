@@ -75,6 +75,13 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("gateway.read_timeout", 30)
 	v.SetDefault("gateway.write_timeout", 30)
 
+	// 可观测性默认配置
+	v.SetDefault("telemetry.metrics.enabled", true)
+	v.SetDefault("telemetry.metrics.path", "/metrics")
+
+	// 会话存储默认配置
+	v.SetDefault("sessions.backend", "jsonl")
+
 	// 工具默认配置
 	v.SetDefault("tools.shell.enabled", true)
 	v.SetDefault("tools.shell.timeout", 120)
@@ -84,9 +91,15 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("tools.shell.sandbox.remove", true)
 	v.SetDefault("tools.shell.sandbox.network", "none")
 	v.SetDefault("tools.shell.sandbox.privileged", false)
+	v.SetDefault("tools.shell.sandbox.scope", "session")
+	v.SetDefault("tools.shell.sandbox.pids_limit", 256)
+	v.SetDefault("tools.shell.sandbox.idle_timeout", 1800)
+	v.SetDefault("tools.shell.policy.risk_action", "approve")
+	v.SetDefault("approvals.behavior", "manual")
 	v.SetDefault("tools.web.search_engine", "travily")
 	v.SetDefault("tools.web.timeout", 10)
 	v.SetDefault("tools.browser.enabled", false)
+	v.SetDefault("tools.browser.idle_timeout", 600)
 	v.SetDefault("browser.headless", true)
 	v.SetDefault("browser.timeout", 30)
 }
@@ -141,6 +154,33 @@ func GetWorkspacePath(cfg *Config) (string, error) {
 	return filepath.Join(home, ".goclaw", "workspace"), nil
 }
 
+// GetSessionsPath 获取会话目录路径
+func GetSessionsPath(cfg *Config) (string, error) {
+	if cfg != nil && cfg.Sessions.Dir != "" {
+		return cfg.Sessions.Dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "sessions"), nil
+}
+
+// GetLogPath 获取网关日志文件路径，logging.file 为 "-" 时返回空字符串
+func GetLogPath(cfg *Config) (string, error) {
+	if cfg != nil && cfg.Logging.File != "" {
+		if cfg.Logging.File == "-" {
+			return "", nil
+		}
+		return cfg.Logging.File, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "logs", "goclaw.log"), nil
+}
+
 // Validate 验证配置 (使用新的验证器)
 func Validate(cfg *Config) error {
 	validator := NewValidator(true)
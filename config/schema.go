@@ -20,6 +20,12 @@ type Config struct {
 	Bindings []BindingConfig `mapstructure:"bindings" json:"bindings"`
 	// ACP (Agent Client Protocol) configuration
 	ACP ACPConfig `mapstructure:"acp" json:"acp"`
+	// 可观测性配置（Prometheus 指标和 OpenTelemetry 追踪）
+	Telemetry TelemetryConfig `mapstructure:"telemetry" json:"telemetry"`
+	// 会话存储配置
+	Sessions SessionsConfig `mapstructure:"sessions" json:"sessions"`
+	// 日志文件和内存缓冲配置
+	Logging LoggingConfig `mapstructure:"logging" json:"logging"`
 }
 
 // WorkspaceConfig Workspace 配置
@@ -40,7 +46,19 @@ type AgentDefaults struct {
 	Temperature        float64          `mapstructure:"temperature" json:"temperature"`
 	MaxTokens          int              `mapstructure:"max_tokens" json:"max_tokens"`
 	MaxHistoryMessages int              `mapstructure:"max_history_messages" json:"max_history_messages"` // 最大历史消息数量
+	Thinking           string           `mapstructure:"thinking" json:"thinking"`                         // 扩展思考级别: off, minimal, low, medium, high, xhigh
 	Subagents          *SubagentsConfig `mapstructure:"subagents" json:"subagents"`
+	Heartbeat          *HeartbeatConfig `mapstructure:"heartbeat" json:"heartbeat"` // 心跳配置，所有 Agent 共用
+}
+
+// HeartbeatConfig 心跳配置：按固定间隔让 Agent 根据工作区的 HEARTBEAT.md 检查待办事项
+type HeartbeatConfig struct {
+	Enabled     bool          `mapstructure:"enabled" json:"enabled"`
+	Every       time.Duration `mapstructure:"every" json:"every"`                 // 心跳间隔，默认 30m
+	Prompt      string        `mapstructure:"prompt" json:"prompt"`               // 覆盖默认的心跳提示词
+	Channel     string        `mapstructure:"channel" json:"channel"`             // 需要汇报时发送到的通道，为空只记录状态
+	ChatID      string        `mapstructure:"chat_id" json:"chat_id"`             // 需要汇报时发送到的会话
+	AckMaxChars int           `mapstructure:"ack_max_chars" json:"ack_max_chars"` // 带 HEARTBEAT_OK 的回复在该长度内仍视为无事汇报，默认 300
 }
 
 // SubagentsConfig 分身配置
@@ -71,8 +89,25 @@ type AgentConfig struct {
 	Workspace    string                 `mapstructure:"workspace" json:"workspace"`         // 独立工作区路径
 	Identity     *AgentIdentity         `mapstructure:"identity" json:"identity"`           // Agent 身份配置
 	SystemPrompt string                 `mapstructure:"system_prompt" json:"system_prompt"` // 系统提示词
+	Thinking     string                 `mapstructure:"thinking" json:"thinking"`           // 扩展思考级别（覆盖默认值）
 	Metadata     map[string]interface{} `mapstructure:"metadata" json:"metadata"`           // 额外元数据
 	Subagents    *AgentSubagentConfig   `mapstructure:"subagents" json:"subagents"`         // 分身配置
+	Hooks        []HookConfig           `mapstructure:"hooks" json:"hooks"`                 // 生命周期钩子
+	Heartbeat    *HeartbeatConfig       `mapstructure:"heartbeat" json:"heartbeat"`         // 心跳配置（覆盖默认值）
+}
+
+// HookConfig 生命周期钩子配置
+// Builtin 和 Command 二选一：Builtin 为 Go 实现的内置钩子，Command 为通过 stdin/stdout 交换 JSON 的外部可执行文件
+type HookConfig struct {
+	Name     string                 `mapstructure:"name" json:"name"`           // 钩子名称，用于日志和拒绝记录
+	Stage    string                 `mapstructure:"stage" json:"stage"`         // pre_llm, post_llm, pre_tool, post_tool, inbound, outbound
+	Builtin  string                 `mapstructure:"builtin" json:"builtin"`     // 内置钩子名称（deny_pattern, redact_pattern）
+	Command  string                 `mapstructure:"command" json:"command"`     // 外部可执行文件
+	Args     []string               `mapstructure:"args" json:"args"`           // 外部命令参数
+	Timeout  int                    `mapstructure:"timeout" json:"timeout"`     // 外部命令超时（秒），默认 10
+	Tools    []string               `mapstructure:"tools" json:"tools"`         // 仅对这些工具生效（工具阶段）
+	FailOpen bool                   `mapstructure:"fail_open" json:"fail_open"` // 钩子出错时放行（默认拒绝）
+	Options  map[string]interface{} `mapstructure:"options" json:"options"`     // 内置钩子选项
 }
 
 // AgentIdentity Agent 身份配置
@@ -125,7 +160,20 @@ type ChannelAccountConfig struct {
 	ServerURL         string   `mapstructure:"server_url" json:"server_url"`                 // Gotify server url
 	AppToken          string   `mapstructure:"app_token" json:"app_token"`                   // Gotify app token
 	Priority          int      `mapstructure:"priority" json:"priority"`                     // Gotify message priority 1-10
+	DMPolicy          string   `mapstructure:"dm_policy" json:"dm_policy"`                   // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs        []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
+}
+
+// GroupPolicyConfig 群聊策略配置
+
+type GroupPolicyConfig struct {
+	RequireMention *bool    `mapstructure:"require_mention" json:"require_mention,omitempty"` // 只响应 @ 机器人的消息，未设置时飞书为 true，其他通道为 false
+	AllowedGroups  []string `mapstructure:"allowed_groups" json:"allowed_groups"`             // 允许的群聊 ID（即 chat_id），为空允许所有群
+	ReplyMode      string   `mapstructure:"reply_mode" json:"reply_mode"`                     // 回复方式: thread（回复原消息，默认）, inline
+	HistoryLimit   int      `mapstructure:"history_limit" json:"history_limit"`               // 附带为上下文的未 @ 群消息条数，0 表示不附带
+	SenderPolicy   string   `mapstructure:"sender_policy" json:"sender_policy"`               // 群成员限制: allowlist（默认，配置了 allowed_ids 时只响应其中的成员）, open
 }
 
 // ChannelTypeAccountConfig 通道类型的多账号配置
@@ -138,7 +186,10 @@ type ChannelTypeAccountConfig struct {
 type TelegramChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
 	Token      string   `mapstructure:"token" json:"token"`
+	DMPolicy   string   `mapstructure:"dm_policy" json:"dm_policy"` // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -147,7 +198,10 @@ type TelegramChannelConfig struct {
 type WhatsAppChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
 	BridgeURL  string   `mapstructure:"bridge_url" json:"bridge_url"`
+	DMPolicy   string   `mapstructure:"dm_policy" json:"dm_policy"` // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -162,10 +216,12 @@ type FeishuChannelConfig struct {
 	WebhookPort       int      `mapstructure:"webhook_port" json:"webhook_port"`
 	Domain            string   `mapstructure:"domain" json:"domain"`             // 飞书域名 (如: feishu, lark)
 	GroupPolicy       string   `mapstructure:"group_policy" json:"group_policy"` // 群聊策略: open, closed, whitelist
-	DMPolicy          string   `mapstructure:"dm_policy" json:"dm_policy"`       // 私聊策略: open, pairing, allowlist, closed (默认: pairing)
+	DMPolicy          string   `mapstructure:"dm_policy" json:"dm_policy"`       // 私聊策略: open, pairing, allowlist, disabled (默认: pairing)
 	AllowedIDs        []string `mapstructure:"allowed_ids" json:"allowed_ids"`
 	// CronOutputChatID 指定 cron 任务输出的目标聊天 ID（用于接收定时任务的通知）
 	CronOutputChatID  string   `mapstructure:"cron_output_chat_id" json:"cron_output_chat_id"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -175,7 +231,10 @@ type QQChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
 	AppID      string   `mapstructure:"app_id" json:"app_id"`           // QQ 机器人 AppID
 	AppSecret  string   `mapstructure:"app_secret" json:"app_secret"`   // AppSecret (ClientSecret)
+	DMPolicy   string   `mapstructure:"dm_policy" json:"dm_policy"`     // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"` // 允许的用户/群ID列表
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -189,6 +248,7 @@ type WeWorkChannelConfig struct {
 	Token          string   `mapstructure:"token" json:"token"`
 	EncodingAESKey string   `mapstructure:"encoding_aes_key" json:"encoding_aes_key"`
 	WebhookPort    int      `mapstructure:"webhook_port" json:"webhook_port"`
+	DMPolicy       string   `mapstructure:"dm_policy" json:"dm_policy"` // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs     []string `mapstructure:"allowed_ids" json:"allowed_ids"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
@@ -199,7 +259,10 @@ type DingTalkChannelConfig struct {
 	Enabled      bool     `mapstructure:"enabled" json:"enabled"`
 	ClientID     string   `mapstructure:"client_id" json:"client_id"`
 	ClientSecret string   `mapstructure:"secret" json:"secret"`
+	DMPolicy     string   `mapstructure:"dm_policy" json:"dm_policy"` // 私聊策略: open, pairing, allowlist, disabled
 	AllowedIDs   []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -212,6 +275,8 @@ type InfoflowChannelConfig struct {
 	AESKey      string   `mapstructure:"aes_key" json:"aes_key"`
 	WebhookPort int      `mapstructure:"webhook_port" json:"webhook_port"`
 	AllowedIDs  []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	// 群聊策略
+	Group GroupPolicyConfig `mapstructure:"group" json:"group"`
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -232,6 +297,7 @@ type ProvidersConfig struct {
 	OpenRouter OpenRouterProviderConfig `mapstructure:"openrouter" json:"openrouter"`
 	OpenAI     OpenAIProviderConfig     `mapstructure:"openai" json:"openai"`
 	Anthropic  AnthropicProviderConfig  `mapstructure:"anthropic" json:"anthropic"`
+	Local      LocalProviderConfig      `mapstructure:"local" json:"local"`
 	Profiles   []ProviderProfileConfig  `mapstructure:"profiles" json:"profiles"`
 	Failover   FailoverConfig           `mapstructure:"failover" json:"failover"`
 }
@@ -239,9 +305,10 @@ type ProvidersConfig struct {
 // ProviderProfileConfig 提供商配置
 type ProviderProfileConfig struct {
 	Name     string `mapstructure:"name" json:"name"`
-	Provider string `mapstructure:"provider" json:"provider"` // openai, anthropic, openrouter
+	Provider string `mapstructure:"provider" json:"provider"` // openai, anthropic, openrouter, local
 	APIKey   string `mapstructure:"api_key" json:"api_key"`
 	BaseURL  string `mapstructure:"base_url" json:"base_url"`
+	Model    string `mapstructure:"model" json:"model"` // 覆盖默认模型（可选）
 	Priority int    `mapstructure:"priority" json:"priority"`
 }
 
@@ -281,6 +348,16 @@ type AnthropicProviderConfig struct {
 	Timeout int    `mapstructure:"timeout" json:"timeout"`
 }
 
+// LocalProviderConfig 本地模型配置（Ollama 或 OpenAI 兼容的本地服务）
+type LocalProviderConfig struct {
+	Enabled        bool   `mapstructure:"enabled" json:"enabled"`
+	BaseURL        string `mapstructure:"base_url" json:"base_url"` // 默认 http://localhost:11434
+	APIKey         string `mapstructure:"api_key" json:"api_key"`   // 可选
+	Model          string `mapstructure:"model" json:"model"`       // 为空时自动发现
+	Timeout        int    `mapstructure:"timeout" json:"timeout"`
+	ToolCallFormat string `mapstructure:"tool_call_format" json:"tool_call_format"` // auto, native, json
+}
+
 // GatewayConfig 网关配置
 type GatewayConfig struct {
 	Host         string          `mapstructure:"host" json:"host"`
@@ -288,6 +365,14 @@ type GatewayConfig struct {
 	ReadTimeout  time.Duration   `mapstructure:"read_timeout" json:"read_timeout"`
 	WriteTimeout time.Duration   `mapstructure:"write_timeout" json:"write_timeout"`
 	WebSocket    WebSocketConfig `mapstructure:"websocket" json:"websocket"`
+	Auth         GatewayAuthConfig `mapstructure:"auth" json:"auth"`
+}
+
+// GatewayAuthConfig 网关令牌和权限配置（在 websocket.enable_auth 启用时生效）
+type GatewayAuthConfig struct {
+	TokensFile   string            `mapstructure:"tokens_file" json:"tokens_file"`     // 令牌哈希存储文件，默认 ~/.goclaw/gateway/tokens.json
+	AuditFile    string            `mapstructure:"audit_file" json:"audit_file"`       // 调用审计日志，默认 ~/.goclaw/gateway/audit.jsonl
+	MethodScopes map[string]string `mapstructure:"method_scopes" json:"method_scopes"` // 覆盖方法所需的权限范围：read、operator、admin
 }
 
 // WebSocketConfig WebSocket 配置
@@ -320,12 +405,28 @@ type FileSystemToolConfig struct {
 
 // ShellToolConfig Shell 工具配置
 type ShellToolConfig struct {
-	Enabled     bool          `mapstructure:"enabled" json:"enabled"`
-	AllowedCmds []string      `mapstructure:"allowed_cmds" json:"allowed_cmds"`
-	DeniedCmds  []string      `mapstructure:"denied_cmds" json:"denied_cmds"`
-	Timeout     int           `mapstructure:"timeout" json:"timeout"`
-	WorkingDir  string        `mapstructure:"working_dir" json:"working_dir"`
-	Sandbox     SandboxConfig `mapstructure:"sandbox" json:"sandbox"`
+	Enabled     bool              `mapstructure:"enabled" json:"enabled"`
+	AllowedCmds []string          `mapstructure:"allowed_cmds" json:"allowed_cmds"`
+	DeniedCmds  []string          `mapstructure:"denied_cmds" json:"denied_cmds"`
+	Timeout     int               `mapstructure:"timeout" json:"timeout"`
+	WorkingDir  string            `mapstructure:"working_dir" json:"working_dir"`
+	Sandbox     SandboxConfig     `mapstructure:"sandbox" json:"sandbox"`
+	Policy      ShellPolicyConfig `mapstructure:"policy" json:"policy"`
+}
+
+// ShellPolicyConfig Shell 命令策略配置
+type ShellPolicyConfig struct {
+	Rules []ShellRuleConfig `mapstructure:"rules" json:"rules"`
+	// 高风险命令（rm -rf、curl | sh、写入工作区外等）的处理方式: approve（默认，走审批）、deny、allow
+	RiskAction string `mapstructure:"risk_action" json:"risk_action"`
+}
+
+// ShellRuleConfig Shell 命令规则
+type ShellRuleConfig struct {
+	Command string `mapstructure:"command" json:"command"` // 命令名，支持 * 通配
+	Args    string `mapstructure:"args" json:"args"`       // 参数模式（以空格连接的参数），支持 * 和 ? 通配，为空匹配任意参数
+	Action  string `mapstructure:"action" json:"action"`   // allow、deny、approve
+	Reason  string `mapstructure:"reason" json:"reason"`
 }
 
 // SandboxConfig Docker 沙箱配置
@@ -336,6 +437,15 @@ type SandboxConfig struct {
 	Remove     bool   `mapstructure:"remove" json:"remove"`
 	Network    string `mapstructure:"network" json:"network"`
 	Privileged bool   `mapstructure:"privileged" json:"privileged"`
+	// 容器复用范围: session（每会话一个容器，默认）、agent（每 Agent 一个容器）、none（每条命令新建容器）
+	Scope        string   `mapstructure:"scope" json:"scope"`
+	CPUs         float64  `mapstructure:"cpus" json:"cpus"`                     // CPU 核数上限，0 表示不限制
+	Memory       string   `mapstructure:"memory" json:"memory"`                 // 内存上限，如 "512m"、"2g"
+	PidsLimit    int64    `mapstructure:"pids_limit" json:"pids_limit"`         // 最大进程数，0 表示不限制
+	DiskSize     string   `mapstructure:"disk_size" json:"disk_size"`           // 容器可写层大小上限，如 "10g"（需存储驱动支持）
+	ReadOnlyRoot bool     `mapstructure:"read_only_root" json:"read_only_root"` // 根文件系统只读（/tmp 使用 tmpfs）
+	Mounts       []string `mapstructure:"mounts" json:"mounts"`                 // 额外挂载 "host:container"，一律只读
+	IdleTimeout  int      `mapstructure:"idle_timeout" json:"idle_timeout"`     // 空闲容器回收时间（秒），默认 1800
 }
 
 // WebToolConfig Web 工具配置
@@ -347,11 +457,13 @@ type WebToolConfig struct {
 
 // BrowserToolConfig 浏览器工具配置
 type BrowserToolConfig struct {
-	Enabled   bool   `mapstructure:"enabled" json:"enabled"`
-	Headless  bool   `mapstructure:"headless" json:"headless"`
-	Timeout   int    `mapstructure:"timeout" json:"timeout"`
-	RelayURL  string `mapstructure:"relay_url" json:"relay_url"`   // OpenClaw relay server URL (e.g., ws://127.0.0.1:18789)
-	RelayMode string `mapstructure:"relay_mode" json:"relay_mode"` // Connection mode: "auto", "direct", "relay"
+	Enabled     bool   `mapstructure:"enabled" json:"enabled"`
+	Headless    bool   `mapstructure:"headless" json:"headless"`
+	Timeout     int    `mapstructure:"timeout" json:"timeout"`
+	RelayURL    string `mapstructure:"relay_url" json:"relay_url"`       // OpenClaw relay server URL (e.g., ws://127.0.0.1:18789)
+	RelayMode   string `mapstructure:"relay_mode" json:"relay_mode"`     // Connection mode: "auto", "direct", "relay"
+	ProfileDir  string `mapstructure:"profile_dir" json:"profile_dir"`   // 持久化 profile 根目录，每个会话使用其中的子目录；为空时使用临时的隔离上下文
+	IdleTimeout int    `mapstructure:"idle_timeout" json:"idle_timeout"` // 空闲浏览器会话回收时间（秒），默认 600
 }
 
 // CronToolConfig Cron 工具配置
@@ -362,8 +474,8 @@ type CronToolConfig struct {
 
 // ApprovalsConfig 审批配置
 type ApprovalsConfig struct {
-	Behavior  string   `mapstructure:"behavior" json:"behavior"`   // auto, manual, prompt
-	Allowlist []string `mapstructure:"allowlist" json:"allowlist"` // 工具允许列表
+	Behavior  string   `mapstructure:"behavior" json:"behavior"`   // auto, manual（默认）, prompt
+	Allowlist []string `mapstructure:"allowlist" json:"allowlist"` // 免审批列表：工具名或 "shell:<命令前缀>"
 }
 
 // MemoryConfig 记忆配置
@@ -375,9 +487,19 @@ type MemoryConfig struct {
 
 // BuiltinMemoryConfig 内置 SQLite 记忆配置
 type BuiltinMemoryConfig struct {
-	Enabled      bool   `mapstructure:"enabled" json:"enabled"`
-	DatabasePath string `mapstructure:"database_path" json:"database_path"`
-	AutoIndex    bool   `mapstructure:"auto_index" json:"auto_index"`
+	Enabled      bool                  `mapstructure:"enabled" json:"enabled"`
+	DatabasePath string                `mapstructure:"database_path" json:"database_path"`
+	AutoIndex    bool                  `mapstructure:"auto_index" json:"auto_index"`
+	Embedding    MemoryEmbeddingConfig `mapstructure:"embedding" json:"embedding"`
+}
+
+// MemoryEmbeddingConfig 内置记忆的向量化配置
+type MemoryEmbeddingConfig struct {
+	Provider  string `mapstructure:"provider" json:"provider"`   // "auto" | "local" | "openai"，auto 在有 API Key 时使用 openai，否则使用 local
+	Dimension int    `mapstructure:"dimension" json:"dimension"` // local 向量维度，默认 384
+	Model     string `mapstructure:"model" json:"model"`         // openai 嵌入模型，默认 text-embedding-3-small
+	APIKey    string `mapstructure:"api_key" json:"api_key"`     // openai API Key，为空时使用 providers.openai / providers.openrouter 的配置
+	BaseURL   string `mapstructure:"base_url" json:"base_url"`   // openai 兼容接口地址
 }
 
 // QMDConfig QMD 记忆配置
@@ -421,6 +543,42 @@ type QMDLimits struct {
 	TimeoutMs       int `mapstructure:"timeout_ms" json:"timeout_ms"`               // 默认 4000
 }
 
+// SessionsConfig 会话存储配置
+type SessionsConfig struct {
+	Backend string `mapstructure:"backend" json:"backend"` // 存储后端：jsonl（默认）或 sqlite
+	Dir     string `mapstructure:"dir" json:"dir"`         // 会话目录，默认 ~/.goclaw/sessions
+	// 导出会话时 --redact 额外脱敏的正则，内置的密钥格式和 redact_pattern 钩子的正则始终生效
+	RedactPatterns []string `mapstructure:"redact_patterns" json:"redact_patterns"`
+}
+
+// LoggingConfig 日志配置
+type LoggingConfig struct {
+	File       string `mapstructure:"file" json:"file"`               // 网关日志文件，默认 ~/.goclaw/logs/goclaw.log，设为 "-" 不写文件
+	MaxSizeMB  int    `mapstructure:"max_size_mb" json:"max_size_mb"` // 单个日志文件超过该大小（MB）后轮转，默认 50
+	MaxBackups int    `mapstructure:"max_backups" json:"max_backups"` // 保留的轮转文件数，默认 5
+	BufferSize int    `mapstructure:"buffer_size" json:"buffer_size"` // 内存中保留供 logs.get 查询的条目数，默认 2000
+}
+
+// TelemetryConfig 可观测性配置
+type TelemetryConfig struct {
+	Metrics MetricsConfig `mapstructure:"metrics" json:"metrics"`
+	Tracing TracingConfig `mapstructure:"tracing" json:"tracing"`
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled" json:"enabled"` // 在网关上暴露指标端点
+	Path    string `mapstructure:"path" json:"path"`       // 指标端点路径，默认 /metrics
+}
+
+// TracingConfig OpenTelemetry 追踪配置
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled" json:"enabled"`
+	File        string  `mapstructure:"file" json:"file"`                 // OTLP/JSON 输出文件，默认 ~/.goclaw/traces/spans.jsonl
+	ServiceName string  `mapstructure:"service_name" json:"service_name"` // service.name 资源属性，默认 goclaw
+	SampleRatio float64 `mapstructure:"sample_ratio" json:"sample_ratio"` // 采样比例 (0, 1]，0 表示全部采样
+}
+
 // ACPConfig ACP (Agent Client Protocol) 配置
 type ACPConfig struct {
 	Enabled               bool                           `mapstructure:"enabled" json:"enabled"`                                 // 是否启用 ACP
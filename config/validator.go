@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -39,6 +40,9 @@ func (v *Validator) Validate(cfg *Config) error {
 		v.validateTools,
 		v.validateGateway,
 		v.validateMemory,
+		v.validateTelemetry,
+		v.validateSessions,
+		v.validateLogging,
 	}
 
 	for _, validator := range validators {
@@ -129,11 +133,21 @@ func (v *Validator) validateAgentDefaults(defaults *AgentDefaults) error {
 		return errors.InvalidConfig("max_tokens must be between 1 and 128000")
 	}
 
+	if err := validateThinkingLevel(defaults.Thinking); err != nil {
+		return err
+	}
+
 	// Validate subagents configuration
 	// Note: Subagents is of type *SubagentsConfig, not *AgentSubagentConfig
 	// Skip validation for now as the structure differs
 	_ = defaults.Subagents
 
+	if defaults.Heartbeat != nil {
+		if err := validateHeartbeatConfig(defaults.Heartbeat); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -144,6 +158,16 @@ func (v *Validator) validateAgentConfig(agent *AgentConfig) error {
 		return errors.InvalidConfig("agent model cannot be empty")
 	}
 
+	if err := validateThinkingLevel(agent.Thinking); err != nil {
+		return err
+	}
+
+	for i := range agent.Hooks {
+		if err := validateHookConfig(&agent.Hooks[i]); err != nil {
+			return err
+		}
+	}
+
 	// Validate subagents configuration
 	if agent.Subagents != nil {
 		if err := v.validateSubagentsConfig(agent.Subagents); err != nil {
@@ -151,6 +175,55 @@ func (v *Validator) validateAgentConfig(agent *AgentConfig) error {
 		}
 	}
 
+	if agent.Heartbeat != nil {
+		if err := validateHeartbeatConfig(agent.Heartbeat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateThinkingLevel validates an extended thinking level (empty means off)
+func validateThinkingLevel(level string) error {
+	switch level {
+	case "", "off", "minimal", "low", "medium", "high", "xhigh":
+		return nil
+	}
+	return errors.InvalidConfig(fmt.Sprintf("invalid thinking level: %s (must be off, minimal, low, medium, high or xhigh)", level))
+}
+
+// validateHookConfig validates a lifecycle hook
+func validateHookConfig(hook *HookConfig) error {
+	stages := []string{"pre_llm", "post_llm", "pre_tool", "post_tool", "inbound", "outbound"}
+	if !slices.Contains(stages, hook.Stage) {
+		return errors.InvalidConfig(fmt.Sprintf("invalid hook stage: %s (must be one of %s)", hook.Stage, strings.Join(stages, ", ")))
+	}
+
+	hasBuiltin := strings.TrimSpace(hook.Builtin) != ""
+	hasCommand := strings.TrimSpace(hook.Command) != ""
+	if hasBuiltin == hasCommand {
+		return errors.InvalidConfig(fmt.Sprintf("hook %q must set exactly one of builtin or command", hook.Name))
+	}
+
+	if hook.Timeout < 0 {
+		return errors.InvalidConfig(fmt.Sprintf("hook %q timeout cannot be negative", hook.Name))
+	}
+
+	return nil
+}
+
+// validateHeartbeatConfig validates heartbeat interval and delivery target
+func validateHeartbeatConfig(hb *HeartbeatConfig) error {
+	if hb.Every != 0 && hb.Every < time.Minute {
+		return errors.InvalidConfig("heartbeat every must be at least 1m")
+	}
+	if hb.AckMaxChars < 0 {
+		return errors.InvalidConfig("heartbeat ack_max_chars cannot be negative")
+	}
+	if (hb.Channel == "") != (hb.ChatID == "") {
+		return errors.InvalidConfig("heartbeat channel and chat_id must be set together")
+	}
 	return nil
 }
 
@@ -201,23 +274,36 @@ func (v *Validator) validateProviders(cfg *Config) error {
 		}
 	}
 
+	// Validate local provider (no API key required)
+	if cfg.Providers.Local.Enabled {
+		hasProvider = true
+		if err := v.validateLocalProvider(&cfg.Providers.Local); err != nil {
+			return err
+		}
+	}
+
 	// Validate profiles
 	for i, profile := range cfg.Providers.Profiles {
 		if profile.Name == "" {
 			return errors.InvalidConfig(fmt.Sprintf("provider profile %d has empty name", i))
 		}
 
-		if profile.APIKey == "" {
-			return errors.InvalidConfig(fmt.Sprintf("provider profile '%s' has empty API key", profile.Name))
-		}
-
 		// Check if provider type is valid
-		validProviders := []string{"openai", "anthropic", "openrouter"}
+		validProviders := []string{"openai", "anthropic", "openrouter", "local"}
 		if !slices.Contains(validProviders, profile.Provider) {
 			return errors.InvalidConfig(fmt.Sprintf("provider profile '%s' has invalid provider type: %s",
 				profile.Name, profile.Provider))
 		}
 
+		// Local profiles talk to a local server and need no API key
+		if profile.Provider == "local" {
+			continue
+		}
+
+		if profile.APIKey == "" {
+			return errors.InvalidConfig(fmt.Sprintf("provider profile '%s' has empty API key", profile.Name))
+		}
+
 		if err := v.validateAPIKey(profile.APIKey); err != nil {
 			return errors.Wrap(err, errors.ErrCodeInvalidConfig,
 				fmt.Sprintf("invalid API key for profile '%s'", profile.Name))
@@ -238,6 +324,21 @@ func (v *Validator) validateProviders(cfg *Config) error {
 	return nil
 }
 
+// validateLocalProvider validates the local model provider configuration
+func (v *Validator) validateLocalProvider(local *LocalProviderConfig) error {
+	if local.BaseURL != "" && !strings.HasPrefix(local.BaseURL, "http://") && !strings.HasPrefix(local.BaseURL, "https://") {
+		return errors.InvalidConfig(fmt.Sprintf("invalid local provider base_url: %s", local.BaseURL))
+	}
+	if local.Timeout < 0 {
+		return errors.InvalidConfig("local provider timeout cannot be negative")
+	}
+	validFormats := []string{"", "auto", "native", "json"}
+	if !slices.Contains(validFormats, local.ToolCallFormat) {
+		return errors.InvalidConfig(fmt.Sprintf("invalid local provider tool_call_format: %s (must be auto, native or json)", local.ToolCallFormat))
+	}
+	return nil
+}
+
 // validateAPIKey validates API key format
 func (v *Validator) validateAPIKey(key string) error {
 	key = strings.TrimSpace(key)
@@ -297,6 +398,7 @@ func (v *Validator) validateChannels(cfg *Config) error {
 		v.validateWeWork,
 		v.validateDingTalk,
 		v.validateInfoflow,
+		v.validateChannelPolicies,
 	}
 
 	for _, validator := range validators {
@@ -443,6 +545,62 @@ func (v *Validator) validateInfoflow(channels *ChannelsConfig) error {
 	return nil
 }
 
+// validateChannelPolicies validates dm_policy and group policy of every channel and account
+func (v *Validator) validateChannelPolicies(channels *ChannelsConfig) error {
+	for _, ch := range []struct {
+		name     string
+		policy   string
+		group    GroupPolicyConfig
+		accounts map[string]ChannelAccountConfig
+	}{
+		{"telegram", channels.Telegram.DMPolicy, channels.Telegram.Group, channels.Telegram.Accounts},
+		{"whatsapp", channels.WhatsApp.DMPolicy, channels.WhatsApp.Group, channels.WhatsApp.Accounts},
+		{"feishu", channels.Feishu.DMPolicy, channels.Feishu.Group, channels.Feishu.Accounts},
+		{"qq", channels.QQ.DMPolicy, channels.QQ.Group, channels.QQ.Accounts},
+		{"wework", channels.WeWork.DMPolicy, GroupPolicyConfig{}, channels.WeWork.Accounts},
+		{"dingtalk", channels.DingTalk.DMPolicy, channels.DingTalk.Group, channels.DingTalk.Accounts},
+		{"infoflow", "", channels.Infoflow.Group, channels.Infoflow.Accounts},
+	} {
+		if err := validateDMPolicy(ch.name, ch.policy); err != nil {
+			return err
+		}
+		if err := validateGroupPolicy(ch.name, &ch.group); err != nil {
+			return err
+		}
+		for accountID, account := range ch.accounts {
+			if err := validateDMPolicy(ch.name+"."+accountID, account.DMPolicy); err != nil {
+				return err
+			}
+			if err := validateGroupPolicy(ch.name+"."+accountID, &account.Group); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateDMPolicy validates a direct message policy (empty means the channel default)
+func validateDMPolicy(channel, policy string) error {
+	switch policy {
+	case "", "open", "pairing", "allowlist", "disabled", "closed":
+		return nil
+	}
+	return errors.InvalidConfig(fmt.Sprintf("invalid %s dm_policy: %s (must be open, pairing, allowlist or disabled)", channel, policy))
+}
+
+// validateGroupPolicy validates the group reply mode and history limit
+func validateGroupPolicy(channel string, group *GroupPolicyConfig) error {
+	switch group.ReplyMode {
+	case "", "thread", "inline":
+	default:
+		return errors.InvalidConfig(fmt.Sprintf("invalid %s group reply_mode: %s (must be thread or inline)", channel, group.ReplyMode))
+	}
+	if group.HistoryLimit < 0 {
+		return errors.InvalidConfig(fmt.Sprintf("%s group history_limit cannot be negative", channel))
+	}
+	return nil
+}
+
 // validateTools validates tool configuration
 func (v *Validator) validateTools(cfg *Config) error {
 	if err := v.validateShellTool(&cfg.Tools.Shell); err != nil {
@@ -487,11 +645,47 @@ func (v *Validator) validateShellTool(shell *ShellToolConfig) error {
 		}
 	}
 
+	// Validate command policy
+	switch shell.Policy.RiskAction {
+	case "", "approve", "deny", "allow":
+	default:
+		return errors.InvalidConfig(fmt.Sprintf("invalid shell policy risk_action: %s (must be approve, deny or allow)", shell.Policy.RiskAction))
+	}
+	for i, rule := range shell.Policy.Rules {
+		if rule.Command == "" {
+			return errors.InvalidConfig(fmt.Sprintf("shell policy rule %d: command is required", i))
+		}
+		switch rule.Action {
+		case "allow", "deny", "approve":
+		default:
+			return errors.InvalidConfig(fmt.Sprintf("shell policy rule %d: invalid action %q (must be allow, deny or approve)", i, rule.Action))
+		}
+	}
+
 	// Validate sandbox configuration
 	if shell.Sandbox.Enabled {
 		if shell.Sandbox.Image == "" {
 			return errors.InvalidConfig("sandbox image is required when enabled")
 		}
+		switch shell.Sandbox.Scope {
+		case "", "session", "agent", "none":
+		default:
+			return errors.InvalidConfig(fmt.Sprintf("invalid sandbox scope: %s (must be session, agent or none)", shell.Sandbox.Scope))
+		}
+		if shell.Sandbox.CPUs < 0 {
+			return errors.InvalidConfig("sandbox cpus must not be negative")
+		}
+		if shell.Sandbox.PidsLimit < 0 {
+			return errors.InvalidConfig("sandbox pids_limit must not be negative")
+		}
+		if shell.Sandbox.IdleTimeout < 0 {
+			return errors.InvalidConfig("sandbox idle_timeout must not be negative")
+		}
+		for _, mount := range shell.Sandbox.Mounts {
+			if parts := strings.Split(mount, ":"); len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+				return errors.InvalidConfig(fmt.Sprintf("invalid sandbox mount: %s (expected host:container)", mount))
+			}
+		}
 	}
 
 	return nil
@@ -517,6 +711,10 @@ func (v *Validator) validateBrowserTool(browser *BrowserToolConfig) error {
 		return errors.InvalidConfig("browser timeout must be between 1 and 600 seconds")
 	}
 
+	if browser.IdleTimeout < 0 {
+		return errors.InvalidConfig("browser idle_timeout must not be negative")
+	}
+
 	return nil
 }
 
@@ -539,6 +737,10 @@ func (v *Validator) validateGateway(cfg *Config) error {
 		return err
 	}
 
+	if err := v.validateGatewayAuth(&cfg.Gateway.Auth); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -575,5 +777,64 @@ func (v *Validator) validateMemory(cfg *Config) error {
 		return errors.InvalidConfig(fmt.Sprintf("invalid memory backend: %s", cfg.Memory.Backend))
 	}
 
+	embedding := cfg.Memory.Builtin.Embedding
+	validProviders := []string{"", "auto", "local", "openai"}
+	if !slices.Contains(validProviders, embedding.Provider) {
+		return errors.InvalidConfig(fmt.Sprintf("invalid memory embedding provider: %s", embedding.Provider))
+	}
+	if embedding.Dimension < 0 {
+		return errors.InvalidConfig("memory embedding dimension cannot be negative")
+	}
+
+	return nil
+}
+
+// validateGatewayAuth validates gateway token and scope configuration
+func (v *Validator) validateGatewayAuth(auth *GatewayAuthConfig) error {
+	validScopes := []string{"read", "operator", "admin"}
+	for method, scope := range auth.MethodScopes {
+		if !slices.Contains(validScopes, scope) {
+			return errors.InvalidConfig(fmt.Sprintf("invalid scope %q for gateway method %s", scope, method))
+		}
+	}
+	return nil
+}
+
+// validateTelemetry validates metrics and tracing configuration
+func (v *Validator) validateTelemetry(cfg *Config) error {
+	metrics := cfg.Telemetry.Metrics
+	if metrics.Path != "" && !strings.HasPrefix(metrics.Path, "/") {
+		return errors.InvalidConfig("telemetry metrics path must start with /")
+	}
+
+	tracing := cfg.Telemetry.Tracing
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		return errors.InvalidConfig("telemetry tracing sample_ratio must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// validateSessions validates the session storage backend and redact patterns
+func (v *Validator) validateSessions(cfg *Config) error {
+	switch cfg.Sessions.Backend {
+	case "", "jsonl", "sqlite":
+	default:
+		return errors.InvalidConfig(fmt.Sprintf("invalid sessions backend %q (valid: jsonl, sqlite)", cfg.Sessions.Backend))
+	}
+	for _, pattern := range cfg.Sessions.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.InvalidConfig(fmt.Sprintf("invalid sessions redact pattern %q: %v", pattern, err))
+		}
+	}
+	return nil
+}
+
+// validateLogging validates log rotation and buffer sizes
+func (v *Validator) validateLogging(cfg *Config) error {
+	logging := cfg.Logging
+	if logging.MaxSizeMB < 0 || logging.MaxBackups < 0 || logging.BufferSize < 0 {
+		return errors.InvalidConfig("logging max_size_mb, max_backups and buffer_size must not be negative")
+	}
 	return nil
 }
@@ -157,3 +157,47 @@ func TestValidatorMissingProvider(t *testing.T) {
 		t.Error("expected error when no provider is configured")
 	}
 }
+
+func TestValidateHookConfig(t *testing.T) {
+	tests := []struct {
+		hook  HookConfig
+		valid bool
+	}{
+		{HookConfig{Stage: "pre_tool", Builtin: "deny_pattern"}, true},
+		{HookConfig{Stage: "outbound", Command: "/usr/local/bin/scrub"}, true},
+		{HookConfig{Stage: "before_tool", Builtin: "deny_pattern"}, false},
+		{HookConfig{Stage: "inbound"}, false},
+		{HookConfig{Stage: "inbound", Builtin: "deny_pattern", Command: "scrub"}, false},
+		{HookConfig{Stage: "inbound", Command: "scrub", Timeout: -1}, false},
+	}
+	for _, tt := range tests {
+		err := validateHookConfig(&tt.hook)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateHookConfig(%+v) = %v, want valid=%v", tt.hook, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateChannelPolicies(t *testing.T) {
+	validator := NewValidator(true)
+	channels := &ChannelsConfig{}
+	channels.Telegram.DMPolicy = "pairing"
+	channels.Feishu.DMPolicy = "closed"
+	if err := validator.validateChannelPolicies(channels); err != nil {
+		t.Fatalf("expected valid policies, got %v", err)
+	}
+
+	channels.QQ.Accounts = map[string]ChannelAccountConfig{"work": {DMPolicy: "friends"}}
+	if err := validator.validateChannelPolicies(channels); err == nil {
+		t.Error("expected an invalid account dm_policy to fail")
+	}
+	channels.QQ.Accounts = nil
+	channels.Telegram.Group.ReplyMode = "inline"
+	if err := validator.validateChannelPolicies(channels); err != nil {
+		t.Fatalf("expected a valid group policy, got %v", err)
+	}
+	channels.Telegram.Group.HistoryLimit = -1
+	if err := validator.validateChannelPolicies(channels); err == nil {
+		t.Error("expected a negative history_limit to fail")
+	}
+}
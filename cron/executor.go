@@ -8,14 +8,24 @@ import (
 	"github.com/google/uuid"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/telemetry"
 	"go.uber.org/zap"
 )
 
+// SystemEventSink receives the payload of next-heartbeat jobs so it can ride
+// along with the next heartbeat turn instead of waking the agent immediately
+type SystemEventSink interface {
+	// EnqueueSystemEvent queues text from source, returning false when no
+	// heartbeat is running to pick it up
+	EnqueueSystemEvent(source, text string) bool
+}
+
 // JobExecutor handles execution of cron jobs
 type JobExecutor struct {
 	bus       *bus.MessageBus
 	runLogger *RunLogger
 	timeout   time.Duration
+	events    SystemEventSink
 }
 
 // NewJobExecutor creates a new job executor
@@ -93,6 +103,12 @@ func (e *JobExecutor) Execute(ctx context.Context, job *Job) error {
 	runLog.Error = errMsg
 	runLog.Duration = finishTime.Sub(startTime)
 
+	jobLabel := job.Name
+	if jobLabel == "" {
+		jobLabel = job.ID
+	}
+	telemetry.RecordCronRun(jobLabel, status, runLog.Duration)
+
 	// Log the run
 	if e.runLogger != nil {
 		if err := e.runLogger.LogRun(runLog); err != nil {
@@ -130,8 +146,29 @@ func (e *JobExecutor) Execute(ctx context.Context, job *Job) error {
 	return nil
 }
 
+// queueForHeartbeat hands next-heartbeat jobs to the heartbeat queue,
+// returning false when the job should be published immediately instead
+func (e *JobExecutor) queueForHeartbeat(job *Job, text string) bool {
+	if job.WakeMode != WakeModeNextHeartbeat || e.events == nil {
+		return false
+	}
+	source := job.Name
+	if source == "" {
+		source = job.ID
+	}
+	if !e.events.EnqueueSystemEvent("cron:"+source, text) {
+		return false
+	}
+	logger.Debug("Queued cron job for next heartbeat", zap.String("job_id", job.ID))
+	return true
+}
+
 // executeSystemEvent executes a system event job
 func (e *JobExecutor) executeSystemEvent(ctx context.Context, job *Job) error {
+	if e.queueForHeartbeat(job, job.Payload.SystemEventType) {
+		return nil
+	}
+
 	// Publish system event to bus
 	msg := &bus.InboundMessage{
 		Channel:  "cron",
@@ -152,6 +189,10 @@ func (e *JobExecutor) executeSystemEvent(ctx context.Context, job *Job) error {
 
 // executeAgentTurn executes an agent turn job
 func (e *JobExecutor) executeAgentTurn(ctx context.Context, job *Job) error {
+	if e.queueForHeartbeat(job, job.Payload.Message) {
+		return nil
+	}
+
 	// Publish message to bus for agent processing
 	msg := &bus.InboundMessage{
 		Channel:  "cron",
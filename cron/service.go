@@ -76,6 +76,11 @@ func NewService(config CronConfig, bus *bus.MessageBus) (*Service, error) {
 	return service, nil
 }
 
+// SetSystemEventSink routes next-heartbeat jobs to the heartbeat runner
+func (s *Service) SetSystemEventSink(sink SystemEventSink) {
+	s.executor.events = sink
+}
+
 // Start starts the cron service
 func (s *Service) Start(ctx context.Context) error {
 	s.jobsMutex.Lock()
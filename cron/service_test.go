@@ -73,3 +73,45 @@ func TestExecuteJobRejectsConcurrentRun(t *testing.T) {
 
 	<-firstDone
 }
+
+type recordingSink struct {
+	accept bool
+	events []string
+}
+
+func (s *recordingSink) EnqueueSystemEvent(source, text string) bool {
+	s.events = append(s.events, source+": "+text)
+	return s.accept
+}
+
+func TestNextHeartbeatJobsGoToSink(t *testing.T) {
+	messageBus := bus.NewMessageBus(1)
+	executor := NewJobExecutor(messageBus, nil, time.Second)
+	sink := &recordingSink{accept: true}
+	executor.events = sink
+
+	job := &Job{
+		ID:       "job-1",
+		Name:     "backup",
+		WakeMode: WakeModeNextHeartbeat,
+		Payload:  Payload{Type: PayloadTypeSystemEvent, SystemEventType: "backup finished"},
+	}
+	if err := executor.Execute(context.Background(), job); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(sink.events) != 1 || sink.events[0] != "cron:backup: backup finished" {
+		t.Fatalf("unexpected queued events %v", sink.events)
+	}
+	if messageBus.InboundCount() != 0 {
+		t.Fatal("expected the queued job not to be published")
+	}
+
+	// Without a running heartbeat the job is published immediately
+	sink.accept = false
+	if err := executor.Execute(context.Background(), job); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if messageBus.InboundCount() != 1 {
+		t.Fatal("expected the job to be published")
+	}
+}
@@ -0,0 +1,78 @@
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit 以 JUnit XML 格式写出结果
+// 断言失败记为 failure，运行出错（如缺少录制）记为 error
+func WriteJUnit(w io.Writer, results ...*SuiteResult) error {
+	doc := junitTestSuites{}
+	for _, result := range results {
+		suite := junitTestSuite{
+			Name:  result.Name,
+			Tests: len(result.Scenarios),
+			Time:  fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		for _, sc := range result.Scenarios {
+			tc := junitTestCase{
+				Name:      sc.Name,
+				ClassName: "eval." + result.Name,
+				Time:      fmt.Sprintf("%.3f", sc.Duration.Seconds()),
+				SystemOut: sc.FinalText,
+			}
+			switch {
+			case sc.Error != "":
+				suite.Errors++
+				tc.Error = &junitMessage{Message: sc.Error, Body: sc.Error}
+			case !sc.Passed:
+				suite.Failures++
+				tc.Failure = &junitMessage{Message: sc.Failures[0], Body: strings.Join(sc.Failures, "\n")}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
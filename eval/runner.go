@@ -0,0 +1,324 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// WorkspacePlaceholder 录制文件中代替临时工作区路径的占位符
+const WorkspacePlaceholder = "$WORKSPACE"
+
+// ToolFactory 为场景工作区创建工具，返回的 cleanup 在场景结束后调用
+type ToolFactory func(workspace string) (toolList []tools.Tool, cleanup func(), err error)
+
+// Options 评测运行选项
+type Options struct {
+	// Record 为 true 时调用 Provider 并把交互写入录制文件，否则从录制文件回放
+	Record bool
+	// Provider 录制时使用的真实提供商
+	Provider providers.Provider
+	// FixtureDir 录制文件目录，默认为套件文件旁的 fixtures
+	FixtureDir string
+	// Tools 工具工厂
+	Tools ToolFactory
+	// Scenario 只运行名称匹配的场景
+	Scenario string
+	// Timeout 单个场景的超时，默认 5 分钟
+	Timeout time.Duration
+}
+
+// ScenarioResult 场景结果
+type ScenarioResult struct {
+	Name        string        `json:"name"`
+	Passed      bool          `json:"passed"`
+	Failures    []string      `json:"failures,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	FinalText   string        `json:"final_text"`
+	ToolsCalled []string      `json:"tools_called"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// SuiteResult 套件结果
+type SuiteResult struct {
+	Name      string           `json:"name"`
+	Scenarios []ScenarioResult `json:"scenarios"`
+	Duration  time.Duration    `json:"duration"`
+}
+
+// Failed 返回未通过的场景数
+func (r *SuiteResult) Failed() int {
+	failed := 0
+	for _, sc := range r.Scenarios {
+		if !sc.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Runner 评测运行器
+type Runner struct {
+	opts Options
+}
+
+// NewRunner 创建评测运行器
+func NewRunner(opts Options) *Runner {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	return &Runner{opts: opts}
+}
+
+// Run 运行套件中的场景
+func (r *Runner) Run(ctx context.Context, suite *Suite) (*SuiteResult, error) {
+	if r.opts.Record && r.opts.Provider == nil {
+		return nil, fmt.Errorf("recording requires a provider")
+	}
+
+	start := time.Now()
+	result := &SuiteResult{Name: suite.Name}
+	for i := range suite.Scenarios {
+		scenario := &suite.Scenarios[i]
+		if r.opts.Scenario != "" && scenario.Name != r.opts.Scenario {
+			continue
+		}
+		result.Scenarios = append(result.Scenarios, r.runScenario(ctx, suite, scenario))
+	}
+	if len(result.Scenarios) == 0 {
+		return nil, fmt.Errorf("no scenario named %s in suite %s", r.opts.Scenario, suite.Name)
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// runScenario runs one scenario in a fresh workspace and checks its expectations
+func (r *Runner) runScenario(ctx context.Context, suite *Suite, scenario *Scenario) ScenarioResult {
+	start := time.Now()
+	result := ScenarioResult{Name: scenario.Name, ToolsCalled: []string{}}
+	fail := func(err error) ScenarioResult {
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	workspace, err := os.MkdirTemp("", "goclaw-eval-*")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(workspace)
+	// Resolve symlinks so tool output uses the same path as the placeholder mapping
+	if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+		workspace = resolved
+	}
+	for name, content := range scenario.Files {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fail(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fail(err)
+		}
+	}
+
+	replacements := map[string]string{workspace: WorkspacePlaceholder}
+	fixturePath := suite.FixturePath(r.opts.FixtureDir, scenario)
+	var provider providers.Provider
+	var recorder *providers.RecordingProvider
+	if r.opts.Record {
+		recorder = providers.NewRecordingProvider(r.opts.Provider, replacements)
+		provider = recorder
+	} else {
+		fixture, err := providers.LoadFixture(fixturePath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fail(fmt.Errorf("no fixture at %s, run with --record first", fixturePath))
+			}
+			return fail(err)
+		}
+		provider = providers.NewReplayProvider(fixture, replacements)
+	}
+
+	toolRegistry, cleanup, err := r.buildTools(workspace, scenario.Tools)
+	if err != nil {
+		return fail(err)
+	}
+	defer cleanup()
+
+	sessionDir, err := os.MkdirTemp("", "goclaw-eval-sessions-*")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(sessionDir)
+	sessionMgr, err := session.NewManager(sessionDir)
+	if err != nil {
+		return fail(err)
+	}
+
+	messageBus := bus.NewMessageBus(10)
+	defer messageBus.Close()
+	ag, err := agent.NewAgent(&agent.NewAgentConfig{
+		Bus:          messageBus,
+		Provider:     provider,
+		SessionMgr:   sessionMgr,
+		Tools:        toolRegistry,
+		Context:      agent.NewContextBuilder(agent.NewMemoryStore(workspace), workspace),
+		Workspace:    workspace,
+		MaxIteration: suite.MaxIterations,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+	prompt := agent.AgentMessage{
+		Role:      agent.RoleUser,
+		Content:   []agent.ContentBlock{agent.TextContent{Text: scenario.Prompt}},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	messages, runErr := ag.GetOrchestrator().Fork().Run(runCtx, []agent.AgentMessage{prompt})
+
+	if recorder != nil && runErr == nil {
+		if err := recorder.Fixture().Save(fixturePath); err != nil {
+			return fail(fmt.Errorf("failed to save fixture: %w", err))
+		}
+	}
+	if runErr != nil {
+		return fail(runErr)
+	}
+
+	result.FinalText, result.ToolsCalled = summarize(messages)
+	result.Failures = check(&scenario.Expect, result.FinalText, result.ToolsCalled, workspace)
+	result.Passed = len(result.Failures) == 0
+	result.Duration = time.Since(start)
+	return result
+}
+
+// buildTools registers the tools of the factory, keeping only allowed ones when a list is given
+func (r *Runner) buildTools(workspace string, allowed []string) (*agent.ToolRegistry, func(), error) {
+	registry := agent.NewToolRegistry()
+	cleanup := func() {}
+	if r.opts.Tools == nil {
+		return registry, cleanup, nil
+	}
+
+	toolList, toolCleanup, err := r.opts.Tools(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if toolCleanup != nil {
+		cleanup = toolCleanup
+	}
+
+	allow := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allow[name] = true
+	}
+	for _, tool := range toolList {
+		if len(allow) > 0 && !allow[tool.Name()] {
+			continue
+		}
+		if err := registry.RegisterExisting(tool); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	for name := range allow {
+		if !registry.Has(name) {
+			cleanup()
+			return nil, nil, fmt.Errorf("unknown tool %s", name)
+		}
+	}
+	return registry, cleanup, nil
+}
+
+// summarize returns the text of the last assistant message and the tools called in order
+func summarize(messages []agent.AgentMessage) (string, []string) {
+	final := ""
+	called := []string{}
+	for _, msg := range messages {
+		if msg.Role != agent.RoleAssistant {
+			continue
+		}
+		var text []string
+		for _, block := range msg.Content {
+			switch b := block.(type) {
+			case agent.TextContent:
+				text = append(text, b.Text)
+			case agent.ToolCallContent:
+				called = append(called, b.Name)
+			}
+		}
+		if len(text) > 0 {
+			final = strings.Join(text, "")
+		}
+	}
+	return final, called
+}
+
+// check evaluates the expectations and returns one message per failed assertion
+func check(expect *Expectations, final string, called []string, workspace string) []string {
+	var failures []string
+	for _, s := range expect.FinalContains {
+		if !strings.Contains(final, s) {
+			failures = append(failures, fmt.Sprintf("final text does not contain %q", s))
+		}
+	}
+	for _, s := range expect.FinalNotContains {
+		if strings.Contains(final, s) {
+			failures = append(failures, fmt.Sprintf("final text contains %q", s))
+		}
+	}
+	if expect.FinalMatches != "" {
+		re, err := regexp.Compile(expect.FinalMatches)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid final_matches: %v", err))
+		} else if !re.MatchString(final) {
+			failures = append(failures, fmt.Sprintf("final text does not match %q", expect.FinalMatches))
+		}
+	}
+
+	calledSet := make(map[string]bool, len(called))
+	for _, name := range called {
+		calledSet[name] = true
+	}
+	for _, name := range expect.ToolsCalled {
+		if !calledSet[name] {
+			failures = append(failures, fmt.Sprintf("tool %s was not called (called: %s)", name, strings.Join(called, ", ")))
+		}
+	}
+	for _, name := range expect.ToolsNotCalled {
+		if calledSet[name] {
+			failures = append(failures, fmt.Sprintf("tool %s was called", name))
+		}
+	}
+
+	for _, file := range expect.Files {
+		data, err := os.ReadFile(filepath.Join(workspace, file.Path))
+		switch {
+		case file.Absent:
+			if err == nil {
+				failures = append(failures, fmt.Sprintf("file %s exists", file.Path))
+			}
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("file %s: %v", file.Path, errors.Unwrap(err)))
+		case file.Equals != nil && string(data) != *file.Equals:
+			failures = append(failures, fmt.Sprintf("file %s is %q, want %q", file.Path, string(data), *file.Equals))
+		case file.Contains != "" && !strings.Contains(string(data), file.Contains):
+			failures = append(failures, fmt.Sprintf("file %s does not contain %q", file.Path, file.Contains))
+		}
+	}
+	return failures
+}
@@ -0,0 +1,182 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/providers"
+)
+
+var workspacePattern = regexp.MustCompile(`Your working directory is: (\S+)`)
+
+// writerProvider stands in for a live model: it writes hello.txt into the
+// workspace named in the system prompt and then reports back
+type writerProvider struct {
+	calls int
+}
+
+func (p *writerProvider) Chat(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls++
+	last := messages[len(messages)-1]
+	if last.Role == "tool" {
+		return &providers.Response{Content: "Created hello.txt"}, nil
+	}
+	workspace := ""
+	for _, msg := range messages {
+		if m := workspacePattern.FindStringSubmatch(msg.Content); m != nil {
+			workspace = m[1]
+		}
+	}
+	return &providers.Response{ToolCalls: []providers.ToolCall{{
+		ID:     "call_1",
+		Name:   "write_file",
+		Params: map[string]interface{}{"path": filepath.Join(workspace, "hello.txt"), "content": "hi there"},
+	}}}, nil
+}
+
+func (p *writerProvider) ChatWithTools(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, toolDefs, options...)
+}
+
+func (p *writerProvider) Close() error {
+	return nil
+}
+
+const testSuite = `
+name: files
+max_iterations: 5
+scenarios:
+  - name: write-hello
+    prompt: Create hello.txt
+    files:
+      notes/existing.txt: keep me
+    tools: [write_file, read_file]
+    expect:
+      final_contains: [hello.txt]
+      tools_called: [write_file]
+      tools_not_called: [read_file]
+      files:
+        - path: hello.txt
+          equals: hi there
+        - path: notes/existing.txt
+          contains: keep
+        - path: other.txt
+          absent: true
+  - name: strict
+    prompt: Create hello.txt
+    tools: [write_file]
+    expect:
+      final_matches: "^Deleted"
+      files:
+        - path: missing.txt
+`
+
+func fileTools(workspace string) ([]tools.Tool, func(), error) {
+	return tools.NewFileSystemTool(nil, nil, workspace).GetTools(), nil, nil
+}
+
+func TestRecordThenReplaySuite(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "files.yaml"), []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadSuite("files", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &writerProvider{}
+	recorded, err := NewRunner(Options{Record: true, Provider: live, Tools: fileTools}).Run(context.Background(), suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recorded.Scenarios[0].Passed {
+		t.Fatalf("expected the recorded scenario to pass: %+v", recorded.Scenarios[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fixtures", "files", "write-hello.json")); err != nil {
+		t.Fatalf("expected a fixture to be written: %v", err)
+	}
+
+	// Replaying runs in a different workspace without calling the live provider
+	calls := live.calls
+	replayed, err := NewRunner(Options{Tools: fileTools}).Run(context.Background(), suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.calls != calls {
+		t.Error("replay called the live provider")
+	}
+	first, second := replayed.Scenarios[0], replayed.Scenarios[1]
+	if !first.Passed || first.FinalText != "Created hello.txt" || strings.Join(first.ToolsCalled, ",") != "write_file" {
+		t.Errorf("unexpected replayed result %+v", first)
+	}
+	if second.Passed || len(second.Failures) != 2 {
+		t.Errorf("expected two failed assertions, got %+v", second)
+	}
+	if replayed.Failed() != 1 {
+		t.Errorf("expected one failed scenario, got %d", replayed.Failed())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, replayed); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, buf.String())
+	}
+	if doc.Suites[0].Tests != 2 || doc.Suites[0].Failures != 1 || doc.Suites[0].Cases[1].Failure == nil {
+		t.Errorf("unexpected JUnit report:\n%s", buf.String())
+	}
+}
+
+func TestReplayMissingOrStaleFixture(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "files.yaml"), []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadSuite(filepath.Join(dir, "files.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewRunner(Options{Tools: fileTools, Scenario: "write-hello"}).Run(context.Background(), suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Scenarios) != 1 || !strings.Contains(result.Scenarios[0].Error, "--record") {
+		t.Errorf("expected a missing fixture error, got %+v", result.Scenarios)
+	}
+
+	if _, err := NewRunner(Options{Record: true, Provider: &writerProvider{}, Tools: fileTools, Scenario: "write-hello"}).Run(context.Background(), suite); err != nil {
+		t.Fatal(err)
+	}
+	// A changed prompt no longer matches the recording
+	suite.Scenarios[0].Prompt = "Create hello.txt please"
+	result, _ = NewRunner(Options{Tools: fileTools, Scenario: "write-hello"}).Run(context.Background(), suite)
+	if !strings.Contains(result.Scenarios[0].Error, providers.ErrNoRecording.Error()) {
+		t.Errorf("expected a stale fixture to be reported, got %+v", result.Scenarios[0])
+	}
+}
+
+func TestLoadSuiteValidation(t *testing.T) {
+	dir := t.TempDir()
+	bad := "scenarios:\n  - name: escape\n    prompt: hi\n    files:\n      ../outside.txt: x\n"
+	path := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSuite(path, ""); err == nil {
+		t.Error("expected a file outside the workspace to be rejected")
+	}
+	if _, err := LoadSuite("nope", dir); err == nil {
+		t.Error("expected a missing suite to fail")
+	}
+}
@@ -0,0 +1,119 @@
+// Package eval 通过录制的提供商响应端到端回归测试 Agent 行为
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite 评测套件，对应一个 YAML 文件
+type Suite struct {
+	Name          string     `yaml:"name"`
+	Description   string     `yaml:"description,omitempty"`
+	MaxIterations int        `yaml:"max_iterations,omitempty"`
+	Scenarios     []Scenario `yaml:"scenarios"`
+
+	// Path 套件文件路径
+	Path string `yaml:"-"`
+}
+
+// Scenario 一个评测场景：在新的工作区中运行一轮对话并检查结果
+type Scenario struct {
+	Name   string            `yaml:"name"`
+	Prompt string            `yaml:"prompt"`
+	Files  map[string]string `yaml:"files,omitempty"` // 运行前写入工作区的文件
+	Tools  []string          `yaml:"tools,omitempty"` // 可用工具，为空时使用全部工具
+	Expect Expectations      `yaml:"expect"`
+}
+
+// Expectations 场景断言
+type Expectations struct {
+	FinalContains    []string     `yaml:"final_contains,omitempty"`
+	FinalNotContains []string     `yaml:"final_not_contains,omitempty"`
+	FinalMatches     string       `yaml:"final_matches,omitempty"` // 正则表达式
+	ToolsCalled      []string     `yaml:"tools_called,omitempty"`
+	ToolsNotCalled   []string     `yaml:"tools_not_called,omitempty"`
+	Files            []FileExpect `yaml:"files,omitempty"`
+}
+
+// FileExpect 工作区文件断言
+type FileExpect struct {
+	Path     string  `yaml:"path"`
+	Absent   bool    `yaml:"absent,omitempty"`
+	Contains string  `yaml:"contains,omitempty"`
+	Equals   *string `yaml:"equals,omitempty"`
+}
+
+// LoadSuite 读取套件
+// name 可以是文件路径，也可以是 dir 下不带扩展名的套件名
+func LoadSuite(name, dir string) (*Suite, error) {
+	path := name
+	if _, err := os.Stat(path); err != nil {
+		found := false
+		for _, ext := range []string{".yaml", ".yml"} {
+			candidate := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(candidate); err == nil {
+				path, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("suite not found: %s", name)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	suite.Path = path
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := suite.validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return &suite, nil
+}
+
+// validate checks that scenarios are named uniquely and have a prompt
+func (s *Suite) validate() error {
+	if len(s.Scenarios) == 0 {
+		return fmt.Errorf("no scenarios")
+	}
+	seen := make(map[string]bool)
+	for i, sc := range s.Scenarios {
+		if sc.Name == "" {
+			return fmt.Errorf("scenario %d has no name", i+1)
+		}
+		if seen[sc.Name] {
+			return fmt.Errorf("duplicate scenario %s", sc.Name)
+		}
+		seen[sc.Name] = true
+		if strings.TrimSpace(sc.Prompt) == "" {
+			return fmt.Errorf("scenario %s has no prompt", sc.Name)
+		}
+		for path := range sc.Files {
+			if filepath.IsAbs(path) || strings.HasPrefix(filepath.Clean(path), "..") {
+				return fmt.Errorf("scenario %s: file %s must be inside the workspace", sc.Name, path)
+			}
+		}
+	}
+	return nil
+}
+
+// FixturePath 返回场景录制文件的路径
+func (s *Suite) FixturePath(fixtureDir string, scenario *Scenario) string {
+	if fixtureDir == "" {
+		fixtureDir = filepath.Join(filepath.Dir(s.Path), "fixtures")
+	}
+	return filepath.Join(fixtureDir, s.Name, scenario.Name+".json")
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/smallnest/goclaw/agent/tools"
+)
+
+// SetApprovalQueue 设置命令审批队列，启用 approvals.* 方法
+// 新的审批请求以 approval.requested 事件广播给拥有 operator 权限的 WebSocket 连接，
+// 事件中包含完整命令和会话键，只读连接收不到
+func (s *Server) SetApprovalQueue(queue *tools.ApprovalQueue) {
+	s.mu.Lock()
+	s.approvals = queue
+	s.mu.Unlock()
+
+	queue.OnRequest(func(p tools.PendingApproval) {
+		s.BroadcastEvent("approval.requested", ScopeOperator, p)
+	})
+}
+
+// getApprovalQueue 获取命令审批队列
+func (s *Server) getApprovalQueue() (*tools.ApprovalQueue, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.approvals == nil {
+		return nil, fmt.Errorf("approval queue is not available")
+	}
+	return s.approvals, nil
+}
+
+// registerApprovalMethods 注册审批方法
+func (s *Server) registerApprovalMethods() {
+	// approvals.pending - 等待处理的审批请求
+	s.handler.registry.Register("approvals.pending", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		queue, err := s.getApprovalQueue()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"pending": queue.Pending()}, nil
+	})
+
+	// approvals.resolve - 放行或拒绝审批请求，参数 id 和 approved
+	s.handler.registry.Register("approvals.resolve", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		queue, err := s.getApprovalQueue()
+		if err != nil {
+			return nil, err
+		}
+		id, _ := params["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("id is required")
+		}
+		approved, ok := params["approved"].(bool)
+		if !ok {
+			return nil, fmt.Errorf("approved must be a boolean")
+		}
+		if err := queue.Resolve(id, approved); err != nil {
+			return nil, err
+		}
+		status := "denied"
+		if approved {
+			status = "approved"
+		}
+		return map[string]interface{}{"id": id, "status": status}, nil
+	})
+}
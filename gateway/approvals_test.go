@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/agent/tools"
+)
+
+func TestApprovalMethods(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "")
+	call := func(method string, params map[string]interface{}) *JSONRPCResponse {
+		return s.handler.HandleRequest("", &JSONRPCRequest{JSONRPC: "2.0", ID: "1", Method: method, Params: params})
+	}
+
+	if resp := call("approvals.pending", nil); resp.Error == nil || !strings.Contains(resp.Error.Message, "not available") {
+		t.Fatalf("expected an error without a queue, got %+v", resp)
+	}
+
+	queue := tools.NewApprovalQueue()
+	s.SetApprovalQueue(queue)
+	result := make(chan bool, 1)
+	go func() {
+		ok, _ := queue.RequestApproval(context.Background(), tools.ApprovalRequest{Tool: "run_shell", Command: "git push --force"})
+		result <- ok
+	}()
+
+	var pending []tools.PendingApproval
+	for len(pending) == 0 {
+		time.Sleep(time.Millisecond)
+		resp := call("approvals.pending", nil)
+		if resp.Error != nil {
+			t.Fatal(resp.Error.Message)
+		}
+		pending = resp.Result.(map[string]interface{})["pending"].([]tools.PendingApproval)
+	}
+
+	if resp := call("approvals.resolve", map[string]interface{}{"id": pending[0].ID}); resp.Error == nil {
+		t.Error("expected approvals.resolve to require approved")
+	}
+	resp := call("approvals.resolve", map[string]interface{}{"id": pending[0].ID, "approved": true})
+	if resp.Error != nil || resp.Result.(map[string]interface{})["status"] != "approved" {
+		t.Fatalf("unexpected approvals.resolve result %+v", resp)
+	}
+	if !<-result {
+		t.Error("expected the command to be approved")
+	}
+}
+
+func TestApprovalEventsRequireOperatorScope(t *testing.T) {
+	s, store, _ := newAuthTestServer(t)
+	ts := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer ts.Close()
+
+	dial := func(scope Scope) *websocket.Conn {
+		t.Helper()
+		token, _, err := store.Create(string(scope), []Scope{scope})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws?token="+token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	read := func(conn *websocket.Conn) wsTestMessage {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var msg wsTestMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	reader := dial(ScopeRead)
+	operator := dial(ScopeOperator)
+	read(reader)   // connected
+	read(operator) // connected
+
+	queue := tools.NewApprovalQueue()
+	s.SetApprovalQueue(queue)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_, _ = queue.RequestApproval(ctx, tools.ApprovalRequest{Tool: "run_shell", Command: "git push --force", SessionKey: "secret-session"})
+	}()
+
+	if msg := read(operator); msg.Method != "approval.requested" {
+		t.Fatalf("expected the operator to receive approval.requested, got %+v", msg)
+	}
+
+	// The event was sent to every allowed connection before this request
+	_ = reader.WriteJSON(JSONRPCRequest{JSONRPC: "2.0", ID: "1", Method: "health"})
+	if msg := read(reader); msg.ID != "1" || msg.Method != "" {
+		t.Errorf("expected a read-scope connection not to receive the approval event, got %+v", msg)
+	}
+}
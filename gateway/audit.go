@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// AuditEntry 一次 RPC 调用的审计记录
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	TokenID    string    `json:"token_id,omitempty"`
+	TokenName  string    `json:"token_name"`
+	Transport  string    `json:"transport"` // http 或 ws
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Method     string    `json:"method"`
+	Scope      Scope     `json:"scope"`
+	Allowed    bool      `json:"allowed"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// AuditLog 以 JSON 行追加写入审计记录，同时输出到日志
+type AuditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// DefaultAuditPath 返回默认的审计日志路径
+func DefaultAuditPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "gateway", "audit.jsonl"), nil
+}
+
+// NewAuditLog 创建追加写入 path 的审计日志
+func NewAuditLog(path string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{w: f, closer: f}, nil
+}
+
+// NewWriterAuditLog 创建写入 w 的审计日志
+func NewWriterAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// Record 写入一条审计记录，nil 审计日志只输出到日志
+func (a *AuditLog) Record(entry AuditEntry) {
+	logger.Info("Gateway RPC call",
+		zap.String("token", entry.TokenName),
+		zap.String("token_id", entry.TokenID),
+		zap.String("transport", entry.Transport),
+		zap.String("method", entry.Method),
+		zap.Bool("allowed", entry.Allowed),
+		zap.String("error", entry.Error))
+
+	if a == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.w == nil {
+		return
+	}
+	if _, err := a.w.Write(append(data, '\n')); err != nil {
+		logger.Warn("Failed to write audit log", zap.Error(err))
+	}
+}
+
+// Close 关闭审计日志
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.w = nil
+	if a.closer != nil {
+		err := a.closer.Close()
+		a.closer = nil
+		return err
+	}
+	return nil
+}
+
+// SetAuditLog 设置审计日志
+func (s *Server) SetAuditLog(audit *AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = audit
+}
+
+// auditLog 返回当前的审计日志
+func (s *Server) auditLog() *AuditLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.audit
+}
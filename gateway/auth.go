@@ -0,0 +1,213 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scope 网关权限范围，admin 包含 operator，operator 包含 read
+type Scope string
+
+const (
+	// ScopeRead 只读：查询状态、会话、通道和任务
+	ScopeRead Scope = "read"
+	// ScopeOperator 运维：发送消息、运行 Agent 和任务、管理会话
+	ScopeOperator Scope = "operator"
+	// ScopeAdmin 管理：修改配置、管理定时任务、创建 ACP 会话
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes 所有权限范围，按权限从低到高排列
+var Scopes = []Scope{ScopeRead, ScopeOperator, ScopeAdmin}
+
+// ParseScope 解析权限范围
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(strings.ToLower(strings.TrimSpace(s)))
+	if !scope.Valid() {
+		return "", fmt.Errorf("invalid scope %q (valid: read, operator, admin)", s)
+	}
+	return scope, nil
+}
+
+// Valid 是否为已知的权限范围
+func (s Scope) Valid() bool {
+	return s.rank() > 0
+}
+
+func (s Scope) rank() int {
+	for i, scope := range Scopes {
+		if scope == s {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// methodScopes 各 RPC 方法默认需要的权限范围，未列出的方法需要 admin
+var methodScopes = map[string]Scope{
+	"health":           ScopeRead,
+	"logs.get":         ScopeOperator,
+	"logs.subscribe":   ScopeOperator,
+	"logs.unsubscribe": ScopeOperator,
+	"config.get":       ScopeAdmin,
+	"config.set":       ScopeAdmin,
+	"agent":            ScopeOperator,
+	"agent.wait":       ScopeOperator,
+	"sessions.list":    ScopeRead,
+	"sessions.get":     ScopeRead,
+	"sessions.clear":   ScopeOperator,
+	"channels.status":  ScopeRead,
+	"channels.list":    ScopeRead,
+	"send":             ScopeOperator,
+	"chat.send":        ScopeOperator,
+	"browser.request":  ScopeOperator,
+	"cron.status":      ScopeRead,
+	"cron.list":        ScopeRead,
+	"cron.runs":        ScopeRead,
+	"cron.run":         ScopeOperator,
+	"cron.add":         ScopeAdmin,
+	"cron.update":      ScopeAdmin,
+	"cron.remove":      ScopeAdmin,
+	"acp_status":       ScopeRead,
+	"acp_list":         ScopeRead,
+	"acp_cancel":       ScopeOperator,
+	"acp_close":        ScopeOperator,
+	"acp_spawn":        ScopeAdmin,
+	"acp_set_mode":     ScopeAdmin,
+
+	"acp_set_config_option": ScopeAdmin,
+
+	"heartbeat.last":    ScopeRead,
+	"heartbeat.enable":  ScopeOperator,
+	"heartbeat.disable": ScopeOperator,
+
+	"approvals.pending": ScopeOperator,
+	"approvals.resolve": ScopeOperator,
+}
+
+// Principal 已认证的调用方
+type Principal struct {
+	TokenID string
+	Name    string
+	Scopes  []Scope
+}
+
+// Allows 调用方是否拥有 required 或更高的权限范围
+func (p *Principal) Allows(required Scope) bool {
+	if p == nil {
+		return false
+	}
+	for _, scope := range p.Scopes {
+		if scope.rank() >= required.rank() {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// anonymousPrincipal 未启用认证时的调用方
+	anonymousPrincipal = &Principal{Name: "anonymous", Scopes: []Scope{ScopeAdmin}}
+	// configTokenPrincipal 使用配置文件中 auth_token 的调用方
+	configTokenPrincipal = &Principal{TokenID: "config", Name: "config", Scopes: []Scope{ScopeAdmin}}
+)
+
+// methodScope 返回方法需要的权限范围，配置中的 gateway.auth.method_scopes 优先
+func (s *Server) methodScope(method string) Scope {
+	if s.config != nil {
+		if scope, ok := s.config.Gateway.Auth.MethodScopes[method]; ok {
+			if parsed, err := ParseScope(scope); err == nil {
+				return parsed
+			}
+		}
+	}
+	if scope, ok := methodScopes[method]; ok {
+		return scope
+	}
+	return ScopeAdmin
+}
+
+// SetTokenStore 设置令牌存储
+func (s *Server) SetTokenStore(store *TokenStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = store
+}
+
+// authenticate 从请求中识别调用方，未启用认证时返回匿名管理员
+// 令牌可以放在 Authorization: Bearer 请求头或 token 查询参数中
+func (s *Server) authenticate(r *http.Request) (*Principal, bool) {
+	s.mu.RLock()
+	enabled, configToken, tokens := s.wsConfig.EnableAuth, s.wsConfig.AuthToken, s.tokens
+	s.mu.RUnlock()
+	if !enabled {
+		return anonymousPrincipal, true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil, false
+	}
+
+	// 使用恒定时间比较防止时序攻击
+	if configToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(configToken)) == 1 {
+		return configTokenPrincipal, true
+	}
+	if tokens != nil {
+		return tokens.Authenticate(token)
+	}
+	return nil, false
+}
+
+// authorizeHTTP 认证请求并检查权限范围，失败时返回 401 或 403
+func (s *Server) authorizeHTTP(r *http.Request, scope Scope) (*Principal, int) {
+	principal, ok := s.authenticate(r)
+	if !ok {
+		return nil, http.StatusUnauthorized
+	}
+	if !principal.Allows(scope) {
+		return principal, http.StatusForbidden
+	}
+	return principal, http.StatusOK
+}
+
+// callMethod 检查调用方的权限范围，执行 RPC 方法并记录审计日志
+func (s *Server) callMethod(principal *Principal, transport, remoteAddr, sessionID string, req *JSONRPCRequest) *JSONRPCResponse {
+	start := time.Now()
+	scope := s.methodScope(req.Method)
+
+	var resp *JSONRPCResponse
+	allowed := principal.Allows(scope)
+	if allowed {
+		resp = s.handler.HandleRequest(sessionID, req)
+	} else {
+		resp = NewErrorResponse(req.ID, ErrorForbidden, fmt.Sprintf("method %s requires the %s scope", req.Method, scope))
+	}
+
+	entry := AuditEntry{
+		Time:       start,
+		Transport:  transport,
+		RemoteAddr: remoteAddr,
+		SessionID:  sessionID,
+		Method:     req.Method,
+		Scope:      scope,
+		Allowed:    allowed,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if principal != nil {
+		entry.TokenID = principal.TokenID
+		entry.TokenName = principal.Name
+	}
+	if resp.Error != nil {
+		entry.Error = resp.Error.Message
+	}
+	s.auditLog().Record(entry)
+
+	return resp
+}
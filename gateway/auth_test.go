@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenStoreLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway", "tokens.json")
+	store := NewTokenStore(path)
+
+	token, record, err := store.Create("ci", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, TokenPrefix) || !strings.HasPrefix(token, record.Hint) {
+		t.Errorf("unexpected token %q hint %q", token, record.Hint)
+	}
+	if _, _, err := store.Create("ci", []Scope{ScopeAdmin}); err == nil {
+		t.Error("expected duplicate active name to fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(token)) {
+		t.Error("token store must not contain the plaintext token")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected token store permissions %v", info.Mode().Perm())
+	}
+
+	// A second store stands in for the running gateway
+	gatewayStore := NewTokenStore(path)
+	principal, ok := gatewayStore.Authenticate(token)
+	if !ok || principal.Name != "ci" || principal.TokenID != record.ID {
+		t.Fatalf("expected token to authenticate, got %+v", principal)
+	}
+	if _, ok := gatewayStore.Authenticate(TokenPrefix + "wrong"); ok {
+		t.Error("unknown token authenticated")
+	}
+
+	if _, err := store.Revoke("ci"); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the reload is not masked by a coarse mtime
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(path, future, future)
+	if _, ok := gatewayStore.Authenticate(token); ok {
+		t.Error("revoked token still authenticates")
+	}
+
+	records, err := store.List()
+	if err != nil || len(records) != 1 || records[0].Active() {
+		t.Errorf("unexpected records %+v %v", records, err)
+	}
+	if _, err := store.Revoke("ci"); err == nil {
+		t.Error("expected revoking twice to fail")
+	}
+}
+
+func TestScopeHierarchy(t *testing.T) {
+	admin := &Principal{Scopes: []Scope{ScopeAdmin}}
+	read := &Principal{Scopes: []Scope{ScopeRead}}
+	if !admin.Allows(ScopeRead) || !admin.Allows(ScopeOperator) {
+		t.Error("admin should include lower scopes")
+	}
+	if read.Allows(ScopeOperator) || !read.Allows(ScopeRead) {
+		t.Error("read should only allow read")
+	}
+	if (*Principal)(nil).Allows(ScopeRead) {
+		t.Error("nil principal should not be allowed")
+	}
+	if _, err := ParseScope("root"); err == nil {
+		t.Error("expected invalid scope error")
+	}
+
+	s, _ := newOpenAITestServer(t, "secret")
+	if s.methodScope("config.set") != ScopeAdmin || s.methodScope("health") != ScopeRead {
+		t.Error("unexpected default method scopes")
+	}
+	if s.methodScope("unknown.method") != ScopeAdmin {
+		t.Error("unknown methods should require admin")
+	}
+	s.config.Gateway.Auth.MethodScopes = map[string]string{"health": "operator"}
+	if s.methodScope("health") != ScopeOperator {
+		t.Error("configured method scope should override the default")
+	}
+}
+
+func newAuthTestServer(t *testing.T) (*Server, *TokenStore, *bytes.Buffer) {
+	t.Helper()
+	s, _ := newOpenAITestServer(t, "secret")
+	store := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	s.SetTokenStore(store)
+	audit := &bytes.Buffer{}
+	s.SetAuditLog(NewWriterAuditLog(audit))
+	return s, store, audit
+}
+
+func postRPC(s *Server, token, method string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": "1", "method": method})
+	req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.handleJSONRPC(rec, req)
+	return rec
+}
+
+func TestJSONRPCScopes(t *testing.T) {
+	s, store, audit := newAuthTestServer(t)
+	token, record, err := store.Create("dashboard", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := postRPC(s, "", "health"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+
+	rec := postRPC(s, token, "health")
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error != nil {
+		t.Errorf("expected health to succeed for a read token: %s", rec.Body.String())
+	}
+
+	rec = postRPC(s, token, "config.set")
+	resp = JSONRPCResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != ErrorForbidden {
+		t.Errorf("expected config.set to be forbidden for a read token: %s", rec.Body.String())
+	}
+
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(entries))
+	}
+	if !entries[0].Allowed || entries[0].Method != "health" || entries[0].TokenID != record.ID {
+		t.Errorf("unexpected audit entry %+v", entries[0])
+	}
+	if entries[1].Allowed || entries[1].Scope != ScopeAdmin || entries[1].TokenName != "dashboard" || entries[1].Transport != "http" {
+		t.Errorf("unexpected audit entry %+v", entries[1])
+	}
+}
+
+func TestHTTPEndpointScopes(t *testing.T) {
+	s, store, _ := newAuthTestServer(t)
+	token, _, err := store.Create("reader", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.handleOpenAIModels(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected read token to list models, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected read token to be forbidden from chat completions, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/ws?token="+token, nil)
+	if _, status := s.authorizeHTTP(req, ScopeRead); status != http.StatusOK {
+		t.Errorf("expected query token to authorize the websocket upgrade, got %d", status)
+	}
+}
+
+func TestAuthDisabledAllowsAll(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "")
+	principal, ok := s.authenticate(httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if !ok || !principal.Allows(ScopeAdmin) {
+		t.Errorf("expected anonymous admin when auth is disabled, got %+v", principal)
+	}
+}
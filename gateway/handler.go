@@ -41,6 +41,9 @@ func NewHandler(messageBus *bus.MessageBus, sessionMgr *session.Manager, channel
 	// 注册系统方法
 	h.registerSystemMethods()
 
+	// 注册日志方法
+	h.registerLogMethods()
+
 	// 注册 Agent 方法
 	h.registerAgentMethods()
 
@@ -108,19 +111,6 @@ func (h *Handler) registerSystemMethods() {
 			"version":   ProtocolVersion,
 		}, nil
 	})
-
-	// logs - 获取日志
-	h.registry.Register("logs.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		lines := 100
-		if l, ok := params["lines"].(float64); ok {
-			lines = int(l)
-		}
-		// 这里应该从日志中读取
-		return map[string]interface{}{
-			"lines": lines,
-			"logs":  []string{}, // 实际应该返回日志
-		}, nil
-	})
 }
 
 // registerAgentMethods 注册 Agent 方法
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/smallnest/goclaw/heartbeat"
+)
+
+// SetHeartbeatRunner 设置心跳运行器，启用 heartbeat.* 方法
+func (s *Server) SetHeartbeatRunner(runner *heartbeat.Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat = runner
+}
+
+// getHeartbeatRunner 获取心跳运行器
+func (s *Server) getHeartbeatRunner() (*heartbeat.Runner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.heartbeat == nil {
+		return nil, fmt.Errorf("heartbeat runner is not available")
+	}
+	return s.heartbeat, nil
+}
+
+// registerHeartbeatMethods 注册心跳方法
+func (s *Server) registerHeartbeatMethods() {
+	// heartbeat.last - 最近一次心跳的结果，agent_id 为空时返回所有 Agent 中最近的一次
+	s.handler.registry.Register("heartbeat.last", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		runner, err := s.getHeartbeatRunner()
+		if err != nil {
+			return nil, err
+		}
+		agentID, _ := params["agent_id"].(string)
+		result := map[string]interface{}{
+			"enabled": runner.Enabled(),
+			"agents":  runner.Agents(),
+		}
+		last := runner.Last(agentID)
+		if last == nil {
+			result["status"] = "never"
+			return result, nil
+		}
+		result["agent_id"] = last.AgentID
+		result["status"] = last.Status
+		result["timestamp"] = last.Timestamp.Unix()
+		result["duration_ms"] = last.DurationMs
+		result["events"] = last.Events
+		for key, value := range map[string]string{"reason": last.Reason, "reply": last.Reply, "error": last.Error} {
+			if value != "" {
+				result[key] = value
+			}
+		}
+		return result, nil
+	})
+
+	// heartbeat.enable - 恢复所有 Agent 的心跳
+	s.handler.registry.Register("heartbeat.enable", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return s.setHeartbeatEnabled(true)
+	})
+
+	// heartbeat.disable - 暂停所有 Agent 的心跳
+	s.handler.registry.Register("heartbeat.disable", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return s.setHeartbeatEnabled(false)
+	})
+}
+
+// setHeartbeatEnabled toggles the runner and reports the new state
+func (s *Server) setHeartbeatEnabled(enabled bool) (interface{}, error) {
+	runner, err := s.getHeartbeatRunner()
+	if err != nil {
+		return nil, err
+	}
+	if err := runner.SetEnabled(enabled); err != nil {
+		return nil, err
+	}
+	status := "enabled"
+	if !enabled {
+		status = "disabled"
+	}
+	if len(runner.Agents()) == 0 {
+		status += " (no agents have heartbeat configured)"
+	}
+	return map[string]interface{}{
+		"status": status,
+		"agents": runner.Agents(),
+	}, nil
+}
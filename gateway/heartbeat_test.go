@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/heartbeat"
+)
+
+func TestHeartbeatMethods(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "")
+	call := func(method string) *JSONRPCResponse {
+		return s.handler.HandleRequest("", &JSONRPCRequest{JSONRPC: "2.0", ID: "1", Method: method})
+	}
+
+	if resp := call("heartbeat.last"); resp.Error == nil || !strings.Contains(resp.Error.Message, "not available") {
+		t.Fatalf("expected an error without a runner, got %+v", resp)
+	}
+
+	s.SetHeartbeatRunner(heartbeat.NewRunner(nil, nil, nil, filepath.Join(t.TempDir(), "state.json")))
+	resp := call("heartbeat.last")
+	if result, ok := resp.Result.(map[string]interface{}); !ok || result["status"] != "never" || result["enabled"] != true {
+		t.Fatalf("unexpected heartbeat.last result %+v", resp)
+	}
+
+	resp = call("heartbeat.disable")
+	if result, ok := resp.Result.(map[string]interface{}); !ok || !strings.HasPrefix(result["status"].(string), "disabled") {
+		t.Fatalf("unexpected heartbeat.disable result %+v", resp)
+	}
+	resp = call("heartbeat.last")
+	if result := resp.Result.(map[string]interface{}); result["enabled"] != false {
+		t.Errorf("expected the runner to be disabled, got %+v", result)
+	}
+}
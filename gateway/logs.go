@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxLogPageLimit logs.get 单次最多返回的条数
+	maxLogPageLimit = 1000
+	// logStreamBuffer 每个订阅在内存中排队的条数，超过后从环形缓冲补发
+	logStreamBuffer = 256
+)
+
+// registerLogMethods 注册日志查询方法
+func (h *Handler) registerLogMethods() {
+	// logs.get - 按游标分页查询内存中的最近日志
+	h.registry.Register("logs.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		buf := logger.Logs()
+		if buf == nil {
+			return nil, fmt.Errorf("log buffer is not available")
+		}
+		query, err := parseLogQuery(params)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Query(query)
+	})
+}
+
+// registerLogStreamMethods 注册需要 WebSocket 连接推送的日志订阅方法
+func (s *Server) registerLogStreamMethods() {
+	// logs.subscribe - 通过 logs.entry 通知推送新日志，cursor 之后的历史日志会先补发
+	s.handler.registry.Register("logs.subscribe", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		conn, ok := s.getConnection(sessionID)
+		if !ok {
+			return nil, fmt.Errorf("logs.subscribe requires a WebSocket connection")
+		}
+		buf := logger.Logs()
+		if buf == nil {
+			return nil, fmt.Errorf("log buffer is not available")
+		}
+		query, err := parseLogQuery(params)
+		if err != nil {
+			return nil, err
+		}
+		if query.Cursor == 0 {
+			query.Cursor = buf.Cursor()
+		}
+
+		entries, cancel := buf.Subscribe(logStreamBuffer)
+		s.logStreamsMu.Lock()
+		if previous, ok := s.logStreams[sessionID]; ok {
+			previous()
+		}
+		s.logStreams[sessionID] = cancel
+		s.logStreamsMu.Unlock()
+
+		go s.streamLogs(conn, buf, query, entries)
+
+		return map[string]interface{}{
+			"subscribed": true,
+			"cursor":     query.Cursor,
+		}, nil
+	})
+
+	// logs.unsubscribe - 取消当前连接的日志订阅
+	s.handler.registry.Register("logs.unsubscribe", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"unsubscribed": s.stopLogStream(sessionID),
+		}, nil
+	})
+}
+
+// stopLogStream 取消连接的日志订阅，返回是否存在订阅
+func (s *Server) stopLogStream(sessionID string) bool {
+	s.logStreamsMu.Lock()
+	cancel, ok := s.logStreams[sessionID]
+	delete(s.logStreams, sessionID)
+	s.logStreamsMu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// streamLogs 向连接推送日志，直到订阅被取消或发送失败
+// 通道中出现序号间隔时（过滤掉的条目或消费过慢被丢弃），从环形缓冲补齐
+func (s *Server) streamLogs(conn *Connection, buf *logger.Buffer, query logger.Query, entries <-chan logger.Entry) {
+	next := query.Cursor
+	send := func(method string, data interface{}) bool {
+		notif, err := s.handler.BroadcastNotification(method, data)
+		if err != nil {
+			return false
+		}
+		return conn.SendMessage(websocket.TextMessage, notif) == nil
+	}
+	catchUp := func() bool {
+		for {
+			page, err := buf.Query(logger.Query{Cursor: next, Limit: logger.DefaultPageLimit, Level: query.Level, Component: query.Component})
+			if err != nil {
+				return false
+			}
+			if page.Dropped > 0 && !send("logs.dropped", map[string]interface{}{"count": page.Dropped}) {
+				return false
+			}
+			for _, entry := range page.Entries {
+				if !send("logs.entry", entry) {
+					return false
+				}
+			}
+			next = page.NextCursor
+			if !page.HasMore {
+				return true
+			}
+		}
+	}
+
+	ok := catchUp()
+	for ok {
+		entry, open := <-entries
+		if !open {
+			return
+		}
+		switch {
+		case entry.Seq < next:
+			// Already delivered by a catch-up
+		case entry.Seq > next:
+			ok = catchUp()
+		default:
+			next = entry.Seq + 1
+			if query.Match(entry) {
+				ok = send("logs.entry", entry)
+			}
+		}
+	}
+
+	logger.Debug("Log stream stopped", zap.String("session_id", conn.ID))
+	s.stopLogStream(conn.ID)
+}
+
+// parseLogQuery 解析 logs.get 和 logs.subscribe 的参数
+func parseLogQuery(params map[string]interface{}) (logger.Query, error) {
+	query := logger.Query{Limit: logger.DefaultPageLimit}
+	if cursor, ok := params["cursor"].(float64); ok {
+		if cursor < 0 {
+			return query, fmt.Errorf("cursor must not be negative")
+		}
+		query.Cursor = uint64(cursor)
+	}
+	// lines 是旧版 logs.get 的参数名
+	for _, key := range []string{"lines", "limit"} {
+		if limit, ok := params[key].(float64); ok {
+			query.Limit = int(limit)
+		}
+	}
+	if query.Limit <= 0 {
+		query.Limit = logger.DefaultPageLimit
+	}
+	if query.Limit > maxLogPageLimit {
+		query.Limit = maxLogPageLimit
+	}
+	query.Level, _ = params["level"].(string)
+	query.Component, _ = params["component"].(string)
+	return query, query.Validate()
+}
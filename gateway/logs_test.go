@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+type wsTestMessage struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	Params struct {
+		Data logger.Entry `json:"data"`
+	} `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+func TestLogsGetAndSubscribe(t *testing.T) {
+	_ = logger.Init("info", false)
+	s, _ := newOpenAITestServer(t, "")
+	ts := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer ts.Close()
+
+	logger.Info("before subscribe", zap.String("component", "logs-test"))
+	logger.Info("unrelated")
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	read := func() wsTestMessage {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var msg wsTestMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	read() // connected
+
+	_ = conn.WriteJSON(JSONRPCRequest{JSONRPC: "2.0", ID: "1", Method: "logs.get", Params: map[string]interface{}{"limit": 5, "component": "logs-test"}})
+	msg := read()
+	var page logger.Page
+	if err := json.Unmarshal(msg.Result, &page); err != nil || msg.Error != nil {
+		t.Fatalf("unexpected logs.get response %+v", msg)
+	}
+	if len(page.Entries) == 0 || page.Entries[len(page.Entries)-1].Message != "before subscribe" {
+		t.Fatalf("expected the component filter to apply, got %+v", page.Entries)
+	}
+
+	// Subscribing from an older cursor replays the entries since then
+	cursor := page.Entries[len(page.Entries)-1].Seq
+	_ = conn.WriteJSON(JSONRPCRequest{JSONRPC: "2.0", ID: "2", Method: "logs.subscribe", Params: map[string]interface{}{"cursor": cursor, "component": "logs-test"}})
+	logger.Info("after subscribe", zap.String("component", "logs-test"))
+	logger.Warn("filtered out")
+
+	var got []string
+	for len(got) < 2 {
+		msg := read()
+		if msg.ID == "2" && msg.Error != nil {
+			t.Fatalf("subscribe failed: %s", msg.Error.Message)
+		}
+		if msg.Method == "logs.entry" {
+			got = append(got, msg.Params.Data.Message)
+		}
+	}
+	if got[0] != "before subscribe" || got[1] != "after subscribe" {
+		t.Errorf("unexpected streamed entries %v", got)
+	}
+
+	_ = conn.WriteJSON(JSONRPCRequest{JSONRPC: "2.0", ID: "3", Method: "logs.unsubscribe"})
+	for {
+		if msg := read(); msg.ID == "3" {
+			if !strings.Contains(string(msg.Result), `"unsubscribed":true`) {
+				t.Errorf("unexpected unsubscribe result %s", msg.Result)
+			}
+			break
+		}
+	}
+}
+
+func TestLogsSubscribeRequiresWebSocket(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "")
+	resp := s.handler.HandleRequest("", &JSONRPCRequest{JSONRPC: "2.0", ID: "1", Method: "logs.subscribe"})
+	if resp.Error == nil || !strings.Contains(resp.Error.Message, "WebSocket") {
+		t.Errorf("expected HTTP callers to be refused, got %+v", resp)
+	}
+}
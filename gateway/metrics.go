@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/smallnest/goclaw/telemetry"
+)
+
+// metricsPath 返回指标端点路径，未启用时返回空字符串
+func (s *Server) metricsPath() string {
+	if s.config == nil || !s.config.Telemetry.Metrics.Enabled {
+		return ""
+	}
+	if s.config.Telemetry.Metrics.Path == "" {
+		return "/metrics"
+	}
+	return s.config.Telemetry.Metrics.Path
+}
+
+// handleMetrics 以 Prometheus 文本格式输出指标，启用认证时需要 read 权限的令牌
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	switch _, status := s.authorizeHTTP(r, ScopeRead); status {
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", status)
+		return
+	case http.StatusForbidden:
+		http.Error(w, "Forbidden", status)
+		return
+	}
+	telemetry.Handler().ServeHTTP(w, r)
+}
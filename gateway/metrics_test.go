@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "secret")
+	if s.metricsPath() != "" {
+		t.Error("metrics should be disabled unless configured")
+	}
+	s.config.Telemetry.Metrics.Enabled = true
+	if s.metricsPath() != "/metrics" {
+		t.Errorf("unexpected default metrics path %q", s.metricsPath())
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.handleMetrics(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "go_goroutines") {
+		t.Errorf("unexpected metrics response %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,704 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// SessionKeyHeader OpenAI 兼容接口中指定会话键的请求头
+	SessionKeyHeader = "X-Goclaw-Session-Key"
+	// ToolEventsHeader 请求额外的工具 SSE 事件（goclaw.tool）
+	ToolEventsHeader = "X-Goclaw-Tool-Events"
+
+	// maxChatRequestSize 请求体大小上限
+	maxChatRequestSize = 10 * 1024 * 1024
+)
+
+// AgentRunner 运行 Agent 对话，由 agent.AgentManager 实现
+type AgentRunner interface {
+	Chat(ctx context.Context, req *agent.ChatRequest) (*agent.ChatResult, error)
+	ListAgents() []string
+}
+
+// SetAgentRunner 设置 Agent 运行器，启用 /v1/chat/completions 和 /v1/models
+func (s *Server) SetAgentRunner(runner AgentRunner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agentRunner = runner
+}
+
+// getAgentRunner 获取 Agent 运行器
+func (s *Server) getAgentRunner() AgentRunner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.agentRunner
+}
+
+// chatCompletionRequest OpenAI chat completions 请求
+type chatCompletionRequest struct {
+	Model      string                  `json:"model"`
+	Messages   []chatCompletionMessage `json:"messages"`
+	Stream     bool                    `json:"stream"`
+	User       string                  `json:"user,omitempty"`
+	ToolEvents bool                    `json:"goclaw_tool_events,omitempty"` // 扩展字段，等同于 X-Goclaw-Tool-Events
+}
+
+// chatCompletionMessage OpenAI 消息，content 可以是字符串或内容片段数组
+type chatCompletionMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	Name       string          `json:"name,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// chatCompletionPart OpenAI 内容片段
+type chatCompletionPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// openAIError OpenAI 格式的错误响应
+type openAIError struct {
+	Error openAIErrorBody `json:"error"`
+}
+
+type openAIErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// writeOpenAIError 写入 OpenAI 格式的错误
+func writeOpenAIError(w http.ResponseWriter, status int, errType, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(openAIError{Error: openAIErrorBody{Message: message, Type: errType, Code: code}})
+}
+
+// authorizeOpenAI 认证 OpenAI 兼容请求并记录审计日志，失败时写入错误响应
+func (s *Server) authorizeOpenAI(w http.ResponseWriter, r *http.Request, scope Scope) bool {
+	principal, status := s.authorizeHTTP(r, scope)
+
+	entry := AuditEntry{
+		Time:       time.Now(),
+		Transport:  "http",
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.URL.Path,
+		Scope:      scope,
+		Allowed:    status == http.StatusOK,
+	}
+	if principal != nil {
+		entry.TokenID = principal.TokenID
+		entry.TokenName = principal.Name
+	}
+	s.auditLog().Record(entry)
+
+	switch status {
+	case http.StatusUnauthorized:
+		writeOpenAIError(w, status, "invalid_request_error", "invalid_api_key", "Invalid gateway token")
+		return false
+	case http.StatusForbidden:
+		writeOpenAIError(w, status, "permission_error", "insufficient_scope", fmt.Sprintf("Gateway token requires the %s scope", scope))
+		return false
+	}
+	return true
+}
+
+// handleOpenAIModels 处理 GET /v1/models，每个 Agent 作为一个模型
+func (s *Server) handleOpenAIModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method_not_allowed", "Method not allowed")
+		return
+	}
+	if !s.authorizeOpenAI(w, r, ScopeRead) {
+		return
+	}
+
+	names := make(map[string]string)
+	for _, agentCfg := range s.config.Agents.List {
+		names[agentCfg.ID] = agentCfg.Name
+	}
+
+	var ids []string
+	if runner := s.getAgentRunner(); runner != nil {
+		ids = runner.ListAgents()
+	} else {
+		for _, agentCfg := range s.config.Agents.List {
+			ids = append(ids, agentCfg.ID)
+		}
+	}
+	sort.Strings(ids)
+
+	data := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		model := map[string]interface{}{
+			"id":       id,
+			"object":   "model",
+			"created":  0,
+			"owned_by": "goclaw",
+		}
+		if names[id] != "" {
+			model["name"] = names[id]
+		}
+		data = append(data, model)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// handleOpenAIChatCompletions 处理 POST /v1/chat/completions
+func (s *Server) handleOpenAIChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method_not_allowed", "Method not allowed")
+		return
+	}
+	if !s.authorizeOpenAI(w, r, ScopeOperator) {
+		return
+	}
+	runner := s.getAgentRunner()
+	if runner == nil {
+		writeOpenAIError(w, http.StatusServiceUnavailable, "server_error", "agents_unavailable", "No agents are running on this gateway")
+		return
+	}
+
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatRequestSize)).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid_json", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "missing_messages", "messages must not be empty")
+		return
+	}
+
+	agentID, ok := resolveAgentModel(req.Model, runner.ListAgents())
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", "model_not_found", fmt.Sprintf("The model '%s' does not exist", req.Model))
+		return
+	}
+
+	messages, err := convertChatCompletionMessages(req.Messages)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid_messages", err.Error())
+		return
+	}
+	last := messages[len(messages)-1]
+	if last.Role != agent.RoleUser {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid_messages", "the last message must have role user")
+		return
+	}
+
+	// 会话键：请求头优先，其次是 user 字段；都没有时本轮不持久化
+	// 两者都放在 openai:<agent>: 命名空间下，调用方无法读写其他渠道的会话（如 telegram:12345）
+	sessionKey := openAISessionKey(agentID, strings.TrimSpace(r.Header.Get(SessionKeyHeader)))
+	if sessionKey == "" {
+		sessionKey = openAISessionKey(agentID, req.User)
+	}
+
+	chatReq := &agent.ChatRequest{
+		AgentID:    agentID,
+		SessionKey: sessionKey,
+		History:    messages[:len(messages)-1],
+		Message:    last,
+		Channel:    "openai",
+		SenderID:   req.User,
+	}
+
+	completionID := "chatcmpl-" + uuid.New().String()
+	model := req.Model
+	if model == "" {
+		model = agentID
+	}
+	if sessionKey != "" {
+		w.Header().Set(SessionKeyHeader, sessionKey)
+	}
+
+	if req.Stream {
+		toolEvents := req.ToolEvents || isTruthy(r.Header.Get(ToolEventsHeader))
+		s.streamChatCompletion(w, r, runner, chatReq, completionID, model, toolEvents)
+		return
+	}
+
+	result, err := runner.Chat(r.Context(), chatReq)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":      completionID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{{
+			"index": 0,
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": cleanReplyText(replyText(result)),
+			},
+			"finish_reason": "stop",
+		}},
+	}
+	// 只在 provider 报告了用量时返回，避免客户端把 0 当作真实用量
+	if result.Usage != nil {
+		response["usage"] = map[string]int{
+			"prompt_tokens":     result.Usage.PromptTokens,
+			"completion_tokens": result.Usage.CompletionTokens,
+			"total_tokens":      result.Usage.TotalTokens,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// streamChatCompletion 以 SSE 返回 chat.completion.chunk
+func (s *Server) streamChatCompletion(w http.ResponseWriter, r *http.Request, runner AgentRunner, chatReq *agent.ChatRequest, completionID, model string, toolEvents bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming_unsupported", "Streaming is not supported")
+		return
+	}
+
+	// Agent turns can outlive the server write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	created := time.Now().Unix()
+	writeEvent := func(event string, payload interface{}) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+	writeChunk := func(delta map[string]interface{}, finishReason interface{}) {
+		writeEvent("", map[string]interface{}{
+			"id":      completionID,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			}},
+		})
+	}
+
+	writeChunk(map[string]interface{}{"role": "assistant"}, nil)
+	writeContent := func(text string) {
+		if text != "" {
+			writeChunk(map[string]interface{}{"content": text}, nil)
+		}
+	}
+
+	// With tool events every iteration streams live between the tool events.
+	// Without them only the reply is streamed: an iteration's text is held back
+	// until it outgrows replyHoldBack or the iteration ends, so the narration of
+	// an iteration that calls tools is dropped when the tool call arrives. Text
+	// that was already released keeps streaming until the tool call and is
+	// separated from the next iteration by a blank line.
+	streamed := false
+	filter := &replyStreamFilter{}
+	var held strings.Builder
+	live, suppressed, sent, pendingBreak := false, false, false, false
+	release := func(text string) {
+		if text == "" {
+			return
+		}
+		if pendingBreak {
+			text = "\n\n" + text
+			pendingBreak = false
+		}
+		sent = true
+		writeContent(text)
+	}
+	chatReq.OnEvent = func(event *agent.Event) {
+		switch event.Type {
+		case agent.EventMessageStart:
+			filter = &replyStreamFilter{}
+			held.Reset()
+			live, suppressed = toolEvents, false
+		case agent.EventMessageEnd:
+			if !suppressed {
+				held.WriteString(filter.Flush())
+				release(held.String())
+				held.Reset()
+			}
+		case agent.EventStreamContent, agent.EventStreamFinal:
+			if event.StreamContent == "" || suppressed {
+				return
+			}
+			streamed = true
+			held.WriteString(filter.Write(event.StreamContent))
+			if !live && utf8.RuneCountInString(held.String()) > replyHoldBack {
+				live = true
+			}
+			if live {
+				release(held.String())
+				held.Reset()
+			}
+		case agent.EventStreamToolCall:
+			if !toolEvents && !suppressed {
+				// Narration of a tool iteration: drop what is held, stop the rest
+				held.Reset()
+				suppressed = true
+				pendingBreak = pendingBreak || (live && sent)
+			}
+		case agent.EventToolExecutionStart:
+			if toolEvents {
+				writeEvent("goclaw.tool", map[string]interface{}{
+					"type":      "tool_start",
+					"id":        event.ToolID,
+					"name":      event.ToolName,
+					"arguments": event.ToolArgs,
+				})
+			}
+		case agent.EventToolExecutionEnd:
+			if toolEvents {
+				payload := map[string]interface{}{
+					"type":     "tool_end",
+					"id":       event.ToolID,
+					"name":     event.ToolName,
+					"is_error": event.ToolError,
+				}
+				if event.ToolResult != nil {
+					payload["result"] = toolResultText(event.ToolResult)
+				}
+				writeEvent("goclaw.tool", payload)
+			}
+		}
+	}
+
+	result, err := runner.Chat(r.Context(), chatReq)
+	if err != nil {
+		logger.Warn("OpenAI-compatible chat failed", zap.String("agent", chatReq.AgentID), zap.Error(err))
+		_, errType, code := chatErrorStatus(err)
+		writeEvent("", openAIError{Error: openAIErrorBody{Message: err.Error(), Type: errType, Code: code}})
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		flusher.Flush()
+		return
+	}
+
+	// Nothing was streamed (non-streaming provider): send the reply at once
+	if !streamed {
+		writeContent(cleanReplyText(replyText(result)))
+	}
+	writeChunk(map[string]interface{}{}, "stop")
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// openAISessionKey 返回 OpenAI 兼容接口的会话键 openai:<agent>:<key>
+// 已带有该前缀的键（如客户端回传响应头中的会话键）保持不变
+func openAISessionKey(agentID, key string) string {
+	if key == "" {
+		return ""
+	}
+	if agentID == "" {
+		agentID = "default"
+	}
+	prefix := "openai:" + agentID + ":"
+	if strings.HasPrefix(key, prefix) {
+		return key
+	}
+	return prefix + key
+}
+
+// writeChatError 将 Agent 错误映射为 OpenAI 错误响应
+func writeChatError(w http.ResponseWriter, err error) {
+	status, errType, code := chatErrorStatus(err)
+	writeOpenAIError(w, status, errType, code, err.Error())
+}
+
+// chatErrorStatus 返回错误对应的 HTTP 状态码和 OpenAI 错误类型
+func chatErrorStatus(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, agent.ErrAgentNotFound):
+		return http.StatusNotFound, "invalid_request_error", "model_not_found"
+	case errors.Is(err, agent.ErrMessageDenied):
+		return http.StatusForbidden, "invalid_request_error", "content_policy_violation"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "server_error", "timeout"
+	default:
+		return http.StatusInternalServerError, "server_error", "agent_error"
+	}
+}
+
+// resolveAgentModel 将请求的模型名映射为 Agent ID；空值和 goclaw 表示默认 Agent
+func resolveAgentModel(model string, agentIDs []string) (string, bool) {
+	model = strings.TrimPrefix(model, "goclaw/")
+	if model == "" || model == "goclaw" {
+		return "", true
+	}
+	for _, id := range agentIDs {
+		if id == model {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// convertChatCompletionMessages 将 OpenAI 消息转换为 Agent 消息
+func convertChatCompletionMessages(msgs []chatCompletionMessage) ([]agent.AgentMessage, error) {
+	result := make([]agent.AgentMessage, 0, len(msgs))
+	for i, msg := range msgs {
+		var role agent.MessageRole
+		switch msg.Role {
+		case "user":
+			role = agent.RoleUser
+		case "assistant":
+			role = agent.RoleAssistant
+		case "system", "developer":
+			// The agent builds its own system prompt
+			continue
+		case "tool":
+			// Client-side tool results have no matching goclaw tool call
+			continue
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, msg.Role)
+		}
+
+		content, err := parseChatContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		result = append(result, agent.AgentMessage{
+			Role:      role,
+			Content:   content,
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("messages must contain at least one user message")
+	}
+	return result, nil
+}
+
+// parseChatContent 解析字符串或内容片段数组
+func parseChatContent(raw json.RawMessage) ([]agent.ContentBlock, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []agent.ContentBlock{agent.TextContent{Text: ""}}, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []agent.ContentBlock{agent.TextContent{Text: text}}, nil
+	}
+
+	var parts []chatCompletionPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("content must be a string or an array of parts")
+	}
+
+	var texts []string
+	var images []agent.ContentBlock
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "image_url":
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				continue
+			}
+			images = append(images, imageFromURL(part.ImageURL.URL))
+		}
+	}
+	return append([]agent.ContentBlock{agent.TextContent{Text: strings.Join(texts, "\n")}}, images...), nil
+}
+
+// imageFromURL 将 image_url 转换为图片内容，支持 data URL
+func imageFromURL(url string) agent.ImageContent {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, ok := strings.Cut(rest, ","); ok && strings.HasSuffix(meta, ";base64") {
+			return agent.ImageContent{Data: data, MimeType: strings.TrimSuffix(meta, ";base64")}
+		}
+	}
+	return agent.ImageContent{URL: url}
+}
+
+// replyText 返回回复的文本内容
+func replyText(result *agent.ChatResult) string {
+	for _, block := range result.Reply.Content {
+		if text, ok := block.(agent.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// toolResultText 返回工具结果的文本内容
+func toolResultText(result *agent.ToolResult) string {
+	var parts []string
+	for _, block := range result.Content {
+		if text, ok := block.(agent.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+var thinkingTagPattern = regexp.MustCompile(`(?s)<thinking>.*?</thinking>|<think>.*?</think>`)
+
+// cleanReplyText 去掉回复中的 <thinking>/<think> 块和 <final> 标签
+func cleanReplyText(text string) string {
+	text = thinkingTagPattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "<final>", "")
+	text = strings.ReplaceAll(text, "</final>", "")
+	return strings.TrimSpace(text)
+}
+
+// replyStreamTags 流式回复中需要过滤的标签，<thinking> 必须在 <think> 之前匹配
+var replyStreamTags = []struct {
+	open, close string
+}{
+	{"<thinking>", "</thinking>"},
+	{"<think>", "</think>"},
+	{"<final>", ""},
+	{"</final>", ""},
+}
+
+// replyHoldBack 不带工具事件的流式响应中，每轮文本在确认不是调用工具前的旁白之前最多暂缓的字符数
+const replyHoldBack = 200
+
+// replyStreamFilter 对一条消息的流式增量做与 cleanReplyText 相同的过滤
+// 可能是标签开头的内容留到下一个增量再判断，首尾空白与 TrimSpace 一致
+type replyStreamFilter struct {
+	pending string // 尚未判断的输入（可能是不完整的标签）
+	closing string // 正在跳过的思考块的结束标签
+	hidden  string // 已跳过的思考块内容，块未闭合时在 Flush 中原样输出
+	started bool   // 已经输出了非空白内容
+	space   string // 暂缓输出的尾部空白
+}
+
+// Write 处理一段增量，返回可以发送的文本
+func (f *replyStreamFilter) Write(delta string) string {
+	f.pending += delta
+	var out strings.Builder
+	for f.pending != "" {
+		if f.closing != "" {
+			idx := strings.Index(f.pending, f.closing)
+			if idx < 0 {
+				// 只保留可能是结束标签开头的部分
+				keep := len(f.pending) - partialTagSuffix(f.pending, f.closing)
+				f.hidden += f.pending[:keep]
+				f.pending = f.pending[keep:]
+				break
+			}
+			f.pending = f.pending[idx+len(f.closing):]
+			f.closing, f.hidden = "", ""
+			continue
+		}
+
+		idx := strings.IndexByte(f.pending, '<')
+		if idx < 0 {
+			f.emit(&out, f.pending)
+			f.pending = ""
+			break
+		}
+		f.emit(&out, f.pending[:idx])
+		f.pending = f.pending[idx:]
+
+		matched, partial := false, false
+		for _, tag := range replyStreamTags {
+			if strings.HasPrefix(f.pending, tag.open) {
+				f.pending = f.pending[len(tag.open):]
+				if tag.close != "" {
+					f.closing, f.hidden = tag.close, tag.open
+				}
+				matched = true
+				break
+			}
+			if strings.HasPrefix(tag.open, f.pending) {
+				partial = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if partial {
+			break
+		}
+		f.emit(&out, "<")
+		f.pending = f.pending[1:]
+	}
+	return out.String()
+}
+
+// Flush 在消息结束时返回剩余的文本，未闭合的思考块与 cleanReplyText 一样原样保留
+func (f *replyStreamFilter) Flush() string {
+	var out strings.Builder
+	f.emit(&out, f.hidden+f.pending)
+	f.pending, f.closing, f.hidden = "", "", ""
+	f.space = ""
+	return out.String()
+}
+
+// emit 输出文本，跳过开头的空白并暂缓尾部空白
+func (f *replyStreamFilter) emit(out *strings.Builder, text string) {
+	if !f.started {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return
+		}
+		f.started = true
+	}
+	trimmed := strings.TrimRightFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		f.space += text
+		return
+	}
+	out.WriteString(f.space)
+	out.WriteString(trimmed)
+	f.space = text[len(trimmed):]
+}
+
+// partialTagSuffix 返回 text 末尾与 tag 开头相同的最长长度
+func partialTagSuffix(text, tag string) int {
+	for n := min(len(text), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// isTruthy 解析布尔请求头
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
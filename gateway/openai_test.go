@@ -0,0 +1,360 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/channels"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// fakeAgentRunner answers every chat with a fixed reply
+type fakeAgentRunner struct {
+	requests []*agent.ChatRequest
+	// gate, when set, makes coder stream a long reply and wait for the client
+	// to receive its beginning before the run finishes
+	gate     chan struct{}
+	gateOpen bool
+}
+
+func (f *fakeAgentRunner) ListAgents() []string {
+	return []string{"main", "coder"}
+}
+
+func (f *fakeAgentRunner) Chat(ctx context.Context, req *agent.ChatRequest) (*agent.ChatResult, error) {
+	f.requests = append(f.requests, req)
+	reply := "<thinking>hmm</thinking>It is Go."
+	if req.AgentID == "coder" {
+		// A tool iteration with narration, then the final iteration with tags split across deltas
+		reply = "<think>ok</think> It is <final>Go.</final>"
+		stream := func(deltas ...string) {
+			req.OnEvent(agent.NewEvent(agent.EventMessageStart))
+			for _, delta := range deltas {
+				req.OnEvent(&agent.Event{Type: agent.EventStreamContent, StreamContent: delta})
+			}
+			req.OnEvent(agent.NewEvent(agent.EventMessageEnd))
+		}
+		if req.OnEvent != nil {
+			req.OnEvent(agent.NewEvent(agent.EventMessageStart))
+			for _, delta := range []string{"Let me look<thi", "nking>plan</thinking>."} {
+				req.OnEvent(&agent.Event{Type: agent.EventStreamContent, StreamContent: delta})
+			}
+			req.OnEvent(&agent.Event{Type: agent.EventStreamToolCall, ToolID: "call_1", ToolName: "read_file"})
+			req.OnEvent(agent.NewEvent(agent.EventMessageEnd))
+			req.OnEvent(agent.NewEvent(agent.EventToolExecutionStart).WithToolExecution("call_1", "read_file", map[string]any{"path": "main.go"}))
+			req.OnEvent(agent.NewEvent(agent.EventToolExecutionEnd).WithToolExecution("call_1", "read_file", nil).
+				WithToolResult(&agent.ToolResult{Content: []agent.ContentBlock{agent.TextContent{Text: "package main"}}}, false))
+			if f.gate == nil {
+				stream("<think>o", "k</think> It is ", "<fin", "al>Go.</final>")
+			} else {
+				long := strings.Repeat("Go is a compiled language. ", 10)
+				reply = long + "Done."
+				req.OnEvent(agent.NewEvent(agent.EventMessageStart))
+				req.OnEvent(&agent.Event{Type: agent.EventStreamContent, StreamContent: long})
+				select {
+				case <-f.gate:
+					f.gateOpen = true
+				case <-time.After(5 * time.Second):
+				}
+				req.OnEvent(&agent.Event{Type: agent.EventStreamContent, StreamContent: "Done."})
+				req.OnEvent(agent.NewEvent(agent.EventMessageEnd))
+			}
+		}
+	}
+	result := &agent.ChatResult{
+		AgentID: req.AgentID,
+		Reply:   agent.AgentMessage{Role: agent.RoleAssistant, Content: []agent.ContentBlock{agent.TextContent{Text: reply}}},
+	}
+	if req.AgentID == "main" {
+		result.Usage = &providers.Usage{PromptTokens: 12, CompletionTokens: 4, TotalTokens: 16}
+	}
+	return result, nil
+}
+
+func newOpenAITestServer(t *testing.T, token string) (*Server, *fakeAgentRunner) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Agents.List = []config.AgentConfig{{ID: "main", Name: "Main"}, {ID: "coder", Name: "Coder"}}
+	cfg.Gateway.WebSocket.EnableAuth = token != ""
+	cfg.Gateway.WebSocket.AuthToken = token
+
+	messageBus := bus.NewMessageBus(10)
+	s := NewServer(cfg, messageBus, channels.NewManager(messageBus), nil, nil, nil)
+	runner := &fakeAgentRunner{}
+	s.SetAgentRunner(runner)
+	return s, runner
+}
+
+func TestOpenAIModels(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "secret")
+
+	rec := httptest.NewRecorder()
+	s.handleOpenAIModels(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.handleOpenAIModels(rec, req)
+
+	var resp struct {
+		Object string `json:"object"`
+		Data   []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Object != "list" || len(resp.Data) != 2 || resp.Data[0].ID != "coder" || resp.Data[1].Name != "Main" {
+		t.Errorf("unexpected models %+v", resp)
+	}
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	s, runner := newOpenAITestServer(t, "")
+
+	body := `{"model": "main", "user": "alice", "messages": [
+		{"role": "system", "content": "ignored"},
+		{"role": "user", "content": "hello"},
+		{"role": "assistant", "content": "hi"},
+		{"role": "user", "content": [{"type": "text", "text": "what is this?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}]}
+	]}`
+	rec := httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage *providers.Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Object != "chat.completion" || resp.Choices[0].Message.Content != "It is Go." {
+		t.Errorf("unexpected response %s", rec.Body.String())
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.TotalTokens != 16 {
+		t.Errorf("expected the run usage, got %s", rec.Body.String())
+	}
+
+	req := runner.requests[0]
+	if req.AgentID != "main" || req.SessionKey != "openai:main:alice" || len(req.History) != 2 {
+		t.Errorf("unexpected chat request %+v", req)
+	}
+	if len(req.Message.Content) != 2 {
+		t.Errorf("expected text and image content, got %+v", req.Message.Content)
+	}
+	if img, ok := req.Message.Content[1].(agent.ImageContent); !ok || img.Data != "AAAA" || img.MimeType != "image/png" {
+		t.Errorf("unexpected image %+v", req.Message.Content[1])
+	}
+
+	// A session header wins over the user field but cannot reach another channel's session
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model": "main", "user": "alice", "messages": [{"role": "user", "content": "hi"}]}`))
+	httpReq.Header.Set(SessionKeyHeader, "telegram:bot:42")
+	rec = httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httpReq)
+	if got := runner.requests[1].SessionKey; got != "openai:main:telegram:bot:42" {
+		t.Errorf("expected the namespaced header session key, got %q", got)
+	}
+	if got := rec.Header().Get(SessionKeyHeader); got != "openai:main:telegram:bot:42" {
+		t.Errorf("expected the session key echoed back, got %q", got)
+	}
+
+	// Echoing the returned key back resumes the same session
+	httpReq = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model": "main", "messages": [{"role": "user", "content": "hi"}]}`))
+	httpReq.Header.Set(SessionKeyHeader, "openai:main:telegram:bot:42")
+	rec = httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httpReq)
+	if got := runner.requests[2].SessionKey; got != "openai:main:telegram:bot:42" {
+		t.Errorf("expected the echoed session key unchanged, got %q", got)
+	}
+
+	// Without reported usage the field is omitted instead of claiming zero tokens
+	rec = httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model": "coder", "messages": [{"role": "user", "content": "hi"}]}`)))
+	if strings.Contains(rec.Body.String(), `"usage"`) {
+		t.Errorf("expected no usage field, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4", "messages": [{"role": "user", "content": "hi"}]}`)))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "model_not_found") {
+		t.Errorf("expected model_not_found, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// readChatStream posts a streaming request and returns the concatenated
+// content, the goclaw.tool event types and whether [DONE] was received
+func readChatStream(t *testing.T, url string, withToolEvents bool) (string, []string, bool) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"model": "coder", "stream": true, "messages": [{"role": "user", "content": "what language?"}]}`))
+	if withToolEvents {
+		req.Header.Set(ToolEventsHeader, "1")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var content strings.Builder
+	var toolEvents []string
+	var done bool
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case line == "data: [DONE]":
+			done = true
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			if event == "goclaw.tool" {
+				var tool map[string]interface{}
+				_ = json.Unmarshal([]byte(data), &tool)
+				toolEvents = append(toolEvents, tool["type"].(string))
+				event = ""
+				continue
+			}
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				t.Fatalf("invalid chunk %q: %v", data, err)
+			}
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	return content.String(), toolEvents, done
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	s, _ := newOpenAITestServer(t, "")
+	server := httptest.NewServer(http.HandlerFunc(s.handleOpenAIChatCompletions))
+	defer server.Close()
+
+	// Tool events stream every iteration live, with the same tag filtering
+	content, toolEvents, done := readChatStream(t, server.URL, true)
+	if content != "Let me look.It is Go." || !done {
+		t.Errorf("unexpected streamed content %q (done=%v)", content, done)
+	}
+	if len(toolEvents) != 2 || toolEvents[0] != "tool_start" || toolEvents[1] != "tool_end" {
+		t.Errorf("unexpected tool events %v", toolEvents)
+	}
+
+	// Otherwise the stream carries exactly the non-streaming content
+	rec := httptest.NewRecorder()
+	s.handleOpenAIChatCompletions(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model": "coder", "messages": [{"role": "user", "content": "what language?"}]}`)))
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	content, toolEvents, done = readChatStream(t, server.URL, false)
+	if content != resp.Choices[0].Message.Content || content != "It is Go." || !done {
+		t.Errorf("streamed content %q differs from the response %q", content, resp.Choices[0].Message.Content)
+	}
+	if len(toolEvents) != 0 {
+		t.Errorf("unexpected tool events %v", toolEvents)
+	}
+}
+
+func TestOpenAIChatCompletionStreamsReplyLive(t *testing.T) {
+	s, runner := newOpenAITestServer(t, "")
+	runner.gate = make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(s.handleOpenAIChatCompletions))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"model": "coder", "stream": true, "messages": [{"role": "user", "content": "what language?"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The long final reply must arrive while the run is still going; the
+	// narration of the tool iteration is dropped
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		_ = json.Unmarshal([]byte(data), &chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if content.Len() == 0 {
+				close(runner.gate)
+			}
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if !runner.gateOpen {
+		t.Error("expected the reply to stream before the run finished")
+	}
+	if want := strings.Repeat("Go is a compiled language. ", 10) + "Done."; content.String() != want {
+		t.Errorf("unexpected streamed content %q", content.String())
+	}
+}
+
+func TestReplyStreamFilter(t *testing.T) {
+	tests := [][]string{
+		{"  <thinking>a</thinking>Hello", " world  "},
+		{"<th", "ink>x</th", "ink>An", "swer <", "fin", "al>ok</final>"},
+		{"a < b and c <f", "oo>"},
+		{"<thinking>never closed"},
+		{"x <", "/fin", "al>"},
+		{"<final>", "  \n"},
+	}
+	for _, deltas := range tests {
+		f := &replyStreamFilter{}
+		var got strings.Builder
+		for _, delta := range deltas {
+			got.WriteString(f.Write(delta))
+		}
+		got.WriteString(f.Flush())
+		if want := cleanReplyText(strings.Join(deltas, "")); got.String() != want {
+			t.Errorf("filtered %q to %q, want %q", deltas, got.String(), want)
+		}
+	}
+}
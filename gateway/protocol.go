@@ -50,6 +50,8 @@ const (
 	ErrorMethodNotFound = -32601
 	ErrorInvalidParams  = -32602
 	ErrorInternalError  = -32603
+	// ErrorForbidden 令牌没有调用该方法所需的权限范围
+	ErrorForbidden = -32003
 )
 
 // NewErrorResponse 创建错误响应
@@ -2,7 +2,6 @@ package gateway
 
 import (
 	"context"
-	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,10 +11,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/channels"
 	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/cron"
+	"github.com/smallnest/goclaw/heartbeat"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/session"
 	"go.uber.org/zap"
@@ -44,9 +45,14 @@ type Server struct {
 	running       bool
 	connections   map[string]*Connection
 	connectionsMu sync.RWMutex
-	enableAuth    bool
-	authToken     string
-	acpMgr        interface{} // ACP manager - will be set if ACP is enabled
+	acpMgr        interface{}       // ACP manager - will be set if ACP is enabled
+	agentRunner   AgentRunner       // OpenAI 兼容接口使用的 Agent 运行器
+	tokens        *TokenStore       // 带权限范围的网关令牌
+	audit         *AuditLog         // RPC 调用审计日志
+	logStreams    map[string]func() // 各连接的日志订阅，值为取消函数
+	logStreamsMu  sync.Mutex
+	heartbeat     *heartbeat.Runner // 心跳运行器
+	approvals     *tools.ApprovalQueue
 }
 
 // WebSocketConfig WebSocket 配置
@@ -99,7 +105,18 @@ func NewServer(cfg *config.Config, messageBus *bus.MessageBus, channelMgr *chann
 		writeTimeout = 10 * time.Second
 	}
 
-	return &Server{
+	tokensPath := cfg.Gateway.Auth.TokensFile
+	if tokensPath == "" {
+		if path, err := DefaultTokensPath(); err == nil {
+			tokensPath = path
+		}
+	}
+	var tokens *TokenStore
+	if tokensPath != "" {
+		tokens = NewTokenStore(tokensPath)
+	}
+
+	s := &Server{
 		config: cfg,
 		wsConfig: &WebSocketConfig{
 			Host:           wsHost,
@@ -119,7 +136,13 @@ func NewServer(cfg *config.Config, messageBus *bus.MessageBus, channelMgr *chann
 		handler:     NewHandler(messageBus, sessionMgr, channelMgr, cronSvc, acpMgr, cfg),
 		connections: make(map[string]*Connection),
 		acpMgr:      acpMgr,
+		tokens:      tokens,
+		logStreams:  make(map[string]func()),
 	}
+	s.registerLogStreamMethods()
+	s.registerHeartbeatMethods()
+	s.registerApprovalMethods()
+	return s
 }
 
 // SetWebSocketConfig 设置 WebSocket 配置
@@ -127,8 +150,6 @@ func (s *Server) SetWebSocketConfig(cfg *WebSocketConfig) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.wsConfig = cfg
-	s.enableAuth = cfg.EnableAuth
-	s.authToken = cfg.AuthToken
 }
 
 // Start 启动服务器
@@ -141,6 +162,22 @@ func (s *Server) Start(ctx context.Context) error {
 	s.running = true
 	s.mu.Unlock()
 
+	// 打开审计日志
+	if s.auditLog() == nil {
+		auditPath := s.config.Gateway.Auth.AuditFile
+		if auditPath == "" {
+			auditPath, _ = DefaultAuditPath()
+		}
+		if auditPath != "" {
+			audit, err := NewAuditLog(auditPath)
+			if err != nil {
+				logger.Warn("Failed to open gateway audit log", zap.Error(err))
+			} else {
+				s.SetAuditLog(audit)
+			}
+		}
+	}
+
 	// 启动 HTTP 服务器
 	if err := s.startHTTPServer(ctx); err != nil {
 		return err
@@ -183,6 +220,15 @@ func (s *Server) startHTTPServer(ctx context.Context) error {
 	// 通用 webhook 端点
 	mux.HandleFunc("/webhook/", s.handleGenericWebhook)
 
+	// OpenAI 兼容端点
+	mux.HandleFunc("/v1/chat/completions", s.handleOpenAIChatCompletions)
+	mux.HandleFunc("/v1/models", s.handleOpenAIModels)
+
+	// Prometheus 指标端点
+	if path := s.metricsPath(); path != "" {
+		mux.HandleFunc(path, s.handleMetrics)
+	}
+
 	// 创建 HTTP 服务器
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.config.Gateway.Host, s.config.Gateway.Port),
@@ -275,6 +321,10 @@ func (s *Server) Stop() error {
 		}
 	}
 
+	if err := s.auditLog().Close(); err != nil {
+		logger.Warn("Failed to close gateway audit log", zap.Error(err))
+	}
+
 	logger.Info("Gateway server stopped")
 	return nil
 }
@@ -302,11 +352,11 @@ func (s *Server) removeConnection(id string) {
 	s.connectionsMu.Lock()
 	defer s.connectionsMu.Unlock()
 	delete(s.connections, id)
+	s.stopLogStream(id)
 }
 
-// _getConnection 获取连接 (未使用，保留供将来使用)
-// nolint:unused
-func (s *Server) _getConnection(id string) (*Connection, bool) {
+// getConnection 获取连接
+func (s *Server) getConnection(id string) (*Connection, bool) {
 	s.connectionsMu.RLock()
 	defer s.connectionsMu.RUnlock()
 	conn, ok := s.connections[id]
@@ -417,9 +467,14 @@ func (s *Server) handleGenericWebhook(w http.ResponseWriter, r *http.Request) {
 
 // handleWebSocket WebSocket 连接处理器
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// 检查认证
-	if s.wsConfig.EnableAuth && !s.authenticateWebSocket(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	// 检查认证，连接上的每个方法调用都使用该令牌的权限范围
+	principal, status := s.authorizeHTTP(r, ScopeRead)
+	switch status {
+	case http.StatusUnauthorized:
+		http.Error(w, "Unauthorized", status)
+		return
+	case http.StatusForbidden:
+		http.Error(w, "Forbidden", status)
 		return
 	}
 
@@ -432,6 +487,8 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// 创建连接对象
 	connection := NewConnection(conn, s.wsConfig)
+	connection.Principal = principal
+	connection.RemoteAddr = r.RemoteAddr
 	sessionID := connection.ID
 
 	// 添加到连接管理
@@ -449,6 +506,8 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		Params: map[string]interface{}{
 			"session_id": sessionID,
 			"version":    ProtocolVersion,
+			"token":      principal.Name,
+			"scopes":     principal.Scopes,
 		},
 	}
 	_ = connection.SendJSON(welcome)
@@ -460,29 +519,6 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go s.handleWebSocketMessages(connection)
 }
 
-// authenticateWebSocket 验证 WebSocket 连接
-func (s *Server) authenticateWebSocket(r *http.Request) bool {
-	// 从查询参数获取 token
-	token := r.URL.Query().Get("token")
-	if token == "" {
-		// 从 Authorization header 获取
-		auth := r.Header.Get("Authorization")
-		if auth != "" {
-			// 支持 "Bearer <token>" 格式
-			if len(auth) > 7 && auth[:7] == "Bearer " {
-				token = auth[7:]
-			}
-		}
-	}
-
-	if token == "" {
-		return false
-	}
-
-	// 使用恒定时间比较防止时序攻击
-	return subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1
-}
-
 // handleWebSocketMessages 处理 WebSocket 消息
 func (s *Server) handleWebSocketMessages(conn *Connection) {
 	defer func() {
@@ -526,7 +562,7 @@ func (s *Server) handleWebSocketMessages(conn *Connection) {
 		)
 
 		// 处理请求
-		resp := s.handler.HandleRequest(conn.ID, req)
+		resp := s.callMethod(conn.Principal, "ws", conn.RemoteAddr, conn.ID, req)
 
 		// 发送响应
 		if err := conn.SendJSON(resp); err != nil {
@@ -594,12 +630,37 @@ func (s *Server) broadcastOutbound(ctx context.Context) {
 	}
 }
 
+// BroadcastEvent 向拥有 scope 或更高权限范围的 WebSocket 连接发送通知
+func (s *Server) BroadcastEvent(method string, scope Scope, data interface{}) {
+	notif, err := s.handler.BroadcastNotification(method, data)
+	if err != nil {
+		logger.Error("Failed to create notification", zap.Error(err))
+		return
+	}
+
+	s.connectionsMu.RLock()
+	defer s.connectionsMu.RUnlock()
+	for _, conn := range s.connections {
+		if !conn.Principal.Allows(scope) {
+			continue
+		}
+		if err := conn.SendMessage(websocket.TextMessage, notif); err != nil {
+			logger.Error("Failed to broadcast notification",
+				zap.String("session_id", conn.ID),
+				zap.String("method", method),
+				zap.Error(err))
+		}
+	}
+}
+
 // Connection WebSocket 连接
 type Connection struct {
 	*websocket.Conn
 	ID string
 	// nolint:unused
-	_sessionID   string // 保留供将来使用
+	_sessionID   string     // 保留供将来使用
+	Principal    *Principal // 建立连接时认证的调用方
+	RemoteAddr   string
 	pingInterval time.Duration
 	pongTimeout  time.Duration
 	mu           sync.Mutex
@@ -705,6 +766,14 @@ func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 认证调用方，方法的权限范围在调用时检查
+	principal, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	// 读取请求体
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -726,7 +795,7 @@ func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
 		zap.String("id", req.ID))
 
 	// 处理请求
-	resp := s.handler.HandleRequest("", req)
+	resp := s.callMethod(principal, "http", r.RemoteAddr, "", req)
 
 	// 发送响应
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenPrefix 网关令牌的前缀，便于在日志和密钥扫描中识别
+const TokenPrefix = "gct_"
+
+// TokenRecord 存储的令牌记录，只保存令牌的 SHA-256 哈希
+type TokenRecord struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash,omitempty"`
+	Hint      string     `json:"hint"` // 令牌的前几位，用于辨认
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active 令牌是否仍然有效
+func (r *TokenRecord) Active() bool {
+	return r.RevokedAt == nil
+}
+
+// TokenStore 基于文件的令牌存储
+// 文件被其他进程（例如 goclaw gateway tokens 命令）修改后会自动重新加载
+type TokenStore struct {
+	path    string
+	mu      sync.Mutex
+	records []*TokenRecord
+	modTime time.Time
+	size    int64
+}
+
+// DefaultTokensPath 返回默认的令牌存储路径
+func DefaultTokensPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "gateway", "tokens.json"), nil
+}
+
+// NewTokenStore 创建令牌存储，文件不存在时视为空
+func NewTokenStore(path string) *TokenStore {
+	return &TokenStore{path: path}
+}
+
+// Path 返回存储文件路径
+func (s *TokenStore) Path() string {
+	return s.path
+}
+
+// Create 创建令牌，返回只会出现这一次的明文令牌
+func (s *TokenStore) Create(name string, scopes []Scope) (string, *TokenRecord, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return "", nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return "", nil, err
+	}
+	for _, r := range s.records {
+		if r.Active() && r.Name == name {
+			return "", nil, fmt.Errorf("an active token named %s already exists", name)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	record := &TokenRecord{
+		ID:        hex.EncodeToString(idBytes),
+		Name:      name,
+		Hash:      hashToken(token),
+		Hint:      token[:len(TokenPrefix)+4],
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	s.records = append(s.records, record)
+	if err := s.saveLocked(); err != nil {
+		s.records = s.records[:len(s.records)-1]
+		return "", nil, err
+	}
+
+	copied := *record
+	return token, &copied, nil
+}
+
+// List 返回所有令牌记录（包括已吊销的），按创建时间排序
+func (s *TokenStore) List() ([]TokenRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	result := make([]TokenRecord, 0, len(s.records))
+	for _, r := range s.records {
+		result = append(result, *r)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Revoke 按 ID 或名称吊销有效令牌
+func (s *TokenStore) Revoke(nameOrID string) (*TokenRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	for _, r := range s.records {
+		if r.Active() && (r.ID == nameOrID || r.Name == nameOrID) {
+			now := time.Now()
+			r.RevokedAt = &now
+			if err := s.saveLocked(); err != nil {
+				r.RevokedAt = nil
+				return nil, err
+			}
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("active token not found: %s", nameOrID)
+}
+
+// Authenticate 验证明文令牌，返回对应的调用方
+func (s *TokenStore) Authenticate(token string) (*Principal, bool) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, false
+	}
+	hash := hashToken(token)
+	for _, r := range s.records {
+		if r.Active() && subtle.ConstantTimeCompare([]byte(hash), []byte(r.Hash)) == 1 {
+			return &Principal{TokenID: r.ID, Name: r.Name, Scopes: r.Scopes}, true
+		}
+	}
+	return nil, false
+}
+
+// loadLocked reloads the file when it changed since the last read
+func (s *TokenStore) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.records = nil
+		s.modTime = time.Time{}
+		s.size = 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat token store: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size && s.records != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token store: %w", err)
+	}
+	var records []*TokenRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("invalid token store %s: %w", s.path, err)
+	}
+	if records == nil {
+		records = []*TokenRecord{}
+	}
+	s.records = records
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return nil
+}
+
+// saveLocked writes the records atomically with owner-only permissions
+func (s *TokenStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+		s.size = info.Size()
+	}
+	return nil
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package heartbeat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// AckToken 心跳回复中表示无事汇报的标记
+	AckToken = "HEARTBEAT_OK"
+	// FileName 工作区中心跳清单的文件名
+	FileName = "HEARTBEAT.md"
+)
+
+// DefaultPrompt 默认的心跳提示词
+const DefaultPrompt = "This is a scheduled heartbeat. Follow the HEARTBEAT.md checklist below strictly " +
+	"and do not repeat tasks from earlier conversations that it does not mention. " +
+	"If nothing needs attention, reply exactly " + AckToken + "."
+
+var htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// ReadChecklist 读取工作区的 HEARTBEAT.md，文件不存在时返回空字符串
+func ReadChecklist(workspace string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(workspace, FileName))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// HasTasks 清单中除标题、HTML 注释和空行外是否还有内容
+// 只有标题或注释的 HEARTBEAT.md 视为空，不会调用模型
+func HasTasks(checklist string) bool {
+	for _, line := range strings.Split(htmlComment.ReplaceAllString(checklist, ""), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildPrompt 组合心跳提示词、清单和排队的系统事件
+func BuildPrompt(prompt, checklist string, events []SystemEvent) string {
+	if strings.TrimSpace(prompt) == "" {
+		prompt = DefaultPrompt
+	}
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	if HasTasks(checklist) {
+		sb.WriteString("\n\n## " + FileName + "\n\n")
+		sb.WriteString(checklist)
+	}
+	if len(events) > 0 {
+		sb.WriteString("\n\n## System events since the last heartbeat\n")
+		for _, event := range events {
+			fmt.Fprintf(&sb, "\n- [%s] %s: %s", event.Time.Format("2006-01-02 15:04"), event.Source, event.Text)
+		}
+	}
+	return sb.String()
+}
+
+// StripAck 去掉回复开头或结尾的 HEARTBEAT_OK
+// 回复为空，或带有标记且剩余内容不超过 maxChars 时视为无事汇报，ack 为 true
+func StripAck(reply string, maxChars int) (rest string, ack bool) {
+	rest = strings.TrimSpace(reply)
+	found := false
+	if trimmed, ok := cutAckToken(rest, strings.CutPrefix); ok {
+		rest, found = strings.TrimLeft(trimmed, ".!:- \n"), true
+	}
+	if trimmed, ok := cutAckToken(strings.TrimRight(rest, ".!"), strings.CutSuffix); ok {
+		rest, found = strings.TrimSpace(trimmed), true
+	}
+	if rest == "" {
+		return "", true
+	}
+	return rest, found && len([]rune(rest)) <= maxChars
+}
+
+// cutAckToken removes the token, tolerating markdown emphasis around it
+func cutAckToken(s string, cut func(string, string) (string, bool)) (string, bool) {
+	for _, token := range []string{"**" + AckToken + "**", "`" + AckToken + "`", AckToken} {
+		if rest, ok := cut(s, token); ok {
+			return rest, true
+		}
+	}
+	return s, false
+}
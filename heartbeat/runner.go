@@ -0,0 +1,386 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultEvery 默认心跳间隔
+	DefaultEvery = 30 * time.Minute
+	// DefaultAckMaxChars 带 HEARTBEAT_OK 的回复在该长度内仍视为无事汇报
+	DefaultAckMaxChars = 300
+
+	maxQueuedEvents = 50
+	maxReplyPreview = 200
+	runTimeout      = 5 * time.Minute
+)
+
+// 心跳运行状态
+const (
+	StatusOK      = "ok"      // 无事汇报，回复被静默
+	StatusSent    = "sent"    // 回复已发送到配置的通道
+	StatusAlert   = "alert"   // 需要汇报，但没有配置通道，只记录在状态中
+	StatusSkipped = "skipped" // HEARTBEAT.md 为空且没有排队的系统事件，未调用模型
+	StatusError   = "error"
+)
+
+// ChatRunner 运行一轮 Agent 对话
+type ChatRunner interface {
+	Chat(ctx context.Context, req *agent.ChatRequest) (*agent.ChatResult, error)
+}
+
+// AgentSettings 单个 Agent 的心跳设置
+type AgentSettings struct {
+	AgentID   string
+	Workspace string // HEARTBEAT.md 所在的工作区
+	Config    config.HeartbeatConfig
+}
+
+// SystemEvent 等待下一次心跳的系统事件
+type SystemEvent struct {
+	Source string    `json:"source"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// RunState 一次心跳的结果
+type RunState struct {
+	AgentID    string    `json:"agent_id"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+	Events     int       `json:"events,omitempty"` // 随本次心跳处理的系统事件数
+	Reply      string    `json:"reply,omitempty"`  // 回复预览，静默时为去掉标记后的内容
+	Error      string    `json:"error,omitempty"`
+}
+
+// persistedState is the on-disk form of the runner state
+type persistedState struct {
+	Enabled bool                 `json:"enabled"`
+	Last    map[string]*RunState `json:"last"`
+}
+
+// Runner 按间隔为每个 Agent 运行心跳
+// 第一个 Agent 负责处理排队的系统事件，SettingsFromConfig 会把默认 Agent 排在最前
+type Runner struct {
+	chat      ChatRunner
+	bus       *bus.MessageBus
+	agents    []AgentSettings
+	statePath string
+
+	mu      sync.Mutex
+	enabled bool
+	events  []SystemEvent
+	last    map[string]*RunState
+	running map[string]bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// DefaultStatePath 返回默认的心跳状态文件路径
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "heartbeat", "state.json"), nil
+}
+
+// SettingsFromConfig 返回启用了心跳的 Agent，单 Agent 配置覆盖默认配置
+// 没有配置 Agent 时使用 default Agent，workspace 为未配置工作区时使用的目录
+func SettingsFromConfig(cfg *config.Config, workspace string) []AgentSettings {
+	agents := cfg.Agents.List
+	if len(agents) == 0 {
+		agents = []config.AgentConfig{{ID: "default", Default: true}}
+	}
+
+	var settings []AgentSettings
+	for _, agentCfg := range agents {
+		hb := cfg.Agents.Defaults.Heartbeat
+		if agentCfg.Heartbeat != nil {
+			hb = agentCfg.Heartbeat
+		}
+		if hb == nil || !hb.Enabled {
+			continue
+		}
+		s := AgentSettings{AgentID: agentCfg.ID, Workspace: agentCfg.Workspace, Config: *hb}
+		if s.Workspace == "" {
+			s.Workspace = workspace
+		}
+		if agentCfg.Default {
+			settings = append([]AgentSettings{s}, settings...)
+		} else {
+			settings = append(settings, s)
+		}
+	}
+	return settings
+}
+
+// NewRunner 创建心跳运行器，statePath 为空时不持久化状态
+func NewRunner(chat ChatRunner, messageBus *bus.MessageBus, agents []AgentSettings, statePath string) *Runner {
+	r := &Runner{
+		chat:      chat,
+		bus:       messageBus,
+		agents:    agents,
+		statePath: statePath,
+		enabled:   true,
+		last:      make(map[string]*RunState),
+		running:   make(map[string]bool),
+	}
+	for i := range r.agents {
+		if r.agents[i].Config.Every <= 0 {
+			r.agents[i].Config.Every = DefaultEvery
+		}
+		if r.agents[i].Config.AckMaxChars <= 0 {
+			r.agents[i].Config.AckMaxChars = DefaultAckMaxChars
+		}
+	}
+	if err := r.load(); err != nil {
+		logger.Warn("Failed to load heartbeat state", zap.Error(err))
+	}
+	return r
+}
+
+// Start 为每个 Agent 启动心跳定时器，第一次心跳在一个间隔之后
+func (r *Runner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, settings := range r.agents {
+		r.wg.Add(1)
+		go r.loop(ctx, settings)
+	}
+	logger.Info("Heartbeat runner started", zap.Int("agents", len(r.agents)), zap.Bool("enabled", r.Enabled()))
+}
+
+// Stop 停止所有定时器并等待正在运行的心跳结束
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, settings AgentSettings) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(settings.Config.Every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.Enabled() {
+				continue
+			}
+			if _, err := r.RunOnce(ctx, settings.AgentID); err != nil {
+				logger.Debug("Heartbeat not run", zap.String("agent_id", settings.AgentID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Agents 返回启用了心跳的 Agent ID
+func (r *Runner) Agents() []string {
+	ids := make([]string, 0, len(r.agents))
+	for _, settings := range r.agents {
+		ids = append(ids, settings.AgentID)
+	}
+	return ids
+}
+
+// Enabled 心跳是否启用
+func (r *Runner) Enabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enabled
+}
+
+// SetEnabled 启用或暂停所有心跳，状态会持久化，重启后保持
+func (r *Runner) SetEnabled(enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = enabled
+	return r.saveLocked()
+}
+
+// Last 返回 Agent 最近一次心跳的结果，agentID 为空时返回所有 Agent 中最近的一次
+func (r *Runner) Last(agentID string) *RunState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if agentID != "" {
+		if state := r.last[agentID]; state != nil {
+			copied := *state
+			return &copied
+		}
+		return nil
+	}
+	var latest *RunState
+	for _, state := range r.last {
+		if latest == nil || state.Timestamp.After(latest.Timestamp) {
+			latest = state
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	copied := *latest
+	return &copied
+}
+
+// EnqueueSystemEvent 排队一条系统事件，随下一次心跳交给 Agent
+// 没有启用心跳时返回 false，调用方应立即处理该事件
+func (r *Runner) EnqueueSystemEvent(source, text string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.agents) == 0 || !r.enabled {
+		return false
+	}
+	if len(r.events) == maxQueuedEvents {
+		r.events = r.events[1:]
+	}
+	r.events = append(r.events, SystemEvent{Source: source, Text: text, Time: time.Now()})
+	return true
+}
+
+// RunOnce 立即为 Agent 运行一次心跳并记录结果
+// 同一 Agent 的上一次心跳尚未结束时返回错误
+func (r *Runner) RunOnce(ctx context.Context, agentID string) (*RunState, error) {
+	var settings *AgentSettings
+	for i := range r.agents {
+		if r.agents[i].AgentID == agentID {
+			settings = &r.agents[i]
+		}
+	}
+	if settings == nil {
+		return nil, fmt.Errorf("heartbeat is not configured for agent %s", agentID)
+	}
+
+	r.mu.Lock()
+	if r.running[agentID] {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("heartbeat for agent %s is still running", agentID)
+	}
+	r.running[agentID] = true
+	var events []SystemEvent
+	if agentID == r.agents[0].AgentID {
+		events, r.events = r.events, nil
+	}
+	r.mu.Unlock()
+
+	started := time.Now()
+	state := r.run(ctx, settings, events)
+	state.AgentID = agentID
+	state.Timestamp = started
+	state.DurationMs = time.Since(started).Milliseconds()
+	state.Events = len(events)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, agentID)
+	if state.Status == StatusError && len(events) > 0 {
+		// Keep the events for the next attempt
+		r.events = append(events, r.events...)
+		if len(r.events) > maxQueuedEvents {
+			r.events = r.events[len(r.events)-maxQueuedEvents:]
+		}
+	}
+	r.last[agentID] = state
+	if err := r.saveLocked(); err != nil {
+		logger.Warn("Failed to save heartbeat state", zap.Error(err))
+	}
+
+	logger.Info("Heartbeat completed",
+		zap.String("agent_id", agentID),
+		zap.String("status", state.Status),
+		zap.Int("events", state.Events),
+		zap.Int64("duration_ms", state.DurationMs))
+	copied := *state
+	return &copied, nil
+}
+
+// run executes one heartbeat turn; the caller fills in the bookkeeping fields
+func (r *Runner) run(ctx context.Context, settings *AgentSettings, events []SystemEvent) *RunState {
+	checklist, err := ReadChecklist(settings.Workspace)
+	if err != nil {
+		return &RunState{Status: StatusError, Error: err.Error()}
+	}
+	if !HasTasks(checklist) && len(events) == 0 {
+		return &RunState{Status: StatusSkipped, Reason: FileName + " is empty"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+	result, err := r.chat.Chat(ctx, &agent.ChatRequest{
+		AgentID:    settings.AgentID,
+		SessionKey: "heartbeat:" + settings.AgentID,
+		Channel:    "heartbeat",
+		SenderID:   "heartbeat",
+		Message: agent.AgentMessage{
+			Role:      agent.RoleUser,
+			Content:   []agent.ContentBlock{agent.TextContent{Text: BuildPrompt(settings.Config.Prompt, checklist, events)}},
+			Timestamp: time.Now().UnixMilli(),
+		},
+	})
+	if err != nil {
+		return &RunState{Status: StatusError, Error: err.Error()}
+	}
+
+	reply, ack := StripAck(replyText(result), settings.Config.AckMaxChars)
+	state := &RunState{Reply: preview(reply)}
+	switch {
+	case ack:
+		state.Status = StatusOK
+	case settings.Config.Channel == "" || r.bus == nil:
+		state.Status = StatusAlert
+		state.Reason = "no delivery channel configured"
+	default:
+		err := r.bus.PublishOutbound(ctx, &bus.OutboundMessage{
+			Channel: settings.Config.Channel,
+			ChatID:  settings.Config.ChatID,
+			Content: reply,
+			Metadata: map[string]interface{}{
+				"agent_id":  settings.AgentID,
+				"heartbeat": true,
+			},
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			state.Status = StatusError
+			state.Error = fmt.Sprintf("failed to deliver heartbeat reply: %v", err)
+		} else {
+			state.Status = StatusSent
+		}
+	}
+	return state
+}
+
+// replyText returns the text content of the final reply
+func replyText(result *agent.ChatResult) string {
+	for _, block := range result.Reply.Content {
+		if text, ok := block.(agent.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// preview truncates a reply for the recorded state
+func preview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxReplyPreview {
+		return text
+	}
+	return string(runes[:maxReplyPreview]) + "..."
+}
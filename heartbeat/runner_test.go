@@ -0,0 +1,130 @@
+package heartbeat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+)
+
+type fakeChat struct {
+	reply   string
+	prompts []string
+}
+
+func (f *fakeChat) Chat(ctx context.Context, req *agent.ChatRequest) (*agent.ChatResult, error) {
+	f.prompts = append(f.prompts, req.Message.Content[0].(agent.TextContent).Text)
+	return &agent.ChatResult{
+		AgentID: req.AgentID,
+		Reply:   agent.AgentMessage{Role: agent.RoleAssistant, Content: []agent.ContentBlock{agent.TextContent{Text: f.reply}}},
+	}, nil
+}
+
+func TestHasTasks(t *testing.T) {
+	template := "# HEARTBEAT.md\n\n# Keep this file empty (or with only comments) to skip heartbeat API calls.\n<!-- add tasks below -->\n"
+	if HasTasks(template) {
+		t.Error("expected headings and comments only to count as empty")
+	}
+	if !HasTasks(template + "- check the inbox\n") {
+		t.Error("expected a task line to count")
+	}
+}
+
+func TestStripAck(t *testing.T) {
+	for _, tc := range []struct {
+		reply string
+		rest  string
+		ack   bool
+	}{
+		{"HEARTBEAT_OK", "", true},
+		{"", "", true},
+		{"**HEARTBEAT_OK**", "", true},
+		{"All quiet. HEARTBEAT_OK", "All quiet.", true},
+		{"HEARTBEAT_OK - " + strings.Repeat("x", 20), strings.Repeat("x", 20), false},
+		{"The build is failing on main.", "The build is failing on main.", false},
+	} {
+		rest, ack := StripAck(tc.reply, 10)
+		if rest != tc.rest || ack != tc.ack {
+			t.Errorf("StripAck(%q) = %q, %v; want %q, %v", tc.reply, rest, ack, tc.rest, tc.ack)
+		}
+	}
+}
+
+func TestSettingsFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Heartbeat = &config.HeartbeatConfig{Enabled: true}
+	cfg.Agents.List = []config.AgentConfig{
+		{ID: "ops", Workspace: "/srv/ops"},
+		{ID: "quiet", Heartbeat: &config.HeartbeatConfig{Enabled: false}},
+		{ID: "main", Default: true},
+	}
+	settings := SettingsFromConfig(cfg, "/srv/default")
+	if len(settings) != 2 || settings[0].AgentID != "main" || settings[0].Workspace != "/srv/default" || settings[1].Workspace != "/srv/ops" {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	workspace := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	chat := &fakeChat{reply: "HEARTBEAT_OK"}
+	messageBus := bus.NewMessageBus(10)
+	runner := NewRunner(chat, messageBus, []AgentSettings{{
+		AgentID:   "main",
+		Workspace: workspace,
+		Config:    config.HeartbeatConfig{Channel: "telegram", ChatID: "42"},
+	}}, statePath)
+	ctx := context.Background()
+
+	// No checklist and no events: the model is not called
+	state, err := runner.RunOnce(ctx, "main")
+	if err != nil || state.Status != StatusSkipped || len(chat.prompts) != 0 {
+		t.Fatalf("expected a skipped run, got %+v, %v", state, err)
+	}
+
+	// Queued events ride along and an ack is suppressed
+	if !runner.EnqueueSystemEvent("cron:backup", "Backup finished") {
+		t.Fatal("expected the event to be queued")
+	}
+	state, _ = runner.RunOnce(ctx, "main")
+	if state.Status != StatusOK || state.Events != 1 || !strings.Contains(chat.prompts[0], "cron:backup: Backup finished") {
+		t.Fatalf("unexpected run %+v, prompt %q", state, chat.prompts)
+	}
+
+	// Anything else is delivered to the configured chat
+	if err := os.WriteFile(filepath.Join(workspace, FileName), []byte("# Tasks\n- check CI\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chat.reply = "CI is red on main."
+	state, _ = runner.RunOnce(ctx, "main")
+	if state.Status != StatusSent || state.Events != 0 || !strings.Contains(chat.prompts[1], "- check CI") {
+		t.Fatalf("unexpected run %+v", state)
+	}
+	outCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msg, err := messageBus.ConsumeOutbound(outCtx)
+	if err != nil || msg.Channel != "telegram" || msg.ChatID != "42" || msg.Content != "CI is red on main." {
+		t.Fatalf("unexpected outbound message %+v, %v", msg, err)
+	}
+
+	// Disabling persists and stops queueing events
+	if err := runner.SetEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	if runner.EnqueueSystemEvent("cron:backup", "ignored") {
+		t.Error("expected a disabled runner to refuse events")
+	}
+	reloaded := NewRunner(chat, messageBus, nil, statePath)
+	if reloaded.Enabled() {
+		t.Error("expected the disabled flag to be restored")
+	}
+	if last := reloaded.Last(""); last == nil || last.Status != StatusSent || last.AgentID != "main" {
+		t.Errorf("expected the last run to be restored, got %+v", last)
+	}
+}
@@ -0,0 +1,64 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// load restores the enabled flag and last results, a missing file is not an error
+func (r *Runner) load() error {
+	if r.statePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read heartbeat state: %w", err)
+	}
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse heartbeat state: %w", err)
+	}
+	r.enabled = state.Enabled
+	for id, last := range state.Last {
+		if last != nil {
+			r.last[id] = last
+		}
+	}
+	return nil
+}
+
+// saveLocked writes the state atomically; the caller holds r.mu
+func (r *Runner) saveLocked() error {
+	if r.statePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.statePath), 0700); err != nil {
+		return fmt.Errorf("failed to create heartbeat directory: %w", err)
+	}
+	data, err := json.MarshalIndent(persistedState{Enabled: r.enabled, Last: r.last}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode heartbeat state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.statePath), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write heartbeat state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write heartbeat state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write heartbeat state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.statePath); err != nil {
+		return fmt.Errorf("failed to write heartbeat state: %w", err)
+	}
+	return nil
+}
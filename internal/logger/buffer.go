@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultBufferSize 内存环形缓冲默认保留的日志条数
+	DefaultBufferSize = 2000
+	// DefaultPageLimit 每次查询默认返回的日志条数
+	DefaultPageLimit = 100
+)
+
+// Entry 内存缓冲中的一条日志
+type Entry struct {
+	Seq       uint64    `json:"seq"` // 单调递增的序号，用作分页游标
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component,omitempty"` // component 或 module 字段，没有时为 logger 名称
+	Message   string    `json:"msg"`
+	Line      string    `json:"line"` // 与日志文件中相同的 JSON 行
+
+	level zapcore.Level
+}
+
+// Query 日志查询条件
+type Query struct {
+	Cursor    uint64 // 返回序号不小于 Cursor 的条目，0 表示返回最新的 Limit 条
+	Limit     int    // 最多返回的条数，默认 DefaultPageLimit
+	Level     string // 最低日志级别，为空返回所有级别
+	Component string // 只返回该组件的日志
+}
+
+// Page 一页查询结果
+type Page struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor uint64  `json:"next_cursor"` // 下一页的游标，也可以作为订阅的起点
+	Dropped    uint64  `json:"dropped"`     // 游标之后已被覆盖、无法再返回的条数
+	HasMore    bool    `json:"has_more"`
+}
+
+// Buffer 保留最近日志的环形缓冲，支持按游标分页和实时订阅
+type Buffer struct {
+	mu     sync.RWMutex
+	ring   []Entry
+	count  int
+	next   uint64
+	subs   map[int]chan Entry
+	nextID int
+}
+
+// NewBuffer 创建保留 size 条日志的缓冲
+func NewBuffer(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Buffer{
+		ring: make([]Entry, size),
+		next: 1,
+		subs: make(map[int]chan Entry),
+	}
+}
+
+// Append 写入一条日志并分配序号，然后推送给订阅者
+func (b *Buffer) Append(e Entry) Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.Seq = b.next
+	b.next++
+	b.ring[e.Seq%uint64(len(b.ring))] = e
+	if b.count < len(b.ring) {
+		b.count++
+	}
+
+	// Never block the logging call on a slow subscriber; gaps show up in the sequence numbers
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return e
+}
+
+// Query 按游标和过滤条件查询日志
+func (b *Buffer) Query(q Query) (*Page, error) {
+	match, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	oldest := b.next - uint64(b.count)
+	page := &Page{Entries: []Entry{}, NextCursor: b.next}
+
+	if q.Cursor == 0 {
+		// Tail: walk backwards collecting the newest matches
+		for seq := b.next - 1; seq >= oldest && seq > 0 && len(page.Entries) < limit; seq-- {
+			if e := b.at(seq); match(e) {
+				page.Entries = append(page.Entries, e)
+			}
+		}
+		for i, j := 0, len(page.Entries)-1; i < j; i, j = i+1, j-1 {
+			page.Entries[i], page.Entries[j] = page.Entries[j], page.Entries[i]
+		}
+		return page, nil
+	}
+
+	seq := q.Cursor
+	if seq < oldest {
+		page.Dropped = oldest - seq
+		seq = oldest
+	}
+	for ; seq < b.next; seq++ {
+		if len(page.Entries) == limit {
+			break
+		}
+		if e := b.at(seq); match(e) {
+			page.Entries = append(page.Entries, e)
+		}
+	}
+	if seq < b.next {
+		page.NextCursor = seq
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+// Subscribe 订阅之后写入的日志，返回的函数取消订阅并关闭通道
+// 订阅者消费过慢时条目会被丢弃，可以通过序号的间隔发现
+func (b *Buffer) Subscribe(size int) (<-chan Entry, func()) {
+	if size <= 0 {
+		size = DefaultPageLimit
+	}
+	ch := make(chan Entry, size)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Cursor 返回下一条日志将使用的序号
+func (b *Buffer) Cursor() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.next
+}
+
+// at returns the entry with the given sequence number; the caller holds the lock
+func (b *Buffer) at(seq uint64) Entry {
+	return b.ring[seq%uint64(len(b.ring))]
+}
+
+// Validate 检查查询的日志级别
+func (q Query) Validate() error {
+	_, err := q.matcher()
+	return err
+}
+
+// Match 条目是否满足查询的过滤条件
+func (q Query) Match(e Entry) bool {
+	match, err := q.matcher()
+	return err == nil && match(e)
+}
+
+// matcher compiles the level and component filters
+func (q Query) matcher() (func(Entry) bool, error) {
+	minLevel := zapcore.DebugLevel
+	if q.Level != "" {
+		level, err := zapcore.ParseLevel(strings.ToLower(q.Level))
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level %q", q.Level)
+		}
+		minLevel = level
+	}
+	return func(e Entry) bool {
+		if e.level < minLevel {
+			return false
+		}
+		return q.Component == "" || e.Component == q.Component
+	}, nil
+}
+
+// bufferCore is a zapcore.Core that encodes entries as JSON lines into a Buffer
+type bufferCore struct {
+	zapcore.LevelEnabler
+	enc       zapcore.Encoder
+	component string
+	buf       *Buffer
+}
+
+func newBufferCore(enc zapcore.Encoder, enab zapcore.LevelEnabler, buf *Buffer) *bufferCore {
+	return &bufferCore{LevelEnabler: enab, enc: enc, buf: buf}
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &bufferCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		component:    componentOf(fields, c.component),
+		buf:          c.buf,
+	}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	line, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	component := componentOf(fields, c.component)
+	if component == "" {
+		component = ent.LoggerName
+	}
+	c.buf.Append(Entry{
+		Time:      ent.Time,
+		Level:     ent.Level.CapitalString(),
+		Component: component,
+		Message:   ent.Message,
+		Line:      strings.TrimRight(line.String(), "\n"),
+		level:     ent.Level,
+	})
+	line.Free()
+	return nil
+}
+
+func (c *bufferCore) Sync() error {
+	return nil
+}
+
+// componentOf returns the last component or module field, or fallback
+func componentOf(fields []zapcore.Field, fallback string) string {
+	for _, f := range fields {
+		if (f.Key == "component" || f.Key == "module") && f.Type == zapcore.StringType {
+			fallback = f.String
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newBufferLogger(size int) (*zap.Logger, *Buffer) {
+	buf := NewBuffer(size)
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{TimeKey: "T", LevelKey: "L", MessageKey: "M", EncodeLevel: zapcore.CapitalLevelEncoder, EncodeTime: zapcore.ISO8601TimeEncoder})
+	return zap.New(newBufferCore(enc, zapcore.DebugLevel, buf)), buf
+}
+
+func TestBufferQuery(t *testing.T) {
+	log, buf := newBufferLogger(5)
+	cron := log.With(zap.String("component", "cron"))
+	log.Debug("one")
+	cron.Info("two")
+	log.Warn("three", zap.String("module", "gateway"))
+	cron.Error("four")
+
+	page, err := buf.Query(Query{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].Message != "three" || page.Entries[1].Message != "four" || page.NextCursor != 5 {
+		t.Fatalf("unexpected tail page %+v", page)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal([]byte(page.Entries[1].Line), &line); err != nil || line["M"] != "four" || line["L"] != "ERROR" || line["component"] != "cron" {
+		t.Errorf("unexpected line %q", page.Entries[1].Line)
+	}
+
+	page, _ = buf.Query(Query{Cursor: 1, Limit: 1, Component: "cron"})
+	if len(page.Entries) != 1 || page.Entries[0].Message != "two" || !page.HasMore || page.NextCursor != 3 {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, _ = buf.Query(Query{Cursor: page.NextCursor, Limit: 1, Component: "cron"})
+	if len(page.Entries) != 1 || page.Entries[0].Message != "four" || page.HasMore {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+
+	page, _ = buf.Query(Query{Cursor: 1, Level: "warn"})
+	if len(page.Entries) != 2 || page.Entries[0].Component != "gateway" {
+		t.Errorf("expected warn and error entries, got %+v", page.Entries)
+	}
+	if _, err := buf.Query(Query{Level: "loud"}); err == nil {
+		t.Error("expected an invalid level to fail")
+	}
+
+	// Wrap around the ring
+	for i := 0; i < 4; i++ {
+		log.Info("more")
+	}
+	page, _ = buf.Query(Query{Cursor: 2})
+	if page.Dropped != 2 || len(page.Entries) != 5 || page.Entries[0].Seq != 4 {
+		t.Errorf("expected the overwritten entries to be reported, got dropped=%d entries=%d", page.Dropped, len(page.Entries))
+	}
+}
+
+func TestBufferSubscribe(t *testing.T) {
+	log, buf := newBufferLogger(10)
+	entries, cancel := buf.Subscribe(1)
+	log.Info("first")
+	log.Info("second") // dropped, the subscriber is not reading
+
+	select {
+	case e := <-entries:
+		if e.Message != "first" || e.Seq != 1 {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an entry")
+	}
+	cancel()
+	cancel()
+	if _, open := <-entries; open {
+		t.Error("expected the channel to be closed")
+	}
+	log.Info("after cancel")
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "goclaw.log")
+	file, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for suffix, want := range map[string]string{"": "dddddddd", ".1": "cccccccc", ".2": "bbbbbbbb"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil || strings.TrimSpace(string(data)) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", suffix, want, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected old backups to be removed")
+	}
+}
@@ -14,20 +14,35 @@ var (
 	logMutex    sync.RWMutex
 	once        sync.Once
 	initialized bool
+	buffer      *Buffer
 )
 
+// Options 日志输出选项
+type Options struct {
+	BufferSize int    // 内存环形缓冲保留的条数，默认 DefaultBufferSize
+	File       string // 以 JSON 行写入的日志文件，为空则不写文件
+	MaxSizeMB  int    // 日志文件超过该大小（MB）后轮转，默认 DefaultMaxSizeMB
+	MaxBackups int    // 保留的轮转文件数，默认 DefaultMaxBackups
+}
+
 // Init 初始化日志 (线程安全)
 // 注意：多次调用 Init 只有第一次调用会真正初始化，后续调用会被忽略
 func Init(level string, development bool) error {
+	return InitWithOptions(level, development, Options{})
+}
+
+// InitWithOptions 按选项初始化日志，除控制台外还写入内存缓冲和可选的轮转文件
+// 与 Init 共用一次性初始化，先调用的生效
+func InitWithOptions(level string, development bool, opts Options) error {
 	var initErr error
 	once.Do(func() {
-		initErr = doInit(level, development)
+		initErr = doInit(level, development, opts)
 	})
 	return initErr
 }
 
 // doInit 执行实际的日志初始化
-func doInit(level string, development bool) error {
+func doInit(level string, development bool, opts Options) error {
 	// 解析日志级别
 	var zapLevel zapcore.Level
 	switch level {
@@ -66,9 +81,30 @@ func doInit(level string, development bool) error {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
+	// 内存缓冲和日志文件使用不带颜色的 JSON 行，goclaw logs 可以直接解析
+	jsonConfig := config.EncoderConfig
+	jsonConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	newBuffer := NewBuffer(opts.BufferSize)
+	cores := []zapcore.Core{newBufferCore(zapcore.NewJSONEncoder(jsonConfig), config.Level, newBuffer)}
+
+	var newFile *RotatingFile
+	if opts.File != "" {
+		file, err := OpenRotatingFile(opts.File, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		newFile = file
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(jsonConfig), file, config.Level))
+	}
+
 	// 创建 logger
-	newLog, err := config.Build(zap.AddCallerSkip(1))
+	newLog, err := config.Build(zap.AddCallerSkip(1), zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+	}))
 	if err != nil {
+		if newFile != nil {
+			_ = newFile.Close()
+		}
 		return err
 	}
 
@@ -76,6 +112,7 @@ func doInit(level string, development bool) error {
 	logMutex.Lock()
 	log = newLog
 	sugar = log.Sugar()
+	buffer = newBuffer
 	initialized = true
 	logMutex.Unlock()
 
@@ -118,6 +155,13 @@ func S() *zap.SugaredLogger {
 	return sugar
 }
 
+// Logs 返回保留最近日志的内存缓冲，日志未初始化时返回 nil
+func Logs() *Buffer {
+	logMutex.RLock()
+	defer logMutex.RUnlock()
+	return buffer
+}
+
 // Sync 同步日志 (线程安全)
 func Sync() error {
 	logMutex.RLock()
@@ -206,6 +206,11 @@ func (p *OpenAIProvider) MaxBatchSize() int {
 	return 2048
 }
 
+// Name identifies the embedding model
+func (p *OpenAIProvider) Name() string {
+	return "openai:" + p.config.Model
+}
+
 // GeminiProvider implements EmbeddingProvider using Google's Gemini API
 // This is a placeholder for future implementation
 type GeminiProvider struct {
@@ -250,3 +255,17 @@ func (p *GeminiProvider) Dimension() int {
 func (p *GeminiProvider) MaxBatchSize() int {
 	return 100 // Gemini typically supports smaller batches
 }
+
+// Name identifies the embedding model
+func (p *GeminiProvider) Name() string {
+	return "gemini:" + p.model
+}
+
+// EmbeddingProviderName returns the identifier recorded in the store for
+// embeddings produced by the provider
+func EmbeddingProviderName(p EmbeddingProvider) string {
+	if named, ok := p.(NamedProvider); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", p)
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultLocalDimension is the default dimension of local embeddings
+	DefaultLocalDimension = 384
+
+	// localProviderVersion identifies the feature extraction scheme.
+	// Bump it whenever tokenization or weighting changes so existing
+	// indexes are detected as stale.
+	localProviderVersion = "v1"
+)
+
+// Feature weights for the local provider. Whole words carry most of the
+// signal, word bigrams capture short phrases and character trigrams make
+// the embedding tolerant to inflections and typos.
+const (
+	localWordWeight    = 1.0
+	localBigramWeight  = 0.5
+	localTrigramWeight = 0.3
+	localStopWeight    = 0.1
+)
+
+// localStopWords are down-weighted instead of dropped so that queries made
+// only of common words still produce a usable vector
+var localStopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true,
+	"but": true, "in": true, "on": true, "at": true, "to": true,
+	"for": true, "of": true, "with": true, "by": true, "from": true,
+	"is": true, "was": true, "are": true, "were": true, "be": true,
+	"it": true, "this": true, "that": true, "as": true, "i": true,
+	"的": true, "了": true, "是": true, "在": true, "和": true,
+}
+
+// LocalProvider implements EmbeddingProvider fully in-process using
+// hashed n-gram features (the "hashing trick"). Each text is tokenized
+// into words, word bigrams and character trigrams; every feature is
+// hashed into one of Dimension buckets with a pseudo-random sign and
+// weighted by sublinear term frequency. The result is L2-normalized, so
+// cosine similarity approximates TF weighted n-gram overlap.
+//
+// It needs no network access or model files and is deterministic, which
+// makes it suitable for air-gapped deployments. It captures lexical rather
+// than semantic similarity.
+type LocalProvider struct {
+	dimension int
+}
+
+// NewLocalProvider creates a local embedding provider.
+// A non-positive dimension selects DefaultLocalDimension.
+func NewLocalProvider(dimension int) (*LocalProvider, error) {
+	if dimension <= 0 {
+		dimension = DefaultLocalDimension
+	}
+	if dimension < 32 {
+		return nil, fmt.Errorf("local embedding dimension must be at least 32, got %d", dimension)
+	}
+	return &LocalProvider{dimension: dimension}, nil
+}
+
+// Name identifies the embedding space produced by this provider
+func (p *LocalProvider) Name() string {
+	return "local-hash-" + localProviderVersion
+}
+
+// Embed generates a single embedding
+func (p *LocalProvider) Embed(text string) ([]float32, error) {
+	vec := make([]float64, p.dimension)
+	for feature, weight := range localFeatures(text) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+
+		idx := int(sum % uint64(p.dimension))
+		if sum>>63 == 1 {
+			vec[idx] -= weight
+		} else {
+			vec[idx] += weight
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, p.dimension)
+	if norm == 0 {
+		return result, nil
+	}
+	for i, v := range vec {
+		result[i] = float32(v / norm)
+	}
+	return result, nil
+}
+
+// EmbedBatch generates multiple embeddings in one call
+func (p *LocalProvider) EmbedBatch(texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := p.Embed(text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = vec
+	}
+	return results, nil
+}
+
+// Dimension returns the dimension of embeddings
+func (p *LocalProvider) Dimension() int {
+	return p.dimension
+}
+
+// MaxBatchSize returns the maximum batch size
+func (p *LocalProvider) MaxBatchSize() int {
+	return 1000
+}
+
+// localFeatures extracts weighted n-gram features from text
+func localFeatures(text string) map[string]float64 {
+	counts := make(map[string]float64)
+	weights := make(map[string]float64)
+	add := func(feature string, weight float64) {
+		counts[feature]++
+		weights[feature] = weight
+	}
+
+	tokens := localTokenize(text)
+	for i, tok := range tokens {
+		weight := localWordWeight
+		if localStopWords[tok] {
+			weight = localStopWeight
+		}
+		add("w:"+tok, weight)
+
+		if i > 0 {
+			add("b:"+tokens[i-1]+" "+tok, localBigramWeight)
+		}
+
+		runes := []rune("<" + tok + ">")
+		if len(runes) > 4 {
+			for j := 0; j+3 <= len(runes); j++ {
+				add("c:"+string(runes[j:j+3]), localTrigramWeight)
+			}
+		}
+	}
+
+	features := make(map[string]float64, len(counts))
+	for feature, tf := range counts {
+		features[feature] = (1 + math.Log(tf)) * weights[feature]
+	}
+	return features
+}
+
+// localTokenize lowercases text and splits it into word tokens.
+// Scripts written without spaces (Han, Hiragana, Katakana, Hangul) are
+// split into overlapping character bigrams, with single characters kept
+// so that one-character queries still match.
+func localTokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			tokens = append(tokens, string(r))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// isCJK reports whether r belongs to a script written without spaces
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocalProviderEmbed(t *testing.T) {
+	p, err := NewLocalProvider(0)
+	if err != nil {
+		t.Fatalf("NewLocalProvider failed: %v", err)
+	}
+	if p.Dimension() != DefaultLocalDimension {
+		t.Errorf("expected default dimension %d, got %d", DefaultLocalDimension, p.Dimension())
+	}
+
+	a, _ := p.Embed("Deploy the service with Kubernetes")
+	b, _ := p.Embed("Deploy the service with Kubernetes")
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("embeddings should be deterministic")
+		}
+	}
+
+	var norm float64
+	for _, v := range a {
+		norm += float64(v * v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected unit vector, got norm %f", norm)
+	}
+
+	empty, _ := p.Embed("  ")
+	for _, v := range empty {
+		if v != 0 {
+			t.Fatal("expected zero vector for empty text")
+		}
+	}
+}
+
+func TestLocalProviderSimilarity(t *testing.T) {
+	p, _ := NewLocalProvider(256)
+	embed := func(text string) []float32 {
+		vec, err := p.Embed(text)
+		if err != nil {
+			t.Fatalf("Embed failed: %v", err)
+		}
+		return vec
+	}
+	sim := func(a, b string) float64 {
+		s, _ := CosineSimilarity(embed(a), embed(b))
+		return s
+	}
+
+	related := sim("kubernetes deployment", "We deployed the API to our Kubernetes cluster")
+	unrelated := sim("kubernetes deployment", "My favourite food is spicy noodles")
+	if related <= unrelated {
+		t.Errorf("expected related text to score higher: %f <= %f", related, unrelated)
+	}
+
+	// Character trigrams make inflected forms similar
+	if s := sim("deploying", "deployed"); s <= 0.1 {
+		t.Errorf("expected inflections to be similar, got %f", s)
+	}
+
+	// Chinese text is split into character n-grams
+	zh := sim("用户喜欢喝咖啡", "用户每天早上喝咖啡")
+	zhOther := sim("用户喜欢喝咖啡", "服务器部署在上海")
+	if zh <= zhOther {
+		t.Errorf("expected related Chinese text to score higher: %f <= %f", zh, zhOther)
+	}
+
+	batch, err := p.EmbedBatch([]string{"a", "b"})
+	if err != nil || len(batch) != 2 || len(batch[0]) != 256 {
+		t.Errorf("unexpected batch result: %d, %v", len(batch), err)
+	}
+
+	if _, err := NewLocalProvider(8); err == nil {
+		t.Error("expected error for tiny dimension")
+	}
+}
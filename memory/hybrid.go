@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -186,6 +187,36 @@ func MergeHybridResults(vector []HybridVectorResult, keyword []HybridKeywordResu
 	return results
 }
 
+// mergeSearchResults merges vector and keyword results of the same store,
+// keeping the full memory of each result.
+// Applies weighted scoring: score = vectorWeight * vectorScore + textWeight * textScore
+func mergeSearchResults(vector, keyword []*SearchResult, vectorWeight, textWeight float64) []*SearchResult {
+	byID := make(map[string]*SearchResult, len(vector)+len(keyword))
+	merged := make([]*SearchResult, 0, len(vector)+len(keyword))
+
+	for _, r := range vector {
+		byID[r.ID] = r
+		merged = append(merged, r)
+	}
+	for _, r := range keyword {
+		if existing, ok := byID[r.ID]; ok {
+			existing.TextScore = r.TextScore
+			continue
+		}
+		byID[r.ID] = r
+		merged = append(merged, r)
+	}
+
+	for _, r := range merged {
+		r.Score = vectorWeight*r.VectorScore + textWeight*r.TextScore
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	return merged
+}
+
 // ExtractKeywords extracts important keywords from query for expansion
 func ExtractKeywords(query string) []string {
 	// Remove common stop words
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -166,9 +167,26 @@ func (m *MemoryManager) Search(ctx context.Context, query string, opts SearchOpt
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	// Perform search
+	// Perform search. A mismatched index can't be compared with the query
+	// vector, so fall back to keyword search until it is re-embedded.
 	results, err := m.store.Search(queryVec, opts)
-	if err != nil {
+	mismatch := errors.Is(err, ErrIndexMismatch)
+	if err != nil && !mismatch {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	// Combine with keyword matches for hybrid search
+	if ts, ok := m.store.(TextSearcher); ok && (opts.Hybrid || mismatch) {
+		keyword, err := ts.SearchText(query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		if mismatch {
+			results = keyword
+		} else {
+			results = mergeSearchResults(results, keyword, opts.VectorWeight, opts.TextWeight)
+		}
+	} else if mismatch {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
@@ -376,6 +394,33 @@ type MemoryStats struct {
 	CacheSize  int `json:"cache_size"`
 }
 
+// Reembed regenerates all stored embeddings with the manager's provider.
+// It is needed after switching embedding providers or dimensions.
+func (m *MemoryManager) Reembed(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	re, ok := m.store.(Reembedder)
+	if !ok {
+		return 0, fmt.Errorf("store does not support re-embedding")
+	}
+	n, err := re.Reembed(m.provider)
+	if err != nil {
+		return 0, err
+	}
+
+	// Cached memories still hold the old vectors
+	m.cache.Clear()
+
+	return n, nil
+}
+
 // ClearCache clears the in-memory cache
 func (m *MemoryManager) ClearCache() {
 	m.mu.Lock()
@@ -26,6 +26,7 @@ type MemorySearchManager interface {
 // BuiltinSearchManager builtin 后端实现
 type BuiltinSearchManager struct {
 	manager *MemoryManager
+	store   *SQLiteStore
 	dbPath  string
 }
 
@@ -35,19 +36,15 @@ type QMDSearchManager struct {
 	fallbackMgr MemorySearchManager // 回退到 builtin
 	useFallback bool
 	config      config.QMDConfig
+	builtin     config.BuiltinMemoryConfig // 回退时使用的 builtin 配置（数据库路径、向量化）
 	workspace   string
 }
 
 // NewBuiltinSearchManager 创建 builtin 搜索管理器
 func NewBuiltinSearchManager(cfg config.MemoryConfig, workspace string) (MemorySearchManager, error) {
-	home, err := os.UserHomeDir()
+	dbPath, err := BuiltinDatabasePath(cfg.Builtin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	dbPath := cfg.Builtin.DatabasePath
-	if dbPath == "" {
-		dbPath = filepath.Join(home, ".goclaw", "memory", "store.db")
+		return nil, err
 	}
 
 	// 确保数据库目录存在
@@ -55,20 +52,21 @@ func NewBuiltinSearchManager(cfg config.MemoryConfig, workspace string) (MemoryS
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
+	// 创建向量化 provider
+	provider, err := NewEmbeddingProvider(cfg.Builtin.Embedding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding provider: %w", err)
+	}
+
 	// 创建存储
-	storeConfig := DefaultStoreConfig(dbPath, nil)
+	storeConfig := DefaultStoreConfig(dbPath, provider)
 	store, err := NewSQLiteStore(storeConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open memory store: %w", err)
 	}
 
-	// 创建管理器（不使用 provider，仅用于元数据）
-	managerConfig := ManagerConfig{
-		Store:        store,
-		Provider:     nil, // QMD 模式下不需要本地 provider
-		CacheMaxSize: 1000,
-	}
-	manager, err := NewMemoryManager(managerConfig)
+	// 创建管理器
+	manager, err := NewMemoryManager(DefaultManagerConfig(store, provider))
 	if err != nil {
 		store.Close()
 		return nil, fmt.Errorf("failed to create memory manager: %w", err)
@@ -76,10 +74,64 @@ func NewBuiltinSearchManager(cfg config.MemoryConfig, workspace string) (MemoryS
 
 	return &BuiltinSearchManager{
 		manager: manager,
+		store:   store,
 		dbPath:  dbPath,
 	}, nil
 }
 
+// BuiltinDatabasePath 返回 builtin 数据库路径，未配置时使用 ~/.goclaw/memory/store.db
+func BuiltinDatabasePath(cfg config.BuiltinMemoryConfig) (string, error) {
+	if cfg.DatabasePath != "" {
+		return cfg.DatabasePath, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goclaw", "memory", "store.db"), nil
+}
+
+// NewEmbeddingProvider 根据配置创建向量化 provider
+// auto（默认）在配置了 API Key 时使用 openai，否则使用完全离线的 local provider
+func NewEmbeddingProvider(cfg config.MemoryEmbeddingConfig) (EmbeddingProvider, error) {
+	provider := cfg.Provider
+	if provider == "" || provider == "auto" {
+		provider = "local"
+		if cfg.APIKey != "" {
+			provider = "openai"
+		}
+	}
+
+	switch provider {
+	case "local":
+		return NewLocalProvider(cfg.Dimension)
+	case "openai":
+		openaiCfg := DefaultOpenAIConfig(cfg.APIKey)
+		if cfg.Model != "" {
+			openaiCfg.Model = cfg.Model
+		}
+		if cfg.BaseURL != "" {
+			openaiCfg.BaseURL = cfg.BaseURL
+		}
+		return NewOpenAIProvider(openaiCfg)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.Provider)
+	}
+}
+
+// ResolveEmbeddingConfig 返回 builtin 记忆的向量化配置
+// 未单独配置 API Key 时使用 providers.openai / providers.openrouter 的 API Key
+func ResolveEmbeddingConfig(cfg *config.Config) config.MemoryEmbeddingConfig {
+	embedding := cfg.Memory.Builtin.Embedding
+	if embedding.APIKey == "" {
+		embedding.APIKey = cfg.Providers.OpenAI.APIKey
+		if embedding.APIKey == "" {
+			embedding.APIKey = cfg.Providers.OpenRouter.APIKey
+		}
+	}
+	return embedding
+}
+
 // Search 执行搜索
 func (m *BuiltinSearchManager) Search(ctx context.Context, query string, opts SearchOptions) ([]*SearchResult, error) {
 	return m.manager.Search(ctx, query, opts)
@@ -105,6 +157,13 @@ func (m *BuiltinSearchManager) GetStatus() map[string]interface{} {
 		status["cache_size"] = stats.CacheSize
 	}
 
+	provider, dimension := m.store.EmbeddingInfo()
+	status["embedding_provider"] = provider
+	status["embedding_dimension"] = dimension
+	if err := m.store.Mismatch(); err != nil {
+		status["index_mismatch"] = err.Error()
+	}
+
 	return status
 }
 
@@ -114,9 +173,11 @@ func (m *BuiltinSearchManager) Close() error {
 }
 
 // NewQMDSearchManager 创建 QMD 搜索管理器
-func NewQMDSearchManager(qmdCfg config.QMDConfig, workspace string) (MemorySearchManager, error) {
+// QMD 不可用时回退到按 cfg.Builtin 配置的 builtin 搜索管理器
+func NewQMDSearchManager(cfg config.MemoryConfig, workspace string) (MemorySearchManager, error) {
+	qmdCfg := cfg.QMD
 	// 转换配置
-	cfg := qmd.QMDConfig{
+	qcfg := qmd.QMDConfig{
 		Command:        qmdCfg.Command,
 		Enabled:        qmdCfg.Enabled,
 		IncludeDefault: qmdCfg.IncludeDefault,
@@ -141,14 +202,14 @@ func NewQMDSearchManager(qmdCfg config.QMDConfig, workspace string) (MemorySearc
 	}
 
 	for i, p := range qmdCfg.Paths {
-		cfg.Paths[i] = qmd.QMDPathConfig{
+		qcfg.Paths[i] = qmd.QMDPathConfig{
 			Name:    p.Name,
 			Path:    p.Path,
 			Pattern: p.Pattern,
 		}
 	}
 
-	qmdMgr := qmd.NewQMDManager(cfg, workspace, "")
+	qmdMgr := qmd.NewQMDManager(qcfg, workspace, "")
 
 	// 尝试初始化
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -156,23 +217,17 @@ func NewQMDSearchManager(qmdCfg config.QMDConfig, workspace string) (MemorySearc
 
 	if err := qmdMgr.Initialize(ctx); err != nil {
 		// QMD 不可用，使用 fallback
-		mgr, err := NewBuiltinSearchManager(config.MemoryConfig{
-			Backend: "builtin",
-			Builtin: config.BuiltinMemoryConfig{
-				Enabled: true,
-			},
-		}, workspace)
-		if err != nil {
-			return nil, err
-		}
-
-		return &QMDSearchManager{
+		m := &QMDSearchManager{
 			qmdMgr:      qmdMgr,
-			fallbackMgr: mgr,
 			useFallback: true,
 			config:      qmdCfg,
+			builtin:     cfg.Builtin,
 			workspace:   workspace,
-		}, nil
+		}
+		if m.fallbackMgr, err = m.newFallback(); err != nil {
+			return nil, err
+		}
+		return m, nil
 	}
 
 	return &QMDSearchManager{
@@ -180,10 +235,18 @@ func NewQMDSearchManager(qmdCfg config.QMDConfig, workspace string) (MemorySearc
 		fallbackMgr: nil,
 		useFallback: false,
 		config:      qmdCfg,
+		builtin:     cfg.Builtin,
 		workspace:   workspace,
 	}, nil
 }
 
+// newFallback 按 builtin 配置创建回退用的搜索管理器
+func (m *QMDSearchManager) newFallback() (MemorySearchManager, error) {
+	builtin := m.builtin
+	builtin.Enabled = true
+	return NewBuiltinSearchManager(config.MemoryConfig{Backend: "builtin", Builtin: builtin}, m.workspace)
+}
+
 // Search 执行搜索
 func (m *QMDSearchManager) Search(ctx context.Context, query string, opts SearchOptions) ([]*SearchResult, error) {
 	if m.useFallback && m.fallbackMgr != nil {
@@ -195,12 +258,11 @@ func (m *QMDSearchManager) Search(ctx context.Context, query string, opts Search
 	if err != nil {
 		// 切换到 fallback
 		if m.fallbackMgr == nil {
-			m.fallbackMgr, _ = NewBuiltinSearchManager(config.MemoryConfig{
-				Backend: "builtin",
-				Builtin: config.BuiltinMemoryConfig{
-					Enabled: true,
-				},
-			}, m.workspace)
+			fallback, fbErr := m.newFallback()
+			if fbErr != nil {
+				return nil, fmt.Errorf("qmd query failed: %w (fallback: %v)", err, fbErr)
+			}
+			m.fallbackMgr = fallback
 		}
 		m.useFallback = true
 		return m.fallbackMgr.Search(ctx, query, opts)
@@ -277,7 +339,7 @@ func GetMemorySearchManager(cfg config.MemoryConfig, workspace string) (MemorySe
 	switch cfg.Backend {
 	case "qmd":
 		if cfg.QMD.Enabled {
-			return NewQMDSearchManager(cfg.QMD, workspace)
+			return NewQMDSearchManager(cfg, workspace)
 		}
 		// 回退到 builtin
 		return GetBuiltinSearchManager(cfg, workspace)
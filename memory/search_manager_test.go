@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestQMDFallbackUsesBuiltinConfig(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "custom.db")
+	mgr, err := NewQMDSearchManager(config.MemoryConfig{
+		Backend: "qmd",
+		QMD:     config.QMDConfig{Enabled: true, Command: filepath.Join(t.TempDir(), "missing-qmd")},
+		Builtin: config.BuiltinMemoryConfig{
+			DatabasePath: dbPath,
+			Embedding:    config.MemoryEmbeddingConfig{Provider: "local", Dimension: 64},
+		},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	qmdMgr := mgr.(*QMDSearchManager)
+	fallback, ok := qmdMgr.fallbackMgr.(*BuiltinSearchManager)
+	if !qmdMgr.useFallback || !ok {
+		t.Fatalf("expected a builtin fallback, got %+v", qmdMgr)
+	}
+	if fallback.dbPath != dbPath {
+		t.Errorf("expected the fallback to use %s, got %s", dbPath, fallback.dbPath)
+	}
+	if _, dimension := fallback.store.EmbeddingInfo(); dimension != 64 {
+		t.Errorf("expected the configured embedding dimension, got %d", dimension)
+	}
+}
@@ -3,9 +3,12 @@ package memory
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,13 +16,24 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrIndexMismatch is returned when the stored embeddings were produced by
+// a different embedding provider or dimension than the current one
+var ErrIndexMismatch = errors.New("memory index was built with a different embedding provider")
+
 // SQLiteStore implements the Store interface using SQLite
 type SQLiteStore struct {
-	db          *sql.DB
-	dbPath      string
-	provider    EmbeddingProvider
-	mu          sync.RWMutex
-	initialized bool
+	db            *sql.DB
+	dbPath        string
+	provider      EmbeddingProvider
+	mu            sync.RWMutex
+	initialized   bool
+	vectorEnabled bool
+	ftsEnabled    bool
+	// indexProvider and indexDimension describe the embeddings stored in the database
+	indexProvider  string
+	indexDimension int
+	// mismatch is set when the stored embeddings don't match the provider
+	mismatch error
 }
 
 // StoreConfig configures the SQLite memory store
@@ -28,7 +42,8 @@ type StoreConfig struct {
 	DBPath string
 	// Provider is the embedding provider to use
 	Provider EmbeddingProvider
-	// EnableVectorSearch enables vector similarity search (requires sqlite-vec)
+	// EnableVectorSearch enables vector similarity search. The sqlite-vec
+	// extension is used when available, otherwise vectors are scanned in-process.
 	EnableVectorSearch bool
 	// EnableFTS enables full-text search
 	EnableFTS bool
@@ -161,10 +176,8 @@ func (s *SQLiteStore) initSchema(config StoreConfig) error {
 
 	// Enable vector search if configured
 	if config.EnableVectorSearch {
-		if err := s.initVectorSearch(); err != nil {
-			// Log warning but don't fail - vector search is optional
-			fmt.Printf("Warning: vector search initialization failed: %v\n", err)
-		}
+		// sqlite-vec is optional - without it vectors are scanned in-process
+		_ = s.initVectorSearch()
 	}
 
 	// Enable FTS if configured
@@ -177,11 +190,73 @@ func (s *SQLiteStore) initSchema(config StoreConfig) error {
 
 	// Store schema version
 	s.setMeta("schema_version", "1")
-	s.setMeta("provider_dimension", fmt.Sprintf("%d", s.provider.Dimension()))
 
+	return s.checkEmbeddingMeta()
+}
+
+// checkEmbeddingMeta compares the embedding provider recorded in the store
+// with the current provider. An empty index adopts the current provider;
+// a populated index built by another provider or dimension is flagged as
+// mismatched until it is re-embedded.
+func (s *SQLiteStore) checkEmbeddingMeta() error {
+	name := EmbeddingProviderName(s.provider)
+	dimension := s.provider.Dimension()
+
+	var embedded int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM memories WHERE dimension > 0`).Scan(&embedded); err != nil {
+		return fmt.Errorf("failed to count embeddings: %w", err)
+	}
+	if embedded == 0 {
+		s.recordEmbeddingMeta(name, dimension)
+		return nil
+	}
+
+	storedName := s.getMeta("embedding_provider")
+	storedDimension, _ := strconv.Atoi(s.getMeta("provider_dimension"))
+	if storedName == "" {
+		// Indexes created before the provider was recorded: trust the
+		// dimension of the stored vectors
+		_ = s.db.QueryRow(`SELECT dimension FROM memories WHERE dimension > 0 LIMIT 1`).Scan(&storedDimension)
+		if storedDimension == dimension {
+			s.recordEmbeddingMeta(name, dimension)
+			return nil
+		}
+		storedName = "unknown"
+	}
+
+	s.indexProvider = storedName
+	s.indexDimension = storedDimension
+	if storedName != name || storedDimension != dimension {
+		s.mismatch = fmt.Errorf("%w: index uses %s (dimension %d) but the current provider is %s (dimension %d); run 'goclaw memory index' to re-embed",
+			ErrIndexMismatch, storedName, storedDimension, name, dimension)
+	}
 	return nil
 }
 
+// recordEmbeddingMeta records the provider that produced the stored embeddings
+func (s *SQLiteStore) recordEmbeddingMeta(name string, dimension int) {
+	s.setMeta("embedding_provider", name)
+	s.setMeta("provider_dimension", strconv.Itoa(dimension))
+	s.indexProvider = name
+	s.indexDimension = dimension
+	s.mismatch = nil
+}
+
+// EmbeddingInfo returns the provider name and dimension of the stored embeddings
+func (s *SQLiteStore) EmbeddingInfo() (string, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.indexProvider, s.indexDimension
+}
+
+// Mismatch returns an error wrapping ErrIndexMismatch if the stored
+// embeddings don't match the current provider, nil otherwise
+func (s *SQLiteStore) Mismatch() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mismatch
+}
+
 // initVectorSearch initializes vector similarity search using sqlite-vec
 func (s *SQLiteStore) initVectorSearch() error {
 	// Try to load sqlite-vec extension
@@ -225,6 +300,7 @@ func (s *SQLiteStore) initVectorSearch() error {
 	}
 
 	s.setMeta("vector_enabled", "true")
+	s.vectorEnabled = true
 	return nil
 }
 
@@ -243,6 +319,7 @@ func (s *SQLiteStore) initFTS() error {
 	}
 
 	s.setMeta("fts_enabled", "true")
+	s.ftsEnabled = true
 	return nil
 }
 
@@ -258,6 +335,13 @@ func (s *SQLiteStore) setMeta(key, value string) {
 	`, key, value, now)
 }
 
+// getMeta reads a value from the metadata table, returning "" if missing
+func (s *SQLiteStore) getMeta(key string) string {
+	var value string
+	_ = s.db.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	return value
+}
+
 // Add adds a memory to the store
 func (s *SQLiteStore) Add(embedding *VectorEmbedding) error {
 	if embedding.ID == "" {
@@ -271,6 +355,10 @@ func (s *SQLiteStore) Add(embedding *VectorEmbedding) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.mismatch != nil && len(embedding.Vector) > 0 {
+		return s.mismatch
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -315,14 +403,14 @@ func (s *SQLiteStore) Add(embedding *VectorEmbedding) error {
 	}
 
 	// Insert into vector table if enabled
-	if s.isVectorEnabled() && len(embedding.Vector) > 0 {
+	if s.vectorEnabled && len(embedding.Vector) > 0 {
 		if err := s.insertVector(tx, embedding.ID, embedding.Vector); err != nil {
 			return fmt.Errorf("failed to insert vector: %w", err)
 		}
 	}
 
 	// Insert into FTS if enabled
-	if s.isFTSEnabled() {
+	if s.ftsEnabled {
 		if err := s.insertFTS(tx, embedding); err != nil {
 			return fmt.Errorf("failed to insert FTS: %w", err)
 		}
@@ -340,6 +428,10 @@ func (s *SQLiteStore) AddBatch(embeddings []*VectorEmbedding) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.mismatch != nil {
+		return s.mismatch
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -391,13 +483,13 @@ func (s *SQLiteStore) AddBatch(embeddings []*VectorEmbedding) error {
 			return fmt.Errorf("failed to insert memory %s: %w", emb.ID, err)
 		}
 
-		if s.isVectorEnabled() && len(emb.Vector) > 0 {
+		if s.vectorEnabled && len(emb.Vector) > 0 {
 			if err := s.insertVector(tx, emb.ID, emb.Vector); err != nil {
 				return fmt.Errorf("failed to insert vector for %s: %w", emb.ID, err)
 			}
 		}
 
-		if s.isFTSEnabled() {
+		if s.ftsEnabled {
 			if err := s.insertFTS(tx, emb); err != nil {
 				return fmt.Errorf("failed to insert FTS for %s: %w", emb.ID, err)
 			}
@@ -426,13 +518,15 @@ func (s *SQLiteStore) insertVector(tx *sql.Tx, id string, vector []float32) erro
 	return err
 }
 
-// insertFTS inserts text into the FTS table
+// insertFTS inserts text into the FTS table, replacing any previous entry.
+// FTS5 tables don't support upserts, so the old row is deleted first.
 func (s *SQLiteStore) insertFTS(tx *sql.Tx, embedding *VectorEmbedding) error {
+	if _, err := tx.Exec(`DELETE FROM memory_fts WHERE id = ?`, embedding.ID); err != nil {
+		return err
+	}
 	_, err := tx.Exec(`
 		INSERT INTO memory_fts (text, id, source, type)
 		VALUES (?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			text = excluded.text
 	`, embedding.Text, embedding.ID, embedding.Source, embedding.Type)
 
 	return err
@@ -446,69 +540,36 @@ func (s *SQLiteStore) Search(query []float32, opts SearchOptions) ([]*SearchResu
 	if len(query) == 0 {
 		return nil, fmt.Errorf("query vector is empty")
 	}
+	if s.mismatch != nil {
+		return nil, s.mismatch
+	}
 
-	var results []*SearchResult
-
-	// If vector search is enabled, use it
-	if s.isVectorEnabled() {
+	// Use sqlite-vec when it is loaded, otherwise scan the stored vectors
+	if s.vectorEnabled {
 		results, err := s.searchVector(query, opts)
 		if err != nil {
 			return nil, fmt.Errorf("vector search failed: %w", err)
 		}
-
-		// If hybrid search is enabled and FTS is available, combine results
-		if opts.Hybrid && s.isFTSEnabled() {
-			ftsResults, err := s.searchFTS(query, opts)
-			if err == nil && len(ftsResults) > 0 {
-				return s.mergeHybridResults(results, ftsResults, opts), nil
-			}
-		}
-
 		return results, nil
 	}
 
-	// Fallback to basic text search
-	if s.isFTSEnabled() {
-		return s.searchFTS(query, opts)
-	}
-
-	// No search available, return empty
-	return results, nil
+	return s.scanVectors(query, opts)
 }
 
-// searchVector performs vector similarity search
+// searchVector performs vector similarity search using sqlite-vec
 func (s *SQLiteStore) searchVector(query []float32, opts SearchOptions) ([]*SearchResult, error) {
-	queryStr := float32SliceToString(query)
-
+	filter, args := filterClause(opts)
 	querySQL := `
-		SELECT
-			m.id,
-			m.text,
-			m.source,
-			m.type,
-			m.created_at,
-			m.updated_at,
-			m.file_path,
-			m.line_number,
-			m.session_key,
-			m.tags,
-			m.importance,
-			m.access_count,
-			m.last_accessed,
-			distance
+		SELECT ` + memoryColumns + `, distance
 		FROM memory_vec v
 		JOIN memories m ON m.id = v.id
-		WHERE v.embedding MATCH ?
-		AND m.source IN (` + sourcePlaceholders(opts.Sources) + `)
-		AND m.type IN (` + typePlaceholders(opts.Types) + `)
+		WHERE v.embedding MATCH ?` + filter + `
 		ORDER BY distance
 		LIMIT ?
 	`
 
-	args := []interface{}{queryStr}
-	args = appendSources(args, opts.Sources)
-	args = appendTypes(args, opts.Types)
-	args = append(args, opts.Limit)
+	args = append([]interface{}{float32SliceToString(query)}, args...)
+	args = append(args, searchLimit(opts))
 
 	rows, err := s.db.Query(querySQL, args...)
 	if err != nil {
@@ -518,113 +579,115 @@ func (s *SQLiteStore) searchVector(query []float32, opts SearchOptions) ([]*Sear
 
 	var results []*SearchResult
 	for rows.Next() {
-		var sr SearchResult
-		var tagsJSON sql.NullString
-		var lastAccessed sql.NullInt64
-		var filePath sql.NullString
-		var sessionKey sql.NullString
-		var lineNumber sql.NullInt64
-		var importance sql.NullFloat64
-		var accessCount sql.NullInt64
-
-		err := rows.Scan(
-			&sr.ID,
-			&sr.Text,
-			&sr.Source,
-			&sr.Type,
-			&sr.CreatedAt,
-			&sr.UpdatedAt,
-			&filePath,
-			&lineNumber,
-			&sessionKey,
-			&tagsJSON,
-			&importance,
-			&accessCount,
-			&lastAccessed,
-			&sr.Score,
-		)
+		var distance float64
+		ve, err := scanMemory(rows, &distance)
 		if err != nil {
 			continue
 		}
 
-		// Populate metadata fields
-		sr.Metadata.FilePath = filePath.String
-		sr.Metadata.LineNumber = int(lineNumber.Int64)
-		sr.Metadata.SessionKey = sessionKey.String
-		sr.Metadata.Importance = importance.Float64
-		sr.Metadata.AccessCount = int(accessCount.Int64)
-
-		if lastAccessed.Valid {
-			sr.Metadata.LastAccessed = time.Unix(lastAccessed.Int64, 0)
+		// Convert distance to similarity score (lower distance = higher score)
+		// Assuming L2 distance, convert to 0-1 range
+		score := 1.0 / (1.0 + distance)
+		if score >= opts.MinScore {
+			results = append(results, &SearchResult{VectorEmbedding: *ve, Score: score, VectorScore: score})
 		}
+	}
 
-		if tagsJSON.Valid {
-			_ = json.Unmarshal([]byte(tagsJSON.String), &sr.Metadata.Tags)
-		}
+	return results, rows.Err()
+}
 
-		// Convert distance to similarity score (lower distance = higher score)
-		// Assuming L2 distance, convert to 0-1 range
-		sr.Score = 1.0 / (1.0 + sr.Score)
+// scanVectors performs exact cosine similarity search over the stored
+// embeddings. It is used when the sqlite-vec extension is unavailable and
+// is fast enough for personal memory stores of a few thousand chunks.
+func (s *SQLiteStore) scanVectors(query []float32, opts SearchOptions) ([]*SearchResult, error) {
+	filter, args := filterClause(opts)
+	args = append([]interface{}{len(query)}, args...)
 
-		if sr.Score >= opts.MinScore {
-			results = append(results, &sr)
+	rows, err := s.db.Query(`
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.dimension = ?`+filter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*SearchResult
+	for rows.Next() {
+		ve, err := scanMemory(rows)
+		if err != nil {
+			continue
+		}
+
+		score, err := CosineSimilarity(query, ve.Vector)
+		if err != nil || score < opts.MinScore {
+			continue
 		}
+		results = append(results, &SearchResult{VectorEmbedding: *ve, Score: score, VectorScore: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return results, nil
-}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit := searchLimit(opts); len(results) > limit {
+		results = results[:limit]
+	}
 
-// searchFTS performs full-text search
-func (s *SQLiteStore) searchFTS(query []float32, opts SearchOptions) ([]*SearchResult, error) {
-	// For now, this is a placeholder
-	// In a full implementation, we'd convert the vector to text or use a separate text query
-	return []*SearchResult{}, nil
+	return results, nil
 }
 
-// mergeHybridResults combines vector and FTS results
-func (s *SQLiteStore) mergeHybridResults(vectorResults, ftsResults []*SearchResult, opts SearchOptions) []*SearchResult {
-	// Create a map of results by ID
-	resultMap := make(map[string]*SearchResult)
+// SearchText performs BM25 keyword search using FTS5. Scores are mapped
+// to the 0-1 range so they can be combined with vector scores.
+func (s *SQLiteStore) SearchText(query string, opts SearchOptions) ([]*SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	// Add vector results
-	for _, vr := range vectorResults {
-		resultMap[vr.ID] = vr
+	if !s.ftsEnabled {
+		return nil, nil
+	}
+	ftsQuery := BuildFTSQuery(query)
+	if ftsQuery == "" {
+		return nil, nil
 	}
 
-	// Merge FTS results
-	for _, fr := range ftsResults {
-		if existing, ok := resultMap[fr.ID]; ok {
-			// Combine scores using weighted average
-			existing.Score = existing.Score*opts.VectorWeight + fr.Score*opts.TextWeight
-		} else {
-			fr.Score = fr.Score * opts.TextWeight
-			resultMap[fr.ID] = fr
-		}
+	filter, args := filterClause(opts)
+	args = append([]interface{}{ftsQuery}, args...)
+	args = append(args, searchLimit(opts))
+
+	rows, err := s.db.Query(`
+		SELECT `+memoryColumns+`, bm25(memory_fts) AS rank
+		FROM memory_fts
+		JOIN memories m ON m.id = memory_fts.id
+		WHERE memory_fts MATCH ?`+filter+`
+		ORDER BY rank
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search failed: %w", err)
 	}
+	defer rows.Close()
 
-	// Convert back to slice and sort by score
 	var results []*SearchResult
-	for _, r := range resultMap {
-		if r.Score >= opts.MinScore {
-			results = append(results, r)
+	for rows.Next() {
+		var rank float64
+		ve, err := scanMemory(rows, &rank)
+		if err != nil {
+			continue
 		}
-	}
 
-	// Sort by score descending
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[i].Score < results[j].Score {
-				results[i], results[j] = results[j], results[i]
-			}
+		// bm25() is negative, more negative meaning more relevant
+		relevance := -rank
+		if relevance < 0 {
+			relevance = 0
 		}
+		score := relevance / (1 + relevance)
+		results = append(results, &SearchResult{VectorEmbedding: *ve, Score: score, TextScore: score})
 	}
 
-	// Limit results
-	if len(results) > opts.Limit {
-		results = results[:opts.Limit]
-	}
-
-	return results
+	return results, rows.Err()
 }
 
 // Get retrieves a memory by ID
@@ -632,40 +695,11 @@ func (s *SQLiteStore) Get(id string) (*VectorEmbedding, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var ve VectorEmbedding
-	var embeddingJSON sql.NullString
-	var tagsJSON sql.NullString
-	var lastAccessed sql.NullInt64
-	var filePath sql.NullString
-	var sessionKey sql.NullString
-	var lineNumber sql.NullInt64
-	var importance sql.NullFloat64
-	var accessCount sql.NullInt64
-
-	err := s.db.QueryRow(`
-		SELECT
-			id, text, source, type, embedding, created_at, updated_at,
-			file_path, line_number, session_key, tags, importance,
-			access_count, last_accessed
-		FROM memories
-		WHERE id = ?
-	`, id).Scan(
-		&ve.ID,
-		&ve.Text,
-		&ve.Source,
-		&ve.Type,
-		&embeddingJSON,
-		&ve.CreatedAt,
-		&ve.UpdatedAt,
-		&filePath,
-		&lineNumber,
-		&sessionKey,
-		&tagsJSON,
-		&importance,
-		&accessCount,
-		&lastAccessed,
-	)
-
+	ve, err := scanMemory(s.db.QueryRow(`
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.id = ?
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("memory not found: %s", id)
 	}
@@ -673,33 +707,10 @@ func (s *SQLiteStore) Get(id string) (*VectorEmbedding, error) {
 		return nil, fmt.Errorf("failed to get memory: %w", err)
 	}
 
-	// Unmarshal embedding
-	if embeddingJSON.Valid {
-		_ = json.Unmarshal([]byte(embeddingJSON.String), &ve.Vector)
-		ve.Dimension = len(ve.Vector)
-	}
-
-	// Populate metadata fields
-	ve.Metadata.FilePath = filePath.String
-	ve.Metadata.LineNumber = int(lineNumber.Int64)
-	ve.Metadata.SessionKey = sessionKey.String
-	ve.Metadata.Importance = importance.Float64
-	ve.Metadata.AccessCount = int(accessCount.Int64)
-
-	// Unmarshal tags
-	if tagsJSON.Valid {
-		_ = json.Unmarshal([]byte(tagsJSON.String), &ve.Metadata.Tags)
-	}
-
-	// Handle last accessed
-	if lastAccessed.Valid {
-		ve.Metadata.LastAccessed = time.Unix(lastAccessed.Int64, 0)
-	}
-
 	// Update access count
 	go s.updateAccessCount(id)
 
-	return &ve, nil
+	return ve, nil
 }
 
 // Delete removes a memory by ID
@@ -719,14 +730,14 @@ func (s *SQLiteStore) Delete(id string) error {
 	}
 
 	// Delete from vector table
-	if s.isVectorEnabled() {
+	if s.vectorEnabled {
 		if _, err := tx.Exec(`DELETE FROM memory_vec WHERE id = ?`, id); err != nil {
 			return fmt.Errorf("failed to delete vector: %w", err)
 		}
 	}
 
 	// Delete from FTS table
-	if s.isFTSEnabled() {
+	if s.ftsEnabled {
 		if _, err := tx.Exec(`DELETE FROM memory_fts WHERE id = ?`, id); err != nil {
 			return fmt.Errorf("failed to delete FTS: %w", err)
 		}
@@ -746,6 +757,10 @@ func (s *SQLiteStore) Update(embedding *VectorEmbedding) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.mismatch != nil && len(embedding.Vector) > 0 {
+		return s.mismatch
+	}
+
 	var tagsJSON string
 	if len(embedding.Metadata.Tags) > 0 {
 		tagsBytes, _ := json.Marshal(embedding.Metadata.Tags)
@@ -758,7 +773,13 @@ func (s *SQLiteStore) Update(embedding *VectorEmbedding) error {
 		embeddingJSON = string(embBytes)
 	}
 
-	_, err := s.db.Exec(`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(`
 		UPDATE memories SET
 			text = ?,
 			source = ?,
@@ -785,6 +806,22 @@ func (s *SQLiteStore) Update(embedding *VectorEmbedding) error {
 		return fmt.Errorf("failed to update memory: %w", err)
 	}
 
+	if s.vectorEnabled && len(embedding.Vector) > 0 {
+		if err := s.insertVector(tx, embedding.ID, embedding.Vector); err != nil {
+			return fmt.Errorf("failed to update vector: %w", err)
+		}
+	}
+
+	if s.ftsEnabled {
+		if err := s.insertFTS(tx, embedding); err != nil {
+			return fmt.Errorf("failed to update FTS: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -794,12 +831,9 @@ func (s *SQLiteStore) List(filter func(*VectorEmbedding) bool) ([]*VectorEmbeddi
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT
-			id, text, source, type, embedding, created_at, updated_at,
-			file_path, line_number, session_key, tags, importance,
-			access_count, last_accessed
-		FROM memories
-		ORDER BY created_at DESC
+		SELECT ` + memoryColumns + `
+		FROM memories m
+		ORDER BY m.created_at DESC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list memories: %w", err)
@@ -808,62 +842,91 @@ func (s *SQLiteStore) List(filter func(*VectorEmbedding) bool) ([]*VectorEmbeddi
 
 	var results []*VectorEmbedding
 	for rows.Next() {
-		var ve VectorEmbedding
-		var embeddingJSON sql.NullString
-		var tagsJSON sql.NullString
-		var lastAccessed sql.NullInt64
-		var filePath sql.NullString
-		var sessionKey sql.NullString
-		var lineNumber sql.NullInt64
-		var importance sql.NullFloat64
-		var accessCount sql.NullInt64
-
-		err := rows.Scan(
-			&ve.ID,
-			&ve.Text,
-			&ve.Source,
-			&ve.Type,
-			&embeddingJSON,
-			&ve.CreatedAt,
-			&ve.UpdatedAt,
-			&filePath,
-			&lineNumber,
-			&sessionKey,
-			&tagsJSON,
-			&importance,
-			&accessCount,
-			&lastAccessed,
-		)
+		ve, err := scanMemory(rows)
 		if err != nil {
 			continue
 		}
 
-		if embeddingJSON.Valid {
-			_ = json.Unmarshal([]byte(embeddingJSON.String), &ve.Vector)
-			ve.Dimension = len(ve.Vector)
+		if filter == nil || filter(ve) {
+			results = append(results, ve)
 		}
+	}
 
-		// Populate metadata fields
-		ve.Metadata.FilePath = filePath.String
-		ve.Metadata.LineNumber = int(lineNumber.Int64)
-		ve.Metadata.SessionKey = sessionKey.String
-		ve.Metadata.Importance = importance.Float64
-		ve.Metadata.AccessCount = int(accessCount.Int64)
+	return results, rows.Err()
+}
+
+// Reembed regenerates the embeddings of all stored memories with the given
+// provider and records it as the index provider. It is used to migrate an
+// index after the embedding provider or its dimension changed.
+func (s *SQLiteStore) Reembed(provider EmbeddingProvider) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		if tagsJSON.Valid {
-			_ = json.Unmarshal([]byte(tagsJSON.String), &ve.Metadata.Tags)
+	rows, err := s.db.Query(`SELECT id, text FROM memories`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list memories: %w", err)
+	}
+	var ids, texts []string
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read memory: %w", err)
 		}
+		ids = append(ids, id)
+		texts = append(texts, text)
+	}
+	rows.Close()
 
-		if lastAccessed.Valid {
-			ve.Metadata.LastAccessed = time.Unix(lastAccessed.Int64, 0)
+	// Generate all embeddings before touching the database so that a
+	// provider failure leaves the index unchanged
+	batchSize := provider.MaxBatchSize()
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		batch, err := provider.EmbedBatch(texts[start:end])
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		if len(batch) != end-start {
+			return 0, fmt.Errorf("provider returned %d embeddings for %d texts", len(batch), end-start)
 		}
+		vectors = append(vectors, batch...)
+	}
 
-		if filter == nil || filter(&ve) {
-			results = append(results, &ve)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+	for i, id := range ids {
+		embBytes, err := json.Marshal(vectors[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal embedding: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE memories SET embedding = ?, dimension = ?, updated_at = ? WHERE id = ?`,
+			string(embBytes), len(vectors[i]), now, id); err != nil {
+			return 0, fmt.Errorf("failed to update embedding for %s: %w", id, err)
+		}
+		if s.vectorEnabled {
+			if err := s.insertVector(tx, id, vectors[i]); err != nil {
+				return 0, fmt.Errorf("failed to insert vector for %s: %w", id, err)
+			}
 		}
 	}
 
-	return results, nil
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.provider = provider
+	s.recordEmbeddingMeta(EmbeddingProviderName(provider), provider.Dimension())
+	return len(ids), nil
 }
 
 // Close closes the store
@@ -891,61 +954,106 @@ func (s *SQLiteStore) updateAccessCount(id string) {
 	`, now, id)
 }
 
-// isVectorEnabled checks if vector search is enabled
-func (s *SQLiteStore) isVectorEnabled() bool {
-	var value string
-	err := s.db.QueryRow("SELECT value FROM meta WHERE key = ?", "vector_enabled").Scan(&value)
-	return err == nil && value == "true"
-}
+// memoryColumns are the memories columns read by scanMemory
+const memoryColumns = `m.id, m.text, m.source, m.type, m.embedding, m.created_at, m.updated_at,
+	m.file_path, m.line_number, m.session_key, m.tags, m.importance,
+	m.access_count, m.last_accessed`
 
-// isFTSEnabled checks if FTS is enabled
-func (s *SQLiteStore) isFTSEnabled() bool {
-	var value string
-	err := s.db.QueryRow("SELECT value FROM meta WHERE key = ?", "fts_enabled").Scan(&value)
-	return err == nil && value == "true"
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-// Helper functions for SQL placeholders
-func sourcePlaceholders(sources []MemorySource) string {
-	if len(sources) == 0 {
-		return "'longterm', 'session', 'daily'"
+// scanMemory scans memoryColumns followed by any extra columns
+func scanMemory(row rowScanner, extra ...interface{}) (*VectorEmbedding, error) {
+	var ve VectorEmbedding
+	var embeddingJSON sql.NullString
+	var createdAt, updatedAt int64
+	var tagsJSON sql.NullString
+	var lastAccessed sql.NullInt64
+	var filePath sql.NullString
+	var sessionKey sql.NullString
+	var lineNumber sql.NullInt64
+	var importance sql.NullFloat64
+	var accessCount sql.NullInt64
+
+	dest := []interface{}{
+		&ve.ID,
+		&ve.Text,
+		&ve.Source,
+		&ve.Type,
+		&embeddingJSON,
+		&createdAt,
+		&updatedAt,
+		&filePath,
+		&lineNumber,
+		&sessionKey,
+		&tagsJSON,
+		&importance,
+		&accessCount,
+		&lastAccessed,
 	}
-	placeholders := make([]string, len(sources))
-	for i := range placeholders {
-		placeholders[i] = "?"
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
-	return "(" + joinString(placeholders, ",") + ")"
-}
 
-func typePlaceholders(types []MemoryType) string {
-	if len(types) == 0 {
-		return "'fact', 'preference', 'context', 'conversation'"
+	ve.CreatedAt = time.Unix(createdAt, 0)
+	ve.UpdatedAt = time.Unix(updatedAt, 0)
+
+	if embeddingJSON.Valid && embeddingJSON.String != "" {
+		_ = json.Unmarshal([]byte(embeddingJSON.String), &ve.Vector)
+		ve.Dimension = len(ve.Vector)
+	}
+
+	// Populate metadata fields
+	ve.Metadata.FilePath = filePath.String
+	ve.Metadata.LineNumber = int(lineNumber.Int64)
+	ve.Metadata.SessionKey = sessionKey.String
+	ve.Metadata.Importance = importance.Float64
+	ve.Metadata.AccessCount = int(accessCount.Int64)
+
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		_ = json.Unmarshal([]byte(tagsJSON.String), &ve.Metadata.Tags)
 	}
-	placeholders := make([]string, len(types))
-	for i := range placeholders {
-		placeholders[i] = "?"
+
+	if lastAccessed.Valid && lastAccessed.Int64 > 0 {
+		ve.Metadata.LastAccessed = time.Unix(lastAccessed.Int64, 0)
 	}
-	return "(" + joinString(placeholders, ",") + ")"
+
+	return &ve, nil
 }
 
-func appendSources(args []interface{}, sources []MemorySource) []interface{} {
-	if len(sources) == 0 {
-		return args
+// filterClause builds the source/type filter for search queries
+func filterClause(opts SearchOptions) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+
+	if len(opts.Sources) > 0 {
+		placeholders := make([]string, len(opts.Sources))
+		for i, source := range opts.Sources {
+			placeholders[i] = "?"
+			args = append(args, string(source))
+		}
+		clause += " AND m.source IN (" + joinString(placeholders, ",") + ")"
 	}
-	for _, s := range sources {
-		args = append(args, string(s))
+	if len(opts.Types) > 0 {
+		placeholders := make([]string, len(opts.Types))
+		for i, t := range opts.Types {
+			placeholders[i] = "?"
+			args = append(args, string(t))
+		}
+		clause += " AND m.type IN (" + joinString(placeholders, ",") + ")"
 	}
-	return args
+
+	return clause, args
 }
 
-func appendTypes(args []interface{}, types []MemoryType) []interface{} {
-	if len(types) == 0 {
-		return args
-	}
-	for _, t := range types {
-		args = append(args, string(t))
+// searchLimit returns the result limit for search queries
+func searchLimit(opts SearchOptions) int {
+	if opts.Limit > 0 {
+		return opts.Limit
 	}
-	return args
+	return 10
 }
 
 func float32SliceToString(vec []float32) string {
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestManager(t *testing.T, dbPath string, dimension int) (*MemoryManager, *SQLiteStore) {
+	t.Helper()
+	provider, err := NewLocalProvider(dimension)
+	if err != nil {
+		t.Fatalf("NewLocalProvider failed: %v", err)
+	}
+	store, err := NewSQLiteStore(DefaultStoreConfig(dbPath, provider))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	manager, err := NewMemoryManager(DefaultManagerConfig(store, provider))
+	if err != nil {
+		t.Fatalf("NewMemoryManager failed: %v", err)
+	}
+	return manager, store
+}
+
+func addTestMemories(t *testing.T, manager *MemoryManager) {
+	t.Helper()
+	items := []MemoryItem{
+		{Text: "The production cluster runs on Kubernetes in Frankfurt", Source: MemorySourceLongTerm, Type: MemoryTypeFact},
+		{Text: "User prefers dark roast coffee in the morning", Source: MemorySourceLongTerm, Type: MemoryTypePreference},
+		{Text: "Weekly sync with the design team is on Thursday", Source: MemorySourceDaily, Type: MemoryTypeContext},
+	}
+	if err := manager.AddMemoryBatch(context.Background(), items); err != nil {
+		t.Fatalf("AddMemoryBatch failed: %v", err)
+	}
+}
+
+func TestSQLiteStoreVectorSearch(t *testing.T) {
+	manager, store := newTestManager(t, filepath.Join(t.TempDir(), "memory.db"), 256)
+	defer manager.Close()
+	addTestMemories(t, manager)
+
+	opts := SearchOptions{Limit: 2, Hybrid: false}
+	results, err := manager.Search(context.Background(), "which cluster runs kubernetes", opts)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Type != MemoryTypeFact {
+		t.Fatalf("expected kubernetes fact first, got %+v", results)
+	}
+	if results[0].VectorScore <= 0 || results[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected result fields %+v", results[0])
+	}
+
+	// Filters apply to the in-process scan
+	opts.Sources = []MemorySource{MemorySourceDaily}
+	results, _ = manager.Search(context.Background(), "kubernetes", opts)
+	for _, r := range results {
+		if r.Source != MemorySourceDaily {
+			t.Errorf("unexpected source %s", r.Source)
+		}
+	}
+
+	name, dimension := store.EmbeddingInfo()
+	if name != "local-hash-v1" || dimension != 256 {
+		t.Errorf("unexpected embedding info %s/%d", name, dimension)
+	}
+
+	all, err := manager.List(context.Background(), nil)
+	if err != nil || len(all) != 3 {
+		t.Errorf("expected 3 memories, got %d, %v", len(all), err)
+	}
+}
+
+func TestSQLiteStoreHybridSearch(t *testing.T) {
+	manager, store := newTestManager(t, filepath.Join(t.TempDir(), "memory.db"), 256)
+	defer manager.Close()
+	addTestMemories(t, manager)
+
+	keyword, err := store.SearchText("Thursday", SearchOptions{Limit: 5})
+	if err != nil {
+		t.Fatalf("SearchText failed: %v", err)
+	}
+	if len(keyword) != 1 || keyword[0].TextScore <= 0 || keyword[0].TextScore >= 1 {
+		t.Fatalf("unexpected keyword results %+v", keyword)
+	}
+
+	results, err := manager.Search(context.Background(), "coffee", SearchOptions{Limit: 3, Hybrid: true, VectorWeight: 0.7, TextWeight: 0.3})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Type != MemoryTypePreference {
+		t.Fatalf("expected coffee preference first, got %+v", results)
+	}
+	if results[0].TextScore == 0 || results[0].VectorScore == 0 {
+		t.Errorf("expected both scores to contribute, got %+v", results[0])
+	}
+
+	// Updating the text refreshes the keyword index
+	ve := &results[0].VectorEmbedding
+	ve.Text = "User switched to green tea"
+	ve.Vector = nil
+	if err := manager.Update(context.Background(), ve); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if keyword, _ := store.SearchText("coffee", SearchOptions{}); len(keyword) != 0 {
+		t.Errorf("expected stale FTS entry to be replaced, got %+v", keyword)
+	}
+}
+
+func TestSQLiteStoreProviderMismatch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	manager, _ := newTestManager(t, dbPath, 256)
+	addTestMemories(t, manager)
+	manager.Close()
+
+	// Reopening with another dimension is detected
+	manager, store := newTestManager(t, dbPath, 128)
+	defer manager.Close()
+	if err := store.Mismatch(); !errors.Is(err, ErrIndexMismatch) {
+		t.Fatalf("expected index mismatch, got %v", err)
+	}
+	if _, err := manager.AddMemory(context.Background(), "new fact", MemorySourceLongTerm, MemoryTypeFact, MemoryMetadata{}); !errors.Is(err, ErrIndexMismatch) {
+		t.Errorf("expected add to be refused, got %v", err)
+	}
+
+	// Search degrades to keyword matching
+	results, err := manager.Search(context.Background(), "Frankfurt", SearchOptions{Limit: 3})
+	if err != nil || len(results) != 1 || results[0].VectorScore != 0 {
+		t.Fatalf("expected keyword fallback, got %+v, %v", results, err)
+	}
+
+	n, err := manager.Reembed(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Reembed = %d, %v", n, err)
+	}
+	if err := store.Mismatch(); err != nil {
+		t.Errorf("expected mismatch to be cleared, got %v", err)
+	}
+	results, err = manager.Search(context.Background(), "Frankfurt cluster", SearchOptions{Limit: 1})
+	if err != nil || len(results) != 1 || results[0].VectorScore == 0 || len(results[0].Vector) != 128 {
+		t.Fatalf("expected vector search after re-embedding, got %+v, %v", results, err)
+	}
+	manager.Close()
+
+	// The new provider is persisted
+	manager, store = newTestManager(t, dbPath, 128)
+	defer manager.Close()
+	if err := store.Mismatch(); err != nil {
+		t.Errorf("unexpected mismatch after reopening: %v", err)
+	}
+}
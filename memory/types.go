@@ -175,6 +175,14 @@ type EmbeddingProvider interface {
 	MaxBatchSize() int
 }
 
+// NamedProvider is implemented by embedding providers that can identify the
+// embedding space they produce, so indexes built by another provider or
+// model can be detected
+type NamedProvider interface {
+	// Name returns a stable identifier such as "openai:text-embedding-3-small"
+	Name() string
+}
+
 // Store defines the interface for memory storage
 type Store interface {
 	// Add adds a memory to the store
@@ -194,3 +202,17 @@ type Store interface {
 	// Close closes the store
 	Close() error
 }
+
+// TextSearcher is implemented by stores that support keyword search,
+// which MemoryManager combines with vector search for hybrid queries
+type TextSearcher interface {
+	// SearchText performs keyword search, scoring results in the 0-1 range
+	SearchText(query string, opts SearchOptions) ([]*SearchResult, error)
+}
+
+// Reembedder is implemented by stores that can regenerate all stored
+// embeddings with a new provider
+type Reembedder interface {
+	// Reembed re-embeds every memory and returns the number of memories updated
+	Reembed(provider EmbeddingProvider) (int, error)
+}
@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,10 +23,25 @@ const (
 	DMPolicyPairing DMPolicy = "pairing"
 	// DMPolicyAllowlist only allows senders in the allowlist
 	DMPolicyAllowlist DMPolicy = "allowlist"
-	// DMPolicyClosed blocks all DMs
+	// DMPolicyDisabled blocks all DMs
+	DMPolicyDisabled DMPolicy = "disabled"
+	// DMPolicyClosed is the legacy name of DMPolicyDisabled
 	DMPolicyClosed DMPolicy = "closed"
 )
 
+// ParseDMPolicy validates a configured policy; "closed" is normalized to
+// DMPolicyDisabled and an empty string is returned unchanged
+func ParseDMPolicy(s string) (DMPolicy, error) {
+	switch policy := DMPolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "", DMPolicyOpen, DMPolicyPairing, DMPolicyAllowlist, DMPolicyDisabled:
+		return policy, nil
+	case DMPolicyClosed:
+		return DMPolicyDisabled, nil
+	default:
+		return "", fmt.Errorf("invalid dm_policy %q (must be open, pairing, allowlist or disabled)", s)
+	}
+}
+
 // PairingRequest represents a pending pairing request
 type PairingRequest struct {
 	ID        string                 `json:"id"`         // sender ID (e.g., open_id, user_id)
@@ -46,21 +62,38 @@ type AllowStore struct {
 	filePath string
 	mu       sync.RWMutex
 	allowMap map[string]string // sender ID -> name
+	stamp    fileStamp         // allowlist file as last loaded or saved
+}
+
+// fileStamp identifies a version of a file written by this or another process
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampOf returns the current stamp of path, zero if it does not exist
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
 }
 
 // PairingStore manages pairing requests and allowlist
 type PairingStore struct {
-	channel      string       // e.g., "feishu", "telegram"
-	accountID    string       // empty for default account
-	dataDir      string       // e.g., ~/.goclaw/credentials/
-	requestsFile string       // path to pending requests JSON
-	allowFile    string       // path to allowlist JSON
-	mu           sync.RWMutex
-	requests     []*PairingRequest
-	allowStore   *AllowStore
-	codeLength   int           // default 8
-	codeExpiry   time.Duration // default 1 hour
-	maxPending   int           // max pending requests, default 3
+	channel       string // e.g., "feishu", "telegram"
+	accountID     string // empty for default account
+	dataDir       string // e.g., ~/.goclaw/credentials/
+	requestsFile  string // path to pending requests JSON
+	allowFile     string // path to allowlist JSON
+	mu            sync.RWMutex
+	requests      []*PairingRequest
+	allowStore    *AllowStore
+	codeLength    int           // default 8
+	codeExpiry    time.Duration // default 1 hour
+	maxPending    int           // max pending requests, default 3
+	requestsStamp fileStamp     // requests file as last loaded or saved
 }
 
 // Config options for PairingStore
@@ -163,9 +196,36 @@ func (ps *PairingStore) load() error {
 		return fmt.Errorf("failed to read allowlist file: %w", err)
 	}
 
+	ps.requestsStamp = stampOf(ps.requestsFile)
+	ps.allowStore.stamp = stampOf(ps.allowFile)
 	return nil
 }
 
+// refresh reloads files changed by another process, such as the pairing CLI
+// approving a code while the gateway is running
+func (ps *PairingStore) refresh() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if stamp := stampOf(ps.requestsFile); stamp != ps.requestsStamp {
+		var requests []*PairingRequest
+		if data, err := os.ReadFile(ps.requestsFile); err == nil && (len(data) == 0 || json.Unmarshal(data, &requests) == nil) {
+			ps.requests = append(make([]*PairingRequest, 0, len(requests)), requests...)
+			ps.requestsStamp = stamp
+		}
+	}
+
+	ps.allowStore.mu.Lock()
+	defer ps.allowStore.mu.Unlock()
+	if stamp := stampOf(ps.allowFile); stamp != ps.allowStore.stamp {
+		allowMap := make(map[string]string)
+		if data, err := os.ReadFile(ps.allowFile); err == nil && (len(data) == 0 || json.Unmarshal(data, &allowMap) == nil) {
+			ps.allowStore.allowMap = allowMap
+			ps.allowStore.stamp = stamp
+		}
+	}
+}
+
 // save saves pairing requests to disk
 // Note: caller must hold the write lock (mu.Lock)
 func (ps *PairingStore) save() error {
@@ -177,6 +237,7 @@ func (ps *PairingStore) save() error {
 	if err := os.WriteFile(ps.requestsFile, data, 0600); err != nil {
 		return fmt.Errorf("failed to write requests file: %w", err)
 	}
+	ps.requestsStamp = stampOf(ps.requestsFile)
 
 	return nil
 }
@@ -216,6 +277,8 @@ func (ps *PairingStore) cleanExpired() {
 // Returns (code, created, error)
 // created is true if a new request was created, false if existing request was found
 func (ps *PairingStore) UpsertRequest(senderID, senderName string) (string, bool, error) {
+	ps.refresh()
+
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
@@ -358,6 +421,8 @@ func (ps *PairingStore) Reject(code string) error {
 
 // IsAllowed checks if a sender is in the allowlist
 func (ps *PairingStore) IsAllowed(senderID string) bool {
+	ps.refresh()
+
 	ps.allowStore.mu.RLock()
 	defer ps.allowStore.mu.RUnlock()
 
@@ -425,6 +490,7 @@ func (as *AllowStore) Save() error {
 	if err := os.WriteFile(as.filePath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write allowlist file: %w", err)
 	}
+	as.stamp = stampOf(as.filePath)
 
 	return nil
 }
@@ -1,21 +1,35 @@
 package providers
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/smallnest/goclaw/internal/logger"
-	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/anthropic"
 	"go.uber.org/zap"
 )
 
+const (
+	// defaultAnthropicBaseURL Anthropic API 默认地址
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	// anthropicAPIVersion Messages API 版本
+	anthropicAPIVersion = "2023-06-01"
+	// defaultAnthropicMaxTokens 未配置 max_tokens 时的默认值（API 要求必填）
+	defaultAnthropicMaxTokens = 4096
+)
+
 // AnthropicProvider Anthropic 提供商
+// 直接调用 Messages API，以支持提示词缓存断点和扩展思考
 type AnthropicProvider struct {
-	llm       llms.Model
+	client    *http.Client
+	apiKey    string
+	baseURL   string
 	model     string
 	maxTokens int
 	timeout   time.Duration
@@ -36,43 +50,125 @@ func NewAnthropicProviderWithTimeout(apiKey, baseURL, model string, maxTokens in
 		model = "claude-3-opus-20240229"
 	}
 
-	opts := []anthropic.Option{
-		anthropic.WithToken(apiKey),
-		anthropic.WithModel(model),
-	}
-
-	if baseURL != "" {
-		// Note: langchaingo's anthropic package might not support WithBaseURL yet,
-		// but we should check if it's available or use a custom client if needed.
-		// For now, we'll try to use it if it exists.
-		// Actually, let's check langchaingo's anthropic option.
-		_ = baseURL // TODO: implement custom base URL support
-		// nolint:staticcheck,emptybranch
+	// 兼容带 /v1 后缀的地址
+	baseURL = strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
 	}
 
 	// 设置超时
+	client := &http.Client{}
 	if timeout > 0 {
-		httpClient := &http.Client{
-			Timeout: timeout,
-		}
-		opts = append(opts, anthropic.WithHTTPClient(httpClient))
+		client.Timeout = timeout
 		logger.Info("Anthropic provider configured with timeout",
 			zap.Duration("timeout", timeout))
 	}
 
-	llm, err := anthropic.New(opts...)
-	if err != nil {
-		return nil, err
-	}
-
 	return &AnthropicProvider{
-		llm:       llm,
+		client:    client,
+		apiKey:    apiKey,
+		baseURL:   baseURL,
 		model:     model,
 		maxTokens: maxTokens,
 		timeout:   timeout,
 	}, nil
 }
 
+// anthropicRequest Messages API 请求
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      []anthropicBlock   `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+}
+
+// anthropicThinking 扩展思考配置
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// anthropicCacheControl 缓存断点
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// ephemeralCache 返回临时缓存断点
+func ephemeralCache() *anthropicCacheControl {
+	return &anthropicCacheControl{Type: "ephemeral"}
+}
+
+// anthropicMessage Messages API 消息
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock 内容块（text, image, tool_use, tool_result, thinking, redacted_thinking）
+type anthropicBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+
+	// thinking / redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicImageSource 图片来源
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool 工具定义
+type anthropicTool struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicResponse Messages API 响应
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicErrorResponse 错误响应
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 // Chat 聊天
 func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
 	opts := &ChatOptions{
@@ -86,121 +182,270 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		opt(opts)
 	}
 
-	// 转换消息
-	langchainMessages := make([]llms.MessageContent, len(messages))
-	for i, msg := range messages {
-		var role llms.ChatMessageType
+	reqBody := buildAnthropicRequest(messages, tools, opts)
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr anthropicErrorResponse
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("anthropic API error (status %d): %s: %s", resp.StatusCode, apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	response := parseAnthropicResponse(&result)
+	if response.Usage.CacheCreationInputTokens > 0 || response.Usage.CacheReadInputTokens > 0 {
+		logger.Debug("Anthropic prompt cache usage",
+			zap.Int("cache_creation_input_tokens", response.Usage.CacheCreationInputTokens),
+			zap.Int("cache_read_input_tokens", response.Usage.CacheReadInputTokens))
+	}
+
+	return response, nil
+}
+
+// buildAnthropicRequest 将通用消息转换为 Messages API 请求
+func buildAnthropicRequest(messages []Message, tools []ToolDefinition, opts *ChatOptions) *anthropicRequest {
+	req := &anthropicRequest{
+		Model:     opts.Model,
+		MaxTokens: opts.MaxTokens,
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	if opts.ThinkingBudget > 0 {
+		// 扩展思考要求 max_tokens 大于预算，且不支持自定义 temperature
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: opts.ThinkingBudget}
+		if req.MaxTokens <= opts.ThinkingBudget {
+			req.MaxTokens = opts.ThinkingBudget + defaultAnthropicMaxTokens
+		}
+	} else if opts.Temperature > 0 {
+		temperature := opts.Temperature
+		req.Temperature = &temperature
+	}
+
+	// 系统提示词
+	breakpoint := -1
+	for _, msg := range messages {
+		if msg.Role != "system" || msg.Content == "" {
+			continue
+		}
+		req.System = append(req.System, anthropicBlock{Type: "text", Text: msg.Content})
+		if msg.CacheBreakpoint {
+			breakpoint = len(req.System) - 1
+		}
+	}
+	if opts.Cache.System && len(req.System) > 0 {
+		if breakpoint < 0 {
+			breakpoint = len(req.System) - 1
+		}
+		req.System[breakpoint].CacheControl = ephemeralCache()
+	}
+
+	// 对话消息，相邻的同角色消息合并（工具结果必须位于同一条 user 消息中）
+	for _, msg := range messages {
+		var role string
+		var blocks []anthropicBlock
 		switch msg.Role {
-		case "user":
-			role = llms.ChatMessageTypeHuman
-		case "assistant":
-			role = llms.ChatMessageTypeAI
 		case "system":
-			role = llms.ChatMessageTypeSystem
+			continue
+		case "assistant":
+			role = "assistant"
+			blocks = anthropicAssistantBlocks(msg)
 		case "tool":
-			role = llms.ChatMessageTypeTool
+			role = "user"
+			blocks = []anthropicBlock{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			}}
 		default:
-			role = llms.ChatMessageTypeHuman
-		}
-
-		// Handle tool result messages
-		if msg.Role == "tool" {
-			langchainMessages[i] = llms.MessageContent{
-				Role: role,
-				Parts: []llms.ContentPart{
-					llms.ToolCallResponse{
-						ToolCallID: msg.ToolCallID,
-						Name:       msg.ToolName,
-						Content:    msg.Content,
-					},
-				},
-			}
-		} else if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
-			// Handle assistant messages with tool calls
-			parts := []llms.ContentPart{
-				llms.TextPart(msg.Content),
+			role = "user"
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
 			}
-			for _, tc := range msg.ToolCalls {
-				args, _ := json.Marshal(tc.Params)
-				parts = append(parts, llms.ToolCall{
-					ID:   tc.ID,
-					Type: "function",
-					FunctionCall: &llms.FunctionCall{
-						Name:      tc.Name,
-						Arguments: string(args),
-					},
-				})
-			}
-			langchainMessages[i] = llms.MessageContent{
-				Role:  role,
-				Parts: parts,
+			for _, img := range msg.Images {
+				blocks = append(blocks, anthropicImageBlock(img))
 			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
 		} else {
-			langchainMessages[i] = llms.TextParts(role, msg.Content)
+			req.Messages = append(req.Messages, anthropicMessage{Role: role, Content: blocks})
 		}
 	}
 
-	// 调用 LLM
-	var llmOpts []llms.CallOption
-	if opts.Temperature > 0 {
-		llmOpts = append(llmOpts, llms.WithTemperature(float64(opts.Temperature)))
+	// 工具定义
+	for _, tool := range tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
 	}
-	if opts.MaxTokens > 0 {
-		llmOpts = append(llmOpts, llms.WithMaxTokens(int(opts.MaxTokens)))
+	if opts.Cache.Tools && len(req.Tools) > 0 {
+		req.Tools[len(req.Tools)-1].CacheControl = ephemeralCache()
 	}
 
-	// 如果有工具，添加工具选项
-	if len(tools) > 0 {
-		langchainTools := make([]llms.Tool, len(tools))
-		for i, tool := range tools {
-			langchainTools[i] = llms.Tool{
-				Type: "function",
-				Function: &llms.FunctionDefinition{
-					Name:        tool.Name,
-					Description: tool.Description,
-					Parameters:  tool.Parameters,
-				},
+	return req
+}
+
+// anthropicAssistantBlocks 转换助手消息，思考块必须位于最前面
+func anthropicAssistantBlocks(msg Message) []anthropicBlock {
+	var blocks []anthropicBlock
+	for _, t := range msg.Thinking {
+		if t.Redacted != "" {
+			blocks = append(blocks, anthropicBlock{Type: "redacted_thinking", Data: t.Redacted})
+		} else if t.Signature != "" {
+			blocks = append(blocks, anthropicBlock{Type: "thinking", Thinking: t.Thinking, Signature: t.Signature})
+		}
+	}
+	if msg.Content != "" {
+		blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+	}
+	for _, tc := range msg.ToolCalls {
+		input := []byte("{}")
+		if tc.Params != nil {
+			if data, err := json.Marshal(tc.Params); err == nil {
+				input = data
 			}
 		}
-		llmOpts = append(llmOpts, llms.WithTools(langchainTools))
+		blocks = append(blocks, anthropicBlock{
+			Type:  "tool_use",
+			ID:    tc.ID,
+			Name:  tc.Name,
+			Input: input,
+		})
 	}
+	return blocks
+}
 
-	completion, err := p.llm.GenerateContent(ctx, langchainMessages, llmOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+// anthropicImageBlock 转换图片（data URL、http URL 或原始 base64）
+func anthropicImageBlock(img string) anthropicBlock {
+	if strings.HasPrefix(img, "http://") || strings.HasPrefix(img, "https://") {
+		return anthropicBlock{Type: "image", Source: &anthropicImageSource{Type: "url", URL: img}}
 	}
 
-	// 解析工具调用
-	var toolCalls []ToolCall
-	if len(completion.Choices) > 0 {
-		if len(completion.Choices[0].ToolCalls) > 0 {
-			logger.Debug("Found tool calls from LLM",
-				zap.Int("count", len(completion.Choices[0].ToolCalls)))
+	mediaType := ""
+	data := img
+	if strings.HasPrefix(img, "data:") {
+		if header, payload, ok := strings.Cut(strings.TrimPrefix(img, "data:"), ","); ok {
+			mediaType = strings.TrimSuffix(header, ";base64")
+			data = payload
+		}
+	}
+	if mediaType == "" {
+		mediaType = "image/png"
+		if raw, err := base64.StdEncoding.DecodeString(data); err == nil {
+			if detected := http.DetectContentType(raw); strings.HasPrefix(detected, "image/") {
+				mediaType = detected
+			}
 		}
-		for _, tc := range completion.Choices[0].ToolCalls {
+	}
+	return anthropicBlock{
+		Type:   "image",
+		Source: &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data},
+	}
+}
+
+// parseAnthropicResponse 解析 Messages API 响应
+func parseAnthropicResponse(result *anthropicResponse) *Response {
+	response := &Response{
+		FinishReason: anthropicFinishReason(result.StopReason),
+		Usage: Usage{
+			PromptTokens:             result.Usage.InputTokens,
+			CompletionTokens:         result.Usage.OutputTokens,
+			CacheCreationInputTokens: result.Usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     result.Usage.CacheReadInputTokens,
+		},
+	}
+	response.Usage.TotalTokens = result.Usage.InputTokens + result.Usage.OutputTokens +
+		result.Usage.CacheCreationInputTokens + result.Usage.CacheReadInputTokens
+
+	var texts []string
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "thinking":
+			response.Thinking = append(response.Thinking, ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			response.Thinking = append(response.Thinking, ThinkingBlock{Redacted: block.Data})
+		case "tool_use":
 			var params map[string]interface{}
-			if err := json.Unmarshal([]byte(tc.FunctionCall.Arguments), &params); err != nil {
-				logger.Error("Failed to unmarshal tool arguments",
-					zap.String("tool", tc.FunctionCall.Name),
-					zap.String("id", tc.ID),
-					zap.Error(err))
-				continue
+			if len(block.Input) > 0 {
+				if err := json.Unmarshal(block.Input, &params); err != nil {
+					logger.Error("Failed to unmarshal tool arguments",
+						zap.String("tool", block.Name),
+						zap.String("id", block.ID),
+						zap.Error(err))
+					continue
+				}
 			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:     tc.ID,
-				Name:   tc.FunctionCall.Name,
+			response.ToolCalls = append(response.ToolCalls, ToolCall{
+				ID:     block.ID,
+				Name:   block.Name,
 				Params: params,
 			})
 		}
 	}
+	response.Content = strings.Join(texts, "")
 
-	response := &Response{
-		Content:      completion.Choices[0].Content,
-		ToolCalls:    toolCalls,
-		FinishReason: "stop",
+	if len(response.ToolCalls) > 0 {
+		logger.Debug("Found tool calls from LLM",
+			zap.Int("count", len(response.ToolCalls)))
 	}
 
-	return response, nil
+	return response
+}
+
+// anthropicFinishReason 将 stop_reason 映射为通用结束原因
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "", "end_turn", "stop_sequence":
+		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	default:
+		return reason
+	}
 }
 
 // ChatWithTools 聊天（带工具）
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAnthropicServer 模拟 Messages API，记录收到的请求
+func fakeAnthropicServer(t *testing.T, response string) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != anthropicAPIVersion {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestAnthropicPromptCacheAndThinking(t *testing.T) {
+	server, requests := fakeAnthropicServer(t, `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"content": [
+			{"type": "thinking", "thinking": "Need the weather tool.", "signature": "sig-1"},
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "tu_1", "name": "weather", "input": {"city": "Paris"}},
+			{"type": "tool_use", "id": "tu_2", "name": "weather", "input": {"city": "Rome"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 12, "output_tokens": 30, "cache_creation_input_tokens": 2048, "cache_read_input_tokens": 4096}
+	}`)
+
+	p, err := NewAnthropicProvider("test-key", server.URL+"/v1/", "claude-test", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []Message{
+		{Role: "system", Content: "stable prefix", CacheBreakpoint: true},
+		{Role: "system", Content: "current time"},
+		{Role: "user", Content: "weather?"},
+	}
+	tools := []ToolDefinition{
+		{Name: "read_file", Description: "read"},
+		{Name: "weather", Description: "weather", Parameters: map[string]interface{}{"type": "object"}},
+	}
+	resp, err := p.Chat(context.Background(), messages, tools,
+		WithThinkingBudget(ThinkingBudgetForLevel("low")),
+		WithCacheHints(CacheHints{Tools: true, System: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := (*requests)[0]
+	system := req["system"].([]interface{})
+	if len(system) != 2 {
+		t.Fatalf("expected 2 system blocks, got %v", system)
+	}
+	if _, ok := system[0].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("expected cache_control on the stable system block")
+	}
+	if _, ok := system[1].(map[string]interface{})["cache_control"]; ok {
+		t.Error("dynamic system block must not be cached")
+	}
+
+	reqTools := req["tools"].([]interface{})
+	if _, ok := reqTools[0].(map[string]interface{})["cache_control"]; ok {
+		t.Error("only the last tool should carry cache_control")
+	}
+	if _, ok := reqTools[1].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("expected cache_control on the last tool")
+	}
+
+	thinking := req["thinking"].(map[string]interface{})
+	if thinking["type"] != "enabled" || thinking["budget_tokens"].(float64) != 2048 {
+		t.Errorf("unexpected thinking config %v", thinking)
+	}
+	if req["max_tokens"].(float64) <= 2048 {
+		t.Errorf("max_tokens must exceed the thinking budget, got %v", req["max_tokens"])
+	}
+	if _, ok := req["temperature"]; ok {
+		t.Error("temperature must be omitted when thinking is enabled")
+	}
+
+	if resp.Usage.CacheCreationInputTokens != 2048 || resp.Usage.CacheReadInputTokens != 4096 {
+		t.Errorf("unexpected cache usage %+v", resp.Usage)
+	}
+	if resp.Usage.TotalTokens != 12+30+2048+4096 {
+		t.Errorf("unexpected total tokens %d", resp.Usage.TotalTokens)
+	}
+	if resp.Content != "Checking." || resp.FinishReason != "tool_calls" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if len(resp.ToolCalls) != 2 || resp.ToolCalls[1].Params["city"] != "Rome" {
+		t.Errorf("expected both tool calls, got %+v", resp.ToolCalls)
+	}
+	if resp.ThinkingText() != "Need the weather tool." || resp.Thinking[0].Signature != "sig-1" {
+		t.Errorf("unexpected thinking %+v", resp.Thinking)
+	}
+}
+
+func TestAnthropicToolTurnRoundTrip(t *testing.T) {
+	server, requests := fakeAnthropicServer(t, `{"content": [{"type": "text", "text": "Sunny"}], "stop_reason": "end_turn", "usage": {"input_tokens": 5, "output_tokens": 1}}`)
+
+	p, err := NewAnthropicProvider("test-key", server.URL, "claude-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []Message{
+		{Role: "system", Content: "prompt"},
+		{Role: "user", Content: "weather?"},
+		{
+			Role:      "assistant",
+			Thinking:  []ThinkingBlock{{Thinking: "call tools", Signature: "sig-1"}, {Redacted: "opaque"}},
+			ToolCalls: []ToolCall{{ID: "tu_1", Name: "weather", Params: map[string]interface{}{"city": "Paris"}}, {ID: "tu_2", Name: "weather"}},
+		},
+		{Role: "tool", ToolCallID: "tu_1", ToolName: "weather", Content: "sunny"},
+		{Role: "tool", ToolCallID: "tu_2", ToolName: "weather", Content: "rainy"},
+	}
+	resp, err := p.Chat(context.Background(), messages, nil, WithCacheHints(CacheHints{System: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "Sunny" || resp.FinishReason != "stop" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	req := (*requests)[0]
+	if req["max_tokens"].(float64) != defaultAnthropicMaxTokens {
+		t.Errorf("expected default max_tokens, got %v", req["max_tokens"])
+	}
+	system := req["system"].([]interface{})
+	if _, ok := system[0].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("expected the last system block to be cached when none is marked")
+	}
+
+	msgs := req["messages"].([]interface{})
+	if len(msgs) != 3 {
+		t.Fatalf("expected user, assistant and merged tool results, got %d messages", len(msgs))
+	}
+	assistant := msgs[1].(map[string]interface{})["content"].([]interface{})
+	var types []string
+	for _, block := range assistant {
+		types = append(types, block.(map[string]interface{})["type"].(string))
+	}
+	if strings.Join(types, ",") != "thinking,redacted_thinking,tool_use,tool_use" {
+		t.Errorf("unexpected assistant blocks %v", types)
+	}
+	if input := assistant[3].(map[string]interface{})["input"]; input == nil {
+		t.Error("tool_use input must always be present")
+	}
+	results := msgs[2].(map[string]interface{})
+	if results["role"] != "user" || len(results["content"].([]interface{})) != 2 {
+		t.Errorf("expected tool results merged into one user message, got %v", results)
+	}
+}
+
+func TestAnthropicAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"type": "error", "error": {"type": "rate_limit_error", "message": "slow down"}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewAnthropicProvider("test-key", server.URL, "claude-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestThinkingBudgetForLevel(t *testing.T) {
+	tests := map[string]int{"": 0, "off": 0, "minimal": 1024, "low": 2048, "medium": 8192, "high": 16384, "XHigh": 32768, "bogus": 0}
+	for level, want := range tests {
+		if got := ThinkingBudgetForLevel(level); got != want {
+			t.Errorf("ThinkingBudgetForLevel(%q) = %d, want %d", level, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"strings"
 
 	"github.com/tmc/langchaingo/llms"
 )
@@ -14,6 +15,18 @@ type Message struct {
 	ToolCallID string     `json:"tool_call_id,omitempty"` // For tool role
 	ToolName   string     `json:"tool_name,omitempty"`    // For tool role - the name of the tool that was called
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // For assistant role
+
+	// Thinking 助手消息的扩展思考块，工具调用轮次需原样回传给提供商
+	Thinking []ThinkingBlock `json:"thinking,omitempty"`
+	// CacheBreakpoint 标记系统消息的缓存断点：该消息及之前的系统提示词可被提供商缓存
+	CacheBreakpoint bool `json:"cache_breakpoint,omitempty"`
+}
+
+// ThinkingBlock 扩展思考内容块
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"` // 提供商签名，回传时用于校验
+	Redacted  string `json:"redacted,omitempty"`  // 被加密的思考内容（redacted_thinking）
 }
 
 // ToolCall 工具调用
@@ -30,6 +43,20 @@ type Response struct {
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason string     `json:"finish_reason"`
 	Usage        Usage      `json:"usage"`
+
+	// Thinking 扩展思考块（仅支持扩展思考的提供商返回）
+	Thinking []ThinkingBlock `json:"thinking,omitempty"`
+}
+
+// ThinkingText 返回所有思考块的文本
+func (r *Response) ThinkingText() string {
+	var parts []string
+	for _, block := range r.Thinking {
+		if block.Thinking != "" {
+			parts = append(parts, block.Thinking)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 // Usage 使用情况
@@ -37,6 +64,10 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// 提示词缓存统计（PromptTokens 不包含这两部分）
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 // Provider LLM 提供商接口
@@ -67,6 +98,20 @@ type ChatOptions struct {
 	Temperature float64
 	MaxTokens   int
 	Stream      bool
+
+	// ThinkingBudget 扩展思考的 token 预算，0 表示关闭
+	ThinkingBudget int
+	// Cache 提示词缓存断点提示
+	Cache CacheHints
+}
+
+// CacheHints 提示词缓存断点提示，不支持缓存的提供商会忽略
+type CacheHints struct {
+	// Tools 缓存工具定义
+	Tools bool
+	// System 缓存系统提示词，断点位于最后一条标记了 CacheBreakpoint 的系统消息
+	// （没有标记时位于最后一条系统消息）
+	System bool
 }
 
 // WithModel 设置模型
@@ -97,6 +142,38 @@ func WithStream(stream bool) ChatOption {
 	}
 }
 
+// WithThinkingBudget 设置扩展思考预算
+func WithThinkingBudget(tokens int) ChatOption {
+	return func(o *ChatOptions) {
+		o.ThinkingBudget = tokens
+	}
+}
+
+// WithCacheHints 设置提示词缓存断点
+func WithCacheHints(hints CacheHints) ChatOption {
+	return func(o *ChatOptions) {
+		o.Cache = hints
+	}
+}
+
+// ThinkingBudgetForLevel 将思考级别（off, minimal, low, medium, high, xhigh）转换为 token 预算
+func ThinkingBudgetForLevel(level string) int {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "minimal":
+		return 1024
+	case "low":
+		return 2048
+	case "medium":
+		return 8192
+	case "high":
+		return 16384
+	case "xhigh":
+		return 32768
+	default:
+		return 0
+	}
+}
+
 // ConvertToLangChainMessages 转换为 LangChain 消息格式
 func ConvertToLangChainMessages(messages []Message) []llms.MessageContent {
 	result := make([]llms.MessageContent, len(messages))
@@ -3,6 +3,8 @@ package providers
 import (
 	"sync"
 	"time"
+
+	"github.com/smallnest/goclaw/telemetry"
 )
 
 // CircuitState 断路器状态
@@ -32,6 +34,8 @@ func (s CircuitState) String() string {
 
 // CircuitBreaker 断路器
 type CircuitBreaker struct {
+	// 名称，设置后状态会导出为指标
+	name string
 	// 失败阈值
 	failureThreshold int
 	// 超时时间（打开后多久进入半开状态）
@@ -62,6 +66,15 @@ func NewCircuitBreaker(failureThreshold int, timeout time.Duration) *CircuitBrea
 	}
 }
 
+// SetName 设置断路器名称并导出当前状态
+func (cb *CircuitBreaker) SetName(name string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.name = name
+	cb.reportState()
+}
+
 // IsOpen 检查断路器是否打开
 func (cb *CircuitBreaker) IsOpen() bool {
 	cb.mu.RLock()
@@ -135,6 +148,14 @@ func (cb *CircuitBreaker) setState(state CircuitState, when time.Time) {
 		cb.failures = 0
 		cb.successCount = 0
 	}
+	cb.reportState()
+}
+
+// reportState 导出当前状态（调用方需持有锁）
+func (cb *CircuitBreaker) reportState() {
+	if cb.name != "" {
+		telemetry.SetCircuitBreakerState(cb.name, int(cb.state))
+	}
 }
 
 // GetState 获取当前状态
@@ -171,6 +192,7 @@ func (cb *CircuitBreaker) Reset() {
 	cb.failures = 0
 	cb.successCount = 0
 	cb.lastStateChange = time.Now()
+	cb.reportState()
 }
 
 // AllowRequest 检查是否允许请求
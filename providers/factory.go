@@ -16,6 +16,7 @@ const (
 	ProviderTypeOpenAI     ProviderType = "openai"
 	ProviderTypeAnthropic  ProviderType = "anthropic"
 	ProviderTypeOpenRouter ProviderType = "openrouter"
+	ProviderTypeLocal      ProviderType = "local"
 )
 
 // NewProvider 创建提供商（支持故障转移和配置轮换）
@@ -47,6 +48,8 @@ func NewSimpleProvider(cfg *config.Config) (Provider, error) {
 	case ProviderTypeOpenRouter:
 		timeout := time.Duration(cfg.Providers.OpenRouter.Timeout) * time.Second
 		return NewOpenRouterProviderWithTimeout(cfg.Providers.OpenRouter.APIKey, cfg.Providers.OpenRouter.BaseURL, model, cfg.Agents.Defaults.MaxTokens, timeout)
+	case ProviderTypeLocal:
+		return NewLocalProviderFromConfig(cfg.Providers.Local, model, cfg.Agents.Defaults.MaxTokens)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
@@ -72,7 +75,7 @@ func NewRotationProviderFromConfig(cfg *config.Config) (Provider, error) {
 
 	// 添加所有配置
 	for _, profileCfg := range cfg.Providers.Profiles {
-		prov, err := createProviderByType(profileCfg.Provider, profileCfg.APIKey, profileCfg.BaseURL, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens)
+		prov, err := createProviderByType(profileCfg.Provider, profileCfg.APIKey, profileCfg.BaseURL, profileModel(cfg, profileCfg), cfg.Agents.Defaults.MaxTokens)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create provider for profile %s: %w", profileCfg.Name, err)
 		}
@@ -88,7 +91,7 @@ func NewRotationProviderFromConfig(cfg *config.Config) (Provider, error) {
 	// 如果只有一个配置，返回第一个提供商
 	if len(cfg.Providers.Profiles) == 1 {
 		p := cfg.Providers.Profiles[0]
-		prov, err := createProviderByType(p.Provider, p.APIKey, p.BaseURL, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens)
+		prov, err := createProviderByType(p.Provider, p.APIKey, p.BaseURL, profileModel(cfg, p), cfg.Agents.Defaults.MaxTokens)
 		if err != nil {
 			return nil, err
 		}
@@ -98,6 +101,14 @@ func NewRotationProviderFromConfig(cfg *config.Config) (Provider, error) {
 	return rotation, nil
 }
 
+// profileModel 返回配置使用的模型，未单独配置时使用默认模型
+func profileModel(cfg *config.Config, profile config.ProviderProfileConfig) string {
+	if profile.Model != "" {
+		return profile.Model
+	}
+	return cfg.Agents.Defaults.Model
+}
+
 // createProviderByType 根据类型创建提供商
 func createProviderByType(providerType, apiKey, baseURL, model string, maxTokens int) (Provider, error) {
 	switch ProviderType(providerType) {
@@ -107,6 +118,8 @@ func createProviderByType(providerType, apiKey, baseURL, model string, maxTokens
 		return NewAnthropicProvider(apiKey, baseURL, model, maxTokens)
 	case ProviderTypeOpenRouter:
 		return NewOpenRouterProvider(apiKey, baseURL, model, maxTokens)
+	case ProviderTypeLocal:
+		return NewLocalProvider(baseURL, apiKey, model, maxTokens)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
@@ -115,17 +128,35 @@ func createProviderByType(providerType, apiKey, baseURL, model string, maxTokens
 // determineProvider 确定提供商
 func determineProvider(cfg *config.Config) (ProviderType, string, error) {
 	model := cfg.Agents.Defaults.Model
+	local := cfg.Providers.Local
 
-	// 检查模型名称前缀
+	// ollama:/local: 前缀，或与本地服务配置的模型相同时，使用本地模型
+	if strings.HasPrefix(model, "ollama:") || strings.HasPrefix(model, "local:") {
+		return ProviderTypeLocal, LocalModelName(model), nil
+	}
+	if local.Enabled && local.Model != "" && model == local.Model {
+		return ProviderTypeLocal, model, nil
+	}
+
+	// 云端模型名称只路由到对应的云端提供商，缺少凭据时报错而不是回退到本地模型
 	if strings.HasPrefix(model, "openrouter:") {
+		if cfg.Providers.OpenRouter.APIKey == "" {
+			return "", "", missingAPIKeyError(model, "openrouter")
+		}
 		return ProviderTypeOpenRouter, strings.TrimPrefix(model, "openrouter:"), nil
 	}
 
 	if strings.HasPrefix(model, "anthropic:") || strings.HasPrefix(model, "claude-") {
+		if cfg.Providers.Anthropic.APIKey == "" {
+			return "", "", missingAPIKeyError(model, "anthropic")
+		}
 		return ProviderTypeAnthropic, model, nil
 	}
 
 	if strings.HasPrefix(model, "openai:") || strings.HasPrefix(model, "gpt-") {
+		if cfg.Providers.OpenAI.APIKey == "" {
+			return "", "", missingAPIKeyError(model, "openai")
+		}
 		return ProviderTypeOpenAI, model, nil
 	}
 
@@ -142,5 +173,15 @@ func determineProvider(cfg *config.Config) (ProviderType, string, error) {
 		return ProviderTypeOpenAI, model, nil
 	}
 
+	// 没有云端凭据时，未指定或无前缀的模型名称使用本地模型
+	if local.Enabled {
+		return ProviderTypeLocal, LocalModelName(model), nil
+	}
+
 	return "", "", fmt.Errorf("no LLM provider API key configured")
 }
+
+// missingAPIKeyError 返回云端模型缺少 API key 的错误
+func missingAPIKeyError(model, provider string) error {
+	return fmt.Errorf("model %q requires providers.%s.api_key; use the ollama: or local: prefix to run a local model", model, provider)
+}
@@ -20,10 +20,12 @@ type FailoverProvider struct {
 
 // NewFailoverProvider 创建故障转移提供商
 func NewFailoverProvider(primary, fallback Provider, errorClassifier errors.ErrorClassifier) *FailoverProvider {
+	circuitBreaker := NewCircuitBreaker(5, 5*time.Minute)
+	circuitBreaker.SetName("failover")
 	return &FailoverProvider{
 		primary:         primary,
 		fallback:        fallback,
-		circuitBreaker:  NewCircuitBreaker(5, 5*time.Minute),
+		circuitBreaker:  circuitBreaker,
 		errorClassifier: errorClassifier,
 	}
 }
@@ -0,0 +1,656 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultLocalBaseURL 本地模型服务默认地址（Ollama）
+	defaultLocalBaseURL = "http://localhost:11434"
+)
+
+// 本地模型的工具调用格式
+const (
+	// LocalToolFormatAuto 发送原生 tools 参数，同时解析以 JSON 文本形式输出的工具调用；
+	// 服务端不支持 tools 参数时自动切换为 json 格式
+	LocalToolFormatAuto = "auto"
+	// LocalToolFormatNative 仅使用原生结构化工具调用
+	LocalToolFormatNative = "native"
+	// LocalToolFormatJSON 不发送 tools 参数，在系统提示词中描述工具并解析 JSON 文本
+	LocalToolFormatJSON = "json"
+)
+
+// LocalProvider 本地模型提供商（Ollama 及 LM Studio、llama.cpp、vLLM 等 OpenAI 兼容服务）
+// 无需云端凭据，未指定模型时自动发现本地可用的模型
+type LocalProvider struct {
+	client     *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+	maxTokens  int
+	toolFormat string
+
+	mu            sync.Mutex
+	resolvedModel string
+	textTools     bool // 服务端不支持原生 tools 参数
+
+	callSeq atomic.Int64
+}
+
+// NewLocalProvider 创建本地模型提供商
+func NewLocalProvider(baseURL, apiKey, model string, maxTokens int) (*LocalProvider, error) {
+	return NewLocalProviderWithTimeout(baseURL, apiKey, model, maxTokens, 0)
+}
+
+// NewLocalProviderWithTimeout 创建带超时的本地模型提供商
+func NewLocalProviderWithTimeout(baseURL, apiKey, model string, maxTokens int, timeout time.Duration) (*LocalProvider, error) {
+	baseURL = strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	if baseURL == "" {
+		baseURL = defaultLocalBaseURL
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("invalid local model base URL: %s", baseURL)
+	}
+
+	client := &http.Client{}
+	if timeout > 0 {
+		client.Timeout = timeout
+		logger.Info("Local provider configured with timeout",
+			zap.Duration("timeout", timeout))
+	}
+
+	return &LocalProvider{
+		client:     client,
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		model:      LocalModelName(model),
+		maxTokens:  maxTokens,
+		toolFormat: LocalToolFormatAuto,
+	}, nil
+}
+
+// NewLocalProviderFromConfig 从配置创建本地模型提供商，model 为空时使用配置中的模型
+func NewLocalProviderFromConfig(cfg config.LocalProviderConfig, model string, maxTokens int) (*LocalProvider, error) {
+	if LocalModelName(model) == "" {
+		model = cfg.Model
+	}
+	p, err := NewLocalProviderWithTimeout(cfg.BaseURL, cfg.APIKey, model, maxTokens, time.Duration(cfg.Timeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ToolCallFormat != "" {
+		p.toolFormat = cfg.ToolCallFormat
+	}
+	return p, nil
+}
+
+// LocalModelName 去掉 ollama: / local: 前缀；云端模型名（其他提供商前缀、claude-、gpt-）返回空，表示需要自动发现
+func LocalModelName(model string) string {
+	model = strings.TrimSpace(model)
+	for _, prefix := range []string{"ollama:", "local:"} {
+		if strings.HasPrefix(model, prefix) {
+			return strings.TrimPrefix(model, prefix)
+		}
+	}
+	for _, prefix := range []string{"openrouter:", "anthropic:", "openai:", "claude-", "gpt-"} {
+		if strings.HasPrefix(model, prefix) {
+			return ""
+		}
+	}
+	return model
+}
+
+// ListModels 发现本地服务上可用的模型
+// 优先使用 Ollama 的 /api/tags，失败时回退到 OpenAI 兼容的 /v1/models
+func (p *LocalProvider) ListModels(ctx context.Context) ([]string, error) {
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	tagsErr := p.getJSON(ctx, "/api/tags", &tags)
+	if tagsErr == nil && len(tags.Models) > 0 {
+		models := make([]string, 0, len(tags.Models))
+		for _, m := range tags.Models {
+			models = append(models, m.Name)
+		}
+		return models, nil
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := p.getJSON(ctx, "/v1/models", &list); err != nil {
+		if tagsErr != nil {
+			return nil, fmt.Errorf("failed to discover local models at %s: %w", p.baseURL, err)
+		}
+		return nil, err
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// getJSON 发送 GET 请求并解析 JSON 响应
+func (p *LocalProvider) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// setHeaders 设置请求头
+func (p *LocalProvider) setHeaders(req *http.Request) {
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
+
+// resolveModel 返回要使用的模型，未配置时使用发现的第一个模型
+func (p *LocalProvider) resolveModel(ctx context.Context, model string) (string, error) {
+	if model = LocalModelName(model); model != "" {
+		return model, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resolvedModel != "" {
+		return p.resolvedModel, nil
+	}
+
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(models) == 0 {
+		return "", fmt.Errorf("no models available on local model server %s (pull one first, e.g. 'ollama pull llama3.1')", p.baseURL)
+	}
+	p.resolvedModel = models[0]
+	logger.Info("Local provider discovered model",
+		zap.String("model", p.resolvedModel),
+		zap.Int("available", len(models)))
+	return p.resolvedModel, nil
+}
+
+// useTextTools 判断本次调用是否使用 JSON 文本工具调用格式
+func (p *LocalProvider) useTextTools() bool {
+	if p.toolFormat == LocalToolFormatJSON {
+		return true
+	}
+	if p.toolFormat == LocalToolFormatNative {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.textTools
+}
+
+// localChatRequest OpenAI 兼容的 chat/completions 请求
+type localChatRequest struct {
+	Model       string         `json:"model"`
+	Messages    []localMessage `json:"messages"`
+	Tools       []localTool    `json:"tools,omitempty"`
+	Temperature *float64       `json:"temperature,omitempty"`
+	MaxTokens   int            `json:"max_tokens,omitempty"`
+	Stream      bool           `json:"stream"`
+}
+
+// localMessage 消息
+type localMessage struct {
+	Role       string          `json:"role"`
+	Content    interface{}     `json:"content"`
+	ToolCalls  []localToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+}
+
+// localToolCall 结构化工具调用
+type localToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+		// 大多数服务返回 JSON 字符串，部分服务直接返回对象
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// localTool 工具定义
+type localTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description,omitempty"`
+		Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+// localChatResponse chat/completions 响应
+type localChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string          `json:"content"`
+			ToolCalls []localToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Chat 聊天
+func (p *LocalProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	opts := &ChatOptions{
+		Model:       p.model,
+		Temperature: 0.7,
+		MaxTokens:   p.maxTokens,
+		Stream:      false,
+	}
+
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	model, err := p.resolveModel(ctx, opts.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	textTools := len(tools) > 0 && p.useTextTools()
+	result, status, err := p.complete(ctx, buildLocalRequest(model, messages, tools, opts, textTools))
+	if err != nil && !textTools && len(tools) > 0 && p.toolFormat == LocalToolFormatAuto && status == http.StatusBadRequest && strings.Contains(strings.ToLower(err.Error()), "tool") {
+		// 服务端不支持原生工具调用，切换为 JSON 文本格式
+		logger.Info("Local model does not support native tools, falling back to JSON text tool calls",
+			zap.String("model", model),
+			zap.Error(err))
+		p.mu.Lock()
+		p.textTools = true
+		p.mu.Unlock()
+		textTools = true
+		result, _, err = p.complete(ctx, buildLocalRequest(model, messages, tools, opts, true))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("local model returned no choices")
+	}
+
+	choice := result.Choices[0]
+	response := &Response{
+		Content:      choice.Message.Content,
+		FinishReason: choice.FinishReason,
+		Usage: Usage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+	}
+	if response.FinishReason == "" {
+		response.FinishReason = "stop"
+	}
+
+	for _, tc := range choice.Message.ToolCalls {
+		response.ToolCalls = append(response.ToolCalls, ToolCall{
+			ID:     p.callID(tc.ID),
+			Name:   tc.Function.Name,
+			Params: parseLocalArguments(tc.Function.Arguments),
+		})
+	}
+
+	// 部分模型以 JSON 文本形式输出工具调用
+	if len(response.ToolCalls) == 0 && len(tools) > 0 && p.toolFormat != LocalToolFormatNative {
+		content, calls := parseTextToolCalls(response.Content, tools)
+		for _, call := range calls {
+			call.ID = p.callID("")
+			response.ToolCalls = append(response.ToolCalls, call)
+		}
+		if len(calls) > 0 {
+			response.Content = content
+			response.FinishReason = "tool_calls"
+			logger.Debug("Parsed tool calls from local model text",
+				zap.Int("count", len(calls)),
+				zap.Bool("text_tools", textTools))
+		}
+	}
+
+	return response, nil
+}
+
+// complete 调用 chat/completions，返回 HTTP 状态码以便调用方判断是否需要降级
+func (p *LocalProvider) complete(ctx context.Context, body *localChatRequest) (*localChatResponse, int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to reach local model server at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("local model API error (status %d): %s", resp.StatusCode, localErrorMessage(raw))
+	}
+
+	var result localChatResponse
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, resp.StatusCode, nil
+}
+
+// localErrorMessage 提取错误信息（OpenAI 格式 {"error":{"message"}} 或 Ollama 格式 {"error":"..."}）
+func localErrorMessage(raw []byte) string {
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &nested) == nil && nested.Error.Message != "" {
+		return nested.Error.Message
+	}
+	var flat struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &flat) == nil && flat.Error != "" {
+		return flat.Error
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// callID 返回工具调用 ID，服务端未提供时生成一个
+func (p *LocalProvider) callID(id string) string {
+	if id != "" {
+		return id
+	}
+	return fmt.Sprintf("call_local_%d", p.callSeq.Add(1))
+}
+
+// buildLocalRequest 构建 chat/completions 请求
+// textTools 为 true 时不发送 tools 参数，工具说明注入系统提示词，工具调用和结果以文本形式回放
+func buildLocalRequest(model string, messages []Message, tools []ToolDefinition, opts *ChatOptions, textTools bool) *localChatRequest {
+	req := &localChatRequest{
+		Model:     model,
+		MaxTokens: opts.MaxTokens,
+	}
+	if opts.Temperature > 0 {
+		temperature := opts.Temperature
+		req.Temperature = &temperature
+	}
+
+	if textTools && len(tools) > 0 {
+		req.Messages = append(req.Messages, localMessage{Role: "system", Content: textToolsPrompt(tools)})
+	}
+
+	for _, msg := range messages {
+		out := localMessage{Role: msg.Role, Content: msg.Content}
+		switch msg.Role {
+		case "assistant":
+			if len(msg.ToolCalls) == 0 {
+				break
+			}
+			if textTools {
+				out.Content = strings.TrimSpace(msg.Content + "\n\n" + formatTextToolCalls(msg.ToolCalls))
+				break
+			}
+			for _, tc := range msg.ToolCalls {
+				call := localToolCall{ID: tc.ID, Type: "function"}
+				call.Function.Name = tc.Name
+				args, _ := json.Marshal(tc.Params)
+				if tc.Params == nil {
+					args = []byte("{}")
+				}
+				call.Function.Arguments, _ = json.Marshal(string(args))
+				out.ToolCalls = append(out.ToolCalls, call)
+			}
+		case "tool":
+			if textTools {
+				out = localMessage{
+					Role:    "user",
+					Content: fmt.Sprintf("Result of tool %s (call %s):\n%s", msg.ToolName, msg.ToolCallID, msg.Content),
+				}
+				break
+			}
+			out.ToolCallID = msg.ToolCallID
+			out.Name = msg.ToolName
+		case "user":
+			if len(msg.Images) > 0 {
+				parts := []map[string]interface{}{{"type": "text", "text": msg.Content}}
+				for _, img := range msg.Images {
+					url := img
+					if !strings.HasPrefix(img, "http://") && !strings.HasPrefix(img, "https://") && !strings.HasPrefix(img, "data:") {
+						url = "data:image/png;base64," + img
+					}
+					parts = append(parts, map[string]interface{}{
+						"type":      "image_url",
+						"image_url": map[string]string{"url": url},
+					})
+				}
+				out.Content = parts
+			}
+		}
+		req.Messages = append(req.Messages, out)
+	}
+
+	if !textTools {
+		for _, tool := range tools {
+			t := localTool{Type: "function"}
+			t.Function.Name = tool.Name
+			t.Function.Description = tool.Description
+			t.Function.Parameters = tool.Parameters
+			req.Tools = append(req.Tools, t)
+		}
+	}
+
+	return req
+}
+
+// textToolsPrompt 为不支持原生工具调用的模型描述可用工具
+func textToolsPrompt(tools []ToolDefinition) string {
+	var sb strings.Builder
+	sb.WriteString("## Tool Calling\n\n")
+	sb.WriteString("You can call the tools listed below. To call a tool, reply with ONLY a JSON object in a ```json code block:\n\n")
+	sb.WriteString("```json\n{\"name\": \"<tool name>\", \"arguments\": {<arguments>}}\n```\n\n")
+	sb.WriteString("To call several tools at once, reply with a JSON array of such objects. ")
+	sb.WriteString("Tool results are sent back to you in the next message. When no tool is needed, answer normally without JSON.\n\n")
+	sb.WriteString("### Available Tools\n")
+	for _, tool := range tools {
+		params, _ := json.Marshal(tool.Parameters)
+		fmt.Fprintf(&sb, "\n- %s: %s\n  parameters: %s\n", tool.Name, tool.Description, params)
+	}
+	return sb.String()
+}
+
+// formatTextToolCalls 将工具调用格式化为 JSON 文本
+func formatTextToolCalls(calls []ToolCall) string {
+	items := make([]map[string]interface{}, 0, len(calls))
+	for _, tc := range calls {
+		args := tc.Params
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		items = append(items, map[string]interface{}{"name": tc.Name, "arguments": args})
+	}
+	var data []byte
+	if len(items) == 1 {
+		data, _ = json.Marshal(items[0])
+	} else {
+		data, _ = json.Marshal(items)
+	}
+	return "```json\n" + string(data) + "\n```"
+}
+
+// parseLocalArguments 解析工具参数（JSON 字符串或对象）
+func parseLocalArguments(raw json.RawMessage) map[string]interface{} {
+	var params map[string]interface{}
+	if len(raw) == 0 {
+		return params
+	}
+
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		raw = json.RawMessage(encoded)
+	}
+	if strings.TrimSpace(string(raw)) == "" {
+		return params
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		logger.Error("Failed to unmarshal tool arguments",
+			zap.String("raw_args", string(raw)),
+			zap.Error(err))
+		params = map[string]interface{}{
+			"__error__":         fmt.Sprintf("Failed to parse arguments: %v", err),
+			"__raw_arguments__": string(raw),
+		}
+	}
+	return params
+}
+
+var (
+	// toolCallTagPattern Hermes / Qwen 风格的 <tool_call>...</tool_call>
+	toolCallTagPattern = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*</tool_call>`)
+	// codeFencePattern ```json ... ``` 代码块
+	codeFencePattern = regexp.MustCompile("(?s)```(?:json|tool_call)?\\s*\\n?(.*?)```")
+)
+
+// parseTextToolCalls 从模型文本中解析工具调用
+// 支持 <tool_call> 标签、```json 代码块和整段 JSON，只接受名称与已声明工具匹配的调用，
+// 返回去掉工具调用后的剩余文本
+func parseTextToolCalls(content string, tools []ToolDefinition) (string, []ToolCall) {
+	known := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		known[tool.Name] = true
+	}
+
+	var calls []ToolCall
+	remaining := content
+	for _, pattern := range []*regexp.Regexp{toolCallTagPattern, codeFencePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(remaining, -1) {
+			if found := decodeTextToolCalls(match[1], known); len(found) > 0 {
+				calls = append(calls, found...)
+				remaining = strings.Replace(remaining, match[0], "", 1)
+			}
+		}
+	}
+
+	if len(calls) == 0 {
+		trimmed := strings.TrimSpace(content)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if found := decodeTextToolCalls(trimmed, known); len(found) > 0 {
+				return "", found
+			}
+		}
+		return content, nil
+	}
+
+	return strings.TrimSpace(remaining), calls
+}
+
+// decodeTextToolCalls 解析一段 JSON 中的工具调用
+func decodeTextToolCalls(text string, known map[string]bool) []ToolCall {
+	var value interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &value); err != nil {
+		return nil
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		if nested, ok := v["tool_calls"].([]interface{}); ok {
+			items = nested
+		} else {
+			items = []interface{}{v}
+		}
+	}
+
+	var calls []ToolCall
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if fn, ok := obj["function"].(map[string]interface{}); ok {
+			obj = fn
+		}
+		name, _ := obj["name"].(string)
+		if !known[name] {
+			return nil
+		}
+
+		var params map[string]interface{}
+		for _, key := range []string{"arguments", "parameters", "args", "input"} {
+			switch args := obj[key].(type) {
+			case map[string]interface{}:
+				params = args
+			case string:
+				_ = json.Unmarshal([]byte(args), &params)
+			}
+			if params != nil {
+				break
+			}
+		}
+		if params == nil {
+			params = map[string]interface{}{}
+		}
+		calls = append(calls, ToolCall{Name: name, Params: params})
+	}
+	return calls
+}
+
+// ChatWithTools 聊天（带工具）
+func (p *LocalProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+// Close 关闭连接
+func (p *LocalProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,277 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// fakeLocalServer 模拟本地模型服务
+type fakeLocalServer struct {
+	tags       bool     // 是否支持 Ollama /api/tags
+	noTools    bool     // 是否拒绝 tools 参数
+	replies    []string // 依次返回的 assistant 消息 JSON
+	requests   []localChatRequest
+	rawBodies  []map[string]interface{}
+	chatCalled int
+}
+
+func (f *fakeLocalServer) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			if !f.tags {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = io.WriteString(w, `{"models": [{"name": "qwen2.5:7b"}, {"name": "llama3.1:8b"}]}`)
+		case "/v1/models":
+			_, _ = io.WriteString(w, `{"data": [{"id": "local-model"}]}`)
+		case "/v1/chat/completions":
+			body, _ := io.ReadAll(r.Body)
+			var req localChatRequest
+			var raw map[string]interface{}
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("invalid request: %v", err)
+			}
+			_ = json.Unmarshal(body, &raw)
+			f.requests = append(f.requests, req)
+			f.rawBodies = append(f.rawBodies, raw)
+			if f.noTools && len(req.Tools) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"error": "registry.ollama.ai/library/gemma:2b does not support tools"}`)
+				return
+			}
+			reply := f.replies[f.chatCalled]
+			f.chatCalled++
+			_, _ = io.WriteString(w, `{"choices": [{"message": `+reply+`, "finish_reason": "stop"}], "usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+var weatherTools = []ToolDefinition{{
+	Name:        "weather",
+	Description: "Get the weather",
+	Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}}},
+}}
+
+func TestLocalProviderListModels(t *testing.T) {
+	fake := &fakeLocalServer{tags: true}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	p, err := NewLocalProvider(server.URL, "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := p.ListModels(context.Background())
+	if err != nil || len(models) != 2 || models[0] != "qwen2.5:7b" {
+		t.Fatalf("unexpected ollama models %v, %v", models, err)
+	}
+
+	// OpenAI-compatible servers without /api/tags
+	fake.tags = false
+	p, _ = NewLocalProvider(server.URL+"/v1", "", "", 0)
+	models, err = p.ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0] != "local-model" {
+		t.Fatalf("unexpected /v1/models result %v, %v", models, err)
+	}
+}
+
+func TestLocalProviderNativeToolCalls(t *testing.T) {
+	fake := &fakeLocalServer{tags: true, replies: []string{
+		`{"role": "assistant", "content": "", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}, {"type": "function", "function": {"name": "weather", "arguments": {"city": "Rome"}}}]}`,
+	}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	p, _ := NewLocalProvider(server.URL, "", "", 0)
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "weather?"}}, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.requests[0].Model != "qwen2.5:7b" {
+		t.Errorf("expected the first discovered model, got %q", fake.requests[0].Model)
+	}
+	if len(fake.requests[0].Tools) != 1 {
+		t.Errorf("expected native tools to be sent")
+	}
+	if len(resp.ToolCalls) != 2 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[1].Params["city"] != "Rome" {
+		t.Fatalf("unexpected tool calls %+v", resp.ToolCalls)
+	}
+	if resp.ToolCalls[1].ID == "" {
+		t.Error("expected a generated tool call id")
+	}
+	if resp.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestLocalProviderJSONTextToolCalls(t *testing.T) {
+	fake := &fakeLocalServer{replies: []string{
+		`{"role": "assistant", "content": "Let me check.\n<tool_call>\n{\"name\": \"weather\", \"arguments\": {\"city\": \"Oslo\"}}\n</tool_call>"}`,
+	}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	p, _ := NewLocalProvider(server.URL, "", "ollama:qwen2.5:7b", 0)
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "weather?"}}, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.requests[0].Model != "qwen2.5:7b" {
+		t.Errorf("expected prefix to be stripped, got %q", fake.requests[0].Model)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Params["city"] != "Oslo" || resp.Content != "Let me check." {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("unexpected finish reason %q", resp.FinishReason)
+	}
+}
+
+func TestLocalProviderFallsBackToTextTools(t *testing.T) {
+	fake := &fakeLocalServer{noTools: true, replies: []string{
+		"{\"role\": \"assistant\", \"content\": \"```json\\n{\\\"name\\\": \\\"weather\\\", \\\"arguments\\\": {\\\"city\\\": \\\"Lima\\\"}}\\n```\"}",
+		`{"role": "assistant", "content": "It is sunny."}`,
+	}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	p, _ := NewLocalProvider(server.URL, "", "gemma:2b", 0)
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "weather?"}}, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Params["city"] != "Lima" || resp.Content != "" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	// The retried request describes the tools in the prompt instead of sending them
+	retry := fake.requests[1]
+	if len(retry.Tools) != 0 || !strings.Contains(retry.Messages[0].Content.(string), "weather") {
+		t.Errorf("expected tools in the system prompt, got %+v", retry)
+	}
+
+	// Later calls replay tool calls and results as text
+	history := []Message{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: resp.ToolCalls},
+		{Role: "tool", ToolCallID: resp.ToolCalls[0].ID, ToolName: "weather", Content: "sunny"},
+	}
+	resp, err = p.Chat(context.Background(), history, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "It is sunny." || len(resp.ToolCalls) != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	last := fake.requests[len(fake.requests)-1]
+	if len(last.Tools) != 0 {
+		t.Error("expected the provider to remember that native tools are unsupported")
+	}
+	msgs := fake.rawBodies[len(fake.rawBodies)-1]["messages"].([]interface{})
+	toolResult := msgs[len(msgs)-1].(map[string]interface{})
+	if toolResult["role"] != "user" || !strings.Contains(toolResult["content"].(string), "Result of tool weather") {
+		t.Errorf("unexpected tool result replay %v", toolResult)
+	}
+}
+
+func TestParseTextToolCalls(t *testing.T) {
+	tests := []struct {
+		content string
+		calls   int
+		rest    string
+	}{
+		{`{"name": "weather", "arguments": {"city": "Paris"}}`, 1, ""},
+		{`[{"name": "weather", "parameters": {"city": "A"}}, {"function": {"name": "weather", "arguments": "{\"city\":\"B\"}"}}]`, 2, ""},
+		{`{"tool_calls": [{"name": "weather", "arguments": {}}]}`, 1, ""},
+		{`{"name": "unknown_tool", "arguments": {}}`, 0, `{"name": "unknown_tool", "arguments": {}}`},
+		{"Here is JSON:\n```json\n{\"a\": 1}\n```", 0, "Here is JSON:\n```json\n{\"a\": 1}\n```"},
+		{"plain answer", 0, "plain answer"},
+	}
+	for _, tt := range tests {
+		rest, calls := parseTextToolCalls(tt.content, weatherTools)
+		if len(calls) != tt.calls || rest != tt.rest {
+			t.Errorf("parseTextToolCalls(%q) = %q, %d calls; want %q, %d", tt.content, rest, len(calls), tt.rest, tt.calls)
+		}
+	}
+}
+
+func TestDetermineLocalProvider(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Model = "ollama:llama3.1"
+	if typ, model, err := determineProvider(cfg); err != nil || typ != ProviderTypeLocal || model != "llama3.1" {
+		t.Errorf("unexpected provider %s %s %v", typ, model, err)
+	}
+
+	// No cloud credentials: an unprefixed or empty model name uses the local provider
+	cfg.Providers.Local.Enabled = true
+	for _, name := range []string{"", "qwen2.5:7b"} {
+		cfg.Agents.Defaults.Model = name
+		if typ, model, err := determineProvider(cfg); err != nil || typ != ProviderTypeLocal || model != name {
+			t.Errorf("%q: unexpected provider %s %s %v", name, typ, model, err)
+		}
+	}
+
+	// Cloud model names never fall through to the local provider
+	for _, name := range []string{"openrouter:anthropic/claude-opus-4-5", "claude-3-5-sonnet-20241022", "gpt-4"} {
+		cfg.Agents.Defaults.Model = name
+		if typ, _, err := determineProvider(cfg); err == nil || !strings.Contains(err.Error(), "api_key") {
+			t.Errorf("%q: expected a missing API key error, got %s %v", name, typ, err)
+		}
+	}
+
+	// The configured local model stays local even with cloud credentials
+	cfg.Providers.Anthropic.APIKey = "sk-ant-test"
+	cfg.Providers.Local.Model = "qwen2.5:7b"
+	cfg.Agents.Defaults.Model = "qwen2.5:7b"
+	if typ, _, _ := determineProvider(cfg); typ != ProviderTypeLocal {
+		t.Errorf("expected the configured local model to use the local provider, got %s", typ)
+	}
+
+	cfg.Agents.Defaults.Model = "openrouter:anthropic/claude-opus-4-5"
+	cfg.Providers.OpenRouter.APIKey = "sk-or-test"
+	if typ, _, _ := determineProvider(cfg); typ != ProviderTypeOpenRouter {
+		t.Errorf("expected openrouter when its key is configured, got %s", typ)
+	}
+
+	prov, err := createProviderByType("local", "", "http://127.0.0.1:11434", "local:phi3", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prov.(*LocalProvider).model != "phi3" {
+		t.Errorf("unexpected model %q", prov.(*LocalProvider).model)
+	}
+}
+
+func TestLocalProviderInRotation(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Model = "gpt-4"
+	cfg.Providers.Failover.Enabled = true
+	cfg.Providers.Profiles = []config.ProviderProfileConfig{
+		{Name: "laptop", Provider: "local", BaseURL: "http://127.0.0.1:11434", Model: "ollama:qwen2.5:7b"},
+		{Name: "workstation", Provider: "local", BaseURL: "http://10.0.0.2:11434"},
+	}
+
+	prov, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotation, ok := prov.(*RotationProvider)
+	if !ok {
+		t.Fatalf("expected rotation provider, got %T", prov)
+	}
+	if len(rotation.profiles) != 2 {
+		t.Errorf("expected 2 profiles, got %d", len(rotation.profiles))
+	}
+}
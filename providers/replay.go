@@ -0,0 +1,315 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FixtureVersion 录制文件格式版本
+const FixtureVersion = 1
+
+// ErrNoRecording 录制文件中没有与请求匹配的响应
+var ErrNoRecording = errors.New("no recorded response for request")
+
+// Fixture 录制的提供商交互，按请求哈希回放
+type Fixture struct {
+	Version   int               `json:"version"`
+	Exchanges []FixtureExchange `json:"exchanges"`
+}
+
+// FixtureExchange 一次请求和对应的响应
+type FixtureExchange struct {
+	Hash     string    `json:"hash"`
+	Request  string    `json:"request,omitempty"` // 最后一条消息，仅供阅读
+	Response *Response `json:"response"`
+}
+
+// LoadFixture 读取录制文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	if fixture.Version > FixtureVersion {
+		return nil, fmt.Errorf("fixture %s has unsupported version %d", path, fixture.Version)
+	}
+	return &fixture, nil
+}
+
+// Save 写入录制文件
+func (f *Fixture) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f.Version = FixtureVersion
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// volatilePatterns 请求中随运行环境变化的部分，计算哈希前替换为占位符
+var volatilePatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}( [A-Za-z0-9+\-]+)?`), "<time>"},
+	{regexp.MustCompile(`(?m)^Runtime: .*$`), "Runtime: <runtime>"},
+}
+
+// NormalizeRequestText 替换时间戳、主机名等易变内容，并应用额外的字面量替换（如临时工作区路径）
+func NormalizeRequestText(text string, replacements map[string]string) string {
+	text = NormalizeLiterals(text, replacements)
+	for _, p := range volatilePatterns {
+		text = p.re.ReplaceAllString(text, p.repl)
+	}
+	return text
+}
+
+// NormalizeLiterals 只应用字面量替换
+func NormalizeLiterals(text string, replacements map[string]string) string {
+	// Longer strings first so a path is replaced before any of its prefixes
+	froms := make([]string, 0, len(replacements))
+	for from := range replacements {
+		if from != "" {
+			froms = append(froms, from)
+		}
+	}
+	sort.Slice(froms, func(i, j int) bool { return len(froms[i]) > len(froms[j]) })
+	for _, from := range froms {
+		text = strings.ReplaceAll(text, from, replacements[from])
+	}
+	return text
+}
+
+// RequestHash 计算请求的哈希
+// 哈希覆盖消息、工具定义（与顺序无关）、模型和思考预算；文本先经过 NormalizeRequestText 处理。
+func RequestHash(messages []Message, tools []ToolDefinition, opts ChatOptions, replacements map[string]string) string {
+	normalize := func(s string) string { return NormalizeRequestText(s, replacements) }
+
+	type hashedMessage struct {
+		Role       string     `json:"role"`
+		Content    string     `json:"content"`
+		ToolCallID string     `json:"tool_call_id,omitempty"`
+		ToolName   string     `json:"tool_name,omitempty"`
+		ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	}
+	payload := struct {
+		Model          string           `json:"model,omitempty"`
+		ThinkingBudget int              `json:"thinking_budget,omitempty"`
+		Tools          []ToolDefinition `json:"tools"`
+		Messages       []hashedMessage  `json:"messages"`
+	}{
+		Model:          opts.Model,
+		ThinkingBudget: opts.ThinkingBudget,
+		Tools:          make([]ToolDefinition, 0, len(tools)),
+		Messages:       make([]hashedMessage, 0, len(messages)),
+	}
+	for _, tool := range tools {
+		tool.Description = normalize(tool.Description)
+		payload.Tools = append(payload.Tools, tool)
+	}
+	// Tool order depends on registry iteration and carries no meaning
+	sort.Slice(payload.Tools, func(i, j int) bool { return payload.Tools[i].Name < payload.Tools[j].Name })
+	for _, msg := range messages {
+		calls := make([]ToolCall, 0, len(msg.ToolCalls))
+		for _, call := range msg.ToolCalls {
+			params, _ := rewriteValue(call.Params, replacements).(map[string]interface{})
+			calls = append(calls, ToolCall{ID: call.ID, Name: call.Name, Params: params})
+		}
+		payload.Messages = append(payload.Messages, hashedMessage{
+			Role:       msg.Role,
+			Content:    normalize(msg.Content),
+			ToolCallID: msg.ToolCallID,
+			ToolName:   msg.ToolName,
+			ToolCalls:  calls,
+		})
+	}
+
+	// encoding/json sorts map keys, so tool parameters hash deterministically
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// requestPreview returns the last message of a request for fixture readers
+func requestPreview(messages []Message, replacements map[string]string) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	last := messages[len(messages)-1]
+	text := NormalizeRequestText(last.Content, replacements)
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return last.Role + ": " + text
+}
+
+// rewriteResponse copies resp with the literal replacements applied to its
+// text and to every string in the tool call parameters
+func rewriteResponse(resp *Response, replacements map[string]string) *Response {
+	out := *resp
+	out.Content = NormalizeLiterals(resp.Content, replacements)
+	if len(resp.ToolCalls) > 0 {
+		out.ToolCalls = make([]ToolCall, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			call.Params, _ = rewriteValue(call.Params, replacements).(map[string]interface{})
+			out.ToolCalls[i] = call
+		}
+	}
+	return &out
+}
+
+func rewriteValue(v interface{}, replacements map[string]string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return NormalizeLiterals(val, replacements)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = rewriteValue(item, replacements)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = rewriteValue(item, replacements)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// invert swaps the keys and values of replacements
+func invert(replacements map[string]string) map[string]string {
+	out := make(map[string]string, len(replacements))
+	for from, to := range replacements {
+		if to != "" {
+			out[to] = from
+		}
+	}
+	return out
+}
+
+// ReplayProvider 从录制文件回放响应的提供商
+//
+// 请求按 RequestHash 匹配；同一哈希录制了多次时按录制顺序依次返回。没有匹配的录制时返回 ErrNoRecording，
+// 通常说明提示词、工具或场景发生了变化，需要重新录制。
+// 录制时替换成占位符的内容（如工作区路径）在回放时换回当前的值。
+type ReplayProvider struct {
+	mu           sync.Mutex
+	responses    map[string][]*Response
+	replacements map[string]string
+}
+
+// NewReplayProvider 创建回放提供商，replacements 与录制时使用的替换保持一致
+func NewReplayProvider(fixture *Fixture, replacements map[string]string) *ReplayProvider {
+	p := &ReplayProvider{
+		responses:    make(map[string][]*Response),
+		replacements: replacements,
+	}
+	for _, exchange := range fixture.Exchanges {
+		p.responses[exchange.Hash] = append(p.responses[exchange.Hash], exchange.Response)
+	}
+	return p
+}
+
+// Chat 回放录制的响应
+func (p *ReplayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	opts := applyChatOptions(options)
+	hash := RequestHash(messages, tools, opts, p.replacements)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.responses[hash]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("%w (hash %s, %s)", ErrNoRecording, hash[:12], requestPreview(messages, p.replacements))
+	}
+	p.responses[hash] = queue[1:]
+	return rewriteResponse(queue[0], invert(p.replacements)), nil
+}
+
+// ChatWithTools 回放录制的响应
+func (p *ReplayProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+// Close 关闭提供商
+func (p *ReplayProvider) Close() error {
+	return nil
+}
+
+// RecordingProvider 包装真实提供商并录制每次交互
+type RecordingProvider struct {
+	inner        Provider
+	replacements map[string]string
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecordingProvider 创建录制提供商
+func NewRecordingProvider(inner Provider, replacements map[string]string) *RecordingProvider {
+	return &RecordingProvider{inner: inner, replacements: replacements}
+}
+
+// Chat 调用真实提供商并记录响应
+func (p *RecordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := applyChatOptions(options)
+	p.mu.Lock()
+	p.fixture.Exchanges = append(p.fixture.Exchanges, FixtureExchange{
+		Hash:     RequestHash(messages, tools, opts, p.replacements),
+		Request:  requestPreview(messages, p.replacements),
+		Response: rewriteResponse(resp, p.replacements),
+	})
+	p.mu.Unlock()
+	return resp, nil
+}
+
+// ChatWithTools 调用真实提供商并记录响应
+func (p *RecordingProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+// Fixture 返回目前录制的交互
+func (p *RecordingProvider) Fixture() *Fixture {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fixture := Fixture{Version: FixtureVersion, Exchanges: append([]FixtureExchange(nil), p.fixture.Exchanges...)}
+	return &fixture
+}
+
+// Close 关闭被包装的提供商
+func (p *RecordingProvider) Close() error {
+	return p.inner.Close()
+}
+
+// applyChatOptions collects the options passed to a chat call
+func applyChatOptions(options []ChatOption) ChatOptions {
+	var opts ChatOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
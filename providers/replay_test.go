@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	stderrors "errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	tools := []ToolDefinition{{Name: "write_file", Description: "Write a file", Parameters: map[string]interface{}{"type": "object"}}}
+	request := func(workspace, now string) []Message {
+		return []Message{
+			{Role: "system", Content: "Current time: " + now + "\nWorkspace: " + workspace},
+			{Role: "user", Content: "write hello"},
+		}
+	}
+
+	inner := &mockProvider{response: &Response{
+		Content:   "writing",
+		ToolCalls: []ToolCall{{ID: "call_1", Name: "write_file", Params: map[string]interface{}{"path": "/tmp/rec/hello.txt"}}},
+	}}
+	recorder := NewRecordingProvider(inner, map[string]string{"/tmp/rec": "$WORKSPACE"})
+	if _, err := recorder.Chat(context.Background(), request("/tmp/rec", "2026-01-02 03:04:05 UTC"), tools, WithModel("m1")); err != nil {
+		t.Fatal(err)
+	}
+	inner.response = &Response{Content: "again"}
+	if _, err := recorder.Chat(context.Background(), request("/tmp/rec", "2026-01-02 03:04:05 UTC"), tools, WithModel("m1")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures", "hello.json")
+	if err := recorder.Fixture().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fixture.Exchanges[0].Response.ToolCalls[0].Params["path"]; got != "$WORKSPACE/hello.txt" {
+		t.Errorf("expected the workspace to be recorded as a placeholder, got %v", got)
+	}
+
+	// Replay in another workspace at another time
+	replay := NewReplayProvider(fixture, map[string]string{"/tmp/play": "$WORKSPACE"})
+	resp, err := replay.Chat(context.Background(), request("/tmp/play", "2027-05-06 07:08:09 CET"), tools, WithModel("m1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "writing" || resp.ToolCalls[0].Params["path"] != "/tmp/play/hello.txt" {
+		t.Errorf("unexpected replayed response %+v", resp)
+	}
+	resp, err = replay.Chat(context.Background(), request("/tmp/play", "2027-05-06 07:08:09 CET"), tools, WithModel("m1"))
+	if err != nil || resp.Content != "again" {
+		t.Errorf("expected the second recording of the same request, got %+v (%v)", resp, err)
+	}
+
+	if _, err := replay.Chat(context.Background(), request("/tmp/play", "2027-05-06 07:08:09 CET"), tools, WithModel("m1")); !stderrors.Is(err, ErrNoRecording) {
+		t.Errorf("expected recordings to be used up, got %v", err)
+	}
+	changed := []ToolDefinition{{Name: "write_file", Description: "Write a file to disk", Parameters: map[string]interface{}{"type": "object"}}}
+	if _, err := NewReplayProvider(fixture, nil).Chat(context.Background(), request("/tmp/rec", "2026-01-02 03:04:05 UTC"), changed, WithModel("m1")); !stderrors.Is(err, ErrNoRecording) {
+		t.Errorf("expected a changed tool description to miss, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 分支会话的元数据键
+// 分支保存为独立的会话，通过这些元数据记录它从哪个会话的哪条消息分出
+const (
+	MetaBranchParent = "branch_parent" // 父会话的键
+	MetaBranchPoint  = "branch_point"  // 从父会话继承的消息数
+	MetaBranchName   = "branch_name"   // 分支名称
+)
+
+// BranchParent 返回分支的父会话键，不是分支时返回空字符串
+func BranchParent(metadata map[string]interface{}) string {
+	parent, _ := metadata[MetaBranchParent].(string)
+	return parent
+}
+
+// BranchPoint 返回分支从父会话继承的消息数
+func BranchPoint(metadata map[string]interface{}) int {
+	switch v := metadata[MetaBranchPoint].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// Fork 从 src 的前 at 条消息创建一个新分支并保存
+// 新分支的键为根会话键加 ~N，name 为空时使用 branch-N
+func (m *Manager) Fork(src *Session, at int, name string) (*Session, error) {
+	src.mu.RLock()
+	if at < 0 || at > len(src.Messages) {
+		src.mu.RUnlock()
+		return nil, fmt.Errorf("fork point %d is out of range (session has %d messages)", at, len(src.Messages))
+	}
+	messages := make([]Message, at)
+	copy(messages, src.Messages[:at])
+	srcKey := src.Key
+	src.mu.RUnlock()
+
+	metas, err := m.ListMeta()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[string]string, len(metas))
+	for _, meta := range metas {
+		parents[meta.Key] = BranchParent(meta.Metadata)
+	}
+	if _, ok := parents[srcKey]; !ok {
+		parents[srcKey] = BranchParent(src.Metadata)
+	}
+	root := branchRoot(srcKey, parents)
+
+	n := 1
+	for {
+		if _, exists := parents[fmt.Sprintf("%s~%d", root, n)]; !exists {
+			break
+		}
+		n++
+	}
+	if name == "" {
+		name = "branch-" + strconv.Itoa(n)
+	}
+
+	branch := newSession(fmt.Sprintf("%s~%d", root, n))
+	branch.Messages = messages
+	branch.Metadata[MetaBranchParent] = srcKey
+	branch.Metadata[MetaBranchPoint] = at
+	branch.Metadata[MetaBranchName] = name
+	branch.stored = true
+
+	m.mu.Lock()
+	m.sessions[branch.Key] = branch
+	m.mu.Unlock()
+
+	if err := m.Save(branch); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+// BranchTree 构建 key 所在的分支树，根节点为最初的会话
+func (m *Manager) BranchTree(key string) (*SessionTree, error) {
+	metas, err := m.ListMeta()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[string]string, len(metas))
+	children := make(map[string][]SessionMeta)
+	for _, meta := range metas {
+		parent := BranchParent(meta.Metadata)
+		parents[meta.Key] = parent
+		if parent != "" {
+			children[parent] = append(children[parent], meta)
+		}
+	}
+	if _, ok := parents[key]; !ok {
+		return nil, fmt.Errorf("session %s: %w", key, os.ErrNotExist)
+	}
+
+	rootSession, err := m.GetOrCreate(branchRoot(key, parents))
+	if err != nil {
+		return nil, err
+	}
+	tree, err := NewSessionTree(rootSession)
+	if err != nil {
+		return nil, err
+	}
+	tree.SetMaxDepth(len(metas) + 1)
+
+	// 逐层按创建时间加入分支
+	for _, list := range children {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+	}
+	queue := []string{rootSession.Key}
+	for len(queue) > 0 {
+		parentKey := queue[0]
+		queue = queue[1:]
+		for _, meta := range children[parentKey] {
+			branch, err := m.GetOrCreate(meta.Key)
+			if err != nil {
+				return nil, err
+			}
+			name, _ := meta.Metadata[MetaBranchName].(string)
+			if _, err := tree.CreateBranch(parentKey, branch, name, "user"); err != nil {
+				return nil, err
+			}
+			if node, err := tree.GetNode(meta.Key); err == nil {
+				node.BranchInfo.Description = "forked at the start"
+				if point := BranchPoint(meta.Metadata); point > 0 {
+					node.BranchInfo.Description = fmt.Sprintf("forked after message %d", point)
+				}
+				node.CreatedAt = meta.CreatedAt
+			}
+			queue = append(queue, meta.Key)
+		}
+	}
+	return tree, nil
+}
+
+// ResolveBranch 在分支树中按会话键或分支名称查找节点
+func ResolveBranch(tree *SessionTree, ref string) (*SessionNode, error) {
+	if node, err := tree.GetNode(ref); err == nil {
+		return node, nil
+	}
+	matches := tree.FindNodesByBranchName(ref)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no branch %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		keys := make([]string, 0, len(matches))
+		for _, node := range matches {
+			keys = append(keys, node.ID)
+		}
+		return nil, fmt.Errorf("branch name %q is ambiguous: %s", ref, strings.Join(keys, ", "))
+	}
+}
+
+// branchRoot follows the parent links of key up to the original session
+func branchRoot(key string, parents map[string]string) string {
+	seen := map[string]bool{key: true}
+	for {
+		parent := parents[key]
+		if parent == "" || seen[parent] {
+			return key
+		}
+		if _, ok := parents[parent]; !ok {
+			// The parent was deleted, the branch becomes a root
+			return key
+		}
+		seen[parent] = true
+		key = parent
+	}
+}
+
+// SearchOptions 会话全文搜索选项
+type SearchOptions struct {
+	Context int      // 每条匹配前后展示的消息数
+	Limit   int      // 最多返回的匹配数，0 表示不限制
+	Prefix  string   // 只搜索键以此开头的会话
+	Roles   []string // 只搜索这些角色的消息，为空表示全部
+}
+
+// SearchHit 一条匹配的消息及其上下文
+type SearchHit struct {
+	Key       string    `json:"key"`
+	Index     int       `json:"index"` // 匹配消息在会话中的下标
+	UpdatedAt time.Time `json:"updated_at"`
+	Start     int       `json:"start"`    // Messages[0] 在会话中的下标
+	Messages  []Message `json:"messages"` // 匹配消息及其前后的上下文
+}
+
+// Match 返回匹配的消息
+func (h *SearchHit) Match() Message {
+	return h.Messages[h.Index-h.Start]
+}
+
+// Search 在所有会话的消息内容中搜索 query（不区分大小写），最近更新的会话排在前面
+func (m *Manager) Search(query string, opts SearchOptions) ([]SearchHit, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	metas, err := m.ListMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []SearchHit
+	for i := len(metas) - 1; i >= 0; i-- {
+		meta := metas[i]
+		if !strings.HasPrefix(meta.Key, opts.Prefix) {
+			continue
+		}
+		messages, err := m.messages(meta.Key)
+		if err != nil {
+			continue
+		}
+		for idx, msg := range messages {
+			if len(opts.Roles) > 0 && !containsString(opts.Roles, msg.Role) {
+				continue
+			}
+			if !strings.Contains(strings.ToLower(msg.Content), query) {
+				continue
+			}
+			// 分支继承的消息只在最初的会话中报告一次
+			if idx < BranchPoint(meta.Metadata) && BranchParent(meta.Metadata) != "" {
+				continue
+			}
+			start := max(idx-opts.Context, 0)
+			end := min(idx+opts.Context+1, len(messages))
+			hits = append(hits, SearchHit{
+				Key:       meta.Key,
+				Index:     idx,
+				UpdatedAt: meta.UpdatedAt,
+				Start:     start,
+				Messages:  messages[start:end],
+			})
+			if opts.Limit > 0 && len(hits) >= opts.Limit {
+				return hits, nil
+			}
+		}
+	}
+	return hits, nil
+}
+
+// messages returns a copy of the messages of a session without adding it to
+// the cache
+func (m *Manager) messages(key string) ([]Message, error) {
+	m.mu.RLock()
+	cached, ok := m.sessions[key]
+	m.mu.RUnlock()
+	if ok {
+		return cached.GetHistory(0), nil
+	}
+	sess, err := m.store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	return sess.Messages, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
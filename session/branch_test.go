@@ -0,0 +1,110 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForkAndBranchTree(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := mgr.GetOrCreate("tui:1")
+	addMessages(t, mgr, root, 0, 4)
+
+	first, err := mgr.Fork(root, 2, "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Key != "tui:1~1" || len(first.Messages) != 2 {
+		t.Fatalf("unexpected branch %s with %d messages", first.Key, len(first.Messages))
+	}
+	first.AddMessage(Message{Role: "user", Content: "edited", Timestamp: time.Now()})
+	if err := mgr.Save(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fork of a branch is numbered after the original session
+	second, err := mgr.Fork(first, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Key != "tui:1~2" || second.Metadata[MetaBranchName] != "branch-2" {
+		t.Fatalf("unexpected branch %s %v", second.Key, second.Metadata)
+	}
+	if _, err := mgr.Fork(root, 5, ""); err == nil {
+		t.Error("expected a fork point past the end to fail")
+	}
+
+	// Rebuild the tree from storage
+	reloaded, _ := NewManager(dir)
+	tree, err := reloaded.BranchTree(second.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootNode, _ := tree.GetRoot()
+	if rootNode.ID != "tui:1" || tree.CountNodes() != 3 {
+		t.Fatalf("unexpected tree rooted at %s with %d nodes", rootNode.ID, tree.CountNodes())
+	}
+	path, _ := tree.GetPath("tui:1~2")
+	if len(path) != 3 || path[1].ID != "tui:1~1" || path[1].BranchInfo.Name != "retry" {
+		t.Errorf("unexpected path %v", path)
+	}
+
+	node, err := ResolveBranch(tree, "retry")
+	if err != nil || node.ID != "tui:1~1" {
+		t.Fatalf("expected to resolve the branch by name, got %v %v", node, err)
+	}
+
+	diff, err := tree.CompareSessions("tui:1", "tui:1~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Common != 2 || diff.RemovedMessages != 2 || diff.AddedMessages != 1 || diff.AddedContent[0].Content != "edited" {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := mgr.GetOrCreate("tui:a")
+	for _, content := range []string{"hello", "Deploy the service", "done", "bye"} {
+		a.AddMessage(Message{Role: "user", Content: content, Timestamp: time.Now()})
+	}
+	_ = mgr.Save(a)
+	b, _ := mgr.GetOrCreate("telegram:b")
+	b.AddMessage(Message{Role: "assistant", Content: "deploy finished", Timestamp: time.Now()})
+	_ = mgr.Save(b)
+	if _, err := mgr.Fork(a, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := mgr.Search("DEPLOY", SearchOptions{Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("expected inherited branch messages to be reported once, got %+v", hits)
+	}
+	for _, hit := range hits {
+		if hit.Key != "tui:a" {
+			continue
+		}
+		if hit.Index != 1 || hit.Start != 0 || len(hit.Messages) != 3 || hit.Match().Content != "Deploy the service" {
+			t.Errorf("unexpected hit %+v", hit)
+		}
+	}
+
+	hits, _ = mgr.Search("deploy", SearchOptions{Prefix: "telegram:"})
+	if len(hits) != 1 || hits[0].Key != "telegram:b" {
+		t.Errorf("expected the prefix to filter sessions, got %+v", hits)
+	}
+	if _, err := mgr.Search("  ", SearchOptions{}); err == nil {
+		t.Error("expected an empty query to fail")
+	}
+}
@@ -0,0 +1,481 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 会话导出格式
+const (
+	ExportFormat  = "goclaw-session"
+	ExportVersion = 1
+
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// DefaultRedactPatterns 默认脱敏的密钥格式（API key、访问令牌、私钥等）
+var DefaultRedactPatterns = []string{
+	`sk-[A-Za-z0-9_\-]{16,}`,                 // OpenAI / Anthropic 风格的 API key
+	`AKIA[0-9A-Z]{16}`,                       // AWS access key
+	`gh[pousr]_[A-Za-z0-9]{30,}`,             // GitHub token
+	`xox[abposr]-[A-Za-z0-9\-]{10,}`,         // Slack token
+	`(?i)bearer\s+[A-Za-z0-9\-._~+/]{16,}=*`, // Authorization 头
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`, // PEM 私钥
+	`(?i)(api[_-]?key|secret|token|password)["']?\s*[:=]\s*["']?[^\s"',]{8,}`,    // key=value 形式的密钥
+}
+
+// ErrSessionExists 导入时目标会话已存在
+var ErrSessionExists = errors.New("session already exists")
+
+// Export 会话导出文件的 JSON 结构，也是 sessions import 接受的格式
+type Export struct {
+	Format     string                 `json:"format"`
+	Version    int                    `json:"version"`
+	Key        string                 `json:"key"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Redacted   bool                   `json:"redacted,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Messages   []Message              `json:"messages"`
+}
+
+// NewExport 创建会话当前内容的导出快照
+func NewExport(sess *Session) *Export {
+	sess.mu.RLock()
+	defer sess.mu.RUnlock()
+
+	export := &Export{
+		Format:     ExportFormat,
+		Version:    ExportVersion,
+		Key:        sess.Key,
+		CreatedAt:  sess.CreatedAt,
+		UpdatedAt:  sess.UpdatedAt,
+		ExportedAt: time.Now(),
+		Metadata:   make(map[string]interface{}, len(sess.Metadata)),
+		Messages:   make([]Message, len(sess.Messages)),
+	}
+	for k, v := range sess.Metadata {
+		export.Metadata[k] = v
+	}
+	copy(export.Messages, sess.Messages)
+	return export
+}
+
+// Redactor 把匹配密钥格式的内容替换为 [REDACTED]
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor 编译脱敏正则
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// String 脱敏一个字符串
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, "[REDACTED]")
+	}
+	return s
+}
+
+// Redact 脱敏导出中的消息内容、工具参数、媒体地址和元数据
+// 消息会被复制，不会修改原会话
+func (r *Redactor) Redact(e *Export) {
+	messages := make([]Message, len(e.Messages))
+	for i, msg := range e.Messages {
+		msg.Content = r.String(msg.Content)
+		if msg.Metadata != nil {
+			msg.Metadata = r.value(msg.Metadata).(map[string]interface{})
+		}
+		if len(msg.Media) > 0 {
+			media := make([]Media, len(msg.Media))
+			for j, m := range msg.Media {
+				m.URL = r.String(m.URL)
+				media[j] = m
+			}
+			msg.Media = media
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				if tc.Params != nil {
+					tc.Params = r.value(tc.Params).(map[string]interface{})
+				}
+				calls[j] = tc
+			}
+			msg.ToolCalls = calls
+		}
+		messages[i] = msg
+	}
+	e.Messages = messages
+	if e.Metadata != nil {
+		e.Metadata = r.value(e.Metadata).(map[string]interface{})
+	}
+	e.Redacted = true
+}
+
+// value redacts the strings in a decoded JSON value, copying maps and slices
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = r.value(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.value(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Write 按格式（md、html、json）写出导出内容
+func (e *Export) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatMarkdown, "markdown":
+		return e.WriteMarkdown(w)
+	case FormatHTML:
+		return e.WriteHTML(w)
+	case FormatJSON:
+		return e.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown export format %q (valid: md, html, json)", format)
+	}
+}
+
+// WriteJSON 写出可以被 sessions import 加载的 JSON
+func (e *Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// exportTurn is a message prepared for rendering, with the results of its
+// tool calls attached
+type exportTurn struct {
+	Role      string
+	Content   string
+	Timestamp time.Time
+	Media     []Media
+	Calls     []exportCall
+}
+
+type exportCall struct {
+	ID     string
+	Name   string
+	Args   string
+	Result string
+	Found  bool // a tool result message was recorded
+}
+
+// turns pairs tool results with the calls that produced them. Tool messages
+// without a matching call are kept as their own turn.
+func (e *Export) turns() []exportTurn {
+	results := make(map[string]string)
+	for _, msg := range e.Messages {
+		if msg.Role == "tool" && msg.ToolCallID != "" {
+			results[msg.ToolCallID] = msg.Content
+		}
+	}
+	callIDs := make(map[string]bool)
+
+	var turns []exportTurn
+	for _, msg := range e.Messages {
+		if msg.Role == "tool" && callIDs[msg.ToolCallID] {
+			continue
+		}
+		turn := exportTurn{Role: msg.Role, Content: msg.Content, Timestamp: msg.Timestamp, Media: msg.Media}
+		for _, tc := range msg.ToolCalls {
+			callIDs[tc.ID] = true
+			result, found := results[tc.ID]
+			turn.Calls = append(turn.Calls, exportCall{ID: tc.ID, Name: tc.Name, Args: formatParams(tc.Params), Result: result, Found: found})
+		}
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func formatParams(params map[string]interface{}) string {
+	if len(params) == 0 {
+		return "{}"
+	}
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return fmt.Sprint(params)
+	}
+	return string(data)
+}
+
+// fence returns a code fence longer than any backtick run in s
+func fence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func roleTitle(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	case "tool":
+		return "Tool result"
+	}
+	return role
+}
+
+// WriteMarkdown 写出 Markdown，工具调用使用可折叠的 <details>
+func (e *Export) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Session %s\n\n", e.Key))
+	sb.WriteString(fmt.Sprintf("- Created: %s\n- Updated: %s\n- Exported: %s\n- Messages: %d\n",
+		formatExportTime(e.CreatedAt), formatExportTime(e.UpdatedAt), formatExportTime(e.ExportedAt), len(e.Messages)))
+	if e.Redacted {
+		sb.WriteString("- Secrets redacted\n")
+	}
+	sb.WriteString("\n")
+
+	for _, turn := range e.turns() {
+		sb.WriteString(fmt.Sprintf("## %s", roleTitle(turn.Role)))
+		if !turn.Timestamp.IsZero() {
+			sb.WriteString(" · " + formatExportTime(turn.Timestamp))
+		}
+		sb.WriteString("\n\n")
+		if turn.Content != "" {
+			if turn.Role == "tool" {
+				f := fence(turn.Content)
+				sb.WriteString(f + "\n" + turn.Content + "\n" + f + "\n\n")
+			} else {
+				sb.WriteString(turn.Content + "\n\n")
+			}
+		}
+		for _, m := range turn.Media {
+			sb.WriteString(fmt.Sprintf("- Attachment: %s\n", mediaReference(m)))
+		}
+		if len(turn.Media) > 0 {
+			sb.WriteString("\n")
+		}
+		for _, call := range turn.Calls {
+			sb.WriteString(fmt.Sprintf("<details>\n<summary>Tool call: <code>%s</code></summary>\n\n", template.HTMLEscapeString(call.Name)))
+			f := fence(call.Args)
+			sb.WriteString("Arguments:\n\n" + f + "json\n" + call.Args + "\n" + f + "\n\n")
+			if call.Found {
+				f = fence(call.Result)
+				sb.WriteString("Result:\n\n" + f + "\n" + call.Result + "\n" + f + "\n\n")
+			} else {
+				sb.WriteString("No result was recorded.\n\n")
+			}
+			sb.WriteString("</details>\n\n")
+		}
+	}
+	_, err := io.WriteString(w, strings.TrimRight(sb.String(), "\n")+"\n")
+	return err
+}
+
+// mediaReference describes an attachment without its inline content
+func mediaReference(m Media) string {
+	ref := m.Type
+	if m.MimeType != "" {
+		ref += " (" + m.MimeType + ")"
+	}
+	switch {
+	case m.URL != "":
+		ref += " " + m.URL
+	case m.Base64 != "":
+		ref += fmt.Sprintf(" [inline, %d bytes]", len(m.Base64)*3/4)
+	}
+	return ref
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+var exportHTMLTemplate = template.Must(template.New("session").Funcs(template.FuncMap{
+	"title": roleTitle,
+	"time":  formatExportTime,
+	"media": mediaReference,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.Export.Key}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #222; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1.5em; }
+.meta { color: #666; font-size: 0.9em; }
+.turn { border-radius: 8px; padding: 0.8em 1em; margin: 1em 0; }
+.user { background: #eef5ff; }
+.assistant { background: #f6f6f6; }
+.system, .tool { background: #fff8e6; }
+.role { font-weight: 600; }
+.time { color: #888; font-size: 0.85em; margin-left: 0.5em; }
+.content { white-space: pre-wrap; margin-top: 0.4em; }
+details { margin-top: 0.6em; background: #fff; border: 1px solid #ddd; border-radius: 6px; padding: 0.4em 0.8em; }
+summary { cursor: pointer; }
+pre { background: #f0f0f0; padding: 0.6em; overflow-x: auto; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>Session {{.Export.Key}}</h1>
+<p class="meta">Created {{time .Export.CreatedAt}} · Updated {{time .Export.UpdatedAt}} · Exported {{time .Export.ExportedAt}} · {{len .Export.Messages}} messages{{if .Export.Redacted}} · secrets redacted{{end}}</p>
+</header>
+{{range .Turns}}<section class="turn {{.Role}}">
+<div><span class="role">{{title .Role}}</span>{{if not .Timestamp.IsZero}}<span class="time">{{time .Timestamp}}</span>{{end}}</div>
+{{if .Content}}{{if eq .Role "tool"}}<pre>{{.Content}}</pre>{{else}}<div class="content">{{.Content}}</div>{{end}}{{end}}
+{{range .Media}}<div class="meta">Attachment: {{media .}}</div>
+{{end}}{{range .Calls}}<details>
+<summary>Tool call: <code>{{.Name}}</code></summary>
+<p>Arguments:</p>
+<pre>{{.Args}}</pre>
+{{if .Found}}<p>Result:</p>
+<pre>{{.Result}}</pre>{{else}}<p class="meta">No result was recorded.</p>{{end}}
+</details>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML 写出独立的 HTML 页面，工具调用使用可折叠的 <details>
+func (e *Export) WriteHTML(w io.Writer) error {
+	return exportHTMLTemplate.Execute(w, struct {
+		Export *Export
+		Turns  []exportTurn
+	}{e, e.turns()})
+}
+
+// ParseExport 读取并校验 JSON 导出
+func ParseExport(r io.Reader) (*Export, error) {
+	var e Export
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&e); err != nil {
+		return nil, fmt.Errorf("invalid session export: %w", err)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Validate 检查导出的格式、版本和消息结构
+func (e *Export) Validate() error {
+	if e.Format != ExportFormat {
+		return fmt.Errorf("not a session export (format %q, expected %q)", e.Format, ExportFormat)
+	}
+	if e.Version < 1 || e.Version > ExportVersion {
+		return fmt.Errorf("unsupported session export version %d (supported: 1-%d)", e.Version, ExportVersion)
+	}
+	if strings.TrimSpace(e.Key) == "" {
+		return errors.New("session export has no key")
+	}
+
+	var problems []string
+	calls := make(map[string]bool)
+	for i, msg := range e.Messages {
+		switch msg.Role {
+		case "user", "assistant", "system":
+		case "tool":
+			if msg.ToolCallID == "" {
+				problems = append(problems, fmt.Sprintf("message %d: tool result without tool_call_id", i+1))
+			} else if !calls[msg.ToolCallID] {
+				problems = append(problems, fmt.Sprintf("message %d: tool result for unknown call %s", i+1, msg.ToolCallID))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("message %d: unknown role %q", i+1, msg.Role))
+		}
+		for _, tc := range msg.ToolCalls {
+			switch {
+			case msg.Role != "assistant":
+				problems = append(problems, fmt.Sprintf("message %d: only assistant messages can call tools", i+1))
+			case tc.ID == "" || tc.Name == "":
+				problems = append(problems, fmt.Sprintf("message %d: tool call without id or name", i+1))
+			default:
+				calls[tc.ID] = true
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid session export: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Import 把导出加载为 key 会话（为空时使用导出中的键）
+// 会话已存在且 overwrite 为 false 时返回错误
+func (m *Manager) Import(e *Export, key string, overwrite bool) (*Session, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if key == "" {
+		key = e.Key
+	}
+
+	if !overwrite {
+		if _, err := m.store.Load(key); err == nil {
+			return nil, fmt.Errorf("%s: %w", key, ErrSessionExists)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	sess := newSession(key)
+	sess.Messages = append(sess.Messages, e.Messages...)
+	if !e.CreatedAt.IsZero() {
+		sess.CreatedAt = e.CreatedAt
+	}
+	if !e.UpdatedAt.IsZero() {
+		sess.UpdatedAt = e.UpdatedAt
+	}
+	for k, v := range e.Metadata {
+		sess.Metadata[k] = v
+	}
+
+	m.mu.Lock()
+	m.sessions[key] = sess
+	m.mu.Unlock()
+
+	if err := m.Save(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
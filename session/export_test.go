@@ -0,0 +1,128 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportFixture(t *testing.T, mgr *Manager) *Session {
+	t.Helper()
+	sess, _ := mgr.GetOrCreate("telegram:7")
+	sess.Metadata["chat"] = "dm"
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sess.AddMessage(Message{Role: "user", Content: "my key is sk-abcdefghijklmnopqrstuv <b>", Timestamp: now,
+		Media: []Media{{Type: "image", URL: "https://example.com/a.png", MimeType: "image/png"}}})
+	sess.AddMessage(Message{Role: "assistant", Timestamp: now, ToolCalls: []ToolCall{
+		{ID: "c1", Name: "exec", Params: map[string]interface{}{"command": "echo token=supersecretvalue"}},
+	}})
+	sess.AddMessage(Message{Role: "tool", Content: "```done```", ToolCallID: "c1", Timestamp: now})
+	sess.AddMessage(Message{Role: "assistant", Content: "All **done**", Timestamp: now})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestExportFormats(t *testing.T) {
+	mgr, _ := NewManager(t.TempDir())
+	sess := exportFixture(t, mgr)
+
+	export := NewExport(sess)
+	redactor, err := NewRedactor(DefaultRedactPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor.Redact(export)
+	if strings.Contains(sess.Messages[0].Content, "[REDACTED]") || sess.Messages[1].ToolCalls[0].Params["command"] != "echo token=supersecretvalue" {
+		t.Fatal("expected redaction to leave the session untouched")
+	}
+
+	var md bytes.Buffer
+	if err := export.Write(&md, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Session telegram:7",
+		"## User · 2026-01-02 03:04:05 UTC",
+		"my key is [REDACTED]",
+		"- Attachment: image (image/png) https://example.com/a.png",
+		"<summary>Tool call: <code>exec</code></summary>",
+		`"command": "echo [REDACTED]"`,
+		"````\n```done```\n````",
+		"All **done**",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("expected %q in markdown:\n%s", want, md.String())
+		}
+	}
+	if strings.Contains(md.String(), "## Tool result") {
+		t.Error("expected the tool result to be shown with its call")
+	}
+
+	var html bytes.Buffer
+	if err := export.Write(&html, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "[REDACTED] &lt;b&gt;") || !strings.Contains(html.String(), "<details>") {
+		t.Errorf("expected escaped content and collapsible tool calls:\n%s", html.String())
+	}
+
+	if err := export.Write(&html, "pdf"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mgr, _ := NewManager(dir)
+	sess := exportFixture(t, mgr)
+
+	var buf bytes.Buffer
+	if err := NewExport(sess).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	export, err := ParseExport(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Import(export, "", false); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected an existing session to be refused, got %v", err)
+	}
+
+	imported, err := mgr.Import(export, "archive:7", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _ := NewManager(dir)
+	got, err := reloaded.GetOrCreate(imported.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 4 || got.Messages[1].ToolCalls[0].Name != "exec" || got.Messages[2].ToolCallID != "c1" || got.Metadata["chat"] != "dm" {
+		t.Errorf("unexpected imported session %+v", got)
+	}
+	if !got.CreatedAt.Equal(sess.CreatedAt) {
+		t.Errorf("expected the creation time to be kept, got %v", got.CreatedAt)
+	}
+}
+
+func TestParseExportValidation(t *testing.T) {
+	cases := map[string]string{
+		"format":  `{"format":"other","version":1,"key":"a","messages":[]}`,
+		"version": `{"format":"goclaw-session","version":9,"key":"a","messages":[]}`,
+		"key":     `{"format":"goclaw-session","version":1,"key":"","messages":[]}`,
+		"role":    `{"format":"goclaw-session","version":1,"key":"a","messages":[{"role":"robot","content":"x"}]}`,
+		"orphan":  `{"format":"goclaw-session","version":1,"key":"a","messages":[{"role":"tool","tool_call_id":"c9","content":"x"}]}`,
+		"json":    `{"format":`,
+	}
+	for name, input := range cases {
+		if _, err := ParseExport(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
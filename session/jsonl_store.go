@@ -0,0 +1,483 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// JSONLStore 基于 JSONL 文件的会话存储
+//
+// 每个会话一个 <key>.jsonl 文件，第一行是元数据，之后每行一条消息。新消息以追加方式写入并 fsync，
+// 最新的元数据写在 <key>.meta.json 中，通过临时文件和原子重命名更新。
+// 崩溃导致的不完整末行会在加载时被截掉。
+type JSONLStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// jsonlHeader JSONL 文件的元数据行
+type jsonlHeader struct {
+	Type      string                 `json:"_type"`
+	Key       string                 `json:"key,omitempty"`
+	CreatedAt interface{}            `json:"created_at"`
+	UpdatedAt interface{}            `json:"updated_at"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// NewJSONLStore 创建 JSONL 会话存储
+func NewJSONLStore(dir string) (*JSONLStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &JSONLStore{dir: dir}, nil
+}
+
+// Dir 返回存储目录
+func (s *JSONLStore) Dir() string {
+	return s.dir
+}
+
+// Load 加载会话
+func (s *JSONLStore) Load(key string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.sessionPath(key)
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	session := newSession(key)
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		last := readErr == io.EOF
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			err := decodeJSONLLine(session, line)
+			switch {
+			case err != nil && last:
+				// 崩溃时写了一半的末行，截掉后继续使用之前的历史
+				logger.Warn("Truncating incomplete session line",
+					zap.String("session", key),
+					zap.Int64("offset", offset))
+				if err := os.Truncate(filePath, offset); err != nil {
+					return nil, fmt.Errorf("failed to repair session file: %w", err)
+				}
+			case err != nil:
+				return nil, fmt.Errorf("invalid session file %s: %w", filePath, err)
+			case last:
+				// 末行完整但缺少换行符，补上以免下次追加时粘连
+				if err := appendNewline(filePath); err != nil {
+					return nil, fmt.Errorf("failed to repair session file: %w", err)
+				}
+			}
+		}
+
+		offset += int64(len(line))
+		if last {
+			break
+		}
+	}
+
+	// 元数据文件比 JSONL 头部更新
+	if meta, err := s.readMeta(key); err == nil {
+		session.CreatedAt = meta.CreatedAt
+		session.UpdatedAt = meta.UpdatedAt
+		if meta.Metadata != nil {
+			session.Metadata = meta.Metadata
+		}
+	}
+
+	return session, nil
+}
+
+// decodeJSONLLine 解析一行元数据或消息
+func decodeJSONLLine(session *Session, line []byte) error {
+	var probe struct {
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return err
+	}
+
+	if probe.Type == "metadata" {
+		var header struct {
+			Key       string                 `json:"key"`
+			CreatedAt *jsonTime              `json:"created_at"`
+			UpdatedAt *jsonTime              `json:"updated_at"`
+			Metadata  map[string]interface{} `json:"metadata"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return err
+		}
+		// 文件名中的特殊字符已被替换，原始键以头部记录的为准
+		if header.Key != "" {
+			session.Key = header.Key
+		}
+		if header.CreatedAt != nil {
+			session.CreatedAt = header.CreatedAt.Time
+		}
+		if header.UpdatedAt != nil {
+			session.UpdatedAt = header.UpdatedAt.Time
+		}
+		if header.Metadata != nil {
+			session.Metadata = header.Metadata
+		}
+		return nil
+	}
+
+	var msg Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return err
+	}
+	session.Messages = append(session.Messages, msg)
+	return nil
+}
+
+// Append 追加消息，文件不存在时先写入元数据行
+func (s *JSONLStore) Append(meta SessionMeta, messages []Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.sessionPath(meta.Key)
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if info.Size() == 0 {
+		if err := encoder.Encode(headerFor(meta)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	// 一次写入所有行并落盘
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return s.writeMeta(meta)
+}
+
+// Rewrite 写入完整的会话文件并原子替换
+func (s *JSONLStore) Rewrite(meta SessionMeta, messages []Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(headerFor(meta)); err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(s.sessionPath(meta.Key), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return s.writeMeta(meta)
+}
+
+// Delete 删除会话文件和元数据文件
+func (s *JSONLStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.sessionPath(key))
+	if metaErr := os.Remove(s.metaPath(key)); metaErr != nil && !os.IsNotExist(metaErr) && err == nil {
+		err = metaErr
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List 列出所有会话键
+func (s *JSONLStore) List() ([]string, error) {
+	metas, err := s.listMeta(false)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(metas))
+	for _, meta := range metas {
+		keys = append(keys, meta.Key)
+	}
+	return keys, nil
+}
+
+// ListMeta 列出所有会话的元数据
+func (s *JSONLStore) ListMeta() ([]SessionMeta, error) {
+	metas, err := s.listMeta(true)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].UpdatedAt.Before(metas[j].UpdatedAt)
+	})
+	return metas, nil
+}
+
+// listMeta reads the sidecar metadata of every session. Sessions written
+// before sidecars existed are loaded in full when full is set.
+func (s *JSONLStore) listMeta(full bool) ([]SessionMeta, error) {
+	fileKeys, err := s.fileKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	metas := make([]SessionMeta, 0, len(fileKeys))
+	for _, fileKey := range fileKeys {
+		s.mu.Lock()
+		meta, err := s.readMeta(fileKey)
+		s.mu.Unlock()
+		if err == nil {
+			metas = append(metas, meta)
+			continue
+		}
+
+		meta = SessionMeta{Key: fileKey}
+		if key := s.headerKey(fileKey); key != "" {
+			meta.Key = key
+		}
+		if full {
+			if sess, err := s.Load(fileKey); err == nil {
+				meta = sess.meta()
+			}
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+// UnresolvedKeys lists sessions whose original key cannot be recovered: files
+// written before keys were recorded, whose name contains a "_" that may have
+// replaced a ":" or "/" of the key. Their List entry is the file name.
+// A session records its key once it is saved again under that key.
+func (s *JSONLStore) UnresolvedKeys() ([]string, error) {
+	fileKeys, err := s.fileKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var unresolved []string
+	for _, fileKey := range fileKeys {
+		if !strings.Contains(fileKey, "_") {
+			// 文件名未被替换过，就是原始键
+			continue
+		}
+		s.mu.Lock()
+		_, err := s.readMeta(fileKey)
+		s.mu.Unlock()
+		if err == nil || s.headerKey(fileKey) != "" {
+			continue
+		}
+		unresolved = append(unresolved, fileKey)
+	}
+	return unresolved, nil
+}
+
+// fileKeys returns the file name (without extension) of every session file
+func (s *JSONLStore) fileKeys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(entry.Name(), ".jsonl"))
+	}
+	return keys, nil
+}
+
+// headerKey returns the key recorded in the metadata line of a session file,
+// or "" for files written before keys were recorded
+func (s *JSONLStore) headerKey(fileKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(filepath.Join(s.dir, fileKey+".jsonl"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(file).ReadBytes('\n')
+	var header jsonlHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Type != "metadata" {
+		return ""
+	}
+	return header.Key
+}
+
+// Close 关闭存储
+func (s *JSONLStore) Close() error {
+	return nil
+}
+
+// readMeta reads the sidecar metadata file
+func (s *JSONLStore) readMeta(key string) (SessionMeta, error) {
+	var meta SessionMeta
+	data, err := os.ReadFile(s.metaPath(key))
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+	if meta.Key == "" {
+		meta.Key = key
+	}
+	return meta, nil
+}
+
+// writeMeta atomically replaces the sidecar metadata file
+func (s *JSONLStore) writeMeta(meta SessionMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.metaPath(meta.Key), data, 0644)
+}
+
+// sessionPath 获取会话文件路径
+func (s *JSONLStore) sessionPath(key string) string {
+	return filepath.Join(s.dir, safeSessionKey(key)+".jsonl")
+}
+
+// metaPath 获取元数据文件路径
+func (s *JSONLStore) metaPath(key string) string {
+	return filepath.Join(s.dir, safeSessionKey(key)+".meta.json")
+}
+
+// safeSessionKey 将 key 中的特殊字符替换为下划线
+func safeSessionKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// appendNewline terminates a final line that was written without its newline
+func appendNewline(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// headerFor builds the metadata line written at the top of a session file
+func headerFor(meta SessionMeta) jsonlHeader {
+	return jsonlHeader{
+		Type:      "metadata",
+		Key:       meta.Key,
+		CreatedAt: meta.CreatedAt,
+		UpdatedAt: meta.UpdatedAt,
+		Metadata:  meta.Metadata,
+	}
+}
+
+// writeFileAtomic writes data to a temporary file, fsyncs it and renames it
+// over path, then fsyncs the directory so the rename survives a crash
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// jsonTime tolerates timestamps that are missing or not RFC 3339
+type jsonTime struct {
+	time.Time
+}
+
+func (t *jsonTime) UnmarshalJSON(data []byte) error {
+	_ = json.Unmarshal(data, &t.Time)
+	return nil
+}
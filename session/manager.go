@@ -1,10 +1,11 @@
 package session
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"os"
-	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 )
@@ -43,6 +44,70 @@ type Session struct {
 	UpdatedAt time.Time              `json:"updated_at"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	mu        sync.RWMutex
+
+	// 持久化状态，由 saveMu 保护
+	saveMu    sync.Mutex
+	stored    bool   // 存储中的内容与 persisted 条消息一致
+	persisted int    // 已写入存储的消息数
+	firstSum  uint64 // 已写入的第一条消息的指纹
+	lastSum   uint64 // 已写入的最后一条消息的指纹
+}
+
+// newSession 创建空会话
+func newSession(key string) *Session {
+	now := time.Now()
+	return &Session{
+		Key:       key,
+		Messages:  []Message{},
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata:  make(map[string]interface{}),
+	}
+}
+
+// meta 返回会话的元数据，调用方需要持有读锁或独占会话
+func (s *Session) meta() SessionMeta {
+	metadata := make(map[string]interface{}, len(s.Metadata))
+	for k, v := range s.Metadata {
+		metadata[k] = v
+	}
+	return SessionMeta{
+		Key:          s.Key,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+		MessageCount: len(s.Messages),
+		Metadata:     metadata,
+	}
+}
+
+// markPersisted 记录存储中已有 messages，调用方需要持有 saveMu 和读锁
+func (s *Session) markPersisted(messages []Message) {
+	s.stored = true
+	s.persisted = len(messages)
+	s.firstSum, s.lastSum = 0, 0
+	if len(messages) > 0 {
+		s.firstSum = messageSum(messages[0])
+		s.lastSum = messageSum(messages[len(messages)-1])
+	}
+}
+
+// appendOnly 判断自上次保存以来消息是否只有追加，调用方需要持有 saveMu 和读锁
+func (s *Session) appendOnly() bool {
+	if !s.stored || len(s.Messages) < s.persisted {
+		return false
+	}
+	if s.persisted == 0 {
+		return true
+	}
+	return messageSum(s.Messages[0]) == s.firstSum &&
+		messageSum(s.Messages[s.persisted-1]) == s.lastSum
+}
+
+// messageSum 计算消息指纹，用于发现已保存的历史被裁剪或修改
+func messageSum(msg Message) uint64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%d", msg.Role, msg.ToolCallID, msg.Timestamp.UnixNano(), msg.Content, len(msg.ToolCalls))
+	return h.Sum64()
 }
 
 // AddMessage 添加消息
@@ -170,22 +235,37 @@ func (s *Session) Clear() {
 
 // Manager 会话管理器
 type Manager struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
-	baseDir  string
+	sessions    map[string]*Session
+	mu          sync.RWMutex
+	store       Store
+	deleteHooks []func(key string)
 }
 
-// NewManager 创建会话管理器
+// NewManager 创建使用 JSONL 文件存储的会话管理器
 func NewManager(baseDir string) (*Manager, error) {
-	// 确保目录存在
-	if err := os.MkdirAll(baseDir, 0755); err != nil {
+	store, err := NewJSONLStore(baseDir)
+	if err != nil {
 		return nil, err
 	}
+	return NewManagerWithStore(store), nil
+}
 
+// NewManagerWithStore 创建使用指定存储的会话管理器
+func NewManagerWithStore(store Store) *Manager {
 	return &Manager{
 		sessions: make(map[string]*Session),
-		baseDir:  baseDir,
-	}, nil
+		store:    store,
+	}
+}
+
+// Store 返回会话存储
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// Close 关闭会话存储
+func (m *Manager) Close() error {
+	return m.store.Close()
 }
 
 // GetOrCreate 获取或创建会话
@@ -198,20 +278,17 @@ func (m *Manager) GetOrCreate(key string) (*Session, error) {
 		return session, nil
 	}
 
-	// 尝试从磁盘加载
-	session, err := m.load(key)
+	// 尝试从存储加载
+	session, err := m.store.Load(key)
 	if err != nil {
-		if !os.IsNotExist(err) {
+		if !errors.Is(err, os.ErrNotExist) {
 			return nil, err
 		}
-		// 文件不存在，创建新会话
-		session = &Session{
-			Key:       key,
-			Messages:  []Message{},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Metadata:  make(map[string]interface{}),
-		}
+		// 不存在，创建新会话
+		session = newSession(key)
+		session.stored = true
+	} else {
+		session.markPersisted(session.Messages)
 	}
 
 	// 添加到缓存
@@ -220,63 +297,64 @@ func (m *Manager) GetOrCreate(key string) (*Session, error) {
 }
 
 // Save 保存会话
+// 自上次保存以来只追加了消息时只写入新消息，否则（清空、裁剪、压缩后）重写整个会话
 func (m *Manager) Save(session *Session) error {
-	session.mu.RLock()
-	defer session.mu.RUnlock()
-
-	// 确定文件路径
-	filePath := m.sessionPath(session.Key)
+	session.saveMu.Lock()
+	defer session.saveMu.Unlock()
 
-	// 创建临时文件
-	tmpPath := filePath + ".tmp"
-	file, err := os.Create(tmpPath)
-	if err != nil {
-		return err
+	session.mu.RLock()
+	meta := session.meta()
+	appendOnly := session.appendOnly()
+	var messages []Message
+	if appendOnly {
+		messages = append([]Message(nil), session.Messages[session.persisted:]...)
+	} else {
+		messages = append([]Message(nil), session.Messages...)
 	}
-	defer file.Close()
-
-	// 写入元数据行
-	encoder := json.NewEncoder(file)
-	metadata := map[string]interface{}{
-		"_type":      "metadata",
-		"created_at": session.CreatedAt,
-		"updated_at": session.UpdatedAt,
-		"metadata":   session.Metadata,
+	// 先按这次保存的内容记录，写入失败时再标记为需要重写
+	session.markPersisted(session.Messages)
+	session.mu.RUnlock()
+
+	var err error
+	if appendOnly {
+		err = m.store.Append(meta, messages)
+	} else {
+		err = m.store.Rewrite(meta, messages)
 	}
-	if err := encoder.Encode(metadata); err != nil {
+	if err != nil {
+		// 存储状态未知，下次保存时重写
+		session.stored = false
 		return err
 	}
+	return nil
+}
 
-	// 写入消息
-	for _, msg := range session.Messages {
-		if err := encoder.Encode(msg); err != nil {
-			return err
-		}
-	}
-
-	// 原子性重命名
-	if err := os.Rename(tmpPath, filePath); err != nil {
-		return err
-	}
+// OnDelete 注册会话结束回调，会话被删除时调用（用于清理会话级资源）
+func (m *Manager) OnDelete(hook func(key string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil
+	m.deleteHooks = append(m.deleteHooks, hook)
 }
 
 // Delete 删除会话
 func (m *Manager) Delete(key string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	// 从缓存中删除
 	delete(m.sessions, key)
+	hooks := append([]func(string){}, m.deleteHooks...)
 
-	// 删除文件
-	filePath := m.sessionPath(key)
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-		return err
+	// 从存储中删除
+	err := m.store.Delete(key)
+	m.mu.Unlock()
+
+	// 在锁外调用回调，避免回调中访问管理器时死锁
+	for _, hook := range hooks {
+		hook(key)
 	}
 
-	return nil
+	return err
 }
 
 // List 列出所有会话
@@ -284,86 +362,28 @@ func (m *Manager) List() ([]string, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// 读取目录
-	entries, err := os.ReadDir(m.baseDir)
-	if err != nil {
-		return nil, err
-	}
-
-	// 提取会话键
-	keys := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".jsonl" {
-			key := strings.TrimSuffix(entry.Name(), ".jsonl")
-			keys = append(keys, key)
-		}
-	}
-
-	return keys, nil
+	return m.store.List()
 }
 
-// load 从磁盘加载会话
-func (m *Manager) load(key string) (*Session, error) {
-	filePath := m.sessionPath(key)
+// ListMeta 列出所有会话的元数据，按更新时间从旧到新排序
+// 已加载到内存中的会话使用内存中的最新状态
+func (m *Manager) ListMeta() ([]SessionMeta, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	// 打开文件
-	file, err := os.Open(filePath)
+	metas, err := m.store.ListMeta()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	// 创建会话
-	session := &Session{
-		Key:       key,
-		Messages:  []Message{},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Metadata:  make(map[string]interface{}),
-	}
-
-	// 解析文件
-	decoder := json.NewDecoder(file)
-	for decoder.More() {
-		var raw map[string]interface{}
-		if err := decoder.Decode(&raw); err != nil {
-			return nil, err
-		}
-
-		// 检查是否为元数据行
-		if msgType, ok := raw["_type"].(string); ok && msgType == "metadata" {
-			if createdAt, ok := raw["created_at"].(string); ok {
-				session.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-			}
-			if updatedAt, ok := raw["updated_at"].(string); ok {
-				session.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-			}
-			if metadata, ok := raw["metadata"].(map[string]interface{}); ok {
-				session.Metadata = metadata
-			}
-		} else {
-			// 消息行
-			data, _ := json.Marshal(raw)
-			var msg Message
-			if err := json.Unmarshal(data, &msg); err != nil {
-				return nil, err
-			}
-			session.Messages = append(session.Messages, msg)
+	for i := range metas {
+		if session, ok := m.sessions[metas[i].Key]; ok {
+			session.mu.RLock()
+			metas[i] = session.meta()
+			session.mu.RUnlock()
 		}
 	}
-
-	return session, nil
-}
-
-// sessionPath 获取会话文件路径
-func (m *Manager) sessionPath(key string) string {
-	// 将 key 中的特殊字符替换为下划线
-	safeKey := strings.Map(func(r rune) rune {
-		if r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|' {
-			return '_'
-		}
-		return r
-	}, key)
-
-	return filepath.Join(m.baseDir, safeKey+".jsonl")
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].UpdatedAt.Before(metas[j].UpdatedAt)
+	})
+	return metas, nil
 }
@@ -98,7 +98,8 @@ func (p *Pruner) PruneSessions() error {
 
 // pruneLRU removes least recently used sessions
 func (p *Pruner) pruneLRU() error {
-	sessions, err := p.listAllSessions()
+	// Metadata comes back sorted by UpdatedAt, oldest first, without loading messages
+	sessions, err := p.manager.ListMeta()
 	if err != nil {
 		return err
 	}
@@ -108,41 +109,10 @@ func (p *Pruner) pruneLRU() error {
 		return nil
 	}
 
-	// Sort by last access time (assumes UpdatedAt reflects this)
-	type sessionInfo struct {
-		key          string
-		updatedAt    time.Time
-		messageCount int
-	}
-
-	sessionInfos := make([]sessionInfo, len(sessions))
-	for i, key := range sessions {
-		session, err := p.manager.GetOrCreate(key)
-		if err != nil {
-			continue
-		}
-		session.mu.RLock()
-		sessionInfos[i] = sessionInfo{
-			key:          key,
-			updatedAt:    session.UpdatedAt,
-			messageCount: len(session.Messages),
-		}
-		session.mu.RUnlock()
-	}
-
-	// Sort by UpdatedAt (oldest first)
-	for i := 0; i < len(sessionInfos)-1; i++ {
-		for j := i + 1; j < len(sessionInfos); j++ {
-			if sessionInfos[i].updatedAt.After(sessionInfos[j].updatedAt) {
-				sessionInfos[i], sessionInfos[j] = sessionInfos[j], sessionInfos[i]
-			}
-		}
-	}
-
 	// Remove oldest sessions
 	toRemove := len(sessions) - p.config.MaxTotalSessions
 	for i := 0; i < toRemove; i++ {
-		if err := p.manager.Delete(sessionInfos[i].key); err != nil {
+		if err := p.manager.Delete(sessions[i].Key); err != nil {
 			continue
 		}
 		p.stats.SessionsPruned++
@@ -163,7 +133,7 @@ func (p *Pruner) pruneLFU() error {
 
 // pruneTTL removes sessions past their TTL
 func (p *Pruner) pruneTTL() error {
-	sessions, err := p.listAllSessions()
+	sessions, err := p.manager.ListMeta()
 	if err != nil {
 		return err
 	}
@@ -171,19 +141,10 @@ func (p *Pruner) pruneTTL() error {
 	now := time.Now()
 	expiredKeys := []string{}
 
-	for _, key := range sessions {
-		session, err := p.manager.GetOrCreate(key)
-		if err != nil {
-			continue
-		}
-
-		session.mu.RLock()
-		updatedAt := session.UpdatedAt
-		session.mu.RUnlock()
-
+	for _, meta := range sessions {
 		// Check if session is past TTL
-		if now.Sub(updatedAt) > p.config.DefaultMessageTTL {
-			expiredKeys = append(expiredKeys, key)
+		if now.Sub(meta.UpdatedAt) > p.config.DefaultMessageTTL {
+			expiredKeys = append(expiredKeys, meta.Key)
 		}
 	}
 
@@ -205,7 +166,7 @@ func (p *Pruner) pruneTTL() error {
 
 // pruneSize removes largest sessions first
 func (p *Pruner) pruneSize() error {
-	sessions, err := p.listAllSessions()
+	sessions, err := p.manager.ListMeta()
 	if err != nil {
 		return err
 	}
@@ -218,18 +179,9 @@ func (p *Pruner) pruneSize() error {
 	}
 
 	sessionSizes := make([]sessionSize, len(sessions))
-	for i, key := range sessions {
-		session, err := p.manager.GetOrCreate(key)
-		if err != nil {
-			continue
-		}
-
-		session.mu.RLock()
-		size := len(session.Messages)
-		session.mu.RUnlock()
-
-		sessionSizes[i] = sessionSize{key: key, size: size}
-		totalMessages += size
+	for i, meta := range sessions {
+		sessionSizes[i] = sessionSize{key: meta.Key, size: meta.MessageCount}
+		totalMessages += meta.MessageCount
 	}
 
 	// If under limit, nothing to do
@@ -387,11 +339,6 @@ func (p *Pruner) EstimateMessages(sessionKey string) int {
 	return totalChars / 4
 }
 
-// listAllSessions lists all session keys
-func (p *Pruner) listAllSessions() ([]string, error) {
-	return p.manager.List()
-}
-
 // ShouldCompact determines if a session should be compacted
 func (p *Pruner) ShouldCompact(sessionKey string, estimatedTokens int) bool {
 	session, err := p.manager.GetOrCreate(sessionKey)
@@ -0,0 +1,278 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/glebarez/sqlite"
+)
+
+// SQLiteStore 基于 SQLite 的会话存储
+//
+// 会话按键索引，消息按 (会话键, 序号) 存储，并在更新时间和消息时间上建立索引，
+// 便于按时间查找会话和消息而无需加载完整历史。
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// NewSQLiteStore 创建 SQLite 会话存储
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite works best with a single connection
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	store := &SQLiteStore{db: db, path: path}
+	if err := store.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	return store, nil
+}
+
+// initSchema creates the tables and indexes
+func (s *SQLiteStore) initSchema() error {
+	statements := []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = FULL",
+		`CREATE TABLE IF NOT EXISTS sessions (
+			key TEXT PRIMARY KEY,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			message_count INTEGER NOT NULL DEFAULT 0,
+			metadata TEXT
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_sessions_updated_at ON sessions(updated_at)",
+		`CREATE TABLE IF NOT EXISTS session_messages (
+			session_key TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			PRIMARY KEY (session_key, seq)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_session_messages_time ON session_messages(session_key, timestamp)",
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path 返回数据库文件路径
+func (s *SQLiteStore) Path() string {
+	return s.path
+}
+
+// Load 加载会话
+func (s *SQLiteStore) Load(key string) (*Session, error) {
+	row := s.db.QueryRow("SELECT created_at, updated_at, metadata FROM sessions WHERE key = ?", key)
+	var createdAt, updatedAt int64
+	var metadata sql.NullString
+	if err := row.Scan(&createdAt, &updatedAt, &metadata); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session %s: %w", key, os.ErrNotExist)
+		}
+		return nil, err
+	}
+
+	session := newSession(key)
+	session.CreatedAt = time.Unix(0, createdAt)
+	session.UpdatedAt = time.Unix(0, updatedAt)
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &session.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata for session %s: %w", key, err)
+		}
+	}
+
+	messages, err := s.queryMessages("SELECT data FROM session_messages WHERE session_key = ? ORDER BY seq", key)
+	if err != nil {
+		return nil, err
+	}
+	session.Messages = messages
+	return session, nil
+}
+
+// MessagesSince 返回会话中时间不早于 since 的消息
+func (s *SQLiteStore) MessagesSince(key string, since time.Time) ([]Message, error) {
+	return s.queryMessages("SELECT data FROM session_messages WHERE session_key = ? AND timestamp >= ? ORDER BY seq",
+		key, since.UnixNano())
+}
+
+// UpdatedSince 返回更新时间不早于 since 的会话元数据，按更新时间排序
+func (s *SQLiteStore) UpdatedSince(since time.Time) ([]SessionMeta, error) {
+	return s.queryMeta("WHERE updated_at >= ?", since.UnixNano())
+}
+
+// Append 追加消息并更新元数据
+func (s *SQLiteStore) Append(meta SessionMeta, messages []Message) error {
+	return s.write(meta, messages, false)
+}
+
+// Rewrite 替换会话的全部消息
+func (s *SQLiteStore) Rewrite(meta SessionMeta, messages []Message) error {
+	return s.write(meta, messages, true)
+}
+
+// write upserts the session row and stores messages in one transaction
+func (s *SQLiteStore) write(meta SessionMeta, messages []Message, replace bool) error {
+	metadata, err := json.Marshal(meta.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	var next int64
+	if replace {
+		if _, err := tx.Exec("DELETE FROM session_messages WHERE session_key = ?", meta.Key); err != nil {
+			return err
+		}
+	} else if err := tx.QueryRow("SELECT COALESCE(MAX(seq) + 1, 0) FROM session_messages WHERE session_key = ?", meta.Key).Scan(&next); err != nil {
+		return err
+	}
+
+	if len(messages) > 0 {
+		stmt, err := tx.Prepare("INSERT INTO session_messages (session_key, seq, role, timestamp, data) VALUES (?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i, msg := range messages {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(meta.Key, next+int64(i), msg.Role, msg.Timestamp.UnixNano(), string(data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM session_messages WHERE session_key = ?", meta.Key).Scan(&count); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO sessions (key, created_at, updated_at, message_count, metadata) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET created_at = excluded.created_at, updated_at = excluded.updated_at,
+			message_count = excluded.message_count, metadata = excluded.metadata`,
+		meta.Key, meta.CreatedAt.UnixNano(), meta.UpdatedAt.UnixNano(), count, string(metadata)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete 删除会话
+func (s *SQLiteStore) Delete(key string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err := tx.Exec("DELETE FROM session_messages WHERE session_key = ?", key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE key = ?", key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// List 列出所有会话键
+func (s *SQLiteStore) List() ([]string, error) {
+	rows, err := s.db.Query("SELECT key FROM sessions ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// ListMeta 列出所有会话的元数据
+func (s *SQLiteStore) ListMeta() ([]SessionMeta, error) {
+	return s.queryMeta("")
+}
+
+// Close 关闭数据库
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// queryMessages decodes the data column of a message query
+func (s *SQLiteStore) queryMessages(query string, args ...interface{}) ([]Message, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// queryMeta lists session rows matching where, oldest update first
+func (s *SQLiteStore) queryMeta(where string, args ...interface{}) ([]SessionMeta, error) {
+	rows, err := s.db.Query("SELECT key, created_at, updated_at, message_count, metadata FROM sessions "+where+" ORDER BY updated_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metas []SessionMeta
+	for rows.Next() {
+		var meta SessionMeta
+		var createdAt, updatedAt int64
+		var metadata sql.NullString
+		if err := rows.Scan(&meta.Key, &createdAt, &updatedAt, &meta.MessageCount, &metadata); err != nil {
+			return nil, err
+		}
+		meta.CreatedAt = time.Unix(0, createdAt)
+		meta.UpdatedAt = time.Unix(0, updatedAt)
+		if metadata.Valid && metadata.String != "" {
+			_ = json.Unmarshal([]byte(metadata.String), &meta.Metadata)
+		}
+		metas = append(metas, meta)
+	}
+	return metas, rows.Err()
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// 会话存储后端
+const (
+	BackendJSONL  = "jsonl"
+	BackendSQLite = "sqlite"
+)
+
+// SessionMeta 会话元数据，不包含消息内容
+type SessionMeta struct {
+	Key          string                 `json:"key"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	MessageCount int                    `json:"message_count"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Store 会话持久化后端
+//
+// Manager 在消息只是追加时调用 Append，在历史被修改（清空、裁剪、压缩）时调用 Rewrite。
+// Load 在会话不存在时返回满足 errors.Is(err, os.ErrNotExist) 的错误。
+type Store interface {
+	// Load 加载会话
+	Load(key string) (*Session, error)
+	// Append 追加消息并更新元数据
+	Append(meta SessionMeta, messages []Message) error
+	// Rewrite 用 messages 替换会话的全部消息
+	Rewrite(meta SessionMeta, messages []Message) error
+	// Delete 删除会话，不存在时不报错
+	Delete(key string) error
+	// List 列出所有会话键
+	List() ([]string, error)
+	// ListMeta 列出所有会话的元数据，按更新时间从旧到新排序
+	ListMeta() ([]SessionMeta, error)
+	// Close 释放存储资源
+	Close() error
+}
+
+// OpenStore 按后端名称打开 dir 下的会话存储
+func OpenStore(backend, dir string) (Store, error) {
+	switch backend {
+	case "", BackendJSONL:
+		return NewJSONLStore(dir)
+	case BackendSQLite:
+		return NewSQLiteStore(filepath.Join(dir, "sessions.db"))
+	default:
+		return nil, fmt.Errorf("unknown session backend: %s", backend)
+	}
+}
+
+// Open 打开 dir 下指定后端的会话管理器
+func Open(backend, dir string) (*Manager, error) {
+	store, err := OpenStore(backend, dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewManagerWithStore(store), nil
+}
+
+// unresolvedKeyLister 由可能无法还原部分会话原始键的存储实现（旧版 JSONL 文件只以替换过字符的文件名命名）
+type unresolvedKeyLister interface {
+	UnresolvedKeys() ([]string, error)
+}
+
+// Migrate 把 src 中的所有会话复制到 dst，返回迁移的会话数和跳过的会话
+// 无法确定原始键的会话不会以错误的键写入 dst，而是原样留在 src 中并返回给调用方
+func Migrate(src, dst Store) (int, []string, error) {
+	keys, err := src.List()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	unresolved := make(map[string]bool)
+	if lister, ok := src.(unresolvedKeyLister); ok {
+		list, err := lister.UnresolvedKeys()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to check session keys: %w", err)
+		}
+		for _, key := range list {
+			unresolved[key] = true
+		}
+	}
+
+	migrated := 0
+	var skipped []string
+	for _, key := range keys {
+		if unresolved[key] {
+			skipped = append(skipped, key)
+			continue
+		}
+		sess, err := src.Load(key)
+		if err != nil {
+			return migrated, skipped, fmt.Errorf("failed to load session %s: %w", key, err)
+		}
+		if err := dst.Rewrite(sess.meta(), sess.Messages); err != nil {
+			return migrated, skipped, fmt.Errorf("failed to write session %s: %w", key, err)
+		}
+		migrated++
+	}
+	return migrated, skipped, nil
+}
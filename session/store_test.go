@@ -0,0 +1,263 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addMessages(t *testing.T, mgr *Manager, sess *Session, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		sess.AddMessage(Message{Role: "user", Content: fmt.Sprintf("message %d", i), Timestamp: time.Now()})
+	}
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestJSONLStoreAppendsIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := mgr.GetOrCreate("telegram:42")
+	sess.Metadata["chat"] = "group"
+	addMessages(t, mgr, sess, 0, 3)
+
+	path := filepath.Join(dir, "telegram_42.jsonl")
+	before, _ := os.ReadFile(path)
+	if countLines(t, path) != 4 {
+		t.Fatalf("expected metadata line and 3 messages, got %d lines", countLines(t, path))
+	}
+
+	addMessages(t, mgr, sess, 3, 5)
+	after, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(after, before) || countLines(t, path) != 6 {
+		t.Fatalf("expected new messages to be appended, got:\n%s", after)
+	}
+
+	reloaded, _ := NewManager(dir)
+	got, err := reloaded.GetOrCreate("telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 5 || got.Messages[4].Content != "message 4" || got.Metadata["chat"] != "group" {
+		t.Errorf("unexpected reloaded session %+v", got)
+	}
+	if !got.UpdatedAt.Equal(sess.UpdatedAt) {
+		t.Errorf("expected metadata from the sidecar, got updated_at %v want %v", got.UpdatedAt, sess.UpdatedAt)
+	}
+
+	keys, _ := reloaded.List()
+	if len(keys) != 1 || keys[0] != "telegram:42" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestJSONLStoreRewritesAfterPruning(t *testing.T) {
+	dir := t.TempDir()
+	mgr, _ := NewManager(dir)
+	sess, _ := mgr.GetOrCreate("dm")
+	addMessages(t, mgr, sess, 0, 10)
+
+	pruner := NewPruner(mgr, DefaultPruneConfig())
+	if err := pruner.PruneMessages("dm", 4); err != nil {
+		t.Fatal(err)
+	}
+	addMessages(t, mgr, sess, 10, 11)
+
+	reloaded, _ := NewManager(dir)
+	got, _ := reloaded.GetOrCreate("dm")
+	if len(got.Messages) != len(sess.Messages) || got.Messages[0].Content != sess.Messages[0].Content {
+		t.Fatalf("expected pruned history to be rewritten, got %d messages want %d", len(got.Messages), len(sess.Messages))
+	}
+
+	sess.Clear()
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _ = NewManager(dir)
+	got, _ = reloaded.GetOrCreate("dm")
+	if len(got.Messages) != 0 {
+		t.Errorf("expected cleared session, got %d messages", len(got.Messages))
+	}
+}
+
+func TestJSONLStoreRecoversTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	mgr, _ := NewManager(dir)
+	sess, _ := mgr.GetOrCreate("crash")
+	addMessages(t, mgr, sess, 0, 3)
+
+	// Simulate a crash in the middle of an append
+	path := filepath.Join(dir, "crash.jsonl")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"role":"user","content":"half`)
+	f.Close()
+
+	reloaded, _ := NewManager(dir)
+	got, err := reloaded.GetOrCreate("crash")
+	if err != nil {
+		t.Fatalf("expected the session to load after a torn write: %v", err)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("expected 3 intact messages, got %d", len(got.Messages))
+	}
+
+	addMessages(t, reloaded, got, 3, 4)
+	again, _ := NewManager(dir)
+	final, err := again.GetOrCreate("crash")
+	if err != nil || len(final.Messages) != 4 {
+		t.Fatalf("expected 4 messages after repair, got %d (%v)", len(final.Messages), err)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := Open(BackendSQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	old, _ := mgr.GetOrCreate("old")
+	old.AddMessage(Message{Role: "user", Content: "ancient", Timestamp: time.Now().Add(-72 * time.Hour)})
+	old.UpdatedAt = time.Now().Add(-72 * time.Hour)
+	if err := mgr.Save(old); err != nil {
+		t.Fatal(err)
+	}
+
+	sess, _ := mgr.GetOrCreate("slack:C1")
+	addMessages(t, mgr, sess, 0, 3)
+	addMessages(t, mgr, sess, 3, 5)
+
+	store := mgr.Store().(*SQLiteStore)
+	loaded, err := store.Load("slack:C1")
+	if err != nil || len(loaded.Messages) != 5 || loaded.Messages[4].Content != "message 4" {
+		t.Fatalf("unexpected loaded session %+v (%v)", loaded, err)
+	}
+
+	recent, err := store.UpdatedSince(time.Now().Add(-time.Hour))
+	if err != nil || len(recent) != 1 || recent[0].Key != "slack:C1" || recent[0].MessageCount != 5 {
+		t.Errorf("unexpected recent sessions %+v (%v)", recent, err)
+	}
+	messages, err := store.MessagesSince("old", time.Now().Add(-time.Hour))
+	if err != nil || len(messages) != 0 {
+		t.Errorf("expected no recent messages in old session, got %d (%v)", len(messages), err)
+	}
+
+	// The pruner works from metadata without loading every session
+	pruner := NewPruner(mgr, PruneConfig{Strategy: PruneStrategyTTL, DefaultMessageTTL: 24 * time.Hour})
+	if err := pruner.PruneSessions(); err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := mgr.List()
+	if len(keys) != 1 || keys[0] != "slack:C1" {
+		t.Errorf("expected the expired session to be pruned, got %v", keys)
+	}
+
+	if _, err := store.Load("old"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestMigrateJSONLToSQLite(t *testing.T) {
+	dir := t.TempDir()
+	mgr, _ := NewManager(dir)
+	for _, key := range []string{"telegram:1", "feishu:2"} {
+		sess, _ := mgr.GetOrCreate(key)
+		sess.Metadata["source"] = key
+		addMessages(t, mgr, sess, 0, 3)
+	}
+
+	src, _ := OpenStore(BackendJSONL, dir)
+	dst, err := OpenStore(BackendSQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	count, skipped, err := Migrate(src, dst)
+	if err != nil || count != 2 || len(skipped) != 0 {
+		t.Fatalf("migrated %d sessions, skipped %v: %v", count, skipped, err)
+	}
+
+	migrated := NewManagerWithStore(dst)
+	sess, err := migrated.GetOrCreate("telegram:1")
+	if err != nil || len(sess.Messages) != 3 || sess.Metadata["source"] != "telegram:1" {
+		t.Errorf("unexpected migrated session %+v (%v)", sess, err)
+	}
+}
+
+func TestMigrateLegacyJSONLKeys(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"_type":"metadata","created_at":"2025-01-01T00:00:00Z","updated_at":"2025-01-01T00:00:00Z","metadata":{}}` + "\n" +
+		`{"role":"user","content":"hello","timestamp":"2025-01-01T00:00:00Z"}` + "\n"
+	keyed := `{"_type":"metadata","key":"feishu:ou_1","created_at":"2025-01-01T00:00:00Z","updated_at":"2025-01-01T00:00:00Z","metadata":{}}` + "\n" +
+		`{"role":"user","content":"hi","timestamp":"2025-01-01T00:00:00Z"}` + "\n"
+	files := map[string]string{
+		"telegram_123.jsonl": legacy, // ":" was replaced, the key is lost
+		"main.jsonl":         legacy, // nothing was replaced, the name is the key
+		"feishu_ou_1.jsonl":  keyed,  // the header records the key, no sidecar
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, _ := OpenStore(BackendJSONL, dir)
+	dst, err := OpenStore(BackendSQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	count, skipped, err := Migrate(src, dst)
+	if err != nil || count != 2 {
+		t.Fatalf("migrated %d sessions: %v", count, err)
+	}
+	if len(skipped) != 1 || skipped[0] != "telegram_123" {
+		t.Errorf("expected the legacy telegram session to be skipped, got %v", skipped)
+	}
+	for _, key := range []string{"main", "feishu:ou_1"} {
+		if sess, err := dst.Load(key); err != nil || len(sess.Messages) != 1 {
+			t.Errorf("expected %s to be migrated under its key, got %+v (%v)", key, sess, err)
+		}
+	}
+	if _, err := dst.Load("telegram_123"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no session under the file name, got %v", err)
+	}
+
+	// Using the session under its real key records the key, so it migrates next time
+	mgr := NewManagerWithStore(src)
+	sess, err := mgr.GetOrCreate("telegram:123")
+	if err != nil || len(sess.Messages) != 1 {
+		t.Fatalf("expected the legacy session to load by key, got %+v (%v)", sess, err)
+	}
+	addMessages(t, mgr, sess, 0, 1)
+	if _, skipped, err = Migrate(src, dst); err != nil || len(skipped) != 0 {
+		t.Fatalf("expected no skipped sessions after use, got %v (%v)", skipped, err)
+	}
+	if sess, err := dst.Load("telegram:123"); err != nil || len(sess.Messages) != 2 {
+		t.Errorf("expected telegram:123 to be migrated, got %+v (%v)", sess, err)
+	}
+}
@@ -455,7 +455,8 @@ func (t *SessionTree) findCommonAncestor(id1, id2 string) string {
 	return ""
 }
 
-// CompareSessions compares two sessions and returns differences
+// CompareSessions compares two sessions and returns differences. Messages after
+// the longest common prefix are reported as removed from id1 and added in id2.
 func (t *SessionTree) CompareSessions(id1, id2 string) (*SessionDiff, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -470,20 +471,35 @@ func (t *SessionTree) CompareSessions(id1, id2 string) (*SessionDiff, error) {
 		return nil, fmt.Errorf("node %s not found", id2)
 	}
 
-	diff := &SessionDiff{
-		ID1:       id1,
-		ID2:       id2,
-		Messages1: len(node1.Session.Messages),
-		Messages2: len(node2.Session.Messages),
+	node1.Session.mu.RLock()
+	defer node1.Session.mu.RUnlock()
+	if node2.Session != node1.Session {
+		node2.Session.mu.RLock()
+		defer node2.Session.mu.RUnlock()
+	}
+	messages1, messages2 := node1.Session.Messages, node2.Session.Messages
+
+	// Branches share the messages before their fork point
+	common := 0
+	for common < len(messages1) && common < len(messages2) &&
+		messageSum(messages1[common]) == messageSum(messages2[common]) {
+		common++
 	}
 
-	// Compare message counts
-	if len(node1.Session.Messages) < len(node2.Session.Messages) {
-		diff.AddedMessages = len(node2.Session.Messages) - len(node1.Session.Messages)
-		diff.AddedContent = node2.Session.Messages[len(node1.Session.Messages):]
-	} else if len(node1.Session.Messages) > len(node2.Session.Messages) {
-		diff.RemovedMessages = len(node1.Session.Messages) - len(node2.Session.Messages)
-		diff.RemovedContent = node1.Session.Messages[len(node2.Session.Messages):]
+	diff := &SessionDiff{
+		ID1:             id1,
+		ID2:             id2,
+		Messages1:       len(messages1),
+		Messages2:       len(messages2),
+		Common:          common,
+		AddedMessages:   len(messages2) - common,
+		RemovedMessages: len(messages1) - common,
+	}
+	if diff.AddedMessages > 0 {
+		diff.AddedContent = append([]Message(nil), messages2[common:]...)
+	}
+	if diff.RemovedMessages > 0 {
+		diff.RemovedContent = append([]Message(nil), messages1[common:]...)
 	}
 
 	return diff, nil
@@ -495,6 +511,7 @@ type SessionDiff struct {
 	ID2             string    `json:"id2"`
 	Messages1       int       `json:"messages1"`
 	Messages2       int       `json:"messages2"`
+	Common          int       `json:"common"` // Number of leading messages both sessions share
 	AddedMessages   int       `json:"added_messages"`
 	RemovedMessages int       `json:"removed_messages"`
 	AddedContent    []Message `json:"added_content,omitempty"`
@@ -0,0 +1,339 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// FileExporter 将 span 以 OTLP/JSON 格式写入文件，每次导出写一行 TracesData
+// 输出格式与 OpenTelemetry Collector 的 file 导出器一致，可以被 otlpjsonfile 接收器读取
+type FileExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewFileExporter 创建追加写入 path 的导出器
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &FileExporter{w: f, closer: f}, nil
+}
+
+// NewWriterExporter 创建写入 w 的导出器
+func NewWriterExporter(w io.Writer) *FileExporter {
+	return &FileExporter{w: w}
+}
+
+// ExportSpans 实现 sdktrace.SpanExporter
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(encodeSpans(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.w == nil {
+		return fmt.Errorf("exporter is shut down")
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+// Shutdown 实现 sdktrace.SpanExporter
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w = nil
+	if e.closer != nil {
+		err := e.closer.Close()
+		e.closer = nil
+		return err
+	}
+	return nil
+}
+
+// TracesData OTLP/JSON 的顶层结构
+type TracesData struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans 同一资源的 span
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource 资源属性
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeSpans 同一 instrumentation scope 的 span
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Scope instrumentation scope
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Span OTLP/JSON span，ID 为十六进制，时间为字符串形式的纳秒
+type Span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []KeyValue  `json:"attributes,omitempty"`
+	Events            []SpanEvent `json:"events,omitempty"`
+	Status            SpanStatus  `json:"status"`
+}
+
+// Attribute 返回属性的字符串形式，不存在时返回空字符串
+func (s Span) Attribute(key string) string {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value.String()
+		}
+	}
+	return ""
+}
+
+// SpanEvent span 事件（例如记录的错误）
+type SpanEvent struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+}
+
+// OTLP 状态码
+const (
+	StatusCodeUnset = 0
+	StatusCodeOK    = 1
+	StatusCodeError = 2
+)
+
+// SpanStatus span 状态
+type SpanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue 属性键值对
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue 属性值，只设置其中一个字段
+type AnyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue `json:"arrayValue,omitempty"`
+}
+
+// ArrayValue 数组属性值
+type ArrayValue struct {
+	Values []AnyValue `json:"values"`
+}
+
+// String 返回属性值的字符串形式
+func (v AnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return *v.IntValue
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	case v.ArrayValue != nil:
+		data, _ := json.Marshal(v.ArrayValue.Values)
+		return string(data)
+	default:
+		return ""
+	}
+}
+
+// ReadSpanFile 读取 FileExporter 写入的文件，按写入顺序返回所有 span
+func ReadSpanFile(path string) ([]Span, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var spans []Span
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var data TracesData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			return nil, fmt.Errorf("invalid trace line: %w", err)
+		}
+		for _, rs := range data.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans, scanner.Err()
+}
+
+// encodeSpans groups spans by resource and scope into OTLP/JSON
+func encodeSpans(spans []sdktrace.ReadOnlySpan) TracesData {
+	var data TracesData
+	resourceIndex := make(map[string]int)
+	scopeIndex := make(map[string]int)
+
+	for _, s := range spans {
+		resourceKey := ""
+		var resourceAttrs []attribute.KeyValue
+		if res := s.Resource(); res != nil {
+			resourceKey = res.Encoded(attribute.DefaultEncoder())
+			resourceAttrs = res.Attributes()
+		}
+		ri, ok := resourceIndex[resourceKey]
+		if !ok {
+			ri = len(data.ResourceSpans)
+			resourceIndex[resourceKey] = ri
+			data.ResourceSpans = append(data.ResourceSpans, ResourceSpans{
+				Resource: Resource{Attributes: encodeAttributes(resourceAttrs)},
+			})
+		}
+
+		scope := s.InstrumentationScope()
+		scopeKey := resourceKey + "\x00" + scope.Name + "\x00" + scope.Version
+		si, ok := scopeIndex[scopeKey]
+		if !ok {
+			si = len(data.ResourceSpans[ri].ScopeSpans)
+			scopeIndex[scopeKey] = si
+			data.ResourceSpans[ri].ScopeSpans = append(data.ResourceSpans[ri].ScopeSpans, ScopeSpans{
+				Scope: Scope{Name: scope.Name, Version: scope.Version},
+			})
+		}
+
+		ss := &data.ResourceSpans[ri].ScopeSpans[si]
+		ss.Spans = append(ss.Spans, encodeSpan(s))
+	}
+	return data
+}
+
+// encodeSpan converts a finished span to OTLP/JSON
+func encodeSpan(s sdktrace.ReadOnlySpan) Span {
+	sc := s.SpanContext()
+	span := Span{
+		TraceID:           sc.TraceID().String(),
+		SpanID:            sc.SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()), // trace.SpanKind values match the OTLP enum
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime().UnixNano(), 10),
+		Attributes:        encodeAttributes(s.Attributes()),
+	}
+	if parent := s.Parent(); parent.IsValid() {
+		span.ParentSpanID = parent.SpanID().String()
+	}
+
+	for _, event := range s.Events() {
+		span.Events = append(span.Events, SpanEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+			Attributes:   encodeAttributes(event.Attributes),
+		})
+	}
+
+	// OTLP orders the status codes differently from the Go API
+	switch s.Status().Code {
+	case codes.Ok:
+		span.Status.Code = StatusCodeOK
+	case codes.Error:
+		span.Status.Code = StatusCodeError
+		span.Status.Message = s.Status().Description
+	}
+	return span
+}
+
+// encodeAttributes converts attributes to OTLP key/values
+func encodeAttributes(attrs []attribute.KeyValue) []KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make([]KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		result = append(result, KeyValue{Key: string(attr.Key), Value: encodeValue(attr.Value)})
+	}
+	return result
+}
+
+// encodeValue converts an attribute value to an OTLP AnyValue
+func encodeValue(v attribute.Value) AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return AnyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return AnyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return AnyValue{DoubleValue: &f}
+	case attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+		var values []AnyValue
+		switch v.Type() {
+		case attribute.BOOLSLICE:
+			for _, b := range v.AsBoolSlice() {
+				values = append(values, encodeValue(attribute.BoolValue(b)))
+			}
+		case attribute.INT64SLICE:
+			for _, i := range v.AsInt64Slice() {
+				values = append(values, encodeValue(attribute.Int64Value(i)))
+			}
+		case attribute.FLOAT64SLICE:
+			for _, f := range v.AsFloat64Slice() {
+				values = append(values, encodeValue(attribute.Float64Value(f)))
+			}
+		default:
+			for _, s := range v.AsStringSlice() {
+				values = append(values, encodeValue(attribute.StringValue(s)))
+			}
+		}
+		return AnyValue{ArrayValue: &ArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return AnyValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,198 @@
+package telemetry
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/smallnest/goclaw/errors"
+)
+
+const namespace = "goclaw"
+
+// 通道消息结果标签
+const (
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultDropped = "dropped"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	// ChannelMessages 通道消息计数，按通道、方向（inbound/outbound）和结果统计
+	ChannelMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_messages_total",
+		Help:      "Messages handled per channel, by direction and result.",
+	}, []string{"channel", "direction", "result"})
+
+	// ProviderRequestDuration LLM 调用耗时
+	ProviderRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of LLM provider calls.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"provider", "result"})
+
+	// ProviderTokens LLM token 用量，type 为 prompt/completion/cache_read/cache_creation
+	ProviderTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_tokens_total",
+		Help:      "Tokens consumed by LLM provider calls.",
+	}, []string{"provider", "type"})
+
+	// ProviderErrors LLM 调用失败次数，reason 为故障转移原因
+	ProviderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "Failed LLM provider calls, by failover reason.",
+	}, []string{"provider", "reason"})
+
+	// CircuitBreakerState 断路器状态：0 关闭，1 打开，2 半开
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state (0 closed, 1 open, 2 half-open).",
+	}, []string{"breaker"})
+
+	// ToolDuration 工具执行耗时
+	ToolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tool_duration_seconds",
+		Help:      "Duration of tool executions.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 180},
+	}, []string{"tool", "result"})
+
+	// CronRuns 定时任务执行结果
+	CronRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_runs_total",
+		Help:      "Cron job runs, by job and status.",
+	}, []string{"job", "status"})
+
+	// CronRunDuration 定时任务执行耗时
+	CronRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cron_run_duration_seconds",
+		Help:      "Duration of cron job runs.",
+	}, []string{"job"})
+
+	classifier = errors.NewSimpleErrorClassifier()
+
+	queues = &queueCollector{
+		desc:  prometheus.NewDesc(namespace+"_bus_queue_depth", "Messages waiting in the message bus queues.", []string{"queue"}, nil),
+		depth: make(map[string]func() int),
+	}
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ChannelMessages,
+		ProviderRequestDuration,
+		ProviderTokens,
+		ProviderErrors,
+		CircuitBreakerState,
+		ToolDuration,
+		CronRuns,
+		CronRunDuration,
+		queues,
+	)
+}
+
+// Registry 返回 goclaw 指标注册表
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Handler 返回 Prometheus 指标的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterQueue 注册一个在采集时读取的队列深度，同名注册会覆盖
+func RegisterQueue(name string, depth func() int) {
+	queues.mu.Lock()
+	defer queues.mu.Unlock()
+	queues.depth[name] = depth
+}
+
+// RecordChannelMessage 记录一条通道消息
+func RecordChannelMessage(channel, direction, result string) {
+	ChannelMessages.WithLabelValues(channel, direction, result).Inc()
+}
+
+// RecordProviderCall 记录一次 LLM 调用，失败时按 SimpleErrorClassifier 的故障转移原因分类
+func RecordProviderCall(provider string, duration time.Duration, err error) {
+	ProviderRequestDuration.WithLabelValues(provider, resultLabel(err)).Observe(duration.Seconds())
+	if err != nil {
+		ProviderErrors.WithLabelValues(provider, FailoverReason(err)).Inc()
+	}
+}
+
+// RecordProviderTokens 记录 LLM token 用量
+func RecordProviderTokens(provider, tokenType string, count int) {
+	if count > 0 {
+		ProviderTokens.WithLabelValues(provider, tokenType).Add(float64(count))
+	}
+}
+
+// FailoverReason 返回错误对应的故障转移原因
+func FailoverReason(err error) string {
+	return string(classifier.ClassifyError(err))
+}
+
+// RecordToolCall 记录一次工具执行
+func RecordToolCall(tool string, duration time.Duration, err error) {
+	ToolDuration.WithLabelValues(tool, resultLabel(err)).Observe(duration.Seconds())
+}
+
+// RecordCronRun 记录一次定时任务执行
+func RecordCronRun(job, status string, duration time.Duration) {
+	CronRuns.WithLabelValues(job, status).Inc()
+	CronRunDuration.WithLabelValues(job).Observe(duration.Seconds())
+}
+
+// SetCircuitBreakerState 更新断路器状态
+func SetCircuitBreakerState(breaker string, state int) {
+	CircuitBreakerState.WithLabelValues(breaker).Set(float64(state))
+}
+
+// resultLabel 将错误转换为 ok/error 标签
+func resultLabel(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultOK
+}
+
+// queueCollector reports queue depths at scrape time
+type queueCollector struct {
+	desc  *prometheus.Desc
+	mu    sync.RWMutex
+	depth map[string]func() int
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.depth))
+	for name := range c.depth {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(c.depth[name]()), name)
+	}
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFileExporterWritesOTLPJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := NewTracerProvider(exporter, config.TracingConfig{ServiceName: "test"})
+	tracer := tp.Tracer(TracerName)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", trace.WithAttributes(
+		attribute.String("key", "value"),
+		attribute.Int("count", 3),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+	))
+	_, child := tracer.Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient))
+	EndSpan(child, errors.New("boom"))
+	EndSpan(parent, nil)
+
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := ReadSpanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	byName := map[string]Span{spans[0].Name: spans[0], spans[1].Name: spans[1]}
+	p, c := byName["parent"], byName["child"]
+	if p.TraceID == "" || len(p.TraceID) != 32 || c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID {
+		t.Errorf("child is not linked to parent: %+v %+v", p, c)
+	}
+	if p.Attribute("key") != "value" || p.Attribute("count") != "3" || p.Attribute("tags") == "" {
+		t.Errorf("unexpected attributes %+v", p.Attributes)
+	}
+	if c.Kind != 3 || c.Status.Code != StatusCodeError || c.Status.Message != "boom" || len(c.Events) != 1 {
+		t.Errorf("unexpected child span %+v", c)
+	}
+	if p.Status.Code != StatusCodeUnset {
+		t.Errorf("unexpected parent status %+v", p.Status)
+	}
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	shutdown, err := SetupTracing(config.TracingConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	RegisterQueue("test_inbound", func() int { return 7 })
+	RecordChannelMessage("telegram", "outbound", ResultError)
+	RecordProviderCall("anthropic", 2*time.Second, errors.New("401 unauthorized"))
+	RecordProviderTokens("anthropic", "prompt", 120)
+	RecordToolCall("shell", 50*time.Millisecond, nil)
+	RecordCronRun("daily", "ok", time.Second)
+	SetCircuitBreakerState("failover", 1)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`goclaw_bus_queue_depth{queue="test_inbound"} 7`,
+		`goclaw_channel_messages_total{channel="telegram",direction="outbound",result="error"} 1`,
+		`goclaw_provider_errors_total{provider="anthropic",reason="auth"} 1`,
+		`goclaw_provider_tokens_total{provider="anthropic",type="prompt"} 120`,
+		`goclaw_tool_duration_seconds_count{result="ok",tool="shell"} 1`,
+		`goclaw_cron_runs_total{job="daily",status="ok"} 1`,
+		`goclaw_circuit_breaker_state{breaker="failover"} 1`,
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+	if !strings.Contains(string(body), "go_goroutines") {
+		t.Error("expected runtime metrics")
+	}
+}